@@ -2,9 +2,9 @@ package httpio
 
 import (
 	"bytes"
-	"io"
-	"io/ioutil"
 	"net/http"
+
+	"github.com/travelgateX/go-io/syncio"
 )
 
 // HTTP gives io.Write methods to a http Client
@@ -29,7 +29,6 @@ func (w *HTTP) Write(p []byte) (int, error) {
 		return 0, err
 	}
 
-	io.Copy(ioutil.Discard, res.Body)
-	res.Body.Close()
+	syncio.DrainClose(res.Body, -1)
 	return len(p), nil
 }
@@ -0,0 +1,200 @@
+// Package iocopy copies a reader to a writer as io.Copy with pooled buffers, progress reports, a
+// bandwidth limit and cancellation. The context is checked between the reads and the writes and
+// while waiting for the limit, a Read or Write blocked in the underlying reader or writer isn't
+// interrupted.
+package iocopy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio"
+	"github.com/travelgateX/go-io/syncio/bufpool"
+)
+
+// DefaultBufferSize is the size of the buffers of the default pool, as io.Copy
+const DefaultBufferSize = 32 << 10
+
+// errInvalidWrite is returned when the writer returns an invalid count, as io.Copy
+var errInvalidWrite = errors.New("iocopy: invalid write result")
+
+var defaultPool = bufpool.New(DefaultBufferSize, 0)
+
+// Progress is a progress report of a Copy
+type Progress struct {
+	// Bytes is the number of bytes copied and Total the number expected, -1 if unknown
+	Bytes int64
+	Total int64
+	// Elapsed is the time since Copy started and Rate the average bytes per second
+	Elapsed time.Duration
+	Rate    float64
+	// ETA is the estimated time to copy the rest at Rate, -1 if unknown
+	ETA time.Duration
+	// Done reports the last report, Err is the error returned by Copy
+	Done bool
+	Err  error
+}
+
+// Option is an option of Copy
+type Option func(*copier)
+
+// SetBufferPool sets the pool of the buffer of the copy, the reads are up to its size. A pool
+// of DefaultBufferSize shared by the copies by default.
+func SetBufferPool(p *bufpool.Pool) Option {
+	return func(c *copier) {
+		c.pool = p
+	}
+}
+
+// SetTotal sets the number of bytes expected, used to estimate the ETA
+func SetTotal(n int64) Option {
+	return func(c *copier) {
+		c.total = n
+	}
+}
+
+// SetProgress calls fn with a report after the writes at most every d, with d <= 0 after
+// every write, and once done. fn is called by the goroutine of Copy.
+func SetProgress(d time.Duration, fn func(Progress)) Option {
+	return func(c *copier) {
+		c.every = d
+		c.progress = fn
+	}
+}
+
+// SetProgressChan sends the reports of SetProgress to ch, the reports are dropped while ch is
+// full but the last one, Copy waits until it's received
+func SetProgressChan(d time.Duration, ch chan<- Progress) Option {
+	return SetProgress(d, func(p Progress) {
+		if p.Done {
+			ch <- p
+			return
+		}
+		select {
+		case ch <- p:
+		default:
+		}
+	})
+}
+
+// SetRateLimit limits the copy to bytesPerSec, the reads are up to the buffer size
+func SetRateLimit(bytesPerSec float64) Option {
+	return func(c *copier) {
+		c.rate = bytesPerSec
+	}
+}
+
+// SetLimiter sets the limiter of the bytes copied, it can be shared to apply a common limit and
+// changed at runtime. The reads are up to its burst.
+func SetLimiter(l *syncio.Limiter) Option {
+	return func(c *copier) {
+		c.limiter = l
+	}
+}
+
+// SetClock replaces the system clock of the reports
+func SetClock(clock syncio.Clock) Option {
+	return func(c *copier) {
+		c.now = clock.Now
+	}
+}
+
+// copier is the configuration and state of a Copy
+type copier struct {
+	pool     *bufpool.Pool
+	total    int64
+	every    time.Duration
+	progress func(Progress)
+	rate     float64
+	limiter  *syncio.Limiter
+	now      func() time.Time
+
+	start, last time.Time
+}
+
+// Copy copies src to dst until EOF, an error or ctx is done, it returns the bytes written and
+// the first error, ctx.Err() once ctx is done. As io.Copy EOF isn't an error, unlike io.Copy
+// the WriterTo and ReaderFrom of src and dst are not used, the data goes through the buffer.
+func Copy(ctx context.Context, dst io.Writer, src io.Reader, options ...Option) (written int64, err error) {
+	c := &copier{pool: defaultPool, total: -1, now: time.Now}
+	for _, o := range options {
+		o(c)
+	}
+	buf := c.pool.Get()
+	defer c.pool.Put(buf)
+	if c.limiter == nil && c.rate > 0 {
+		c.limiter = syncio.NewLimiter(c.rate, len(buf))
+	}
+	chunk := buf
+	if c.limiter != nil {
+		if burst := c.limiter.Burst(); burst < len(chunk) {
+			chunk = chunk[:burst]
+		}
+	}
+	c.start = c.now()
+	c.last = c.start
+
+	defer func() {
+		c.report(written, true, err)
+	}()
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		n, rerr := src.Read(chunk)
+		if n > 0 {
+			if c.limiter != nil {
+				if err := c.limiter.WaitNContext(ctx, n); err != nil {
+					return written, err
+				}
+			}
+			m, werr := dst.Write(chunk[:n])
+			if m < 0 || m > n {
+				m = 0
+				if werr == nil {
+					werr = errInvalidWrite
+				}
+			}
+			written += int64(m)
+			if werr == nil && m < n {
+				werr = io.ErrShortWrite
+			}
+			if werr != nil {
+				return written, werr
+			}
+			c.report(written, false, nil)
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+// report calls the progress function if it's due
+func (c *copier) report(written int64, done bool, err error) {
+	if c.progress == nil {
+		return
+	}
+	now := c.now()
+	if !done && now.Sub(c.last) < c.every {
+		return
+	}
+	c.last = now
+	p := Progress{Bytes: written, Total: c.total, Elapsed: now.Sub(c.start), ETA: -1, Done: done, Err: err}
+	if p.Elapsed > 0 {
+		p.Rate = float64(written) / p.Elapsed.Seconds()
+	}
+	switch {
+	case c.total < 0:
+	case written >= c.total:
+		p.ETA = 0
+	case p.Rate > 0:
+		p.ETA = time.Duration(float64(c.total-written) / p.Rate * float64(time.Second))
+	}
+	c.progress(p)
+}
@@ -0,0 +1,144 @@
+package iocopy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio"
+	"github.com/travelgateX/go-io/syncio/bufpool"
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+// readerFunc adapts a function to io.Reader
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestCopy(t *testing.T) {
+	data := strings.Repeat("0123456789", 1000)
+	var out bytes.Buffer
+	n, err := Copy(context.Background(), &out, strings.NewReader(data), SetBufferPool(bufpool.New(64, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(data)) || out.String() != data {
+		t.Errorf("copied %v bytes, expected: %v", n, len(data))
+	}
+}
+
+func TestCopyProgress(t *testing.T) {
+	clock := synctest.NewFakeClock(time.Unix(0, 0))
+	src := strings.NewReader(strings.Repeat("x", 1000))
+	// every read of 100 bytes takes a second
+	slow := readerFunc(func(p []byte) (int, error) {
+		clock.Advance(time.Second)
+		return src.Read(p)
+	})
+	var reports []Progress
+	_, err := Copy(context.Background(), io.Discard, slow,
+		SetBufferPool(bufpool.New(100, 1)), SetTotal(1000), SetClock(clock),
+		SetProgress(2*time.Second, func(p Progress) { reports = append(reports, p) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) != 6 {
+		t.Fatalf("reports: %+v, expected 5 and the last one", reports)
+	}
+	p := reports[1]
+	if p.Bytes != 400 || p.Elapsed != 4*time.Second || p.Rate != 100 || p.ETA != 6*time.Second || p.Done {
+		t.Errorf("report: %+v, expected 400 bytes in 4s at 100B/s and 6s left", p)
+	}
+	last := reports[len(reports)-1]
+	if !last.Done || last.Bytes != 1000 || last.ETA != 0 || last.Err != nil {
+		t.Errorf("last report: %+v, expected done with 1000 bytes", last)
+	}
+}
+
+func TestCopyProgressChan(t *testing.T) {
+	ch := make(chan Progress)
+	done := make(chan error)
+	go func() {
+		_, err := Copy(context.Background(), io.Discard, strings.NewReader("data"), SetProgressChan(0, ch))
+		done <- err
+	}()
+	// the reports not received are dropped but the last one
+	p := <-ch
+	for !p.Done {
+		p = <-ch
+	}
+	if p.Bytes != 4 || p.Total != -1 || p.ETA != -1 {
+		t.Errorf("report: %+v, expected the last one with 4 bytes", p)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reads := 0
+	src := readerFunc(func(p []byte) (int, error) {
+		if reads++; reads == 2 {
+			cancel()
+		}
+		return copy(p, "abcd"), nil
+	})
+	var last Progress
+	n, err := Copy(ctx, io.Discard, src, SetProgress(0, func(p Progress) { last = p }))
+	if !errors.Is(err, context.Canceled) || n != 8 {
+		t.Errorf("copied %v bytes, %v, expected 8 bytes, %v", n, err, context.Canceled)
+	}
+	if !last.Done || last.Err != err {
+		t.Errorf("last report: %+v, expected done with %v", last, err)
+	}
+}
+
+func TestCopyRateLimit(t *testing.T) {
+	var out bytes.Buffer
+	start := time.Now()
+	// the bucket starts full with a burst of 100 bytes
+	n, err := Copy(context.Background(), &out, strings.NewReader(strings.Repeat("x", 300)),
+		SetLimiter(syncio.NewLimiter(1000, 100)))
+	if err != nil || n != 300 {
+		t.Fatalf("copied %v bytes, %v", n, err)
+	}
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Errorf("copied 300 bytes at 1000B/s in %v, expected about 200ms", d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	n, err = Copy(ctx, io.Discard, strings.NewReader(strings.Repeat("x", 300)), SetRateLimit(10),
+		SetBufferPool(bufpool.New(100, 1)))
+	if err != context.DeadlineExceeded || n != 100 {
+		t.Errorf("copied %v bytes, %v, expected 100 bytes, %v", n, err, context.DeadlineExceeded)
+	}
+}
+
+func TestCopyErrors(t *testing.T) {
+	fail := errors.New("read failed")
+	src := io.MultiReader(strings.NewReader("abc"), readerFunc(func([]byte) (int, error) { return 0, fail }))
+	var out bytes.Buffer
+	if n, err := Copy(context.Background(), &out, src); err != fail || n != 3 {
+		t.Errorf("copied %v bytes, %v, expected 3 bytes, %v", n, err, fail)
+	}
+
+	failing := synctest.FaultWriter{FailEvery: 2}
+	if n, err := Copy(context.Background(), &failing, io.MultiReader(strings.NewReader("ab"), strings.NewReader("cd"))); err != synctest.ErrInjected || n != 2 {
+		t.Errorf("copied %v bytes, %v, expected 2 bytes, %v", n, err, synctest.ErrInjected)
+	}
+
+	short := synctest.FaultWriter{W: io.Discard, ShortEvery: 1}
+	if _, err := Copy(context.Background(), &short, strings.NewReader("abcd")); err != io.ErrShortWrite {
+		t.Errorf("short write error: %v, expected: %v", err, io.ErrShortWrite)
+	}
+}
@@ -0,0 +1,269 @@
+package netio
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+// ErrConnClosed is returned by the sink writes of a closed ConnWriter
+var ErrConnClosed = errors.New("netio: connection writer closed")
+
+// DialFunc dials a connection, as net.Dialer.DialContext
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// ConnOption is an option of NewConnWriter
+type ConnOption func(*ConnWriter)
+
+// SetDialer sets the dial of the connections, a net.Dialer with a 5s timeout by default
+func SetDialer(dial DialFunc) ConnOption {
+	return func(w *ConnWriter) {
+		w.sink.dial = dial
+	}
+}
+
+// SetBackoff sets the wait between the dials once the connection is lost,
+// syncio.ExponentialBackoff(100ms, 10s) by default
+func SetBackoff(backoff syncio.BackoffFunc) ConnOption {
+	return func(w *ConnWriter) {
+		w.sink.backoff = backoff
+	}
+}
+
+// SetWriteTimeout sets the write deadline of every write to the connection, a write not done
+// in time drops the connection. 0, the default, is no deadline.
+func SetWriteTimeout(d time.Duration) ConnOption {
+	return func(w *ConnWriter) {
+		w.sink.writeTimeout = d
+	}
+}
+
+// SetCloseTimeout sets the time given by Close to flush the data, 5s by default
+func SetCloseTimeout(d time.Duration) ConnOption {
+	return func(w *ConnWriter) {
+		w.closeTimeout = d
+	}
+}
+
+// SetBuffer sets the options of the syncio.Buffer
+func SetBuffer(options ...syncio.BufferOption) ConnOption {
+	return func(w *ConnWriter) {
+		w.options = options
+	}
+}
+
+// ConnStats are the counters of a ConnWriter: Dials is the number of connections dialed,
+// DialErrors the failed dials, WriteErrors the failed writes that dropped a connection and
+// Connected reports if a connection is open
+type ConnStats struct {
+	Dials       int64
+	DialErrors  int64
+	WriteErrors int64
+	Connected   bool
+}
+
+// ConnWriter writes to a tcp, udp or unix socket through a syncio.Buffer: the connection is
+// dialed by the first flush and it's dialed again with backoff once a write fails, the rest of
+// the batch is written to the new one. During an outage the Buffer holds the writes, they
+// wait for space once its buffers are full unless its options discard them, e.g.
+// syncio.SetOverflowPolicy. With udp every flush is a datagram so the buffer size must fit it.
+type ConnWriter struct {
+	buf          *syncio.Buffer
+	sink         *connSink
+	options      []syncio.BufferOption
+	closeTimeout time.Duration
+}
+
+// NewConnWriter returns a ConnWriter writing to address on network, as net.Dial
+func NewConnWriter(network, address string, options ...ConnOption) *ConnWriter {
+	w := &ConnWriter{
+		sink:         &connSink{network: network, address: address, stop: make(chan struct{})},
+		closeTimeout: 5 * time.Second,
+	}
+	for _, o := range options {
+		o(w)
+	}
+	if w.sink.dial == nil {
+		d := &net.Dialer{Timeout: 5 * time.Second}
+		w.sink.dial = d.DialContext
+	}
+	if w.sink.backoff == nil {
+		w.sink.backoff = syncio.ExponentialBackoff(100*time.Millisecond, 10*time.Second)
+	}
+	w.buf = syncio.NewBuffer(w.sink, w.options...)
+	return w
+}
+
+// Write buffers p
+func (w *ConnWriter) Write(p []byte) (int, error) {
+	if w.buf == nil {
+		return 0, syncio.ErrNotInitialized
+	}
+	return w.buf.Write(p)
+}
+
+// Flush writes the buffered data, it waits for the connection
+func (w *ConnWriter) Flush() error {
+	if w.buf == nil {
+		return syncio.ErrNotInitialized
+	}
+	return w.buf.Flush()
+}
+
+// Buffer returns the syncio.Buffer of the writes
+func (w *ConnWriter) Buffer() *syncio.Buffer {
+	return w.buf
+}
+
+// Stats returns the counters of the connections
+func (w *ConnWriter) Stats() ConnStats {
+	s := w.sink
+	s.mu.Lock()
+	connected := s.conn != nil
+	s.mu.Unlock()
+	return ConnStats{
+		Dials:       atomic.LoadInt64(&s.dials),
+		DialErrors:  atomic.LoadInt64(&s.dialErrors),
+		WriteErrors: atomic.LoadInt64(&s.writeErrors),
+		Connected:   connected,
+	}
+}
+
+// Close flushes the data within the close timeout and closes the connection, the data not
+// written by then is abandoned and reported as syncio.CloseTimeout does
+func (w *ConnWriter) Close() error {
+	if w.buf == nil {
+		return syncio.ErrNotInitialized
+	}
+	_, err := w.buf.CloseTimeout(w.closeTimeout)
+	if cerr := w.sink.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// connSink is the underlying writer of the Buffer of a ConnWriter, it's written by the flush
+// goroutine and closed by CloseTimeout to interrupt the write in flight
+type connSink struct {
+	network, address string
+	dial             DialFunc
+	backoff          syncio.BackoffFunc
+	writeTimeout     time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	stop chan struct{}
+	once sync.Once
+
+	dials       int64
+	dialErrors  int64
+	writeErrors int64
+}
+
+// Write writes p to the connection, dialing it until it succeeds or the sink is closed
+func (s *connSink) Write(p []byte) (int, error) {
+	written := 0
+	attempt := 0
+	for {
+		conn, err := s.connect()
+		if err == nil {
+			if s.writeTimeout > 0 {
+				conn.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+			}
+			var n int
+			n, err = conn.Write(p[written:])
+			written += n
+			if err == nil {
+				return written, nil
+			}
+			atomic.AddInt64(&s.writeErrors, 1)
+			s.drop(conn)
+		}
+		if s.closed() {
+			return written, err
+		}
+		attempt++
+		t := time.NewTimer(s.backoff(attempt))
+		select {
+		case <-t.C:
+		case <-s.stop:
+			t.Stop()
+			return written, ErrConnClosed
+		}
+	}
+}
+
+// connect returns the connection, dialing it if there is none
+func (s *connSink) connect() (net.Conn, error) {
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+	if s.closed() {
+		return nil, ErrConnClosed
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	atomic.AddInt64(&s.dials, 1)
+	conn, err := s.dial(ctx, s.network, s.address)
+	if err != nil {
+		atomic.AddInt64(&s.dialErrors, 1)
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed() {
+		conn.Close()
+		return nil, ErrConnClosed
+	}
+	s.conn = conn
+	return conn, nil
+}
+
+// drop closes the connection after a failed write
+func (s *connSink) drop(conn net.Conn) {
+	conn.Close()
+	s.mu.Lock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+	s.mu.Unlock()
+}
+
+func (s *connSink) closed() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops the dials and closes the connection, the write in flight fails
+func (s *connSink) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		close(s.stop)
+		if s.conn != nil {
+			err = s.conn.Close()
+			s.conn = nil
+		}
+	})
+	return err
+}
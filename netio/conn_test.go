@@ -0,0 +1,132 @@
+package netio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+// pipeServer dials net.Pipe connections failing the first dials, the data received by the
+// server ends is collected in order
+type pipeServer struct {
+	mu       sync.Mutex
+	fails    int
+	conns    []net.Conn
+	received bytes.Buffer
+	wg       sync.WaitGroup
+}
+
+func (s *pipeServer) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fails > 0 {
+		s.fails--
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	s.conns = append(s.conns, server)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		p := make([]byte, 64)
+		for {
+			n, err := server.Read(p)
+			s.mu.Lock()
+			s.received.Write(p[:n])
+			s.mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return client, nil
+}
+
+func (s *pipeServer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.received.String()
+}
+
+func TestConnWriterReconnect(t *testing.T) {
+	s := &pipeServer{fails: 2}
+	w := NewConnWriter("tcp", "collector:514", SetDialer(s.dial), SetBackoff(func(int) time.Duration { return time.Millisecond }))
+	w.Write([]byte("hello "))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if st := w.Stats(); st.Dials != 3 || st.DialErrors != 2 || !st.Connected {
+		t.Errorf("stats after the dial errors: %+v", st)
+	}
+
+	// the collector restarts, the next flush goes to a new connection
+	s.mu.Lock()
+	s.conns[0].Close()
+	s.mu.Unlock()
+	w.Write([]byte("world"))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s.wg.Wait()
+	if got := s.String(); got != "hello world" {
+		t.Errorf("received %q", got)
+	}
+	if st := w.Stats(); st.Dials != 4 || st.WriteErrors != 1 || st.Connected {
+		t.Errorf("stats after the restart: %+v", st)
+	}
+}
+
+func TestConnWriterCloseTimeout(t *testing.T) {
+	s := &pipeServer{fails: 1 << 30}
+	w := NewConnWriter("tcp", "collector:514", SetDialer(s.dial), SetBackoff(func(int) time.Duration { return time.Millisecond }),
+		SetCloseTimeout(50*time.Millisecond))
+	w.Write([]byte("lost"))
+	start := time.Now()
+	if err := w.Close(); !errors.Is(err, syncio.ErrCloseTimeout) {
+		t.Errorf("close: %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("close took %v", d)
+	}
+}
+
+func TestConnWriterTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+	received := make(chan []byte, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		p, _ := io.ReadAll(c)
+		received <- p
+	}()
+	w := NewConnWriter("tcp", l.Addr().String(), SetBuffer(syncio.SetBufferSize(16)))
+	var expected []byte
+	for i := 0; i < 100; i++ {
+		p := []byte{byte('a' + i%26), byte('a' + i%26), '\n'}
+		w.Write(p)
+		expected = append(expected, p...)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if p := <-received; !bytes.Equal(p, expected) {
+		t.Errorf("received %q", p)
+	}
+}
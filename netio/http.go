@@ -2,9 +2,9 @@ package netio
 
 import (
 	"bytes"
-	"io"
-	"io/ioutil"
 	"net/http"
+
+	"github.com/travelgateX/go-io/syncio"
 )
 
 // HTTPWriter gives io.Write methods to a http Client
@@ -29,7 +29,6 @@ func (w *HTTPWriter) Write(p []byte) (int, error) {
 		return 0, err
 	}
 
-	io.Copy(ioutil.Discard, res.Body)
-	res.Body.Close()
+	syncio.DrainClose(res.Body, -1)
 	return len(p), nil
 }
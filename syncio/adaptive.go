@@ -0,0 +1,140 @@
+package syncio
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// SetAdaptiveSizing lets the Buffer resize its buffers between min and max bytes based on the
+// observed write rate, the target is to fill a buffer in one flush interval (or one second
+// without flush interval). The rate is measured over windows of that duration and the size is
+// only adjusted when a buffer is flushed. SetBufferSize sets the initial size, min by default.
+func SetAdaptiveSizing(min, max int) BufferOption {
+	return func(b *Buffer) {
+		if min < 1 {
+			min = 1
+		}
+		if max < min {
+			max = min
+		}
+		b.adaptive = &adaptiveSizing{min: min, max: max}
+	}
+}
+
+// adaptiveSizing is the controller of SetAdaptiveSizing, it's guarded by bufmu
+type adaptiveSizing struct {
+	min, max int
+	target   time.Duration
+	// bytes flushed since the window start
+	bytes int
+	start time.Time
+}
+
+func (a *adaptiveSizing) init(tb *Buffer) {
+	a.target = tb.flushInterval
+	if a.target <= 0 {
+		a.target = time.Second
+	}
+	if tb.bufSize < a.min {
+		tb.bufSize = a.min
+	} else if tb.bufSize > a.max {
+		tb.bufSize = a.max
+	}
+	a.start = tb.clock.Now()
+}
+
+// observe accounts a flushed buffer of n bytes and resizes once per window, the caller
+// must hold bufmu before getting the next buffer from the pool
+func (a *adaptiveSizing) observe(tb *Buffer, n int) {
+	a.bytes += n
+	now := tb.clock.Now()
+	elapsed := now.Sub(a.start)
+	if elapsed < a.target {
+		return
+	}
+
+	size := int(float64(a.bytes) / elapsed.Seconds() * a.target.Seconds())
+	if size < a.min {
+		size = a.min
+	} else if size > a.max {
+		size = a.max
+	}
+	a.bytes = 0
+	a.start = now
+
+	// ignore small variations to not reallocate the pool on every window
+	diff := size - tb.bufSize
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff <= tb.bufSize/10 {
+		return
+	}
+	tb.bufSize = size
+	tb.pool.Resize(size)
+	atomic.StoreInt32(&tb.stats.BufferSize, int32(size))
+	atomic.AddInt32(&tb.stats.Resizes, 1)
+	if tb.journal != nil {
+		tb.journal.record(journalResize, uint64(size))
+	}
+}
+
+// intervalWeight is the weight of the last flush duration in the SetAdaptiveInterval EWMA
+const intervalWeight = 0.25
+
+// SetAdaptiveInterval adjusts the flush interval between min and max to the sink latency, an
+// EWMA of the flush durations updated at each flush: the ticks are never more frequent than
+// the flushes the sink can take. SetFlushInterval sets the initial interval, min by default.
+// The current interval is reported in Stats.FlushInterval and used from the next tick.
+func SetAdaptiveInterval(min, max time.Duration) BufferOption {
+	return func(b *Buffer) {
+		if min < time.Millisecond {
+			min = time.Millisecond
+		}
+		if max < min {
+			max = min
+		}
+		b.adaptiveInterval = &adaptiveInterval{min: min, max: max}
+	}
+}
+
+// adaptiveInterval is the controller of SetAdaptiveInterval, the EWMA is only used by the
+// flush goroutine and the interval is read by the ticker goroutine
+type adaptiveInterval struct {
+	min, max time.Duration
+	ewma     float64
+	observed bool
+	interval atomic.Int64
+}
+
+func (a *adaptiveInterval) init(tb *Buffer) {
+	tb.flushInterval = a.clamp(tb.flushInterval)
+	a.interval.Store(int64(tb.flushInterval))
+}
+
+func (a *adaptiveInterval) clamp(d time.Duration) time.Duration {
+	if d < a.min {
+		return a.min
+	}
+	if d > a.max {
+		return a.max
+	}
+	return d
+}
+
+// observe accounts the duration of a flush to the sink
+func (a *adaptiveInterval) observe(tb *Buffer, d time.Duration) {
+	if a.observed {
+		a.ewma = intervalWeight*float64(d) + (1-intervalWeight)*a.ewma
+	} else {
+		a.ewma = float64(d)
+		a.observed = true
+	}
+	interval := a.clamp(time.Duration(a.ewma))
+	a.interval.Store(int64(interval))
+	atomic.StoreInt64((*int64)(&tb.stats.FlushInterval), int64(interval))
+}
+
+func (a *adaptiveInterval) current() time.Duration {
+	return time.Duration(a.interval.Load())
+}
@@ -0,0 +1,116 @@
+package syncio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveSizingSteady(t *testing.T) {
+	clock := newFakeClock()
+	tb := NewBuffer(&testWriter{}, SetClock(clock), SetFlushInterval(time.Second), SetAdaptiveSizing(1024, 64*1024))
+	defer tb.Close()
+
+	// 10KB/s during 10 seconds
+	p := make([]byte, 100)
+	for i := 0; i < 1000; i++ {
+		clock.Advance(10 * time.Millisecond)
+		tb.Write(p)
+	}
+
+	s := tb.Stats()
+	if s.BufferSize < 9*1024 || s.BufferSize > 11*1024 {
+		t.Errorf("buffer size: %v, expected ~10KB", s.BufferSize)
+	}
+	if s.Resizes == 0 {
+		t.Errorf("no resizes registered")
+	}
+}
+
+func TestAdaptiveSizingBursty(t *testing.T) {
+	clock := newFakeClock()
+	tb := NewBuffer(&testWriter{}, SetClock(clock), SetFlushInterval(time.Second), SetAdaptiveSizing(1024, 64*1024))
+	defer tb.Close()
+
+	// bursts of 5KB every second, the flush emulates the interval tick
+	p := make([]byte, 500)
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 10; j++ {
+			tb.Write(p)
+		}
+		clock.Advance(time.Second)
+		tb.Flush()
+	}
+
+	s := tb.Stats()
+	if s.BufferSize < 4*1024 || s.BufferSize > 6*1024 {
+		t.Errorf("buffer size: %v, expected ~5KB", s.BufferSize)
+	}
+	resizes := s.Resizes
+
+	// it has converged: the size must be stable
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			tb.Write(p)
+		}
+		clock.Advance(time.Second)
+		tb.Flush()
+	}
+	if s := tb.Stats(); s.Resizes != resizes {
+		t.Errorf("resizes with a stable load: %v, expected: %v", s.Resizes-resizes, 0)
+	}
+}
+
+func TestAdaptiveSizingIdle(t *testing.T) {
+	clock := newFakeClock()
+	tb := NewBuffer(&testWriter{}, SetClock(clock), SetFlushInterval(time.Second), SetBufferSize(32*1024), SetAdaptiveSizing(1024, 64*1024))
+	defer tb.Close()
+
+	for i := 0; i < 5; i++ {
+		tb.Write(make([]byte, 10))
+		clock.Advance(time.Second)
+		tb.Flush()
+	}
+	if s := tb.Stats(); s.BufferSize != 1024 {
+		t.Errorf("buffer size: %v, expected the minimum: %v", s.BufferSize, 1024)
+	}
+}
+
+func TestAdaptiveInterval(t *testing.T) {
+	clock := newFakeClock()
+	var latency time.Duration
+	sink := writerFunc(func(p []byte) (int, error) {
+		clock.Advance(latency)
+		return len(p), nil
+	})
+	tb := NewBuffer(sink, SetClock(clock), SetFlushInterval(50*time.Millisecond), SetAdaptiveInterval(10*time.Millisecond, time.Second))
+	defer tb.Close()
+
+	flush := func(d time.Duration) time.Duration {
+		latency = d
+		tb.Write([]byte("x"))
+		tb.Flush()
+		return tb.Stats().FlushInterval
+	}
+	if d := tb.Stats().FlushInterval; d != 50*time.Millisecond {
+		t.Errorf("initial interval: %v, expected: %v", d, 50*time.Millisecond)
+	}
+	if d := flush(300 * time.Millisecond); d != 300*time.Millisecond {
+		t.Errorf("interval: %v, expected: %v", d, 300*time.Millisecond)
+	}
+	// the ticker is replaced on the next tick
+	clock.ticker(0) <- time.Time{}
+	clock.ticker(1)
+
+	if d := flush(100 * time.Millisecond); d != 250*time.Millisecond {
+		t.Errorf("interval: %v, expected: %v", d, 250*time.Millisecond)
+	}
+	if d := flush(5 * time.Second); d != time.Second {
+		t.Errorf("interval: %v, expected the max: %v", d, time.Second)
+	}
+	for i := 0; i < 30; i++ {
+		flush(0)
+	}
+	if d := tb.Stats().FlushInterval; d != 10*time.Millisecond {
+		t.Errorf("interval: %v, expected the min: %v", d, 10*time.Millisecond)
+	}
+}
@@ -0,0 +1,20 @@
+package syncio
+
+import "time"
+
+// SetFlushAlignment aligns the ticks of the flush interval to the wall clock, the first tick
+// is at the next multiple of align since the zero time, e.g. at the start of every minute
+// with a minute, and the next ones every interval. The interval should be a multiple of
+// align to keep every tick aligned. The multiples are of absolute time, so they aren't
+// affected by the daylight saving changes. Flush and Close are not aligned.
+func SetFlushAlignment(align time.Duration) BufferOption {
+	return func(b *Buffer) {
+		b.flushAlignment = align
+	}
+}
+
+// alignDelay returns the time until the next aligned tick
+func (tb *Buffer) alignDelay() time.Duration {
+	now := tb.clock.Now()
+	return now.Truncate(tb.flushAlignment).Add(tb.flushAlignment).Sub(now)
+}
@@ -0,0 +1,57 @@
+package syncio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlushAlignment(t *testing.T) {
+	clock := newFakeClock()
+	clock.Advance(17*time.Second + 500*time.Millisecond)
+	tw := &testWriter{}
+	tb := NewBuffer(tw, SetClock(clock), SetFlushInterval(time.Minute), SetFlushAlignment(time.Minute))
+
+	tb.Write([]byte("data"))
+	first := clock.ticker(0)
+	if d := clock.periods[0]; d != 42*time.Second+500*time.Millisecond {
+		t.Errorf("first tick in: %v, expected: 42.5s", d)
+	}
+	clock.Advance(42*time.Second + 500*time.Millisecond)
+	first <- clock.Now()
+	// the ticker is replaced by the interval one after the aligned tick
+	clock.ticker(1)
+	if d := clock.periods[1]; d != time.Minute {
+		t.Errorf("next ticks every: %v, expected: 1m", d)
+	}
+	settle(tb)
+	if tw.writes != 1 {
+		t.Errorf("writes after the aligned tick: %v, expected: 1", tw.writes)
+	}
+
+	// Close doesn't wait for the next tick
+	tb.Write([]byte("data"))
+	tb.Close()
+	if tw.writes != 2 {
+		t.Errorf("writes after close: %v, expected: 2", tw.writes)
+	}
+}
+
+func TestAlignDelay(t *testing.T) {
+	for _, c := range []struct {
+		at, expected time.Duration
+	}{
+		// at a boundary the tick is at the next one
+		{0, time.Hour},
+		{time.Nanosecond, time.Hour - time.Nanosecond},
+		{59 * time.Minute, time.Minute},
+		// the boundaries are of absolute time, e.g. across a DST change in the local zone
+		{24*time.Hour*88 + 30*time.Minute, 30 * time.Minute},
+	} {
+		clock := newFakeClock()
+		clock.Advance(c.at)
+		tb := &Buffer{clock: clock, flushAlignment: time.Hour}
+		if d := tb.alignDelay(); d != c.expected {
+			t.Errorf("delay at %v: %v, expected: %v", clock.Now(), d, c.expected)
+		}
+	}
+}
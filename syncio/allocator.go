@@ -0,0 +1,37 @@
+package syncio
+
+import "github.com/travelgateX/go-io/syncio/internal"
+
+// SetAllocator allocates the buffers of the pool with alloc instead of the Go heap, e.g. from
+// a reserved arena or mmap. free is called with the buffers dropped: when the pool is full,
+// resized by SetAdaptiveSizing and on Close, the buffers still in use then are freed once
+// they are released. The scratch memory of the flush goroutine and the SetRetention copies
+// stay on the heap. alloc must return a slice of at least size bytes.
+func SetAllocator(alloc func(size int) []byte, free func([]byte)) BufferOption {
+	return func(b *Buffer) {
+		b.alloc = alloc
+		b.free = free
+	}
+}
+
+// freeMemory frees the memory of the pool once the Buffer is closed, the active buffer is
+// replaced by an empty one
+func (tb *Buffer) freeMemory() {
+	if !tb.allocating() {
+		return
+	}
+	tb.lockBuf()
+	buf := tb.buf
+	tb.buf = internal.Empty()
+	tb.unlockBuf()
+	tb.putBuffer(buf)
+	tb.pool.Close()
+	if tb.budget != nil {
+		tb.budget.detach(tb)
+	}
+}
+
+// allocating reports if the pool memory is allocated by SetAllocator or accounted by SetBudget
+func (tb *Buffer) allocating() bool {
+	return tb.alloc != nil || tb.budget != nil
+}
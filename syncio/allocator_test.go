@@ -0,0 +1,86 @@
+package syncio
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingAllocator tracks the live slices it allocated
+type countingAllocator struct {
+	mu     sync.Mutex
+	live   map[*byte]bool
+	allocs int
+}
+
+func (a *countingAllocator) alloc(size int) []byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b := make([]byte, size)
+	a.live[&b[0]] = true
+	a.allocs++
+	return b
+}
+
+func (a *countingAllocator) free(b []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.live[&b[:1][0]] {
+		panic("free of a slice not allocated")
+	}
+	delete(a.live, &b[:1][0])
+}
+
+func (a *countingAllocator) lives() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.live)
+}
+
+func TestAllocator(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		a := &countingAllocator{live: map[*byte]bool{}}
+		var sink bytes.Buffer
+		tb := NewBuffer(&sink, append([]BufferOption{SetBufferSize(8), SetBufferPoolSize(2), SetRetention(64),
+			SetAllocator(a.alloc, a.free), SetFlushTransform(func(dst, src []byte) ([]byte, error) {
+				return append(dst, src...), nil
+			})}, mode...)...)
+		for i := 0; i < 20; i++ {
+			tb.Write([]byte("0123456"))
+		}
+		tb.Flush()
+		tb.Write([]byte("tail"))
+		if err := tb.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if sink.Len() != 20*7+4 {
+			t.Errorf("sink bytes: %v, expected: %v", sink.Len(), 20*7+4)
+		}
+		if a.allocs == 0 || a.lives() != 0 {
+			t.Errorf("%v buffers alive after close of %v allocated", a.lives(), a.allocs)
+		}
+		if len(tb.Snapshot()) != 0 || len(tb.Steal()) != 0 {
+			t.Error("data pending after close")
+		}
+	})
+}
+
+func TestAllocatorCloseTimeout(t *testing.T) {
+	a := &countingAllocator{live: map[*byte]bool{}}
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(4), SetAllocator(a.alloc, a.free))
+	tb.Write([]byte("abcd"))
+	tb.Write([]byte("efgh"))
+	tb.Write([]byte("ij"))
+	if _, err := tb.CloseTimeout(10 * time.Millisecond); err == nil {
+		t.Fatal("close with the sink blocked without error")
+	}
+	// the batch in flight is freed once written
+	close(bw.release)
+	<-tb.done
+	if a.lives() != 0 {
+		t.Errorf("%v buffers alive after close of %v allocated", a.lives(), a.allocs)
+	}
+}
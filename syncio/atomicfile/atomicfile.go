@@ -0,0 +1,219 @@
+// Package atomicfile writes a file atomically: the data goes to a temporary file next to it,
+// optionally through a syncio.Buffer, that replaces the file on Close once synced, so a crash
+// leaves either the old content or the new one. The filesystem is pluggable to write to
+// io/fs like implementations such as afero.
+package atomicfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+// ErrClosed is returned by the calls of a Writer committed or aborted
+var ErrClosed = errors.New("atomicfile: write on closed writer")
+
+// File is a file of a FS
+type File interface {
+	io.Writer
+	Sync() error
+	Close() error
+}
+
+// FS is the filesystem of a Writer, the methods have the semantics of the os functions. An
+// afero.Fs is adapted returning its afero.File from OpenFile.
+type FS interface {
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+}
+
+// OS is the FS of the os package
+var OS FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// Option is an option of NewWriter
+type Option func(*Writer)
+
+// SetFS sets the filesystem, OS by default
+func SetFS(fsys FS) Option {
+	return func(w *Writer) {
+		w.fs = fsys
+	}
+}
+
+// SetFileMode sets the permissions of the file, 0644 by default
+func SetFileMode(mode os.FileMode) Option {
+	return func(w *Writer) {
+		w.mode = mode
+	}
+}
+
+// SetBuffer writes the data through a syncio.Buffer with the options, it's closed before the
+// temporary file is synced so its flush errors abort the write
+func SetBuffer(options ...syncio.BufferOption) Option {
+	return func(w *Writer) {
+		w.bufOptions = append([]syncio.BufferOption{}, options...)
+		w.buffered = true
+	}
+}
+
+// Writer writes a file atomically, the temporary file replaces the file on Close and it's
+// removed by Abort. A failed Write aborts the file on Close. It's safe for concurrent use.
+type Writer struct {
+	fs         FS
+	mode       os.FileMode
+	buffered   bool
+	bufOptions []syncio.BufferOption
+
+	path string
+	tmp  string
+	f    File
+	tb   *syncio.Buffer
+
+	mu     sync.Mutex
+	closed bool
+	err    error
+}
+
+// NewWriter creates the temporary file of path in its directory
+func NewWriter(path string, options ...Option) (*Writer, error) {
+	w := &Writer{fs: OS, mode: 0o644, path: path}
+	for _, o := range options {
+		o(w)
+	}
+	// the temporary file is in the same directory for the rename to be atomic
+	dir, name := filepath.Split(path)
+	for i := 0; ; i++ {
+		w.tmp = filepath.Join(dir, fmt.Sprintf(".%s.%d-%d.tmp", name, os.Getpid(), time.Now().UnixNano()))
+		f, err := w.fs.OpenFile(w.tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, w.mode)
+		if err == nil {
+			w.f = f
+			break
+		}
+		if !errors.Is(err, os.ErrExist) || i == 10 {
+			return nil, err
+		}
+	}
+	if w.buffered {
+		w.tb = syncio.NewBuffer(w.f, w.bufOptions...)
+	}
+	return w, nil
+}
+
+// Name returns the path of the file
+func (w *Writer) Name() string {
+	return w.path
+}
+
+// TempName returns the path of the temporary file
+func (w *Writer) TempName() string {
+	return w.tmp
+}
+
+// Write writes p to the temporary file
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	var n int
+	var err error
+	if w.tb != nil {
+		n, err = w.tb.Write(p)
+	} else {
+		n, err = w.f.Write(p)
+	}
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// Close syncs the temporary file and renames it to the file, then syncs the directory. After
+// a failure the temporary file is removed and the file is left as it was.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+	err := w.err
+	if w.tb != nil {
+		if cerr := w.tb.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err == nil {
+		err = w.f.Sync()
+	}
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = w.fs.Rename(w.tmp, w.path)
+	}
+	if err != nil {
+		w.fs.Remove(w.tmp)
+		return err
+	}
+	return w.syncDir()
+}
+
+// syncDir makes the rename durable, the directories can't be synced on windows
+func (w *Writer) syncDir() error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := w.fs.OpenFile(filepath.Dir(w.path), os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Abort discards the data written removing the temporary file, the file is left as it was.
+// It does nothing after Close.
+func (w *Writer) Abort() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.tb != nil {
+		// the buffered data is abandoned
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w.tb.CloseContext(ctx)
+	}
+	w.f.Close()
+	return w.fs.Remove(w.tmp)
+}
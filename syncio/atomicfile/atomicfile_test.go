@@ -0,0 +1,119 @@
+package atomicfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+func TestWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(path, SetFileMode(0o600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("new "))
+	w.Write([]byte("content"))
+	// the file is replaced by Close only
+	if p, _ := os.ReadFile(path); string(p) != "old" {
+		t.Errorf("content before close: %q, expected: %q", p, "old")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := os.ReadFile(path); string(p) != "new content" {
+		t.Errorf("content: %q, expected: %q", p, "new content")
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("stat: %v, %v, expected mode 0600", fi, err)
+	}
+	if _, err := os.Stat(w.TempName()); !os.IsNotExist(err) {
+		t.Errorf("temporary file after close: %v", err)
+	}
+	if _, err := w.Write([]byte("x")); err != ErrClosed {
+		t.Errorf("write after close: %v, expected: %v", err, ErrClosed)
+	}
+}
+
+func TestWriterAbort(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "snapshot")
+	w, err := NewWriter(path, SetBuffer(syncio.SetBufferSize(16)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("partial snapshot data"))
+	if err := w.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("files after abort: %v, expected none", entries)
+	}
+	if err := w.Close(); err != ErrClosed {
+		t.Errorf("close after abort: %v, expected: %v", err, ErrClosed)
+	}
+}
+
+func TestWriterBuffer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot")
+	w, err := NewWriter(path, SetBuffer(syncio.SetBufferSize(8)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		w.Write([]byte("0123456789"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 1000 {
+		t.Errorf("stat: %v, %v, expected 1000 bytes", fi, err)
+	}
+}
+
+// failingFS fails the writes of the files
+type failingFS struct {
+	FS
+	err error
+}
+
+type failingFile struct {
+	File
+	err error
+}
+
+func (fs failingFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := fs.FS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return failingFile{f, fs.err}, nil
+}
+
+func (f failingFile) Write(p []byte) (int, error) {
+	return 0, f.err
+}
+
+func TestWriterFailure(t *testing.T) {
+	errWrite := errors.New("disk full")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	w, err := NewWriter(path, SetFS(failingFS{OS, errWrite}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("abc"))
+	if err := w.Close(); err != errWrite {
+		t.Errorf("close: %v, expected: %v", err, errWrite)
+	}
+	// the failed file isn't committed
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("files after a failure: %v, expected none", entries)
+	}
+}
@@ -0,0 +1,123 @@
+package syncio
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// SetMaxBacklogAge limits the age of the oldest unflushed byte, once it's older than d the
+// policy is applied to the new writes until the data reaches the underlying writer:
+// OverflowBlock makes them wait and OverflowDropNewest discards them, counted in
+// Stats.BacklogDrops. Backlogged reports it meanwhile. The data waiting in the active buffer
+// is flushed when it's the oldest. It panics with OverflowDropOldest or an unknown policy.
+// The age is checked by the flushes, the ticks and the writes that don't fit in the active
+// buffer, it's reported by Stats.BacklogAge.
+func SetMaxBacklogAge(d time.Duration, policy OverflowPolicy) BufferOption {
+	mustValid(overflowPolicyNames, int(policy), "OverflowPolicy")
+	if policy == OverflowDropOldest {
+		panic(fmt.Sprintf("syncio: OverflowPolicy %v is not supported by SetMaxBacklogAge", policy))
+	}
+	return func(b *Buffer) {
+		b.backlog = &backlog{maxAge: d, policy: policy}
+	}
+}
+
+// backlog is the age tracking of SetMaxBacklogAge. The stamps are the clock time in
+// nanoseconds of the first byte of the active buffer or a batch, every batch keeps the stamp
+// of its data so the oldest byte is in the batch being written, the first queued batch or
+// the active buffer, in this order. A stamp of 0 means no data.
+type backlog struct {
+	maxAge time.Duration
+	policy OverflowPolicy
+
+	// active is stamped by the first write of the active buffer
+	active atomic.Int64
+	// writing is the stamp of the batches being written by the flush goroutine
+	writing atomic.Int64
+	// oldest is the stamp of the oldest byte seen by the last check
+	oldest   atomic.Int64
+	exceeded atomic.Bool
+}
+
+func (l *backlog) now(tb *Buffer) int64 {
+	return tb.clock.Now().UnixNano()
+}
+
+// stamp marks the first write of the active buffer, the caller must own it
+func (l *backlog) stamp(tb *Buffer) {
+	l.active.Store(l.now(tb))
+}
+
+// take returns the stamp of the active buffer data sent to flush, the stamp is kept for the
+// bytes carried to the next buffer
+func (l *backlog) take(tb *Buffer, carry bool) int64 {
+	since := l.active.Load()
+	if since == 0 {
+		since = l.now(tb)
+	}
+	if !carry {
+		l.active.Store(0)
+	}
+	return since
+}
+
+// check updates the oldest stamp and the exceeded flag, the writers waiting for the backlog
+// are woken up when it's no longer exceeded. The caller must hold bufmu.
+func (l *backlog) check(tb *Buffer) bool {
+	since := l.writing.Load()
+	for i := 0; since == 0 && i < len(tb.queue); i++ {
+		if tb.queue[i].len() > 0 {
+			since = tb.queue[i].since
+		}
+	}
+	if since == 0 {
+		since = l.active.Load()
+	}
+	l.oldest.Store(since)
+	exceeded := since != 0 && time.Duration(l.now(tb)-since) > l.maxAge
+	if l.exceeded.Swap(exceeded) && !exceeded {
+		tb.space.Broadcast()
+	}
+	return exceeded
+}
+
+// age returns the age of the oldest byte seen by the last check
+func (l *backlog) age(tb *Buffer) time.Duration {
+	since := l.oldest.Load()
+	if since == 0 {
+		return 0
+	}
+	return time.Duration(l.now(tb) - since)
+}
+
+// groupSince returns the stamp of the first batch with data of a group
+func groupSince(group []*batch) int64 {
+	for _, b := range group {
+		if b.len() > 0 {
+			return b.since
+		}
+	}
+	return 0
+}
+
+// backlogged checks the backlog before a Write, when the active buffer has the oldest data
+// it's sent to flush. The caller must hold bufmu and the queue must have space.
+func (tb *Buffer) backlogged(sw *swap) bool {
+	if !tb.backlog.check(tb) {
+		return false
+	}
+	if tb.backlog.oldest.Load() == tb.backlog.active.Load() {
+		tb.own()
+		*sw = tb.flush(TriggerTick, nil)
+		tb.flushedBetweenTicks = true
+	}
+	// the synchronous mode writes the backlog before returning from Write
+	return !tb.synchronous
+}
+
+// Backlogged reports if the backlog is older than SetMaxBacklogAge, it's updated when
+// the age is checked
+func (tb *Buffer) Backlogged() bool {
+	return tb.backlog != nil && tb.backlog.exceeded.Load()
+}
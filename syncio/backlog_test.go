@@ -0,0 +1,93 @@
+package syncio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaxBacklogAgeBlock(t *testing.T) {
+	clock := newFakeClock()
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(4), SetClock(clock), SetMaxBacklogAge(time.Second, OverflowBlock))
+
+	tb.Write([]byte("aaaa")) // in flight
+	clock.Advance(2 * time.Second)
+	done := make(chan struct{})
+	go func() {
+		tb.Write([]byte("bbbb"))
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("write not blocked by the backlog")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if !tb.Backlogged() {
+		t.Error("backlog age exceeded not reported")
+	}
+	if s := tb.Stats(); s.BacklogAge != 2*time.Second {
+		t.Errorf("backlog age: %v, expected: %v", s.BacklogAge, 2*time.Second)
+	}
+
+	close(bw.release)
+	<-done
+	tb.Close()
+	if s := bw.out.String(); s != "aaaabbbb" {
+		t.Errorf("written data: %q, expected: %q", s, "aaaabbbb")
+	}
+	if s := tb.Stats(); tb.Backlogged() || s.BacklogAge != 0 || s.BacklogDrops != 0 {
+		t.Errorf("backlogged: %v, stats: %+v", tb.Backlogged(), s)
+	}
+}
+
+func TestMaxBacklogAgeDrop(t *testing.T) {
+	clock := newFakeClock()
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(4), SetClock(clock), SetMaxBacklogAge(time.Second, OverflowDropNewest))
+
+	tb.Write([]byte("aaaa"))
+	clock.Advance(2 * time.Second)
+	if n, err := tb.Write([]byte("bbbb")); n != 4 || err != nil {
+		t.Errorf("dropped write: %v, %v", n, err)
+	}
+	// the fast path is disabled while backlogged
+	tb.Write([]byte("c"))
+	close(bw.release)
+	tb.Close()
+	if s := bw.out.String(); s != "aaaa" {
+		t.Errorf("written data: %q, expected: %q", s, "aaaa")
+	}
+	if s := tb.Stats(); s.BacklogDrops != 2 {
+		t.Errorf("backlog drops: %v, expected: 2", s.BacklogDrops)
+	}
+}
+
+func TestMaxBacklogAgeActiveBuffer(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		clock := newFakeClock()
+		w := &lockedBuffer{}
+		tb := NewBuffer(w, append([]BufferOption{SetBufferSize(8), SetClock(clock), SetMaxBacklogAge(time.Second, OverflowBlock)}, mode...)...)
+
+		tb.Write([]byte("a"))
+		clock.Advance(2 * time.Second)
+		// the old data of the active buffer is flushed before accepting the write
+		tb.Write([]byte("bbbbbbbb"))
+		tb.Write([]byte("c"))
+		if s := tb.Stats(); s.BacklogAge != 0 || tb.Backlogged() {
+			t.Errorf("backlog age after flushing the old data: %v", s.BacklogAge)
+		}
+		tb.Close()
+		if s := w.String(); s != "abbbbbbbbc" {
+			t.Errorf("written data: %q, expected: %q", s, "abbbbbbbbc")
+		}
+	})
+}
+
+func TestMaxBacklogAgePolicy(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("OverflowDropOldest accepted")
+		}
+	}()
+	SetMaxBacklogAge(time.Second, OverflowDropOldest)
+}
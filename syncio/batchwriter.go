@@ -0,0 +1,253 @@
+package syncio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Encoder serializes a batch of records to the underlying writer of a BatchWriter, the records
+// are only valid during the call. The encoders of this package keep their memory between the
+// calls, they must not be shared by several BatchWriters.
+type Encoder interface {
+	Encode(w io.Writer, records [][]byte) error
+}
+
+// EncoderFunc is a function implementing Encoder
+type EncoderFunc func(w io.Writer, records [][]byte) error
+
+func (f EncoderFunc) Encode(w io.Writer, records [][]byte) error {
+	return f(w, records)
+}
+
+// DelimitedEncoder writes the records of a batch with one Write, each one followed by
+// delimiter, e.g. newline delimited JSON
+func DelimitedEncoder(delimiter []byte) Encoder {
+	var buf []byte
+	return EncoderFunc(func(w io.Writer, records [][]byte) error {
+		buf = buf[:0]
+		for _, r := range records {
+			buf = append(append(buf, r...), delimiter...)
+		}
+		return writeAll(w, buf)
+	})
+}
+
+// JSONArrayEncoder writes the records of a batch as the elements of a JSON array with one
+// Write, the records must be JSON values. It fails without writing if one of them isn't.
+func JSONArrayEncoder() Encoder {
+	var buf []byte
+	return EncoderFunc(func(w io.Writer, records [][]byte) error {
+		buf = append(buf[:0], '[')
+		for i, r := range records {
+			if !json.Valid(r) {
+				return fmt.Errorf("batch record %v isn't a JSON value", i)
+			}
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, bytes.TrimSpace(r)...)
+		}
+		return writeAll(w, append(buf, ']'))
+	})
+}
+
+// writeAll writes p with one Write, a short write is io.ErrShortWrite
+func writeAll(w io.Writer, p []byte) error {
+	n, err := w.Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+// BatchOption is an option of NewBatchWriter
+type BatchOption func(*BatchWriter)
+
+// SetBatchRecords sets the number of records of a batch, 500 by default
+func SetBatchRecords(n int) BatchOption {
+	return func(w *BatchWriter) {
+		w.maxRecords = n
+	}
+}
+
+// SetBatchBytes bounds the size of the records of a batch, a record that doesn't fit goes to
+// the next batch and a bigger one is a batch alone. 0, the default, doesn't bound it.
+func SetBatchBytes(n int) BatchOption {
+	return func(w *BatchWriter) {
+		w.maxBytes = n
+	}
+}
+
+// BatchWriter groups the writes in batches by number of records and size, every Write is a
+// record, and writes them with an Encoder from the goroutine completing the batch. It has no
+// ticks, Flush writes an incomplete batch. As the underlying writer of a Buffer with
+// SetRecordMode it receives the records of the flushes with WriteBatch, regrouping them,
+// and with SetSinkFlush every flush writes the incomplete batch; closing the Buffer doesn't
+// close it. A batch that fails is discarded, the error is returned by the call that wrote
+// it. It's safe for concurrent use.
+type BatchWriter struct {
+	w          io.Writer
+	enc        Encoder
+	maxRecords int
+	maxBytes   int
+
+	mu     sync.Mutex
+	closed bool
+	// data are the records of the batch concatenated, ends their end offsets
+	data    []byte
+	ends    []int
+	records [][]byte
+
+	stats BatchStats
+}
+
+// BatchStats are the counters of a BatchWriter
+type BatchStats struct {
+	// Batches is the number of batches written and Records and Bytes their records and size,
+	// before encoding
+	Batches int64
+	Records int64
+	Bytes   int64
+	// Errors is the number of batches failed and discarded
+	Errors int64
+	// Pending is the number of records of the incomplete batch
+	Pending int64
+}
+
+// NewBatchWriter returns a BatchWriter writing the batches to w with enc, a nil enc writes the
+// records concatenated
+func NewBatchWriter(w io.Writer, enc Encoder, options ...BatchOption) *BatchWriter {
+	const defaultBatchRecords = 500
+	bw := &BatchWriter{w: w, enc: enc}
+	for _, o := range options {
+		o(bw)
+	}
+	if bw.maxRecords <= 0 {
+		bw.maxRecords = defaultBatchRecords
+	}
+	if bw.enc == nil {
+		bw.enc = DelimitedEncoder(nil)
+	}
+	return bw
+}
+
+// Write adds p to the batch as a record, the batch is written once complete
+func (w *BatchWriter) Write(p []byte) (int, error) {
+	if w.w == nil {
+		return 0, ErrNotInitialized
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.add(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteBatch adds the records to the batch as RecordWriter, it returns the number of records
+// added before an error
+func (w *BatchWriter) WriteBatch(records [][]byte) (int, error) {
+	if w.w == nil {
+		return 0, ErrNotInitialized
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, r := range records {
+		if err := w.add(r); err != nil {
+			return i, err
+		}
+	}
+	return len(records), nil
+}
+
+// add adds a record writing the batches completed, the caller must hold mu
+func (w *BatchWriter) add(p []byte) error {
+	if w.closed {
+		return ErrWriteOnClosed
+	}
+	if w.maxBytes > 0 && len(w.ends) > 0 && len(w.data)+len(p) > w.maxBytes {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	w.data = append(w.data, p...)
+	w.ends = append(w.ends, len(w.data))
+	atomic.StoreInt64(&w.stats.Pending, int64(len(w.ends)))
+	if len(w.ends) >= w.maxRecords || w.maxBytes > 0 && len(w.data) >= w.maxBytes {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush encodes the batch, the caller must hold mu
+func (w *BatchWriter) flush() error {
+	if len(w.ends) == 0 {
+		return nil
+	}
+	records, start := w.records[:0], 0
+	for _, end := range w.ends {
+		records = append(records, w.data[start:end:end])
+		start = end
+	}
+	err := w.enc.Encode(w.w, records)
+	if err != nil {
+		atomic.AddInt64(&w.stats.Errors, 1)
+	} else {
+		atomic.AddInt64(&w.stats.Batches, 1)
+		atomic.AddInt64(&w.stats.Records, int64(len(records)))
+		atomic.AddInt64(&w.stats.Bytes, int64(len(w.data)))
+	}
+	for i := range records {
+		records[i] = nil
+	}
+	w.records = records
+	w.data, w.ends = w.data[:0], w.ends[:0]
+	atomic.StoreInt64(&w.stats.Pending, 0)
+	return err
+}
+
+// Flush writes the incomplete batch
+func (w *BatchWriter) Flush() error {
+	if w.w == nil {
+		return ErrNotInitialized
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+// Stats returns a copy of the batch counters
+func (w *BatchWriter) Stats() BatchStats {
+	return BatchStats{
+		Batches: atomic.LoadInt64(&w.stats.Batches),
+		Records: atomic.LoadInt64(&w.stats.Records),
+		Bytes:   atomic.LoadInt64(&w.stats.Bytes),
+		Errors:  atomic.LoadInt64(&w.stats.Errors),
+		Pending: atomic.LoadInt64(&w.stats.Pending),
+	}
+}
+
+// Close writes the incomplete batch and closes the underlying writer if it's an io.Closer,
+// the writes fail with ErrWriteOnClosed from now on. Calling it again does nothing.
+func (w *BatchWriter) Close() error {
+	if w.w == nil {
+		return ErrNotInitialized
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := w.flush()
+	if c, ok := w.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
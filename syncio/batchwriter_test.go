@@ -0,0 +1,112 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBatchWriter(t *testing.T) {
+	var out recordingWriter
+	w := NewBatchWriter(&out, DelimitedEncoder([]byte("\n")), SetBatchRecords(3))
+	for _, r := range []string{"a", "b", "c", "d", "e"} {
+		if n, err := w.Write([]byte(r)); n != 1 || err != nil {
+			t.Fatalf("write: %v, %v", n, err)
+		}
+	}
+	if s := w.Stats(); s.Batches != 1 || s.Records != 3 || s.Pending != 2 {
+		t.Errorf("stats: %+v, expected a batch of 3 records and 2 pending", s)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"a\nb\nc\n", "d\ne\n"}
+	if len(out.writes) != len(expected) {
+		t.Fatalf("writes: %q, expected: %q", out.writes, expected)
+	}
+	for i, e := range expected {
+		if string(out.writes[i]) != e {
+			t.Errorf("write %v: %q, expected: %q", i, out.writes[i], e)
+		}
+	}
+	if _, err := w.Write([]byte("f")); err != ErrWriteOnClosed {
+		t.Errorf("write after close: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+}
+
+func TestBatchWriterBytes(t *testing.T) {
+	var out recordingWriter
+	w := NewBatchWriter(&out, nil, SetBatchBytes(8))
+	for _, r := range []string{"abc", "def", "ghi", strings.Repeat("x", 10), "jk"} {
+		w.Write([]byte(r))
+	}
+	w.Flush()
+	// the record that doesn't fit goes to the next batch, a bigger one is alone
+	expected := []string{"abcdef", "ghi", strings.Repeat("x", 10), "jk"}
+	if len(out.writes) != len(expected) {
+		t.Fatalf("writes: %q, expected: %q", out.writes, expected)
+	}
+	for i, e := range expected {
+		if string(out.writes[i]) != e {
+			t.Errorf("write %v: %q, expected: %q", i, out.writes[i], e)
+		}
+	}
+}
+
+func TestBatchWriterJSON(t *testing.T) {
+	var out bytes.Buffer
+	w := NewBatchWriter(&out, JSONArrayEncoder(), SetBatchRecords(2))
+	w.Write([]byte(`{"a":1}`))
+	w.Write([]byte(` {"b":2} `))
+	if out.String() != `[{"a":1},{"b":2}]` {
+		t.Errorf("encoded: %s", out.String())
+	}
+	w.Write([]byte(`{"c":`))
+	if err := w.Flush(); err == nil {
+		t.Error("flush of an invalid record: nil, expected error")
+	}
+	if s := w.Stats(); s.Errors != 1 || s.Pending != 0 {
+		t.Errorf("stats: %+v, expected the batch discarded", s)
+	}
+}
+
+func TestBatchWriterBuffer(t *testing.T) {
+	errSink := errors.New("sink failed")
+	var out recordingWriter
+	bw := NewBatchWriter(&out, DelimitedEncoder([]byte("\n")), SetBatchRecords(4))
+	tb := NewBuffer(bw, SetRecordMode(true), SetBufferSize(16))
+	for i := 0; i < 10; i++ {
+		tb.Write([]byte{'a' + byte(i)})
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s := bw.Stats(); s.Records != 8 || s.Pending != 2 {
+		t.Errorf("stats: %+v, expected 8 records and 2 pending", s)
+	}
+	// closing the Buffer doesn't close its underlying writer
+	bw.Close()
+	if s := bw.Stats(); s.Records != 10 || s.Batches != 3 {
+		t.Errorf("stats: %+v, expected 10 records in 3 batches", s)
+	}
+
+	failing := NewBatchWriter(writerFunc(func(p []byte) (int, error) { return 0, errSink }), nil, SetBatchRecords(2))
+	n, err := failing.WriteBatch([][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	if n != 1 || err != errSink {
+		t.Errorf("failed batch: %v, %v, expected: 1, %v", n, err, errSink)
+	}
+}
+
+func TestBatchWriterZeroValue(t *testing.T) {
+	var w BatchWriter
+	if _, err := w.Write([]byte("a")); err != ErrNotInitialized {
+		t.Errorf("write: %v, expected: %v", err, ErrNotInitialized)
+	}
+	if err := w.Close(); err != ErrNotInitialized {
+		t.Errorf("close: %v, expected: %v", err, ErrNotInitialized)
+	}
+	var _ io.WriteCloser = &w
+	var _ RecordWriter = &w
+}
@@ -0,0 +1,177 @@
+package syncio
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WorkloadSpec describes the synthetic workload replayed by Benchmark
+type WorkloadSpec struct {
+	// the write sizes are uniformly distributed between MinWriteSize and MaxWriteSize
+	MinWriteSize int
+	MaxWriteSize int
+	// Concurrency is the number of goroutines writing, 1 by default
+	Concurrency int
+	// Duration is the time writing to each candidate, 1s by default
+	Duration time.Duration
+	// Seed makes the write sizes and contents reproducible
+	Seed int64
+}
+
+// Config is a candidate configuration for Benchmark, the zero values use the Buffer defaults
+type Config struct {
+	Name          string
+	BufferSize    int
+	PoolSize      int
+	FlushInterval time.Duration
+	// Options are applied after the other fields
+	Options []BufferOption
+}
+
+func (c Config) options() []BufferOption {
+	var opts []BufferOption
+	if c.BufferSize > 0 {
+		opts = append(opts, SetBufferSize(c.BufferSize))
+	}
+	if c.PoolSize > 0 {
+		opts = append(opts, SetBufferPoolSize(c.PoolSize))
+	}
+	if c.FlushInterval > 0 {
+		opts = append(opts, SetFlushInterval(c.FlushInterval))
+	}
+	return append(opts, c.Options...)
+}
+
+// Result is the measure of a candidate configuration
+type Result struct {
+	Config Config
+	// Writes and Bytes are the data written to the Buffer
+	Writes int64
+	Bytes  int64
+	// Elapsed includes the Close of the Buffer, Throughput is Bytes per second
+	Elapsed    time.Duration
+	Throughput float64
+	// P99Latency is the 99th percentile of the Write latency, measured on a sample of the writes
+	P99Latency time.Duration
+	// PeakMemory is the maximum heap growth observed while writing, it's sampled
+	PeakMemory uint64
+	// Stats of the Buffer after closing it
+	Stats Stats
+}
+
+// latencySamples is the number of latencies sampled by every writer goroutine
+const latencySamples = 4096
+
+// Benchmark replays the workload against w with every candidate configuration, it's meant to
+// tune the options for a sink. The candidates run one after the other with the same sequence of
+// writes. It returns the results measured until ctx is done and ctx.Err().
+func Benchmark(ctx context.Context, w io.Writer, workload WorkloadSpec, candidates []Config) ([]Result, error) {
+	if workload.MinWriteSize < 1 {
+		workload.MinWriteSize = 1
+	}
+	if workload.MaxWriteSize < workload.MinWriteSize {
+		workload.MaxWriteSize = workload.MinWriteSize
+	}
+	if workload.Concurrency < 1 {
+		workload.Concurrency = 1
+	}
+	if workload.Duration <= 0 {
+		workload.Duration = time.Second
+	}
+	data := make([]byte, workload.MaxWriteSize)
+	rand.New(rand.NewSource(workload.Seed)).Read(data)
+
+	results := make([]Result, 0, len(candidates))
+	for _, c := range candidates {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		results = append(results, benchmarkConfig(ctx, w, workload, data, c))
+	}
+	return results, ctx.Err()
+}
+
+func benchmarkConfig(ctx context.Context, w io.Writer, workload WorkloadSpec, data []byte, c Config) Result {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	base := ms.HeapAlloc
+
+	ctx, cancel := context.WithTimeout(ctx, workload.Duration)
+	defer cancel()
+	tb := NewBuffer(w, c.options()...)
+
+	// the memory is sampled until the writers finish
+	peak := make(chan uint64)
+	go func() {
+		var max uint64
+		t := time.NewTicker(10 * time.Millisecond)
+		defer t.Stop()
+		for {
+			var ms runtime.MemStats
+			runtime.ReadMemStats(&ms)
+			if ms.HeapAlloc > base && ms.HeapAlloc-base > max {
+				max = ms.HeapAlloc - base
+			}
+			select {
+			case <-ctx.Done():
+				peak <- max
+				return
+			case <-t.C:
+			}
+		}
+	}()
+
+	res := Result{Config: c}
+	var mu sync.Mutex
+	var samples []time.Duration
+	wg := sync.WaitGroup{}
+	start := time.Now()
+	for i := 0; i < workload.Concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(workload.Seed + int64(i)))
+			sizes := workload.MaxWriteSize - workload.MinWriteSize + 1
+			var writes, bytes int64
+			sample := make([]time.Duration, 0, latencySamples)
+			for ctx.Err() == nil {
+				n := workload.MinWriteSize + rnd.Intn(sizes)
+				t := time.Now()
+				tb.Write(data[:n])
+				latency := time.Since(t)
+				writes++
+				bytes += int64(n)
+				// reservoir sampling
+				if len(sample) < latencySamples {
+					sample = append(sample, latency)
+				} else if j := rnd.Int63n(writes); j < latencySamples {
+					sample[j] = latency
+				}
+			}
+			mu.Lock()
+			res.Writes += writes
+			res.Bytes += bytes
+			samples = append(samples, sample...)
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	tb.Close()
+	res.Elapsed = time.Since(start)
+	cancel()
+
+	res.PeakMemory = <-peak
+	res.Throughput = float64(res.Bytes) / res.Elapsed.Seconds()
+	if len(samples) > 0 {
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		res.P99Latency = samples[len(samples)*99/100]
+	}
+	res.Stats = tb.Stats()
+	return res
+}
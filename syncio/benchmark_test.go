@@ -0,0 +1,39 @@
+package syncio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+func TestBenchmarkHarness(t *testing.T) {
+	workload := WorkloadSpec{MinWriteSize: 16, MaxWriteSize: 256, Concurrency: 2, Duration: 50 * time.Millisecond, Seed: 1}
+	candidates := []Config{
+		{Name: "small", BufferSize: 1024},
+		{Name: "big", BufferSize: 64 * 1024, PoolSize: 4, FlushInterval: 10 * time.Millisecond},
+	}
+	results, err := Benchmark(context.Background(), &synctest.SlowWriter{Delay: time.Millisecond}, workload, candidates)
+	if err != nil {
+		t.Fatalf("benchmark: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results: %v, expected: 2", len(results))
+	}
+	for _, r := range results {
+		t.Logf("%v: %.0f B/s, p99 %v, peak memory %v, flushes %v", r.Config.Name, r.Throughput, r.P99Latency, r.PeakMemory, r.Stats.Flushes)
+		if r.Writes == 0 || r.Bytes < 16*r.Writes || r.Throughput <= 0 || r.P99Latency <= 0 || r.Stats.Flushes == 0 {
+			t.Errorf("%v: unexpected result: %+v", r.Config.Name, r)
+		}
+	}
+}
+
+func TestBenchmarkHarnessCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := Benchmark(ctx, &testWriter{}, WorkloadSpec{}, []Config{{Name: "default"}})
+	if err != context.Canceled || len(results) != 0 {
+		t.Errorf("benchmark: %v results, %v, expected: 0, %v", len(results), err, context.Canceled)
+	}
+}
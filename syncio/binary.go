@@ -0,0 +1,69 @@
+package syncio
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+var _ io.ByteWriter = &Buffer{}
+
+// WriteByte writes a single byte, it implements io.ByteWriter
+func (tb *Buffer) WriteByte(c byte) error {
+	b := [1]byte{c}
+	_, err := tb.Write(b[:])
+	return err
+}
+
+// WriteUint16 writes v encoded with order as a single Write
+func (tb *Buffer) WriteUint16(v uint16, order binary.ByteOrder) error {
+	var b [2]byte
+	switch order {
+	case binary.BigEndian:
+		binary.BigEndian.PutUint16(b[:], v)
+	case binary.LittleEndian:
+		binary.LittleEndian.PutUint16(b[:], v)
+	default:
+		return tb.writeOrder(2, func(p []byte) { order.PutUint16(p, v) })
+	}
+	_, err := tb.Write(b[:])
+	return err
+}
+
+// WriteUint32 writes v encoded with order as a single Write
+func (tb *Buffer) WriteUint32(v uint32, order binary.ByteOrder) error {
+	var b [4]byte
+	switch order {
+	case binary.BigEndian:
+		binary.BigEndian.PutUint32(b[:], v)
+	case binary.LittleEndian:
+		binary.LittleEndian.PutUint32(b[:], v)
+	default:
+		return tb.writeOrder(4, func(p []byte) { order.PutUint32(p, v) })
+	}
+	_, err := tb.Write(b[:])
+	return err
+}
+
+// WriteUint64 writes v encoded with order as a single Write
+func (tb *Buffer) WriteUint64(v uint64, order binary.ByteOrder) error {
+	var b [8]byte
+	switch order {
+	case binary.BigEndian:
+		binary.BigEndian.PutUint64(b[:], v)
+	case binary.LittleEndian:
+		binary.LittleEndian.PutUint64(b[:], v)
+	default:
+		return tb.writeOrder(8, func(p []byte) { order.PutUint64(p, v) })
+	}
+	_, err := tb.Write(b[:])
+	return err
+}
+
+// writeOrder writes n bytes encoded by put, the slice escapes through the ByteOrder
+// interface so the standard byte orders avoid this path
+func (tb *Buffer) writeOrder(n int, put func([]byte)) error {
+	p := make([]byte, n)
+	put(p)
+	_, err := tb.Write(p)
+	return err
+}
@@ -0,0 +1,45 @@
+package syncio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestWriteBinary(t *testing.T) {
+	var out bytes.Buffer
+	tb := NewBuffer(&out)
+	tb.WriteByte(1)
+	tb.WriteUint16(0x0203, binary.BigEndian)
+	tb.WriteUint32(0x07060504, binary.LittleEndian)
+	tb.WriteUint64(0x08090a0b0c0d0e0f, binary.BigEndian)
+	tb.Close()
+
+	expected := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	if !bytes.Equal(out.Bytes(), expected) {
+		t.Errorf("written: %v, expected: %v", out.Bytes(), expected)
+	}
+}
+
+func TestWriteBinaryAllocs(t *testing.T) {
+	tb := NewBuffer(&testWriter{}, SetBufferSize(1<<20))
+	defer tb.Close()
+	n := testing.AllocsPerRun(1000, func() {
+		tb.WriteByte(1)
+		tb.WriteUint16(1, binary.BigEndian)
+		tb.WriteUint32(1, binary.LittleEndian)
+		tb.WriteUint64(1, binary.BigEndian)
+	})
+	if n != 0 {
+		t.Errorf("allocs: %v, expected: 0", n)
+	}
+}
+
+func BenchmarkWriteUint64(b *testing.B) {
+	tb := NewBuffer(&testWriter{}, SetBufferSize(64*1024))
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		tb.WriteUint64(uint64(n), binary.BigEndian)
+	}
+	tb.Close()
+}
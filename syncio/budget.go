@@ -0,0 +1,234 @@
+package syncio
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Budget caps the memory of the buffer pools of the Buffers sharing it with SetBudget, e.g. one
+// Buffer per tenant. The writes of a Buffer holding more than its share, the limit divided by
+// the Buffers and at least two buffers, wait while the total is over the limit; the memory is
+// released as their batches are written. Going over the limit drops the free buffers of the
+// pools first. A Buffer within its share never waits, and the waits aren't served in order:
+// the writes of a Buffer go on once it's back within its share or the total under the limit,
+// even with the writes of other Buffers still waiting.
+type Budget struct {
+	limit int64
+	used  atomic.Int64
+
+	mu      sync.Mutex
+	members map[*Buffer]struct{}
+	waiters []*budgetWaiter
+
+	waits    atomic.Int64
+	waitTime atomic.Int64
+}
+
+// budgetWaiter is a write waiting for memory, c is closed once it can go on
+type budgetWaiter struct {
+	tb *Buffer
+	c  chan struct{}
+}
+
+// BudgetStats are the counters of a Budget
+type BudgetStats struct {
+	// Limit is the memory limit and Used the memory of the pools of the Buffers
+	Limit int64
+	Used  int64
+	// Buffers is the number of Buffers sharing the budget and Waiting the writes waiting
+	Buffers int
+	Waiting int
+	// Waits is the number of writes that waited and WaitTime the total time waiting
+	Waits    int64
+	WaitTime time.Duration
+}
+
+// NewBudget returns a Budget of limit bytes
+func NewBudget(limit int64) *Budget {
+	return &Budget{limit: limit, members: map[*Buffer]struct{}{}}
+}
+
+// SetBudget accounts the memory of the buffer pool in b, the time waiting for it is reported in
+// Stats.BudgetWait. The pool memory is allocated as with SetAllocator: the buffers dropped are
+// released to b, and the ones of the pool once the Buffer is closed.
+func SetBudget(b *Budget) BufferOption {
+	return func(tb *Buffer) {
+		tb.budget = b
+	}
+}
+
+// Stats returns the budget counters
+func (b *Budget) Stats() BudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BudgetStats{
+		Limit:    b.limit,
+		Used:     b.used.Load(),
+		Buffers:  len(b.members),
+		Waiting:  len(b.waiters),
+		Waits:    b.waits.Load(),
+		WaitTime: time.Duration(b.waitTime.Load()),
+	}
+}
+
+// attach adds tb to the budget, once the Buffer is closed detach removes it
+func (b *Budget) attach(tb *Buffer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.members[tb] = struct{}{}
+	tb.budgetMin = 2 * int64(tb.bufSize)
+}
+
+// detach removes tb releasing its memory still accounted, e.g. the buffers of the batches not
+// yet written by a write timed out
+func (b *Budget) detach(tb *Buffer) {
+	b.mu.Lock()
+	if _, ok := b.members[tb]; !ok {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.members, tb)
+	b.used.Add(-tb.budgetUsed.Swap(0))
+	b.wake()
+	b.mu.Unlock()
+}
+
+// charge accounts n bytes allocated by tb, the free buffers of the pools are dropped when it
+// goes over the limit
+func (b *Budget) charge(tb *Buffer, n int) {
+	b.mu.Lock()
+	if _, ok := b.members[tb]; !ok {
+		b.mu.Unlock()
+		return
+	}
+	tb.budgetUsed.Add(int64(n))
+	over := b.used.Add(int64(n)) > b.limit
+	b.mu.Unlock()
+	if over {
+		b.reclaim()
+	}
+}
+
+// refund releases n bytes of tb
+func (b *Budget) refund(tb *Buffer, n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.members[tb]; !ok {
+		return
+	}
+	tb.budgetUsed.Add(-int64(n))
+	b.used.Add(-int64(n))
+	b.wake()
+}
+
+// over reports if the memory is over the limit
+func (b *Budget) over() bool {
+	return b.used.Load() > b.limit
+}
+
+// reclaim drops the free buffers of the pools, the caller must not hold mu
+func (b *Budget) reclaim() {
+	b.mu.Lock()
+	pools := make([]*Buffer, 0, len(b.members))
+	for tb := range b.members {
+		pools = append(pools, tb)
+	}
+	b.mu.Unlock()
+	for _, tb := range pools {
+		tb.pool.Trim()
+	}
+}
+
+// blocked reports if the writes of tb must wait, the caller must hold mu
+func (b *Budget) blocked(tb *Buffer) bool {
+	if !b.over() {
+		return false
+	}
+	share := b.limit
+	if n := int64(len(b.members)); n > 0 {
+		share /= n
+	}
+	if share < tb.budgetMin {
+		share = tb.budgetMin
+	}
+	return tb.budgetUsed.Load() > share
+}
+
+// wake lets go on the waiters not blocked anymore, the caller must hold mu
+func (b *Budget) wake() {
+	waiters := b.waiters[:0]
+	for _, w := range b.waiters {
+		if _, ok := b.members[w.tb]; ok && b.blocked(w.tb) {
+			waiters = append(waiters, w)
+			continue
+		}
+		close(w.c)
+	}
+	for i := len(waiters); i < len(b.waiters); i++ {
+		b.waiters[i] = nil
+	}
+	b.waiters = waiters
+}
+
+// wait waits until the writes of tb can go on, a non nil ctx aborts the wait
+func (b *Budget) wait(ctx context.Context, tb *Buffer) error {
+	if !b.over() {
+		return nil
+	}
+	b.reclaim()
+	b.mu.Lock()
+	if !b.blocked(tb) {
+		b.mu.Unlock()
+		return nil
+	}
+	w := &budgetWaiter{tb: tb, c: make(chan struct{})}
+	b.waiters = append(b.waiters, w)
+	b.mu.Unlock()
+
+	start := tb.clock.Now()
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+	var err error
+	select {
+	case <-w.c:
+	case <-done:
+		err = ctx.Err()
+		b.mu.Lock()
+		for i, o := range b.waiters {
+			if o == w {
+				b.waiters = append(b.waiters[:i], b.waiters[i+1:]...)
+				break
+			}
+		}
+		b.mu.Unlock()
+	}
+	d := int64(tb.clock.Now().Sub(start))
+	b.waits.Add(1)
+	b.waitTime.Add(d)
+	atomic.AddInt64((*int64)(&tb.stats.BudgetWait), d)
+	return err
+}
+
+// budgetPool returns the allocator and free of the pool accounting the memory in the budget
+func (tb *Buffer) budgetPool() (func(size int) []byte, func([]byte)) {
+	alloc, free := tb.alloc, tb.free
+	return func(size int) []byte {
+			var p []byte
+			if alloc != nil {
+				p = alloc(size)
+			} else {
+				p = make([]byte, size)
+			}
+			tb.budget.charge(tb, cap(p))
+			return p
+		}, func(p []byte) {
+			tb.budget.refund(tb, cap(p))
+			if free != nil {
+				free(p)
+			}
+		}
+}
@@ -0,0 +1,166 @@
+package syncio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitBudget waits until a write of the budget is waiting
+func waitBudget(t *testing.T, b *Budget) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); b.Stats().Waiting == 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("no write waiting: %+v", b.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	budget := NewBudget(16)
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(16), SetBudget(budget))
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for i := 0; i < 10; i++ {
+			tb.Write([]byte("aaaa"))
+		}
+	}()
+	waitBudget(t, budget)
+	if s := budget.Stats(); s.Used > 16+4 || s.Buffers != 1 {
+		t.Errorf("used: %v, buffers: %v, expected at most 20 used by 1 buffer", s.Used, s.Buffers)
+	}
+
+	// a Buffer within its share doesn't wait
+	var out lockedBuffer
+	other := NewBuffer(&out, SetBufferSize(4), SetBudget(budget))
+	other.Write([]byte("bbbbbbbb"))
+	if err := other.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "bbbbbbbb" {
+		t.Errorf("written: %q, expected: %q", out.String(), "bbbbbbbb")
+	}
+
+	close(bw.release)
+	<-written
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s := bw.out.String(); s != strings.Repeat("aaaa", 10) {
+		t.Errorf("written: %q", s)
+	}
+	s := budget.Stats()
+	if s.Used != 0 || s.Buffers != 0 || s.Waiting != 0 {
+		t.Errorf("after close: %+v, expected nothing used", s)
+	}
+	if s.Waits == 0 || s.WaitTime <= 0 {
+		t.Errorf("waits: %v, wait time: %v, expected waits", s.Waits, s.WaitTime)
+	}
+	if d := tb.Stats().BudgetWait; d <= 0 || d > s.WaitTime {
+		t.Errorf("budget wait: %v, expected at most %v", d, s.WaitTime)
+	}
+}
+
+func TestBudgetWaitersOrder(t *testing.T) {
+	// the writes of a Buffer back within its share don't wait for the older waiters
+	budget := NewBudget(16)
+	bwA := &blockingWriter{release: make(chan struct{})}
+	bwB := &blockingWriter{release: make(chan struct{})}
+	a := NewBuffer(bwA, SetBufferSize(4), SetBufferPoolSize(16), SetBudget(budget))
+	b := NewBuffer(bwB, SetBufferSize(4), SetBufferPoolSize(16), SetBudget(budget))
+	write := func(tb *Buffer, p string) chan struct{} {
+		written := make(chan struct{})
+		go func() {
+			defer close(written)
+			for i := 0; i < 10; i++ {
+				tb.Write([]byte(p))
+			}
+		}()
+		return written
+	}
+
+	writtenA := write(a, "aaaa")
+	waitBudget(t, budget)
+	writtenB := write(b, "bbbb")
+	for deadline := time.Now().Add(time.Second); budget.Stats().Waiting < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("writes waiting: %+v, expected 2", budget.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(bwB.release)
+	select {
+	case <-writtenB:
+	case <-time.After(time.Second):
+		t.Fatal("writes of the second Buffer waiting for the first one")
+	}
+	if s := budget.Stats(); s.Waiting != 1 {
+		t.Errorf("writes waiting: %v, expected 1", s.Waiting)
+	}
+
+	close(bwA.release)
+	<-writtenA
+	if err := errors.Join(a.Close(), b.Close()); err != nil {
+		t.Fatal(err)
+	}
+	if sa, sb := bwA.out.String(), bwB.out.String(); sa != strings.Repeat("aaaa", 10) || sb != strings.Repeat("bbbb", 10) {
+		t.Errorf("written: %q and %q", sa, sb)
+	}
+}
+
+func TestBudgetContext(t *testing.T) {
+	budget := NewBudget(8)
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(16), SetBudget(budget))
+	defer tb.Close()
+	defer close(bw.release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	res := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := tb.WriteContext(ctx, []byte("aaaa")); err != nil {
+				res <- err
+				return
+			}
+		}
+	}()
+	waitBudget(t, budget)
+	cancel()
+	if err := <-res; !errors.Is(err, context.Canceled) {
+		t.Errorf("write error: %v, expected: %v", err, context.Canceled)
+	}
+	if s := budget.Stats(); s.Waiting != 0 || s.Waits != 1 {
+		t.Errorf("waiting: %v, waits: %v, expected 0 and 1", s.Waiting, s.Waits)
+	}
+}
+
+func TestBudgetAllocator(t *testing.T) {
+	budget := NewBudget(1 << 20)
+	var allocs, frees int
+	alloc := func(size int) []byte {
+		allocs++
+		return make([]byte, size)
+	}
+	free := func([]byte) { frees++ }
+	var out bytes.Buffer
+	tb := NewBuffer(&out, SetBufferSize(4), SetSynchronousMode(true), SetAllocator(alloc, free), SetBudget(budget))
+	tb.Write([]byte("aaaabbbbcccc"))
+	if s := budget.Stats(); s.Used != int64(allocs)*4 {
+		t.Errorf("used: %v, expected: %v", s.Used, allocs*4)
+	}
+	tb.Close()
+	if allocs == 0 || frees != allocs {
+		t.Errorf("allocs: %v, frees: %v, expected the same", allocs, frees)
+	}
+	if s := budget.Stats(); s.Used != 0 {
+		t.Errorf("used after close: %v, expected: 0", s.Used)
+	}
+}
@@ -1,8 +1,12 @@
 package syncio
 
 import (
+	"context"
 	"errors"
 	"io"
+	"net"
+	"runtime"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,9 +19,16 @@ var _ io.WriteCloser = &Buffer{}
 // Buffer is a buffer which implements io.WriteCloser methods where writes can be done
 // concurrently, the writes store the data in a buffer thats its later flushed to an underlying writer
 // when its full or ticks.
-// The underlying writer might receive concurrent writes.
+// The underlying writer receives the data in order from a single goroutine, so it won't
+// receive concurrent writes.
 // Closing blocks the caller until all writes finish.
+// Close can be called any number of times from any goroutine and Stats at any point of the
+// lifecycle. The zero value isn't usable, its methods fail with ErrNotInitialized and its
+// accessors return zero values, see NewBuffer.
 type Buffer struct {
+	// first field to keep the 64-bit counters aligned for atomic access on 32-bit platforms
+	stats Stats
+
 	bufmu sync.Mutex
 	buf   *internal.Buffer
 	// buffer write operations to the underlaying writer are potentially slow, a new buffer
@@ -28,21 +39,264 @@ type Buffer struct {
 	bufSize       int
 	poolSize      int
 	flushInterval time.Duration
+	onFlushError  func(*FlushError, []byte)
+	deadLetter    io.Writer
+	sem           *Semaphore
+	closePolicy   ClosePolicy
+	panicPolicy   PanicPolicy
+	maxFlushBytes int
+	flushContext  func() context.Context
+	sinkFlush     bool
+	transform     func(dst, src []byte) ([]byte, error)
+	compressor    Compressor
+	codecName     string
+	reporter      *statsReporter
+	// header is written before the first batch of every writer when headerPending
+	header        func() []byte
+	headerPending bool
+	retention     *retention
+	head          *headRetention
+	// errs is the channel of Errors, see errchan.go
+	errs errorChannel
+	// utf8Boundaries is only enabled without recordMode
+	utf8Boundaries bool
+	// parent is set when the underlying writer is a Buffer, see hierarchy.go
+	parent   *Buffer
+	logger   func(event string, fields map[string]any)
+	clock    Clock
+	adaptive *adaptiveSizing
+	// adaptiveInterval starts the ticker even without flushInterval
+	adaptiveInterval *adaptiveInterval
+	backlog          *backlog
+	writeSizes       bool
+	// scheduler dispatches the ticks instead of the ticker goroutine, see scheduler.go
+	scheduler *FlushScheduler
+	// flushAlignment aligns the first tick, see align.go
+	flushAlignment time.Duration
+	// journal records the operations, see SetJournal
+	journal *journal
+	// wal persists the writes until they are flushed, see SetWriteAheadLog
+	wal    WriteAheadLog
+	walBuf []byte
+	// walOff is the log offset of the active buffer data, walCommits the ranges that left
+	walOff     int64
+	walCommits walCommits
+	// readerClosed reports that the Reader of NewPair is closed, see closedErr
+	readerClosed atomic.Bool
+	// framing writes the batches as frames, see SetFraming
+	framing *framing
+	// alloc and free are the allocator of the pool memory, see SetAllocator
+	alloc func(size int) []byte
+	free  func([]byte)
+	// budget accounts the pool memory in budgetUsed, budgetMin is the minimum share of the
+	// Buffer, see SetBudget
+	budget     *Budget
+	budgetUsed atomic.Int64
+	budgetMin  int64
+	// sinkPending holds the batches until the writer is provided, it's guarded by bufmu, see
+	// lazysink.go
+	sinkPending bool
+	lazySink    *lazySink
+	// maxErrorQueue bounds the payloads of onFlushError, errorQueued is the size given since
+	// the last successful flush, it's used by the flush goroutine
+	maxErrorQueue int64
+	errorQueued   int64
+	// producers are the open PipeWriter handles, see producer.go
+	producers           atomic.Int64
+	closeOnLastProducer bool
+	// handles are the counters of the WriterHandle handles, see handle.go
+	handles atomic.Pointer[handles]
 
 	// control flag to not flush per tick if a flush is
 	// already done by full buffer
 	flushedBetweenTicks bool
+	// childBytes is the data of the active buffer copied from child Buffers, see hierarchy.go
+	childBytes int
 
 	closed bool
-	// wg is a group of writes in progress
-	wg sync.WaitGroup
+	// classes is the data of SetPriorityClasses, see classes.go
+	classes *classes
+	// flushPolicy decides the flushes besides the full buffer, see flushpolicy.go
+	flushPolicy *flushPolicy
+	// retry is the policy of SetRetryPolicy, see retry.go
+	retry *retryPolicy
+	// overflow is the policy of the writes with a full queue, see overflow.go
+	overflow OverflowPolicy
+	// maxLatency is the age limit of the active buffer data, see latency.go
+	maxLatency time.Duration
+	// idleTimeout and onIdle are the idle detection, see idleflush.go
+	idleTimeout time.Duration
+	onIdle      func()
+	// writeTimeout bounds the sink writes, see writetimeout.go
+	writeTimeout time.Duration
+	sinkWedged   atomic.Bool
+	// beforeFlush and afterFlush are the flush hooks, see flushhooks.go
+	beforeFlush func(n int)
+	afterFlush  func(n int, d time.Duration, err error)
+	// spill is the part of the queue on disk, see spilldir.go
+	spill *spillQueue
+	// flushConcurrency and unordered are the settings of the flush workers, see flushworkers.go
+	flushConcurrency int
+	unordered        bool
+	workers          *flushWorkers
+	// dedup and dedupMaxAge are the settings of SetDedupWindow, see dedupwindow.go
+	dedup       *dedupWindow
+	dedupMaxAge time.Duration
+	// writesClosed is set by CloseWrites and Close under bufmu, see closewrites.go
+	writesClosed atomic.Bool
+	// replaying holds the writers until Replay finishes, see replay.go
+	replaying bool
+	// queue holds the batches waiting to be written by the flush goroutine in order, it's
+	// guarded by bufmu. Writers wait for space when it holds poolSize batches, and the
+	// flush goroutine waits for batches when it's empty.
+	queue []*batch
+	space *sync.Cond
+	ready *sync.Cond
+	// stop ends the ticker goroutine
+	stop chan struct{}
+	// done is closed when the flush goroutine has written all batches
+	done chan struct{}
 
-	stats Stats
+	// recordMode keeps the boundaries of the writes, records is the scratch slice used
+	// by the flush goroutine to deliver them
+	recordMode bool
+	records    [][]byte
+	// maxSinkWrite splits the sink writes, ends is the scratch slice of the record ends,
+	// see sinksize.go
+	maxSinkWrite int
+	ends         []int
+
+	// group and merged are the scratch memory of the flush goroutine for SetMaxFlushBytes
+	group  []*batch
+	merged []byte
+	// vectored writes the groups without merging them, vec, vecBufs and vecw are its scratch
+	// memory, see vectored.go
+	vectored bool
+	vec      [][]byte
+	vecBufs  []*internal.Buffer
+	vecw     net.Buffers
+	// readerFrom is the io.ReaderFrom of the underlying writer the batches are written with
+	readerFrom io.ReaderFrom
+	// flushed is the size of the stream sent to flush, it's used by the flush goroutine
+	flushed int64
+	// pending is the first flush error since the last barrier, it's used by the flush goroutine
+	pending *FlushError
+	// rates are the samples of the Stats rates, see rates.go
+	rates rates
+	// sinkSince is the clock time in nanoseconds of the sink write in progress, 0 if none,
+	// and sinkBytes its size, see InFlightFlush
+	sinkSince atomic.Int64
+	sinkBytes atomic.Int64
+	// failing reports if the last flush failed, see multibuffer.go
+	failing atomic.Bool
+	// reserved is the space handed out by Reserve while reserving, see reserve.go
+	reserved  reservation
+	reserving atomic.Bool
+	// sinkCtx is the context of the sink write in flight and sinkCancel its cancel, see
+	// interrupt.go
+	sinkCtx    context.Context
+	sinkCancel sinkCancel
+	// handout is the end of the memory of the data being flushed, see ErrSelfWrite
+	handout atomic.Pointer[byte]
+	// flushWait signals the writes done, see WaitForFlushes
+	flushWait flushWait
+
+	// synchronous replaces the flush goroutine by inlinemu, see synchronous.go
+	synchronous bool
+	manualTick  bool
+	inlinemu    sync.Mutex
+	inlineDone  bool
+
+	// singleWriter enables the Write fast path, state is the owner of the active buffer
+	singleWriter bool
+	state        int32
+
+	// fastWrites enables the lock free path for small writes, see fastpath.go
+	fastWrites bool
+	// features are the optional features of the Write path, the default configuration has
+	// none and takes a single branch on them
+	features  uint32
+	cursor    atomic.Uint64
+	cursorEnd atomic.Int64
+
+	// acct is only enabled with the syncio_debug build tag
+	acct accounting
+}
+
+// owners of the active buffer with SetSingleWriter
+const (
+	stateFree   int32 = iota // nobody is using the active buffer
+	stateWriter              // the Write fast path is using it
+	stateLocked              // a goroutine holding bufmu is using it
+	stateClosed              // the Buffer is closed, bufmu is enough to use it
+)
+
+// batch is a unit of work for the flush goroutine, it holds either a pooled buffer
+// or a slice of an oversized write. A batch without data is used as a barrier to
+// wait until the previous batches have been written.
+type batch struct {
+	buf     *internal.Buffer
+	p       []byte
+	trigger FlushTrigger
+	// childBytes is the batch data that comes from child Buffers
+	childBytes int
+	// done receives the first error since the last barrier once the batch is written
+	done chan error
+	// swap replaces the underlying writer after writing the batch, see SwapWriter
+	swap *writerSwap
+	// seek seeks the underlying writer after writing the batch, see Seek
+	seek *writerSeek
+	// sync syncs the underlying writer after writing the batch, see Sync
+	sync *writerSync
+	// since is the stamp of the batch data for SetMaxBacklogAge
+	since int64
+	// classes marks the batch replaced by the SetPriorityClasses data when it's dequeued
+	classes bool
+	// walOff is the SetWriteAheadLog offset of the batch data
+	walOff int64
+	// scratch reports that p is the scratch memory of merge
+	scratch bool
+	// vec is the data of a group written without merging and bufs their buffers
+	vec  [][]byte
+	bufs []*internal.Buffer
+	// ticket is the turn of the batch to be written with SetPreserveOrder, dispatched marks
+	// the batches handed to the flush workers
+	ticket     uint64
+	dispatched bool
+	// dst is the buffer of the transformed data, detached is set when the sink write timed out
+	// and closed once the flush is done with the batch: its memory is released once both the
+	// flush and the write end, see writetimeout.go
+	dst      *internal.Buffer
+	detached chan struct{}
+}
+
+// bytes returns the batch data, it's nil for a vectored batch
+func (b *batch) bytes() []byte {
+	if b.buf != nil {
+		return b.buf.Bytes()
+	}
+	return b.p
+}
+
+func (b *batch) len() int {
+	if b.vec != nil {
+		n := 0
+		for _, p := range b.vec {
+			n += len(p)
+		}
+		return n
+	}
+	return len(b.bytes())
 }
 
 // NewBuffer wraps a writer with a buffer layer that will write to an underlying writer
 // when its buffer is full or a timer ticks
-// Call Close to free goroutines, Close blocks until all buffers flush, calling Close and then Write won't panic
+// Call Close to free goroutines, Close blocks until all buffers flush, calling Close and then Write won't panic.
+// When w is a *Buffer the batches are handed over to it instead of copied, see hierarchy.go
+// When w is nil the sink is pending until SwapWriter provides it, or SetLazySink creates it:
+// the writes are buffered up to the full queue without flushing, Flush waits for the sink,
+// and the data is written in order once it's there. Closing before that discards the data
+// as a flush error, to the dead letter writer if set.
 func NewBuffer(w io.Writer, options ...BufferOption) *Buffer {
 	const (
 		defaultBufSize  = 4096
@@ -50,9 +304,17 @@ func NewBuffer(w io.Writer, options ...BufferOption) *Buffer {
 	)
 
 	tb := &Buffer{writer: w}
+	tb.parent, _ = w.(*Buffer)
 	for _, o := range options {
 		o(tb)
 	}
+	if tb.wal != nil && tb.classes != nil {
+		panic("syncio: SetWriteAheadLog is not supported with SetPriorityClasses")
+	}
+	if tb.lazySink != nil {
+		tb.writer, tb.parent = nil, nil
+	}
+	tb.sinkPending = tb.writer == nil
 
 	if tb.bufSize == 0 {
 		tb.bufSize = defaultBufSize
@@ -60,26 +322,70 @@ func NewBuffer(w io.Writer, options ...BufferOption) *Buffer {
 	if tb.poolSize == 0 {
 		tb.poolSize = defaultPoolSize
 	}
-	tb.pool = internal.NewBufferPool(tb.poolSize, tb.bufSize)
-	tb.buf = tb.getBuffer()
-
-	if tb.flushInterval > 0 {
-		go func() {
-			t := time.NewTicker(tb.flushInterval)
-			for !tb.closed {
-				select {
-				case <-t.C:
-					tb.bufmu.Lock()
-					if !tb.flushedBetweenTicks {
-						tb.flush()
-					} else {
-						tb.flushedBetweenTicks = false
-					}
-					tb.bufmu.Unlock()
-				}
-			}
-			t.Stop()
-		}()
+	if tb.clock == nil {
+		tb.clock = systemClock{}
+	}
+	if tb.adaptive != nil {
+		tb.adaptive.init(tb)
+	}
+	if tb.adaptiveInterval != nil {
+		tb.adaptiveInterval.init(tb)
+	}
+	if tb.flushPolicy != nil {
+		tb.flushPolicy.flushed(tb)
+	}
+	tb.detectSink()
+	tb.stats.FlushInterval = tb.flushInterval
+	tb.stats.BufferSize = int32(tb.bufSize)
+	if tb.budget != nil {
+		tb.budget.attach(tb)
+		alloc, free := tb.budgetPool()
+		tb.pool = internal.NewBufferPoolAllocator(tb.poolSize, tb.bufSize, alloc, free)
+	} else if tb.alloc != nil {
+		tb.pool = internal.NewBufferPoolAllocator(tb.poolSize, tb.bufSize, tb.alloc, tb.free)
+	} else {
+		tb.pool = internal.NewBufferPool(tb.poolSize, tb.bufSize)
+	}
+	tb.rates.sample(tb)
+	tb.buf, _ = tb.getBuffer()
+	tb.fastWrites = !tb.singleWriter && !tb.recordMode && tb.journal == nil && tb.wal == nil && tb.flushPolicy == nil
+	tb.utf8Boundaries = tb.utf8Boundaries && !tb.recordMode
+	if tb.dedup != nil {
+		tb.dedup.maxAge = tb.dedupMaxAge
+	}
+	tb.features = tb.writeFeatures()
+	if tb.fastWrites {
+		tb.openCursor()
+	} else {
+		tb.cursor.Store(cursorSealed)
+	}
+	tb.space = sync.NewCond(&tb.bufmu)
+	tb.ready = sync.NewCond(&tb.bufmu)
+	tb.done = make(chan struct{})
+	if tb.journal != nil {
+		tb.journal.start(tb)
+	}
+
+	if tb.scheduler != nil {
+		tb.scheduler.add(tb)
+	}
+	if tb.reporter != nil {
+		go tb.reportLoop()
+	}
+	if tb.maxLatency > 0 {
+		tb.startLatency()
+	}
+	if tb.idleTimeout > 0 {
+		go tb.idleLoop()
+	}
+	if tb.synchronous {
+		return tb
+	}
+	tb.startWorkers()
+	go tb.flushLoop()
+	if tb.scheduler == nil && tb.maxLatency <= 0 && tb.flushInterval > 0 {
+		tb.stop = make(chan struct{})
+		go tb.tickLoop()
 	}
 
 	return tb
@@ -96,7 +402,8 @@ func SetBufferSize(s int) BufferOption {
 }
 
 // SetBufferPoolSize to set the minimum number of buffers
-// that will be retained in the pool which won't be garbage collected
+// that will be retained in the pool which won't be garbage collected,
+// it's also the number of full buffers that can wait to be written before writes block
 func SetBufferPoolSize(s int) BufferOption {
 	return func(b *Buffer) {
 		b.poolSize = s
@@ -111,87 +418,888 @@ func SetFlushInterval(d time.Duration) BufferOption {
 	}
 }
 
+// SetSingleWriter enables a Write path without locking for Buffers written from a single
+// goroutine, only the handoff of the active buffer with the flush goroutine is synchronized.
+// Calling Write concurrently with this option is undefined behavior.
+func SetSingleWriter(single bool) BufferOption {
+	return func(b *Buffer) {
+		b.singleWriter = single
+	}
+}
+
+// SetOnFlushError sets a callback invoked from the flush goroutine every time a batch
+// can't be written, it receives the error and the bytes that were not written,
+// which are only valid during the call. Errors returns a channel of the errors instead.
+func SetOnFlushError(fn func(err *FlushError, unwritten []byte)) BufferOption {
+	return func(b *Buffer) {
+		b.onFlushError = fn
+	}
+}
+
 // ErrWriteOnClosed is returned when a write is done after closing
 var ErrWriteOnClosed = errors.New("write on closed writer")
 
-// Write enqueues the data to be buffered
+// Write enqueues the data to be buffered, it fails with ErrSelfWrite when p is the data
+// the Buffer is flushing. p is copied before Write returns and must not be modified
+// meanwhile, as any io.Writer.
 func (tb *Buffer) Write(p []byte) (int, error) {
+	return tb.writeCounted(nil, p, &tb.stats.CallerWrites)
+}
+
+// writeCounted is Write counting the successful writes in writes, the counter of a
+// WriterHandle or CallerWrites, a non nil ctx aborts the wait for space
+func (tb *Buffer) writeCounted(ctx context.Context, p []byte, writes *int64) (int, error) {
+	if !tb.initialized() {
+		return 0, ErrNotInitialized
+	}
+	if tb.selfWrite(p) {
+		return 0, ErrSelfWrite
+	}
+	if tb.features == 0 {
+		if len(p) < smallWrite && tb.writeSmall(p) {
+			atomic.AddInt64(writes, 1)
+			return len(p), nil
+		}
+		return tb.writeSlow(ctx, p, writes, nil)
+	}
+	return tb.writeFeatured(ctx, p, writes)
+}
+
+// writeFeatured is the Write path with optional features
+func (tb *Buffer) writeFeatured(ctx context.Context, p []byte, writes *int64) (int, error) {
 	lenP := len(p)
+	if tb.features&featureWriteSizes != 0 {
+		tb.stats.WriteSizes.observe(lenP)
+	}
+	if tb.features&featureDedup != 0 && tb.dedupWrite(p) {
+		atomic.AddInt64(writes, 1)
+		return lenP, nil
+	}
+
+	if tb.features&featureBacklog != 0 && tb.backlog.exceeded.Load() {
+		// the backlog policy is applied by the slow path
+	} else if tb.features&featureSingleWriter != 0 && atomic.CompareAndSwapInt32(&tb.state, stateFree, stateWriter) {
+		// fast path: the data fits in the active buffer
+		if lenP < tb.bufSize && lenP <= tb.buf.Available() {
+			tb.acct.accept(lenP)
+			if tb.journal != nil {
+				tb.journal.write(p)
+			}
+			if tb.backlog != nil && tb.buf.Buffered() == 0 {
+				tb.backlog.stamp(tb)
+			}
+			tb.buf.Write(p)
+			if tb.recordMode {
+				tb.buf.Mark()
+			}
+			atomic.StoreInt32(&tb.state, stateFree)
+			atomic.AddInt64(writes, 1)
+			return lenP, nil
+		}
+		atomic.StoreInt32(&tb.state, stateFree)
+	} else if tb.fastWrites && lenP < smallWrite && tb.writeSmall(p) {
+		atomic.AddInt64(writes, 1)
+		return lenP, nil
+	}
+	return tb.writeSlow(ctx, p, writes, nil)
+}
 
+// writeSlow is the Write path locking bufmu, owned is p when the Buffer takes its ownership,
+// see writeLocked
+func (tb *Buffer) writeSlow(ctx context.Context, p []byte, writes *int64, owned []byte) (int, error) {
+	lenP := len(p)
+	var bsw swap
+	var watch spaceWatch
+	defer watch.stop()
+	var dropped *internal.Buffer
+	if tb.overflow == OverflowDropOldest {
+		defer func() {
+			if dropped != nil {
+				tb.putBuffer(dropped)
+			}
+		}()
+	}
+	if tb.budget != nil {
+		if err := tb.budget.wait(ctx, tb); err != nil {
+			return 0, err
+		}
+	}
 	tb.bufmu.Lock()
-	defer tb.bufmu.Unlock()
-	if tb.closed {
-		return 0, ErrWriteOnClosed
+	if ok, err := tb.admit(ctx, lenP, &watch, &dropped, &bsw); !ok {
+		if err != nil {
+			return 0, err
+		}
+		atomic.AddInt64(writes, 1)
+		if tb.logger != nil {
+			tb.logSwap(bsw)
+		}
+		return lenP, nil
+	}
+	tb.own()
+	if tb.wal != nil {
+		if err := tb.appendWAL(p); err != nil {
+			tb.unlockBuf()
+			return 0, err
+		}
+	}
+	sw := tb.writeLocked(p, owned)
+	tb.unlockBuf()
+	atomic.AddInt64(writes, 1)
+
+	if tb.logger != nil {
+		tb.logSwap(bsw)
+		tb.logSwap(sw)
+	}
+	tb.flushInline()
+	return lenP, nil
+}
+
+// admit waits until a write of n bytes can be buffered, the caller holds bufmu. It returns
+// false with bufmu released when the write is discarded by the overflow policies, which
+// counts as written, or fails. dropped is the batch discarded by OverflowDropOldest and bsw
+// the flush done by the backlog policy.
+func (tb *Buffer) admit(ctx context.Context, n int, watch *spaceWatch, dropped **internal.Buffer, bsw *swap) (bool, error) {
+	for !tb.writesClosed.Load() {
+		// backpressure: wait until the flush goroutine catches up
+		if tb.queueFull(n) || tb.replaying {
+			if tb.replaying {
+				// Replay keeps the order
+			} else if tb.overflow == OverflowDropNewest {
+				tb.dropNewest(n)
+				tb.unlockBuf()
+				return false, nil
+			} else if tb.overflow == OverflowDropOldest && *dropped == nil {
+				var ok bool
+				if *dropped, ok = tb.dropOldest(); ok {
+					continue
+				}
+			}
+			if err := tb.waitSpace(ctx, watch); err != nil {
+				tb.unlockBuf()
+				return false, err
+			}
+			continue
+		}
+		if tb.backlog == nil || !tb.backlogged(bsw) {
+			break
+		}
+		if tb.backlog.policy == OverflowDropNewest {
+			atomic.AddInt64(&tb.stats.BacklogDrops, 1)
+			tb.countError(ErrorDropped)
+			tb.unlockBuf()
+			return false, nil
+		}
+		if err := tb.waitSpace(ctx, watch); err != nil {
+			tb.unlockBuf()
+			return false, err
+		}
+	}
+	if tb.writesClosed.Load() {
+		tb.unlockBuf()
+		tb.countError(ErrorClosed)
+		return false, tb.closedErr()
 	}
+	return true, nil
+}
 
+// writeLocked copies p to the active buffer, the caller must own it. owned is p when the
+// Buffer takes its ownership, it's enqueued as a batch instead of copied when it doesn't fit.
+// It's apart from p so the Write slices don't escape.
+func (tb *Buffer) writeLocked(p, owned []byte) (sw swap) {
+	lenP := len(p)
+	if tb.journal != nil {
+		tb.journal.write(p)
+	}
 	// case when p is bigger than the buffer size:
 	// copy to intermediate buffer to make sure that the write is not blocked by the underlying write
 	// and p is not retained, this is an unexpected use case, TickedBuffer should
 	// have buffers with size multiple times higher than a single write...
 	// TODO: improve the performance of this usecase ???
+	tb.acct.accept(lenP)
 	if lenP >= tb.bufSize {
-		b := make([]byte, lenP)
-		copy(b, p)
-		tb.wg.Add(1)
-		go func() {
-			_, err := tb.writer.Write(b)
-			if err != nil {
-				atomic.AddInt32(&tb.stats.FlushErrors, 1)
-			}
-			tb.wg.Done()
-		}()
-		return lenP, nil
+		// the buffered data goes first to keep the order
+		sw = tb.flush(TriggerSize, nil)
+		tb.enqueue(&batch{p: tb.oversized(p, owned), trigger: TriggerSize})
+		return sw
 	}
-
 	if lenP > tb.buf.Available() {
-		tb.flush()
+		sw = tb.flush(TriggerSize, nil)
 		tb.flushedBetweenTicks = true
+		// it doesn't fit with the bytes carried by SetUTF8Boundaries
+		if owned != nil || lenP > tb.buf.Available() {
+			tb.enqueue(&batch{p: tb.oversized(p, owned), trigger: TriggerSize})
+			return sw
+		}
+	}
+	if tb.backlog != nil && tb.buf.Buffered() == 0 {
+		tb.backlog.stamp(tb)
 	}
 	tb.buf.Write(p)
+	return tb.appended(sw)
+}
 
-	return lenP, nil
+// appended records a write added to the active buffer, sw is the flush done by the write
+func (tb *Buffer) appended(sw swap) swap {
+	if tb.recordMode {
+		tb.buf.Mark()
+	}
+	if tb.flushPolicy != nil {
+		tb.flushPolicy.writes++
+		if sw.bytes == 0 {
+			sw = tb.flushPolicy.check(tb, false)
+		}
+	}
+	return sw
 }
 
-// Close is concurrent safe and blocks until the remaining data in buffer is flushed
+// oversized returns p to be enqueued as a batch, owned if it's not nil or else a copy, the
+// caller must have flushed the active buffer
+func (tb *Buffer) oversized(p, owned []byte) []byte {
+	if owned != nil && !tb.utf8Boundaries {
+		return owned
+	}
+	if !tb.utf8Boundaries {
+		b := make([]byte, len(p))
+		copy(b, p)
+		return b
+	}
+	return tb.carryRune(p)
+}
+
+// Flush sends the buffered data to the underlying writer and blocks until it's written,
+// the returned error is the first flush error since the last call to Flush or Close
+func (tb *Buffer) Flush() error {
+	if !tb.initialized() {
+		return ErrNotInitialized
+	}
+	tb.lockBuf()
+	if tb.closed {
+		tb.unlockBuf()
+		tb.countError(ErrorClosed)
+		return ErrWriteOnClosed
+	}
+	done := make(chan error, 1)
+	sw := tb.flush(TriggerManual, done)
+	tb.unlockBuf()
+
+	if tb.logger != nil {
+		tb.logSwap(sw)
+	}
+	tb.flushInline()
+	return <-done
+}
+
+// Close is concurrent safe and blocks until the remaining data in buffer is flushed,
+// the returned error is the first flush error since the last call to Flush.
+// See SetClosePolicy to discard the remaining data instead
 func (tb *Buffer) Close() error {
-	tb.bufmu.Lock()
+	if !tb.initialized() {
+		return ErrNotInitialized
+	}
+	done, ok := tb.startClose()
+	if !ok {
+		<-tb.done
+		tb.waitReport()
+		return nil
+	}
+	if tb.closePolicy == CloseAbandon {
+		if cerr := tb.abandon(); cerr.Batches > 0 {
+			tb.countError(ErrorDropped)
+		}
+	}
+	tb.flushInline()
+	err := <-done
+	<-tb.done
+	tb.freeMemory()
+	tb.waitReport()
+	return err
+}
+
+// startClose marks the Buffer closed and sends the remaining data to flush, the returned
+// channel receives the flush result. It returns false if the Buffer was already closed.
+func (tb *Buffer) startClose() (chan error, bool) {
+	tb.lockBuf()
+	if tb.closed {
+		tb.unlockBuf()
+		return nil, false
+	}
 	tb.closed = true
-	tb.bufmu.Unlock()
+	tb.writesClosed.Store(true)
+	if tb.singleWriter {
+		// the fast path is disabled from now on
+		atomic.StoreInt32(&tb.state, stateClosed)
+	}
+	// the cursor isn't reopened once closed
 
 	// flush remaining data in buffers
-	tb.flush()
+	done := make(chan error, 1)
+	sw := tb.flush(TriggerClose, done)
+	// writers waiting for space must see the buffer closed
+	tb.space.Broadcast()
+	if tb.stop != nil {
+		close(tb.stop)
+	}
+	tb.unlockBuf()
+	if tb.scheduler != nil {
+		tb.scheduler.remove(tb)
+	}
+
+	if tb.logger != nil {
+		tb.logSwap(sw)
+	}
+	return done, true
+}
 
-	tb.wg.Wait()
-	return nil
+// lockBuf locks bufmu and takes the ownership of the active buffer
+func (tb *Buffer) lockBuf() {
+	tb.bufmu.Lock()
+	tb.own()
 }
 
-// flush writes all the data of the current buffer to the underlying writer
-// the buffer used to write is put in background and its sent back to the
-// buffer pool when its operation finishes. A new buffer is obtained to continue
-// serving incoming writes.
-func (tb *Buffer) flush() {
-	if tb.buf.Buffered() == 0 {
+// unlockBuf releases the ownership of the active buffer and bufmu
+func (tb *Buffer) unlockBuf() {
+	// the fast paths stay disabled while replaying
+	if !tb.replaying {
+		if tb.singleWriter {
+			atomic.CompareAndSwapInt32(&tb.state, stateLocked, stateFree)
+		}
+		if tb.fastWrites && !tb.writesClosed.Load() {
+			tb.openCursor()
+		}
+	}
+	tb.bufmu.Unlock()
+}
+
+// own waits until the Write fast paths are not using the active buffer, the caller must hold bufmu
+func (tb *Buffer) own() {
+	if tb.fastWrites {
+		tb.sealCursor()
 		return
 	}
-	buf := tb.buf
-	tb.buf = tb.getBuffer()
-	tb.wg.Add(1)
-	go func() {
-		_, err := buf.WriteTo(tb.writer)
-		if err != nil {
-			atomic.AddInt32(&tb.stats.FlushErrors, 1)
+	if !tb.singleWriter {
+		return
+	}
+	for !atomic.CompareAndSwapInt32(&tb.state, stateFree, stateLocked) {
+		// stateLocked is only kept by Replay between bufmu holders
+		if s := atomic.LoadInt32(&tb.state); s == stateClosed || s == stateLocked {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+// swap describes a buffer replacement done by flush, it's used to log the event
+// once bufmu is released
+type swap struct {
+	bytes   int
+	trigger FlushTrigger
+	alloc   bool
+	size    int
+}
+
+// flush sends the current buffer to the flush goroutine, a new buffer is obtained to continue
+// serving incoming writes. If done is not nil the batch is sent even when empty to report
+// when the previous writes finish. The caller must hold bufmu.
+func (tb *Buffer) flush(trigger FlushTrigger, done chan error) swap {
+	return tb.flushBatch(&batch{trigger: trigger, done: done})
+}
+
+// flushBatch is flush with the batch of the current buffer, its swap, seek or sync is done
+// once it's written. The caller must hold bufmu.
+func (tb *Buffer) flushBatch(b *batch) (sw swap) {
+	trigger, done := b.trigger, b.done
+	if tb.classes != nil {
+		tb.queueClasses(trigger)
+	}
+	carry := 0
+	if tb.utf8Boundaries && (trigger == TriggerSize || trigger == TriggerTick || trigger == TriggerIdle) {
+		carry = partialRune(tb.buf.Bytes())
+	}
+	if n := tb.buf.Buffered() - carry; n > 0 {
+		b.buf = tb.buf
+		if tb.childBytes > 0 {
+			// the carried bytes are counted as child data of the next batch
+			b.childBytes = tb.childBytes
+			if b.childBytes > n {
+				b.childBytes = n
+			}
+			tb.childBytes -= b.childBytes
+		}
+		if tb.backlog != nil {
+			b.since = tb.backlog.take(tb, carry > 0)
+		}
+		if tb.adaptive != nil {
+			tb.adaptive.observe(tb, n)
+		}
+		if tb.flushPolicy != nil {
+			tb.flushPolicy.flushed(tb)
+		}
+		tb.buf, sw.alloc = tb.getBuffer()
+		if carry > 0 {
+			tb.buf.Write(b.buf.Bytes()[n:])
+			b.buf.SetBuffered(n)
+		}
+		sw.bytes = n
+		sw.trigger = trigger
+		sw.size = tb.buf.Cap()
+	} else if done == nil {
+		return
+	}
+	if tb.journal != nil {
+		tb.journal.record(journalFlush, uint64(trigger), uint64(sw.bytes))
+	}
+	tb.enqueue(b)
+	return
+}
+
+// enqueue appends a batch to the queue and wakes up the flush goroutine, the caller must
+// hold bufmu. The queue can temporarily hold more than poolSize batches, the writers
+// wait for space before modifying the buffer so the order is preserved.
+func (tb *Buffer) enqueue(b *batch) {
+	if tb.wal != nil {
+		b.walOff = tb.walRange(b.len())
+	}
+	if tb.backlog != nil && b.since == 0 && b.len() > 0 {
+		b.since = tb.backlog.now(tb)
+	}
+	if tb.spill == nil || !tb.spill.take(tb, b) {
+		tb.queue = append(tb.queue, b)
+	}
+	tb.ready.Signal()
+}
+
+// dequeue waits for the next batches to write, it returns nil when the Buffer is closed
+// and the queue is empty, or without waiting if wait is false. With SetMaxFlushBytes the
+// following batches are taken while they fit, up to a barrier. The returned slice is reused
+// by the next call.
+func (tb *Buffer) dequeue(wait bool) []*batch {
+	tb.bufmu.Lock()
+	defer tb.bufmu.Unlock()
+	if tb.backlog != nil {
+		// the previous group was written
+		tb.backlog.writing.Store(0)
+		tb.backlog.check(tb)
+	}
+	if tb.spill != nil {
+		tb.spill.refill(tb)
+	}
+	for wait && (len(tb.queue) == 0 || tb.sinkPending) && !tb.closed {
+		tb.ready.Wait()
+		if tb.spill != nil {
+			tb.spill.refill(tb)
+		}
+	}
+	if len(tb.queue) == 0 || tb.sinkPending && !tb.closed {
+		return nil
+	}
+	head, i := tb.queue[0], 1
+	if head.classes {
+		var done bool
+		if head, done = tb.takeClasses(head); !done {
+			// the marker stays at the head for the rest of the class data
+			i = 0
+		}
+	}
+	group := append(tb.group[:0], head)
+	size := head.len()
+	if (tb.coalesces() || tb.vectored) && i > 0 {
+		for ; i < len(tb.queue) && group[len(group)-1].done == nil && !tb.queue[i].classes; i++ {
+			if size += tb.queue[i].len(); tb.maxFlushBytes > 0 && size > tb.maxFlushBytes {
+				break
+			}
+			group = append(group, tb.queue[i])
+		}
+	}
+	for j := 0; j < i; j++ {
+		tb.queue[j] = nil
+	}
+	tb.queue = tb.queue[i:]
+	tb.group = group
+	if tb.backlog != nil {
+		tb.backlog.writing.Store(groupSince(group))
+	}
+	tb.space.Broadcast()
+	return group
+}
+
+// flushLoop writes the batches to the underlying writer, the used buffers are sent back
+// to the buffer pool
+func (tb *Buffer) flushLoop() {
+	for group := tb.dequeue(true); group != nil; group = tb.dequeue(true) {
+		if tb.workers != nil {
+			tb.workers.run(tb, group)
+		} else {
+			tb.writeGroup(group)
+		}
+	}
+	if tb.workers != nil {
+		tb.workers.stop()
+	}
+	tb.errs.close()
+	close(tb.done)
+}
+
+// writeGroup writes a group of batches returned by dequeue and reports the result to the
+// barriers
+func (tb *Buffer) writeGroup(group []*batch) {
+	for _, g := range group {
+		if n := g.len(); n > 0 {
+			atomic.AddInt64(&tb.stats.Batches, 1)
+			if g.childBytes > 0 {
+				atomic.AddInt64(&tb.stats.ChildBytes, int64(g.childBytes))
+			}
+			if n > g.childBytes {
+				atomic.AddInt64(&tb.stats.DirectBytes, int64(n-g.childBytes))
+			}
+		}
+	}
+	b := group[0]
+	if len(group) > 1 && tb.vectored {
+		b = tb.vectorize(group)
+	} else if len(group) > 1 {
+		b = tb.merge(group)
+	}
+	for i := range group {
+		group[i] = nil
+	}
+	if r, ok := tb.writer.(batchRouter); ok && b.len() > 0 {
+		r.beginBatch()
+	}
+	if err := tb.write(b); err != nil && tb.pending == nil {
+		tb.pending = err
+	}
+	tb.acct.check(tb)
+	tb.flushWait.wake(tb)
+	if b.seek != nil {
+		tb.seekWriter(b.seek)
+	}
+	if b.sync != nil {
+		b.sync.err = tb.syncWriter(b.sync.deep)
+	}
+	if b.swap != nil {
+		tb.swapWriter(b.swap)
+	}
+	if b.done != nil {
+		if tb.pending != nil {
+			b.done <- tb.pending
+		} else {
+			b.done <- nil
+		}
+		tb.pending = nil
+	}
+}
+
+// tickLoop flushes the buffer every flushInterval unless a full buffer was flushed
+// between ticks
+func (tb *Buffer) tickLoop() {
+	interval := tb.flushInterval
+	first, aligning := interval, tb.flushAlignment > 0
+	if aligning {
+		first = tb.alignDelay()
+	}
+	c, stop := tb.clock.NewTicker(first)
+	defer func() { stop() }()
+	for {
+		select {
+		case <-tb.stop:
+			return
+		case <-c:
+			if aligning {
+				// the next ticks keep the alignment of the first one
+				aligning = false
+				stop()
+				c, stop = tb.clock.NewTicker(interval)
+			}
+			tb.tick()
+			if tb.adaptiveInterval != nil {
+				if d := tb.adaptiveInterval.current(); d != interval {
+					stop()
+					interval = d
+					c, stop = tb.clock.NewTicker(interval)
+				}
+			}
+		}
+	}
+}
+
+// attemptSink writes the batch data p to the underlying writer, within the write timeout if set
+func (tb *Buffer) attemptSink(b *batch, p []byte) (int, error) {
+	if tb.writeTimeout > 0 {
+		return tb.attemptSinkTimeout(b, p)
+	}
+	return tb.attemptSinkWrite(b, p)
+}
+
+// attemptSinkWrite writes the batch data p to the underlying writer holding the write semaphore
+func (tb *Buffer) attemptSinkWrite(b *batch, p []byte) (int, error) {
+	tb.acquire()
+	sinkStart := tb.clock.Now()
+	tb.sinkBytes.Store(int64(len(p)))
+	tb.sinkSince.Store(sinkStart.UnixNano())
+	tb.startSinkContext()
+	n, err := tb.writeSink(b, p)
+	tb.endSinkContext()
+	tb.sinkSince.Store(0)
+	latency := tb.clock.Now().Sub(sinkStart)
+	tb.stats.observeFlushLatency(latency)
+	if tb.adaptiveInterval != nil {
+		tb.adaptiveInterval.observe(tb, latency)
+	}
+	tb.release()
+	return n, err
+}
+
+// writeSink writes the batch data p to the underlying writer, its panics are recovered
+// following the panic policy
+func (tb *Buffer) writeSink(b *batch, p []byte) (n int, err error) {
+	if tb.panicPolicy != PanicRepanic {
+		defer func() {
+			if v := recover(); v != nil {
+				n, err = 0, &PanicError{Value: v, Stack: debug.Stack()}
+				atomic.AddInt64(&tb.stats.Panics, 1)
+			}
+		}()
+	}
+	if tb.headerPending {
+		if err = tb.writeHeader(); err != nil {
+			// the batch isn't written without its header
+			return 0, err
+		}
+	}
+	switch {
+	case tb.framing != nil:
+		n, err = tb.writeFrame(b, p)
+	case tb.recordMode:
+		n, err = tb.writeRecords(b)
+	case b.vec != nil:
+		n, err = tb.writeVectored(b.vec)
+	default:
+		// the short writes are retried
+		n, err = tb.writeChunks(p, nil)
+	}
+	if err == nil && tb.sinkFlush {
+		err = tb.flushSink()
+	}
+	return n, err
+}
+
+// tick flushes the active buffer for a tick of the ticker goroutine or Tick, it returns false
+// if the Buffer is closed
+func (tb *Buffer) tick() bool {
+	_, ok := tb.tickFlush()
+	return ok
+}
+
+// tickFlush is tick returning the bytes flushed
+func (tb *Buffer) tickFlush() (int, bool) {
+	tb.rates.sample(tb)
+	if tb.lazySink != nil {
+		tb.tryLazySink()
+	}
+	var sw swap
+	tb.lockBuf()
+	if tb.backlog != nil {
+		tb.backlog.check(tb)
+	}
+	closed := tb.closed
+	// with a full queue the data waits for the next tick
+	if !closed && !tb.queueFull(0) {
+		if tb.classes != nil {
+			tb.queueClasses(TriggerTick)
+		}
+		if tb.flushPolicy != nil {
+			sw = tb.flushPolicy.check(tb, true)
+			tb.flushedBetweenTicks = false
+		} else if !tb.flushedBetweenTicks {
+			sw = tb.flush(TriggerTick, nil)
+		} else {
+			tb.flushedBetweenTicks = false
+		}
+	}
+	tb.unlockBuf()
+
+	if tb.logger != nil {
+		tb.logSwap(sw)
+	}
+	return sw.bytes, !closed
+}
+
+// write writes a batch to the underlying writer, it reports the error to the
+// onFlushError callback
+func (tb *Buffer) write(b *batch) *FlushError {
+	p := b.bytes()
+	defer func() {
+		if b.detached != nil {
+			// released by the write timed out once it ends
+			close(b.detached)
+		} else {
+			tb.releaseBatch(b)
 		}
-		tb.pool.Put(buf)
-		tb.wg.Done()
 	}()
+	size := b.len()
+	if size == 0 {
+		return nil
+	}
+	accepted := size
+	tb.handOut(p)
+	defer tb.handOut(nil)
+	p, dst, terr := tb.transformBatch(p)
+	if dst != nil {
+		b.dst = dst
+		size = len(p)
+		tb.handOut(p)
+	}
+	offset := tb.flushed
+	tb.flushed += int64(size)
+
+	var start time.Time
+	if tb.logger != nil || tb.afterFlush != nil {
+		start = tb.clock.Now()
+	}
+	if tb.logger != nil {
+		tb.logger(EventFlushStart, map[string]any{"bytes": size, "trigger": b.trigger.String()})
+	}
+	if tb.beforeFlush != nil {
+		tb.beforeFlush(size)
+	}
+	var n int
+	var err error
+	attempt := 1
+	if terr == nil {
+		atomic.AddInt64(&tb.stats.Flushes, 1)
+	}
+	if tb.parent != nil {
+		n, err = tb.writeParent(b)
+	} else if terr != nil {
+		err = terr
+	} else if tb.writer == nil {
+		// closed before the sink was provided
+		err = ErrSinkPending
+	} else {
+		if tb.workers != nil {
+			tb.workers.sinkStart(b)
+		}
+		n, err = tb.attemptSink(b, p)
+		for err != nil && n == 0 && b.detached == nil && tb.retry != nil && tb.retry.wait(tb, attempt, err) {
+			attempt++
+			n, err = tb.attemptSink(b, p)
+		}
+		if tb.workers != nil {
+			tb.workers.sinkEnd()
+		}
+	}
+	if n < 0 || n > size {
+		n = 0
+	}
+	if tb.retention != nil && tb.parent == nil && n > 0 {
+		holder, owned := b.buf, b.buf == nil && !b.scratch
+		if dst != nil {
+			// the transform output isn't always in dst
+			holder, owned = nil, false
+			if &p[0] == &dst.Scratch()[0] {
+				holder = dst
+			}
+		}
+		if tb.allocating() {
+			// the allocator memory is freed on close
+			holder = nil
+		}
+		tb.retention.add(offset, p[:n], holder, owned)
+	}
+	if tb.head != nil && tb.parent == nil && n > 0 {
+		tb.head.add(p[:n])
+	}
+	if err == nil && n < size {
+		err = io.ErrShortWrite
+	}
+	if tb.logger != nil {
+		tb.logger(EventFlushEnd, map[string]any{"bytes": size, "written": n, "trigger": b.trigger.String(), "duration": tb.clock.Now().Sub(start), "error": err})
+	}
+	if tb.afterFlush != nil {
+		tb.afterFlush(n, tb.clock.Now().Sub(start), err)
+	}
+	tb.failing.Store(err != nil)
+	tb.commitWAL(b.walOff, accepted)
+	if b.dispatched {
+		tb.acct.complete(accepted)
+	}
+	if err == nil {
+		tb.acct.flushed(accepted, 0)
+		tb.errorQueued = 0
+		return nil
+	}
+	if b.vec != nil {
+		// the unwritten bytes are only copied on error
+		p = b.flatten()
+	}
+	if len(p) == accepted {
+		tb.acct.flushed(n, len(p)-n)
+	} else {
+		// the transformed bytes written don't match the accepted ones
+		tb.acct.flushed(0, accepted)
+	}
+
+	atomic.AddInt32(&tb.stats.FlushErrors, 1)
+	// the dead letter goes first for the category
+	deadLettered := tb.toDeadLetter(p[n:])
+	ferr := &FlushError{
+		Err:        err,
+		BatchBytes: len(p),
+		Written:    n,
+		Unwritten:  len(p) - n,
+		Offset:     offset + int64(n),
+		Attempt:    attempt,
+		Trigger:    b.trigger,
+		LostData:   n < len(p),
+		Category:   flushCategory(err, deadLettered),
+	}
+	tb.countError(ferr.Category)
+	if tb.logger != nil {
+		tb.logger(EventDrop, map[string]any{"bytes": len(p) - n, "trigger": b.trigger.String(), "error": err})
+	}
+	if tb.onFlushError != nil {
+		tb.onFlushError(ferr, tb.errorPayload(ferr, p[n:]))
+	}
+	tb.errs.send(ferr)
+	if _, ok := err.(*PanicError); ok && tb.panicPolicy == PanicClose {
+		// the flush goroutine writes the remaining batches before ending
+		tb.startClose()
+	}
+	return ferr
 }
 
-func (tb *Buffer) getBuffer() *internal.Buffer {
+func (tb *Buffer) getBuffer() (*internal.Buffer, bool) {
 	b, alloc := tb.pool.Get()
 	if alloc {
-		tb.stats.BufferAllocs++
+		atomic.AddInt32(&tb.stats.BufferAllocs, 1)
+	}
+	return b, alloc
+}
+
+// releaseBatch sends the buffers of a written batch back to the pool
+func (tb *Buffer) releaseBatch(b *batch) {
+	// the buffer is nil if it was handed over to the parent
+	if b.buf != nil {
+		tb.putBuffer(b.buf)
+	}
+	for i, buf := range b.bufs {
+		tb.putBuffer(buf)
+		b.bufs[i] = nil
+	}
+	if b.dst != nil {
+		tb.putBuffer(b.dst)
+		b.dst = nil
+	}
+}
+
+func (tb *Buffer) putBuffer(b *internal.Buffer) {
+	if !tb.pool.Put(b) && tb.logger != nil {
+		tb.logger(EventPoolShrink, map[string]any{"size": b.Cap()})
+	}
+	if tb.budget != nil && tb.budget.over() {
+		// the memory of the buffers written is released to the budget
+		tb.pool.Trim()
 	}
-	return b
 }
 
 // Stats contains performance statistics, some of the settings for this writer
@@ -204,9 +1312,153 @@ type Stats struct {
 	BufferAllocs int32
 	// Count of errors obtained trying to write to the underlying writer
 	FlushErrors int32
+	// BufferSize is the current size of the buffers, it only changes with SetAdaptiveSizing
+	BufferSize int32
+	// Resizes is the number of buffer size changes done by SetAdaptiveSizing
+	Resizes int32
+	// Flushes is the number of flushes to the underlying writer or the parent Buffer, a group
+	// of batches merged by SetMaxFlushBytes is a single flush. See SinkWrites for the calls.
+	Flushes int64
+	// Batches is the number of batches flushed, Batches/Flushes is the average of batches
+	// merged by SetMaxFlushBytes in a single write
+	Batches int64
+	// Records is the number of records flushed with SetRecordMode,
+	// Records/Flushes is the average of records per flush
+	Records int64
+	// ReplayedBytes is the number of bytes written with Replay
+	ReplayedBytes int64
+	// RetainedBytes is the size of the SetRetention window
+	RetainedBytes int64
+	// SinkWait is the total time waiting for the SetWriteSemaphore semaphore
+	SinkWait time.Duration
+	// BudgetWait is the total time the writes waited for memory of the SetBudget budget
+	BudgetWait time.Duration
+	// DirectBytes is the number of bytes flushed that were written with Write, ChildBytes the
+	// number of bytes flushed that were handed over by child Buffers writing into this one
+	DirectBytes int64
+	ChildBytes  int64
+	// FlushInterval is the current flush interval, it only changes with SetAdaptiveInterval
+	FlushInterval time.Duration
+	// Panics is the number of panics of the underlying writer recovered, see SetPanicPolicy
+	Panics int64
+	// BacklogAge is the age of the oldest unflushed byte and BacklogDrops the number of writes
+	// discarded because it was older than SetMaxBacklogAge, they are only set with it
+	BacklogAge   time.Duration
+	BacklogDrops int64
+	// Errors are the error counters by category, the flush errors are counted in FlushErrors too
+	Errors [errorCategories]int64
+	// WriteSizes is the distribution of the Write sizes, it's only set with SetWriteSizeHistogram
+	WriteSizes WriteSizeHistogram
+	// CallerWrites is the number of Write calls that returned without error, including the
+	// writes discarded by SetMaxBacklogAge and SetDedupWindow and the writes of the
+	// WriterHandle handles
+	CallerWrites int64
+	// SinkWrites is the number of calls to the underlying writer: the retries of the short
+	// writes, the headers and the WriteBatch calls count, the handovers to a parent don't
+	SinkWrites int64
+	// BytesPerSec and WritesPerSec are the rates of the flushed bytes and CallerWrites over
+	// the last 1, 10 and 60 seconds, or since NewBuffer within the first seconds. The
+	// counters are sampled by the ticks and the Stats calls, the rates are computed from the
+	// last sample at least a window old.
+	BytesPerSec1   float64
+	BytesPerSec10  float64
+	BytesPerSec60  float64
+	WritesPerSec1  float64
+	WritesPerSec10 float64
+	WritesPerSec60 float64
+	// DiscardedPayloads is the number of SetOnFlushError calls without the unwritten bytes
+	// because of SetMaxErrorQueueBytes, DiscardedPayloadBytes the size of those bytes
+	DiscardedPayloads     int64
+	DiscardedPayloadBytes int64
+	// PendingBytes is the size of the data accepted and not yet handed to the underlying
+	// writer, once the writes are closed by CloseWrites or Close it follows the drain. The
+	// batch being written isn't included.
+	PendingBytes int64
+	// ClassBytes is the size of the writes of every SetPriorityClasses class, ClassDrops the
+	// bytes of the class discarded by the overflows
+	ClassBytes [MaxPriorityClasses]int64
+	ClassDrops [MaxPriorityClasses]int64
+	// Retries is the number of flush attempts retried by SetRetryPolicy
+	Retries int64
+	// OverflowDrops is the number of writes and batches discarded by SetOverflowPolicy and
+	// OverflowDropBytes their size
+	OverflowDrops     int64
+	OverflowDropBytes int64
+	// FlushLatencies is the distribution of the durations of the calls to the underlying
+	// writer, the retries count as calls
+	FlushLatencies FlushLatencyHistogram
+	// FlushLatencySum is the total duration of those calls, FlushLatencyMin and FlushLatencyMax
+	// the shortest and longest, 0 before the first call. See FlushLatencyAvg.
+	FlushLatencySum time.Duration
+	FlushLatencyMin time.Duration
+	FlushLatencyMax time.Duration
+	// SpilledBytes is the size of the batches spilled by SetSpillDir, SpillBytes the size of
+	// the data spilled not yet read back and SpillErrors the number of batches that couldn't
+	// be spilled or read back
+	SpilledBytes int64
+	SpillBytes   int64
+	SpillErrors  int64
+	// DedupDrops is the number of writes discarded by SetDedupWindow and DedupDropBytes their size
+	DedupDrops     int64
+	DedupDropBytes int64
 }
 
-// Stats returns a copy of the current writer stats
+// Stats returns a copy of the current writer stats, it's safe for concurrent use and doesn't allocate
 func (tb *Buffer) Stats() Stats {
-	return tb.stats
+	var s Stats
+	tb.StatsInto(&s)
+	return s
+}
+
+// StatsInto copies the current writer stats into s
+func (tb *Buffer) StatsInto(s *Stats) {
+	s.BufferAllocs = atomic.LoadInt32(&tb.stats.BufferAllocs)
+	s.FlushErrors = atomic.LoadInt32(&tb.stats.FlushErrors)
+	s.BufferSize = atomic.LoadInt32(&tb.stats.BufferSize)
+	s.Resizes = atomic.LoadInt32(&tb.stats.Resizes)
+	s.Flushes = atomic.LoadInt64(&tb.stats.Flushes)
+	s.Batches = atomic.LoadInt64(&tb.stats.Batches)
+	s.Records = atomic.LoadInt64(&tb.stats.Records)
+	s.ReplayedBytes = atomic.LoadInt64(&tb.stats.ReplayedBytes)
+	if tb.retention != nil {
+		s.RetainedBytes = tb.retention.retained()
+	}
+	s.SinkWait = time.Duration(atomic.LoadInt64((*int64)(&tb.stats.SinkWait)))
+	s.BudgetWait = time.Duration(atomic.LoadInt64((*int64)(&tb.stats.BudgetWait)))
+	s.DirectBytes = atomic.LoadInt64(&tb.stats.DirectBytes)
+	s.ChildBytes = atomic.LoadInt64(&tb.stats.ChildBytes)
+	s.FlushInterval = time.Duration(atomic.LoadInt64((*int64)(&tb.stats.FlushInterval)))
+	s.Panics = atomic.LoadInt64(&tb.stats.Panics)
+	if tb.backlog != nil {
+		s.BacklogAge = tb.backlog.age(tb)
+	}
+	s.BacklogDrops = atomic.LoadInt64(&tb.stats.BacklogDrops)
+	for i := range s.Errors {
+		s.Errors[i] = atomic.LoadInt64(&tb.stats.Errors[i])
+	}
+	tb.stats.WriteSizes.load(&s.WriteSizes)
+	s.CallerWrites = tb.callerWrites()
+	s.SinkWrites = atomic.LoadInt64(&tb.stats.SinkWrites)
+	if tb.initialized() {
+		tb.rates.load(tb, s)
+	}
+	s.DiscardedPayloads = atomic.LoadInt64(&tb.stats.DiscardedPayloads)
+	s.DiscardedPayloadBytes = atomic.LoadInt64(&tb.stats.DiscardedPayloadBytes)
+	if tb.initialized() {
+		s.PendingBytes = tb.pendingSize()
+	}
+	for i := range s.ClassBytes {
+		s.ClassBytes[i] = atomic.LoadInt64(&tb.stats.ClassBytes[i])
+		s.ClassDrops[i] = atomic.LoadInt64(&tb.stats.ClassDrops[i])
+	}
+	s.Retries = atomic.LoadInt64(&tb.stats.Retries)
+	s.OverflowDrops = atomic.LoadInt64(&tb.stats.OverflowDrops)
+	s.OverflowDropBytes = atomic.LoadInt64(&tb.stats.OverflowDropBytes)
+	tb.stats.FlushLatencies.load(&s.FlushLatencies)
+	tb.stats.loadFlushLatencyRange(s)
+	s.SpilledBytes = atomic.LoadInt64(&tb.stats.SpilledBytes)
+	s.SpillBytes = atomic.LoadInt64(&tb.stats.SpillBytes)
+	s.SpillErrors = atomic.LoadInt64(&tb.stats.SpillErrors)
+	s.DedupDrops = atomic.LoadInt64(&tb.stats.DedupDrops)
+	s.DedupDropBytes = atomic.LoadInt64(&tb.stats.DedupDropBytes)
 }
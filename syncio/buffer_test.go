@@ -1,6 +1,11 @@
 package syncio
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -18,25 +23,48 @@ func (w *testWriter) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
+// modes are the Buffer modes the core tests run with
+var modes = []struct {
+	name string
+	opts []BufferOption
+}{
+	{"goroutine", nil},
+	{"synchronous", []BufferOption{SetSynchronousMode(true)}},
+}
+
+// inModes runs test in every mode, the mode options must be appended to the test options
+func inModes(t *testing.T, test func(t *testing.T, mode []BufferOption)) {
+	for _, m := range modes {
+		t.Run(m.name, func(t *testing.T) {
+			test(t, m.opts)
+		})
+	}
+}
+
 func TestConcurrentWrites(t *testing.T) {
+	inModes(t, testConcurrentWrites)
+}
+
+func testConcurrentWrites(t *testing.T, mode []BufferOption) {
 	concurrency := 100
 	size := 1024
 	tw := &testWriter{}
-	tb := NewBuffer(tw, SetBufferSize(size))
+	tb := NewBuffer(tw, append([]BufferOption{SetBufferSize(size)}, mode...)...)
 
 	p := make([]byte, size)
 	wg := sync.WaitGroup{}
 	for i := 0; i < concurrency; i++ {
 		wg.Add(1)
 		go func() {
+			defer wg.Done()
 			n, err := tb.Write(p)
 			if err != nil {
-				t.Fatalf(err.Error())
+				t.Errorf(err.Error())
+				return
 			}
 			if n != size {
-				t.Fatalf("writed %v bytes, exepcted: %v", n, size)
+				t.Errorf("writed %v bytes, exepcted: %v", n, size)
 			}
-			wg.Done()
 		}()
 	}
 	wg.Wait()
@@ -62,6 +90,8 @@ func TestTicks(t *testing.T) {
 
 	tw := &testWriter{}
 	tb := NewBuffer(tw, SetBufferSize(size+1), SetFlushInterval(tickInterval/2))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
 	p := make([]byte, block)
 	for i := 0; i < iterations; i++ {
@@ -72,7 +102,10 @@ func TestTicks(t *testing.T) {
 		if n != block {
 			t.Fatalf("writed %v bytes, expected: %v", n, block)
 		}
-		time.Sleep(tickInterval)
+		// every write is flushed by its own tick
+		if err := tb.WaitForFlushes(ctx, int64(i+1)); err != nil {
+			t.Fatalf("waiting for tick %v: %v", i, err)
+		}
 	}
 	tb.Close()
 
@@ -87,9 +120,56 @@ func TestTicks(t *testing.T) {
 	}
 }
 
+// TestManualTicks is TestTicks with the ticks done by the caller
+func TestManualTicks(t *testing.T) {
+	iterations := 10
+	size := 1020
+	block := size / iterations
+
+	goroutines := runtime.NumGoroutine()
+	tw := &testWriter{}
+	tb := NewBuffer(tw, SetBufferSize(size+1), SetManualTick(true))
+	if n := runtime.NumGoroutine(); n != goroutines {
+		t.Errorf("goroutines: %v, expected: %v", n, goroutines)
+	}
+
+	p := make([]byte, block)
+	for i := 0; i < iterations; i++ {
+		n, err := tb.Write(p)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if n != block {
+			t.Fatalf("writed %v bytes, expected: %v", n, block)
+		}
+		if err := tb.Tick(); err != nil {
+			t.Fatal(err)
+		}
+		// the tick is written before returning
+		if tw.writes != int64(i+1) {
+			t.Fatalf("test writer writes after tick %v: %v", i, tw.writes)
+		}
+	}
+	tb.Close()
+	if err := tb.Tick(); err != ErrWriteOnClosed {
+		t.Errorf("tick on closed: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+
+	if tw.writes != int64(iterations) {
+		t.Errorf("test writer writes: %v, actual writes: %v", tw.writes, iterations)
+	}
+	if tw.bytes != int64(size) {
+		t.Errorf("test writer bytes: %v, actual bytes: %v", tw.bytes, size)
+	}
+}
+
 func TestClose(t *testing.T) {
+	inModes(t, testClose)
+}
+
+func testClose(t *testing.T, mode []BufferOption) {
 	tw := &testWriter{}
-	tb := NewBuffer(tw)
+	tb := NewBuffer(tw, mode...)
 
 	p := make([]byte, 8)
 	tb.Write(p)
@@ -149,3 +229,191 @@ func BenchmarkParallelWrites(b *testing.B) {
 	tb.Close()
 	b.Logf("Writer stats: %v, buffers: %v", tw, tb.stats.BufferAllocs)
 }
+
+// TestSingleWriterHandoff must run with the race detector, the ticker, Flush and Snapshot
+// take the active buffer from the lock free Write path
+func TestSingleWriterHandoff(t *testing.T) {
+	inModes(t, testSingleWriterHandoff)
+}
+
+func testSingleWriterHandoff(t *testing.T, mode []BufferOption) {
+	out := &bytes.Buffer{}
+	tb := NewBuffer(out, append([]BufferOption{SetSingleWriter(true), SetBufferSize(64), SetFlushInterval(time.Millisecond)}, mode...)...)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				tb.Snapshot()
+				tb.Flush()
+			}
+		}
+	}()
+
+	expected := &bytes.Buffer{}
+	for i := 0; i < 10000; i++ {
+		line := []byte(strconv.Itoa(i) + "\n")
+		expected.Write(line)
+		tb.Write(line)
+	}
+	close(stop)
+	<-done
+	tb.Close()
+
+	if !bytes.Equal(out.Bytes(), expected.Bytes()) {
+		t.Errorf("written data doesn't match, %v bytes, expected %v", out.Len(), expected.Len())
+	}
+	if _, err := tb.Write([]byte("x")); err != ErrWriteOnClosed {
+		t.Errorf("write on closed: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+}
+
+func BenchmarkWritesSingleWriter(b *testing.B) {
+	size := 1024
+	tw := &testWriter{}
+	tb := NewBuffer(tw, SetBufferSize(size*100), SetBufferPoolSize(b.N/100), SetFlushInterval(time.Second*2), SetSingleWriter(true))
+
+	p := make([]byte, size)
+	for n := 0; n < b.N; n++ {
+		tb.Write(p)
+	}
+
+	tb.Close()
+	b.Logf("Writer stats: %v, buffers: %v", tw, tb.stats.BufferAllocs)
+}
+
+// TestSmallWrites checks that the lock free small writes are not lost or corrupted when
+// the buffer is swapped by other writes
+func TestSmallWrites(t *testing.T) {
+	inModes(t, testSmallWrites)
+}
+
+func testSmallWrites(t *testing.T, mode []BufferOption) {
+	// the writes to out are serialized
+	var out bytes.Buffer
+	tb := NewBuffer(&out, append([]BufferOption{SetBufferSize(256)}, mode...)...)
+
+	writers, lines := 8, 500
+	wg := sync.WaitGroup{}
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				tb.Write([]byte(strconv.Itoa(i) + ":" + strconv.Itoa(j) + "\n"))
+				switch j % 100 {
+				case 50:
+					tb.Flush()
+				case 75:
+					tb.Snapshot()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	tb.Close()
+
+	next := make([]int, writers)
+	for _, l := range bytes.Split(bytes.TrimSuffix(out.Bytes(), []byte("\n")), []byte("\n")) {
+		i, j, ok := bytes.Cut(l, []byte(":"))
+		wi, err1 := strconv.Atoi(string(i))
+		wj, err2 := strconv.Atoi(string(j))
+		if !ok || err1 != nil || err2 != nil || wi >= writers {
+			t.Fatalf("corrupted line: %q", l)
+		}
+		if wj != next[wi] {
+			t.Fatalf("writer %v line: %v, expected: %v", wi, wj, next[wi])
+		}
+		next[wi]++
+	}
+	for i, n := range next {
+		if n != lines {
+			t.Errorf("writer %v lines: %v, expected: %v", i, n, lines)
+		}
+	}
+}
+
+func BenchmarkSmallWrites(b *testing.B) {
+	p := make([]byte, 32)
+	for _, histogram := range []bool{false, true} {
+		tb := NewBuffer(&testWriter{}, SetBufferSize(64*1024), SetFlushInterval(time.Second), SetWriteSizeHistogram(histogram))
+		name := ""
+		if histogram {
+			name = "histogram/"
+		}
+		b.Run(name+"serial", func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				tb.Write(p)
+			}
+		})
+		b.Run(name+"parallel", func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					tb.Write(p)
+				}
+			})
+		})
+		tb.Close()
+	}
+}
+
+func TestStatsAllocs(t *testing.T) {
+	tb := NewBuffer(&testWriter{})
+	defer tb.Close()
+	var s Stats
+	if n := testing.AllocsPerRun(100, func() { s = tb.Stats() }); n != 0 {
+		t.Errorf("Stats allocs: %v, expected: 0", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { tb.StatsInto(&s) }); n != 0 {
+		t.Errorf("StatsInto allocs: %v, expected: 0", n)
+	}
+}
+
+func TestWriteCounters(t *testing.T) {
+	inModes(t, testWriteCounters)
+}
+
+func testWriteCounters(t *testing.T, mode []BufferOption) {
+	p := []byte("0123456789")
+	for _, c := range []struct {
+		name   string
+		w      func() io.Writer
+		opts   []BufferOption
+		writes [][]byte
+		// flushes and sink are the expected Flushes and SinkWrites
+		flushes, sink int64
+	}{
+		{"single writer", nil, []BufferOption{SetSingleWriter(true)}, [][]byte{p[:4], p[:4], p[:4]}, 1, 1},
+		{"small", nil, nil, [][]byte{p[:4], p[:4], p[:4]}, 1, 1},
+		{"oversized", nil, []BufferOption{SetBufferSize(8)}, [][]byte{p[:4], p, p}, 3, 3},
+		{"short writes", func() io.Writer { return &shortWriter{max: 3} }, nil, [][]byte{p}, 1, 4},
+		{"header", nil, []BufferOption{SetSinkHeader(func() []byte { return []byte("h\n") })}, [][]byte{p, p}, 1, 2},
+		{"records", nil, []BufferOption{SetRecordMode(true)}, [][]byte{p, p}, 1, 1},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var w io.Writer = &testWriter{}
+			if c.w != nil {
+				w = c.w()
+			}
+			tb := NewBuffer(w, append(append([]BufferOption{SetBufferSize(64)}, c.opts...), mode...)...)
+			for _, p := range c.writes {
+				if _, err := tb.Write(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tb.Close(); err != nil {
+				t.Fatal(err)
+			}
+			tb.Write(p)
+			s := tb.Stats()
+			if s.CallerWrites != int64(len(c.writes)) || s.Flushes != c.flushes || s.SinkWrites != c.sink {
+				t.Errorf("caller writes: %v, flushes: %v, sink writes: %v, expected: %v, %v, %v", s.CallerWrites, s.Flushes, s.SinkWrites, len(c.writes), c.flushes, c.sink)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Package bufpool is a pool of fixed size byte slices, it's the pool of the syncio.Buffer
+// buffers.
+package bufpool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Pool is a pool of byte slices of the same size, it's safe for concurrent use.
+// A bounded Pool keeps at most maxBuffers free slices that are never garbage collected,
+// https://golang.org/doc/effective_go.html#leaky_buffer. An unbounded Pool is backed by a
+// sync.Pool: it keeps any number of free slices but they can be collected.
+type Pool struct {
+	size atomic.Int64
+	// free is the free list of a bounded pool, unbounded is used otherwise
+	free      chan []byte
+	unbounded sync.Pool
+	// alloc and release are the allocator of NewWithAllocator, closed frees the slices
+	// put from now on
+	alloc   func(size int) []byte
+	release func([]byte)
+	closed  atomic.Bool
+
+	allocs   atomic.Int64
+	reuses   atomic.Int64
+	discards atomic.Int64
+	drops    atomic.Int64
+}
+
+// Stats are the counters of a Pool
+type Stats struct {
+	// Allocs is the number of slices allocated by Get and Reuses the number of slices
+	// taken from the pool
+	Allocs int64
+	Reuses int64
+	// Discards is the number of slices dropped because their capacity isn't the size of the
+	// pool, Drops the number of slices dropped because a bounded pool was full
+	Discards int64
+	Drops    int64
+}
+
+// New returns a pool of slices of size bytes keeping up to maxBuffers free slices, a
+// maxBuffers lower than 1 makes the pool unbounded
+func New(size, maxBuffers int) *Pool {
+	p := &Pool{}
+	p.size.Store(int64(size))
+	if maxBuffers > 0 {
+		p.free = make(chan []byte, maxBuffers)
+	}
+	return p
+}
+
+// NewWithAllocator returns a pool of slices allocated by alloc, the slices dropped by the
+// pool are freed with free: the ones of a full pool, of a previous size and put after
+// Close. The pool is always bounded, a maxBuffers lower than 1 keeps no free slice. The
+// slices put must be the ones of Get, not subslices.
+func NewWithAllocator(size, maxBuffers int, alloc func(size int) []byte, free func([]byte)) *Pool {
+	if maxBuffers < 0 {
+		maxBuffers = 0
+	}
+	p := &Pool{free: make(chan []byte, maxBuffers), alloc: alloc, release: free}
+	p.size.Store(int64(size))
+	return p
+}
+
+// Size returns the size of the slices
+func (p *Pool) Size() int {
+	return int(p.size.Load())
+}
+
+// Get returns a slice of the pool size from the pool, if there is none it's allocated
+func (p *Pool) Get() []byte {
+	if b, ok := p.TryGet(); ok {
+		return b
+	}
+	return p.Alloc()
+}
+
+// Alloc returns a new slice of the pool size without taking one from the pool
+func (p *Pool) Alloc() []byte {
+	p.allocs.Add(1)
+	if p.alloc != nil {
+		return p.alloc(p.Size())[:p.Size()]
+	}
+	return make([]byte, p.Size())
+}
+
+// drop frees a slice of an allocator
+func (p *Pool) drop(b []byte) {
+	if p.release != nil {
+		p.release(b)
+	}
+}
+
+// TryGet returns a slice of the pool size from the pool, false if there is none
+func (p *Pool) TryGet() ([]byte, bool) {
+	size := p.Size()
+	for {
+		b, ok := p.take()
+		if !ok {
+			return nil, false
+		}
+		if cap(b) != size {
+			// taken before a Resize
+			p.discards.Add(1)
+			p.drop(b)
+			continue
+		}
+		p.reuses.Add(1)
+		return b[:size], true
+	}
+}
+
+func (p *Pool) take() ([]byte, bool) {
+	if p.free == nil {
+		if b, ok := p.unbounded.Get().(*[]byte); ok {
+			return *b, true
+		}
+		return nil, false
+	}
+	select {
+	case b := <-p.free:
+		return b, true
+	default:
+		return nil, false
+	}
+}
+
+// Put returns b to the pool, it must not be used after the call. A slice whose capacity
+// isn't the pool size is dropped, so a slice of a different pool or a subslice of a pooled
+// one is never handed out twice. It returns false if b was dropped.
+func (p *Pool) Put(b []byte) bool {
+	if cap(b) != p.Size() {
+		p.discards.Add(1)
+		p.drop(b)
+		return false
+	}
+	b = b[:cap(b)]
+	if p.free == nil {
+		p.unbounded.Put(&b)
+		return true
+	}
+	if p.closed.Load() {
+		p.drop(b)
+		return false
+	}
+	select {
+	case p.free <- b:
+		if p.closed.Load() {
+			// put while closing
+			p.drain()
+		}
+		return true
+	default:
+		p.drops.Add(1)
+		p.drop(b)
+		return false
+	}
+}
+
+// Close drops the free slices of a bounded pool, and the slices put from now on, they are
+// freed with the allocator of NewWithAllocator. Get still works.
+func (p *Pool) Close() {
+	p.closed.Store(true)
+	p.drain()
+}
+
+// Trim drops the free slices of a bounded pool, unlike Close the slices put from now on are
+// kept. It returns the number of slices dropped.
+func (p *Pool) Trim() int {
+	return p.drain()
+}
+
+func (p *Pool) drain() int {
+	n := 0
+	for {
+		select {
+		case b := <-p.free:
+			p.drop(b)
+			n++
+		default:
+			return n
+		}
+	}
+}
+
+// Resize changes the size of the slices from now on, the free slices of the previous size
+// are discarded by Get and the ones returned later by Put
+func (p *Pool) Resize(size int) {
+	p.size.Store(int64(size))
+}
+
+// Stats returns the pool counters
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Allocs:   p.allocs.Load(),
+		Reuses:   p.reuses.Load(),
+		Discards: p.discards.Load(),
+		Drops:    p.drops.Load(),
+	}
+}
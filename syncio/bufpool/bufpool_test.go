@@ -0,0 +1,117 @@
+package bufpool
+
+import "testing"
+
+func TestPool(t *testing.T) {
+	p := New(8, 2)
+	a, b, c := p.Get(), p.Get(), p.Get()
+	if len(a) != 8 || cap(a) != 8 {
+		t.Fatalf("len: %v, cap: %v, expected: 8", len(a), cap(a))
+	}
+	if !p.Put(a[:0]) || !p.Put(b) {
+		t.Error("put of pooled slices failed")
+	}
+	if p.Put(c) {
+		t.Error("put on a full pool succeeded")
+	}
+	if d := p.Get(); len(d) != 8 || &d[0] != &a[0] {
+		t.Error("the first free slice wasn't reused with the pool size")
+	}
+	// subslices and foreign slices don't have the pool capacity
+	if p.Put(b[1:]) || p.Put(make([]byte, 16)) {
+		t.Error("put of a slice with another capacity succeeded")
+	}
+	if s, expected := p.Stats(), (Stats{Allocs: 3, Reuses: 1, Discards: 2, Drops: 1}); s != expected {
+		t.Errorf("stats: %+v, expected: %+v", s, expected)
+	}
+}
+
+func TestPoolResize(t *testing.T) {
+	p := New(8, 2)
+	old := p.Get()
+	p.Put(p.Get())
+	p.Resize(16)
+	if p.Put(old) {
+		t.Error("put of a slice of the previous size succeeded")
+	}
+	if b := p.Get(); len(b) != 16 {
+		t.Errorf("len after resize: %v, expected: 16", len(b))
+	}
+	if s, expected := p.Stats(), (Stats{Allocs: 3, Discards: 2}); s != expected {
+		t.Errorf("stats: %+v, expected: %+v", s, expected)
+	}
+}
+
+func TestPoolUnbounded(t *testing.T) {
+	p := New(8, 0)
+	for i := 0; i < 10; i++ {
+		if !p.Put(make([]byte, 8)) {
+			t.Fatal("put on an unbounded pool failed")
+		}
+	}
+	if p.Put(make([]byte, 4)) {
+		t.Error("put of a slice with another capacity succeeded")
+	}
+	if b := p.Get(); len(b) != 8 {
+		t.Errorf("len: %v, expected: 8", len(b))
+	}
+	if s := p.Stats(); s.Drops != 0 || s.Discards != 1 || s.Allocs+s.Reuses != 1 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+func TestPoolTrim(t *testing.T) {
+	freed := 0
+	p := NewWithAllocator(8, 2, func(size int) []byte { return make([]byte, size) }, func([]byte) { freed++ })
+	a, b := p.Get(), p.Get()
+	p.Put(a)
+	p.Put(b)
+	if n := p.Trim(); n != 2 || freed != 2 {
+		t.Errorf("trimmed: %v, freed: %v, expected: 2", n, freed)
+	}
+	// unlike Close the pool keeps working
+	c := p.Get()
+	if !p.Put(c) || p.Trim() != 1 {
+		t.Error("slice put after Trim not pooled")
+	}
+	if n := New(8, 0).Trim(); n != 0 {
+		t.Errorf("trimmed from an unbounded pool: %v", n)
+	}
+}
+
+func TestPoolAllocator(t *testing.T) {
+	live := map[*byte]bool{}
+	p := NewWithAllocator(8, 1, func(size int) []byte {
+		b := make([]byte, size)
+		live[&b[0]] = true
+		return b
+	}, func(b []byte) {
+		if !live[&b[:1][0]] {
+			t.Fatal("free of a slice not allocated")
+		}
+		delete(live, &b[:1][0])
+	})
+	a, b := p.Get(), p.Get()
+	p.Put(a)
+	// the pool is full
+	p.Put(b)
+	if len(live) != 1 {
+		t.Errorf("live slices: %v, expected: 1", len(live))
+	}
+	c := p.Get()
+	p.Resize(16)
+	// freed as a slice of the previous size
+	p.Put(c)
+	d := p.Get()
+	p.Close()
+	if len(live) != 1 {
+		t.Errorf("live slices after close: %v, expected: 1", len(live))
+	}
+	p.Put(d)
+	if len(live) != 0 {
+		t.Errorf("live slices after the put on closed: %v, expected: 0", len(live))
+	}
+	if s, expected := p.Stats(), (Stats{Allocs: 3, Reuses: 1, Discards: 1, Drops: 1}); s != expected {
+		t.Errorf("stats: %+v, expected: %+v", s, expected)
+	}
+}
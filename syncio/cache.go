@@ -0,0 +1,336 @@
+package syncio
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCacheFull is returned by WriterCache.Get when the maximum of open Buffers is reached
+// and all of them are pinned
+var ErrCacheFull = errors.New("writer cache full: all the buffers are in use")
+
+// WriterCache keeps a Buffer per key created on demand by a factory, the least recently used
+// Buffers are closed when the maximum of open Buffers is reached or when they are idle. Get
+// pins the Buffer until Release is called, a pinned Buffer is never evicted.
+type WriterCache struct {
+	factory     func(key string) (*Buffer, error)
+	maxOpen     int
+	idleTimeout time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	// lru has the least recently used entry at the back
+	lru list.List
+	// closing has the evicted Buffers being closed, a Get of the key waits for it
+	closing map[string]chan struct{}
+	closed  bool
+	stop    chan struct{}
+	done    chan struct{}
+
+	stats CacheStats
+}
+
+type cacheEntry struct {
+	key     string
+	buf     *Buffer
+	elem    *list.Element
+	refs    int
+	opened  time.Time
+	lastUse time.Time
+	gets    int64
+	// unpinned is closed when refs reaches 0 and closed once the Buffer is closed, they are
+	// only set by CloseAll
+	unpinned chan struct{}
+	closed   chan struct{}
+	closeErr error
+}
+
+// CacheStats are the counters of a WriterCache
+type CacheStats struct {
+	// Open is the number of open Buffers and Pinned the ones in use
+	Open   int
+	Pinned int
+	// Gets is the number of Get calls and Opens the ones that created a Buffer
+	Gets  int64
+	Opens int64
+	// IdleEvictions and CapacityEvictions are the Buffers closed by the idle timeout and by
+	// the maximum of open Buffers
+	IdleEvictions     int64
+	CapacityEvictions int64
+	// CloseErrors is the number of evicted Buffers whose Close failed
+	CloseErrors int64
+}
+
+// CacheKeyStats are the counters of an open Buffer of a WriterCache
+type CacheKeyStats struct {
+	Gets    int64
+	Pinned  int
+	Opened  time.Time
+	LastUse time.Time
+	Buffer  Stats
+}
+
+// NewWriterCache returns a cache of the Buffers created by factory, maxOpen limits the open
+// Buffers and idleTimeout closes the Buffers not used within it, 0 disables any of them.
+// The factory is called with the cache locked, it must not use the cache.
+func NewWriterCache(factory func(key string) (*Buffer, error), maxOpen int, idleTimeout time.Duration) *WriterCache {
+	c := &WriterCache{
+		factory:     factory,
+		maxOpen:     maxOpen,
+		idleTimeout: idleTimeout,
+		entries:     make(map[string]*cacheEntry),
+		closing:     make(map[string]chan struct{}),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	if idleTimeout > 0 {
+		go c.evictLoop()
+	} else {
+		close(c.done)
+	}
+	return c
+}
+
+// Get returns the Buffer of key pinned, creating it if needed, Release must be called when
+// it's no longer used
+func (c *WriterCache) Get(key string) (*Buffer, error) {
+	c.mu.Lock()
+	for {
+		if c.closed {
+			c.mu.Unlock()
+			return nil, ErrWriteOnClosed
+		}
+		ch, ok := c.closing[key]
+		if !ok {
+			break
+		}
+		// the new Buffer can't write before the evicted one is flushed
+		c.mu.Unlock()
+		<-ch
+		c.mu.Lock()
+	}
+	c.stats.Gets++
+	e, ok := c.entries[key]
+	if !ok {
+		var evicted *cacheEntry
+		if c.maxOpen > 0 && len(c.entries) >= c.maxOpen {
+			if evicted = c.oldestUnpinned(); evicted == nil {
+				c.mu.Unlock()
+				return nil, ErrCacheFull
+			}
+			c.stats.CapacityEvictions++
+			c.remove(evicted)
+		}
+		buf, err := c.factory(key)
+		if err != nil {
+			c.mu.Unlock()
+			c.closeEvicted(evicted)
+			return nil, err
+		}
+		now := time.Now()
+		e = &cacheEntry{key: key, buf: buf, opened: now, lastUse: now}
+		e.elem = c.lru.PushFront(e)
+		c.entries[key] = e
+		c.stats.Opens++
+		defer c.closeEvicted(evicted)
+	}
+	c.lru.MoveToFront(e.elem)
+	e.refs++
+	e.gets++
+	e.lastUse = time.Now()
+	buf := e.buf
+	c.mu.Unlock()
+	return buf, nil
+}
+
+// Release unpins the Buffer of key returned by Get
+func (c *WriterCache) Release(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || e.refs == 0 {
+		panic("syncio: WriterCache.Release of a key not pinned: " + key)
+	}
+	e.refs--
+	e.lastUse = time.Now()
+	if e.refs == 0 && e.unpinned != nil {
+		close(e.unpinned)
+	}
+}
+
+// Write writes p to the Buffer of key
+func (c *WriterCache) Write(key string, p []byte) (int, error) {
+	buf, err := c.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Release(key)
+	return buf.Write(p)
+}
+
+// oldestUnpinned returns the least recently used entry that is not pinned or nil
+func (c *WriterCache) oldestUnpinned() *cacheEntry {
+	for el := c.lru.Back(); el != nil; el = el.Prev() {
+		if e := el.Value.(*cacheEntry); e.refs == 0 {
+			return e
+		}
+	}
+	return nil
+}
+
+// remove takes e out of the cache and marks its key as closing, the caller must call
+// closeEvicted without the lock
+func (c *WriterCache) remove(e *cacheEntry) {
+	c.lru.Remove(e.elem)
+	delete(c.entries, e.key)
+	c.closing[e.key] = make(chan struct{})
+}
+
+func (c *WriterCache) closeEvicted(e *cacheEntry) {
+	if e == nil {
+		return
+	}
+	err := e.buf.Close()
+
+	c.mu.Lock()
+	if err != nil {
+		c.stats.CloseErrors++
+	}
+	close(c.closing[e.key])
+	delete(c.closing, e.key)
+	c.mu.Unlock()
+}
+
+func (c *WriterCache) evictLoop() {
+	defer close(c.done)
+	t := time.NewTicker(c.idleTimeout / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case now := <-t.C:
+			for _, e := range c.evictIdle(now) {
+				c.closeEvicted(e)
+			}
+		}
+	}
+}
+
+func (c *WriterCache) evictIdle(now time.Time) []*cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		// CloseAll closes them
+		return nil
+	}
+	var evicted []*cacheEntry
+	for el := c.lru.Back(); el != nil; {
+		e := el.Value.(*cacheEntry)
+		el = el.Prev()
+		if e.refs == 0 && now.Sub(e.lastUse) >= c.idleTimeout {
+			c.stats.IdleEvictions++
+			c.remove(e)
+			evicted = append(evicted, e)
+		}
+	}
+	return evicted
+}
+
+// CloseAll closes all the Buffers, the pinned ones once they are released. Get fails after
+// it. If ctx is done before all the Buffers are closed its error is returned and they
+// are closed in background, otherwise the Close errors are returned in a *MultiError by key.
+func (c *WriterCache) CloseAll(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.stop)
+	}
+	entries := make([]*cacheEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+		if e.unpinned != nil {
+			// being closed by a previous CloseAll
+			continue
+		}
+		e.unpinned = make(chan struct{})
+		e.closed = make(chan struct{})
+		if e.refs == 0 {
+			close(e.unpinned)
+		}
+		go c.closeEntry(e)
+	}
+	closing := make([]chan struct{}, 0, len(c.closing))
+	for _, ch := range c.closing {
+		closing = append(closing, ch)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	var errs MultiError
+	for i, e := range entries {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.closed:
+			errs.add(e.key, i, e.closeErr)
+		}
+	}
+	for _, ch := range closing {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.done:
+	}
+	return errs.err()
+}
+
+// closeEntry closes the Buffer of e once it's unpinned
+func (c *WriterCache) closeEntry(e *cacheEntry) {
+	<-e.unpinned
+	e.closeErr = e.buf.Close()
+	c.mu.Lock()
+	c.lru.Remove(e.elem)
+	delete(c.entries, e.key)
+	c.mu.Unlock()
+	close(e.closed)
+}
+
+// Stats returns a copy of the cache counters
+func (c *WriterCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Open = len(c.entries)
+	for _, e := range c.entries {
+		if e.refs > 0 {
+			s.Pinned++
+		}
+	}
+	return s
+}
+
+// KeyStats returns the counters of the open Buffer of key, false if it's not open
+func (c *WriterCache) KeyStats(key string) (CacheKeyStats, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return CacheKeyStats{}, false
+	}
+	s := CacheKeyStats{Gets: e.gets, Pinned: e.refs, Opened: e.opened, LastUse: e.lastUse}
+	buf := e.buf
+	c.mu.Unlock()
+	buf.StatsInto(&s.Buffer)
+	return s, true
+}
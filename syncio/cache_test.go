@@ -0,0 +1,178 @@
+package syncio
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// cacheSinks creates a lockedBuffer per key
+type cacheSinks struct {
+	mu    sync.Mutex
+	sinks map[string]*lockedBuffer
+}
+
+func (s *cacheSinks) factory(key string) (*Buffer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sinks == nil {
+		s.sinks = make(map[string]*lockedBuffer)
+	}
+	w, ok := s.sinks[key]
+	if !ok {
+		w = &lockedBuffer{}
+		s.sinks[key] = w
+	}
+	return NewBuffer(w, SetBufferSize(64), SetFlushInterval(time.Hour)), nil
+}
+
+func (s *cacheSinks) String(key string) string {
+	s.mu.Lock()
+	w := s.sinks[key]
+	s.mu.Unlock()
+	return w.String()
+}
+
+func TestWriterCacheCapacity(t *testing.T) {
+	sinks := &cacheSinks{}
+	c := NewWriterCache(sinks.factory, 2, 0)
+
+	c.Write("a", []byte("a1"))
+	b, _ := c.Get("b")
+	b.Write([]byte("b1"))
+	// a is the least recently used
+	c.Write("c", []byte("c1"))
+	if s := sinks.String("a"); s != "a1" {
+		t.Errorf("evicted buffer data: %q, expected: %q", s, "a1")
+	}
+	if _, ok := c.KeyStats("a"); ok {
+		t.Error("evicted key is open")
+	}
+	// b is pinned, c is evicted
+	c.Write("a", []byte("a2"))
+	if s := sinks.String("c"); s != "c1" {
+		t.Errorf("evicted buffer data: %q, expected: %q", s, "c1")
+	}
+	c.Get("a")
+	if _, err := c.Get("d"); err != ErrCacheFull {
+		t.Errorf("get with pinned buffers: %v, expected: %v", err, ErrCacheFull)
+	}
+	c.Release("a")
+	c.Release("b")
+	// b is the least recently used
+	if _, err := c.Get("d"); err != nil {
+		t.Fatal(err)
+	}
+	c.Release("d")
+	if s := sinks.String("b"); s != "b1" {
+		t.Errorf("evicted buffer data: %q, expected: %q", s, "b1")
+	}
+
+	s := c.Stats()
+	if s.Open != 2 || s.Pinned != 0 || s.Opens != 5 || s.CapacityEvictions != 3 || s.Gets != 7 {
+		t.Errorf("stats: %+v", s)
+	}
+	if err := c.CloseAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := sinks.String("a"); s != "a1a2" {
+		t.Errorf("buffer data: %q, expected: %q", s, "a1a2")
+	}
+	if _, err := c.Get("a"); err != ErrWriteOnClosed {
+		t.Errorf("get after close: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+}
+
+func TestWriterCacheIdle(t *testing.T) {
+	sinks := &cacheSinks{}
+	c := NewWriterCache(sinks.factory, 0, 20*time.Millisecond)
+	defer c.CloseAll(context.Background())
+
+	c.Write("idle", []byte("x"))
+	b, _ := c.Get("pinned")
+	time.Sleep(100 * time.Millisecond)
+
+	if s := sinks.String("idle"); s != "x" {
+		t.Errorf("idle buffer data: %q, expected: %q", s, "x")
+	}
+	ks, ok := c.KeyStats("pinned")
+	if !ok || ks.Pinned != 1 || ks.Gets != 1 {
+		t.Errorf("pinned key stats: %+v, open: %v", ks, ok)
+	}
+	if _, err := b.Write([]byte("y")); err != nil {
+		t.Errorf("pinned buffer write: %v", err)
+	}
+	c.Release("pinned")
+	if s := c.Stats(); s.IdleEvictions != 1 || s.Open != 1 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+func TestWriterCacheCloseAllPinned(t *testing.T) {
+	sinks := &cacheSinks{}
+	c := NewWriterCache(sinks.factory, 0, 0)
+	b, _ := c.Get("a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.CloseAll(ctx); err != context.DeadlineExceeded {
+		t.Errorf("close all with a pinned buffer: %v, expected: %v", err, context.DeadlineExceeded)
+	}
+	if _, err := b.Write([]byte("a1")); err != nil {
+		t.Errorf("pinned buffer write: %v", err)
+	}
+	c.Release("a")
+	if err := c.CloseAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := sinks.String("a"); s != "a1" {
+		t.Errorf("buffer data: %q, expected: %q", s, "a1")
+	}
+}
+
+// TestWriterCacheConcurrent must run with the race detector, the evictions happen while
+// other keys are written
+func TestWriterCacheConcurrent(t *testing.T) {
+	sinks := &cacheSinks{}
+	c := NewWriterCache(sinks.factory, 3, time.Millisecond)
+
+	keys, writers, lines := 5, 8, 200
+	wg := sync.WaitGroup{}
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				key := strconv.Itoa((i + j) % keys)
+				for {
+					_, err := c.Write(key, []byte(strconv.Itoa(i)+"\n"))
+					if err == nil {
+						break
+					}
+					if err != ErrCacheFull {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := c.CloseAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for k := 0; k < keys; k++ {
+		total += bytes.Count([]byte(sinks.String(strconv.Itoa(k))), []byte("\n"))
+	}
+	if total != writers*lines {
+		t.Errorf("lines written: %v, expected: %v", total, writers*lines)
+	}
+	if s := c.Stats(); s.CloseErrors != 0 || s.Open != 0 {
+		t.Errorf("stats: %+v", s)
+	}
+}
@@ -0,0 +1,135 @@
+// Package checksum computes a running digest of the data written to or read from a stream,
+// e.g. the underlying writer of a syncio.Buffer, to verify it end to end without a second
+// pass over the data
+package checksum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+)
+
+// ErrMismatch is matched by the MismatchError of a digest different from the expected one
+var ErrMismatch = errors.New("checksum mismatch")
+
+// MismatchError is returned when the digest of the data isn't the expected one
+type MismatchError struct {
+	Expected []byte
+	Actual   []byte
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%v: %x, expected: %x", ErrMismatch, e.Actual, e.Expected)
+}
+
+// Unwrap returns ErrMismatch
+func (e *MismatchError) Unwrap() error {
+	return ErrMismatch
+}
+
+// digest is a hash guarded for Sum to be called while the data flows
+type digest struct {
+	mu sync.Mutex
+	h  hash.Hash
+	n  int64
+}
+
+func (d *digest) add(p []byte) {
+	d.mu.Lock()
+	d.h.Write(p)
+	d.n += int64(len(p))
+	d.mu.Unlock()
+}
+
+// Sum returns the digest of the data until now
+func (d *digest) Sum() []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.h.Sum(nil)
+}
+
+// SumHex returns the digest in hexadecimal, as printed by the sha256sum tools
+func (d *digest) SumHex() string {
+	return hex.EncodeToString(d.Sum())
+}
+
+// Len returns the size of the data until now
+func (d *digest) Len() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.n
+}
+
+// verify compares the digest with expected
+func (d *digest) verify(expected []byte) error {
+	if sum := d.Sum(); !bytes.Equal(sum, expected) {
+		return &MismatchError{Expected: expected, Actual: sum}
+	}
+	return nil
+}
+
+// Writer hashes the bytes written to the underlying writer, only the bytes it accepted. Sum
+// is safe to call concurrently with Write.
+type Writer struct {
+	w io.Writer
+	digest
+}
+
+// NewWriter returns a Writer writing to w and hashing with h, e.g. crc32.NewIEEE() or
+// sha256.New()
+func NewWriter(w io.Writer, h hash.Hash) *Writer {
+	return &Writer{w: w, digest: digest{h: h}}
+}
+
+// Write writes p to the underlying writer and hashes the bytes written
+func (w *Writer) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if n > 0 {
+		w.add(p[:n])
+	}
+	return n, err
+}
+
+// Verify returns a *MismatchError if the digest of the data written isn't expected
+func (w *Writer) Verify(expected []byte) error {
+	return w.verify(expected)
+}
+
+// Reader hashes the bytes read from the underlying reader and verifies them at the end of
+// the stream
+type Reader struct {
+	r        io.Reader
+	expected []byte
+	digest
+}
+
+// NewReader returns a Reader reading from r and hashing with h, the read returning io.EOF
+// returns a *MismatchError instead if the digest isn't expected. A nil expected isn't
+// verified.
+func NewReader(r io.Reader, h hash.Hash, expected []byte) *Reader {
+	return &Reader{r: r, expected: expected, digest: digest{h: h}}
+}
+
+// Read reads from the underlying reader and hashes the bytes read
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.add(p[:n])
+	}
+	if err == io.EOF && r.expected != nil {
+		if verr := r.verify(r.expected); verr != nil {
+			err = verr
+		}
+	}
+	return n, err
+}
+
+// Verify returns a *MismatchError if the digest of the data read isn't the expected one,
+// it's meant to check a stream not read until io.EOF
+func (r *Reader) Verify() error {
+	return r.verify(r.expected)
+}
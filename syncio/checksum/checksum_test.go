@@ -0,0 +1,94 @@
+package checksum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"hash/crc32"
+	"io"
+	"testing"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+// shortWriter accepts at most max bytes per write
+type shortWriter struct {
+	bytes.Buffer
+	max int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.max {
+		n, _ := w.Buffer.Write(p[:w.max])
+		return n, io.ErrShortWrite
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestWriterReader(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	expected := sha256.Sum256(data)
+
+	var out bytes.Buffer
+	w := NewWriter(&out, sha256.New())
+	tb := syncio.NewBuffer(w, syncio.SetBufferSize(256))
+	for i := 0; i < len(data); i += 70 {
+		end := i + 70
+		if end > len(data) {
+			end = len(data)
+		}
+		tb.Write(data[i:end])
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Verify(expected[:]); err != nil {
+		t.Error(err)
+	}
+	if w.Len() != int64(len(data)) {
+		t.Errorf("hashed %v bytes, expected: %v", w.Len(), len(data))
+	}
+
+	r := NewReader(&out, sha256.New(), w.Sum())
+	read, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(read, data) {
+		t.Errorf("read %v bytes: %v", len(read), err)
+	}
+	if r.SumHex() != w.SumHex() {
+		t.Errorf("reader sum %v, writer sum %v", r.SumHex(), w.SumHex())
+	}
+}
+
+func TestReaderMismatch(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte("tampered")), crc32.NewIEEE(), crc32.NewIEEE().Sum(nil))
+	_, err := io.ReadAll(r)
+	var merr *MismatchError
+	if !errors.As(err, &merr) || !errors.Is(err, ErrMismatch) {
+		t.Fatalf("read error: %v, expected a mismatch", err)
+	}
+	if !bytes.Equal(merr.Actual, r.Sum()) {
+		t.Errorf("actual %x, expected: %x", merr.Actual, r.Sum())
+	}
+	if err := r.Verify(); !errors.Is(err, ErrMismatch) {
+		t.Errorf("verify: %v", err)
+	}
+
+	// not verified without the expected digest
+	r = NewReader(bytes.NewReader([]byte("data")), crc32.NewIEEE(), nil)
+	if _, err := io.ReadAll(r); err != nil {
+		t.Errorf("read error without expected digest: %v", err)
+	}
+}
+
+func TestWriterShortWrite(t *testing.T) {
+	sink := &shortWriter{max: 3}
+	w := NewWriter(sink, crc32.NewIEEE())
+	if n, err := w.Write([]byte("abcdef")); n != 3 || err != io.ErrShortWrite {
+		t.Fatalf("write: %v, %v", n, err)
+	}
+	h := crc32.NewIEEE()
+	h.Write([]byte("abc"))
+	if err := w.Verify(h.Sum(nil)); err != nil {
+		t.Errorf("only the written bytes must be hashed: %v", err)
+	}
+}
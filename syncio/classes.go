@@ -0,0 +1,202 @@
+package syncio
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// MaxPriorityClasses is the maximum number of classes of SetPriorityClasses
+const MaxPriorityClasses = 8
+
+// ErrPriorityClass is returned by WriteClass with a class not set by SetPriorityClasses or
+// a write bigger than the memory of the classes
+var ErrPriorityClass = errors.New("invalid priority class write")
+
+// SetPriorityClasses enables WriteClass with a class per policy, class 0 being the lowest
+// priority, so several kinds of data share the batches of the underlying writer. The data of
+// the classes waits to be flushed in at most maxBytes: a write that doesn't fit evicts the
+// oldest data of the lower classes first, except the ones with OverflowBlock, then the policy
+// of its class is applied, it waits for the flushes, it's discarded or it evicts the oldest
+// data of its class. The class data is flushed by the ticks, Flush, Close and once it fills
+// a buffer, highest class first and in writes up to SetMaxFlushBytes when it's set.
+func SetPriorityClasses(maxBytes int64, policies ...OverflowPolicy) BufferOption {
+	if len(policies) == 0 || len(policies) > MaxPriorityClasses {
+		panic(fmt.Sprintf("syncio: %v priority classes, the range is 1 to %v", len(policies), MaxPriorityClasses))
+	}
+	for _, p := range policies {
+		mustValid(overflowPolicyNames, int(p), "OverflowPolicy")
+	}
+	if maxBytes <= 0 {
+		panic(fmt.Sprintf("syncio: invalid priority classes memory %v", maxBytes))
+	}
+	policies = append([]OverflowPolicy(nil), policies...)
+	return func(b *Buffer) {
+		b.classes = &classes{max: maxBytes, policies: policies, records: make([][][]byte, len(policies))}
+	}
+}
+
+// classes holds the data of SetPriorityClasses until it's taken by the flush goroutine, it's
+// guarded by bufmu. held is the size of the records and queued reports that the marker batch
+// of the class data is in the queue.
+type classes struct {
+	max      int64
+	policies []OverflowPolicy
+	records  [][][]byte
+	held     int64
+	queued   bool
+}
+
+// WriteClass writes p with the priority class of SetPriorityClasses, the bytes are copied.
+// The class data is written in the batches of its own, without ordering with the Write data.
+// A write discarded by the policy of its class returns no error and it's counted in
+// Stats.ClassDrops.
+func (tb *Buffer) WriteClass(class int, p []byte) (int, error) {
+	if !tb.initialized() {
+		return 0, ErrNotInitialized
+	}
+	c := tb.classes
+	if c == nil || class < 0 || class >= len(c.policies) {
+		return 0, fmt.Errorf("%w: class %v", ErrPriorityClass, class)
+	}
+	n := int64(len(p))
+	if n > c.max {
+		return 0, fmt.Errorf("%w: %v bytes over the memory of %v", ErrPriorityClass, n, c.max)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	tb.bufmu.Lock()
+	for !tb.writesClosed.Load() && c.held+n > c.max {
+		if c.evict(tb, class) {
+			continue
+		}
+		policy := c.policies[class]
+		if policy == OverflowDropOldest && len(c.records[class]) > 0 {
+			c.drop(tb, class)
+			continue
+		}
+		if policy != OverflowBlock {
+			// nothing older of the class to discard
+			tb.bufmu.Unlock()
+			atomic.AddInt64(&tb.stats.ClassBytes[class], n)
+			atomic.AddInt64(&tb.stats.ClassDrops[class], n)
+			tb.countError(ErrorDropped)
+			atomic.AddInt64(&tb.stats.CallerWrites, 1)
+			return len(p), nil
+		}
+		// the class data is flushed to make space
+		tb.queueClasses(TriggerSize)
+		if tb.synchronous {
+			tb.bufmu.Unlock()
+			tb.flushInline()
+			tb.bufmu.Lock()
+			continue
+		}
+		tb.space.Wait()
+	}
+	if tb.writesClosed.Load() {
+		tb.bufmu.Unlock()
+		tb.countError(ErrorClosed)
+		return 0, tb.closedErr()
+	}
+	rec := make([]byte, len(p))
+	copy(rec, p)
+	c.records[class] = append(c.records[class], rec)
+	c.held += n
+	tb.acct.accept(len(p))
+	atomic.AddInt64(&tb.stats.ClassBytes[class], n)
+	if c.held >= int64(tb.bufSize) {
+		tb.queueClasses(TriggerSize)
+	}
+	tb.bufmu.Unlock()
+	atomic.AddInt64(&tb.stats.CallerWrites, 1)
+	tb.flushInline()
+	return len(p), nil
+}
+
+// evict discards the oldest record of the lowest class below class without OverflowBlock,
+// it reports if there was one
+func (c *classes) evict(tb *Buffer, class int) bool {
+	for i := 0; i < class; i++ {
+		if c.policies[i] != OverflowBlock && len(c.records[i]) > 0 {
+			c.drop(tb, i)
+			return true
+		}
+	}
+	return false
+}
+
+// drop discards the oldest record of class
+func (c *classes) drop(tb *Buffer, class int) {
+	rec := c.records[class][0]
+	c.records[class][0] = nil
+	c.records[class] = c.records[class][1:]
+	c.held -= int64(len(rec))
+	tb.acct.remove(len(rec))
+	atomic.AddInt64(&tb.stats.ClassDrops[class], int64(len(rec)))
+	tb.countError(ErrorDropped)
+}
+
+// queueClasses enqueues the marker batch of the class data unless it's queued or there's no
+// data, the caller must hold bufmu
+func (tb *Buffer) queueClasses(trigger FlushTrigger) {
+	c := tb.classes
+	if c == nil || c.queued || c.held == 0 {
+		return
+	}
+	c.queued = true
+	tb.enqueue(&batch{trigger: trigger, classes: true})
+}
+
+// takeClasses returns the batch of the class data for the marker at the head of the queue,
+// highest class first up to SetMaxFlushBytes. It reports if the marker is done, otherwise it
+// stays at the head for the rest of the data. The caller must hold bufmu.
+func (tb *Buffer) takeClasses(marker *batch) (*batch, bool) {
+	c := tb.classes
+	size := c.held
+	if tb.maxFlushBytes > 0 && int64(tb.maxFlushBytes) < size {
+		size = int64(tb.maxFlushBytes)
+	}
+	p := make([]byte, 0, size)
+	full := false
+	for class := len(c.records) - 1; class >= 0 && !full; class-- {
+		recs := c.records[class]
+		i := 0
+		for ; i < len(recs); i++ {
+			if len(p) > 0 && int64(len(p)+len(recs[i])) > size {
+				full = true
+				break
+			}
+			p = append(p, recs[i]...)
+			recs[i] = nil
+		}
+		c.records[class] = recs[i:]
+	}
+	c.held -= int64(len(p))
+	if c.held == 0 {
+		c.queued = false
+	}
+	return &batch{p: p, trigger: marker.trigger}, !c.queued
+}
+
+// dropClasses discards the class data not yet taken by the flush goroutine, it returns the
+// data highest class first. The caller must hold bufmu.
+func (tb *Buffer) dropClasses() []byte {
+	c := tb.classes
+	if c == nil || c.held == 0 {
+		return nil
+	}
+	p := make([]byte, 0, c.held)
+	for class := len(c.records) - 1; class >= 0; class-- {
+		for _, rec := range c.records[class] {
+			p = append(p, rec...)
+		}
+		c.records[class] = nil
+	}
+	tb.acct.remove(len(p))
+	c.held = 0
+	// the queue with the marker is abandoned too
+	c.queued = false
+	return p
+}
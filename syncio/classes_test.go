@@ -0,0 +1,125 @@
+package syncio
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	classDebug = iota
+	classMetrics
+	classAudit
+)
+
+func TestPriorityClassesEviction(t *testing.T) {
+	inModes(t, testPriorityClassesEviction)
+}
+
+func testPriorityClassesEviction(t *testing.T, mode []BufferOption) {
+	tw := &lockedBuffer{}
+	tb := NewBuffer(tw, append([]BufferOption{
+		SetBufferSize(1024),
+		SetPriorityClasses(64, OverflowDropOldest, OverflowDropOldest, OverflowBlock),
+	}, mode...)...)
+
+	var want strings.Builder
+	for i := 0; i < 2; i++ {
+		tb.WriteClass(classAudit, []byte("audit-0123456789"))
+		want.WriteString("audit-0123456789")
+	}
+	var metrics strings.Builder
+	for i := 0; i < 2; i++ {
+		tb.WriteClass(classMetrics, []byte("metrics"+string(rune('0'+i))))
+		metrics.WriteString("metrics" + string(rune('0'+i)))
+	}
+	// the debug data only fits by evicting its own
+	for i := 0; i < 10; i++ {
+		tb.WriteClass(classDebug, []byte("debug-0"+string(rune('0'+i))))
+	}
+	// the memory is full, the audit data evicts the debug one
+	tb.WriteClass(classAudit, []byte("audit-0123456789"))
+	want.WriteString("audit-0123456789")
+	want.WriteString(metrics.String())
+
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := tw.String(); got != want.String() {
+		t.Fatalf("flushed %q, want %q", got, want.String())
+	}
+	s := tb.Stats()
+	if s.ClassDrops[classDebug] != 80 || s.ClassDrops[classMetrics] != 0 || s.ClassDrops[classAudit] != 0 {
+		t.Fatalf("drops by class %v", s.ClassDrops)
+	}
+	if s.ClassBytes[classDebug] != 80 || s.ClassBytes[classMetrics] != 16 || s.ClassBytes[classAudit] != 48 {
+		t.Fatalf("bytes by class %v", s.ClassBytes)
+	}
+}
+
+func TestPriorityClassesMaxFlushBytes(t *testing.T) {
+	rw := &recordingWriter{}
+	tb := NewBuffer(rw, SetBufferSize(1024), SetMaxFlushBytes(8),
+		SetPriorityClasses(1024, OverflowDropNewest, OverflowDropNewest, OverflowBlock))
+	tb.WriteClass(classDebug, []byte("dddd"))
+	tb.WriteClass(classMetrics, []byte("mmmm"))
+	tb.WriteClass(classAudit, []byte("aaaa"))
+	tb.WriteClass(classDebug, []byte("dddd"))
+	tb.WriteClass(classAudit, []byte("aaaa"))
+	if err := tb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"aaaaaaaa", "mmmmdddd", "dddd"}
+	if strings.Join(rw.writes, ",") != strings.Join(want, ",") {
+		t.Fatalf("writes %q, want %q", rw.writes, want)
+	}
+	tb.Close()
+}
+
+func TestPriorityClassesDropNewest(t *testing.T) {
+	tw := &lockedBuffer{}
+	tb := NewBuffer(tw, SetPriorityClasses(8, OverflowDropNewest, OverflowBlock))
+	tb.WriteClass(1, []byte("aaaaaa"))
+	if n, err := tb.WriteClass(0, []byte("bbbb")); n != 4 || err != nil {
+		t.Fatalf("dropped write: %v, %v", n, err)
+	}
+	tb.Close()
+	if got := tw.String(); got != "aaaaaa" {
+		t.Fatalf("flushed %q", got)
+	}
+	if d := tb.Stats().ClassDrops[0]; d != 4 {
+		t.Fatalf("%v bytes dropped", d)
+	}
+}
+
+func TestPriorityClassesBlock(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		tw := &lockedBuffer{}
+		tb := NewBuffer(tw, append([]BufferOption{SetBufferSize(1024), SetPriorityClasses(8, OverflowBlock)}, mode...)...)
+		// the second write waits for the first one to be flushed
+		tb.WriteClass(0, []byte("aaaaaa"))
+		tb.WriteClass(0, []byte("bbbbbb"))
+		tb.Close()
+		if got := tw.String(); got != "aaaaaabbbbbb" {
+			t.Fatalf("flushed %q", got)
+		}
+	})
+}
+
+func TestPriorityClassesInvalid(t *testing.T) {
+	tb := NewBuffer(&testWriter{}, SetPriorityClasses(8, OverflowBlock))
+	defer tb.Close()
+	for _, c := range []struct {
+		class int
+		p     string
+	}{{-1, "a"}, {1, "a"}, {0, "123456789"}} {
+		if _, err := tb.WriteClass(c.class, []byte(c.p)); !errors.Is(err, ErrPriorityClass) {
+			t.Errorf("class %v of %v bytes: %v", c.class, len(c.p), err)
+		}
+	}
+	plain := NewBuffer(&testWriter{})
+	defer plain.Close()
+	if _, err := plain.WriteClass(0, []byte("a")); !errors.Is(err, ErrPriorityClass) {
+		t.Errorf("without classes: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package syncio
+
+import "time"
+
+// Clock provides the time to a Buffer, it can be replaced to test the time based behaviors
+// without sleeping. NewTicker returns the channel where the ticks are delivered and a
+// function to stop it.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) (c <-chan time.Time, stop func())
+}
+
+// SetClock to replace the system clock
+func SetClock(c Clock) BufferOption {
+	return func(b *Buffer) {
+		b.clock = c
+	}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTicker(d)
+	return t.C, t.Stop
+}
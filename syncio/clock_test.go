@@ -0,0 +1,55 @@
+package syncio
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock is a manual Clock, the time only moves with Advance and the tickers
+// only tick with Tick
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []chan time.Time
+	// periods are the durations of the tickers
+	periods []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) (<-chan time.Time, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := make(chan time.Time)
+	c.tickers = append(c.tickers, t)
+	c.periods = append(c.periods, d)
+	return t, func() {}
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// ticker waits until the i-th ticker is created, the Buffer tickers are created
+// by the ticker goroutine
+func (c *fakeClock) ticker(i int) chan time.Time {
+	for {
+		c.mu.Lock()
+		if len(c.tickers) > i {
+			defer c.mu.Unlock()
+			return c.tickers[i]
+		}
+		c.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+}
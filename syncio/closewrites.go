@@ -0,0 +1,50 @@
+package syncio
+
+import "sync/atomic"
+
+// CloseWrites closes the Buffer for writing: the next writes fail with ErrWriteOnClosed while
+// the data already accepted keeps being flushed by the ticks, the full buffers and Flush. Close
+// must still be called to flush the rest and release the Buffer. Stats.PendingBytes reports the
+// data left from then on. Calling it again does nothing.
+func (tb *Buffer) CloseWrites() error {
+	if !tb.initialized() {
+		return ErrNotInitialized
+	}
+	tb.lockBuf()
+	defer tb.unlockBuf()
+	if tb.writesClosed.Load() {
+		return nil
+	}
+	// the fast paths are disabled from now on, the cursor isn't reopened
+	tb.writesClosed.Store(true)
+	if tb.singleWriter {
+		atomic.StoreInt32(&tb.state, stateClosed)
+	}
+	// writers waiting for space must see the writes closed
+	tb.space.Broadcast()
+	return nil
+}
+
+// WritesClosed reports if the writes fail because of CloseWrites or Close
+func (tb *Buffer) WritesClosed() bool {
+	return tb.writesClosed.Load()
+}
+
+// pendingSize returns the size of the data accepted and not yet sent to the flush goroutine,
+// the queued batches, the active buffer and the class data
+func (tb *Buffer) pendingSize() int64 {
+	// the lock free writes are only counted once the cursor is sealed
+	tb.lockBuf()
+	defer tb.unlockBuf()
+	n := int64(tb.buf.Buffered())
+	for _, b := range tb.queue {
+		n += int64(b.len())
+	}
+	if tb.classes != nil {
+		n += tb.classes.held
+	}
+	if tb.spill != nil {
+		n += tb.spill.size
+	}
+	return n
+}
@@ -0,0 +1,113 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloseWrites(t *testing.T) {
+	inModes(t, testCloseWrites)
+}
+
+func testCloseWrites(t *testing.T, mode []BufferOption) {
+	for _, opts := range [][]BufferOption{nil, {SetSingleWriter(true)}} {
+		tw := &lockedBuffer{}
+		tb := NewBuffer(tw, append(append([]BufferOption{SetBufferSize(16)}, opts...), mode...)...)
+		tb.Write([]byte("abc"))
+		if tb.WritesClosed() {
+			t.Fatal("writes closed before CloseWrites")
+		}
+		if err := tb.CloseWrites(); err != nil {
+			t.Fatal(err)
+		}
+		if err := tb.CloseWrites(); err != nil {
+			t.Fatal(err)
+		}
+		if !tb.WritesClosed() {
+			t.Fatal("writes not closed")
+		}
+		if _, err := tb.Write([]byte("def")); err != ErrWriteOnClosed {
+			t.Fatalf("Write after CloseWrites: %v", err)
+		}
+		if _, err := tb.Replay(strings.NewReader("ghi\n"), []byte("\n")); err != ErrWriteOnClosed {
+			t.Fatalf("Replay after CloseWrites: %v", err)
+		}
+		s := tb.Stats()
+		if s.PendingBytes != 3 {
+			t.Fatalf("pending %v bytes, want 3", s.PendingBytes)
+		}
+		if s.Errors[ErrorClosed] != 2 {
+			t.Fatalf("%v closed errors, want 2", s.Errors[ErrorClosed])
+		}
+
+		// the data accepted is still flushed
+		if err := tb.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if got := tw.String(); got != "abc" {
+			t.Fatalf("flushed %q", got)
+		}
+		if n := tb.Stats().PendingBytes; n != 0 {
+			t.Fatalf("pending %v bytes after Flush", n)
+		}
+		if err := tb.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCloseWritesTicks(t *testing.T) {
+	tw := &lockedBuffer{}
+	tb := NewBuffer(tw, SetBufferSize(1024), SetFlushInterval(10*time.Millisecond))
+	defer tb.Close()
+	tb.Write([]byte("abc"))
+	tb.CloseWrites()
+	deadline := time.Now().Add(5 * time.Second)
+	for tb.Stats().PendingBytes != 0 || tw.String() != "abc" {
+		if time.Now().After(deadline) {
+			t.Fatalf("not drained by the ticks: %q", tw.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCloseWritesWaitingWriter(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(1))
+	tb.Write([]byte("aaaa")) // in flight
+	time.Sleep(10 * time.Millisecond)
+	tb.Write([]byte("bbbb")) // queued by the next write
+	tb.Write([]byte("cccc"))
+
+	res := make(chan error, 1)
+	go func() {
+		_, err := tb.Write([]byte("dddd"))
+		res <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	tb.CloseWrites()
+	select {
+	case err := <-res:
+		if !errors.Is(err, ErrWriteOnClosed) {
+			t.Fatalf("waiting write: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting write not released by CloseWrites")
+	}
+
+	var dump bytes.Buffer
+	tb.DumpState(&dump)
+	if !strings.Contains(dump.String(), "writes closed: true") {
+		t.Fatalf("state not in the dump:\n%s", dump.String())
+	}
+	close(bw.release)
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := bw.out.String(); got != "aaaabbbbcccc" {
+		t.Fatalf("flushed %q", got)
+	}
+}
@@ -0,0 +1,34 @@
+package syncio
+
+// SetMaxFlushBytes lets the flush goroutine merge the queued batches in a single write of up to
+// n bytes, for sinks with a high cost per write. The batches are copied into a scratch buffer
+// of n bytes, or written with a single vectored write without copying them when the underlying
+// writer is a net.Conn or an *os.File on Unix; a net.Conn gets the vectored writes of the
+// queued batches without it too, see vectored.go. A batch is never split and one bigger than
+// n is written alone.
+// It has no effect with SetRecordMode or when writing into another Buffer.
+func SetMaxFlushBytes(n int) BufferOption {
+	return func(b *Buffer) {
+		b.maxFlushBytes = n
+	}
+}
+
+// coalesces reports if the queued batches can be merged
+func (tb *Buffer) coalesces() bool {
+	return tb.maxFlushBytes > 0 && !tb.recordMode && tb.parent == nil
+}
+
+// merge copies a group of batches into a single batch, the buffers of the group are sent back
+// to the pool. The last batch of the group is the only one that can be a barrier.
+func (tb *Buffer) merge(group []*batch) *batch {
+	p := tb.merged[:0]
+	for _, b := range group {
+		p = append(p, b.bytes()...)
+		if b.buf != nil {
+			tb.putBuffer(b.buf)
+		}
+	}
+	tb.merged = p
+	last := group[len(group)-1]
+	return &batch{p: p, scratch: true, walOff: group[0].walOff, trigger: group[0].trigger, done: last.done, swap: last.swap, seek: last.seek, sync: last.sync}
+}
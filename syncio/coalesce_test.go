@@ -0,0 +1,77 @@
+package syncio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// recordingWriter keeps every write separately
+type recordingWriter struct {
+	writes []string
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestMaxFlushBytes(t *testing.T) {
+	release := make(chan struct{})
+	rw := &recordingWriter{}
+	// the first write is held while the next batches are queued
+	tb := NewBuffer(writerFunc(func(p []byte) (int, error) {
+		if len(rw.writes) == 0 {
+			<-release
+		}
+		return rw.Write(p)
+	}), SetBufferSize(5), SetBufferPoolSize(8), SetMaxFlushBytes(10))
+
+	// every write flushes the previous one
+	tb.Write([]byte("aaaa"))
+	tb.Write([]byte("bbbb"))
+	time.Sleep(10 * time.Millisecond)
+	for _, w := range []string{"cccc", "dddd", "eeeeeeeeeeee", "ff"} {
+		tb.Write([]byte(w))
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	tb.Close()
+
+	expected := []string{"aaaa", "bbbbcccc", "dddd", "eeeeeeeeeeee", "ff"}
+	if len(rw.writes) != len(expected) {
+		t.Fatalf("writes: %q, expected: %q", rw.writes, expected)
+	}
+	for i := range expected {
+		if rw.writes[i] != expected[i] {
+			t.Errorf("writes: %q, expected: %q", rw.writes, expected)
+			break
+		}
+	}
+	if s := tb.Stats(); s.Batches != 6 || s.Flushes != 5 {
+		t.Errorf("batches: %v, flushes: %v, expected: 6, 5", s.Batches, s.Flushes)
+	}
+}
+
+func TestMaxFlushBytesBarrier(t *testing.T) {
+	var out bytes.Buffer
+	tb := NewBuffer(&out, SetBufferSize(4), SetMaxFlushBytes(1024))
+	for i := 0; i < 100; i++ {
+		tb.Write([]byte("abc"))
+		if i%10 == 0 {
+			if err := tb.Flush(); err != nil {
+				t.Fatalf("flush: %v", err)
+			}
+		}
+	}
+	tb.Close()
+	if out.String() != string(bytes.Repeat([]byte("abc"), 100)) {
+		t.Errorf("written: %q", out.String())
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,146 @@
+package syncio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownCodec is returned by NewCodecReader with a name not registered
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// Codec is a block compression format registered by name, e.g. snappy, lz4 or zstd with a
+// dictionary: it compresses the batches as a Compressor and decompresses them for CodecReader.
+// Decompress appends the decompressed src to dst and returns it.
+type Codec interface {
+	Compressor
+	Decompress(dst, src []byte) ([]byte, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	// codecs are the registered codecs by name, gzip is built in
+	codecs = map[string]Codec{"gzip": Gzip.(Codec)}
+)
+
+// RegisterCodec makes c available by name to SetCodec and NewCodecReader, usually from the init
+// of the package providing it. It panics with an empty name, a nil codec or a name already
+// registered.
+func RegisterCodec(name string, c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if name == "" || c == nil {
+		panic("syncio: RegisterCodec with an empty name or a nil codec")
+	}
+	if _, dup := codecs[name]; dup {
+		panic(fmt.Sprintf("syncio: RegisterCodec called twice for %q", name))
+	}
+	codecs[name] = c
+}
+
+// LookupCodec returns the codec registered as name
+func LookupCodec(name string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[name]
+	return c, ok
+}
+
+// Codecs returns the names of the registered codecs, sorted
+func Codecs() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetCodec is SetCompression with the codec registered as name, it panics with a name not
+// registered. The batches of a block codec can't be told apart once concatenated: with
+// SetFraming every one is a frame that CodecReader decompresses.
+func SetCodec(name string) BufferOption {
+	c, ok := LookupCodec(name)
+	if !ok {
+		panic(fmt.Sprintf("syncio: %v %q", ErrUnknownCodec, name))
+	}
+	return func(b *Buffer) {
+		b.compressor = c
+		b.codecName = name
+	}
+}
+
+// Decompress decompresses the gzip members of src
+func (c *gzipCompressor) Decompress(dst, src []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return dst, err
+	}
+	out := bytes.NewBuffer(dst)
+	if _, err := io.Copy(out, zr); err != nil {
+		return dst, err
+	}
+	return out.Bytes(), nil
+}
+
+// CodecReader reads the records of a stream written with SetFraming, the ones of the frames
+// with FrameCompressed are decompressed by a Codec. The frames are validated by a
+// BatchReader. It's not safe for concurrent use.
+type CodecReader struct {
+	frames *BatchReader
+	codec  Codec
+	// frame is the frame being read and next its next record, rest is the unread data of the
+	// current record and buf the memory of the decompressed ones
+	frame *Frame
+	next  int
+	rest  []byte
+	buf   []byte
+}
+
+var _ io.Reader = &CodecReader{}
+
+// NewCodecReader returns a reader of the frames of r decompressed with the codec registered as
+// name, ErrUnknownCodec if there is none
+func NewCodecReader(r io.Reader, name string) (*CodecReader, error) {
+	c, ok := LookupCodec(name)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCodec, name)
+	}
+	return &CodecReader{frames: NewBatchReader(r), codec: c}, nil
+}
+
+// Read reads the records concatenated, it returns io.EOF at the end of the stream between
+// frames and the errors of BatchReader and the codec
+func (r *CodecReader) Read(p []byte) (int, error) {
+	for len(r.rest) == 0 {
+		if r.frame == nil || r.next == len(r.frame.Records) {
+			f, err := r.frames.Next()
+			if err != nil {
+				r.frame = nil
+				return 0, err
+			}
+			r.frame, r.next = f, 0
+			continue
+		}
+		rec := r.frame.Records[r.next]
+		r.next++
+		if r.frame.Flags&FrameCompressed == 0 {
+			r.rest = rec
+			continue
+		}
+		var err error
+		if r.buf, err = r.codec.Decompress(r.buf[:0], rec); err != nil {
+			return 0, err
+		}
+		r.rest = r.buf
+	}
+	n := copy(p, r.rest)
+	r.rest = r.rest[n:]
+	return n, nil
+}
@@ -0,0 +1,99 @@
+package syncio
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// flateCodec is a block codec as the third party ones
+type flateCodec struct{}
+
+func (flateCodec) Compress(dst, src []byte) ([]byte, error) {
+	out := bytes.NewBuffer(dst)
+	zw, _ := flate.NewWriter(out, flate.BestSpeed)
+	zw.Write(src)
+	if err := zw.Close(); err != nil {
+		return dst, err
+	}
+	return out.Bytes(), nil
+}
+
+func (flateCodec) Decompress(dst, src []byte) ([]byte, error) {
+	out := bytes.NewBuffer(dst)
+	if _, err := io.Copy(out, flate.NewReader(bytes.NewReader(src))); err != nil {
+		return dst, err
+	}
+	return out.Bytes(), nil
+}
+
+func init() {
+	RegisterCodec("test-flate", flateCodec{})
+}
+
+func TestCodec(t *testing.T) {
+	for _, name := range []string{"gzip", "test-flate"} {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			tb := NewBuffer(&out, SetBufferSize(64), SetFraming(FrameChecksum), SetCodec(name))
+			data := strings.Repeat("some log line\n", 20)
+			tb.Write([]byte(data))
+			tb.Write([]byte("abc"))
+			tb.Close()
+			if s := tb.Stats(); s.Flushes < 2 {
+				t.Errorf("flushes: %v, expected 2 at least", s.Flushes)
+			}
+
+			r, err := NewCodecReader(&out, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(p) != data+"abc" {
+				t.Errorf("decompressed: %q, expected: %q", p, data+"abc")
+			}
+		})
+	}
+}
+
+func TestCodecReaderUncompressed(t *testing.T) {
+	var out bytes.Buffer
+	tb := NewBuffer(&out, SetRecordMode(true), SetFraming(0))
+	tb.Write([]byte("one\n"))
+	tb.Write([]byte("two\n"))
+	tb.Close()
+	r, _ := NewCodecReader(&out, "gzip")
+	if p, err := io.ReadAll(r); err != nil || string(p) != "one\ntwo\n" {
+		t.Errorf("read: %q, %v, expected: %q", p, err, "one\ntwo\n")
+	}
+}
+
+func TestCodecRegistry(t *testing.T) {
+	if names := Codecs(); len(names) < 2 || names[0] != "gzip" || names[1] != "test-flate" {
+		t.Errorf("codecs: %v, expected gzip and test-flate", names)
+	}
+	if _, err := NewCodecReader(nil, "missing"); !errors.Is(err, ErrUnknownCodec) {
+		t.Errorf("reader of an unknown codec: %v, expected: %v", err, ErrUnknownCodec)
+	}
+	for name, register := range map[string]func(){
+		"duplicate": func() { RegisterCodec("gzip", flateCodec{}) },
+		"empty":     func() { RegisterCodec("", flateCodec{}) },
+		"nil":       func() { RegisterCodec("nil", nil) },
+		"unknown":   func() { SetCodec("missing") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%v codec accepted", name)
+				}
+			}()
+			register()
+		}()
+	}
+}
@@ -0,0 +1,60 @@
+package syncio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"sync"
+)
+
+// Compressor compresses the batches of SetCompression, Compress appends the compressed src to
+// dst and returns it. It's called from the flush goroutines of every Buffer using it.
+type Compressor interface {
+	Compress(dst, src []byte) ([]byte, error)
+}
+
+// Gzip is the gzip Compressor with the default compression level
+var Gzip Compressor = GzipLevel(gzip.DefaultCompression)
+
+// GzipLevel returns a gzip Compressor with the level of compress/gzip, it panics with an
+// invalid level. Every batch is a gzip member, their concatenation is read by gzip.Reader.
+func GzipLevel(level int) Compressor {
+	if _, err := gzip.NewWriterLevel(nil, level); err != nil {
+		panic(fmt.Sprintf("syncio: %v", err))
+	}
+	return &gzipCompressor{level: level}
+}
+
+// gzipCompressor reuses the gzip writers, their state is most of the cost of a small batch
+type gzipCompressor struct {
+	level   int
+	writers sync.Pool
+}
+
+func (c *gzipCompressor) Compress(dst, src []byte) ([]byte, error) {
+	out := bytes.NewBuffer(dst)
+	zw, _ := c.writers.Get().(*gzip.Writer)
+	if zw == nil {
+		zw, _ = gzip.NewWriterLevel(out, c.level)
+	} else {
+		zw.Reset(out)
+	}
+	defer c.writers.Put(zw)
+	if _, err := zw.Write(src); err != nil {
+		return dst, err
+	}
+	if err := zw.Close(); err != nil {
+		return dst, err
+	}
+	return out.Bytes(), nil
+}
+
+// SetCompression compresses every batch with c after SetFlushTransform and before it's
+// written, into the pool buffers. With SetFraming the frames have FrameCompressed. As the
+// transform, it has no effect with SetRecordMode or when writing into another Buffer.
+func SetCompression(c Compressor) BufferOption {
+	return func(b *Buffer) {
+		b.compressor = c
+		b.codecName = ""
+	}
+}
@@ -0,0 +1,70 @@
+package syncio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		var out bytes.Buffer
+		upper := func(dst, src []byte) ([]byte, error) {
+			return append(dst, bytes.ToUpper(src)...), nil
+		}
+		tb := NewBuffer(&out, append([]BufferOption{SetBufferSize(64), SetFlushTransform(upper), SetCompression(Gzip)}, mode...)...)
+		data := strings.Repeat("some log line\n", 20)
+		tb.Write([]byte(data))
+		tb.Write([]byte("abc"))
+		tb.Flush()
+		tb.Write([]byte("def\n"))
+		tb.Close()
+
+		if s := tb.Stats(); s.Flushes < 3 {
+			t.Errorf("flushes: %v, expected 3 at least", s.Flushes)
+		}
+		zr, err := gzip.NewReader(&out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := strings.ToUpper(data + "abcdef\n"); string(p) != expected {
+			t.Errorf("decompressed: %q, expected: %q", p, expected)
+		}
+	})
+}
+
+func TestCompressionFraming(t *testing.T) {
+	var out bytes.Buffer
+	tb := NewBuffer(&out, SetFraming(FrameChecksum), SetCompression(GzipLevel(gzip.BestSpeed)))
+	tb.Write([]byte("abc"))
+	tb.Close()
+	f, _, err := ParseFrame(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Flags != FrameChecksum|FrameCompressed || len(f.Records) != 1 {
+		t.Fatalf("frame: %+v, expected a compressed record", f)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(f.Records[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := io.ReadAll(zr); string(p) != "abc" {
+		t.Errorf("record: %q, expected: %q", p, "abc")
+	}
+}
+
+func TestGzipLevelInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("invalid level accepted")
+		}
+	}()
+	GzipLevel(42)
+}
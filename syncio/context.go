@@ -0,0 +1,98 @@
+package syncio
+
+import (
+	"context"
+	"io"
+)
+
+// ContextWriter is implemented by the underlying writers that receive a context with every write,
+// e.g. to carry deadlines or tracing metadata
+type ContextWriter interface {
+	WriteContext(ctx context.Context, p []byte) (int, error)
+}
+
+// SetFlushContext sets a function called before every flush to get the context passed to the
+// underlying writer if it implements ContextWriter, other writers are not affected. CloseTimeout
+// cancels the context of the write in flight at the deadline.
+func SetFlushContext(fn func() context.Context) BufferOption {
+	return func(b *Buffer) {
+		b.flushContext = fn
+	}
+}
+
+// sink returns the writer used for a flush
+func (tb *Buffer) sink() io.Writer {
+	if tb.sinkCtx != nil {
+		return contextWriter{tb.writer.(ContextWriter), tb.sinkCtx}
+	}
+	if tb.flushContext != nil {
+		if cw, ok := tb.writer.(ContextWriter); ok {
+			return contextWriter{cw, tb.flushContext()}
+		}
+	}
+	return tb.writer
+}
+
+// contextWriter adapts a ContextWriter to io.Writer
+type contextWriter struct {
+	w   ContextWriter
+	ctx context.Context
+}
+
+func (c contextWriter) Write(p []byte) (int, error) {
+	return c.w.WriteContext(c.ctx, p)
+}
+
+// WriteContext is Write aborted with the ctx error when ctx is done while the write waits for
+// space in a full pool or for the backlog to drain, nothing of p is written then. A write
+// already copied to the buffer isn't undone, in synchronous mode the inline flush isn't
+// interrupted, SetFlushContext bounds it.
+func (tb *Buffer) WriteContext(ctx context.Context, p []byte) (int, error) {
+	if !tb.initialized() {
+		return 0, ErrNotInitialized
+	}
+	if ctx.Done() == nil {
+		return tb.Write(p)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return tb.writeCounted(ctx, p, &tb.stats.CallerWrites)
+}
+
+// spaceWatch wakes up the writers waiting for space when the context of a WriteContext is done
+type spaceWatch struct {
+	done chan struct{}
+}
+
+// waitSpace waits on space, the caller must hold bufmu. With a ctx it returns the ctx error
+// instead of waiting once ctx is done, the first wait starts the watch.
+func (tb *Buffer) waitSpace(ctx context.Context, w *spaceWatch) error {
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if w.done == nil {
+			w.done = make(chan struct{})
+			go func(done <-chan struct{}) {
+				select {
+				case <-ctx.Done():
+					// bufmu orders the broadcast after the Wait of the writer
+					tb.bufmu.Lock()
+					tb.space.Broadcast()
+					tb.bufmu.Unlock()
+				case <-done:
+				}
+			}(w.done)
+		}
+	}
+	tb.space.Wait()
+	return nil
+}
+
+// stop ends the watch
+func (w *spaceWatch) stop() {
+	if w.done != nil {
+		close(w.done)
+	}
+}
@@ -0,0 +1,93 @@
+package syncio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type ctxKey struct{}
+
+// contextSink keeps the context values received
+type contextSink struct {
+	values []any
+	writes int
+}
+
+func (s *contextSink) Write(p []byte) (int, error) {
+	s.writes++
+	return len(p), nil
+}
+
+func (s *contextSink) WriteContext(ctx context.Context, p []byte) (int, error) {
+	s.values = append(s.values, ctx.Value(ctxKey{}))
+	return len(p), nil
+}
+
+func TestFlushContext(t *testing.T) {
+	sink := &contextSink{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "trace")
+	tb := NewBuffer(sink, SetFlushContext(func() context.Context { return ctx }))
+	tb.Write([]byte("a"))
+	tb.Flush()
+	tb.Write([]byte("b"))
+	tb.Close()
+
+	if len(sink.values) != 2 || sink.values[0] != "trace" || sink.values[1] != "trace" || sink.writes != 0 {
+		t.Errorf("context values: %v, plain writes: %v, expected 2 writes with the context", sink.values, sink.writes)
+	}
+}
+
+func TestFlushContextUnset(t *testing.T) {
+	sink := &contextSink{}
+	tb := NewBuffer(sink)
+	tb.Write([]byte("a"))
+	tb.Close()
+	if len(sink.values) != 0 || sink.writes != 1 {
+		t.Errorf("context writes: %v, plain writes: %v, expected a plain write", len(sink.values), sink.writes)
+	}
+}
+
+func TestWriteContext(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(1))
+
+	tb.Write([]byte("aaa")) // in flight once the next write fills the buffer
+	tb.Write([]byte("bbb"))
+	time.Sleep(10 * time.Millisecond)
+	tb.Write([]byte("ccc")) // fills the pool
+
+	ctx, cancel := context.WithCancel(context.Background())
+	res := make(chan error, 1)
+	go func() {
+		_, err := tb.WriteContext(ctx, []byte("ddd"))
+		res <- err
+	}()
+	select {
+	case err := <-res:
+		t.Fatalf("write didn't wait for space: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	cancel()
+	select {
+	case err := <-res:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("write error: %v, expected: %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("write not aborted by the context")
+	}
+	if _, err := tb.WriteContext(ctx, []byte("eee")); !errors.Is(err, context.Canceled) {
+		t.Errorf("write with done context: %v, expected: %v", err, context.Canceled)
+	}
+
+	close(bw.release)
+	if n, err := tb.WriteContext(context.Background(), []byte("fff")); n != 3 || err != nil {
+		t.Errorf("write: %v, %v, expected: 3, nil", n, err)
+	}
+	tb.Close()
+	if bw.out.String() != "aaabbbcccfff" {
+		t.Errorf("written: %q, expected: %q", bw.out.String(), "aaabbbcccfff")
+	}
+}
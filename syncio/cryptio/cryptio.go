@@ -0,0 +1,270 @@
+// Package cryptio encrypts a stream in authenticated chunks with AES-GCM, e.g. the underlying
+// writer of a syncio.Buffer: every Write, a flushed batch, is sealed as a chunk with its own
+// nonce and Reader opens them back in order. The chunks are sealed into a buffer reused by the
+// next writes so the flushes don't allocate.
+//
+// The stream starts with the version and a random nonce prefix, every chunk is its sealed
+// length, big endian uint32, and the sealed data. The nonce of a chunk is the prefix and its
+// number, so the chunks can't be reordered, and Close seals an empty last chunk so a
+// truncated stream is detected.
+package cryptio
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MaxChunkSize is the maximum size of the data of a chunk, the bigger writes are split
+const MaxChunkSize = 1 << 20
+
+const (
+	version    = 1
+	prefixSize = 8
+	headerSize = 1 + prefixSize
+	// the nonce is the prefix and the big endian chunk number
+	nonceSize = prefixSize + 4
+	// the additional data of a chunk marks the last one
+	dataChunk = 0
+	lastChunk = 1
+)
+
+var (
+	// ErrClosed is returned by the writes of a closed Writer
+	ErrClosed = errors.New("cryptio: write on closed writer")
+	// ErrAuth is matched by the errors of the chunks that couldn't be authenticated, the
+	// key is wrong or the data was modified
+	ErrAuth = errors.New("cryptio: message authentication failed")
+	// ErrFormat is matched by the errors of a stream that isn't a cryptio stream
+	ErrFormat = errors.New("cryptio: invalid stream")
+	// errTooManyChunks is returned when the chunk numbers are exhausted
+	errTooManyChunks  = errors.New("cryptio: too many chunks")
+	errNotInitialized = errors.New("cryptio: not initialized")
+)
+
+// newAEAD returns the AES-GCM of key, 16, 24 or 32 bytes for AES-128, AES-192 or AES-256
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, nonceSize)
+}
+
+// nonce numbers the chunks of a stream
+type nonce struct {
+	b     [nonceSize]byte
+	chunk uint64
+}
+
+// next returns the nonce of the next chunk
+func (n *nonce) next() ([]byte, error) {
+	if n.chunk > 1<<32-1 {
+		return nil, errTooManyChunks
+	}
+	binary.BigEndian.PutUint32(n.b[prefixSize:], uint32(n.chunk))
+	n.chunk++
+	return n.b[:], nil
+}
+
+// Writer encrypts the writes, every Write is a chunk written with a single Write to the
+// underlying writer. It's safe for concurrent use. Once a write to the underlying writer
+// fails the stream is broken and the next writes return the error.
+type Writer struct {
+	w    io.Writer
+	aead cipher.AEAD
+
+	mu      sync.Mutex
+	nonce   nonce
+	started bool
+	closed  bool
+	err     error
+	// buf is the sealed chunk, reused by every write
+	buf []byte
+	ad  [1]byte
+}
+
+// NewWriter returns a Writer encrypting the data written to w with key, 16, 24 or 32 bytes for
+// AES-128, AES-192 or AES-256
+func NewWriter(w io.Writer, key []byte) (*Writer, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	cw := &Writer{w: w, aead: aead}
+	if _, err := io.ReadFull(rand.Reader, cw.nonce.b[:prefixSize]); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// Write seals p in a chunk, in several if it's bigger than MaxChunkSize
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.aead == nil {
+		return 0, errNotInitialized
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > MaxChunkSize {
+			n = MaxChunkSize
+		}
+		if err := w.seal(p[:n], dataChunk); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// seal writes p as a chunk, the first one after the stream header
+func (w *Writer) seal(p []byte, kind byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	nonce, err := w.nonce.next()
+	if err != nil {
+		w.err = err
+		return err
+	}
+	w.buf = w.buf[:0]
+	if !w.started {
+		w.buf = append(w.buf, version)
+		w.buf = append(w.buf, nonce[:prefixSize]...)
+	}
+	start := len(w.buf) + 4
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(len(p)+w.aead.Overhead()))
+	w.ad[0] = kind
+	w.buf = w.aead.Seal(w.buf[:start], nonce, p, w.ad[:])
+	if _, err := w.w.Write(w.buf); err != nil {
+		w.err = err
+		return err
+	}
+	w.started = true
+	return nil
+}
+
+// Close writes the last chunk and closes the underlying writer if it's an io.Closer, the
+// next writes fail with ErrClosed
+func (w *Writer) Close() error {
+	if w.aead == nil {
+		return errNotInitialized
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+	err := w.seal(nil, lastChunk)
+	if c, ok := w.w.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Reader decrypts a stream written by Writer, it returns io.ErrUnexpectedEOF if the stream
+// ends before the last chunk. The reads are not safe for concurrent use.
+type Reader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	nonce nonce
+	// buf is the opened chunk and rest its unread data
+	buf  []byte
+	rest []byte
+	err  error
+	hdr  [headerSize]byte
+	ad   [1]byte
+}
+
+// NewReader returns a Reader decrypting the stream read from r with key
+func NewReader(r io.Reader, key []byte) (*Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{r: r, aead: aead}, nil
+}
+
+// Read reads the decrypted data, the data of a chunk is only returned once authenticated
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.aead == nil {
+		return 0, errNotInitialized
+	}
+	for len(r.rest) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.open()
+	}
+	n := copy(p, r.rest)
+	r.rest = r.rest[n:]
+	return n, nil
+}
+
+// open reads and opens the next chunk, it returns io.EOF after the last one
+func (r *Reader) open() error {
+	if r.nonce.chunk == 0 {
+		if _, err := io.ReadFull(r.r, r.hdr[:]); err != nil {
+			return unexpected(err)
+		}
+		if r.hdr[0] != version {
+			return fmt.Errorf("%w: version %v", ErrFormat, r.hdr[0])
+		}
+		copy(r.nonce.b[:prefixSize], r.hdr[1:])
+	}
+	if _, err := io.ReadFull(r.r, r.hdr[:4]); err != nil {
+		return unexpected(err)
+	}
+	size := int(binary.BigEndian.Uint32(r.hdr[:4]))
+	if size < r.aead.Overhead() || size > MaxChunkSize+r.aead.Overhead() {
+		return fmt.Errorf("%w: chunk of %v bytes", ErrFormat, size)
+	}
+	if cap(r.buf) < size {
+		r.buf = make([]byte, size)
+	}
+	r.buf = r.buf[:size]
+	if _, err := io.ReadFull(r.r, r.buf); err != nil {
+		return unexpected(err)
+	}
+	nonce, err := r.nonce.next()
+	if err != nil {
+		return err
+	}
+	// only the last chunk is empty, its kind is authenticated as additional data so a data
+	// chunk can't be passed as the last one
+	r.ad[0] = dataChunk
+	if size == r.aead.Overhead() {
+		r.ad[0] = lastChunk
+	}
+	p, err := r.aead.Open(r.buf[:0], nonce, r.buf, r.ad[:])
+	if err != nil {
+		return fmt.Errorf("%w: chunk %v", ErrAuth, r.nonce.chunk-1)
+	}
+	if r.ad[0] == lastChunk {
+		return io.EOF
+	}
+	r.rest = p
+	return nil
+}
+
+// unexpected returns the read error of a stream ended before its last chunk
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package cryptio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+var key = bytes.Repeat([]byte{7}, 32)
+
+func TestRoundTrip(t *testing.T) {
+	var stream bytes.Buffer
+	w, err := NewWriter(&stream, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb := syncio.NewBuffer(w, syncio.SetBufferSize(64))
+	var expected []byte
+	for i := 0; i < 100; i++ {
+		p := bytes.Repeat([]byte{byte(i)}, i)
+		tb.Write(p)
+		expected = append(expected, p...)
+	}
+	big := bytes.Repeat([]byte("x"), MaxChunkSize+10)
+	tb.Write(big)
+	expected = append(expected, big...)
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("x")); err != ErrClosed {
+		t.Errorf("write after close: %v", err)
+	}
+	if bytes.Contains(stream.Bytes(), bytes.Repeat([]byte{99}, 99)) {
+		t.Error("plain text in the stream")
+	}
+
+	r, err := NewReader(bytes.NewReader(stream.Bytes()), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("read %v bytes, expected %v", len(got), len(expected))
+	}
+}
+
+// sealed returns a stream of the chunks
+func sealed(t *testing.T, chunks ...string) []byte {
+	var stream bytes.Buffer
+	w, err := NewWriter(&stream, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range chunks {
+		w.Write([]byte(c))
+	}
+	w.Close()
+	return stream.Bytes()
+}
+
+func readAll(stream []byte, key []byte) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(stream), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestReaderErrors(t *testing.T) {
+	stream := sealed(t, "hello", "world")
+	if p, err := readAll(stream, key); err != nil || string(p) != "helloworld" {
+		t.Fatalf("read %q, %v", p, err)
+	}
+
+	wrong := bytes.Repeat([]byte{8}, 32)
+	if _, err := readAll(stream, wrong); !errors.Is(err, ErrAuth) {
+		t.Errorf("wrong key: %v", err)
+	}
+
+	tampered := append([]byte(nil), stream...)
+	tampered[headerSize+4] ^= 1
+	if _, err := readAll(tampered, key); !errors.Is(err, ErrAuth) {
+		t.Errorf("tampered: %v", err)
+	}
+
+	// without the last chunk
+	last := 4 + 16
+	if p, err := readAll(stream[:len(stream)-last], key); err != io.ErrUnexpectedEOF || string(p) != "helloworld" {
+		t.Errorf("truncated: %q, %v", p, err)
+	}
+	if _, err := readAll(nil, key); err != io.ErrUnexpectedEOF {
+		t.Errorf("empty stream: %v", err)
+	}
+
+	// the chunks swapped
+	chunk := 4 + 5 + 16
+	swapped := append([]byte(nil), stream[:headerSize]...)
+	swapped = append(swapped, stream[headerSize+chunk:headerSize+2*chunk]...)
+	swapped = append(swapped, stream[headerSize:headerSize+chunk]...)
+	swapped = append(swapped, stream[headerSize+2*chunk:]...)
+	if _, err := readAll(swapped, key); !errors.Is(err, ErrAuth) {
+		t.Errorf("swapped chunks: %v", err)
+	}
+
+	bad := append([]byte(nil), stream...)
+	bad[0] = 9
+	if _, err := readAll(bad, key); !errors.Is(err, ErrFormat) {
+		t.Errorf("version: %v", err)
+	}
+
+	if _, err := NewWriter(io.Discard, []byte("short")); err == nil {
+		t.Error("invalid key accepted")
+	}
+}
+
+func TestWriteAllocs(t *testing.T) {
+	w, err := NewWriter(io.Discard, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, 4096)
+	w.Write(p)
+	if allocs := testing.AllocsPerRun(100, func() { w.Write(p) }); allocs != 0 {
+		t.Errorf("%v allocs per write", allocs)
+	}
+}
@@ -0,0 +1,21 @@
+package syncio
+
+import "io"
+
+// SetDeadLetter sets a writer to receive the data discarded by the Buffer: the bytes not written
+// after a flush error and the batches abandoned by CloseTimeout. It's called from the goroutine
+// discarding the data and its errors are ignored.
+func SetDeadLetter(w io.Writer) BufferOption {
+	return func(b *Buffer) {
+		b.deadLetter = w
+	}
+}
+
+// toDeadLetter reports if p was written to the dead letter writer
+func (tb *Buffer) toDeadLetter(p []byte) bool {
+	if tb.deadLetter == nil || len(p) == 0 {
+		return false
+	}
+	_, err := tb.deadLetter.Write(p)
+	return err == nil
+}
@@ -0,0 +1,67 @@
+//go:build syncio_debug
+
+package syncio
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// accounting checks that no byte is lost or duplicated by the Buffer, it's enabled with the
+// syncio_debug build tag. The counters are updated while owning the active buffer or from the
+// flush goroutine, so they are consistent when checked holding the lock after a flush.
+type accounting struct {
+	accepted int64 // bytes returned as written by Write
+	written  int64 // bytes accepted by the underlying writer
+	dropped  int64 // bytes discarded after flush errors, abandoned by CloseTimeout or stolen
+	inflight int64 // bytes handed to the flush workers and not complete
+}
+
+func (a *accounting) accept(n int) {
+	atomic.AddInt64(&a.accepted, int64(n))
+}
+
+func (a *accounting) flushed(written, dropped int) {
+	atomic.AddInt64(&a.written, int64(written))
+	atomic.AddInt64(&a.dropped, int64(dropped))
+}
+
+func (a *accounting) dispatch(n int) {
+	atomic.AddInt64(&a.inflight, int64(n))
+}
+
+func (a *accounting) complete(n int) {
+	atomic.AddInt64(&a.inflight, -int64(n))
+}
+
+func (a *accounting) remove(n int) {
+	atomic.AddInt64(&a.dropped, int64(n))
+}
+
+// check panics if written + buffered + dropped != accepted, it's called from the flush goroutine
+// after every batch
+func (a *accounting) check(tb *Buffer) {
+	tb.lockBuf()
+	active := tb.buf.Buffered()
+	buffered := int64(active)
+	for _, b := range tb.queue {
+		buffered += int64(b.len())
+	}
+	if tb.classes != nil {
+		buffered += tb.classes.held
+	}
+	if tb.spill != nil {
+		buffered += tb.spill.size
+	}
+	buffered += atomic.LoadInt64(&a.inflight)
+	queued, closed := len(tb.queue), tb.closed
+	accepted := atomic.LoadInt64(&a.accepted)
+	written := atomic.LoadInt64(&a.written)
+	dropped := atomic.LoadInt64(&a.dropped)
+	tb.unlockBuf()
+	if written+buffered+dropped != accepted {
+		// Stats takes bufmu
+		panic(fmt.Sprintf("syncio: accounting violation: written %v + buffered %v + dropped %v != accepted %v (active buffer: %v bytes, queued batches: %v, closed: %v, stats: %+v)",
+			written, buffered, dropped, accepted, active, queued, closed, tb.Stats()))
+	}
+}
@@ -0,0 +1,182 @@
+package syncio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultDedupMaxLine is the default maximum length of a line to be compared by a DedupingWriter
+const DefaultDedupMaxLine = 64 * 1024
+
+// DedupingWriter suppresses consecutive identical lines, a summary line is written when
+// a different line arrives, when the window ticks or on Close.
+// Lines longer than the max line length are written without being compared.
+type DedupingWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	format  func(line []byte, count int) []byte
+	maxLine int
+
+	// last is the last complete line written, including the \n
+	last  []byte
+	count int
+	// partial is a line not yet terminated
+	partial []byte
+	// passthrough is set while writing the rest of a line longer than maxLine
+	passthrough bool
+	err         error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+var _ io.WriteCloser = &DedupingWriter{}
+
+// DedupWriter returns a writer that suppresses consecutive identical lines written to w,
+// format builds the summary line for a line repeated count times after being written, if nil
+// a "last message repeated N times" line is used. The summary of a run is written every window.
+// Call Close to free the ticker goroutine and write the pending data.
+func DedupWriter(w io.Writer, window time.Duration, format func(line []byte, count int) []byte) *DedupingWriter {
+	return newDedupWriter(w, window, format, systemClock{})
+}
+
+func newDedupWriter(w io.Writer, window time.Duration, format func(line []byte, count int) []byte, clock Clock) *DedupingWriter {
+	if format == nil {
+		format = repeatedLine
+	}
+	d := &DedupingWriter{
+		w:       w,
+		format:  format,
+		maxLine: DefaultDedupMaxLine,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if window <= 0 {
+		close(d.done)
+		return d
+	}
+	c, stop := clock.NewTicker(window)
+	go func() {
+		defer close(d.done)
+		defer stop()
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-c:
+				d.mu.Lock()
+				d.summary()
+				d.mu.Unlock()
+			}
+		}
+	}()
+	return d
+}
+
+func repeatedLine(line []byte, count int) []byte {
+	return fmt.Appendf(nil, "last message repeated %d times\n", count)
+}
+
+// SetMaxLine changes the maximum length of the compared lines, DefaultDedupMaxLine by default
+func (d *DedupingWriter) SetMaxLine(n int) {
+	d.mu.Lock()
+	d.maxLine = n
+	d.mu.Unlock()
+}
+
+// Write writes the lines of p which are not a repetition of the previous one, an error
+// writing to the underlying writer is returned by all subsequent calls
+func (d *DedupingWriter) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	for rest := p; len(rest) > 0 && d.err == nil; {
+		i := bytes.IndexByte(rest, '\n')
+		var chunk []byte
+		if i < 0 {
+			chunk, rest = rest, nil
+		} else {
+			chunk, rest = rest[:i+1], rest[i+1:]
+		}
+		complete := i >= 0
+
+		if d.passthrough {
+			d.write(chunk)
+			d.passthrough = !complete
+			continue
+		}
+		if len(d.partial)+len(chunk) > d.maxLine {
+			// too long to be compared
+			d.summary()
+			d.last = d.last[:0]
+			d.write(d.partial)
+			d.write(chunk)
+			d.partial = d.partial[:0]
+			d.passthrough = !complete
+			continue
+		}
+		d.partial = append(d.partial, chunk...)
+		if complete {
+			d.line(d.partial)
+			d.partial = d.partial[:0]
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return len(p), nil
+}
+
+// line handles a complete line, the caller must hold mu
+func (d *DedupingWriter) line(l []byte) {
+	if len(d.last) > 0 && bytes.Equal(l, d.last) {
+		d.count++
+		return
+	}
+	d.summary()
+	d.last = append(d.last[:0], l...)
+	d.write(l)
+}
+
+// summary writes the summary of the pending repetitions, the caller must hold mu
+func (d *DedupingWriter) summary() {
+	if d.count == 0 {
+		return
+	}
+	d.write(d.format(d.last, d.count))
+	d.count = 0
+}
+
+func (d *DedupingWriter) write(p []byte) {
+	if d.err != nil || len(p) == 0 {
+		return
+	}
+	_, d.err = d.w.Write(p)
+}
+
+// Close stops the ticker and writes the pending summary and unterminated line,
+// it doesn't close the underlying writer
+func (d *DedupingWriter) Close() error {
+	d.mu.Lock()
+	select {
+	case <-d.stop:
+		d.mu.Unlock()
+		return nil
+	default:
+		close(d.stop)
+	}
+	d.summary()
+	d.write(d.partial)
+	d.partial = d.partial[:0]
+	err := d.err
+	d.mu.Unlock()
+
+	<-d.done
+	return err
+}
@@ -0,0 +1,59 @@
+package syncio
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDedupWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	format := func(line []byte, count int) []byte {
+		return fmt.Appendf(nil, "x%d\n", count)
+	}
+	d := DedupWriter(out, 0, format)
+
+	d.Write([]byte("a\na\na"))
+	d.Write([]byte("\nb\n")) // the third a is split across writes
+	d.Write([]byte("b\nb\nc"))
+	d.Close()
+
+	expected := "a\nx2\nb\nx2\nc"
+	if out.String() != expected {
+		t.Errorf("written: %q, expected: %q", out.String(), expected)
+	}
+}
+
+func TestDedupWriterWindow(t *testing.T) {
+	clock := newFakeClock()
+	out := &bytes.Buffer{}
+	d := newDedupWriter(out, time.Second, nil, clock)
+
+	d.Write([]byte("a\na\na\n"))
+	// the second tick is received once the first is handled
+	clock.tickers[0] <- time.Time{}
+	clock.tickers[0] <- time.Time{}
+	d.Write([]byte("a\n"))
+	d.Close()
+
+	expected := "a\nlast message repeated 2 times\nlast message repeated 1 times\n"
+	if out.String() != expected {
+		t.Errorf("written: %q, expected: %q", out.String(), expected)
+	}
+}
+
+func TestDedupWriterMaxLine(t *testing.T) {
+	out := &bytes.Buffer{}
+	d := DedupWriter(out, 0, nil)
+	d.SetMaxLine(4)
+
+	d.Write([]byte("long"))
+	d.Write([]byte("line\nlongline\nab\nab\n"))
+	d.Close()
+
+	expected := "longline\nlongline\nab\nlast message repeated 1 times\n"
+	if out.String() != expected {
+		t.Errorf("written: %q, expected: %q", out.String(), expected)
+	}
+}
@@ -0,0 +1,113 @@
+package syncio
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SetDedupWindow discards the writes whose key is the key of one of the last n writes taken,
+// they return without error and are counted in Stats.DedupDrops. key returns the key of a
+// write, the whole write if nil, it must not retain the slice. SetDedupMaxAge forgets the keys
+// older than a duration.
+// A discarded write doesn't enter the window, so a burst of identical writes is taken once every
+// n different writes. n <= 0 disables it.
+func SetDedupWindow(n int, key func(p []byte) string) BufferOption {
+	return func(b *Buffer) {
+		if n <= 0 {
+			b.dedup = nil
+			return
+		}
+		b.dedup = &dedupWindow{key: key, keys: make([]dedupKey, n), seen: make(map[string]int, n)}
+	}
+}
+
+// SetDedupMaxAge forgets the keys of SetDedupWindow taken more than d ago, the window is
+// only bounded by its number of writes with d <= 0
+func SetDedupMaxAge(d time.Duration) BufferOption {
+	return func(b *Buffer) {
+		b.dedupMaxAge = d
+	}
+}
+
+// dedupWindow is the ring of the keys of the last writes taken, seen counts the writes of
+// every key in the ring. maxAge is set from Buffer.dedupMaxAge by NewBuffer.
+type dedupWindow struct {
+	key    func(p []byte) string
+	maxAge time.Duration
+
+	mu    sync.Mutex
+	keys  []dedupKey
+	start int
+	n     int
+	seen  map[string]int
+	// scratch is the copy of the write given to key
+	scratch []byte
+}
+
+type dedupKey struct {
+	key string
+	at  time.Time
+}
+
+// duplicate reports if p must be discarded, otherwise its key enters the window
+func (d *dedupWindow) duplicate(tb *Buffer, p []byte) bool {
+	var now time.Time
+	if d.maxAge > 0 {
+		now = tb.clock.Now()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.maxAge > 0 {
+		for d.n > 0 && now.Sub(d.keys[d.start].at) > d.maxAge {
+			d.forget()
+		}
+	}
+	if d.key == nil {
+		// the lookup doesn't allocate
+		if d.seen[string(p)] > 0 {
+			return true
+		}
+		d.add(string(p), now)
+		return false
+	}
+	// key gets a copy so the Write slices don't escape
+	d.scratch = append(d.scratch[:0], p...)
+	k := d.key(d.scratch)
+	if d.seen[k] > 0 {
+		return true
+	}
+	d.add(k, now)
+	return false
+}
+
+// add writes the key in the window, the oldest one is forgotten when it's full
+func (d *dedupWindow) add(key string, at time.Time) {
+	if d.n == len(d.keys) {
+		d.forget()
+	}
+	d.keys[(d.start+d.n)%len(d.keys)] = dedupKey{key: key, at: at}
+	d.n++
+	d.seen[key]++
+}
+
+// forget removes the oldest key of the window
+func (d *dedupWindow) forget() {
+	k := d.keys[d.start].key
+	if d.seen[k]--; d.seen[k] == 0 {
+		delete(d.seen, k)
+	}
+	d.keys[d.start] = dedupKey{}
+	d.start = (d.start + 1) % len(d.keys)
+	d.n--
+}
+
+// dedupWrite reports if p is discarded by SetDedupWindow, it's counted
+func (tb *Buffer) dedupWrite(p []byte) bool {
+	if !tb.dedup.duplicate(tb, p) {
+		return false
+	}
+	atomic.AddInt64(&tb.stats.DedupDrops, 1)
+	atomic.AddInt64(&tb.stats.DedupDropBytes, int64(len(p)))
+	return true
+}
@@ -0,0 +1,56 @@
+package syncio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDedupWindow(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		var out lockedBuffer
+		tb := NewBuffer(&out, append([]BufferOption{SetDedupWindow(2, nil)}, mode...)...)
+		for _, w := range []string{"a\n", "a\n", "b\n", "a\n", "c\n", "d\n", "a\n", "a\n"} {
+			if n, err := tb.Write([]byte(w)); n != len(w) || err != nil {
+				t.Fatalf("write %q: %v, %v", w, n, err)
+			}
+		}
+		tb.Close()
+		// a is forgotten once two other writes are taken
+		if got := out.String(); got != "a\nb\nc\nd\na\n" {
+			t.Errorf("written %q", got)
+		}
+		s := tb.Stats()
+		if s.DedupDrops != 3 || s.DedupDropBytes != 6 || s.CallerWrites != 8 {
+			t.Errorf("dedup drops %v, bytes %v, caller writes %v", s.DedupDrops, s.DedupDropBytes, s.CallerWrites)
+		}
+	})
+}
+
+func TestDedupWindowKeyAge(t *testing.T) {
+	clock := newFakeClock()
+	var out bytes.Buffer
+	// the key is the metric name before the space
+	key := func(p []byte) string {
+		if i := bytes.IndexByte(p, ' '); i >= 0 {
+			return string(p[:i])
+		}
+		return string(p)
+	}
+	tb := NewBuffer(&out, SetClock(clock), SetSynchronousMode(true), SetDedupWindow(100, key), SetDedupMaxAge(time.Second))
+	tb.Write([]byte("cpu 1\n"))
+	tb.Write([]byte("cpu 2\n"))
+	tb.Write([]byte("mem 1\n"))
+	clock.Advance(500 * time.Millisecond)
+	tb.Write([]byte("cpu 3\n"))
+	clock.Advance(time.Second)
+	tb.Write([]byte("cpu 4\n"))
+	tb.Write([]byte("mem 2\n"))
+	tb.Close()
+	if got := out.String(); got != "cpu 1\nmem 1\ncpu 4\nmem 2\n" {
+		t.Errorf("written %q", got)
+	}
+	if s := tb.Stats(); s.DedupDrops != 2 {
+		t.Errorf("dedup drops %v", s.DedupDrops)
+	}
+}
@@ -0,0 +1,65 @@
+package syncio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+
+	"github.com/travelgateX/go-io/syncio/bufpool"
+)
+
+const drainBufferSize = 32 * 1024
+
+// drainPool holds the buffers used to discard data
+var drainPool = bufpool.New(drainBufferSize, 8)
+
+// DrainClose reads and discards up to max bytes from rc and closes it, a negative max
+// drains until EOF. rc is closed even if draining fails, the errors of both
+// operations are joined. Draining a http response body allows to reuse the connection.
+func DrainClose(rc io.ReadCloser, max int64) (int64, error) {
+	var n int64
+	err := drain(rc, max, &n)
+	return n, errors.Join(err, rc.Close())
+}
+
+// DrainCloseContext is DrainClose but it stops waiting for rc when ctx is done, in that
+// case rc is closed while the drain may be still blocked reading it, so rc must support
+// a concurrent Close, which usually unblocks the read, e.g. network connections and http bodies.
+func DrainCloseContext(ctx context.Context, rc io.ReadCloser, max int64) (int64, error) {
+	var n int64
+	done := make(chan error, 1)
+	go func() {
+		done <- drain(rc, max, &n)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return atomic.LoadInt64(&n), errors.Join(err, rc.Close())
+}
+
+// drain discards the data of r until EOF or max bytes, n is updated atomically
+func drain(r io.Reader, max int64, n *int64) error {
+	buf := drainPool.Get()
+	defer drainPool.Put(buf)
+
+	for max < 0 || atomic.LoadInt64(n) < max {
+		p := buf
+		if rest := max - atomic.LoadInt64(n); max >= 0 && rest < int64(len(p)) {
+			p = p[:rest]
+		}
+		m, err := r.Read(p)
+		atomic.AddInt64(n, int64(m))
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
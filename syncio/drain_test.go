@@ -0,0 +1,69 @@
+package syncio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testReadCloser struct {
+	io.Reader
+	closed   chan struct{}
+	closeErr error
+}
+
+func newTestReadCloser(r io.Reader) *testReadCloser {
+	return &testReadCloser{Reader: r, closed: make(chan struct{})}
+}
+
+func (rc *testReadCloser) Close() error {
+	close(rc.closed)
+	return rc.closeErr
+}
+
+// hungReader blocks until it's closed
+type hungReader struct {
+	*testReadCloser
+}
+
+func (h hungReader) Read(p []byte) (int, error) {
+	<-h.closed
+	return 0, errors.New("read on closed")
+}
+
+func TestDrainClose(t *testing.T) {
+	rc := newTestReadCloser(strings.NewReader(strings.Repeat("x", 100*1024)))
+	n, err := DrainClose(rc, -1)
+	if n != 100*1024 || err != nil {
+		t.Errorf("drained %v bytes, error: %v, expected %v bytes", n, err, 100*1024)
+	}
+
+	rc = newTestReadCloser(strings.NewReader(strings.Repeat("x", 100*1024)))
+	rc.closeErr = errors.New("close error")
+	n, err = DrainClose(rc, 1000)
+	if n != 1000 || !errors.Is(err, rc.closeErr) {
+		t.Errorf("drained %v bytes, error: %v, expected %v bytes and the close error", n, err, 1000)
+	}
+}
+
+func TestDrainCloseContext(t *testing.T) {
+	h := hungReader{newTestReadCloser(nil)}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := DrainCloseContext(ctx, struct {
+		io.Reader
+		io.Closer
+	}{h, h}, -1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error: %v, expected: %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case <-h.closed:
+	default:
+		t.Errorf("reader not closed after cancellation")
+	}
+}
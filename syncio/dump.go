@@ -0,0 +1,107 @@
+package syncio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DumpState writes a human readable report of the Buffer state to w: the options, the
+// counters, the queue and whether the flush goroutine is writing to the underlying writer
+// and since when. It's meant to be logged when a service wedges, the writers are only held
+// while the queue is copied. The report is written with a single Write.
+func (tb *Buffer) DumpState(w io.Writer) error {
+	if !tb.initialized() {
+		return ErrNotInitialized
+	}
+	var out bytes.Buffer
+	tb.dumpState(&out, "")
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func (tb *Buffer) dumpState(out *bytes.Buffer, indent string) {
+	options := tb.AppliedOptions()
+	var s Stats
+	tb.StatsInto(&s)
+
+	tb.bufmu.Lock()
+	closed, replaying := tb.closed, tb.replaying
+	queued, queuedBytes := len(tb.queue), 0
+	for _, b := range tb.queue {
+		queuedBytes += b.len()
+	}
+	tb.bufmu.Unlock()
+
+	fmt.Fprintf(out, "%soptions:\n", indent)
+	for _, o := range options {
+		values := make([]string, len(o.Values))
+		for i, v := range o.Values {
+			if v == nil {
+				values[i] = "-"
+			} else {
+				values[i] = fmt.Sprint(v)
+			}
+		}
+		fmt.Fprintf(out, "%s  %s: %s\n", indent, o.Name, strings.Join(values, ", "))
+	}
+
+	fmt.Fprintf(out, "%sstats:\n", indent)
+	v := reflect.ValueOf(s)
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if name == "Errors" {
+			for c, n := range s.Errors {
+				fmt.Fprintf(out, "%s  Errors.%v: %v\n", indent, ErrorCategory(c), n)
+			}
+			continue
+		}
+		fmt.Fprintf(out, "%s  %s: %v\n", indent, name, v.Field(i).Interface())
+	}
+
+	fmt.Fprintf(out, "%sstate:\n", indent)
+	fmt.Fprintf(out, "%s  closed: %v\n", indent, closed)
+	fmt.Fprintf(out, "%s  writes closed: %v\n", indent, tb.WritesClosed())
+	fmt.Fprintf(out, "%s  replaying: %v\n", indent, replaying)
+	fmt.Fprintf(out, "%s  queue: %v/%v batches, %v bytes\n", indent, queued, tb.poolSize, queuedBytes)
+	fmt.Fprintf(out, "%s  backlogged: %v\n", indent, tb.Backlogged())
+	if active, since, n := tb.InFlightFlush(); active {
+		fmt.Fprintf(out, "%s  sink: writing %v bytes for %v\n", indent, n, tb.clock.Now().Sub(since))
+	} else if tb.SinkPending() {
+		fmt.Fprintf(out, "%s  sink: pending\n", indent)
+	} else {
+		fmt.Fprintf(out, "%s  sink: idle\n", indent)
+	}
+}
+
+// DumpAll writes the DumpState report of every open Buffer of the cache to w sorted by key,
+// after the cache counters. The report is written with a single Write.
+func (c *WriterCache) DumpAll(w io.Writer) error {
+	stats := c.Stats()
+	type entryState struct {
+		key  string
+		buf  *Buffer
+		refs int
+		gets int64
+	}
+	c.mu.Lock()
+	states := make([]entryState, 0, len(c.entries))
+	for _, e := range c.entries {
+		states = append(states, entryState{e.key, e.buf, e.refs, e.gets})
+	}
+	c.mu.Unlock()
+	sort.Slice(states, func(i, j int) bool { return states[i].key < states[j].key })
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "cache: %v open, %v pinned, %v gets, %v opens, %v idle evictions, %v capacity evictions, %v close errors\n",
+		stats.Open, stats.Pinned, stats.Gets, stats.Opens, stats.IdleEvictions, stats.CapacityEvictions, stats.CloseErrors)
+	for _, e := range states {
+		fmt.Fprintf(&out, "buffer %q: %v pinned, %v gets\n", e.key, e.refs, e.gets)
+		e.buf.dumpState(&out, "  ")
+	}
+	_, err := w.Write(out.Bytes())
+	return err
+}
@@ -0,0 +1,90 @@
+package syncio
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// golden compares out with the golden file name in testdata, -update rewrites it
+func golden(t *testing.T, name string, out []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, out, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, expected) {
+		t.Errorf("%v doesn't match, got:\n%s", path, out)
+	}
+}
+
+func TestDumpState(t *testing.T) {
+	clock := newFakeClock()
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(16), SetClock(clock))
+
+	tb.Write([]byte("0123456789"))
+	flushed := make(chan error, 1)
+	go func() { flushed <- tb.Flush() }()
+	for tb.sinkSince.Load() == 0 {
+		runtime.Gosched()
+	}
+	// a queued batch and data in the active buffer
+	tb.Write([]byte("abcdefghijkl"))
+	tb.Write([]byte("mnopq"))
+	clock.Advance(2 * time.Second)
+
+	var out bytes.Buffer
+	if err := tb.DumpState(&out); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "dump_state.golden", out.Bytes())
+
+	close(bw.release)
+	if err := <-flushed; err != nil {
+		t.Fatal(err)
+	}
+	tb.Close()
+	out.Reset()
+	tb.DumpState(&out)
+	if !bytes.Contains(out.Bytes(), []byte("  closed: true\n")) || !bytes.Contains(out.Bytes(), []byte("  sink: idle\n")) {
+		t.Errorf("dump after close:\n%s", out.Bytes())
+	}
+}
+
+func TestDumpAll(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWriterCache(func(key string) (*Buffer, error) {
+		return NewBuffer(&testWriter{}, SetBufferSize(64), SetClock(clock)), nil
+	}, 0, 0)
+
+	for _, key := range []string{"b", "a", "b"} {
+		if _, err := c.Write(key, []byte(key+"\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := c.DumpAll(&out); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "dump_all.golden", out.Bytes())
+	c.Release("a")
+	c.CloseAll(context.Background())
+}
@@ -0,0 +1,54 @@
+package syncio
+
+import "sync"
+
+// ErrorChannelSize is the number of flush errors held by the channel of Errors, the next ones
+// are dropped until they're received
+const ErrorChannelSize = 16
+
+// errorChannel is the channel of Errors, created by the first call
+type errorChannel struct {
+	mu     sync.Mutex
+	c      chan error
+	closed bool
+}
+
+// Errors returns a channel receiving the *FlushError of every batch that couldn't be written,
+// as SetOnFlushError, so the tick flush errors are seen when they happen. The errors are
+// dropped while the channel is full and it's closed once the Buffer is closed and its data
+// flushed. Only the errors after the first call are sent, every call returns the same channel.
+func (tb *Buffer) Errors() <-chan error {
+	e := &tb.errs
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.c == nil {
+		e.c = make(chan error, ErrorChannelSize)
+		if e.closed || !tb.initialized() {
+			close(e.c)
+		}
+	}
+	return e.c
+}
+
+// send sends a flush error to the channel of Errors without blocking
+func (e *errorChannel) send(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.c == nil || e.closed {
+		return
+	}
+	select {
+	case e.c <- err:
+	default:
+	}
+}
+
+// close closes the channel of Errors once the flushes are done
+func (e *errorChannel) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.closed && e.c != nil {
+		close(e.c)
+	}
+	e.closed = true
+}
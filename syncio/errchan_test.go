@@ -0,0 +1,65 @@
+package syncio
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+func TestErrorsChannel(t *testing.T) {
+	fw := synctest.NewFailingWriter(nil, nil)
+	tb := NewBuffer(fw, SetBufferSize(4), SetFlushInterval(time.Millisecond))
+	errs := tb.Errors()
+	if tb.Errors() != errs {
+		t.Fatal("Errors returned a new channel")
+	}
+	tb.Write([]byte("ab"))
+
+	// the tick flush error is received without calling Flush
+	select {
+	case err := <-errs:
+		var ferr *FlushError
+		if !errors.As(err, &ferr) || ferr.Trigger != TriggerTick || !errors.Is(err, synctest.ErrInjected) {
+			t.Fatalf("error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tick flush error not received")
+	}
+
+	// the channel doesn't block the flushes when it's full
+	for i := 0; i < 2*ErrorChannelSize; i++ {
+		tb.Write([]byte("abcd"))
+	}
+	tb.Close()
+	n := 0
+	for range errs {
+		n++
+	}
+	if n == 0 || n > ErrorChannelSize {
+		t.Fatalf("%v errors received", n)
+	}
+	if _, ok := <-tb.Errors(); ok {
+		t.Fatal("channel open after Close")
+	}
+}
+
+func TestErrorsChannelAfterClose(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		tb := NewBuffer(&testWriter{}, mode...)
+		tb.Close()
+		select {
+		case _, ok := <-tb.Errors():
+			if ok {
+				t.Fatal("error received")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("channel not closed")
+		}
+	})
+	var zero Buffer
+	if _, ok := <-zero.Errors(); ok {
+		t.Fatal("error received from the zero value")
+	}
+}
@@ -0,0 +1,39 @@
+package syncio
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrPayloadDiscarded is wrapped by the FlushError passed to SetOnFlushError without the
+// unwritten bytes once SetMaxErrorQueueBytes is reached
+var ErrPayloadDiscarded = errors.New("flush error payload discarded")
+
+// SetMaxErrorQueueBytes bounds the unwritten bytes handed to the SetOnFlushError callback
+// while the underlying writer keeps failing, for the callbacks that queue them: once the
+// payloads since the last successful flush reach n bytes, the next failures invoke the
+// callback with nil unwritten bytes and the error wrapping ErrPayloadDiscarded, counted in
+// Stats.DiscardedPayloads. The payload that reaches n is still given whole. 0, the default,
+// doesn't bound them.
+func SetMaxErrorQueueBytes(n int64) BufferOption {
+	return func(b *Buffer) {
+		b.maxErrorQueue = n
+	}
+}
+
+// errorPayload returns the unwritten bytes to give to the callback, it's used by the flush
+// goroutine
+func (tb *Buffer) errorPayload(ferr *FlushError, unwritten []byte) []byte {
+	if tb.maxErrorQueue <= 0 || len(unwritten) == 0 {
+		return unwritten
+	}
+	if tb.errorQueued < tb.maxErrorQueue {
+		tb.errorQueued += int64(len(unwritten))
+		return unwritten
+	}
+	atomic.AddInt64(&tb.stats.DiscardedPayloads, 1)
+	atomic.AddInt64(&tb.stats.DiscardedPayloadBytes, int64(len(unwritten)))
+	ferr.Err = fmt.Errorf("%w: %w", ErrPayloadDiscarded, ferr.Err)
+	return nil
+}
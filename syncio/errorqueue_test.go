@@ -0,0 +1,56 @@
+package syncio
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+func TestMaxErrorQueueBytes(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		var payloads []int
+		var discarded []bool
+		fw := synctest.NewFailingWriter(nil, nil)
+		tb := NewBuffer(fw, append([]BufferOption{SetMaxErrorQueueBytes(10), SetOnFlushError(func(err *FlushError, unwritten []byte) {
+			payloads = append(payloads, len(unwritten))
+			discarded = append(discarded, errors.Is(err, ErrPayloadDiscarded))
+			if !errors.Is(err, synctest.ErrInjected) {
+				t.Errorf("flush error: %v, expected to wrap the sink error", err)
+			}
+		})}, mode...)...)
+
+		flush := func() {
+			tb.Write([]byte("data"))
+			if err := tb.Flush(); err == nil {
+				t.Fatal("flush to the failing sink without error")
+			}
+		}
+		// the third payload reaches the limit
+		for i := 0; i < 5; i++ {
+			flush()
+		}
+		expected := []int{4, 4, 4, 0, 0}
+		for i := range expected {
+			if payloads[i] != expected[i] || discarded[i] != (expected[i] == 0) {
+				t.Errorf("failure %v: %v bytes, discarded: %v, expected: %v bytes", i, payloads[i], discarded[i], expected[i])
+			}
+		}
+		if s := tb.Stats(); s.DiscardedPayloads != 2 || s.DiscardedPayloadBytes != 8 || s.FlushErrors != 5 {
+			t.Errorf("stats: %v discarded payloads of %v bytes, %v errors, expected: 2, 8, 5", s.DiscardedPayloads, s.DiscardedPayloadBytes, s.FlushErrors)
+		}
+
+		// a successful flush ends the outage
+		fw.SetFailing(false)
+		tb.Write([]byte("data"))
+		if err := tb.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		fw.SetFailing(true)
+		flush()
+		if n := payloads[len(payloads)-1]; n != 4 {
+			t.Errorf("payload after the outage: %v bytes, expected: 4", n)
+		}
+		tb.Close()
+	})
+}
@@ -0,0 +1,103 @@
+package syncio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// FlushError is the error produced when a batch can't be written to the underlying writer,
+// it wraps the error returned by the writer
+type FlushError struct {
+	Err error
+	// BatchBytes is the size of the batch that was being flushed
+	BatchBytes int
+	// Written is the number of bytes of the batch accepted by the underlying writer,
+	// Unwritten the number of bytes left
+	Written   int
+	Unwritten int
+	// Offset is the position of the first unwritten byte in the stream of bytes flushed
+	// by the Buffer, the bytes lost by previous errors included
+	Offset int64
+	// Attempt is the number of writes tried for this batch
+	Attempt int
+	Trigger FlushTrigger
+	// LostData reports if the bytes not written were discarded
+	LostData bool
+	// Category is the counter of Stats.Errors incremented by the error
+	Category ErrorCategory
+}
+
+func (e *FlushError) Error() string {
+	return fmt.Sprintf("flush error (%v, attempt %v): %v of %v bytes written, unwritten from offset %v: %v", e.Trigger, e.Attempt, e.Written, e.BatchBytes, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying writer error
+func (e *FlushError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is the error of a flush that panicked in the underlying writer, see SetPanicPolicy
+type PanicError struct {
+	// Value is the value passed to panic and Stack the stack trace of the goroutine that panicked
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("underlying writer panic: %v", e.Value)
+}
+
+var errShortBatch = errors.New("short batch write")
+
+// ErrorCategory classifies the errors counted in Stats.Errors, every error is counted once
+// in a single category
+type ErrorCategory int
+
+const (
+	ErrorSink           ErrorCategory = iota // a flush failed in the underlying writer, its header or the flush transform
+	ErrorRetryExhausted                      // a flush failed because the short writes retried made no progress
+	ErrorDropped                             // data was discarded by a policy: SetMaxBacklogAge or CloseAbandon
+	ErrorDeadLettered                        // a flush failed and the unwritten data was written to the dead letter writer
+	ErrorTimeout                             // CloseTimeout abandoned data, CloseOnContext or a sink write timed out
+	ErrorClosed                              // a call failed with ErrWriteOnClosed or ErrReaderClosed, a flush too when the parent Buffer is closed
+	errorCategories
+)
+
+var errorCategoryNames = []string{"sink", "retry_exhausted", "dropped", "dead_lettered", "timeout", "closed"}
+
+func (c ErrorCategory) String() string {
+	return enumString(errorCategoryNames, int(c))
+}
+
+func (c ErrorCategory) MarshalText() ([]byte, error) {
+	return enumMarshal(errorCategoryNames, int(c), "ErrorCategory")
+}
+
+func (c *ErrorCategory) UnmarshalText(text []byte) error {
+	return enumUnmarshal(errorCategoryNames, text, "ErrorCategory", (*int)(c))
+}
+
+// flushCategory returns the category of a flush error, the data handling goes first
+func flushCategory(err error, deadLettered bool) ErrorCategory {
+	switch {
+	case deadLettered:
+		return ErrorDeadLettered
+	case err == ErrWriteOnClosed:
+		return ErrorClosed
+	case errors.Is(err, ErrWriteTimeout):
+		return ErrorTimeout
+	case errors.Is(err, io.ErrShortWrite) || err == errShortBatch:
+		return ErrorRetryExhausted
+	default:
+		return ErrorSink
+	}
+}
+
+func (tb *Buffer) countError(c ErrorCategory) {
+	atomic.AddInt64(&tb.stats.Errors[c], 1)
+	if tb.journal != nil {
+		tb.journal.record(journalError, uint64(c))
+	}
+}
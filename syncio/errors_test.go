@@ -0,0 +1,245 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+type flushErrors struct {
+	mu   sync.Mutex
+	errs []*FlushError
+	lost int
+}
+
+func (fe *flushErrors) callback(err *FlushError, unwritten []byte) {
+	fe.mu.Lock()
+	fe.errs = append(fe.errs, err)
+	fe.lost += len(unwritten)
+	fe.mu.Unlock()
+}
+
+func (fe *flushErrors) get() ([]*FlushError, int) {
+	fe.mu.Lock()
+	defer fe.mu.Unlock()
+	return fe.errs, fe.lost
+}
+
+func assertFlushError(t *testing.T, err error, trigger FlushTrigger, batch, written int) {
+	t.Helper()
+	var ferr *FlushError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("expected a FlushError, got: %v", err)
+	}
+	if !errors.Is(err, synctest.ErrInjected) {
+		t.Errorf("FlushError doesn't wrap the writer error: %v", err)
+	}
+	if ferr.Trigger != trigger || ferr.BatchBytes != batch || ferr.Written != written || ferr.Attempt != 1 || !ferr.LostData {
+		t.Errorf("unexpected FlushError: %+v", *ferr)
+	}
+}
+
+func TestFlushErrorSize(t *testing.T) {
+	fw := synctest.NewFailingWriter(nil, nil)
+	fw.Partial = 3
+	fe := &flushErrors{}
+	tb := NewBuffer(fw, SetBufferSize(10), SetOnFlushError(fe.callback))
+
+	tb.Write(make([]byte, 6))
+	tb.Write(make([]byte, 6))
+	err := tb.Flush()
+	// the size flush error is reported by Flush
+	assertFlushError(t, err, TriggerSize, 6, 3)
+
+	tb.Close()
+	errs, lost := fe.get()
+	if len(errs) != 2 {
+		t.Fatalf("callback errors: %v, expected: 2", len(errs))
+	}
+	assertFlushError(t, errs[1], TriggerManual, 6, 3)
+	if lost != 6 {
+		t.Errorf("unwritten bytes: %v, expected: 6", lost)
+	}
+	if s := tb.Stats(); s.FlushErrors != 2 {
+		t.Errorf("stats flush errors: %v, expected: 2", s.FlushErrors)
+	}
+}
+
+func TestFlushErrorOversized(t *testing.T) {
+	fw := synctest.NewFailingWriter(nil, nil)
+	tb := NewBuffer(fw, SetBufferSize(10))
+
+	tb.Write(make([]byte, 20))
+	assertFlushError(t, tb.Close(), TriggerSize, 20, 0)
+}
+
+func TestFlushErrorTick(t *testing.T) {
+	fw := synctest.NewFailingWriter(nil, nil)
+	fe := &flushErrors{}
+	tb := NewBuffer(fw, SetFlushInterval(10*time.Millisecond), SetOnFlushError(fe.callback))
+
+	tb.Write(make([]byte, 5))
+	time.Sleep(50 * time.Millisecond)
+	errs, _ := fe.get()
+	if len(errs) != 1 {
+		t.Fatalf("callback errors: %v, expected: 1", len(errs))
+	}
+	assertFlushError(t, errs[0], TriggerTick, 5, 0)
+
+	// already reported errors are returned once
+	assertFlushError(t, tb.Flush(), TriggerTick, 5, 0)
+	if err := tb.Close(); err != nil {
+		t.Errorf("close error: %v, expected nil", err)
+	}
+}
+
+func TestFlushErrorManualAndClose(t *testing.T) {
+	out := &bytes.Buffer{}
+	fw := synctest.NewFailingWriter(out, nil)
+	tb := NewBuffer(fw)
+
+	tb.Write([]byte("lost"))
+	assertFlushError(t, tb.Flush(), TriggerManual, 4, 0)
+
+	fw.SetFailing(false)
+	tb.Write([]byte("ok"))
+	if err := tb.Flush(); err != nil {
+		t.Errorf("flush error: %v, expected nil", err)
+	}
+
+	fw.SetFailing(true)
+	tb.Write([]byte("lost"))
+	assertFlushError(t, tb.Close(), TriggerClose, 4, 0)
+	if out.String() != "ok" {
+		t.Errorf("written: %q, expected: %q", out.String(), "ok")
+	}
+	if err := tb.Flush(); err != ErrWriteOnClosed {
+		t.Errorf("flush on closed: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+}
+
+func TestFlushErrorClosePartial(t *testing.T) {
+	fw := synctest.NewFailingWriter(nil, nil)
+	fw.SetFailing(false)
+	var dl bytes.Buffer
+	fe := &flushErrors{}
+	tb := NewBuffer(fw, SetBufferSize(64), SetOnFlushError(fe.callback), SetDeadLetter(&dl))
+
+	tb.Write([]byte("0123456789"))
+	tb.Flush()
+	// the final batch fails halfway
+	fw.SetFailing(true)
+	fw.Partial = 8
+	tb.Write([]byte("abcdefghijklmnop"))
+	err := tb.Close()
+
+	var ferr *FlushError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("close error: %v, expected a FlushError", err)
+	}
+	if ferr.Trigger != TriggerClose || ferr.Written != 8 || ferr.Unwritten != 8 || ferr.Offset != 18 {
+		t.Errorf("unexpected FlushError: %+v", *ferr)
+	}
+	if _, lost := fe.get(); lost != 8 || dl.String() != "ijklmnop" {
+		t.Errorf("lost: %v, dead letter: %q, expected: 8, %q", lost, dl.String(), "ijklmnop")
+	}
+}
+
+func TestErrorCategories(t *testing.T) {
+	tests := []struct {
+		category ErrorCategory
+		// run returns the Buffer once the error path is done and the returned error
+		run func() (*Buffer, error)
+	}{
+		{ErrorSink, func() (*Buffer, error) {
+			tb := NewBuffer(synctest.NewFailingWriter(nil, nil))
+			tb.Write([]byte("lost"))
+			return tb, tb.Flush()
+		}},
+		{ErrorSink, func() (*Buffer, error) {
+			// the dead letter writer fails too
+			tb := NewBuffer(synctest.NewFailingWriter(nil, nil), SetDeadLetter(synctest.NewFailingWriter(nil, nil)))
+			tb.Write([]byte("lost"))
+			return tb, tb.Flush()
+		}},
+		{ErrorRetryExhausted, func() (*Buffer, error) {
+			tb := NewBuffer(&shortWriter{max: 0})
+			tb.Write([]byte("lost"))
+			return tb, tb.Flush()
+		}},
+		{ErrorDeadLettered, func() (*Buffer, error) {
+			tb := NewBuffer(synctest.NewFailingWriter(nil, nil), SetDeadLetter(&bytes.Buffer{}))
+			tb.Write([]byte("saved"))
+			return tb, tb.Flush()
+		}},
+		{ErrorDropped, func() (*Buffer, error) {
+			clock := newFakeClock()
+			bw := &blockingWriter{release: make(chan struct{})}
+			tb := NewBuffer(bw, SetBufferSize(4), SetClock(clock), SetMaxBacklogAge(time.Second, OverflowDropNewest))
+			tb.Write([]byte("aaaa"))
+			clock.Advance(2 * time.Second)
+			tb.Write([]byte("b"))
+			close(bw.release)
+			return tb, tb.Close()
+		}},
+		{ErrorDropped, func() (*Buffer, error) {
+			bw := &blockingWriter{release: make(chan struct{})}
+			tb := NewBuffer(bw, SetBufferSize(4), SetClosePolicy(CloseAbandon))
+			tb.Write([]byte("aaaa"))
+			time.Sleep(10 * time.Millisecond)
+			tb.Write([]byte("bbbb"))
+			time.AfterFunc(10*time.Millisecond, func() { close(bw.release) })
+			return tb, tb.Close()
+		}},
+		{ErrorTimeout, func() (*Buffer, error) {
+			bw := &blockingWriter{release: make(chan struct{})}
+			tb := NewBuffer(bw, SetBufferSize(4))
+			tb.Write([]byte("aaaa"))
+			tb.Write([]byte("bbbb"))
+			_, err := tb.CloseTimeout(10 * time.Millisecond)
+			close(bw.release)
+			<-tb.done
+			return tb, err
+		}},
+		{ErrorClosed, func() (*Buffer, error) {
+			tb := NewBuffer(&testWriter{})
+			tb.Close()
+			_, err := tb.Write([]byte("closed"))
+			return tb, err
+		}},
+		{ErrorClosed, func() (*Buffer, error) {
+			parent := NewBuffer(&testWriter{})
+			parent.Close()
+			tb := NewBuffer(parent)
+			tb.Write([]byte("closed"))
+			return tb, tb.Flush()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category.String(), func(t *testing.T) {
+			tb, err := tt.run()
+			// the dropped writes succeed
+			if err == nil && tt.category != ErrorDropped {
+				t.Fatal("expected an error")
+			}
+			var ferr *FlushError
+			if errors.As(err, &ferr) && ferr.Category != tt.category {
+				t.Errorf("flush error category: %v, expected: %v", ferr.Category, tt.category)
+			}
+			s := tb.Stats()
+			for c, n := range s.Errors {
+				expected := int64(0)
+				if ErrorCategory(c) == tt.category {
+					expected = 1
+				}
+				if n != expected {
+					t.Errorf("%v errors: %v, expected: %v", ErrorCategory(c), n, expected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package syncio
+
+import (
+	"math"
+	"runtime"
+)
+
+// smallWrite is the maximum size of the writes served by the lock free path
+const smallWrite = 64
+
+// The features of the Write path, when none is enabled Write only checks ErrSelfWrite
+// before the lock free path, see BenchmarkWriteHotPath
+const (
+	featureWriteSizes uint32 = 1 << iota
+	featureBacklog
+	featureSingleWriter
+	// featureLocked disables the lock free path, e.g. to keep the order of the records
+	featureLocked
+	featureDedup
+)
+
+// writeFeatures returns the features of the Write path enabled by the options
+func (tb *Buffer) writeFeatures() uint32 {
+	var f uint32
+	if tb.writeSizes {
+		f |= featureWriteSizes
+	}
+	// the scheduler and SetMaxLatency add a backlog
+	if tb.backlog != nil || tb.scheduler != nil || tb.maxLatency > 0 {
+		f |= featureBacklog
+	}
+	// the flush policy is called and the writes are appended to the log by the slow path
+	if tb.singleWriter && tb.flushPolicy == nil && tb.wal == nil {
+		f |= featureSingleWriter
+	}
+	if !tb.fastWrites {
+		f |= featureLocked
+	}
+	if tb.dedup != nil {
+		f |= featureDedup
+	}
+	return f
+}
+
+// The small writes reserve space in the active buffer with an atomic cursor and copy
+// their data without locking bufmu. The cursor word packs the reserved offset (low 32 bits),
+// the number of writers copying (bits 32-62) and a sealed flag (bit 63).
+// The goroutines holding bufmu seal the cursor before using the active buffer, once all
+// the copies finish the buffer contains every successful reservation, which are always
+// contiguous from the previous buffered size, and it's reopened when bufmu is released.
+const (
+	cursorPos    = 1<<32 - 1
+	cursorWriter = 1 << 32
+	cursorSealed = 1 << 63
+)
+
+// writeSmall tries the lock free path, returns false if the write must go through the slow path
+func (tb *Buffer) writeSmall(p []byte) bool {
+	r := tb.cursor.Add(cursorWriter | uint64(len(p)))
+	if r&cursorSealed != 0 {
+		tb.cursor.Add(^uint64(cursorWriter - 1))
+		return false
+	}
+	end := int64(r & cursorPos)
+	off := end - int64(len(p))
+	// the buffer can't be swapped while there are writers copying
+	buf := tb.buf.Scratch()
+	if end > int64(len(buf)) {
+		// every reservation after this one fails too, the valid data ends here
+		for {
+			e := tb.cursorEnd.Load()
+			if off >= e || tb.cursorEnd.CompareAndSwap(e, off) {
+				break
+			}
+		}
+		tb.cursor.Add(^uint64(cursorWriter - 1))
+		return false
+	}
+	tb.acct.accept(len(p))
+	if off == 0 && tb.backlog != nil {
+		tb.backlog.stamp(tb)
+	}
+	copy(buf[off:end], p)
+	tb.cursor.Add(^uint64(cursorWriter - 1))
+	return true
+}
+
+// sealCursor disables the lock free path and waits for the copies in progress, the
+// buffered size of the active buffer is updated. The caller must hold bufmu.
+func (tb *Buffer) sealCursor() {
+	var old uint64
+	for {
+		old = tb.cursor.Load()
+		if old&cursorSealed != 0 {
+			// sealed with the buffer closed
+			return
+		}
+		if tb.cursor.CompareAndSwap(old, old|cursorSealed) {
+			break
+		}
+	}
+	for tb.cursor.Load()&^cursorSealed >= cursorWriter {
+		runtime.Gosched()
+	}
+
+	n := int64(old & cursorPos)
+	if n > int64(tb.buf.Cap()) {
+		n = tb.cursorEnd.Load()
+	}
+	tb.buf.SetBuffered(int(n))
+}
+
+// openCursor enables the lock free path from the buffered size of the active buffer,
+// the caller must hold bufmu and have sealed the cursor
+func (tb *Buffer) openCursor() {
+	tb.cursorEnd.Store(math.MaxInt64)
+	for {
+		// keep the count of writers that are backing off a sealed cursor
+		old := tb.cursor.Load()
+		c := old&^(cursorSealed|cursorPos) | uint64(tb.buf.Buffered())
+		if tb.cursor.CompareAndSwap(old, c) {
+			return
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package syncio
+
+import (
+	"io"
+	"sync"
+)
+
+// FileReader reads an io.ReaderAt sequentially fetching the next chunks in parallel, it's meant
+// for files on network filesystems where sequential reads leave the connection idle.
+// The bytes are delivered in order and the error of a chunk is returned when the reader reaches it.
+// Call Close to stop the workers, Read and Close must not be called concurrently.
+type FileReader struct {
+	ra        io.ReaderAt
+	size      int64
+	chunkSize int
+	depth     int
+	workers   int
+
+	// next is the offset of the next chunk to fetch and pending the fetched chunks in order
+	next    int64
+	pending []*chunk
+	jobs    chan *chunk
+	free    chan []byte
+
+	closeOnce sync.Once
+	closed    bool
+}
+
+type chunk struct {
+	off  int64
+	buf  []byte
+	pos  int
+	err  error
+	done chan struct{}
+}
+
+// FileReaderOption is the signature of the FileReader options
+type FileReaderOption func(*FileReader)
+
+// SetReadChunkSize sets the size of the reads done to the io.ReaderAt, the default is 1MB
+func SetReadChunkSize(n int) FileReaderOption {
+	return func(r *FileReader) {
+		r.chunkSize = n
+	}
+}
+
+// SetReadAhead sets the number of chunks fetched ahead of the reader, the default is 4
+func SetReadAhead(n int) FileReaderOption {
+	return func(r *FileReader) {
+		r.depth = n
+	}
+}
+
+// SetReadWorkers sets the number of concurrent reads, the default is the read ahead depth
+func SetReadWorkers(n int) FileReaderOption {
+	return func(r *FileReader) {
+		r.workers = n
+	}
+}
+
+// NewFileReader returns a FileReader for the first size bytes of ra
+func NewFileReader(ra io.ReaderAt, size int64, options ...FileReaderOption) *FileReader {
+	const (
+		defaultChunkSize = 1 << 20
+		defaultDepth     = 4
+	)
+	r := &FileReader{ra: ra, size: size}
+	for _, o := range options {
+		o(r)
+	}
+	if r.chunkSize <= 0 {
+		r.chunkSize = defaultChunkSize
+	}
+	if r.depth <= 0 {
+		r.depth = defaultDepth
+	}
+	if r.workers <= 0 || r.workers > r.depth {
+		r.workers = r.depth
+	}
+	r.jobs = make(chan *chunk, r.depth)
+	r.free = make(chan []byte, r.depth)
+	for i := 0; i < r.workers; i++ {
+		go r.fetch()
+	}
+	return r
+}
+
+func (r *FileReader) fetch() {
+	for c := range r.jobs {
+		n, err := r.ra.ReadAt(c.buf, c.off)
+		if n == len(c.buf) {
+			err = nil
+		} else if err == nil || err == io.EOF {
+			// the size was bigger than the data
+			err = io.ErrUnexpectedEOF
+		}
+		c.buf, c.err = c.buf[:n], err
+		close(c.done)
+	}
+}
+
+// schedule sends the fetches to keep depth chunks ahead
+func (r *FileReader) schedule() {
+	for len(r.pending) < r.depth && r.next < r.size {
+		size := int64(r.chunkSize)
+		if r.size-r.next < size {
+			size = r.size - r.next
+		}
+		var buf []byte
+		select {
+		case buf = <-r.free:
+		default:
+			buf = make([]byte, r.chunkSize)
+		}
+		c := &chunk{off: r.next, buf: buf[:size], done: make(chan struct{})}
+		r.next += size
+		r.pending = append(r.pending, c)
+		// never blocks, there are at most depth jobs
+		r.jobs <- c
+	}
+}
+
+// Read reads the fetched chunks in order, it's not concurrent safe
+func (r *FileReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, ErrReaderClosed
+	}
+	r.schedule()
+	if len(r.pending) == 0 {
+		return 0, io.EOF
+	}
+
+	c := r.pending[0]
+	<-c.done
+	n := copy(p, c.buf[c.pos:])
+	c.pos += n
+	if c.pos < len(c.buf) {
+		return n, nil
+	}
+	if c.err != nil {
+		// keep returning the error of the chunk
+		if n > 0 {
+			return n, nil
+		}
+		return 0, c.err
+	}
+	r.pending[0] = nil
+	r.pending = r.pending[1:]
+	select {
+	case r.free <- c.buf[:cap(c.buf)]:
+	default:
+	}
+	return n, nil
+}
+
+// Close stops the workers once the fetches in progress finish, it doesn't close the io.ReaderAt
+func (r *FileReader) Close() error {
+	r.closeOnce.Do(func() {
+		r.closed = true
+		close(r.jobs)
+	})
+	return nil
+}
@@ -0,0 +1,90 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowReaderAt counts the concurrent reads and fails the reads from failAt
+type slowReaderAt struct {
+	r      *bytes.Reader
+	failAt int64
+	active int32
+	max    int32
+}
+
+var errReadAt = errors.New("read error")
+
+func (s *slowReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n := atomic.AddInt32(&s.active, 1)
+	defer atomic.AddInt32(&s.active, -1)
+	for {
+		m := atomic.LoadInt32(&s.max)
+		if n <= m || atomic.CompareAndSwapInt32(&s.max, m, n) {
+			break
+		}
+	}
+	time.Sleep(2 * time.Millisecond)
+	if s.failAt > 0 && off >= s.failAt {
+		return 0, errReadAt
+	}
+	return s.r.ReadAt(p, off)
+}
+
+func testData(n int) []byte {
+	p := make([]byte, n)
+	for i := range p {
+		p[i] = byte(i)
+	}
+	return p
+}
+
+func TestFileReader(t *testing.T) {
+	data := testData(10000)
+	ra := &slowReaderAt{r: bytes.NewReader(data)}
+	r := NewFileReader(ra, int64(len(data)), SetReadChunkSize(1000), SetReadAhead(4))
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("read data doesn't match")
+	}
+	if ra.max < 2 {
+		t.Errorf("concurrent reads: %v, expected parallel fetches", ra.max)
+	}
+}
+
+func TestFileReaderError(t *testing.T) {
+	data := testData(10000)
+	ra := &slowReaderAt{r: bytes.NewReader(data), failAt: 5000}
+	r := NewFileReader(ra, int64(len(data)), SetReadChunkSize(1000), SetReadAhead(8), SetReadWorkers(2))
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != errReadAt {
+		t.Errorf("read error: %v, expected: %v", err, errReadAt)
+	}
+	// the failed chunk was fetched ahead but the previous bytes are delivered first
+	if !bytes.Equal(got, data[:5000]) {
+		t.Errorf("read %v bytes, expected: %v", len(got), 5000)
+	}
+	if ra.max > 2 {
+		t.Errorf("concurrent reads: %v, expected at most 2 workers", ra.max)
+	}
+}
+
+func TestFileReaderShortSource(t *testing.T) {
+	r := NewFileReader(bytes.NewReader(testData(100)), 150, SetReadChunkSize(64))
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != io.ErrUnexpectedEOF || len(got) != 100 {
+		t.Errorf("read: %v bytes, %v, expected: 100 bytes, %v", len(got), err, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,74 @@
+package syncio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// ErrSinkChain is returned by FlushDeep when the writer chain has a cycle or more than
+// maxSinkChain layers
+var ErrSinkChain = errors.New("invalid writer chain")
+
+// maxSinkChain bounds the layers walked by FlushDeep
+const maxSinkChain = 64
+
+// unwrapper is implemented by the middleware writers giving access to the writer they wrap,
+// as the Unwrap of the errors
+type unwrapper interface {
+	Unwrap() io.Writer
+}
+
+// FlushDeep flushes the buffered data as Sync and then every layer of the underlying writer,
+// so the data can be read back from the file at the end of the chain once it returns. From
+// the underlying writer, each layer is flushed with its Flush method and synced with its
+// Sync method, then the writer returned by its Unwrap() io.Writer method is the next layer.
+// The walk ends at a writer without Unwrap, a Buffer in the chain is flushed by its own
+// FlushDeep. The error is the first error of the layers, or ErrSinkChain for a cycle, the
+// previous layers being flushed.
+func (tb *Buffer) FlushDeep() error {
+	return tb.syncBarrier(true)
+}
+
+// flushChain flushes and syncs the layers of the underlying writer from the flush goroutine
+func (tb *Buffer) flushChain() error {
+	seen := []io.Writer{tb}
+	w := tb.writer
+	for w != nil {
+		// only the pointers are compared, any value can be a writer
+		if reflect.ValueOf(w).Kind() == reflect.Pointer {
+			for _, s := range seen {
+				if s == w {
+					return fmt.Errorf("%w: cycle at %T", ErrSinkChain, w)
+				}
+			}
+		}
+		if len(seen) > maxSinkChain {
+			return fmt.Errorf("%w: more than %v layers", ErrSinkChain, maxSinkChain)
+		}
+		seen = append(seen, w)
+
+		if b, ok := w.(*Buffer); ok {
+			return b.FlushDeep()
+		}
+		if f, ok := w.(flusher); ok {
+			if err := f.Flush(); err != nil {
+				return err
+			}
+		} else if f, ok := w.(httpFlusher); ok {
+			f.Flush()
+		}
+		if s, ok := w.(syncer); ok {
+			if err := s.Sync(); err != nil {
+				return err
+			}
+		}
+		u, ok := w.(unwrapper)
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+	return nil
+}
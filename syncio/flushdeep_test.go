@@ -0,0 +1,128 @@
+package syncio
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// bufferedFile is a middleware buffering in memory the writes to its file
+type bufferedFile struct {
+	*bufio.Writer
+	f *os.File
+}
+
+func (w *bufferedFile) Unwrap() io.Writer {
+	return w.f
+}
+
+// loopWriter unwraps to itself
+type loopWriter struct{ testWriter }
+
+func (w *loopWriter) Unwrap() io.Writer {
+	return w
+}
+
+// endlessWriter is a value unwrapping to a new layer forever
+type endlessWriter struct{ depth int }
+
+func (w endlessWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (w endlessWriter) Unwrap() io.Writer {
+	return endlessWriter{w.depth + 1}
+}
+
+// nilWriter unwraps to nothing
+type nilWriter struct{ testWriter }
+
+func (w *nilWriter) Unwrap() io.Writer {
+	return nil
+}
+
+func TestFlushDeep(t *testing.T) {
+	inModes(t, testFlushDeep)
+}
+
+func testFlushDeep(t *testing.T, mode []BufferOption) {
+	path := filepath.Join(t.TempDir(), "out")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	chain := &bufferedFile{Writer: bufio.NewWriterSize(f, 4096), f: f}
+	tb := NewBuffer(chain, append([]BufferOption{SetBufferSize(1024)}, mode...)...)
+	defer tb.Close()
+
+	tb.Write([]byte("first line\n"))
+	if err := tb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// the data is still in the bufio layer
+	if p, _ := os.ReadFile(path); len(p) != 0 {
+		t.Fatalf("read %q before FlushDeep", p)
+	}
+	tb.Write([]byte("second line\n"))
+	if err := tb.FlushDeep(); err != nil {
+		t.Fatal(err)
+	}
+	p, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p) != "first line\nsecond line\n" {
+		t.Fatalf("read %q after FlushDeep", p)
+	}
+}
+
+func TestFlushDeepParent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	parent := NewBuffer(&bufferedFile{Writer: bufio.NewWriter(f), f: f})
+	defer parent.Close()
+	child := NewBuffer(parent)
+	defer child.Close()
+
+	child.Write([]byte("abc"))
+	if err := child.FlushDeep(); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := os.ReadFile(path); string(p) != "abc" {
+		t.Fatalf("read %q after FlushDeep", p)
+	}
+}
+
+func TestFlushDeepInvalidChain(t *testing.T) {
+	for name, w := range map[string]io.Writer{
+		"cycle":   &loopWriter{},
+		"endless": endlessWriter{},
+	} {
+		tb := NewBuffer(w)
+		tb.Write([]byte("abc"))
+		if err := tb.FlushDeep(); !errors.Is(err, ErrSinkChain) {
+			t.Errorf("%v: %v", name, err)
+		}
+		tb.Close()
+	}
+
+	nw := &nilWriter{}
+	tb := NewBuffer(nw)
+	defer tb.Close()
+	tb.Write([]byte("abc"))
+	if err := tb.FlushDeep(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(&nw.bytes); n != 3 {
+		t.Fatalf("%v bytes written", n)
+	}
+}
@@ -0,0 +1,15 @@
+package syncio
+
+import "time"
+
+// SetFlushHooks sets callbacks invoked from the flush goroutine around every write of a batch
+// to the underlying writer, e.g. to start and end a tracing span: before receives the batch
+// size and after the bytes written, the duration of the write, retries included, and its
+// error. The size is the one after SetFlushTransform. Either hook can be nil. The hooks run on the
+// write path, a slow hook delays the flushes.
+func SetFlushHooks(before func(n int), after func(n int, d time.Duration, err error)) BufferOption {
+	return func(b *Buffer) {
+		b.beforeFlush = before
+		b.afterFlush = after
+	}
+}
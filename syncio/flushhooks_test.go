@@ -0,0 +1,51 @@
+package syncio
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFlushHooks(t *testing.T) {
+	errSink := errors.New("sink failed")
+	fail := false
+	sink := writerFunc(func(p []byte) (int, error) {
+		if fail {
+			return 0, errSink
+		}
+		time.Sleep(5 * time.Millisecond)
+		return len(p), nil
+	})
+	var before []int
+	type flush struct {
+		n   int
+		d   time.Duration
+		err error
+	}
+	var after []flush
+	tb := NewBuffer(sink, SetFlushHooks(
+		func(n int) { before = append(before, n) },
+		func(n int, d time.Duration, err error) { after = append(after, flush{n, d, err}) },
+	))
+	tb.Write([]byte("abc"))
+	if err := tb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	fail = true
+	tb.Write([]byte("defgh"))
+	tb.Flush()
+	tb.Close()
+
+	if len(before) != 2 || before[0] != 3 || before[1] != 5 {
+		t.Errorf("before: %v, expected: [3 5]", before)
+	}
+	if len(after) != 2 {
+		t.Fatalf("after: %v, expected 2 calls", after)
+	}
+	if f := after[0]; f.n != 3 || f.err != nil || f.d < 5*time.Millisecond {
+		t.Errorf("after the first flush: %+v, expected 3 bytes in 5ms at least", f)
+	}
+	if f := after[1]; f.n != 0 || f.err != errSink {
+		t.Errorf("after the failed flush: %+v, expected: 0 bytes, %v", f, errSink)
+	}
+}
@@ -0,0 +1,111 @@
+package syncio
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FlushLatencyBounds are the upper bounds of the FlushLatencyHistogram buckets, the last
+// bucket counts the calls of the last bound or more
+var FlushLatencyBounds = [...]time.Duration{time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond, time.Second, 10 * time.Second}
+
+// FlushLatencyHistogram counts the calls to the underlying writer shorter than every
+// FlushLatencyBounds, and the ones of the last bound or more
+type FlushLatencyHistogram [len(FlushLatencyBounds) + 1]int64
+
+func (h *FlushLatencyHistogram) observe(d time.Duration) {
+	i := 0
+	for i < len(FlushLatencyBounds) && d >= FlushLatencyBounds[i] {
+		i++
+	}
+	atomic.AddInt64(&h[i], 1)
+}
+
+// load copies the histogram reading it atomically
+func (h *FlushLatencyHistogram) load(dst *FlushLatencyHistogram) {
+	for i := range h {
+		dst[i] = atomic.LoadInt64(&h[i])
+	}
+}
+
+// observeFlushLatency counts a call to the underlying writer lasting d, the minimum is kept
+// plus one so 0 means no call
+func (s *Stats) observeFlushLatency(d time.Duration) {
+	s.FlushLatencies.observe(d)
+	atomic.AddInt64((*int64)(&s.FlushLatencySum), int64(d))
+	for {
+		m := atomic.LoadInt64((*int64)(&s.FlushLatencyMin))
+		if m != 0 && m <= int64(d)+1 || atomic.CompareAndSwapInt64((*int64)(&s.FlushLatencyMin), m, int64(d)+1) {
+			break
+		}
+	}
+	for {
+		m := atomic.LoadInt64((*int64)(&s.FlushLatencyMax))
+		if m >= int64(d) || atomic.CompareAndSwapInt64((*int64)(&s.FlushLatencyMax), m, int64(d)) {
+			break
+		}
+	}
+}
+
+// loadFlushLatencyRange copies the sum, minimum and maximum of the flush latencies into dst
+func (s *Stats) loadFlushLatencyRange(dst *Stats) {
+	dst.FlushLatencySum = time.Duration(atomic.LoadInt64((*int64)(&s.FlushLatencySum)))
+	dst.FlushLatencyMin = 0
+	if m := atomic.LoadInt64((*int64)(&s.FlushLatencyMin)); m != 0 {
+		dst.FlushLatencyMin = time.Duration(m - 1)
+	}
+	dst.FlushLatencyMax = time.Duration(atomic.LoadInt64((*int64)(&s.FlushLatencyMax)))
+}
+
+// FlushLatencyAvg returns the average duration of the calls to the underlying writer, 0
+// without calls. Of a Delta it's the average between the two Stats.
+func (s Stats) FlushLatencyAvg() time.Duration {
+	calls := s.FlushLatencies.calls()
+	if calls == 0 {
+		return 0
+	}
+	return s.FlushLatencySum / time.Duration(calls)
+}
+
+// calls returns the number of calls counted by the histogram
+func (h *FlushLatencyHistogram) calls() int64 {
+	var n int64
+	for _, c := range h {
+		n += c
+	}
+	return n
+}
+
+// bucketName returns the name of the bucket i, e.g. "<10ms" or ">=10s"
+func (h *FlushLatencyHistogram) bucketName(i int) string {
+	if i < len(FlushLatencyBounds) {
+		return "<" + FlushLatencyBounds[i].String()
+	}
+	return ">=" + FlushLatencyBounds[len(FlushLatencyBounds)-1].String()
+}
+
+// String returns the counts of the buckets, e.g. "<1ms:3 <10ms:0 ... >=10s:1"
+func (h FlushLatencyHistogram) String() string {
+	var sb strings.Builder
+	for i, n := range h {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(h.bucketName(i))
+		sb.WriteByte(':')
+		sb.WriteString(strconv.FormatInt(n, 10))
+	}
+	return sb.String()
+}
+
+// MarshalJSON returns an object with the counts by bucket name
+func (h FlushLatencyHistogram) MarshalJSON() ([]byte, error) {
+	m := make(map[string]int64, len(h))
+	for i, n := range h {
+		m[h.bucketName(i)] = n
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,41 @@
+package syncio
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFlushLatencyHistogram(t *testing.T) {
+	clock := newFakeClock()
+	latencies := []time.Duration{0, 999 * time.Microsecond, time.Millisecond, 50 * time.Millisecond, 2 * time.Second, time.Minute}
+	calls := 0
+	tb := NewBuffer(writerFunc(func(p []byte) (int, error) {
+		clock.Advance(latencies[calls])
+		calls++
+		return len(p), nil
+	}), SetClock(clock), SetBufferSize(4))
+	for range latencies {
+		tb.Write([]byte("abcd"))
+		tb.Flush()
+	}
+	tb.Close()
+
+	s := tb.Stats()
+	expected := FlushLatencyHistogram{2, 1, 1, 0, 1, 1}
+	if s.FlushLatencies != expected {
+		t.Errorf("flush latencies: %v, expected: %v", s.FlushLatencies, expected)
+	}
+	if str := s.FlushLatencies.String(); str != "<1ms:2 <10ms:1 <100ms:1 <1s:0 <10s:1 >=10s:1" {
+		t.Errorf("flush latencies string: %q", str)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct{ FlushLatencies map[string]int64 }
+	json.Unmarshal(data, &decoded)
+	if decoded.FlushLatencies["<1ms"] != 2 || decoded.FlushLatencies[">=10s"] != 1 || len(decoded.FlushLatencies) != len(expected) {
+		t.Errorf("flush latencies json: %s", data)
+	}
+}
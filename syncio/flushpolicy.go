@@ -0,0 +1,102 @@
+package syncio
+
+import "time"
+
+// FlushPolicy decides when the active buffer is flushed besides the full buffer, e.g. on the
+// number of records or on a memory pressure signal. ShouldFlush is called after every write and
+// at every tick holding the Buffer lock, it must be fast and must not call the Buffer.
+type FlushPolicy interface {
+	ShouldFlush(s FlushState) bool
+}
+
+// FlushState is the state of the active buffer given to a FlushPolicy
+type FlushState struct {
+	// Pending and Writes are the bytes and the writes in the active buffer
+	Pending int
+	Writes  int
+	// SinceLast is the time since the last flush, or since NewBuffer
+	SinceLast time.Duration
+	// Tick is set when the policy is called by a tick instead of a write
+	Tick bool
+}
+
+// FlushPolicyFunc is a function implementing FlushPolicy
+type FlushPolicyFunc func(s FlushState) bool
+
+func (f FlushPolicyFunc) ShouldFlush(s FlushState) bool {
+	return f(s)
+}
+
+// FlushOnBytes flushes once n bytes are buffered
+func FlushOnBytes(n int) FlushPolicy {
+	return FlushPolicyFunc(func(s FlushState) bool {
+		return s.Pending >= n
+	})
+}
+
+// FlushOnWrites flushes once n writes are buffered, the records with SetRecordMode
+func FlushOnWrites(n int) FlushPolicy {
+	return FlushPolicyFunc(func(s FlushState) bool {
+		return s.Writes >= n
+	})
+}
+
+// FlushOnAge flushes the buffered data d after the last flush, it's checked by the writes and
+// the ticks so the ticks bound the delay when there are no writes
+func FlushOnAge(d time.Duration) FlushPolicy {
+	return FlushPolicyFunc(func(s FlushState) bool {
+		return s.Pending > 0 && s.SinceLast >= d
+	})
+}
+
+// AnyFlushPolicy flushes when any of the policies does
+func AnyFlushPolicy(policies ...FlushPolicy) FlushPolicy {
+	return FlushPolicyFunc(func(s FlushState) bool {
+		for _, p := range policies {
+			if p.ShouldFlush(s) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// SetFlushPolicy sets the policy deciding the flushes, they have TriggerPolicy. The ticks of
+// SetFlushInterval only call the policy instead of flushing, AnyFlushPolicy with FlushOnAge
+// keeps the flushes by interval. The full buffer still flushes with TriggerSize. The policy
+// disables the lock free path of the small writes.
+func SetFlushPolicy(p FlushPolicy) BufferOption {
+	return func(b *Buffer) {
+		if p == nil {
+			b.flushPolicy = nil
+			return
+		}
+		b.flushPolicy = &flushPolicy{policy: p}
+	}
+}
+
+// flushPolicy is the state of SetFlushPolicy, it's guarded by bufmu
+type flushPolicy struct {
+	policy FlushPolicy
+	writes int
+	last   time.Time
+}
+
+// check calls the policy and flushes the active buffer if it decides so, the caller must
+// own the active buffer
+func (fp *flushPolicy) check(tb *Buffer, tick bool) (sw swap) {
+	if tb.buf.Buffered() == 0 {
+		return
+	}
+	s := FlushState{Pending: tb.buf.Buffered(), Writes: fp.writes, SinceLast: tb.clock.Now().Sub(fp.last), Tick: tick}
+	if !fp.policy.ShouldFlush(s) {
+		return
+	}
+	return tb.flush(TriggerPolicy, nil)
+}
+
+// flushed restarts the state, the caller must hold bufmu
+func (fp *flushPolicy) flushed(tb *Buffer) {
+	fp.writes = 0
+	fp.last = tb.clock.Now()
+}
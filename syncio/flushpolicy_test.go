@@ -0,0 +1,64 @@
+package syncio
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFlushPolicyWrites(t *testing.T) {
+	rw := &recordingWriter{}
+	tb := NewBuffer(rw, SetSynchronousMode(true), SetFlushPolicy(FlushOnWrites(3)))
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		tb.Write([]byte(s))
+	}
+	if !reflect.DeepEqual(rw.writes, []string{"abc"}) {
+		t.Errorf("writes: %q, expected: %q", rw.writes, []string{"abc"})
+	}
+	tb.Close()
+	if !reflect.DeepEqual(rw.writes, []string{"abc", "de"}) {
+		t.Errorf("writes: %q, expected: %q", rw.writes, []string{"abc", "de"})
+	}
+}
+
+func TestFlushPolicyTick(t *testing.T) {
+	clock := newFakeClock()
+	rw := &recordingWriter{}
+	var ticks int
+	policy := AnyFlushPolicy(FlushOnAge(time.Second), FlushPolicyFunc(func(s FlushState) bool {
+		if s.Tick {
+			ticks++
+		}
+		return false
+	}))
+	tb := NewBuffer(rw, SetClock(clock), SetSynchronousMode(true), SetManualTick(true),
+		SetFlushInterval(100*time.Millisecond), SetFlushPolicy(policy))
+	defer tb.Close()
+
+	tb.Write([]byte("a"))
+	clock.Advance(500 * time.Millisecond)
+	tb.Tick()
+	if len(rw.writes) != 0 {
+		t.Errorf("writes before the age: %q", rw.writes)
+	}
+	clock.Advance(500 * time.Millisecond)
+	tb.Tick()
+	if !reflect.DeepEqual(rw.writes, []string{"a"}) {
+		t.Errorf("writes: %q, expected: %q", rw.writes, []string{"a"})
+	}
+	if ticks != 1 {
+		t.Errorf("policy ticks: %v, expected: 1", ticks)
+	}
+
+	// the age restarts with the flush
+	tb.Write([]byte("b"))
+	clock.Advance(500 * time.Millisecond)
+	tb.Tick()
+	if len(rw.writes) != 1 {
+		t.Errorf("writes: %q, expected 1", rw.writes)
+	}
+	s := tb.Stats()
+	if s.Flushes != 1 {
+		t.Errorf("flushes: %v, expected: 1", s.Flushes)
+	}
+}
@@ -0,0 +1,130 @@
+package syncio
+
+import "sync"
+
+// SetFlushConcurrency writes up to n batches to the underlying writer at the same time from n
+// goroutines, for the sinks with slow but independent writes, e.g. the parts of an object
+// storage upload. The work around the writes is still done one batch at a time: the flush
+// transforms, the dead letter, the callbacks and the stats. With SetPreserveOrder, the default,
+// the writes keep the queue order, a batch is written once the previous one is complete, and
+// the workers only overlap the transforms with the writes. The barriers of Flush, Sync, Seek
+// and SwapWriter wait for the batches in flight. It's ignored with the options relying on a
+// single write in flight: SetSynchronousMode, SetRecordMode, SetFraming, SetSinkHeader,
+// SetFlushContext, SetAdaptiveInterval, SetRetention, SetHeadRetention, SetMaxFlushBytes and
+// when writing into another Buffer; a routing writer is written one batch at a time.
+func SetFlushConcurrency(n int) BufferOption {
+	return func(b *Buffer) {
+		b.flushConcurrency = n
+	}
+}
+
+// SetPreserveOrder keeps the order of the writes with SetFlushConcurrency, true by default.
+// Without it the batches are written in any order, for the sinks that don't need it.
+func SetPreserveOrder(preserve bool) BufferOption {
+	return func(b *Buffer) {
+		b.unordered = !preserve
+	}
+}
+
+// flushWorkers are the goroutines of SetFlushConcurrency. mu guards the flush state the
+// single flush goroutine has otherwise, it's released while writing to the underlying writer.
+type flushWorkers struct {
+	ordered bool
+	jobs    chan *batch
+	// inflight are the batches handed to the workers and not complete
+	inflight sync.WaitGroup
+	mu       sync.Mutex
+
+	// with ordered, the batch with the ticket next is the one that can write, tickets is the
+	// last ticket given
+	turnmu  sync.Mutex
+	turn    *sync.Cond
+	next    uint64
+	tickets uint64
+}
+
+// startWorkers starts the goroutines of SetFlushConcurrency
+func (tb *Buffer) startWorkers() {
+	if tb.flushConcurrency <= 1 || tb.recordMode || tb.framing != nil || tb.header != nil ||
+		tb.flushContext != nil || tb.adaptiveInterval != nil || tb.retention != nil ||
+		tb.head != nil || tb.maxFlushBytes > 0 || tb.parent != nil {
+		return
+	}
+	w := &flushWorkers{ordered: !tb.unordered, jobs: make(chan *batch), next: 1}
+	w.turn = sync.NewCond(&w.turnmu)
+	tb.workers = w
+	for i := 0; i < tb.flushConcurrency; i++ {
+		go w.work(tb)
+	}
+}
+
+// run writes a group from the flush goroutine, the single batches go to the workers and the
+// rest is written once the batches in flight are complete
+func (w *flushWorkers) run(tb *Buffer, group []*batch) {
+	b := group[0]
+	if _, routed := tb.writer.(batchRouter); len(group) == 1 && !routed &&
+		b.done == nil && b.swap == nil && b.seek == nil && b.sync == nil {
+		if w.ordered {
+			w.tickets++
+			b.ticket = w.tickets
+		}
+		b.dispatched = true
+		tb.acct.dispatch(b.len())
+		w.inflight.Add(1)
+		w.jobs <- b
+		return
+	}
+	w.inflight.Wait()
+	w.mu.Lock()
+	tb.writeGroup(group)
+	w.mu.Unlock()
+}
+
+// work writes the batches handed by the flush goroutine
+func (w *flushWorkers) work(tb *Buffer) {
+	var group [1]*batch
+	for b := range w.jobs {
+		ticket := b.ticket
+		group[0] = b
+		w.mu.Lock()
+		tb.writeGroup(group[:])
+		w.mu.Unlock()
+		if ticket != 0 {
+			// the next batch writes once this one is complete
+			w.wait(ticket)
+			w.turnmu.Lock()
+			w.next++
+			w.turn.Broadcast()
+			w.turnmu.Unlock()
+		}
+		w.inflight.Done()
+	}
+}
+
+// wait waits for the turn of the ticket
+func (w *flushWorkers) wait(ticket uint64) {
+	w.turnmu.Lock()
+	for w.next != ticket {
+		w.turn.Wait()
+	}
+	w.turnmu.Unlock()
+}
+
+// sinkStart releases mu while writing b to the underlying writer, once it's its turn
+func (w *flushWorkers) sinkStart(b *batch) {
+	w.mu.Unlock()
+	if b.ticket != 0 {
+		w.wait(b.ticket)
+	}
+}
+
+// sinkEnd takes mu back after writing to the underlying writer
+func (w *flushWorkers) sinkEnd() {
+	w.mu.Lock()
+}
+
+// stop ends the workers once the batches in flight are complete
+func (w *flushWorkers) stop() {
+	w.inflight.Wait()
+	close(w.jobs)
+}
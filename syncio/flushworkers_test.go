@@ -0,0 +1,107 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrentWriter records the writes and the maximum number of them at the same time
+type concurrentWriter struct {
+	delay time.Duration
+	err   error
+
+	mu      sync.Mutex
+	active  int
+	maxSeen int
+	writes  []string
+}
+
+func (w *concurrentWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	w.active++
+	if w.active > w.maxSeen {
+		w.maxSeen = w.active
+	}
+	w.mu.Unlock()
+	time.Sleep(w.delay)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.active--
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+// writeBatches writes n batches of a buffer of 16 bytes
+func writeBatches(tb *Buffer, n int) []string {
+	var batches []string
+	for i := 0; i < n; i++ {
+		p := bytes.Repeat([]byte{'a' + byte(i)}, 16)
+		batches = append(batches, string(p))
+		tb.Write(p)
+	}
+	return batches
+}
+
+func TestFlushConcurrency(t *testing.T) {
+	sink := &concurrentWriter{delay: 20 * time.Millisecond}
+	tb := NewBuffer(sink, SetBufferSize(16), SetBufferPoolSize(8), SetFlushConcurrency(4), SetPreserveOrder(false))
+	batches := writeBatches(tb, 8)
+	if err := tb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// Flush waits for the batches in flight
+	if len(sink.writes) != len(batches) {
+		t.Fatalf("writes after flush: %v, expected: %v", len(sink.writes), len(batches))
+	}
+	if sink.maxSeen < 2 || sink.maxSeen > 4 {
+		t.Errorf("concurrent writes: %v, expected between 2 and 4", sink.maxSeen)
+	}
+	sort.Strings(sink.writes)
+	if strings.Join(sink.writes, "") != strings.Join(batches, "") {
+		t.Errorf("written: %q, expected: %q", sink.writes, batches)
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlushConcurrencyOrdered(t *testing.T) {
+	sink := &concurrentWriter{delay: time.Millisecond}
+	tb := NewBuffer(sink, SetBufferSize(16), SetBufferPoolSize(8), SetFlushConcurrency(4))
+	batches := writeBatches(tb, 20)
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if sink.maxSeen != 1 {
+		t.Errorf("concurrent writes: %v, expected: 1", sink.maxSeen)
+	}
+	if strings.Join(sink.writes, ",") != strings.Join(batches, ",") {
+		t.Errorf("written: %q, expected: %q", sink.writes, batches)
+	}
+}
+
+func TestFlushConcurrencyErrors(t *testing.T) {
+	errSink := errors.New("sink failed")
+	sink := &concurrentWriter{err: errSink}
+	var dl lockedBuffer
+	tb := NewBuffer(sink, SetBufferSize(16), SetFlushConcurrency(2), SetPreserveOrder(false), SetDeadLetter(&dl))
+	writeBatches(tb, 4)
+	if err := tb.Flush(); !errors.Is(err, errSink) {
+		t.Errorf("flush: %v, expected: %v", err, errSink)
+	}
+	tb.Close()
+	if dl.Len() != 4*16 {
+		t.Errorf("dead letter: %v bytes, expected: %v", dl.Len(), 4*16)
+	}
+	if s := tb.Stats(); s.FlushErrors != 4 {
+		t.Errorf("flush errors: %v, expected: 4", s.FlushErrors)
+	}
+}
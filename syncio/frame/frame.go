@@ -0,0 +1,196 @@
+// Package frame length-prefixes the messages of a stream so their boundaries survive the
+// buffering and the transport: Writer writes every message with its length before it and
+// Reader returns the messages one at a time. A Writer is a syncio.RecordWriter, as the
+// underlying writer of a syncio.Buffer with SetRecordMode every Write to the Buffer is a
+// message and the messages of a flush are written at once.
+package frame
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DefaultMaxSize is the default maximum size of a message
+const DefaultMaxSize = 64 << 20
+
+// ErrTooLarge is matched by the errors of the messages bigger than the maximum size
+var ErrTooLarge = errors.New("frame: message too large")
+
+// Prefix is the encoding of the message lengths
+type Prefix int
+
+const (
+	Varint Prefix = iota // unsigned varint as encoding/binary.AppendUvarint, the default
+	Uint32               // big endian uint32
+)
+
+// Option is an option of NewWriter and NewReader, both sides of a stream must use the same
+type Option func(*config)
+
+type config struct {
+	prefix  Prefix
+	maxSize int
+}
+
+// SetPrefix sets the encoding of the lengths, Varint by default
+func SetPrefix(p Prefix) Option {
+	return func(c *config) {
+		c.prefix = p
+	}
+}
+
+// SetMaxSize sets the maximum size of a message, DefaultMaxSize by default. The Writer fails
+// the bigger messages and the Reader stops at them, so a corrupted length doesn't allocate it.
+func SetMaxSize(n int) Option {
+	return func(c *config) {
+		c.maxSize = n
+	}
+}
+
+func newConfig(options []Option) config {
+	c := config{maxSize: DefaultMaxSize}
+	for _, o := range options {
+		o(&c)
+	}
+	if c.maxSize <= 0 || c.prefix == Uint32 && uint64(c.maxSize) > 1<<32-1 {
+		c.maxSize = DefaultMaxSize
+	}
+	return c
+}
+
+// appendLen appends the length prefix of a message of n bytes
+func (c *config) appendLen(dst []byte, n int) []byte {
+	if c.prefix == Uint32 {
+		return binary.BigEndian.AppendUint32(dst, uint32(n))
+	}
+	return binary.AppendUvarint(dst, uint64(n))
+}
+
+// lenSize returns the size of the length prefix of a message of n bytes
+func (c *config) lenSize(n int) int {
+	if c.prefix == Uint32 {
+		return 4
+	}
+	var b [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(b[:], uint64(n))
+}
+
+func (c *config) tooLarge(n uint64) error {
+	return fmt.Errorf("%w: %v bytes, the maximum is %v", ErrTooLarge, n, c.maxSize)
+}
+
+// Writer writes every Write as a message with one Write to the underlying writer, so a
+// message isn't split by a syncio.Buffer. It's safe for concurrent use.
+type Writer struct {
+	w   io.Writer
+	cfg config
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewWriter returns a Writer of the messages to w
+func NewWriter(w io.Writer, options ...Option) *Writer {
+	return &Writer{w: w, cfg: newConfig(options)}
+}
+
+// Write writes p as a message, it returns len(p) once the whole message is written
+func (w *Writer) Write(p []byte) (int, error) {
+	if len(p) > w.cfg.maxSize {
+		return 0, w.cfg.tooLarge(uint64(len(p)))
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.cfg.appendLen(w.buf[:0], len(p)), p...)
+	n, err := w.w.Write(w.buf)
+	if err == nil && n < len(w.buf) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteBatch writes every record as a message with one Write to the underlying writer, it
+// returns the number of messages written whole, as syncio.RecordWriter
+func (w *Writer) WriteBatch(records [][]byte) (int, error) {
+	for _, r := range records {
+		if len(r) > w.cfg.maxSize {
+			return 0, w.cfg.tooLarge(uint64(len(r)))
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = w.buf[:0]
+	for _, r := range records {
+		w.buf = append(w.cfg.appendLen(w.buf, len(r)), r...)
+	}
+	n, err := w.w.Write(w.buf)
+	if err == nil && n < len(w.buf) {
+		err = io.ErrShortWrite
+	}
+	if err == nil {
+		return len(records), nil
+	}
+	// the messages before the short write
+	written, off := 0, 0
+	for _, r := range records {
+		if off += w.cfg.lenSize(len(r)) + len(r); off > n {
+			break
+		}
+		written++
+	}
+	return written, err
+}
+
+// Reader reads the messages written by a Writer, it's not safe for concurrent use
+type Reader struct {
+	r   *bufio.Reader
+	cfg config
+	buf []byte
+}
+
+// NewReader returns a Reader of the messages of r
+func NewReader(r io.Reader, options ...Option) *Reader {
+	return &Reader{r: bufio.NewReader(r), cfg: newConfig(options)}
+}
+
+// Next returns the next message, valid until the next call. It returns io.EOF at the end of
+// the stream between messages and io.ErrUnexpectedEOF for a message cut short.
+func (r *Reader) Next() ([]byte, error) {
+	n, err := r.readLen()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.cfg.maxSize) {
+		return nil, r.cfg.tooLarge(n)
+	}
+	if uint64(cap(r.buf)) < n {
+		r.buf = make([]byte, n)
+	}
+	p := r.buf[:n]
+	if _, err := io.ReadFull(r.r, p); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// readLen reads the length prefix of the next message
+func (r *Reader) readLen() (uint64, error) {
+	if r.cfg.prefix == Uint32 {
+		var b [4]byte
+		if _, err := io.ReadFull(r.r, b[:]); err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(b[:])), nil
+	}
+	return binary.ReadUvarint(r.r)
+}
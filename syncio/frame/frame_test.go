@@ -0,0 +1,95 @@
+package frame
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+func TestFrame(t *testing.T) {
+	messages := []string{"", "a", strings.Repeat("b", 300), "hello"}
+	for _, prefix := range []Prefix{Varint, Uint32} {
+		var stream bytes.Buffer
+		w := NewWriter(&stream, SetPrefix(prefix))
+		for _, m := range messages {
+			if n, err := w.Write([]byte(m)); n != len(m) || err != nil {
+				t.Fatalf("prefix %v: write: %v, %v", prefix, n, err)
+			}
+		}
+		r := NewReader(&stream, SetPrefix(prefix))
+		for _, m := range messages {
+			p, err := r.Next()
+			if err != nil || string(p) != m {
+				t.Fatalf("prefix %v: next: %q, %v, expected: %q", prefix, p, err, m)
+			}
+		}
+		if _, err := r.Next(); err != io.EOF {
+			t.Errorf("prefix %v: next at the end: %v, expected: %v", prefix, err, io.EOF)
+		}
+	}
+}
+
+func TestFrameErrors(t *testing.T) {
+	var stream bytes.Buffer
+	w := NewWriter(&stream, SetMaxSize(4))
+	if _, err := w.Write([]byte("12345")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("write too large: %v, expected: %v", err, ErrTooLarge)
+	}
+	if _, err := NewReader(bytes.NewReader([]byte{9, 'a'}), SetMaxSize(4)).Next(); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("read too large: %v, expected: %v", err, ErrTooLarge)
+	}
+	if _, err := NewReader(bytes.NewReader([]byte{3, 'a'})).Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated message: %v, expected: %v", err, io.ErrUnexpectedEOF)
+	}
+	if _, err := NewReader(bytes.NewReader([]byte{0, 0}), SetPrefix(Uint32)).Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated length: %v, expected: %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+// shortWriter accepts n bytes
+type shortWriter struct {
+	n   int
+	out bytes.Buffer
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		p = p[:w.n]
+	}
+	w.n -= len(p)
+	return w.out.Write(p)
+}
+
+func TestFrameWriteBatch(t *testing.T) {
+	// 2 messages of 1 + 3 bytes and part of the third
+	w := NewWriter(&shortWriter{n: 10}, SetPrefix(Varint))
+	n, err := w.WriteBatch([][]byte{[]byte("abc"), []byte("def"), []byte("ghi")})
+	if n != 2 || err != io.ErrShortWrite {
+		t.Errorf("short batch: %v, %v, expected: 2, %v", n, err, io.ErrShortWrite)
+	}
+}
+
+func TestFrameBuffer(t *testing.T) {
+	var stream bytes.Buffer
+	tb := syncio.NewBuffer(NewWriter(&stream), syncio.SetRecordMode(true), syncio.SetBufferSize(32))
+	messages := []string{"first", "second message", "third", strings.Repeat("x", 100), "last"}
+	for _, m := range messages {
+		tb.Write([]byte(m))
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s := tb.Stats(); s.Records != int64(len(messages)) {
+		t.Errorf("records: %v, expected: %v", s.Records, len(messages))
+	}
+	r := NewReader(&stream)
+	for _, m := range messages {
+		if p, err := r.Next(); err != nil || string(p) != m {
+			t.Fatalf("next: %q, %v, expected: %q", p, err, m)
+		}
+	}
+}
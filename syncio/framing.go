@@ -0,0 +1,250 @@
+package syncio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync/atomic"
+)
+
+// The frames of SetFraming are, all little endian:
+//
+//	magic    4 bytes "SYBF"
+//	version  uint8, frameVersion
+//	flags    uint8, FrameFlags
+//	sequence uint64, the number of frames written before by the Buffer
+//	count    uint32, the number of records
+//	lengths  count uint32, the length of every record
+//	payload  the records concatenated
+//	crc      uint32, the CRC32C of the previous bytes of the frame, only with FrameChecksum
+const (
+	frameMagic      = "SYBF"
+	frameVersion    = 1
+	frameHeaderSize = len(frameMagic) + 2 + 8 + 4
+	// maxFrameRecords and maxFramePayload bound the memory of a corrupted frame
+	maxFrameRecords = 1 << 24
+	maxFramePayload = 1 << 30
+)
+
+// FrameFlags are the flags of a frame
+type FrameFlags uint8
+
+const (
+	// FrameChecksum is set when the frame ends with a CRC32C
+	FrameChecksum FrameFlags = 1 << iota
+	// FrameCompressed marks the payload as compressed by the SetCompression or the
+	// SetFlushTransform of the writer, the frame format doesn't define the compression
+	FrameCompressed
+
+	frameKnownFlags = FrameChecksum | FrameCompressed
+)
+
+// The errors of BatchReader and ParseFrame, matched by the Err of a *FrameError
+var (
+	ErrFrameMagic     = errors.New("not a frame")
+	ErrFrameVersion   = errors.New("unknown frame version")
+	ErrFrameFlags     = errors.New("unknown frame flags")
+	ErrFrameChecksum  = errors.New("frame checksum mismatch")
+	ErrFrameTruncated = errors.New("truncated frame")
+	ErrFrameTooLarge  = errors.New("frame too large")
+)
+
+// FrameError is an invalid frame at Offset of the stream
+type FrameError struct {
+	Offset int64
+	Err    error
+}
+
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("frame at offset %v: %v", e.Offset, e.Err)
+}
+
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
+
+// SetFraming writes every batch as a frame with the records of SetRecordMode, or the batch as
+// a single record, that BatchReader reads back. flags are the flags of the frames, with
+// FrameChecksum they end with a CRC32C. The frame is written with the data transformed by
+// SetFlushTransform, it replaces the WriteBatch of a RecordWriter and the vectored writes.
+func SetFraming(flags FrameFlags) BufferOption {
+	return func(b *Buffer) {
+		b.framing = &framing{flags: flags}
+	}
+}
+
+// framing is the state of SetFraming used by the flush goroutine, frame is the scratch
+// memory of the encoded frame
+type framing struct {
+	flags FrameFlags
+	seq   uint64
+	frame []byte
+}
+
+// writeFrame writes the batch as a frame, it returns the bytes of p written, all or none
+func (tb *Buffer) writeFrame(b *batch, p []byte) (int, error) {
+	fr := tb.framing
+	records := tb.records[:0]
+	if tb.recordMode && b.buf != nil {
+		records = b.buf.Records(records)
+		atomic.AddInt64(&tb.stats.Records, int64(len(records)))
+	} else {
+		records = append(records, p)
+	}
+	tb.records = records
+	f := Frame{Flags: fr.flags, Sequence: fr.seq, Records: records}
+	if tb.compressor != nil && !tb.recordMode {
+		f.Flags |= FrameCompressed
+	}
+	fr.frame = AppendFrame(fr.frame[:0], &f)
+	for i := range records {
+		records[i] = nil
+	}
+	_, err := tb.writeChunks(fr.frame, nil)
+	if err != nil {
+		return 0, err
+	}
+	fr.seq++
+	return len(p), nil
+}
+
+// Frame is a batch of records with the wire format of SetFraming
+type Frame struct {
+	Flags    FrameFlags
+	Sequence uint64
+	Records  [][]byte
+}
+
+// AppendFrame appends f encoded to dst, with the CRC32C if f.Flags has FrameChecksum
+func AppendFrame(dst []byte, f *Frame) []byte {
+	start := len(dst)
+	dst = append(dst, frameMagic...)
+	dst = append(dst, frameVersion, byte(f.Flags))
+	dst = binary.LittleEndian.AppendUint64(dst, f.Sequence)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(f.Records)))
+	for _, r := range f.Records {
+		dst = binary.LittleEndian.AppendUint32(dst, uint32(len(r)))
+	}
+	for _, r := range f.Records {
+		dst = append(dst, r...)
+	}
+	if f.Flags&FrameChecksum != 0 {
+		dst = binary.LittleEndian.AppendUint32(dst, crc32.Checksum(dst[start:], castagnoli))
+	}
+	return dst
+}
+
+// ParseFrame decodes the frame at the start of p, it returns the frame size. The records
+// point into p. The errors are a *FrameError at offset 0.
+func ParseFrame(p []byte) (Frame, int, error) {
+	r := NewBatchReader(bytes.NewReader(p))
+	f, err := r.Next()
+	if err == io.EOF {
+		err = &FrameError{Err: ErrFrameTruncated}
+	}
+	if err != nil {
+		return Frame{}, 0, err
+	}
+	// the records are copied by the reader, they're sliced from p instead
+	off := frameHeaderSize + 4*len(f.Records)
+	for i, rec := range f.Records {
+		f.Records[i] = p[off : off+len(rec) : off+len(rec)]
+		off += len(rec)
+	}
+	return *f, int(r.offset), nil
+}
+
+// BatchReader reads the frames of a stream written with SetFraming, it validates the checksums
+// and rejects the unknown versions and flags with a *FrameError
+type BatchReader struct {
+	r      *bufio.Reader
+	offset int64
+	buf    []byte
+	frame  Frame
+}
+
+// NewBatchReader returns a reader of the frames of r
+func NewBatchReader(r io.Reader) *BatchReader {
+	return &BatchReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next frame, its records are valid until the next call. It returns io.EOF
+// at the end of the stream between frames, a frame cut short is ErrFrameTruncated.
+func (r *BatchReader) Next() (*Frame, error) {
+	start := r.offset
+	fail := func(err error) (*Frame, error) {
+		return nil, &FrameError{Offset: start, Err: err}
+	}
+	buf := r.buf[:0]
+	var err error
+	if buf, err = r.read(buf, frameHeaderSize); err != nil {
+		if err == io.EOF && len(buf) == 0 {
+			return nil, io.EOF
+		}
+		return fail(ErrFrameTruncated)
+	}
+	if string(buf[:len(frameMagic)]) != frameMagic {
+		return fail(ErrFrameMagic)
+	}
+	if v := buf[len(frameMagic)]; v != frameVersion {
+		return fail(fmt.Errorf("%w: %v", ErrFrameVersion, v))
+	}
+	flags := FrameFlags(buf[len(frameMagic)+1])
+	if flags&^frameKnownFlags != 0 {
+		return fail(fmt.Errorf("%w: %#x", ErrFrameFlags, byte(flags)))
+	}
+	seq := binary.LittleEndian.Uint64(buf[len(frameMagic)+2:])
+	count := binary.LittleEndian.Uint32(buf[frameHeaderSize-4:])
+	if count > maxFrameRecords {
+		return fail(fmt.Errorf("%w: %v records", ErrFrameTooLarge, count))
+	}
+	if buf, err = r.read(buf, 4*int(count)); err != nil {
+		return fail(ErrFrameTruncated)
+	}
+	var size uint64
+	for i := 0; i < int(count); i++ {
+		size += uint64(binary.LittleEndian.Uint32(buf[frameHeaderSize+4*i:]))
+	}
+	if size > maxFramePayload {
+		return fail(fmt.Errorf("%w: %v bytes", ErrFrameTooLarge, size))
+	}
+	if buf, err = r.read(buf, int(size)); err != nil {
+		return fail(ErrFrameTruncated)
+	}
+	if flags&FrameChecksum != 0 {
+		end := len(buf)
+		if buf, err = r.read(buf, 4); err != nil {
+			return fail(ErrFrameTruncated)
+		}
+		if crc32.Checksum(buf[:end], castagnoli) != binary.LittleEndian.Uint32(buf[end:]) {
+			return fail(ErrFrameChecksum)
+		}
+	}
+	r.buf = buf
+
+	records := r.frame.Records[:0]
+	off := frameHeaderSize + 4*int(count)
+	for i := 0; i < int(count); i++ {
+		n := int(binary.LittleEndian.Uint32(buf[frameHeaderSize+4*i:]))
+		records = append(records, buf[off:off+n:off+n])
+		off += n
+	}
+	r.frame = Frame{Flags: flags, Sequence: seq, Records: records}
+	return &r.frame, nil
+}
+
+// read appends n bytes of the stream to buf
+func (r *BatchReader) read(buf []byte, n int) ([]byte, error) {
+	start := len(buf)
+	if cap(buf)-start < n {
+		buf = append(buf[:cap(buf)], make([]byte, start+n-cap(buf))...)
+	}
+	buf = buf[:start+n]
+	m, err := io.ReadFull(r.r, buf[start:])
+	r.offset += int64(m)
+	return buf[:start+m], err
+}
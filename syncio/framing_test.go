@@ -0,0 +1,98 @@
+package syncio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	for _, flags := range []FrameFlags{0, FrameChecksum, FrameChecksum | FrameCompressed} {
+		f := Frame{Flags: flags, Sequence: 7, Records: [][]byte{[]byte("a"), {}, []byte("bcd")}}
+		p := AppendFrame([]byte("prefix"), &f)[len("prefix"):]
+		got, n, err := ParseFrame(append(p, "next"...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(p) || got.Flags != flags || got.Sequence != 7 || string(bytes.Join(got.Records, []byte("|"))) != "a||bcd" {
+			t.Errorf("flags %v: parsed %+v in %v bytes, expected %v bytes", flags, got, n, len(p))
+		}
+	}
+}
+
+func TestFramingBuffer(t *testing.T) {
+	var out bytes.Buffer
+	tb := NewBuffer(&out, SetRecordMode(true), SetFraming(FrameChecksum), SetBufferSize(16))
+	records := []string{"first", "second", "third record is long", "4"}
+	for _, r := range records {
+		tb.Write([]byte(r))
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewBatchReader(&out)
+	var got []string
+	for seq := uint64(0); ; seq++ {
+		f, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if f.Sequence != seq || f.Flags != FrameChecksum {
+			t.Errorf("frame %v: sequence %v, flags %v", seq, f.Sequence, f.Flags)
+		}
+		for _, rec := range f.Records {
+			got = append(got, string(rec))
+		}
+	}
+	if strings.Join(got, ",") != strings.Join(records, ",") {
+		t.Errorf("records: %q, expected: %q", got, records)
+	}
+}
+
+func TestBatchReaderErrors(t *testing.T) {
+	valid := AppendFrame(nil, &Frame{Flags: FrameChecksum, Sequence: 1, Records: [][]byte{[]byte("abc")}})
+	corrupt := func(fn func(p []byte) []byte) []byte {
+		return fn(append([]byte(nil), valid...))
+	}
+	tests := []struct {
+		name  string
+		data  []byte
+		err   error
+		valid int
+	}{
+		{"magic", corrupt(func(p []byte) []byte { p[0] = 'X'; return p }), ErrFrameMagic, 0},
+		{"version", corrupt(func(p []byte) []byte { p[4] = 2; return p }), ErrFrameVersion, 0},
+		{"flags", corrupt(func(p []byte) []byte { p[5] |= 0x80; return p }), ErrFrameFlags, 0},
+		{"checksum", corrupt(func(p []byte) []byte { p[len(p)-5] ^= 1; return p }), ErrFrameChecksum, 0},
+		{"truncated", valid[:len(valid)-1], ErrFrameTruncated, 0},
+		{"truncated header", valid[:3], ErrFrameTruncated, 0},
+		{"too many records", corrupt(func(p []byte) []byte {
+			binary.LittleEndian.PutUint32(p[14:], maxFrameRecords+1)
+			return p
+		}), ErrFrameTooLarge, 0},
+		{"second frame", append(append([]byte(nil), valid...), "garbage that isn't a frame"...), ErrFrameMagic, 1},
+	}
+	for _, test := range tests {
+		r := NewBatchReader(bytes.NewReader(test.data))
+		for i := 0; i < test.valid; i++ {
+			if _, err := r.Next(); err != nil {
+				t.Fatalf("%v: frame %v: %v", test.name, i, err)
+			}
+		}
+		_, err := r.Next()
+		var ferr *FrameError
+		if !errors.As(err, &ferr) || !errors.Is(err, test.err) {
+			t.Errorf("%v: %v, expected: %v", test.name, err, test.err)
+			continue
+		}
+		if expected := int64(test.valid * len(valid)); ferr.Offset != expected {
+			t.Errorf("%v: offset %v, expected: %v", test.name, ferr.Offset, expected)
+		}
+	}
+}
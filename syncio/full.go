@@ -0,0 +1,57 @@
+package syncio
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// FullWriter returns a writer that writes every p completely to w, the short writes without
+// error are retried with the remaining bytes. A write that makes no progress returns
+// io.ErrShortWrite.
+func FullWriter(w io.Writer) io.Writer {
+	return fullWriter{w}
+}
+
+type fullWriter struct {
+	w io.Writer
+}
+
+func (f fullWriter) Write(p []byte) (int, error) {
+	return writeFull(f.w, p)
+}
+
+// writeFull writes p to w retrying the short writes
+func writeFull(w io.Writer, p []byte) (int, error) {
+	return writeFullCount(w, p, nil)
+}
+
+// writeFullCount is writeFull adding the calls to w to calls when it's not nil
+func writeFullCount(w io.Writer, p []byte, calls *int64) (int, error) {
+	written := 0
+	for written < len(p) {
+		if calls != nil {
+			atomic.AddInt64(calls, 1)
+		}
+		n, err := w.Write(p[written:])
+		if n < 0 || n > len(p)-written {
+			n = 0
+		}
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if n == 0 {
+			return written, io.ErrShortWrite
+		}
+	}
+	return written, nil
+}
+
+// writeFull writes p to the sink retrying the short writes, the calls are counted in
+// Stats.SinkWrites
+func (tb *Buffer) writeFull(p []byte) (int, error) {
+	if tb.readerFrom != nil {
+		return tb.readFromSink(p)
+	}
+	return writeFullCount(tb.sink(), p, &tb.stats.SinkWrites)
+}
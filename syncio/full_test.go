@@ -0,0 +1,58 @@
+package syncio
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// shortWriter accepts at most max bytes per write without error
+type shortWriter struct {
+	max int
+	out bytes.Buffer
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.max {
+		p = p[:w.max]
+	}
+	return w.out.Write(p)
+}
+
+func TestFullWriter(t *testing.T) {
+	sw := &shortWriter{max: 7}
+	p := bytes.Repeat([]byte("0123456789"), 10)
+	if n, err := FullWriter(sw).Write(p); n != len(p) || err != nil {
+		t.Errorf("write: %v, %v, expected: %v, nil", n, err, len(p))
+	}
+	if !bytes.Equal(sw.out.Bytes(), p) {
+		t.Errorf("written: %q, expected: %q", sw.out.Bytes(), p)
+	}
+
+	n, err := FullWriter(&shortWriter{max: 0}).Write(p)
+	if n != 0 || err != io.ErrShortWrite {
+		t.Errorf("write without progress: %v, %v, expected: 0, %v", n, err, io.ErrShortWrite)
+	}
+}
+
+func TestBufferShortWrites(t *testing.T) {
+	sw := &shortWriter{max: 7}
+	tb := NewBuffer(sw, SetBufferSize(64))
+	var expected []byte
+	for i := 0; i < 100; i++ {
+		p := []byte("line " + string(rune('a'+i%26)) + "\n")
+		expected = append(expected, p...)
+		tb.Write(p)
+	}
+	tb.Write(bytes.Repeat([]byte("x"), 100)) // bigger than the buffer
+	expected = append(expected, bytes.Repeat([]byte("x"), 100)...)
+	if err := tb.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if !bytes.Equal(sw.out.Bytes(), expected) {
+		t.Errorf("written: %q, expected: %q", sw.out.Bytes(), expected)
+	}
+	if s := tb.Stats(); s.FlushErrors != 0 {
+		t.Errorf("flush errors: %v, expected: 0", s.FlushErrors)
+	}
+}
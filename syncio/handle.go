@@ -0,0 +1,111 @@
+package syncio
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// handleCounters are the counters of the WriterHandle handles with a name, writes replaces
+// CallerWrites for their writes so a handle adds a single atomic per Write
+type handleCounters struct {
+	writes  int64
+	bytes   atomic.Int64
+	limiter atomic.Pointer[Limiter]
+}
+
+// handles are the counters of the WriterHandle names, the Buffer field is nil until
+// the first handle so StatsInto only loads it
+type handles struct {
+	mu     sync.Mutex
+	byName map[string]*handleCounters
+}
+
+// writerHandle is a WriterHandle handle
+type writerHandle struct {
+	tb     *Buffer
+	c      *handleCounters
+	closed atomic.Bool
+}
+
+// WriterHandle returns a writer of a producer sharing the Buffer that counts its writes and
+// bytes by name, see HandleStats, the handles with the same name share the counters and
+// SetHandleLimiter. Its Close only marks the handle closed, its writes fail with
+// io.ErrClosedPipe after Close. The counters of the closed handles are kept.
+func (tb *Buffer) WriterHandle(name string) io.WriteCloser {
+	return &writerHandle{tb: tb, c: tb.initHandles().counters(name)}
+}
+
+// SetHandleLimiter paces the writes of the WriterHandle handles named name with l, taking a
+// token per byte, l can be shared by several names. A nil l removes the limit.
+func (tb *Buffer) SetHandleLimiter(name string, l *Limiter) {
+	tb.initHandles().counters(name).limiter.Store(l)
+}
+
+// HandleStats returns the stats of the WriterHandle names, only CallerWrites and DirectBytes,
+// the bytes written by the handles, are set
+func (tb *Buffer) HandleStats() map[string]Stats {
+	h := tb.handles.Load()
+	if h == nil {
+		return map[string]Stats{}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats := make(map[string]Stats, len(h.byName))
+	for name, c := range h.byName {
+		stats[name] = Stats{CallerWrites: atomic.LoadInt64(&c.writes), DirectBytes: c.bytes.Load()}
+	}
+	return stats
+}
+
+func (h *handles) counters(name string) *handleCounters {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := h.byName[name]
+	if c == nil {
+		c = &handleCounters{}
+		h.byName[name] = c
+	}
+	return c
+}
+
+func (tb *Buffer) initHandles() *handles {
+	if h := tb.handles.Load(); h != nil {
+		return h
+	}
+	tb.handles.CompareAndSwap(nil, &handles{byName: map[string]*handleCounters{}})
+	return tb.handles.Load()
+}
+
+// callerWrites returns CallerWrites with the writes of the handles
+func (tb *Buffer) callerWrites() int64 {
+	writes := atomic.LoadInt64(&tb.stats.CallerWrites)
+	if h := tb.handles.Load(); h != nil {
+		h.mu.Lock()
+		for _, c := range h.byName {
+			writes += atomic.LoadInt64(&c.writes)
+		}
+		h.mu.Unlock()
+	}
+	return writes
+}
+
+func (w *writerHandle) Write(p []byte) (int, error) {
+	if w.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+	if l := w.c.limiter.Load(); l != nil {
+		l.WaitN(len(p))
+	}
+	n, err := w.tb.writeCounted(nil, p, &w.c.writes)
+	if n > 0 {
+		w.c.bytes.Add(int64(n))
+	}
+	return n, err
+}
+
+// Close marks the handle closed, the Buffer isn't flushed nor closed
+func (w *writerHandle) Close() error {
+	w.closed.Store(true)
+	return nil
+}
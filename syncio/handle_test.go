@@ -0,0 +1,74 @@
+package syncio
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriterHandle(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		tw := &testWriter{}
+		tb := NewBuffer(tw, append(mode, SetBufferSize(64))...)
+		a, b := tb.WriterHandle("a"), tb.WriterHandle("b")
+		var wg sync.WaitGroup
+		for _, w := range []io.Writer{a, b, tb.WriterHandle("a")} {
+			wg.Add(1)
+			go func(w io.Writer) {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					w.Write([]byte("0123456789"))
+				}
+			}(w)
+		}
+		wg.Wait()
+		tb.Write([]byte("abc"))
+
+		if err := a.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := a.Write([]byte("x")); err != io.ErrClosedPipe {
+			t.Errorf("write on a closed handle: %v", err)
+		}
+		// the Buffer and the other handles keep working
+		if _, err := b.Write([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+		if err := tb.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		stats := tb.HandleStats()
+		if s := stats["a"]; s.CallerWrites != 200 || s.DirectBytes != 2000 {
+			t.Errorf("handle a: %v writes, %v bytes, expected: 200, 2000", s.CallerWrites, s.DirectBytes)
+		}
+		if s := stats["b"]; s.CallerWrites != 101 || s.DirectBytes != 1001 {
+			t.Errorf("handle b: %v writes, %v bytes, expected: 101, 1001", s.CallerWrites, s.DirectBytes)
+		}
+		if s := tb.Stats(); s.CallerWrites != 302 || s.DirectBytes != 3004 {
+			t.Errorf("buffer: %v writes, %v bytes, expected: 302, 3004", s.CallerWrites, s.DirectBytes)
+		}
+	})
+}
+
+func TestHandleLimiter(t *testing.T) {
+	tb := NewBuffer(&testWriter{})
+	defer tb.Close()
+	// the burst is spent by the first write, the second waits for 100 tokens
+	tb.SetHandleLimiter("slow", NewLimiter(2000, 100))
+	slow, fast := tb.WriterHandle("slow"), tb.WriterHandle("fast")
+	start := time.Now()
+	slow.Write(make([]byte, 100))
+	slow.Write(make([]byte, 100))
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("limited writes done in %v, expected at least 50ms", elapsed)
+	}
+	start = time.Now()
+	for i := 0; i < 10; i++ {
+		fast.Write(make([]byte, 100))
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("writes of another handle limited: %v", elapsed)
+	}
+}
@@ -0,0 +1,51 @@
+package syncio
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// SetHeadRetention keeps a copy of the first n bytes written to the underlying writer, they
+// can be read with Head, e.g. by a tool attached late that wants the output from the start.
+// It has no effect when writing into another Buffer.
+func SetHeadRetention(n int) BufferOption {
+	return func(b *Buffer) {
+		if n > 0 {
+			b.head = &headRetention{size: n}
+		}
+	}
+}
+
+// headRetention is the data of SetHeadRetention, p is only appended up to its capacity so
+// the bytes given to the readers never change
+type headRetention struct {
+	mu   sync.Mutex
+	size int
+	p    []byte
+}
+
+// add copies the start of p while the head isn't full, it's called by the flush goroutine
+func (h *headRetention) add(p []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.p == nil {
+		h.p = make([]byte, 0, h.size)
+	}
+	if room := h.size - len(h.p); len(p) > room {
+		p = p[:room]
+	}
+	h.p = append(h.p, p...)
+}
+
+// Head returns a reader of the first bytes written to the underlying writer kept by
+// SetHeadRetention, the ones written when it's called. It can be called while the flushes
+// continue, the reader is empty without the option.
+func (tb *Buffer) Head() io.Reader {
+	if tb.head == nil {
+		return bytes.NewReader(nil)
+	}
+	tb.head.mu.Lock()
+	defer tb.head.mu.Unlock()
+	return bytes.NewReader(tb.head.p)
+}
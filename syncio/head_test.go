@@ -0,0 +1,62 @@
+package syncio
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestHeadRetention(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		tw := &lockedBuffer{}
+		tb := NewBuffer(tw, append([]BufferOption{SetBufferSize(4), SetHeadRetention(10)}, mode...)...)
+		defer tb.Close()
+		for _, w := range []string{"0123", "4567", "89ab", "cdef"} {
+			tb.Write([]byte(w))
+		}
+		tb.Flush()
+		p, err := io.ReadAll(tb.Head())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(p) != "0123456789" {
+			t.Fatalf("head %q", p)
+		}
+	})
+}
+
+func TestHeadDuringFlushes(t *testing.T) {
+	tb := NewBuffer(&testWriter{}, SetBufferSize(16), SetHeadRetention(4096))
+	defer tb.Close()
+	line := []byte("0123456789abcde\n")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			tb.Write(line)
+		}
+		tb.Flush()
+	}()
+	for i := 0; i < 100; i++ {
+		p, _ := io.ReadAll(tb.Head())
+		if len(p)%len(line) != 0 || !bytes.Equal(p, bytes.Repeat(line, len(p)/len(line))) {
+			t.Fatalf("head of %v bytes", len(p))
+		}
+	}
+	wg.Wait()
+	if p, _ := io.ReadAll(tb.Head()); len(p) != 4096 {
+		t.Fatalf("head of %v bytes", len(p))
+	}
+}
+
+func TestHeadDisabled(t *testing.T) {
+	tb := NewBuffer(&testWriter{})
+	defer tb.Close()
+	tb.Write([]byte("abc"))
+	tb.Flush()
+	if p, _ := io.ReadAll(tb.Head()); len(p) != 0 {
+		t.Fatalf("head %q without SetHeadRetention", p)
+	}
+}
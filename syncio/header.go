@@ -0,0 +1,24 @@
+package syncio
+
+// SetSinkHeader sets a function returning a header, e.g. a CSV header row or a byte order mark,
+// written as the first bytes every underlying writer receives: before the first batch flushed
+// to it, whatever the trigger, and again after SwapWriter. If the header write fails the batch
+// is reported as a flush error and the header is written with the next batch.
+// It has no effect when writing into another Buffer, set it on the parent instead.
+func SetSinkHeader(fn func() []byte) BufferOption {
+	return func(b *Buffer) {
+		b.header = fn
+		b.headerPending = fn != nil
+	}
+}
+
+// writeHeader writes the header to the underlying writer, it's called from the flush goroutine
+func (tb *Buffer) writeHeader() error {
+	if h := tb.header(); len(h) > 0 {
+		if _, err := tb.writeChunks(h, nil); err != nil {
+			return err
+		}
+	}
+	tb.headerPending = false
+	return nil
+}
@@ -0,0 +1,68 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSinkHeader(t *testing.T) {
+	header := func() []byte { return []byte("id,name\n") }
+	first := &lockedBuffer{}
+	tb := NewBuffer(first, SetBufferSize(32), SetFlushInterval(time.Millisecond), SetSinkHeader(header))
+
+	// the concurrent first writes race with the ticks
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				tb.Write([]byte("1,a\n"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	second := &bytes.Buffer{}
+	tb.SwapWriter(second)
+	tb.Write([]byte("2,b\n"))
+	tb.Close()
+
+	expected := "id,name\n" + strings.Repeat("1,a\n", 400)
+	if s := first.String(); s != expected {
+		t.Errorf("first writer data: %v bytes, expected: %v, header count: %v", len(s), len(expected), strings.Count(s, "id,name\n"))
+	}
+	if s := second.String(); s != "id,name\n2,b\n" {
+		t.Errorf("second writer data: %q, expected: %q", s, "id,name\n2,b\n")
+	}
+}
+
+func TestSinkHeaderError(t *testing.T) {
+	out := &bytes.Buffer{}
+	failures := 1
+	sink := writerFunc(func(p []byte) (int, error) {
+		if failures > 0 {
+			failures--
+			return 0, errors.New("sink down")
+		}
+		return out.Write(p)
+	})
+	dead := &bytes.Buffer{}
+	tb := NewBuffer(sink, SetDeadLetter(dead), SetSinkHeader(func() []byte { return []byte("H\n") }))
+	tb.Write([]byte("lost\n"))
+	if err := tb.Flush(); err == nil {
+		t.Error("expected the header write error")
+	}
+	tb.Write([]byte("kept\n"))
+	tb.Close()
+	if s := out.String(); s != "H\nkept\n" {
+		t.Errorf("written data: %q, expected: %q", s, "H\nkept\n")
+	}
+	if s := dead.String(); s != "lost\n" {
+		t.Errorf("dead letter data: %q, expected: %q", s, "lost\n")
+	}
+}
@@ -0,0 +1,68 @@
+package syncio
+
+// A Buffer writing into another Buffer, the parent, hands its batches over instead of writing them.
+// A batch smaller than the parent buffers is appended to the parent active buffer as a Write, so
+// the parent sink sees batches of the parent size whatever the size of the children. A bigger one
+// is enqueued in the parent with the ownership of its memory, so the bytes are copied once from the
+// first Write to the parent sink, the handed buffers are recycled by the parent pool.
+// Closing a child Buffer doesn't close the parent.
+
+// handoff moves a child batch to tb, it reports if the batch memory belongs to tb from now on.
+// It blocks while the queue is full and fails if the writes of tb are closed.
+func (tb *Buffer) handoff(b *batch) (bool, error) {
+	n := b.len()
+	tb.bufmu.Lock()
+	for (tb.queueFull(n) || tb.replaying) && !tb.writesClosed.Load() {
+		tb.space.Wait()
+	}
+	if tb.writesClosed.Load() {
+		tb.bufmu.Unlock()
+		return false, ErrWriteOnClosed
+	}
+	tb.own()
+	if tb.wal != nil {
+		if err := tb.appendWAL(b.bytes()); err != nil {
+			tb.unlockBuf()
+			return false, err
+		}
+	}
+
+	var sw, wsw swap
+	// the records of a child batch are kept by handing it over
+	handed := tb.recordMode || n >= tb.bufSize
+	if handed {
+		tb.acct.accept(n)
+		// the buffered data goes first to keep the order
+		sw = tb.flush(TriggerSize, nil)
+		tb.enqueue(&batch{buf: b.buf, p: b.p, trigger: TriggerSize, childBytes: n})
+	} else {
+		if n > tb.buf.Available() {
+			sw = tb.flush(TriggerSize, nil)
+			tb.flushedBetweenTicks = true
+		}
+		tb.childBytes += n
+		wsw = tb.writeLocked(b.bytes(), nil)
+	}
+	tb.unlockBuf()
+
+	if tb.logger != nil {
+		tb.logSwap(sw)
+		tb.logSwap(wsw)
+	}
+	tb.flushInline()
+	return handed, nil
+}
+
+// writeParent hands a batch over to the parent Buffer, the batch loses its memory if the
+// parent takes it
+func (tb *Buffer) writeParent(b *batch) (int, error) {
+	n := b.len()
+	handed, err := tb.parent.handoff(b)
+	if err != nil {
+		return 0, err
+	}
+	if handed {
+		b.buf, b.p = nil, nil
+	}
+	return n, nil
+}
@@ -0,0 +1,95 @@
+package syncio
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// sinkWriter keeps the written bytes and the address and size of every write
+type sinkWriter struct {
+	out   bytes.Buffer
+	addrs []*byte
+	sizes []int
+}
+
+func (w *sinkWriter) Write(p []byte) (int, error) {
+	w.addrs = append(w.addrs, &p[0])
+	w.sizes = append(w.sizes, len(p))
+	return w.out.Write(p)
+}
+
+func TestChildBuffer(t *testing.T) {
+	sw := &sinkWriter{}
+	parent := NewBuffer(sw, SetBufferSize(8))
+	child := NewBuffer(parent, SetBufferSize(16))
+
+	parent.Write([]byte("aa"))
+	// a batch of the parent size is handed over
+	child.Write([]byte("bbbbbbbbb"))
+	childBuf := &child.buf.Scratch()[0]
+	if err := child.Flush(); err != nil {
+		t.Fatalf("child flush: %v", err)
+	}
+	// closing the child doesn't close the parent
+	child.Close()
+	if _, err := parent.Write([]byte("cc")); err != nil {
+		t.Fatalf("parent write after closing the child: %v", err)
+	}
+	parent.Close()
+
+	if s := sw.out.String(); s != "aabbbbbbbbbcc" {
+		t.Errorf("written: %q, expected: %q", s, "aabbbbbbbbbcc")
+	}
+	if len(sw.addrs) != 3 || sw.addrs[1] != childBuf {
+		t.Errorf("child buffer not handed over to the parent sink")
+	}
+	if s := parent.Stats(); s.ChildBytes != 9 || s.DirectBytes != 4 {
+		t.Errorf("child bytes: %v, direct bytes: %v, expected: 9, 4", s.ChildBytes, s.DirectBytes)
+	}
+}
+
+func TestChildBufferBatchSizes(t *testing.T) {
+	sw := &sinkWriter{}
+	parent := NewBuffer(sw, SetBufferSize(64), SetManualTick(true))
+	child := NewBuffer(parent, SetBufferSize(16), SetManualTick(true))
+
+	var want bytes.Buffer
+	for i := 0; i < 8; i++ {
+		d := bytes.Repeat([]byte{'a' + byte(i)}, 16)
+		parent.Write(d)
+		want.Write(d)
+		c := bytes.Repeat([]byte{'A' + byte(i)}, 8)
+		child.Write(c)
+		child.Write(c)
+		// the child batch is written before the next direct write
+		child.Flush()
+		want.Write(c)
+		want.Write(c)
+	}
+	child.Close()
+	parent.Close()
+
+	if sw.out.String() != want.String() {
+		t.Errorf("written: %q, expected: %q", sw.out.String(), want.String())
+	}
+	// the child batches are appended to the parent batches instead of cutting them
+	if fmt.Sprint(sw.sizes) != "[64 64 64 64]" {
+		t.Errorf("sink writes: %v, expected 4 writes of 64 bytes", sw.sizes)
+	}
+	if s := parent.Stats(); s.ChildBytes != 128 || s.DirectBytes != 128 || s.Batches != 4 {
+		t.Errorf("child bytes: %v, direct bytes: %v, batches: %v, expected: 128, 128, 4",
+			s.ChildBytes, s.DirectBytes, s.Batches)
+	}
+}
+
+func TestChildBufferParentClosed(t *testing.T) {
+	parent := NewBuffer(&testWriter{})
+	parent.Close()
+	var lost []byte
+	child := NewBuffer(parent, SetOnFlushError(func(_ *FlushError, p []byte) { lost = append(lost, p...) }))
+	child.Write([]byte("abc"))
+	if err := child.Close(); err == nil || string(lost) != "abc" {
+		t.Errorf("close error: %v, lost: %q, expected a flush error losing %q", err, lost, "abc")
+	}
+}
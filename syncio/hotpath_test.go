@@ -0,0 +1,96 @@
+package syncio
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// hotPathConfigs are the configurations of BenchmarkWriteHotPath, the first one has no options
+var hotPathConfigs = []struct {
+	name    string
+	options []BufferOption
+}{
+	{"default", nil},
+	{"histogram", []BufferOption{SetWriteSizeHistogram(true)}},
+	{"record", []BufferOption{SetRecordMode(true)}},
+	{"backlog", []BufferOption{SetMaxBacklogAge(time.Hour, OverflowBlock)}},
+	{"journal", []BufferOption{SetJournal(io.Discard)}},
+}
+
+var hotPathSizes = []struct {
+	name string
+	size int
+}{
+	{"small", 16},
+	{"medium", 512},
+	{"large", 8 << 10},
+}
+
+func BenchmarkWriteHotPath(b *testing.B) {
+	for _, c := range hotPathConfigs {
+		for _, s := range hotPathSizes {
+			p := make([]byte, s.size)
+			b.Run(c.name+"/"+s.name+"/serial", func(b *testing.B) {
+				tb := NewBuffer(io.Discard, append([]BufferOption{SetBufferSize(64 << 10)}, c.options...)...)
+				defer tb.Close()
+				b.SetBytes(int64(len(p)))
+				for n := 0; n < b.N; n++ {
+					tb.Write(p)
+				}
+			})
+			b.Run(c.name+"/"+s.name+"/parallel", func(b *testing.B) {
+				tb := NewBuffer(io.Discard, append([]BufferOption{SetBufferSize(64 << 10)}, c.options...)...)
+				defer tb.Close()
+				b.SetBytes(int64(len(p)))
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						tb.Write(p)
+					}
+				})
+			})
+		}
+		b.Run(c.name+"/small/single_writer", func(b *testing.B) {
+			tb := NewBuffer(io.Discard, append([]BufferOption{SetBufferSize(64 << 10), SetSingleWriter(true)}, c.options...)...)
+			defer tb.Close()
+			p := make([]byte, 16)
+			for n := 0; n < b.N; n++ {
+				tb.Write(p)
+			}
+		})
+	}
+}
+
+// requireNoAllocs fails the test if fn allocates
+func requireNoAllocs(t *testing.T, name string, fn func()) {
+	t.Helper()
+	if allocs := testing.AllocsPerRun(100, fn); allocs != 0 {
+		t.Errorf("%v: %v allocs/op, expected none", name, allocs)
+	}
+}
+
+func TestWriteHotPathAllocs(t *testing.T) {
+	for _, s := range hotPathSizes[:2] {
+		// the buffer holds every write so there are no flushes
+		tb := NewBuffer(io.Discard, SetBufferSize(1<<20))
+		p := make([]byte, s.size)
+		requireNoAllocs(t, s.name, func() { tb.Write(p) })
+		tb.Close()
+	}
+}
+
+func TestWriteFeatures(t *testing.T) {
+	// the options that don't change the Write path keep the default path
+	tb := NewBuffer(io.Discard, SetFlushInterval(time.Second), SetMaxFlushBytes(1<<20), SetSinkFlush(true))
+	defer tb.Close()
+	if tb.features != 0 {
+		t.Errorf("features: %b, expected none", tb.features)
+	}
+	for _, c := range hotPathConfigs[1:] {
+		tb := NewBuffer(io.Discard, c.options...)
+		if tb.features == 0 {
+			t.Errorf("%v: no features", c.name)
+		}
+		tb.Close()
+	}
+}
@@ -0,0 +1,165 @@
+package syncio
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrIdleTimeout is returned by an IdleReader when no data arrives within the timeout,
+// it implements net.Error with Timeout() true and matches os.ErrDeadlineExceeded
+var ErrIdleTimeout error = idleTimeoutError{}
+
+type idleTimeoutError struct{}
+
+func (idleTimeoutError) Error() string   { return "read idle timeout" }
+func (idleTimeoutError) Timeout() bool   { return true }
+func (idleTimeoutError) Temporary() bool { return true }
+func (idleTimeoutError) Is(target error) bool {
+	return target == os.ErrDeadlineExceeded
+}
+
+// ErrReaderClosed is returned when reading from a closed reader: IdleReader, Reader, FileReader
+// and the Tee consumers
+var ErrReaderClosed = errors.New("read on closed reader")
+
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// IdleReader fails a Read when the underlying reader produces nothing within a timeout.
+// If the underlying reader supports SetReadDeadline (net.Conn, os.File pipes) it's used,
+// otherwise the reads are done by a goroutine: a timed out read is still pending and
+// its data is returned by the next Read, the goroutine exits on Close once that read returns.
+type IdleReader struct {
+	r     io.Reader
+	d     time.Duration
+	dl    readDeadliner
+	bufsz int
+
+	// fallback state, Read is not concurrent safe as usual for io.Reader
+	started bool
+	pending bool
+	rest    []byte
+	err     error
+	req     chan int
+	res     chan readResult
+
+	closeOnce sync.Once
+	stop      chan struct{}
+}
+
+type readResult struct {
+	p   []byte
+	err error
+}
+
+var _ io.ReadCloser = &IdleReader{}
+
+// IdleTimeoutReader wraps r failing the reads that don't receive data within d
+func IdleTimeoutReader(r io.Reader, d time.Duration) *IdleReader {
+	ir := &IdleReader{r: r, d: d, stop: make(chan struct{}), bufsz: drainBufferSize}
+	if dl, ok := r.(readDeadliner); ok {
+		ir.dl = dl
+	}
+	return ir
+}
+
+func (ir *IdleReader) Read(p []byte) (int, error) {
+	select {
+	case <-ir.stop:
+		return 0, ErrReaderClosed
+	default:
+	}
+	if ir.dl != nil {
+		if err := ir.dl.SetReadDeadline(time.Now().Add(ir.d)); err == nil {
+			n, err := ir.r.Read(p)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				err = ErrIdleTimeout
+			}
+			return n, err
+		}
+		// deadlines not supported after all, e.g. regular files
+		ir.dl = nil
+	}
+
+	if len(ir.rest) > 0 {
+		n := copy(p, ir.rest)
+		ir.rest = ir.rest[n:]
+		return n, nil
+	}
+	if ir.err != nil {
+		err := ir.err
+		ir.err = nil
+		return 0, err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if !ir.started {
+		ir.started = true
+		ir.req = make(chan int)
+		ir.res = make(chan readResult)
+		go ir.reader()
+	}
+	if !ir.pending {
+		size := len(p)
+		if size > ir.bufsz {
+			size = ir.bufsz
+		}
+		ir.req <- size
+		ir.pending = true
+	}
+
+	t := time.NewTimer(ir.d)
+	defer t.Stop()
+	select {
+	case res := <-ir.res:
+		ir.pending = false
+		n := copy(p, res.p)
+		ir.rest = res.p[n:]
+		if len(ir.rest) > 0 {
+			ir.err = res.err
+			return n, nil
+		}
+		return n, res.err
+	case <-t.C:
+		return 0, ErrIdleTimeout
+	case <-ir.stop:
+		return 0, ErrReaderClosed
+	}
+}
+
+// reader is the fallback goroutine that reads from the underlying reader
+func (ir *IdleReader) reader() {
+	buf := make([]byte, ir.bufsz)
+	for {
+		select {
+		case <-ir.stop:
+			return
+		case size := <-ir.req:
+			n, err := ir.r.Read(buf[:size])
+			select {
+			case ir.res <- readResult{p: buf[:n], err: err}:
+			case <-ir.stop:
+				return
+			}
+		}
+	}
+}
+
+// Close releases the fallback goroutine and closes the underlying reader if it's an io.Closer,
+// which usually unblocks a pending read
+func (ir *IdleReader) Close() error {
+	var err error
+	ir.closeOnce.Do(func() {
+		close(ir.stop)
+		if c, ok := ir.r.(io.Closer); ok {
+			err = c.Close()
+		}
+	})
+	return err
+}
@@ -0,0 +1,65 @@
+package syncio
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestIdleTimeoutReaderFallback(t *testing.T) {
+	pr, pw := io.Pipe()
+	ir := IdleTimeoutReader(pr, 20*time.Millisecond)
+	defer ir.Close()
+
+	p := make([]byte, 16)
+	_, err := ir.Read(p)
+	var nerr net.Error
+	if !errors.As(err, &nerr) || !nerr.Timeout() || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("read error: %v, expected a timeout", err)
+	}
+
+	// the data of the pending read is not lost
+	go pw.Write([]byte("hello"))
+	n, err := ir.Read(p)
+	if err != nil || string(p[:n]) != "hello" {
+		t.Errorf("read: %q, %v, expected: %q", p[:n], err, "hello")
+	}
+
+	pw.Close()
+	if _, err := ir.Read(p); err != io.EOF {
+		t.Errorf("read error: %v, expected: %v", err, io.EOF)
+	}
+}
+
+func TestIdleTimeoutReaderDeadline(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+	ir := IdleTimeoutReader(c1, 20*time.Millisecond)
+	defer ir.Close()
+	if ir.dl == nil {
+		t.Fatalf("SetReadDeadline not detected")
+	}
+
+	p := make([]byte, 16)
+	if _, err := ir.Read(p); err != ErrIdleTimeout {
+		t.Fatalf("read error: %v, expected: %v", err, ErrIdleTimeout)
+	}
+	go c2.Write([]byte("hello"))
+	n, err := ir.Read(p)
+	if err != nil || string(p[:n]) != "hello" {
+		t.Errorf("read: %q, %v, expected: %q", p[:n], err, "hello")
+	}
+}
+
+func TestIdleTimeoutReaderClose(t *testing.T) {
+	pr, _ := io.Pipe()
+	ir := IdleTimeoutReader(pr, 10*time.Millisecond)
+	ir.Read(make([]byte, 1))
+	ir.Close()
+	if _, err := ir.Read(make([]byte, 1)); err != ErrReaderClosed {
+		t.Errorf("read error: %v, expected: %v", err, ErrReaderClosed)
+	}
+}
@@ -0,0 +1,70 @@
+package syncio
+
+import "time"
+
+// idleSteps is the number of checks of the writes within the idle timeout
+const idleSteps = 8
+
+// SetIdleTimeout flushes the active buffer once no write arrives for d, with TriggerIdle, and
+// drops the free buffers of the pool until the next write, the active buffer is kept. Then
+// onIdle, if not nil, is called by the goroutine checking the writes, e.g. to Close the
+// Buffer. It's called once per idle period, the next write starts a new one. The writes are
+// checked every d/8, so the Buffer is idle between d and d+d/8 after the last one.
+func SetIdleTimeout(d time.Duration, onIdle func()) BufferOption {
+	return func(b *Buffer) {
+		b.idleTimeout = d
+		b.onIdle = onIdle
+	}
+}
+
+// idleLoop checks the number of writes until the Buffer is closed, the Buffer is idle once
+// it doesn't change for the idle timeout
+func (tb *Buffer) idleLoop() {
+	period := tb.idleTimeout / idleSteps
+	if period <= 0 {
+		period = tb.idleTimeout
+	}
+	c, stop := tb.clock.NewTicker(period)
+	defer stop()
+	writes, since, idle := tb.callerWrites(), tb.clock.Now(), false
+	for {
+		select {
+		case <-tb.done:
+			return
+		case <-c:
+			if w := tb.callerWrites(); w != writes {
+				writes, since, idle = w, tb.clock.Now(), false
+				continue
+			}
+			if idle {
+				// the buffers of the flushes in flight are back
+				tb.pool.Trim()
+				continue
+			}
+			if tb.clock.Now().Sub(since) < tb.idleTimeout {
+				continue
+			}
+			idle = true
+			tb.idleFlush()
+			if tb.onIdle != nil {
+				tb.onIdle()
+			}
+		}
+	}
+}
+
+// idleFlush flushes the active buffer and drops the free buffers, the data of a full queue
+// waits for the next tick
+func (tb *Buffer) idleFlush() {
+	var sw swap
+	tb.lockBuf()
+	if !tb.closed && !tb.queueFull(0) {
+		sw = tb.flush(TriggerIdle, nil)
+	}
+	tb.unlockBuf()
+	if tb.logger != nil {
+		tb.logSwap(sw)
+	}
+	tb.flushInline()
+	tb.pool.Trim()
+}
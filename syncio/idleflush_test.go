@@ -0,0 +1,110 @@
+package syncio
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// idleTicker returns the ticker of the idle checks, the other tickers are created concurrently
+func idleTicker(clock *fakeClock, period time.Duration) chan time.Time {
+	for i := 0; ; i++ {
+		t := clock.ticker(i)
+		clock.mu.Lock()
+		d := clock.periods[i]
+		clock.mu.Unlock()
+		if d == period {
+			return t
+		}
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		clock := newFakeClock()
+		sink := &lockedBuffer{}
+		idle := make(chan struct{}, 2)
+		var freed atomic.Int64
+		alloc := func(size int) []byte { return make([]byte, size) }
+		tb := NewBuffer(sink, append([]BufferOption{SetClock(clock), SetFlushInterval(time.Hour), SetAllocator(alloc, func([]byte) { freed.Add(1) }),
+			SetIdleTimeout(80*time.Millisecond, func() { idle <- struct{}{} })}, mode...)...)
+		defer tb.Close()
+		ticker := idleTicker(clock, 10*time.Millisecond)
+		// the second tick is received once the first one is handled
+		tick := func(d time.Duration) {
+			clock.Advance(d)
+			ticker <- clock.Now()
+			ticker <- clock.Now()
+		}
+		wait := func(expected string) {
+			t.Helper()
+			deadline := time.Now().Add(time.Second)
+			for sink.String() != expected && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if s := sink.String(); s != expected {
+				t.Fatalf("written: %q, expected: %q", s, expected)
+			}
+		}
+
+		tb.Write([]byte("a"))
+		tick(10 * time.Millisecond)
+		tick(40 * time.Millisecond)
+		if s := sink.String(); s != "" || len(idle) != 0 {
+			t.Fatalf("flushed before the idle timeout: %q", s)
+		}
+		tick(40 * time.Millisecond)
+		wait("a")
+		if len(idle) != 1 {
+			t.Fatalf("idle calls: %v, expected: 1", len(idle))
+		}
+		// the buffer of the idle flush is dropped at the next checks once written
+		for deadline := time.Now().Add(time.Second); freed.Load() == 0 && time.Now().Before(deadline); {
+			tick(10 * time.Millisecond)
+			time.Sleep(time.Millisecond)
+		}
+		if freed.Load() == 0 {
+			t.Error("free buffers not dropped when idle")
+		}
+
+		// idle once per period
+		tick(time.Second)
+		if len(idle) != 1 {
+			t.Fatalf("idle calls: %v, expected: 1", len(idle))
+		}
+		tb.Write([]byte("b"))
+		tick(10 * time.Millisecond)
+		tick(80 * time.Millisecond)
+		wait("ab")
+		if len(idle) != 2 {
+			t.Fatalf("idle calls: %v, expected: 2", len(idle))
+		}
+	})
+}
+
+func TestIdleTimeoutClose(t *testing.T) {
+	clock := newFakeClock()
+	sink := &lockedBuffer{}
+	var tb *Buffer
+	closed := make(chan error, 1)
+	tb = NewBuffer(sink, SetClock(clock), SetIdleTimeout(80*time.Millisecond, func() { closed <- tb.Close() }))
+	ticker := idleTicker(clock, 10*time.Millisecond)
+	tb.Write([]byte("residue"))
+	// the second tick is received once the first one is handled
+	ticker <- clock.Now()
+	ticker <- clock.Now()
+	clock.Advance(80 * time.Millisecond)
+	ticker <- clock.Now()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("idle Buffer not closed")
+	}
+	if s := sink.String(); s != "residue" {
+		t.Errorf("written: %q, expected: %q", s, "residue")
+	}
+}
@@ -0,0 +1,15 @@
+package syncio
+
+import "time"
+
+// InFlightFlush reports the write to the underlying writer in progress: when it started by
+// the Buffer clock and the size of the batch, after the transformation. A write stuck in the
+// sink is active with a growing age, contrary to a busy flush goroutine that starts new ones.
+// The bytes may be the ones of the next write when it starts meanwhile.
+func (tb *Buffer) InFlightFlush() (active bool, since time.Time, bytes int) {
+	ns := tb.sinkSince.Load()
+	if ns == 0 {
+		return false, time.Time{}, 0
+	}
+	return true, time.Unix(0, ns), int(tb.sinkBytes.Load())
+}
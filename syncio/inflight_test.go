@@ -0,0 +1,44 @@
+package syncio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+func TestInFlightFlush(t *testing.T) {
+	tb := NewBuffer(&synctest.SlowWriter{Delay: 300 * time.Millisecond})
+	if active, _, _ := tb.InFlightFlush(); active {
+		t.Fatal("flush in flight before writing")
+	}
+
+	tb.Write([]byte("0123456789"))
+	flushed := make(chan error, 1)
+	go func() { flushed <- tb.Flush() }()
+	active, since, n := tb.InFlightFlush()
+	for !active {
+		time.Sleep(time.Millisecond)
+		active, since, n = tb.InFlightFlush()
+	}
+	if n != 10 {
+		t.Errorf("bytes in flight: %v, expected: 10", n)
+	}
+	first := time.Since(since)
+	time.Sleep(50 * time.Millisecond)
+	active, again, _ := tb.InFlightFlush()
+	if !active || !again.Equal(since) {
+		t.Fatalf("flush in flight: %v since %v, expected since %v", active, again, since)
+	}
+	if d := time.Since(again); d < first+50*time.Millisecond {
+		t.Errorf("blocked for %v after sleeping, expected more than %v", d, first+50*time.Millisecond)
+	}
+
+	if err := <-flushed; err != nil {
+		t.Fatal(err)
+	}
+	if active, _, _ := tb.InFlightFlush(); active {
+		t.Error("flush in flight after flushing")
+	}
+	tb.Close()
+}
@@ -0,0 +1,128 @@
+package syncio
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBounds are the upper bounds of the LatencyHistogram buckets, the last bucket counts
+// the latencies over the last bound
+var LatencyBounds = [...]time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// LatencyHistogram counts the latencies lower than every LatencyBounds, and over the last one
+type LatencyHistogram [len(LatencyBounds) + 1]int64
+
+func (h *LatencyHistogram) observe(d time.Duration) {
+	i := 0
+	for i < len(LatencyBounds) && d >= LatencyBounds[i] {
+		i++
+	}
+	atomic.AddInt64(&h[i], 1)
+}
+
+// InstrumentedReader counts the reads of a reader, see InstrumentReader
+type InstrumentedReader struct {
+	r io.Reader
+
+	// stats is read and written atomically
+	stats ReaderStats
+	// eof is the unix time of the first EOF in nanoseconds
+	eof int64
+
+	reporting atomic.Bool
+	closeOnce sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// ReaderStats are the counters of an InstrumentedReader
+type ReaderStats struct {
+	Reads int64
+	Bytes int64
+	// Errors is the number of reads that failed, io.EOF excluded
+	Errors int64
+	// EOFTime is the time when io.EOF was first returned, zero until then
+	EOFTime time.Time
+	// Latency is the histogram of the Read durations
+	Latency LatencyHistogram
+}
+
+var _ io.ReadCloser = &InstrumentedReader{}
+
+// InstrumentReader wraps r counting its reads
+func InstrumentReader(r io.Reader) *InstrumentedReader {
+	return &InstrumentedReader{r: r, stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+func (ir *InstrumentedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := ir.r.Read(p)
+	ir.stats.Latency.observe(time.Since(start))
+	atomic.AddInt64(&ir.stats.Reads, 1)
+	atomic.AddInt64(&ir.stats.Bytes, int64(n))
+	if err == io.EOF {
+		atomic.CompareAndSwapInt64(&ir.eof, 0, time.Now().UnixNano())
+	} else if err != nil {
+		atomic.AddInt64(&ir.stats.Errors, 1)
+	}
+	return n, err
+}
+
+// Stats returns a copy of the reader counters, it doesn't allocate
+func (ir *InstrumentedReader) Stats() ReaderStats {
+	var s ReaderStats
+	ir.StatsInto(&s)
+	return s
+}
+
+// StatsInto copies the reader counters into s
+func (ir *InstrumentedReader) StatsInto(s *ReaderStats) {
+	s.Reads = atomic.LoadInt64(&ir.stats.Reads)
+	s.Bytes = atomic.LoadInt64(&ir.stats.Bytes)
+	s.Errors = atomic.LoadInt64(&ir.stats.Errors)
+	s.EOFTime = time.Time{}
+	if eof := atomic.LoadInt64(&ir.eof); eof != 0 {
+		s.EOFTime = time.Unix(0, eof)
+	}
+	for i := range s.Latency {
+		s.Latency[i] = atomic.LoadInt64(&ir.stats.Latency[i])
+	}
+}
+
+// ReportStats reports the reader stats to r every interval with the given name until Close,
+// which does a last report, see SetStatsReporter. It must be called once.
+func (ir *InstrumentedReader) ReportStats(r StatsReporter, name string, interval time.Duration) {
+	sr := &statsReporter{r: r, name: name, interval: interval, done: ir.done}
+	ir.reporting.Store(true)
+	go func() {
+		var s ReaderStats
+		sr.run(systemClock{}, ir.stop, func() {
+			ir.StatsInto(&s)
+			r.ReportReader(name, &s)
+		})
+	}()
+}
+
+// Close stops the stats reporting after a last report, and closes the underlying reader if
+// it's an io.Closer
+func (ir *InstrumentedReader) Close() error {
+	ir.closeOnce.Do(func() {
+		close(ir.stop)
+	})
+	if ir.reporting.Load() {
+		<-ir.done
+	}
+	if c, ok := ir.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
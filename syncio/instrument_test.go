@@ -0,0 +1,95 @@
+package syncio
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestInstrumentReader(t *testing.T) {
+	ir := InstrumentReader(iotest.OneByteReader(strings.NewReader("abc")))
+	before := time.Now()
+	if _, err := io.ReadAll(ir); err != nil {
+		t.Fatal(err)
+	}
+	s := ir.Stats()
+	if s.Reads != 4 || s.Bytes != 3 || s.Errors != 0 {
+		t.Errorf("stats: %+v", s)
+	}
+	if s.EOFTime.Before(before) {
+		t.Errorf("EOF time: %v, expected after %v", s.EOFTime, before)
+	}
+	var reads int64
+	for _, n := range s.Latency {
+		reads += n
+	}
+	if reads != s.Reads {
+		t.Errorf("latency histogram reads: %v, expected: %v", reads, s.Reads)
+	}
+
+	fail := InstrumentReader(iotest.ErrReader(errors.New("read error")))
+	fail.Read(make([]byte, 1))
+	if s := fail.Stats(); s.Errors != 1 || !s.EOFTime.IsZero() {
+		t.Errorf("stats: %+v", s)
+	}
+	if n := testing.AllocsPerRun(100, func() { ir.StatsInto(&s) }); n != 0 {
+		t.Errorf("StatsInto allocs: %v, expected: 0", n)
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var h LatencyHistogram
+	h.observe(0)
+	h.observe(LatencyBounds[0])
+	h.observe(time.Hour)
+	if h[0] != 1 || h[1] != 1 || h[len(h)-1] != 1 {
+		t.Errorf("histogram: %v", h)
+	}
+}
+
+// testReporter keeps the last reported stats
+type testReporter struct {
+	mu      sync.Mutex
+	buffers map[string]Stats
+	readers map[string]ReaderStats
+}
+
+func (r *testReporter) ReportBuffer(name string, s *Stats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.buffers == nil {
+		r.buffers = make(map[string]Stats)
+	}
+	r.buffers[name] = *s
+}
+
+func (r *testReporter) ReportReader(name string, s *ReaderStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.readers == nil {
+		r.readers = make(map[string]ReaderStats)
+	}
+	r.readers[name] = *s
+}
+
+func TestStatsReporter(t *testing.T) {
+	r := &testReporter{}
+	ir := InstrumentReader(strings.NewReader("data"))
+	ir.ReportStats(r, "in", time.Hour)
+	tb := NewBuffer(&testWriter{}, SetStatsReporter(r, "out", time.Hour))
+
+	io.Copy(tb, ir)
+	// the last stats are reported on close
+	ir.Close()
+	tb.Close()
+	if s := r.readers["in"]; s.Bytes != 4 {
+		t.Errorf("reported reader stats: %+v", s)
+	}
+	if s := r.buffers["out"]; s.DirectBytes != 4 {
+		t.Errorf("reported buffer stats: %+v", s)
+	}
+}
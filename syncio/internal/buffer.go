@@ -3,6 +3,7 @@ package internal
 import (
 	"fmt"
 	"io"
+	"sync/atomic"
 )
 
 // Buffer is a sized Buffer which implement write methods
@@ -14,10 +15,12 @@ import (
 type Buffer struct {
 	buf []byte
 	n   int
-}
-
-func newBuffer(size int) *Buffer {
-	return &Buffer{buf: make([]byte, size)}
+	// ends are the offsets where each record ends, only used when marked
+	ends []int
+	// refs is the number of holders, see refs.go
+	refs atomic.Int32
+	// pool is the pool of the memory
+	pool *BufferPool
 }
 
 // Buffered returns the size of the data writen in the buffer
@@ -33,6 +36,7 @@ func (b *Buffer) Available() int {
 // Write copies p into the internal buffer,
 // an error is returned if p is bigger than the available memory
 func (b *Buffer) Write(p []byte) (int, error) {
+	b.checkLive()
 	// make sure that this will never happen
 	if len(p) > b.Available() {
 		return 0, fmt.Errorf("buffer write error: buffer has %v mem available and p has length %v", b.Available(), len(p))
@@ -69,4 +73,49 @@ func (b *Buffer) WriteTo(w io.Writer) (n int64, err error) {
 // Reset sets the buffer as empty
 func (b *Buffer) Reset() {
 	b.n = 0
+	b.ends = b.ends[:0]
+}
+
+// Mark registers the end of a record at the current position
+func (b *Buffer) Mark() {
+	b.ends = append(b.ends, b.n)
+}
+
+// Records appends to dst the records delimited by Mark, the data after the last mark
+// is appended as a record
+func (b *Buffer) Records(dst [][]byte) [][]byte {
+	b.checkLive()
+	start := 0
+	for _, end := range b.ends {
+		dst = append(dst, b.buf[start:end])
+		start = end
+	}
+	if start < b.n {
+		dst = append(dst, b.buf[start:b.n])
+	}
+	return dst
+}
+
+// Bytes returns the buffered data, it's valid until the next buffer modification
+func (b *Buffer) Bytes() []byte {
+	b.checkLive()
+	return b.buf[:b.n]
+}
+
+// Cap returns the size of the buffer
+func (b *Buffer) Cap() int {
+	return cap(b.buf)
+}
+
+// Scratch returns the whole buffer memory to be used as a temporary buffer,
+// the buffered data is not modified
+func (b *Buffer) Scratch() []byte {
+	b.checkLive()
+	return b.buf[:cap(b.buf)]
+}
+
+// SetBuffered sets the size of the data written in the buffer, it's used when the
+// data has been copied directly into Scratch
+func (b *Buffer) SetBuffered(n int) {
+	b.n = n
 }
@@ -0,0 +1,6 @@
+//go:build syncio_debug
+
+package internal
+
+// debugChecks enables the checks of the released Buffers, see refs.go
+const debugChecks = true
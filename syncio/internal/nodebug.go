@@ -0,0 +1,6 @@
+//go:build !syncio_debug
+
+package internal
+
+// debugChecks disables the checks of the released Buffers without the syncio_debug build tag
+const debugChecks = false
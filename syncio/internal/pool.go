@@ -1,41 +1,106 @@
 package internal
 
-// BufferPool is a pool of buffers implementation where the buffers
-// can't be garbage collected. https://golang.org/doc/effective_go.html#leaky_buffer
+import "github.com/travelgateX/go-io/syncio/bufpool"
+
+// BufferPool is a pool of Buffers, the memory is pooled by a bounded bufpool.Pool and the
+// Buffers without memory are kept apart to reuse their record offsets.
 type BufferPool struct {
-	free   chan *Buffer
-	bufcap int
+	mem    *bufpool.Pool
+	shells chan *Buffer
+	// allocator reports that mem has an allocator, its Buffers always go back to it
+	allocator bool
 }
 
 // NewBufferPool instances a bufferPool with 'size' buffers,
 // buffers will be allocated with a 'bufcap' capacity
 func NewBufferPool(size, bufcap int) *BufferPool {
 	return &BufferPool{
-		free:   make(chan *Buffer, size),
-		bufcap: bufcap,
+		mem:    bufpool.New(bufcap, size),
+		shells: make(chan *Buffer, size),
+	}
+}
+
+// NewBufferPoolAllocator is NewBufferPool with the memory allocated by alloc and freed by
+// free, see bufpool.NewWithAllocator
+func NewBufferPoolAllocator(size, bufcap int, alloc func(size int) []byte, free func([]byte)) *BufferPool {
+	return &BufferPool{
+		mem:       bufpool.NewWithAllocator(bufcap, size, alloc, free),
+		shells:    make(chan *Buffer, size),
+		allocator: true,
 	}
 }
 
+// Mem returns the pool of the memory, e.g. to share it with a reader
+func (p *BufferPool) Mem() *bufpool.Pool {
+	return p.mem
+}
+
 // Get returns an available buffer, if any, a new one will be allocated.
 // Returns a bool indicating if an allocation happened
 func (p *BufferPool) Get() (*Buffer, bool) {
+	buf, ok := p.mem.TryGet()
+	if !ok {
+		// allocated here to report the allocation
+		buf = p.mem.Alloc()
+	}
+	var b *Buffer
 	select {
-	case buf := <-p.free:
-		// got one
-		return buf, false
+	case b = <-p.shells:
 	default:
-		// there aren't free buffers, allocate new one
-		return newBuffer(p.bufcap), true
+		b = &Buffer{}
 	}
+	b.buf = buf
+	b.pool = p
+	b.refs.Store(1)
+	return b, !ok
+}
+
+// Empty returns a Buffer without memory that is never put back, e.g. the active buffer of a
+// closed Buffer once its memory is freed
+func Empty() *Buffer {
+	b := &Buffer{}
+	b.refs.Store(1)
+	return b
 }
 
-// Put returns a buffer, its dropped on the floor if the pool is full
-func (p *BufferPool) Put(b *Buffer) {
+// Put releases a reference of a buffer, with the last one the buffer is dropped on the floor
+// if the pool is full or its capacity doesn't match the pool's one.
+// Returns false if the buffer was dropped
+func (p *BufferPool) Put(b *Buffer) bool {
+	if b.pool != p && b.pool != nil && (p.allocator || b.pool.allocator) {
+		// handed over by another pool, the memory of an allocator goes back to it
+		return b.pool.Put(b)
+	}
+	if !b.release() {
+		return true
+	}
+	b.poison()
 	b.Reset()
+	kept := p.mem.Put(b.buf)
+	b.buf = nil
+	if debugChecks {
+		// the released shell keeps failing the checks
+		return kept
+	}
 	select {
-	case p.free <- b:
-		// reuse buffer
+	case p.shells <- b:
 	default:
-		// free list full; drop
 	}
+	return kept
+}
+
+// Close drops the pooled memory and the one put from now on, see bufpool.Pool.Close
+func (p *BufferPool) Close() {
+	p.mem.Close()
+}
+
+// Trim drops the pooled memory, see bufpool.Pool.Trim
+func (p *BufferPool) Trim() int {
+	return p.mem.Trim()
+}
+
+// Resize changes the capacity of the buffers allocated from now on, buffers with
+// a different capacity are dropped when they are returned
+func (p *BufferPool) Resize(bufcap int) {
+	p.mem.Resize(bufcap)
 }
@@ -0,0 +1,39 @@
+package internal
+
+// The Buffers are reference counted so the flushed data can be held without copying it, e.g.
+// by the retention window, Get returns a Buffer with a reference and Put releases one. The
+// memory goes back to the pool with the last reference. With the syncio_debug build tag the
+// released memory is poisoned and its use panics.
+
+// Retain adds a reference to a Buffer, it must be released with BufferPool.Put
+func (b *Buffer) Retain() {
+	if b.refs.Add(1) <= 1 {
+		panic("syncio: retain of a released buffer")
+	}
+}
+
+// release drops a reference, it returns true if it was the last one
+func (b *Buffer) release() bool {
+	n := b.refs.Add(-1)
+	if n < 0 {
+		panic("syncio: buffer released twice")
+	}
+	return n == 0
+}
+
+// checkLive panics on the use of a released Buffer with the syncio_debug build tag
+func (b *Buffer) checkLive() {
+	if debugChecks && b.refs.Load() <= 0 {
+		panic("syncio: use of a released buffer")
+	}
+}
+
+// poison overwrites the memory of a released Buffer with the syncio_debug build tag
+func (b *Buffer) poison() {
+	if debugChecks {
+		p := b.buf[:cap(b.buf)]
+		for i := range p {
+			p[i] = 0xdd
+		}
+	}
+}
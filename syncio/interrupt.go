@@ -0,0 +1,131 @@
+package syncio
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Escalation is the action taken by CloseTimeout to interrupt the sink write in flight at the
+// deadline, the actions are tried in this order of preference
+type Escalation int
+
+const (
+	EscalationNone     Escalation = iota // the write wasn't interrupted
+	EscalationCancel                     // the context of the ContextWriter write was cancelled
+	EscalationDeadline                   // the write deadline of the writer was set to now
+	EscalationClose                      // the underlying writer was closed
+)
+
+var escalationNames = []string{"none", "cancel", "deadline", "close"}
+
+func (e Escalation) String() string {
+	return enumString(escalationNames, int(e))
+}
+
+func (e Escalation) MarshalText() ([]byte, error) {
+	return enumMarshal(escalationNames, int(e), "Escalation")
+}
+
+func (e *Escalation) UnmarshalText(text []byte) error {
+	return enumUnmarshal(escalationNames, text, "Escalation", (*int)(e))
+}
+
+// escalationGrace is the time given to an escalation to end the sink write before the next one
+const escalationGrace = 100 * time.Millisecond
+
+// deadlineWriter is implemented by net.Conn and os.File
+type deadlineWriter interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// sinkCancel is the cancel of the context of the sink write in flight, it's set by the flush
+// goroutine and called by CloseTimeout
+type sinkCancel struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// startSinkContext sets the cancelable context of a sink write to a ContextWriter with
+// SetFlushContext, it's used by the flush goroutine
+func (tb *Buffer) startSinkContext() {
+	if tb.flushContext == nil {
+		return
+	}
+	if _, ok := tb.writer.(ContextWriter); !ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(tb.flushContext())
+	tb.sinkCtx = ctx
+	tb.sinkCancel.mu.Lock()
+	tb.sinkCancel.cancel = cancel
+	tb.sinkCancel.mu.Unlock()
+}
+
+func (tb *Buffer) endSinkContext() {
+	if tb.sinkCtx == nil {
+		return
+	}
+	tb.sinkCancel.mu.Lock()
+	cancel := tb.sinkCancel.cancel
+	tb.sinkCancel.cancel = nil
+	tb.sinkCancel.mu.Unlock()
+	cancel()
+	tb.sinkCtx = nil
+}
+
+// interrupt escalates the actions supported by the underlying writer to end the sink write in
+// flight, giving escalationGrace to each one, until the flush goroutine ends. It returns the
+// last action taken and if the flush goroutine ended.
+func (tb *Buffer) interrupt() (Escalation, bool) {
+	if tb.sinkSince.Load() == 0 {
+		return EscalationNone, false
+	}
+	tb.bufmu.Lock()
+	w := tb.writer
+	tb.bufmu.Unlock()
+	last := EscalationNone
+	for e := EscalationCancel; e <= EscalationClose; e++ {
+		if !tb.escalate(e, w) {
+			continue
+		}
+		last = e
+		t := time.NewTimer(escalationGrace)
+		select {
+		case <-tb.done:
+			t.Stop()
+			return e, true
+		case <-t.C:
+		}
+	}
+	return last, false
+}
+
+// escalate takes the action e on w, it returns false if w doesn't support it
+func (tb *Buffer) escalate(e Escalation, w io.Writer) bool {
+	switch e {
+	case EscalationCancel:
+		tb.sinkCancel.mu.Lock()
+		cancel := tb.sinkCancel.cancel
+		tb.sinkCancel.mu.Unlock()
+		if cancel == nil {
+			return false
+		}
+		cancel()
+	case EscalationDeadline:
+		d, ok := w.(deadlineWriter)
+		if !ok {
+			return false
+		}
+		// a deadline in the past times out the write in progress
+		d.SetWriteDeadline(time.Now())
+	case EscalationClose:
+		c, ok := w.(io.Closer)
+		if !ok {
+			return false
+		}
+		c.Close()
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package syncio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hungWriter blocks the writes until its context is cancelled, its deadline is set or it's
+// closed, according to the interfaces of the test sinks
+type hungWriter struct {
+	once    sync.Once
+	unblock chan struct{}
+}
+
+func (w *hungWriter) Write(p []byte) (int, error) {
+	<-w.unblock
+	return 0, io.ErrClosedPipe
+}
+
+func (w *hungWriter) stop() {
+	w.once.Do(func() { close(w.unblock) })
+}
+
+// ctxSink only unblocks when the context of the write is cancelled
+type ctxSink struct{ hungWriter }
+
+func (w *ctxSink) WriteContext(ctx context.Context, p []byte) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-w.unblock:
+		return 0, io.ErrClosedPipe
+	}
+}
+
+type deadlineSink struct{ hungWriter }
+
+func (w *deadlineSink) SetWriteDeadline(t time.Time) error {
+	w.stop()
+	return nil
+}
+
+type closeSink struct{ hungWriter }
+
+func (w *closeSink) Close() error {
+	w.stop()
+	return nil
+}
+
+func TestCloseTimeoutInterrupt(t *testing.T) {
+	tests := []struct {
+		name       string
+		sink       func() io.Writer
+		escalation Escalation
+	}{
+		{"context", func() io.Writer { return &ctxSink{hungWriter{unblock: make(chan struct{})}} }, EscalationCancel},
+		{"deadline", func() io.Writer { return &deadlineSink{hungWriter{unblock: make(chan struct{})}} }, EscalationDeadline},
+		{"close", func() io.Writer { return &closeSink{hungWriter{unblock: make(chan struct{})}} }, EscalationClose},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sink := test.sink()
+			tb := NewBuffer(sink, SetBufferSize(4), SetBufferPoolSize(4),
+				SetFlushContext(context.Background))
+			tb.Write([]byte("aaa")) // in flight once the next write fills the buffer
+			tb.Write([]byte("bbb"))
+			for tb.sinkSince.Load() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			_, err := tb.CloseTimeout(10 * time.Millisecond)
+			var cerr *CloseTimeoutError
+			if !errors.As(err, &cerr) {
+				t.Fatalf("close error: %v, expected: %v", err, ErrCloseTimeout)
+			}
+			if cerr.Escalation != test.escalation || !cerr.Interrupted {
+				t.Errorf("escalation: %v, interrupted: %v, expected: %v, true", cerr.Escalation, cerr.Interrupted, test.escalation)
+			}
+			select {
+			case <-tb.done:
+			default:
+				t.Error("flush goroutine running after the interrupted write")
+			}
+		})
+	}
+}
+
+func TestCloseTimeoutNotInterrupted(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4))
+	tb.Write([]byte("aaa"))
+	tb.Write([]byte("bbb"))
+	for tb.sinkSince.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_, err := tb.CloseTimeout(10 * time.Millisecond)
+	var cerr *CloseTimeoutError
+	if !errors.As(err, &cerr) || cerr.Escalation != EscalationNone || cerr.Interrupted {
+		t.Errorf("close error: %#v, expected no escalation", err)
+	}
+	close(bw.release)
+	tb.Close()
+}
@@ -0,0 +1,203 @@
+package syncio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// journals start with journalMagic followed by the format version, the records are an op
+// byte followed by its fields as uvarints:
+//
+//	config: buffer size, pool size, max flush bytes, max sink write size, flags
+//	write:  length, FNV-1a 32 hash of the data
+//	flush:  trigger, bytes
+//	resize: buffer size
+//	error:  category
+const (
+	journalMagic   = "SYJR"
+	journalVersion = 1
+	// journalRecordMode is the config flag of SetRecordMode
+	journalRecordMode = 1 << 0
+	maxJournalFields  = 5
+)
+
+const (
+	journalConfig byte = iota + 1
+	journalWrite
+	journalFlush
+	journalResize
+	journalError
+)
+
+// ErrJournalVersion is returned by ReplayJournal when the journal has an unknown format version
+var ErrJournalVersion = errors.New("unknown journal version")
+
+// SetJournal records the operations of the Buffer to w for the bug reports: the
+// configuration, the length and hash of every Write, the flushes with their trigger and
+// size, the buffer resizes and the errors by category, never the data written. Every
+// operation is a single Write of a few bytes to w, that can be another Buffer, the
+// journal stops at the first error of w. ReplayJournal executes it again. The lock free
+// path of the small concurrent writes is disabled so the writes are journaled in order.
+func SetJournal(w io.Writer) BufferOption {
+	return func(b *Buffer) {
+		b.journal = &journal{w: w}
+	}
+}
+
+// journal is the writer of SetJournal, mu serializes the records of the writers, the flush
+// goroutine and the error counters
+type journal struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// start writes the header and the configuration of tb
+func (j *journal) start(tb *Buffer) {
+	var flags uint64
+	if tb.recordMode {
+		flags |= journalRecordMode
+	}
+	j.mu.Lock()
+	_, j.err = j.w.Write(append([]byte(journalMagic), journalVersion))
+	j.mu.Unlock()
+	j.record(journalConfig, uint64(tb.bufSize), uint64(tb.poolSize), uint64(tb.maxFlushBytes), uint64(tb.maxSinkWrite), flags)
+}
+
+// record writes an op with its fields in a single Write
+func (j *journal) record(op byte, fields ...uint64) {
+	var buf [1 + maxJournalFields*binary.MaxVarintLen64]byte
+	buf[0] = op
+	n := 1
+	for _, f := range fields {
+		n += binary.PutUvarint(buf[n:], f)
+	}
+	j.mu.Lock()
+	if j.err == nil {
+		_, j.err = j.w.Write(buf[:n])
+	}
+	j.mu.Unlock()
+}
+
+func (j *journal) write(p []byte) {
+	// FNV-1a
+	h := uint32(2166136261)
+	for _, c := range p {
+		h ^= uint32(c)
+		h *= 16777619
+	}
+	j.record(journalWrite, uint64(len(p)), uint64(h))
+}
+
+// ReplayJournal executes the operations of a SetJournal journal against a new Buffer writing
+// to sink, with the same configuration and in synchronous mode. The data of the writes is
+// generated, byte i of the stream is i modulo 251, the size flushes are done by the writes
+// and the other flushes by Tick, Flush and Close as journaled, the errors are ignored. It
+// returns the Buffer closed, or open when the journal ends before the close, and the first
+// error reading the journal.
+func ReplayJournal(r io.Reader, sink io.Writer) (*Buffer, error) {
+	br := bufio.NewReader(r)
+	var header [len(journalMagic) + 1]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return nil, err
+	}
+	if string(header[:len(journalMagic)]) != journalMagic {
+		return nil, errors.New("not a journal")
+	}
+	if v := header[len(journalMagic)]; v != journalVersion {
+		return nil, fmt.Errorf("%w: %v", ErrJournalVersion, v)
+	}
+
+	fields := func(n int) ([]uint64, error) {
+		var f [maxJournalFields]uint64
+		for i := 0; i < n; i++ {
+			v, err := binary.ReadUvarint(br)
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return nil, err
+			}
+			f[i] = v
+		}
+		return f[:n], nil
+	}
+	if op, err := br.ReadByte(); err != nil {
+		return nil, err
+	} else if op != journalConfig {
+		return nil, fmt.Errorf("journal starts with op %v instead of the config", op)
+	}
+	cfg, err := fields(5)
+	if err != nil {
+		return nil, err
+	}
+	tb := NewBuffer(sink, SetBufferSize(int(cfg[0])), SetBufferPoolSize(int(cfg[1])),
+		SetMaxFlushBytes(int(cfg[2])), SetMaxSinkWriteSize(int(cfg[3])),
+		SetRecordMode(cfg[4]&journalRecordMode != 0), SetManualTick(true))
+
+	var data []byte
+	var offset int
+	for {
+		op, err := br.ReadByte()
+		if err == io.EOF {
+			return tb, nil
+		} else if err != nil {
+			return tb, err
+		}
+		var f []uint64
+		switch op {
+		case journalWrite, journalFlush:
+			f, err = fields(2)
+		case journalResize, journalError:
+			f, err = fields(1)
+		default:
+			return tb, fmt.Errorf("unknown journal op %v", op)
+		}
+		if err != nil {
+			return tb, err
+		}
+
+		switch op {
+		case journalWrite:
+			if uint64(cap(data)) < f[0] {
+				data = make([]byte, f[0])
+			}
+			data = data[:f[0]]
+			for i := range data {
+				data[i] = byte((offset + i) % 251)
+			}
+			offset += len(data)
+			tb.Write(data)
+		case journalFlush:
+			switch FlushTrigger(f[0]) {
+			case TriggerTick, TriggerPolicy, TriggerIdle:
+				tb.replayFlush(FlushTrigger(f[0]))
+			case TriggerManual:
+				tb.Flush()
+			case TriggerClose:
+				tb.Close()
+				return tb, nil
+			}
+		case journalResize:
+			tb.bufmu.Lock()
+			tb.bufSize = int(f[0])
+			tb.pool.Resize(tb.bufSize)
+			tb.bufmu.Unlock()
+		}
+	}
+}
+
+// replayFlush flushes the active buffer as the journaled flush, Tick would skip it after a
+// size flush
+func (tb *Buffer) replayFlush(trigger FlushTrigger) {
+	tb.lockBuf()
+	if !tb.closed {
+		tb.flush(trigger, nil)
+	}
+	tb.unlockBuf()
+	tb.flushInline()
+}
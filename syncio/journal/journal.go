@@ -0,0 +1,519 @@
+// Package journal is an append-only write-ahead log of the writes of a syncio.Buffer, so the
+// data buffered in memory survives a crash: with syncio.SetWriteAheadLog every write is
+// appended to the Log before it's buffered and committed once it's flushed, and Replay
+// delivers the data not committed when the process died, e.g. on SIGKILL.
+// The Log is a directory of segment files rolled over by size, every record is checksummed
+// and the segments whose data is all committed are removed.
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultSegmentSize is the default size of the segment files
+const DefaultSegmentSize = 64 << 20
+
+// ErrClosed is returned by the appends of a closed Log
+var ErrClosed = errors.New("journal: log closed")
+
+// ErrPending is returned by Open when the directory holds the segments of a previous run,
+// they must be delivered by Replay first
+var ErrPending = errors.New("journal: segments not replayed")
+
+// ErrCorrupt is matched by the errors of Replay reading a record with a bad checksum or
+// truncated before the last segment
+var ErrCorrupt = errors.New("journal: corrupt segment")
+
+// every segment starts with segmentMagic, the format version, and as uvarints the offset of
+// its first byte in the data appended and the data committed when it was created. The
+// records are a type byte, the payload length as uvarint, the payload and the big endian
+// CRC-32C of all of them:
+//
+//	write:  the data appended
+//	commit: the data committed as uvarint, all the data before it was flushed
+const (
+	segmentMagic   = "SYWL"
+	segmentVersion = 1
+	segmentExt     = ".wal"
+)
+
+const (
+	recordWrite byte = iota + 1
+	recordCommit
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Option is an option of Open
+type Option func(*Log)
+
+// SetSegmentSize sets the size of the segment files, DefaultSegmentSize by default. A segment
+// is rolled over before an append would exceed it, a bigger write fills a segment alone.
+func SetSegmentSize(n int64) Option {
+	return func(l *Log) {
+		l.segmentSize = n
+	}
+}
+
+// SetSync syncs the segment to disk before Append returns, true by default. Without it the
+// appends survive the crash of the process but not the crash of the system.
+func SetSync(sync bool) Option {
+	return func(l *Log) {
+		l.sync = sync
+	}
+}
+
+// Log is the write-ahead log of a directory, it implements syncio.WriteAheadLog. It's safe
+// for concurrent use.
+type Log struct {
+	dir         string
+	segmentSize int64
+	sync        bool
+
+	mu sync.Mutex
+	f  *os.File
+	// cur is the segment of f with its size, segments the previous ones not removed yet
+	cur      segment
+	size     int64
+	segments []segment
+	// seq is the number of the last segment
+	seq       uint64
+	appended  int64
+	committed int64
+	buf       []byte
+	// err is the first error writing a segment, the appends fail from now on
+	err    error
+	closed bool
+}
+
+// segment is a segment file, base is the offset of its first byte in the data appended
+// and end the offset after its last byte once it's rolled over
+type segment struct {
+	name string
+	base int64
+	end  int64
+}
+
+// Stats are the counters of a Log
+type Stats struct {
+	// Appended is the size of the data appended and Committed of the data committed
+	Appended  int64
+	Committed int64
+	// Segments is the number of segment files
+	Segments int
+}
+
+// Open creates a Log in dir, creating it if needed. It fails with ErrPending if dir holds
+// the segments of a previous run not delivered by Replay.
+func Open(dir string, options ...Option) (*Log, error) {
+	l := &Log{dir: dir, segmentSize: DefaultSegmentSize, sync: true}
+	for _, o := range options {
+		o(l)
+	}
+	if l.segmentSize <= 0 {
+		l.segmentSize = DefaultSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	names, err := segmentNames(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		return nil, fmt.Errorf("%w: %v segments in %v", ErrPending, len(names), dir)
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open creates the next segment, the caller holds mu but in Open
+func (l *Log) open() error {
+	l.seq++
+	name := filepath.Join(l.dir, fmt.Sprintf("%016x%v", l.seq, segmentExt))
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	header := append([]byte(segmentMagic), segmentVersion)
+	header = binary.AppendUvarint(header, uint64(l.appended))
+	header = binary.AppendUvarint(header, uint64(l.committed))
+	if _, err := f.Write(header); err != nil {
+		f.Close()
+		os.Remove(name)
+		return err
+	}
+	if l.sync {
+		if err := syncDir(f, l.dir); err != nil {
+			f.Close()
+			os.Remove(name)
+			return err
+		}
+	}
+	l.f, l.size, l.cur = f, int64(len(header)), segment{name: name, base: l.appended}
+	return nil
+}
+
+// syncDir syncs the new segment f and its directory entry
+func syncDir(f *os.File, dir string) error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// rollover closes the current segment and opens the next one, the caller holds mu
+func (l *Log) rollover() error {
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	l.cur.end = l.appended
+	l.segments = append(l.segments, l.cur)
+	return l.open()
+}
+
+// record writes a record with one Write, the caller holds mu
+func (l *Log) record(typ byte, payload []byte) error {
+	l.buf = append(l.buf[:0], typ)
+	l.buf = binary.AppendUvarint(l.buf, uint64(len(payload)))
+	l.buf = append(l.buf, payload...)
+	l.buf = binary.BigEndian.AppendUint32(l.buf, crc32.Checksum(l.buf, crcTable))
+	n, err := l.f.Write(l.buf)
+	l.size += int64(n)
+	if err == nil && n < len(l.buf) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+// Append appends p to the log, and syncs it with SetSync. Once a segment fails to be written
+// the next appends fail with the same error.
+func (l *Log) Append(p []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	if l.err != nil {
+		return l.err
+	}
+	size := int64(1 + binary.MaxVarintLen64 + len(p) + 4)
+	if l.cur.base < l.appended && l.size+size > l.segmentSize {
+		if l.err = l.rollover(); l.err != nil {
+			return l.err
+		}
+	}
+	if l.err = l.record(recordWrite, p); l.err != nil {
+		return l.err
+	}
+	if l.sync {
+		if l.err = l.f.Sync(); l.err != nil {
+			return l.err
+		}
+	}
+	l.appended += int64(len(p))
+	return nil
+}
+
+// Commit marks the next n bytes appended as flushed, the segments with all their data
+// committed are removed. The commits aren't synced: the data of a commit lost in a crash
+// is delivered again by Replay.
+func (l *Log) Commit(n int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	if l.err != nil {
+		return l.err
+	}
+	if n <= 0 {
+		return nil
+	}
+	l.committed += n
+	if l.committed > l.appended {
+		l.committed = l.appended
+	}
+	var payload [binary.MaxVarintLen64]byte
+	if l.err = l.record(recordCommit, payload[:binary.PutUvarint(payload[:], uint64(l.committed))]); l.err != nil {
+		return l.err
+	}
+	// the commit is recorded in the current segment before the previous ones are removed
+	i := 0
+	for ; i < len(l.segments) && l.segments[i].end <= l.committed; i++ {
+		if err := os.Remove(l.segments[i].name); err != nil && !os.IsNotExist(err) {
+			break
+		}
+	}
+	l.segments = l.segments[:copy(l.segments, l.segments[i:])]
+	return nil
+}
+
+// Stats returns the counters of the log
+func (l *Log) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := Stats{Appended: l.appended, Committed: l.committed, Segments: len(l.segments)}
+	if !l.closed || l.committed < l.appended {
+		// the current segment
+		s.Segments++
+	}
+	return s
+}
+
+// Close closes the log, the segments are removed when all the data is committed and kept for
+// Replay otherwise. The Buffer writing to the log must be closed first.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	if l.committed < l.appended || l.err != nil {
+		err := l.f.Sync()
+		if cerr := l.f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+	err := l.f.Close()
+	l.segments = append(l.segments, l.cur)
+	for _, s := range l.segments {
+		if rerr := os.Remove(s.name); err == nil {
+			err = rerr
+		}
+	}
+	l.segments = nil
+	return err
+}
+
+// segmentNames returns the segment files of dir in order
+func segmentNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), segmentExt) {
+			names = append(names, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// segmentReader reads the records of a segment file
+type segmentReader struct {
+	name string
+	f    *os.File
+	r    *bufio.Reader
+	// size is the size of the file and off the offset of the next record
+	size int64
+	off  int64
+	// base and committed are the header fields
+	base      int64
+	committed int64
+}
+
+// openSegment opens a segment and reads its header, it returns errTorn if the header is
+// truncated
+func openSegment(name string) (*segmentReader, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s := &segmentReader{name: name, f: f, r: bufio.NewReader(f), size: info.Size()}
+	var header [len(segmentMagic) + 1]byte
+	_, err = io.ReadFull(s.r, header[:])
+	var base, committed uint64
+	if err == nil {
+		base, err = binary.ReadUvarint(s.r)
+	}
+	if err == nil {
+		committed, err = binary.ReadUvarint(s.r)
+	}
+	if err != nil {
+		// the crash happened creating the segment
+		f.Close()
+		return nil, errTorn
+	}
+	if string(header[:len(segmentMagic)]) != segmentMagic {
+		f.Close()
+		return nil, fmt.Errorf("%w: %v: bad header", ErrCorrupt, name)
+	}
+	if v := header[len(segmentMagic)]; v != segmentVersion {
+		f.Close()
+		return nil, fmt.Errorf("%w: %v: unknown version %v", ErrCorrupt, name, v)
+	}
+	s.base, s.committed = int64(base), int64(committed)
+	s.off = int64(len(header)) + int64(uvarintLen(base)+uvarintLen(committed))
+	return s, nil
+}
+
+func uvarintLen(v uint64) int {
+	var b [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(b[:], v)
+}
+
+// next returns the next record, io.EOF at the end of the segment and errTorn for a record
+// truncated or with a bad checksum
+func (s *segmentReader) next() (byte, []byte, error) {
+	typ, err := s.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, err := binary.ReadUvarint(s.r)
+	if err != nil || n > uint64(s.size-s.off) {
+		return 0, nil, errTorn
+	}
+	rec := make([]byte, 1+uvarintLen(n)+int(n)+4)
+	rec[0] = typ
+	binary.PutUvarint(rec[1:], n)
+	if _, err := io.ReadFull(s.r, rec[1+uvarintLen(n):]); err != nil {
+		return 0, nil, errTorn
+	}
+	sum := binary.BigEndian.Uint32(rec[len(rec)-4:])
+	if crc32.Checksum(rec[:len(rec)-4], crcTable) != sum {
+		return 0, nil, errTorn
+	}
+	s.off += int64(len(rec))
+	return typ, rec[len(rec)-4-int(n) : len(rec)-4], nil
+}
+
+// errTorn is the error of a record not written whole
+var errTorn = errors.New("torn record")
+
+// records calls fn with the records of the segment, a torn record ends the last segment
+func (s *segmentReader) records(last bool, fn func(typ byte, payload []byte) error) error {
+	for {
+		typ, payload, err := s.next()
+		if err == io.EOF || err == errTorn && last {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v: record at offset %v", ErrCorrupt, s.name, s.off)
+		}
+		if err := fn(typ, payload); err != nil {
+			return err
+		}
+	}
+}
+
+// Replay writes to w the data of the segments in dir not committed, every write appended
+// with one Write, and removes the segments once it's written. A record torn at the end of
+// the last segment, by a crash while it was written, is ignored. It returns the bytes
+// written, and 0 without error if dir holds no segments.
+func Replay(dir string, w io.Writer) (int64, error) {
+	names, err := segmentNames(dir)
+	if err != nil || len(names) == 0 {
+		return 0, err
+	}
+
+	// the last commit is found first, it can be in a segment after the data it commits
+	var committed int64
+	for i, name := range names {
+		s, err := openSegment(name)
+		if err == errTorn && i == len(names)-1 {
+			break
+		}
+		if err == errTorn {
+			err = fmt.Errorf("%w: %v: bad header", ErrCorrupt, name)
+		}
+		if err != nil {
+			return 0, err
+		}
+		if s.committed > committed {
+			committed = s.committed
+		}
+		err = s.records(i == len(names)-1, func(typ byte, payload []byte) error {
+			if typ != recordCommit {
+				return nil
+			}
+			v, n := binary.Uvarint(payload)
+			if n <= 0 {
+				return fmt.Errorf("%w: %v: bad commit", ErrCorrupt, name)
+			}
+			if int64(v) > committed {
+				committed = int64(v)
+			}
+			return nil
+		})
+		s.f.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var written int64
+	for i, name := range names {
+		s, err := openSegment(name)
+		if err == errTorn && i == len(names)-1 {
+			break
+		}
+		if err != nil {
+			return written, err
+		}
+		offset := s.base
+		err = s.records(i == len(names)-1, func(typ byte, payload []byte) error {
+			if typ != recordWrite {
+				return nil
+			}
+			end := offset + int64(len(payload))
+			if end > committed {
+				if offset < committed {
+					payload = payload[committed-offset:]
+				}
+				n, err := w.Write(payload)
+				written += int64(n)
+				if err == nil && n < len(payload) {
+					err = io.ErrShortWrite
+				}
+				if err != nil {
+					return err
+				}
+			}
+			offset = end
+			return nil
+		})
+		s.f.Close()
+		if err != nil {
+			return written, err
+		}
+	}
+	for _, name := range names {
+		if err := os.Remove(name); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,255 @@
+package journal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+var _ syncio.WriteAheadLog = (*Log)(nil)
+
+func replay(t *testing.T, dir string) string {
+	t.Helper()
+	var out bytes.Buffer
+	n, err := Replay(dir, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(out.Len()) {
+		t.Errorf("replayed: %v, written: %v", n, out.Len())
+	}
+	return out.String()
+}
+
+func segments(t *testing.T, dir string) int {
+	t.Helper()
+	names, err := segmentNames(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(names)
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"aaa", "bbb", "ccc"} {
+		if err := l.Append([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the commit ends in the middle of a write
+	l.Commit(4)
+	if _, err := Open(dir); !errors.Is(err, ErrPending) {
+		t.Errorf("open error: %v, expected: %v", err, ErrPending)
+	}
+
+	// crash without closing the log
+	if s := replay(t, dir); s != "bbccc" {
+		t.Errorf("replayed: %q, expected: %q", s, "bbccc")
+	}
+	if n := segments(t, dir); n != 0 {
+		t.Errorf("segments after replay: %v", n)
+	}
+	if s := replay(t, dir); s != "" {
+		t.Errorf("replayed again: %q", s)
+	}
+	l2, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l2.Close()
+}
+
+func TestClose(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := Open(dir)
+	l.Append([]byte("aaa"))
+	l.Commit(3)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := segments(t, dir); n != 0 {
+		t.Errorf("segments after close: %v, expected: 0", n)
+	}
+	if err := l.Append([]byte("b")); err != ErrClosed {
+		t.Errorf("append error: %v, expected: %v", err, ErrClosed)
+	}
+
+	// the data not committed is kept
+	l, _ = Open(dir)
+	l.Append([]byte("aaa"))
+	l.Close()
+	if s := replay(t, dir); s != "aaa" {
+		t.Errorf("replayed: %q, expected: %q", s, "aaa")
+	}
+}
+
+func TestSegments(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := Open(dir, SetSegmentSize(64), SetSync(false))
+	defer l.Close()
+	var all []string
+	for i := 0; i < 20; i++ {
+		s := strings.Repeat(string(rune('a'+i)), 10)
+		all = append(all, s)
+		l.Append([]byte(s))
+	}
+	if s := l.Stats(); s.Segments < 5 || s.Appended != 200 {
+		t.Fatalf("stats: %+v, expected at least 5 segments", s)
+	}
+	// a write bigger than the segment fills it alone
+	l.Append([]byte(strings.Repeat("z", 100)))
+	all = append(all, strings.Repeat("z", 100))
+
+	l.Commit(150)
+	s := l.Stats()
+	if n := segments(t, dir); n != s.Segments || s.Committed != 150 {
+		t.Errorf("segments: %v, stats: %+v", n, s)
+	}
+	if s.Segments >= 5 {
+		t.Errorf("segments: %v, expected the committed ones removed", s.Segments)
+	}
+	if got, want := replay(t, dir), strings.Join(all, "")[150:]; got != want {
+		t.Errorf("replayed: %q, expected: %q", got, want)
+	}
+}
+
+func TestTorn(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := Open(dir, SetSegmentSize(32), SetSync(false))
+	for _, s := range []string{"aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc", "dddddddddd"} {
+		l.Append([]byte(s))
+	}
+	names, _ := segmentNames(dir)
+	last := names[len(names)-1]
+	info, _ := os.Stat(last)
+	// the crash happened writing the last record
+	os.Truncate(last, info.Size()-3)
+	if s := replay(t, dir); s != "aaaaaaaaaabbbbbbbbbbcccccccccc" {
+		t.Errorf("replayed: %q", s)
+	}
+
+	// a bad checksum before the last segment
+	dir = t.TempDir()
+	l, _ = Open(dir, SetSegmentSize(32), SetSync(false))
+	for _, s := range []string{"aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc"} {
+		l.Append([]byte(s))
+	}
+	names, _ = segmentNames(dir)
+	p, _ := os.ReadFile(names[0])
+	p[len(p)-6] ^= 0xff
+	os.WriteFile(names[0], p, 0644)
+	if _, err := Replay(dir, &bytes.Buffer{}); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("replay error: %v, expected: %v", err, ErrCorrupt)
+	}
+	if n := segments(t, dir); n != len(names) {
+		t.Errorf("segments: %v, expected them kept", n)
+	}
+
+	// a segment created at the crash
+	dir = t.TempDir()
+	l, _ = Open(dir)
+	l.Append([]byte("aaa"))
+	os.WriteFile(filepath.Join(dir, "ffffffffffffffff"+segmentExt), []byte(segmentMagic), 0644)
+	if s := replay(t, dir); s != "aaa" {
+		t.Errorf("replayed: %q, expected: %q", s, "aaa")
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := Open(dir, SetSync(false))
+	var sink bytes.Buffer
+	tb := syncio.NewBuffer(&sink, syncio.SetBufferSize(16), syncio.SetSynchronousMode(true),
+		syncio.SetManualTick(true), syncio.SetWriteAheadLog(l))
+	var all []string
+	for i := 0; i < 10; i++ {
+		s := strings.Repeat(string(rune('a'+i)), 5) + "\n"
+		all = append(all, s)
+		if _, err := tb.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := l.Stats(); s.Appended != 60 || s.Committed != int64(sink.Len()) {
+		t.Errorf("log: %+v, flushed: %v", s, sink.Len())
+	}
+
+	// crash before the buffered data is flushed
+	flushed := sink.String()
+	replayed := replay(t, dir)
+	if flushed+replayed != strings.Join(all, "") || replayed == "" {
+		t.Errorf("flushed: %q, replayed: %q", flushed, replayed)
+	}
+
+	tb.Close()
+	if s := l.Stats(); s.Committed != s.Appended {
+		t.Errorf("log after close: %+v, expected all committed", s)
+	}
+	l.Close()
+}
+
+// gatedSink blocks the writes starting with gate until release is closed
+type gatedSink struct {
+	gate    byte
+	release chan struct{}
+	mu      sync.Mutex
+	out     bytes.Buffer
+}
+
+func (w *gatedSink) Write(p []byte) (int, error) {
+	if p[0] == w.gate {
+		<-w.release
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.out.Write(p)
+}
+
+func (w *gatedSink) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.out.String()
+}
+
+func TestBufferConcurrency(t *testing.T) {
+	// the second batch is written before the first one, the commit waits for both
+	dir := t.TempDir()
+	l, _ := Open(dir, SetSync(false))
+	sink := &gatedSink{gate: 'a', release: make(chan struct{})}
+	tb := syncio.NewBuffer(sink, syncio.SetBufferSize(8),
+		syncio.SetFlushConcurrency(2), syncio.SetPreserveOrder(false), syncio.SetWriteAheadLog(l))
+	tb.Write([]byte("aaaaaaaa"))
+	tb.Write([]byte("bbbbbbbb"))
+	for deadline := time.Now().Add(time.Second); sink.String() != "bbbbbbbb"; {
+		if time.Now().After(deadline) {
+			t.Fatalf("written: %q, expected: %q", sink.String(), "bbbbbbbb")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if s := l.Stats(); s.Committed != 0 {
+		t.Errorf("committed: %v, expected: 0", s.Committed)
+	}
+
+	// crash before the first batch is written
+	if s := replay(t, dir); s != "aaaaaaaabbbbbbbb" {
+		t.Errorf("replayed: %q, expected: %q", s, "aaaaaaaabbbbbbbb")
+	}
+
+	close(sink.release)
+	tb.Close()
+	if s := l.Stats(); s.Committed != 16 || s.Appended != 16 {
+		t.Errorf("log after close: %+v, expected 16 bytes committed", s)
+	}
+	l.Close()
+}
@@ -0,0 +1,70 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	var journal bytes.Buffer
+	clock := newFakeClock()
+	sink := &recordingWriter{}
+	tb := NewBuffer(sink, SetBufferSize(8), SetClock(clock), SetJournal(&journal))
+
+	// the data is the one generated by the replay
+	var offset int
+	write := func(n int) {
+		p := make([]byte, n)
+		for i := range p {
+			p[i] = byte((offset + i) % 251)
+		}
+		offset += n
+		tb.Write(p)
+	}
+	write(3)
+	write(7)
+	clock.Advance(time.Second)
+	tb.tick()
+	write(2)
+	settle(tb)
+	tb.Flush()
+	write(20)
+	write(1)
+	tb.Close()
+	tb.Write([]byte("closed"))
+
+	if bytes.Contains(journal.Bytes(), []byte("closed")) {
+		t.Error("journal with the data written")
+	}
+	replayed := &recordingWriter{}
+	rb, err := ReplayJournal(bytes.NewReader(journal.Bytes()), replayed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed.writes) != len(sink.writes) {
+		t.Fatalf("replayed %v sink writes: %q, expected %v: %q", len(replayed.writes), replayed.writes, len(sink.writes), sink.writes)
+	}
+	for i := range sink.writes {
+		if replayed.writes[i] != sink.writes[i] {
+			t.Errorf("replayed sink write %v: %q, expected: %q", i, replayed.writes[i], sink.writes[i])
+		}
+	}
+	if s := rb.Stats(); s.Errors[ErrorClosed] != 0 || s.CallerWrites != 5 {
+		t.Errorf("replayed stats: %+v", s)
+	}
+	if err := rb.Flush(); err != ErrWriteOnClosed {
+		t.Errorf("replayed Buffer flush: %v, expected: closed", err)
+	}
+}
+
+func TestReplayJournalVersion(t *testing.T) {
+	journal := append([]byte(journalMagic), journalVersion+1, journalConfig)
+	if _, err := ReplayJournal(bytes.NewReader(journal), &bytes.Buffer{}); !errors.Is(err, ErrJournalVersion) {
+		t.Errorf("replay: %v, expected: %v", err, ErrJournalVersion)
+	}
+	if _, err := ReplayJournal(bytes.NewReader([]byte("data")), &bytes.Buffer{}); err == nil {
+		t.Error("replay of data that isn't a journal")
+	}
+}
@@ -0,0 +1,67 @@
+package syncio
+
+import "time"
+
+// latencySteps is the number of checks of the data age within the max latency
+const latencySteps = 8
+
+// SetMaxLatency flushes the active buffer before its oldest byte is d old, so the data is
+// handed to the flush goroutine within d of being written instead of on every tick: the
+// buffer is only flushed when it holds data about to be late. It replaces the ticks of the
+// flush interval and it's ignored with SetFlushScheduler. The age is checked every d/8 and
+// the time waiting in the queue for the underlying writer is not included, with
+// SetSynchronousMode the late data is written by the checking goroutine.
+func SetMaxLatency(d time.Duration) BufferOption {
+	return func(b *Buffer) {
+		b.maxLatency = d
+	}
+}
+
+// startLatency starts the goroutine checking the age of the data, tracked with the backlog
+// stamps as SetFlushScheduler
+func (tb *Buffer) startLatency() {
+	if tb.scheduler != nil {
+		return
+	}
+	if tb.backlog == nil {
+		tb.backlog = &backlog{maxAge: scheduledAge}
+	}
+	tb.stop = make(chan struct{})
+	go tb.latencyLoop()
+}
+
+// latencyLoop flushes the active buffer when its data would be late at the next check
+func (tb *Buffer) latencyLoop() {
+	period := tb.maxLatency / latencySteps
+	if period <= 0 {
+		period = tb.maxLatency
+	}
+	c, stop := tb.clock.NewTicker(period)
+	defer stop()
+	for {
+		select {
+		case <-tb.stop:
+			return
+		case <-c:
+			since := tb.backlog.active.Load()
+			if since == 0 || time.Duration(tb.clock.Now().UnixNano()-since) < tb.maxLatency-period {
+				continue
+			}
+			tb.latencyFlush()
+		}
+	}
+}
+
+// latencyFlush flushes the active buffer, the data of a full queue waits for the next check
+func (tb *Buffer) latencyFlush() {
+	var sw swap
+	tb.lockBuf()
+	if !tb.closed && !tb.queueFull(0) {
+		sw = tb.flush(TriggerTick, nil)
+	}
+	tb.unlockBuf()
+	if tb.logger != nil {
+		tb.logSwap(sw)
+	}
+	tb.flushInline()
+}
@@ -0,0 +1,53 @@
+package syncio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaxLatency(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		clock := newFakeClock()
+		sink := &lockedBuffer{}
+		tb := NewBuffer(sink, append([]BufferOption{SetClock(clock), SetMaxLatency(80 * time.Millisecond), SetFlushInterval(time.Hour)}, mode...)...)
+		defer tb.Close()
+		ticker := clock.ticker(0)
+		// the second tick is received once the first one is handled
+		tick := func(d time.Duration) {
+			clock.Advance(d)
+			ticker <- clock.Now()
+			ticker <- clock.Now()
+		}
+		wait := func(expected string) {
+			t.Helper()
+			deadline := time.Now().Add(time.Second)
+			for sink.String() != expected && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if s := sink.String(); s != expected {
+				t.Fatalf("written: %q, expected: %q", s, expected)
+			}
+		}
+
+		tick(10 * time.Millisecond)
+		tb.Write([]byte("a"))
+		tick(10 * time.Millisecond)
+		wait("")
+		// late at the next check
+		tick(60 * time.Millisecond)
+		wait("a")
+
+		// the fresh data isn't flushed
+		tb.Write([]byte("b"))
+		tick(10 * time.Millisecond)
+		tick(10 * time.Millisecond)
+		if s := sink.String(); s != "a" {
+			t.Fatalf("fresh data flushed: %q", s)
+		}
+		tick(60 * time.Millisecond)
+		wait("ab")
+		if s := tb.Stats(); s.Flushes != 2 {
+			t.Errorf("flushes: %v, expected: 2", s.Flushes)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package syncio
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrSinkPending is the flush error of the data discarded when a Buffer is closed before
+// its underlying writer is provided
+var ErrSinkPending = errors.New("underlying writer not provided")
+
+// maxSinkBackoff is the maximum delay between the attempts of the SetLazySink factory
+const maxSinkBackoff = time.Minute
+
+// SetLazySink creates the underlying writer with fn once it's ready, e.g. a connection
+// established after the producers start, the writer given to NewBuffer is ignored. fn is
+// tried on the ticks with a backoff that doubles from the flush interval up to a minute,
+// it's meant to be used with SetFlushInterval or SetManualTick. See NewBuffer for the
+// pending sink, fn may block the ticks meanwhile.
+func SetLazySink(fn func() (io.Writer, error)) BufferOption {
+	return func(b *Buffer) {
+		b.lazySink = &lazySink{fn: fn}
+	}
+}
+
+// lazySink is the factory of SetLazySink with the state of its backoff, mu serializes
+// the attempts of the concurrent ticks
+type lazySink struct {
+	fn func() (io.Writer, error)
+
+	mu      sync.Mutex
+	next    time.Time
+	backoff time.Duration
+}
+
+// tryLazySink calls the SetLazySink factory if its backoff elapsed, the caller must not
+// hold bufmu
+func (tb *Buffer) tryLazySink() {
+	l := tb.lazySink
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tb.bufmu.Lock()
+	pending := tb.sinkPending && !tb.closed
+	tb.bufmu.Unlock()
+	now := tb.clock.Now()
+	if !pending || now.Before(l.next) {
+		return
+	}
+	w, err := l.fn()
+	if err != nil {
+		if l.backoff *= 2; l.backoff == 0 {
+			l.backoff = tb.flushInterval
+		}
+		if l.backoff > maxSinkBackoff || l.backoff <= 0 {
+			l.backoff = maxSinkBackoff
+		}
+		l.next = now.Add(l.backoff)
+		if tb.logger != nil {
+			tb.logger(EventSinkPending, map[string]any{"error": err, "retry": l.backoff})
+		}
+		return
+	}
+	tb.lockBuf()
+	if !tb.closed {
+		tb.provideSink(w)
+	}
+	tb.unlockBuf()
+	tb.flushInline()
+}
+
+// provideSink sets the pending underlying writer and lets the flush goroutine write the
+// queued batches, the caller must hold bufmu
+func (tb *Buffer) provideSink(w io.Writer) {
+	tb.writer = w
+	tb.parent, _ = w.(*Buffer)
+	tb.detectSink()
+	tb.sinkPending = false
+	tb.ready.Signal()
+}
+
+// SinkPending reports if the Buffer is waiting for its underlying writer, see NewBuffer
+func (tb *Buffer) SinkPending() bool {
+	tb.bufmu.Lock()
+	defer tb.bufmu.Unlock()
+	return tb.sinkPending
+}
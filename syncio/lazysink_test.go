@@ -0,0 +1,79 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestPendingSink(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		tb := NewBuffer(nil, append([]BufferOption{SetBufferSize(4)}, mode...)...)
+		tb.Write([]byte("abcd"))
+		tb.Write([]byte("efgh"))
+		tb.Write([]byte("ij"))
+		if !tb.SinkPending() {
+			t.Fatal("sink not pending")
+		}
+
+		sink := &bytes.Buffer{}
+		if old, err := tb.SwapWriter(sink); old != nil || err != nil {
+			t.Fatalf("swap of the pending sink: %v, %v", old, err)
+		}
+		if err := tb.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if sink.String() != "abcdefghij" {
+			t.Errorf("sink data: %q, expected: %q", sink.String(), "abcdefghij")
+		}
+	})
+}
+
+func TestPendingSinkClosed(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		dl := &bytes.Buffer{}
+		tb := NewBuffer(nil, append([]BufferOption{SetBufferSize(4), SetDeadLetter(dl)}, mode...)...)
+		tb.Write([]byte("abcdef"))
+		if err := tb.Close(); !errors.Is(err, ErrSinkPending) {
+			t.Errorf("close: %v, expected: %v", err, ErrSinkPending)
+		}
+		if dl.String() != "abcdef" {
+			t.Errorf("dead letter data: %q, expected: %q", dl.String(), "abcdef")
+		}
+	})
+}
+
+func TestLazySink(t *testing.T) {
+	clock := newFakeClock()
+	sink := &bytes.Buffer{}
+	attempts := 0
+	tb := NewBuffer(&bytes.Buffer{}, SetClock(clock), SetManualTick(true), SetFlushInterval(time.Second),
+		SetLazySink(func() (io.Writer, error) {
+			if attempts++; attempts < 4 {
+				return nil, errors.New("not ready")
+			}
+			return sink, nil
+		}))
+	tb.Write([]byte("data"))
+
+	// the backoff doubles from the flush interval
+	expected := []int{1, 2, 2, 3, 3, 3, 3, 4}
+	for i, n := range expected {
+		if i > 0 {
+			clock.Advance(time.Second)
+		}
+		tb.Tick()
+		if attempts != n {
+			t.Fatalf("attempts after tick %v: %v, expected: %v", i, attempts, n)
+		}
+		if n < 4 && (sink.Len() > 0 || !tb.SinkPending()) {
+			t.Fatalf("sink provided after tick %v", i)
+		}
+	}
+	if sink.String() != "data" {
+		t.Errorf("sink data: %q, expected: %q", sink.String(), "data")
+	}
+	tb.Close()
+}
@@ -0,0 +1,32 @@
+package syncio
+
+// Event names passed to the SetLogger function, they are stable so log pipelines can rely on them.
+// The fields sent with each event are listed next to it.
+const (
+	EventSwap        = "swap"         // the active buffer was sent to flush: bytes, trigger
+	EventFlushStart  = "flush_start"  // a batch is going to be written: bytes, trigger
+	EventFlushEnd    = "flush_end"    // a batch write returned: bytes, written, trigger, duration, error
+	EventPoolGrow    = "pool_grow"    // a buffer was allocated because the pool was empty: size, allocs
+	EventPoolShrink  = "pool_shrink"  // a buffer was released because the pool was full: size
+	EventDrop        = "drop"         // data was discarded after a flush error: bytes, trigger, error
+	EventSinkPending = "sink_pending" // the SetLazySink factory failed: error, retry
+)
+
+// SetLogger sets a function to receive the Buffer lifecycle events, meant for debugging.
+// It's never called holding the write path lock, and there is no cost when it's not set.
+func SetLogger(fn func(event string, fields map[string]any)) BufferOption {
+	return func(b *Buffer) {
+		b.logger = fn
+	}
+}
+
+// logSwap logs the events of a buffer replacement, it must be called after releasing bufmu
+func (tb *Buffer) logSwap(sw swap) {
+	if sw.bytes == 0 {
+		return
+	}
+	tb.logger(EventSwap, map[string]any{"bytes": sw.bytes, "trigger": sw.trigger.String()})
+	if sw.alloc {
+		tb.logger(EventPoolGrow, map[string]any{"size": sw.size, "allocs": tb.Stats().BufferAllocs})
+	}
+}
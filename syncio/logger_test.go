@@ -0,0 +1,73 @@
+package syncio
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func TestLogger(t *testing.T) {
+	el := &eventLog{}
+	var tb *Buffer
+	logger := func(event string, fields map[string]any) {
+		el.mu.Lock()
+		defer el.mu.Unlock()
+		el.events = append(el.events, event)
+		// deadlocks if the caller holds the lock
+		tb.bufmu.Lock()
+		tb.bufmu.Unlock()
+	}
+	fw := synctest.NewFailingWriter(nil, nil)
+	fw.SetFailing(false)
+	tb = NewBuffer(fw, SetBufferSize(10), SetBufferPoolSize(1), SetLogger(logger))
+
+	done := make(chan struct{})
+	go func() {
+		tb.Write(make([]byte, 6))
+		tb.Write(make([]byte, 6)) // swap with a new buffer
+		tb.Flush()
+		fw.SetFailing(true)
+		tb.Write(make([]byte, 6))
+		tb.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("logger called holding the write lock")
+	}
+
+	// the pool is full after closing, force a release
+	b1, _ := tb.pool.Get()
+	b2, _ := tb.pool.Get()
+	tb.putBuffer(b1)
+	tb.putBuffer(b2)
+
+	// events from the writers and the flush goroutine interleave, check the counts
+	expected := map[string]int{
+		EventSwap:       3,
+		EventPoolGrow:   1,
+		EventFlushStart: 3,
+		EventFlushEnd:   3,
+		EventDrop:       1,
+		EventPoolShrink: 1,
+	}
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	counts := map[string]int{}
+	for _, e := range el.events {
+		counts[e]++
+	}
+	for e, n := range expected {
+		if counts[e] < n {
+			t.Errorf("%v events: %v, expected at least: %v", e, counts[e], n)
+		}
+	}
+}
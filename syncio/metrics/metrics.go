@@ -0,0 +1,108 @@
+// Package metrics exports the stats of the syncio Buffers for the dashboards: a Registry is
+// an http.Handler serving them in the OpenMetrics text format, scraped by Prometheus, and is
+// published as an expvar variable.
+package metrics
+
+import (
+	"bytes"
+	"expvar"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+// ContentType is the content type of the OpenMetrics text served by a Registry
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// Registry is a set of named Buffers whose stats are read at every scrape, the counters are
+// read atomically by Buffer.StatsInto so the values are never older than the request. It's
+// safe for concurrent use.
+type Registry struct {
+	prefix string
+	labels map[string]string
+
+	mu      sync.Mutex
+	buffers map[string]*syncio.Buffer
+}
+
+// NewRegistry returns an empty Registry, the metric names start with prefix, e.g. "syncio_",
+// and the samples have the labels besides the label "buffer" with the name of the Buffer
+func NewRegistry(prefix string, labels map[string]string) *Registry {
+	return &Registry{prefix: prefix, labels: labels, buffers: make(map[string]*syncio.Buffer)}
+}
+
+// Register adds b with the given name, replacing the Buffer registered with it
+func (r *Registry) Register(name string, b *syncio.Buffer) {
+	r.mu.Lock()
+	r.buffers[name] = b
+	r.mu.Unlock()
+}
+
+// Unregister removes the Buffer registered with name, a closed Buffer keeps being exported
+// until it's removed
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.buffers, name)
+	r.mu.Unlock()
+}
+
+// snapshot returns the names sorted and the stats of the registered Buffers
+func (r *Registry) snapshot() ([]string, []syncio.Stats) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.buffers))
+	for name := range r.buffers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	stats := make([]syncio.Stats, len(names))
+	for i, name := range names {
+		r.buffers[name].StatsInto(&stats[i])
+	}
+	r.mu.Unlock()
+	return names, stats
+}
+
+// WriteOpenMetrics writes the stats of every registered Buffer to w in the OpenMetrics text
+// format, see syncio.Stats.WriteOpenMetrics
+func (r *Registry) WriteOpenMetrics(w io.Writer) error {
+	names, stats := r.snapshot()
+	labels := make([]map[string]string, len(names))
+	for i, name := range names {
+		labels[i] = make(map[string]string, len(r.labels)+1)
+		for k, v := range r.labels {
+			labels[i][k] = v
+		}
+		labels[i]["buffer"] = name
+	}
+	return syncio.WriteOpenMetricsSet(w, r.prefix, labels, stats)
+}
+
+// ServeHTTP serves the OpenMetrics text of WriteOpenMetrics
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var out bytes.Buffer
+	if err := r.WriteOpenMetrics(&out); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Write(out.Bytes())
+}
+
+// Stats returns the stats of the registered Buffers by name
+func (r *Registry) Stats() map[string]syncio.Stats {
+	names, stats := r.snapshot()
+	m := make(map[string]syncio.Stats, len(names))
+	for i, name := range names {
+		m[name] = stats[i]
+	}
+	return m
+}
+
+// Publish publishes the Registry as the expvar variable name, its value is the JSON object
+// of Stats. It panics if name is already published, as expvar.Publish.
+func (r *Registry) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return r.Stats() }))
+}
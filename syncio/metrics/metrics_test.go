@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+// lockedWriter discards the data, the buffers flush concurrently with the scrapes
+type lockedWriter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	w.n += len(p)
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+func TestRegistryServeHTTP(t *testing.T) {
+	a := syncio.NewBuffer(&lockedWriter{})
+	b := syncio.NewBuffer(&lockedWriter{})
+	defer a.Close()
+	defer b.Close()
+	a.Write([]byte("hello"))
+	a.Flush()
+
+	r := NewRegistry("app_", map[string]string{"env": "test"})
+	r.Register("b", b)
+	r.Register("a", a)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("content type: %q", ct)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE app_flushes counter\n",
+		`app_flushes_total{buffer="a",env="test"} 1` + "\n",
+		`app_flushes_total{buffer="b",env="test"} 0` + "\n",
+		`app_flush_latency_seconds_bucket{buffer="a",env="test",le="+Inf"} 1` + "\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+	if strings.Count(body, "# TYPE app_flushes ") != 1 {
+		t.Errorf("family repeated:\n%s", body)
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Errorf("not terminated:\n%s", body)
+	}
+
+	r.Unregister("b")
+	var out strings.Builder
+	if err := r.WriteOpenMetrics(&out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), `buffer="b"`) {
+		t.Errorf("unregistered buffer exported:\n%s", out.String())
+	}
+}
+
+// published numbers the expvar names of the tests, a name can't be published twice with -count
+var published atomic.Int64
+
+func TestRegistryPublish(t *testing.T) {
+	tb := syncio.NewBuffer(&lockedWriter{})
+	defer tb.Close()
+	r := NewRegistry("", nil)
+	r.Register("events", tb)
+	name := fmt.Sprintf("syncio_test_buffers_%v", published.Add(1))
+	r.Publish(name)
+
+	v := expvar.Get(name)
+	if v == nil {
+		t.Fatal("not published")
+	}
+	tb.Write([]byte("hello"))
+	tb.Flush()
+	var decoded map[string]struct{ Flushes, CallerWrites int64 }
+	if err := json.Unmarshal([]byte(v.String()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if s := decoded["events"]; s.Flushes != 1 || s.CallerWrites != 1 {
+		t.Errorf("published stats: %+v", decoded)
+	}
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	tb := syncio.NewBuffer(&lockedWriter{}, syncio.SetBufferSize(16))
+	r := NewRegistry("syncio_", nil)
+	r.Register("events", tb)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			tb.Write([]byte("0123456789"))
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if err := r.WriteOpenMetrics(io.Discard); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	tb.Close()
+}
@@ -0,0 +1,14 @@
+package syncio
+
+import "errors"
+
+// ErrNotInitialized is returned by the methods of a Buffer not created by NewBuffer, or a
+// Reader not created by NewReader
+var ErrNotInitialized = errors.New("buffer not created by NewBuffer")
+
+// initialized reports if the Buffer was created by NewBuffer, the zero value fails with
+// ErrNotInitialized instead of dereferencing its nil fields. done is set before the Buffer
+// is returned and never changes.
+func (tb *Buffer) initialized() bool {
+	return tb.done != nil
+}
@@ -0,0 +1,120 @@
+package syncio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestZeroValue(t *testing.T) {
+	var tb Buffer
+	calls := map[string]func() error{
+		"Write": func() error { _, err := tb.Write([]byte("abc")); return err },
+		"WriteContext": func() error {
+			_, err := tb.WriteContext(context.Background(), []byte("abc"))
+			return err
+		},
+		"WriteOwned":  func() error { _, err := tb.WriteOwned([]byte("abc")); return err },
+		"Reserve":     func() error { _, err := tb.Reserve(3); return err },
+		"ReadFrom":    func() error { _, err := tb.ReadFrom(strings.NewReader("abc")); return err },
+		"Commit":      func() error { return tb.Commit(3) },
+		"WriteByte":   func() error { return tb.WriteByte('a') },
+		"WriteUint16": func() error { return tb.WriteUint16(1, binary.BigEndian) },
+		"WriteUint32": func() error { return tb.WriteUint32(1, binary.BigEndian) },
+		"WriteUint64": func() error { return tb.WriteUint64(1, binary.BigEndian) },
+		"Flush":       tb.Flush,
+		"Sync":        tb.Sync,
+		"CloseWrites": tb.CloseWrites,
+		"FlushDeep":   tb.FlushDeep,
+		"Tick":        tb.Tick,
+		"Seek":        func() error { _, err := tb.Seek(0, io.SeekStart); return err },
+		"SwapWriter":  func() error { _, err := tb.SwapWriter(&testWriter{}); return err },
+		"Replay":      func() error { _, err := tb.Replay(strings.NewReader("a\n"), []byte("\n")); return err },
+		"DumpState":   func() error { return tb.DumpState(&bytes.Buffer{}) },
+		"CloseTimeout": func() error {
+			_, err := tb.CloseTimeout(time.Second)
+			return err
+		},
+		"CloseContext": func() error {
+			_, err := tb.CloseContext(context.Background())
+			return err
+		},
+		"CloseOnContext": func() error {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return <-tb.CloseOnContext(ctx, 0)
+		},
+		"WaitForFlushes": func() error { return tb.WaitForFlushes(context.Background(), 1) },
+		"WaitForBytes":   func() error { return tb.WaitForBytes(context.Background(), 1) },
+		"PipeWriter": func() error {
+			pw := tb.PipeWriter()
+			defer pw.Close()
+			_, err := pw.Write([]byte("a"))
+			return err
+		},
+		"Close": tb.Close,
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, ErrNotInitialized) {
+			t.Errorf("%v: %v, expected: %v", name, err, ErrNotInitialized)
+		}
+	}
+
+	// the accessors return the zero values
+	if s := tb.Stats(); s.Flushes != 0 || s.CallerWrites != 0 {
+		t.Errorf("stats: %+v", s)
+	}
+	tb.StatsInto(&Stats{})
+	tb.AppliedOptions()
+	if tb.Snapshot() != nil || tb.Steal() != nil || tb.SinkPending() || tb.Backlogged() || tb.Producers() != 0 {
+		t.Error("accessors of the zero value")
+	}
+	if _, err := tb.ReaderAt().ReadAt(make([]byte, 1), 0); err == nil {
+		t.Error("data retained")
+	}
+	if active, _, _ := tb.InFlightFlush(); active {
+		t.Error("flush in flight")
+	}
+}
+
+func TestConcurrentClose(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		tw := &testWriter{}
+		tb := NewBuffer(tw, append(mode, SetBufferSize(16))...)
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				tb.Write([]byte("0123456789"))
+			}()
+			go func() {
+				defer wg.Done()
+				tb.Close()
+			}()
+			// Stats is callable at any point of the lifecycle
+			go func() {
+				defer wg.Done()
+				tb.Stats()
+				tb.DumpState(io.Discard)
+			}()
+		}
+		wg.Wait()
+		if err := tb.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tb.Write([]byte("a")); err != ErrWriteOnClosed {
+			t.Errorf("write after close: %v", err)
+		}
+		if s := tb.Stats(); s.DirectBytes != atomic.LoadInt64(&tw.bytes) {
+			t.Errorf("bytes flushed: %v, written: %v", s.DirectBytes, atomic.LoadInt64(&tw.bytes))
+		}
+	})
+}
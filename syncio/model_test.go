@@ -0,0 +1,233 @@
+package syncio
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// modelSink records the sink writes, it's only read once the Buffer settles
+type modelSink struct {
+	bytes.Buffer
+	writes int64
+}
+
+func (s *modelSink) Write(p []byte) (int, error) {
+	s.writes++
+	return s.Buffer.Write(p)
+}
+
+// settle waits until the queued batches are written, the active buffer is not flushed
+func settle(tb *Buffer) {
+	done := make(chan error, 1)
+	tb.bufmu.Lock()
+	closed := tb.closed
+	if !closed {
+		tb.enqueue(&batch{done: done})
+	}
+	tb.bufmu.Unlock()
+	if closed {
+		<-tb.done
+		return
+	}
+	tb.flushInline()
+	<-done
+}
+
+const (
+	opWrite = iota
+	opFlush
+	opTick
+	opSwap
+	opSteal
+	opClose
+	numOps
+)
+
+// model is the reference of the Buffer state machine, written is the data accepted and starts
+// the offset of the first byte of every sink
+type model struct {
+	t       *testing.T
+	tb      *Buffer
+	clock   *fakeClock
+	bufSize int
+	sync    bool
+
+	sinks   []*modelSink
+	starts  []int
+	written []byte
+	writes  int64
+	closed  bool
+}
+
+// runModel drives the Buffer with the operations encoded in data, the first byte selects the
+// options. The invariants are checked after every operation.
+func runModel(t *testing.T, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	cfg := data[0]
+	data = data[1:]
+	m := &model{t: t, clock: newFakeClock(), bufSize: 1 + int(cfg&0x0f), sync: cfg&0x40 != 0}
+	// the ticks are done by the model, without flush interval there is no ticker goroutine
+	opts := []BufferOption{SetBufferSize(m.bufSize), SetClock(m.clock)}
+	if cfg&0x10 != 0 {
+		opts = append(opts, SetSingleWriter(true))
+	}
+	if cfg&0x20 != 0 {
+		opts = append(opts, SetMaxFlushBytes(2*m.bufSize))
+	}
+	if m.sync {
+		opts = append(opts, SetSynchronousMode(true))
+	}
+	m.sinks = []*modelSink{{}}
+	m.starts = []int{0}
+	m.tb = NewBuffer(m.sinks[0], opts...)
+
+	for i := 0; len(data) > 0; i++ {
+		op := data[0] % numOps
+		data = data[1:]
+		var arg byte
+		if len(data) > 0 {
+			arg = data[0]
+		}
+		if op == opWrite && len(data) > 0 {
+			data = data[1:]
+		}
+		m.step(op, arg)
+		settle(m.tb)
+		m.check(i, op)
+	}
+	m.step(opClose, 0)
+	settle(m.tb)
+	m.check(-1, opClose)
+	if s := m.tb.Snapshot(); len(s) > 0 {
+		t.Fatalf("%v bytes not flushed on close", len(s))
+	}
+}
+
+func (m *model) step(op, arg byte) {
+	t, tb := m.t, m.tb
+	var err error
+	switch op {
+	case opWrite:
+		p := make([]byte, arg)
+		for i := range p {
+			p[i] = byte((len(m.written) + i) % 251)
+		}
+		_, err = tb.Write(p)
+		if err == nil {
+			m.written = append(m.written, p...)
+			m.writes++
+		}
+	case opFlush:
+		err = tb.Flush()
+		if err == nil {
+			if s := tb.Snapshot(); len(s) > 0 {
+				t.Fatalf("%v bytes pending after flush", len(s))
+			}
+		}
+	case opTick:
+		m.clock.Advance(time.Second)
+		if m.sync {
+			// the synchronous mode has no ticker
+			err = tb.Tick()
+		} else {
+			tb.tick()
+		}
+	case opSwap:
+		w := &modelSink{}
+		var old io.Writer
+		old, err = tb.SwapWriter(w)
+		if err == nil {
+			if old != m.sinks[len(m.sinks)-1] {
+				t.Fatalf("swap returned writer %p, expected: %p", old, m.sinks[len(m.sinks)-1])
+			}
+			m.sinks = append(m.sinks, w)
+			m.starts = append(m.starts, len(m.written))
+		}
+	case opSteal:
+		stolen := tb.Steal()
+		rest := len(m.written) - len(stolen)
+		if rest < 0 || !bytes.Equal(stolen, m.written[rest:]) {
+			t.Fatalf("stolen %v bytes don't match the last written", len(stolen))
+		}
+		m.written = m.written[:rest]
+		for i := range m.starts {
+			if m.starts[i] > rest {
+				m.starts[i] = rest
+			}
+		}
+	case opClose:
+		err = tb.Close()
+		if m.closed && err != nil {
+			t.Fatalf("second close: %v", err)
+		}
+		m.closed = true
+		err = nil
+	}
+	if m.closed && op != opClose && op != opTick && op != opSteal && err != ErrWriteOnClosed {
+		t.Fatalf("op %v on closed: %v, expected: %v", op, err, ErrWriteOnClosed)
+	} else if !m.closed && err != nil {
+		t.Fatalf("op %v: %v", op, err)
+	}
+}
+
+func (m *model) check(i int, op byte) {
+	t := m.t
+	var out []byte
+	var writes int64
+	for j, s := range m.sinks {
+		end := m.starts[j] + s.Len()
+		if end > len(m.written) || !bytes.Equal(s.Bytes(), m.written[m.starts[j]:end]) {
+			t.Fatalf("op %v (%v): sink %v data doesn't match", i, op, j)
+		}
+		if j < len(m.sinks)-1 && end != m.starts[j+1] {
+			t.Fatalf("op %v (%v): sink %v has %v bytes, expected: %v", i, op, j, s.Len(), m.starts[j+1]-m.starts[j])
+		}
+		out = append(out, s.Bytes()...)
+		writes += s.writes
+	}
+	pending := m.tb.Snapshot()
+	if len(pending) > m.bufSize {
+		t.Fatalf("op %v (%v): %v bytes pending once settled, buffer size: %v", i, op, len(pending), m.bufSize)
+	}
+	if !bytes.Equal(append(out, pending...), m.written) {
+		t.Fatalf("op %v (%v): flushed and pending data doesn't match the written", i, op)
+	}
+
+	s := m.tb.Stats()
+	if s.DirectBytes != int64(len(out)) || s.Flushes != writes || s.SinkWrites != writes || s.CallerWrites != m.writes || s.FlushErrors != 0 || s.Batches < s.Flushes {
+		t.Fatalf("op %v (%v): stats: %+v, flushed %v bytes in %v writes", i, op, s, len(out), writes)
+	}
+}
+
+// FuzzBuffer checks the Buffer against the model for the operations sequences in the corpus
+func FuzzBuffer(f *testing.F) {
+	f.Add([]byte{0x07, opWrite, 3, opWrite, 9, opTick, opFlush, opSwap, opWrite, 20, opClose})
+	f.Add([]byte{0x13, opWrite, 1, opWrite, 1, opSteal, opWrite, 16, opSwap, opTick, opFlush})
+	f.Add([]byte{0x25, opWrite, 4, opWrite, 4, opWrite, 4, opTick, opSteal, opClose, opWrite, 1})
+	f.Add([]byte{0x48, opWrite, 30, opSwap, opWrite, 2, opFlush, opSteal, opClose, opFlush})
+	f.Fuzz(runModel)
+}
+
+// TestBufferModel runs random operations sequences against the model
+func TestBufferModel(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		data := make([]byte, 1+rnd.Intn(64))
+		rnd.Read(data)
+		// mostly writes
+		for j := 1; j < len(data); j++ {
+			if rnd.Intn(2) == 0 {
+				data[j] = opWrite
+			}
+		}
+		runModel(t, data)
+		if t.Failed() {
+			t.Fatalf("failed sequence: %v", data)
+		}
+	}
+}
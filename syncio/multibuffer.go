@@ -0,0 +1,136 @@
+package syncio
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+)
+
+// SinkPolicy is the behavior of a MultiBuffer for a sink that can't take a write
+type SinkPolicy int
+
+const (
+	SinkBlock SinkPolicy = iota // the write waits for space in the sink Buffer
+	SinkDrop                    // the write is dropped for the sink when its Buffer is full
+	SinkSkip                    // as SinkBlock, and as SinkDrop while the last flush of the sink failed
+)
+
+var sinkPolicyNames = []string{"block", "drop", "skip"}
+
+func (p SinkPolicy) String() string {
+	return enumString(sinkPolicyNames, int(p))
+}
+
+func (p SinkPolicy) MarshalText() ([]byte, error) {
+	return enumMarshal(sinkPolicyNames, int(p), "SinkPolicy")
+}
+
+func (p *SinkPolicy) UnmarshalText(text []byte) error {
+	return enumUnmarshal(sinkPolicyNames, text, "SinkPolicy", (*int)(p))
+}
+
+// MultiSink is a sink of a MultiBuffer, Options are the options of its Buffer
+type MultiSink struct {
+	Name    string
+	Writer  io.Writer
+	Options []BufferOption
+	Policy  SinkPolicy
+}
+
+// MultiSinkStats are the counters of a sink of a MultiBuffer, Drops are the writes dropped
+// for it and DropBytes their bytes
+type MultiSinkStats struct {
+	Drops     int64
+	DropBytes int64
+}
+
+// MultiBuffer fans the writes out to several writers, each with its own Buffer, so a slow or
+// failing sink doesn't stall the others unless its policy is SinkBlock. The sinks are written
+// in order, a sink in synchronous mode flushes inline whatever its policy.
+type MultiBuffer struct {
+	sinks []multiSink
+}
+
+type multiSink struct {
+	name   string
+	buf    *Buffer
+	policy SinkPolicy
+	stats  MultiSinkStats
+}
+
+// dropContext is done, the writes with it fail instead of waiting for space
+var dropContext = func() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}()
+
+// NewMultiBuffer returns a MultiBuffer writing to the sinks
+func NewMultiBuffer(sinks ...MultiSink) *MultiBuffer {
+	m := &MultiBuffer{sinks: make([]multiSink, len(sinks))}
+	for i, s := range sinks {
+		m.sinks[i] = multiSink{name: s.Name, buf: NewBuffer(s.Writer, s.Options...), policy: s.Policy}
+	}
+	return m
+}
+
+// Write writes p to every sink, the errors are returned in a *MultiError by sink. It returns
+// len(p) when a sink took p, the drops aren't errors.
+func (m *MultiBuffer) Write(p []byte) (int, error) {
+	var errs MultiError
+	taken := false
+	for i := range m.sinks {
+		s := &m.sinks[i]
+		var err error
+		if s.policy == SinkDrop || s.policy == SinkSkip && s.buf.failing.Load() {
+			_, err = s.buf.writeCounted(dropContext, p, &s.buf.stats.CallerWrites)
+			if err == context.Canceled {
+				atomic.AddInt64(&s.stats.Drops, 1)
+				atomic.AddInt64(&s.stats.DropBytes, int64(len(p)))
+				continue
+			}
+		} else {
+			_, err = s.buf.Write(p)
+		}
+		if err == nil {
+			taken = true
+		}
+		errs.add(s.name, i, err)
+	}
+	if !taken && len(m.sinks) > 0 {
+		return 0, errs.err()
+	}
+	return len(p), errs.err()
+}
+
+// Buffer returns the Buffer of the sink i, in the order of NewMultiBuffer
+func (m *MultiBuffer) Buffer(i int) *Buffer {
+	return m.sinks[i].buf
+}
+
+// Stats returns the counters of the sink i
+func (m *MultiBuffer) Stats(i int) MultiSinkStats {
+	s := &m.sinks[i].stats
+	return MultiSinkStats{
+		Drops:     atomic.LoadInt64(&s.Drops),
+		DropBytes: atomic.LoadInt64(&s.DropBytes),
+	}
+}
+
+// Flush flushes every sink, the errors are returned in a *MultiError by sink
+func (m *MultiBuffer) Flush() error {
+	var errs MultiError
+	for i := range m.sinks {
+		errs.add(m.sinks[i].name, i, m.sinks[i].buf.Flush())
+	}
+	return errs.err()
+}
+
+// Close closes every sink, the errors are returned in a *MultiError by sink
+func (m *MultiBuffer) Close() error {
+	var errs MultiError
+	for i := range m.sinks {
+		errs.add(m.sinks[i].name, i, m.sinks[i].buf.Close())
+	}
+	return errs.err()
+}
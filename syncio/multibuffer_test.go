@@ -0,0 +1,104 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMultiBufferDrop(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	var fast lockedBuffer
+	m := NewMultiBuffer(
+		MultiSink{Name: "slow", Writer: bw, Options: []BufferOption{SetBufferSize(4), SetBufferPoolSize(1)}, Policy: SinkDrop},
+		MultiSink{Name: "fast", Writer: &fast, Options: []BufferOption{SetBufferSize(4)}},
+	)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if n, err := m.Write([]byte("abc")); n != 3 || err != nil {
+				t.Errorf("write: %v, %v, expected: 3, nil", n, err)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the slow sink stalled the writes")
+	}
+	if s := m.Stats(0); s.Drops == 0 || s.DropBytes != 3*s.Drops {
+		t.Errorf("slow sink stats: %+v, expected drops", s)
+	}
+	if s := m.Stats(1); s.Drops != 0 {
+		t.Errorf("fast sink drops: %v, expected: 0", s.Drops)
+	}
+
+	close(bw.release)
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fast.Len() != 300 {
+		t.Errorf("fast sink bytes: %v, expected: %v", fast.Len(), 300)
+	}
+	if n := int64(bw.out.Len()) + 3*m.Stats(0).Drops; n != 300 {
+		t.Errorf("slow sink bytes and drops: %v, expected: %v", n, 300)
+	}
+}
+
+func TestMultiBufferSkip(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int64
+	errSink := errors.New("sink down")
+	// the sink fails and then hangs
+	failing := writerFunc(func(p []byte) (int, error) {
+		if calls.Add(1) == 1 {
+			return 0, errSink
+		}
+		<-release
+		return len(p), nil
+	})
+	m := NewMultiBuffer(
+		MultiSink{Name: "failing", Writer: failing, Options: []BufferOption{SetBufferSize(4), SetBufferPoolSize(1)}, Policy: SinkSkip},
+		MultiSink{Name: "ok", Writer: &bytes.Buffer{}},
+	)
+	defer m.Close()
+
+	m.Write([]byte("abc"))
+	if err := m.Flush(); !errors.Is(err, errSink) {
+		t.Fatalf("flush: %v, expected: %v", err, errSink)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			m.Write([]byte("abc"))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the failing sink stalled the writes")
+	}
+	if s := m.Stats(0); s.Drops == 0 {
+		t.Errorf("failing sink stats: %+v, expected drops", s)
+	}
+	close(release)
+}
+
+func TestMultiBufferErrors(t *testing.T) {
+	m := NewMultiBuffer(MultiSink{Name: "a", Writer: &bytes.Buffer{}}, MultiSink{Name: "b", Writer: &bytes.Buffer{}})
+	m.Buffer(1).Close()
+	n, err := m.Write([]byte("abc"))
+	var merr *MultiError
+	if n != 3 || !errors.As(err, &merr) || len(merr.Errors) != 1 || merr.Errors[0].Name != "b" || !errors.Is(err, ErrWriteOnClosed) {
+		t.Errorf("write: %v, %v, expected: 3 and the error of b", n, err)
+	}
+	m.Buffer(0).Close()
+	if n, err := m.Write([]byte("abc")); n != 0 || err == nil {
+		t.Errorf("write to closed sinks: %v, %v, expected: 0 and an error", n, err)
+	}
+}
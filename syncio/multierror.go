@@ -0,0 +1,70 @@
+package syncio
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MultiError is the error of an operation on several Buffers, e.g. WriterCache.CloseAll,
+// with the error of every member that failed. errors.Is and errors.As match the member
+// errors, and errors.As with a *MemberError finds the first failed member.
+type MultiError struct {
+	Errors []*MemberError
+}
+
+// MemberError is the error of a member of a MultiError, Name is its key when it has one and
+// Index its position in the operation
+type MemberError struct {
+	Name  string
+	Index int
+	Err   error
+}
+
+func (e *MemberError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("%v: %v", e.Name, e.Err)
+	}
+	return fmt.Sprintf("#%v: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the error of the member
+func (e *MemberError) Unwrap() error {
+	return e.Err
+}
+
+func (e *MultiError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%v errors", len(e.Errors))
+	for _, m := range e.Errors {
+		sb.WriteString("; ")
+		sb.WriteString(m.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns the member errors, as errors.Join
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, m := range e.Errors {
+		errs[i] = m
+	}
+	return errs
+}
+
+// add appends the error of a member if it's not nil
+func (e *MultiError) add(name string, index int, err error) {
+	if err != nil {
+		e.Errors = append(e.Errors, &MemberError{Name: name, Index: index, Err: err})
+	}
+}
+
+// err returns e, or nil without member errors
+func (e *MultiError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,68 @@
+package syncio
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+func TestWriterCacheCloseAllErrors(t *testing.T) {
+	c := NewWriterCache(func(key string) (*Buffer, error) {
+		if key == "ok" {
+			return NewBuffer(&testWriter{}), nil
+		}
+		return NewBuffer(synctest.NewFailingWriter(nil, nil)), nil
+	}, 0, 0)
+	for _, key := range []string{"b", "ok", "a"} {
+		tb, err := c.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tb.Write([]byte(key))
+		c.Release(key)
+	}
+
+	err := c.CloseAll(context.Background())
+	var multi *MultiError
+	if !errors.As(err, &multi) || len(multi.Errors) != 2 {
+		t.Fatalf("close all: %v, expected the errors of a and b", err)
+	}
+	if multi.Errors[0].Name != "a" || multi.Errors[1].Name != "b" {
+		t.Errorf("member errors: %v, expected a and b", err)
+	}
+	var member *MemberError
+	if !errors.As(err, &member) || member.Name != "a" {
+		t.Errorf("first member error: %v, expected a", member)
+	}
+	var ferr *FlushError
+	if !errors.As(err, &ferr) || !errors.Is(err, synctest.ErrInjected) {
+		t.Errorf("close all: %v, expected to match the flush errors", err)
+	}
+	if s := err.Error(); !strings.HasPrefix(s, "2 errors; a: flush error") || !strings.Contains(s, "; b: flush error") {
+		t.Errorf("error message: %q", s)
+	}
+}
+
+func TestAutoCloseErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tb := NewBuffer(synctest.NewFailingWriter(nil, nil))
+	tb.Write([]byte("data"))
+	res := AutoClose(ctx, time.Second, NewBuffer(&testWriter{}), tb)
+	cancel()
+
+	err := <-res
+	var member *MemberError
+	if !errors.As(err, &member) || member.Index != 1 || member.Name != "" {
+		t.Fatalf("auto close: %v, expected the error of the second buffer", err)
+	}
+	if !errors.Is(err, synctest.ErrInjected) {
+		t.Errorf("auto close: %v, expected to match %v", err, synctest.ErrInjected)
+	}
+	if s := err.Error(); !strings.HasPrefix(s, "#1: flush error") {
+		t.Errorf("error message: %q", s)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !syncio_debug
+
+package syncio
+
+// accounting is a no-op without the syncio_debug build tag, see debug.go
+type accounting struct{}
+
+func (a *accounting) accept(n int)                 {}
+func (a *accounting) flushed(written, dropped int) {}
+func (a *accounting) dispatch(n int)               {}
+func (a *accounting) complete(n int)               {}
+func (a *accounting) remove(n int)                 {}
+func (a *accounting) check(tb *Buffer)             {}
@@ -0,0 +1,107 @@
+package syncio
+
+import (
+	"bytes"
+	"io"
+)
+
+// NormalizingWriter rewrites the line endings \r\n, \r and \n to a target ending before
+// writing to an underlying writer. A \r at the end of a write is retained until the
+// next write tells if it's followed by a \n, Close writes it if there is no next write.
+type NormalizingWriter struct {
+	w      io.Writer
+	target []byte
+	// cr is a pending \r from the previous write
+	cr  bool
+	buf []byte
+}
+
+var _ io.WriteCloser = &NormalizingWriter{}
+
+// NewlineNormalizer returns a writer that normalizes the line endings of the data written to w,
+// data that is already normalized is written without copies
+func NewlineNormalizer(w io.Writer, target []byte) *NormalizingWriter {
+	return &NormalizingWriter{w: w, target: target}
+}
+
+// Write normalizes p and writes it to the underlying writer, if the underlying writer fails
+// the number of bytes written is only accurate when p didn't need to be rewritten
+func (n *NormalizingWriter) Write(p []byte) (int, error) {
+	if n.normalized(p) {
+		return n.w.Write(p)
+	}
+
+	out := n.buf[:0]
+	for _, c := range p {
+		if n.cr {
+			n.cr = false
+			out = append(out, n.target...)
+			if c == '\n' {
+				continue
+			}
+		}
+		switch c {
+		case '\r':
+			n.cr = true
+		case '\n':
+			out = append(out, n.target...)
+		default:
+			out = append(out, c)
+		}
+	}
+	n.buf = out
+
+	if len(out) > 0 {
+		if _, err := n.w.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close writes the pending \r, if any, it doesn't close the underlying writer
+func (n *NormalizingWriter) Close() error {
+	if !n.cr {
+		return nil
+	}
+	n.cr = false
+	_, err := n.w.Write(n.target)
+	return err
+}
+
+// normalized reports if p would be written unmodified
+func (n *NormalizingWriter) normalized(p []byte) bool {
+	if n.cr {
+		return false
+	}
+	// common cases without a byte by byte scan
+	if bytes.IndexByte(p, '\r') < 0 {
+		return bytes.IndexByte(p, '\n') < 0 || (len(n.target) == 1 && n.target[0] == '\n')
+	}
+
+	for i := 0; i < len(p); {
+		switch p[i] {
+		case '\r':
+			if i+1 == len(p) {
+				// it would be retained
+				return false
+			}
+			if p[i+1] == '\n' {
+				if !bytes.Equal(n.target, []byte("\r\n")) {
+					return false
+				}
+				i += 2
+				continue
+			}
+			if !bytes.Equal(n.target, []byte("\r")) {
+				return false
+			}
+		case '\n':
+			if !bytes.Equal(n.target, []byte("\n")) {
+				return false
+			}
+		}
+		i++
+	}
+	return true
+}
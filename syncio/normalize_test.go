@@ -0,0 +1,68 @@
+package syncio
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// normalizeReference is a straightforward normalization of a complete stream
+func normalizeReference(p, target []byte) []byte {
+	p = bytes.ReplaceAll(p, []byte("\r\n"), []byte("\n"))
+	p = bytes.ReplaceAll(p, []byte("\r"), []byte("\n"))
+	return bytes.ReplaceAll(p, []byte("\n"), target)
+}
+
+func FuzzNewlineNormalizer(f *testing.F) {
+	f.Add([]byte("a\r\nb\rc\nd\r"), int64(1), uint8(0))
+	f.Add([]byte("\r\r\n\n\r"), int64(2), uint8(1))
+	f.Add([]byte("no endings"), int64(3), uint8(2))
+	targets := [][]byte{[]byte("\n"), []byte("\r\n"), []byte("\r"), []byte("<br>")}
+
+	f.Fuzz(func(t *testing.T, data []byte, seed int64, targetIdx uint8) {
+		target := targets[int(targetIdx)%len(targets)]
+		out := &bytes.Buffer{}
+		nw := NewlineNormalizer(out, target)
+
+		// random chunking
+		r := rand.New(rand.NewSource(seed))
+		for rest := data; len(rest) > 0; {
+			n := r.Intn(len(rest)) + 1
+			if _, err := nw.Write(rest[:n]); err != nil {
+				t.Fatal(err)
+			}
+			rest = rest[n:]
+		}
+		nw.Close()
+
+		if expected := normalizeReference(data, target); !bytes.Equal(out.Bytes(), expected) {
+			t.Errorf("normalized %q to %q, expected: %q", data, out.Bytes(), expected)
+		}
+	})
+}
+
+func TestNewlineNormalizerSplitCRLF(t *testing.T) {
+	out := &bytes.Buffer{}
+	nw := NewlineNormalizer(out, []byte("\n"))
+	nw.Write([]byte("a\r"))
+	nw.Write([]byte("\nb\r"))
+	nw.Write([]byte("c"))
+	if out.String() != "a\nb\nc" {
+		t.Errorf("normalized: %q, expected: %q", out.String(), "a\nb\nc")
+	}
+}
+
+func TestNewlineNormalizerAllocs(t *testing.T) {
+	lf := []byte("line one\nline two\n")
+	crlf := []byte("line one\r\nline two\r\n")
+	nlf := NewlineNormalizer(&testWriter{}, []byte("\n"))
+	ncrlf := NewlineNormalizer(&testWriter{}, []byte("\r\n"))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		nlf.Write(lf)
+		ncrlf.Write(crlf)
+	})
+	if allocs != 0 {
+		t.Errorf("allocations per normalized write: %v, expected 0", allocs)
+	}
+}
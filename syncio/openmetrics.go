@@ -0,0 +1,252 @@
+package syncio
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// omFamily is a metric family of WriteOpenMetrics, samples writes the samples of s
+type omFamily struct {
+	name, typ, help string
+	samples         func(e *omEncoder, name string, s *Stats)
+}
+
+func omCounter(name, help string, v func(s *Stats) float64) omFamily {
+	return omFamily{name, "counter", help, func(e *omEncoder, name string, s *Stats) {
+		e.sample(name+"_total", "", "", v(s))
+	}}
+}
+
+func omGauge(name, help string, v func(s *Stats) float64) omFamily {
+	return omFamily{name, "gauge", help, func(e *omEncoder, name string, s *Stats) {
+		e.sample(name, "", "", v(s))
+	}}
+}
+
+// omClasses is the counter of the classes of SetPriorityClasses, the classes never written
+// have no sample
+func omClasses(name, help string, v func(s *Stats) *[MaxPriorityClasses]int64) omFamily {
+	return omFamily{name, "counter", help, func(e *omEncoder, name string, s *Stats) {
+		for i, n := range v(s) {
+			if s.ClassBytes[i] > 0 {
+				e.sample(name+"_total", "class", strconv.Itoa(i), float64(n))
+			}
+		}
+	}}
+}
+
+// omRates is the gauge of a rate by window
+func omRates(name, help string, v func(s *Stats) [3]float64) omFamily {
+	return omFamily{name, "gauge", help, func(e *omEncoder, name string, s *Stats) {
+		for i, r := range v(s) {
+			e.sample(name, "window", [...]string{"1s", "10s", "60s"}[i], r)
+		}
+	}}
+}
+
+var omFamilies = []omFamily{
+	omCounter("buffer_allocs", "buffers allocated because the pool had no free buffers", func(s *Stats) float64 { return float64(s.BufferAllocs) }),
+	omCounter("flush_errors", "errors writing to the underlying writer", func(s *Stats) float64 { return float64(s.FlushErrors) }),
+	omGauge("buffer_size_bytes", "size of the buffers", func(s *Stats) float64 { return float64(s.BufferSize) }),
+	omCounter("resizes", "buffer size changes of SetAdaptiveSizing", func(s *Stats) float64 { return float64(s.Resizes) }),
+	omCounter("flushes", "flushes to the underlying writer or the parent Buffer", func(s *Stats) float64 { return float64(s.Flushes) }),
+	omCounter("batches", "batches flushed", func(s *Stats) float64 { return float64(s.Batches) }),
+	omCounter("records", "records flushed with SetRecordMode", func(s *Stats) float64 { return float64(s.Records) }),
+	omCounter("replayed_bytes", "bytes written with Replay", func(s *Stats) float64 { return float64(s.ReplayedBytes) }),
+	omGauge("retained_bytes", "size of the SetRetention window", func(s *Stats) float64 { return float64(s.RetainedBytes) }),
+	omCounter("sink_wait_seconds", "time waiting for the SetWriteSemaphore semaphore", func(s *Stats) float64 { return s.SinkWait.Seconds() }),
+	omCounter("budget_wait_seconds", "time waiting for memory of the SetBudget budget", func(s *Stats) float64 { return s.BudgetWait.Seconds() }),
+	omCounter("direct_bytes", "bytes flushed that were written with Write", func(s *Stats) float64 { return float64(s.DirectBytes) }),
+	omCounter("child_bytes", "bytes flushed that were handed over by child Buffers", func(s *Stats) float64 { return float64(s.ChildBytes) }),
+	omGauge("flush_interval_seconds", "flush interval", func(s *Stats) float64 { return s.FlushInterval.Seconds() }),
+	omCounter("panics", "panics of the underlying writer recovered", func(s *Stats) float64 { return float64(s.Panics) }),
+	omGauge("backlog_age_seconds", "age of the oldest unflushed byte", func(s *Stats) float64 { return s.BacklogAge.Seconds() }),
+	omCounter("backlog_drops", "writes discarded by SetMaxBacklogAge", func(s *Stats) float64 { return float64(s.BacklogDrops) }),
+	{"errors", "counter", "errors by category", func(e *omEncoder, name string, s *Stats) {
+		for i, n := range s.Errors {
+			e.sample(name+"_total", "category", errorCategoryNames[i], float64(n))
+		}
+	}},
+	// the buckets count the writes smaller than the bounds, the le of a bound is the size
+	// below it
+	{"write_size_bytes", "histogram", "sizes of the writes with SetWriteSizeHistogram", func(e *omEncoder, name string, s *Stats) {
+		var count int64
+		for i, n := range s.WriteSizes {
+			count += n
+			le := "+Inf"
+			if i < len(WriteSizeBounds) {
+				le = strconv.Itoa(WriteSizeBounds[i] - 1)
+			}
+			e.sample(name+"_bucket", "le", le, float64(count))
+		}
+	}},
+	omCounter("caller_writes", "Write calls that returned without error", func(s *Stats) float64 { return float64(s.CallerWrites) }),
+	omCounter("sink_writes", "calls to the underlying writer", func(s *Stats) float64 { return float64(s.SinkWrites) }),
+	omRates("flushed_bytes_per_second", "rate of the bytes flushed", func(s *Stats) [3]float64 {
+		return [3]float64{s.BytesPerSec1, s.BytesPerSec10, s.BytesPerSec60}
+	}),
+	omRates("writes_per_second", "rate of the Write calls", func(s *Stats) [3]float64 {
+		return [3]float64{s.WritesPerSec1, s.WritesPerSec10, s.WritesPerSec60}
+	}),
+	omCounter("discarded_payloads", "SetOnFlushError calls without the unwritten bytes", func(s *Stats) float64 { return float64(s.DiscardedPayloads) }),
+	omCounter("discarded_payload_bytes", "unwritten bytes not given to SetOnFlushError", func(s *Stats) float64 { return float64(s.DiscardedPayloadBytes) }),
+	omGauge("pending_bytes", "bytes accepted and not yet flushed", func(s *Stats) float64 { return float64(s.PendingBytes) }),
+	omClasses("class_bytes", "bytes written with WriteClass by priority class", func(s *Stats) *[MaxPriorityClasses]int64 { return &s.ClassBytes }),
+	omClasses("class_drop_bytes", "bytes discarded by the overflow of the priority classes", func(s *Stats) *[MaxPriorityClasses]int64 { return &s.ClassDrops }),
+	omCounter("retries", "flush attempts retried by the retry policy", func(s *Stats) float64 { return float64(s.Retries) }),
+	omCounter("overflow_drops", "writes and batches discarded by the overflow policy", func(s *Stats) float64 { return float64(s.OverflowDrops) }),
+	omCounter("overflow_drop_bytes", "bytes discarded by the overflow policy", func(s *Stats) float64 { return float64(s.OverflowDropBytes) }),
+	omCounter("spilled_bytes", "bytes of the batches spilled to disk", func(s *Stats) float64 { return float64(s.SpilledBytes) }),
+	omGauge("spill_bytes", "bytes spilled not yet read back", func(s *Stats) float64 { return float64(s.SpillBytes) }),
+	omCounter("spill_errors", "batches that couldn't be spilled or read back", func(s *Stats) float64 { return float64(s.SpillErrors) }),
+	omCounter("dedup_drops", "writes discarded by the dedup window", func(s *Stats) float64 { return float64(s.DedupDrops) }),
+	omCounter("dedup_drop_bytes", "bytes of the writes discarded by the dedup window", func(s *Stats) float64 { return float64(s.DedupDropBytes) }),
+	{"flush_latency_seconds", "histogram", "durations of the calls to the underlying writer", func(e *omEncoder, name string, s *Stats) {
+		var count int64
+		for i, n := range s.FlushLatencies {
+			count += n
+			le := "+Inf"
+			if i < len(FlushLatencyBounds) {
+				le = omValue(FlushLatencyBounds[i].Seconds())
+			}
+			e.sample(name+"_bucket", "le", le, float64(count))
+		}
+		e.sample(name+"_count", "", "", float64(count))
+		e.sample(name+"_sum", "", "", s.FlushLatencySum.Seconds())
+	}},
+	omGauge("flush_latency_min_seconds", "shortest call to the underlying writer", func(s *Stats) float64 { return s.FlushLatencyMin.Seconds() }),
+	omGauge("flush_latency_max_seconds", "longest call to the underlying writer", func(s *Stats) float64 { return s.FlushLatencyMax.Seconds() }),
+}
+
+// omEncoder writes the OpenMetrics text, labels are the labels of the current Stats
+type omEncoder struct {
+	out    bytes.Buffer
+	labels string
+}
+
+// WriteOpenMetrics writes s to w in the OpenMetrics text format with a single Write, the
+// metric names start with prefix, e.g. "syncio_", and the samples have the labels. The
+// histogram of the write sizes is only filled with SetWriteSizeHistogram.
+func (s Stats) WriteOpenMetrics(w io.Writer, prefix string, labels map[string]string) error {
+	return writeOpenMetrics(w, prefix, []map[string]string{labels}, []Stats{s})
+}
+
+// WriteOpenMetricsSet writes the stats of several Buffers as Stats.WriteOpenMetrics in a
+// single exposition, the samples of stats[i] have the labels labels[i]
+func WriteOpenMetricsSet(w io.Writer, prefix string, labels []map[string]string, stats []Stats) error {
+	if len(labels) != len(stats) {
+		panic("syncio: WriteOpenMetricsSet with different lengths of labels and stats")
+	}
+	return writeOpenMetrics(w, prefix, labels, stats)
+}
+
+// WriteOpenMetrics writes the stats of every open Buffer of the cache as Stats.WriteOpenMetrics,
+// the samples of a Buffer also have the label "buffer" with its key, replacing a "buffer"
+// label of labels
+func (c *WriterCache) WriteOpenMetrics(w io.Writer, prefix string, labels map[string]string) error {
+	c.mu.Lock()
+	entries := make([]*cacheEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	c.mu.Unlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	members := make([]map[string]string, len(entries))
+	stats := make([]Stats, len(entries))
+	for i, e := range entries {
+		members[i] = make(map[string]string, len(labels)+1)
+		for k, v := range labels {
+			members[i][k] = v
+		}
+		members[i]["buffer"] = e.key
+		stats[i] = e.buf.Stats()
+	}
+	return writeOpenMetrics(w, prefix, members, stats)
+}
+
+// writeOpenMetrics writes every family with the samples of every Stats with its labels
+func writeOpenMetrics(w io.Writer, prefix string, labels []map[string]string, stats []Stats) error {
+	e := &omEncoder{}
+	encoded := make([]string, len(labels))
+	for i, l := range labels {
+		encoded[i] = omLabels(l)
+	}
+	for _, f := range omFamilies {
+		name := omName(prefix + f.name)
+		e.out.WriteString("# TYPE " + name + " " + f.typ + "\n")
+		e.out.WriteString("# HELP " + name + " " + omEscape(f.help, false) + "\n")
+		for i := range stats {
+			e.labels = encoded[i]
+			f.samples(e, name, &stats[i])
+		}
+	}
+	e.out.WriteString("# EOF\n")
+	_, err := w.Write(e.out.Bytes())
+	return err
+}
+
+// sample writes a sample with the labels of the Stats and the label key, if not empty
+func (e *omEncoder) sample(name, key, value string, v float64) {
+	e.out.WriteString(name)
+	labels := e.labels
+	if key != "" {
+		if labels != "" {
+			labels += ","
+		}
+		labels += omName(key) + `="` + omEscape(value, true) + `"`
+	}
+	if labels != "" {
+		e.out.WriteString("{" + labels + "}")
+	}
+	e.out.WriteByte(' ')
+	e.out.WriteString(omValue(v))
+	e.out.WriteByte('\n')
+}
+
+// omLabels returns the labels sorted by name without the braces
+func omLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for i, k := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(omName(k) + `="` + omEscape(labels[k], true) + `"`)
+	}
+	return sb.String()
+}
+
+// omName replaces the characters not valid in a metric or label name by '_'
+func omName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == ':' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// omEscape escapes the backslashes and line feeds of a HELP text, and the double quotes of
+// a label value
+func omEscape(s string, quote bool) string {
+	r := []string{`\`, `\\`, "\n", `\n`}
+	if quote {
+		r = append(r, `"`, `\"`)
+	}
+	return strings.NewReplacer(r...).Replace(s)
+}
+
+func omValue(v float64) string {
+	if v == float64(int64(v)) {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
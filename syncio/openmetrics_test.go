@@ -0,0 +1,52 @@
+package syncio
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	s := Stats{
+		BufferAllocs:  2,
+		BufferSize:    4096,
+		Flushes:       10,
+		Batches:       12,
+		DirectBytes:   1 << 20,
+		SinkWait:      1500 * time.Millisecond,
+		FlushInterval: time.Second,
+		CallerWrites:  300,
+		SinkWrites:    11,
+		BytesPerSec1:  512.5,
+	}
+	s.Errors[ErrorSink] = 3
+	s.WriteSizes = WriteSizeHistogram{5, 0, 2, 0, 0, 0, 1}
+
+	var out bytes.Buffer
+	// the label values are escaped and the invalid name characters replaced
+	labels := map[string]string{"service": `say "hi"\` + "\n", "host-name": "a"}
+	if err := s.WriteOpenMetrics(&out, "syncio_", labels); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "openmetrics.golden", out.Bytes())
+}
+
+func TestCacheWriteOpenMetrics(t *testing.T) {
+	clock := newFakeClock()
+	c := NewWriterCache(func(key string) (*Buffer, error) {
+		return NewBuffer(&testWriter{}, SetBufferSize(64), SetClock(clock)), nil
+	}, 0, 0)
+	defer c.CloseAll(context.Background())
+	for _, key := range []string{"b", "a", "b"} {
+		if _, err := c.Write(key, []byte(key+"\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := c.WriteOpenMetrics(&out, "app_", map[string]string{"env": "test"}); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "openmetrics_cache.golden", out.Bytes())
+}
@@ -0,0 +1,265 @@
+package syncio
+
+import (
+	"fmt"
+	"time"
+)
+
+// OptionSpec describes a BufferOption for the tooling, e.g. to generate configuration
+// forms, see OptionCatalog
+type OptionSpec struct {
+	// Name is the name of the function returning the option, e.g. "SetBufferSize"
+	Name   string
+	Params []OptionParam
+	Doc    string
+}
+
+// OptionParam describes a parameter of a BufferOption
+type OptionParam struct {
+	Name string
+	// Type is the Go type of the parameter
+	Type string
+	// Default is the value without the option, nil when the option disables the feature
+	Default any
+	// Min and Max are the valid range of a number, nil when it's unbounded. Values are
+	// the valid text values of an enum.
+	Min    any
+	Max    any
+	Values []string
+}
+
+// OptionValue is the value of a BufferOption in a Buffer, see AppliedOptions
+type OptionValue struct {
+	Name string
+	// Values are the values of the parameters, the writers and interfaces are given by their
+	// type name and the callbacks by "func", they are nil when not set
+	Values []any
+}
+
+type optionEntry struct {
+	OptionSpec
+	// values returns the parameters of the option in tb, the caller must hold bufmu
+	values func(tb *Buffer) []any
+}
+
+func typeName(set bool, v any) any {
+	if !set {
+		return nil
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func funcName(set bool) any {
+	if !set {
+		return nil
+	}
+	return "func"
+}
+
+func boolParam(name string) []OptionParam {
+	return []OptionParam{{Name: name, Type: "bool", Default: false}}
+}
+
+func funcParam(name, typ string) []OptionParam {
+	return []OptionParam{{Name: name, Type: typ}}
+}
+
+var optionCatalog = []optionEntry{
+	{OptionSpec{"SetBufferSize", []OptionParam{{Name: "s", Type: "int", Default: 4096, Min: 1}}, "size of the buffers, a buffer is flushed when full"},
+		func(tb *Buffer) []any { return []any{tb.bufSize} }},
+	{OptionSpec{"SetBufferPoolSize", []OptionParam{{Name: "s", Type: "int", Default: 2, Min: 1}}, "number of full buffers that can wait to be written before the writes block"},
+		func(tb *Buffer) []any { return []any{tb.poolSize} }},
+	{OptionSpec{"SetFlushInterval", []OptionParam{{Name: "d", Type: "time.Duration", Default: time.Duration(0), Min: time.Duration(0)}}, "interval between flushes, 0 disables the ticks"},
+		func(tb *Buffer) []any { return []any{tb.flushInterval} }},
+	{OptionSpec{"SetSingleWriter", boolParam("single"), "Write path without locking for a single writing goroutine"},
+		func(tb *Buffer) []any { return []any{tb.singleWriter} }},
+	{OptionSpec{"SetOnFlushError", funcParam("fn", "func(err *FlushError, unwritten []byte)"), "callback of the flush errors"},
+		func(tb *Buffer) []any { return []any{funcName(tb.onFlushError != nil)} }},
+	{OptionSpec{"SetDeadLetter", funcParam("w", "io.Writer"), "writer of the data discarded"},
+		func(tb *Buffer) []any { return []any{typeName(tb.deadLetter != nil, tb.deadLetter)} }},
+	{OptionSpec{"SetWriteSemaphore", funcParam("sem", "*Semaphore"), "semaphore limiting the concurrent writes to the sinks"},
+		func(tb *Buffer) []any { return []any{typeName(tb.sem != nil, tb.sem)} }},
+	{OptionSpec{"SetClosePolicy", []OptionParam{{Name: "p", Type: "ClosePolicy", Default: CloseFlush, Values: closePolicyNames}}, "behavior of Close with data not flushed"},
+		func(tb *Buffer) []any { return []any{tb.closePolicy} }},
+	{OptionSpec{"SetPanicPolicy", []OptionParam{{Name: "p", Type: "PanicPolicy", Default: PanicRecover, Values: panicPolicyNames}}, "behavior when the underlying writer panics"},
+		func(tb *Buffer) []any { return []any{tb.panicPolicy} }},
+	{OptionSpec{"SetMaxFlushBytes", []OptionParam{{Name: "n", Type: "int", Default: 0, Min: 0}}, "maximum size of the queued batches merged in a single write, 0 disables it"},
+		func(tb *Buffer) []any { return []any{tb.maxFlushBytes} }},
+	{OptionSpec{"SetFlushContext", funcParam("fn", "func() context.Context"), "context of the writes to a ContextWriter"},
+		func(tb *Buffer) []any { return []any{funcName(tb.flushContext != nil)} }},
+	{OptionSpec{"SetSinkFlush", boolParam("enabled"), "flush the underlying writer after every batch"},
+		func(tb *Buffer) []any { return []any{tb.sinkFlush} }},
+	{OptionSpec{"SetFlushTransform", funcParam("fn", "func(dst, src []byte) ([]byte, error)"), "transformation of every batch before writing it"},
+		func(tb *Buffer) []any { return []any{funcName(tb.transform != nil)} }},
+	{OptionSpec{"SetCompression", funcParam("c", "Compressor"), "compression of every batch before writing it"},
+		func(tb *Buffer) []any { return []any{typeName(tb.compressor != nil, tb.compressor)} }},
+	{OptionSpec{"SetCodec", []OptionParam{{Name: "name", Type: "string", Default: ""}}, "name of the registered codec compressing every batch"},
+		func(tb *Buffer) []any { return []any{tb.codecName} }},
+	{OptionSpec{"SetStatsReporter", []OptionParam{{Name: "r", Type: "StatsReporter"}, {Name: "name", Type: "string"}, {Name: "interval", Type: "time.Duration", Min: time.Duration(0)}}, "periodic report of the stats"},
+		func(tb *Buffer) []any {
+			if tb.reporter == nil {
+				return []any{nil, nil, nil}
+			}
+			return []any{typeName(true, tb.reporter.r), tb.reporter.name, tb.reporter.interval}
+		}},
+	{OptionSpec{"SetSinkHeader", funcParam("fn", "func() []byte"), "header written first to every underlying writer"},
+		func(tb *Buffer) []any { return []any{funcName(tb.header != nil)} }},
+	{OptionSpec{"SetRetention", []OptionParam{{Name: "n", Type: "int64", Default: int64(0), Min: int64(0)}}, "size of the window of data written kept for ReaderAt"},
+		func(tb *Buffer) []any {
+			if tb.retention == nil {
+				return []any{int64(0)}
+			}
+			return []any{tb.retention.size}
+		}},
+	{OptionSpec{"SetUTF8Boundaries", boolParam("enabled"), "cut the size and tick flushes at rune boundaries"},
+		func(tb *Buffer) []any { return []any{tb.utf8Boundaries} }},
+	{OptionSpec{"SetLogger", funcParam("fn", "func(event string, fields map[string]any)"), "logger of the buffer events"},
+		func(tb *Buffer) []any { return []any{funcName(tb.logger != nil)} }},
+	{OptionSpec{"SetClock", []OptionParam{{Name: "c", Type: "Clock", Default: "syncio.systemClock"}}, "clock of the time based behaviors"},
+		func(tb *Buffer) []any { return []any{typeName(true, tb.clock)} }},
+	{OptionSpec{"SetAdaptiveSizing", []OptionParam{{Name: "min", Type: "int", Min: 1}, {Name: "max", Type: "int", Min: 1}}, "buffer size adjusted to the write rate"},
+		func(tb *Buffer) []any {
+			if tb.adaptive == nil {
+				return []any{nil, nil}
+			}
+			return []any{tb.adaptive.min, tb.adaptive.max}
+		}},
+	{OptionSpec{"SetAdaptiveInterval", []OptionParam{{Name: "min", Type: "time.Duration", Min: time.Millisecond}, {Name: "max", Type: "time.Duration", Min: time.Millisecond}}, "flush interval adjusted to the sink latency"},
+		func(tb *Buffer) []any {
+			if tb.adaptiveInterval == nil {
+				return []any{nil, nil}
+			}
+			return []any{tb.adaptiveInterval.min, tb.adaptiveInterval.max}
+		}},
+	{OptionSpec{"SetMaxBacklogAge", []OptionParam{{Name: "d", Type: "time.Duration", Min: time.Duration(0)}, {Name: "policy", Type: "OverflowPolicy", Values: overflowPolicyNames[:2]}}, "policy of the writes once the oldest unflushed byte is older than d"},
+		func(tb *Buffer) []any {
+			if tb.backlog == nil || tb.backlog.maxAge == scheduledAge {
+				return []any{nil, nil}
+			}
+			return []any{tb.backlog.maxAge, tb.backlog.policy}
+		}},
+	{OptionSpec{"SetWriteSizeHistogram", boolParam("enabled"), "histogram of the write sizes in Stats"},
+		func(tb *Buffer) []any { return []any{tb.writeSizes} }},
+	{OptionSpec{"SetRecordMode", boolParam("enabled"), "keep the boundaries of the writes"},
+		func(tb *Buffer) []any { return []any{tb.recordMode} }},
+	{OptionSpec{"SetSynchronousMode", boolParam("enabled"), "flush inline without goroutines"},
+		func(tb *Buffer) []any { return []any{tb.synchronous} }},
+	{OptionSpec{"SetManualTick", boolParam("enabled"), "ticks done by the caller with Tick, it enables SetSynchronousMode"},
+		func(tb *Buffer) []any { return []any{tb.manualTick} }},
+	{OptionSpec{"SetCloseOnLastProducer", boolParam("enabled"), "close the Buffer when the last PipeWriter is closed"},
+		func(tb *Buffer) []any { return []any{tb.closeOnLastProducer} }},
+	{OptionSpec{"SetFlushScheduler", funcParam("s", "*FlushScheduler"), "flushes dispatched by a scheduler shared by the Buffers instead of the ticks"},
+		func(tb *Buffer) []any { return []any{typeName(tb.scheduler != nil, tb.scheduler)} }},
+	{OptionSpec{"SetMaxSinkWriteSize", []OptionParam{{Name: "n", Type: "int", Default: 0, Min: 0}}, "maximum size of a write to the underlying writer, 0 disables it"},
+		func(tb *Buffer) []any { return []any{tb.maxSinkWrite} }},
+	{OptionSpec{"SetFlushAlignment", []OptionParam{{Name: "align", Type: "time.Duration", Default: time.Duration(0), Min: time.Duration(0)}}, "ticks aligned to the multiples of align of the wall clock, 0 disables it"},
+		func(tb *Buffer) []any { return []any{tb.flushAlignment} }},
+	{OptionSpec{"SetJournal", funcParam("w", "io.Writer"), "writer of the journal of the operations, see ReplayJournal"},
+		func(tb *Buffer) []any {
+			if tb.journal == nil {
+				return []any{nil}
+			}
+			return []any{typeName(true, tb.journal.w)}
+		}},
+	{OptionSpec{"SetMaxErrorQueueBytes", []OptionParam{{Name: "n", Type: "int64", Default: int64(0), Min: int64(0)}}, "maximum size of the unwritten bytes given to SetOnFlushError during an outage, 0 disables it"},
+		func(tb *Buffer) []any { return []any{tb.maxErrorQueue} }},
+	{OptionSpec{"SetLazySink", funcParam("fn", "func() (io.Writer, error)"), "factory of the underlying writer retried on the ticks until it's ready"},
+		func(tb *Buffer) []any { return []any{funcName(tb.lazySink != nil)} }},
+	{OptionSpec{"SetAllocator", []OptionParam{{Name: "alloc", Type: "func(size int) []byte"}, {Name: "free", Type: "func([]byte)"}}, "allocator of the buffers of the pool instead of the Go heap"},
+		func(tb *Buffer) []any { return []any{funcName(tb.alloc != nil), funcName(tb.free != nil)} }},
+	{OptionSpec{"SetFraming", []OptionParam{{Name: "flags", Type: "FrameFlags"}}, "batches written as frames read by BatchReader"},
+		func(tb *Buffer) []any {
+			if tb.framing == nil {
+				return []any{nil}
+			}
+			return []any{tb.framing.flags}
+		}},
+	{OptionSpec{"SetPriorityClasses", []OptionParam{{Name: "maxBytes", Type: "int64", Min: int64(1)}, {Name: "policies", Type: "...OverflowPolicy", Values: overflowPolicyNames}}, "classes of WriteClass sharing the batches, the lower ones evicted first"},
+		func(tb *Buffer) []any {
+			if tb.classes == nil {
+				return []any{nil, nil}
+			}
+			return []any{tb.classes.max, append([]OverflowPolicy(nil), tb.classes.policies...)}
+		}},
+	{OptionSpec{"SetHeadRetention", []OptionParam{{Name: "n", Type: "int", Default: 0, Min: 0}}, "first bytes written to the underlying writer kept for Head"},
+		func(tb *Buffer) []any {
+			if tb.head == nil {
+				return []any{0}
+			}
+			return []any{tb.head.size}
+		}},
+	{OptionSpec{"SetRetryPolicy", []OptionParam{{Name: "maxRetries", Type: "int", Default: 0, Min: 0}, {Name: "backoff", Type: "BackoffFunc"}}, "retries of the flushes that wrote nothing"},
+		func(tb *Buffer) []any {
+			if tb.retry == nil {
+				return []any{0, nil}
+			}
+			return []any{tb.retry.max, funcName(true)}
+		}},
+	{OptionSpec{"SetOverflowPolicy", []OptionParam{{Name: "policy", Type: "OverflowPolicy", Default: OverflowBlock, Values: overflowPolicyNames}}, "behavior of the writes when the queue is full"},
+		func(tb *Buffer) []any { return []any{tb.overflow} }},
+	{OptionSpec{"SetFlushPolicy", funcParam("p", "FlushPolicy"), "policy deciding the flushes instead of the ticks"},
+		func(tb *Buffer) []any {
+			if tb.flushPolicy == nil {
+				return []any{nil}
+			}
+			return []any{typeName(true, tb.flushPolicy.policy)}
+		}},
+	{OptionSpec{"SetMaxLatency", []OptionParam{{Name: "d", Type: "time.Duration", Default: time.Duration(0), Min: time.Duration(0)}}, "maximum age of the buffered data before it's flushed, instead of the ticks"},
+		func(tb *Buffer) []any { return []any{tb.maxLatency} }},
+	{OptionSpec{"SetFlushHooks", []OptionParam{{Name: "before", Type: "func(n int)"}, {Name: "after", Type: "func(n int, d time.Duration, err error)"}}, "callbacks around every write of a batch to the underlying writer"},
+		func(tb *Buffer) []any { return []any{funcName(tb.beforeFlush != nil), funcName(tb.afterFlush != nil)} }},
+	{OptionSpec{"SetSpillDir", []OptionParam{{Name: "dir", Type: "string", Default: ""}, {Name: "maxBytes", Type: "int64", Default: int64(0), Min: int64(0)}}, "directory of the batches spilled when the queue is full and maximum size of the spilled data, 0 disables it"},
+		func(tb *Buffer) []any {
+			if tb.spill == nil {
+				return []any{"", int64(0)}
+			}
+			return []any{tb.spill.dir, tb.spill.max}
+		}},
+	{OptionSpec{"SetFlushConcurrency", []OptionParam{{Name: "n", Type: "int", Default: 0, Min: 0}}, "number of batches written to the underlying writer at the same time"},
+		func(tb *Buffer) []any { return []any{tb.flushConcurrency} }},
+	{OptionSpec{"SetPreserveOrder", []OptionParam{{Name: "preserve", Type: "bool", Default: true}}, "order of the writes with SetFlushConcurrency"},
+		func(tb *Buffer) []any { return []any{!tb.unordered} }},
+	{OptionSpec{"SetDedupWindow", []OptionParam{{Name: "n", Type: "int", Default: 0, Min: 0}, {Name: "key", Type: "func(p []byte) string"}}, "number of the last writes whose keys discard the identical writes"},
+		func(tb *Buffer) []any {
+			if tb.dedup == nil {
+				return []any{0, funcName(false)}
+			}
+			return []any{len(tb.dedup.keys), funcName(tb.dedup.key != nil)}
+		}},
+	{OptionSpec{"SetDedupMaxAge", []OptionParam{{Name: "d", Type: "time.Duration", Default: time.Duration(0), Min: time.Duration(0)}}, "age of the keys forgotten by the dedup window, 0 for none"},
+		func(tb *Buffer) []any { return []any{tb.dedupMaxAge} }},
+	{OptionSpec{"SetIdleTimeout", []OptionParam{{Name: "d", Type: "time.Duration", Default: time.Duration(0), Min: time.Duration(0)}, {Name: "onIdle", Type: "func()"}}, "time without writes before the buffer is flushed and the free memory dropped, 0 disables it"},
+		func(tb *Buffer) []any { return []any{tb.idleTimeout, funcName(tb.onIdle != nil)} }},
+	{OptionSpec{"SetWriteTimeout", []OptionParam{{Name: "d", Type: "time.Duration", Default: time.Duration(0), Min: time.Duration(0)}}, "maximum duration of a write to the underlying writer, 0 disables it"},
+		func(tb *Buffer) []any { return []any{tb.writeTimeout} }},
+	{OptionSpec{"SetBudget", funcParam("b", "*Budget"), "budget capping the pool memory shared with other Buffers"},
+		func(tb *Buffer) []any { return []any{typeName(tb.budget != nil, tb.budget)} }},
+	{OptionSpec{"SetWriteAheadLog", funcParam("l", "WriteAheadLog"), "log persisting the writes until they are flushed, see the journal package"},
+		func(tb *Buffer) []any { return []any{typeName(tb.wal != nil, tb.wal)} }},
+}
+
+// OptionCatalog returns the description of every BufferOption
+func OptionCatalog() []OptionSpec {
+	specs := make([]OptionSpec, len(optionCatalog))
+	for i, e := range optionCatalog {
+		specs[i] = e.OptionSpec
+		specs[i].Params = append([]OptionParam(nil), e.Params...)
+		for j := range specs[i].Params {
+			// the enum names are shared with the enum methods
+			specs[i].Params[j].Values = append([]string(nil), e.Params[j].Values...)
+		}
+	}
+	return specs
+}
+
+// AppliedOptions returns the value of every BufferOption in the Buffer after defaulting,
+// in the OptionCatalog order
+func (tb *Buffer) AppliedOptions() []OptionValue {
+	tb.bufmu.Lock()
+	defer tb.bufmu.Unlock()
+	values := make([]OptionValue, len(optionCatalog))
+	for i, e := range optionCatalog {
+		values[i] = OptionValue{Name: e.Name, Values: e.values(tb)}
+	}
+	return values
+}
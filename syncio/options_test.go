@@ -0,0 +1,88 @@
+package syncio
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOptionCatalog checks that every function returning a BufferOption is in the catalog
+func TestOptionCatalog(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := map[string]int{}
+	for _, f := range pkgs["syncio"].Files {
+		for _, d := range f.Decls {
+			fn, ok := d.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Type.Results == nil || len(fn.Type.Results.List) != 1 {
+				continue
+			}
+			if id, ok := fn.Type.Results.List[0].Type.(*ast.Ident); ok && id.Name == "BufferOption" {
+				options[fn.Name.Name] = fn.Type.Params.NumFields()
+			}
+		}
+	}
+
+	catalog := OptionCatalog()
+	for _, spec := range catalog {
+		params, ok := options[spec.Name]
+		if !ok {
+			t.Errorf("%v: not a BufferOption", spec.Name)
+		} else if params != len(spec.Params) {
+			t.Errorf("%v: %v params, expected: %v", spec.Name, len(spec.Params), params)
+		}
+		delete(options, spec.Name)
+	}
+	for name := range options {
+		t.Errorf("%v: missing in the catalog", name)
+	}
+
+	catalog[0].Params[0].Default = 0
+	if OptionCatalog()[0].Params[0].Default != 4096 {
+		t.Error("catalog modified by the caller")
+	}
+}
+
+func TestAppliedOptions(t *testing.T) {
+	tb := NewBuffer(&testWriter{}, SetBufferSize(100), SetFlushInterval(time.Second), SetDeadLetter(&testWriter{}),
+		SetRecordMode(true), SetUTF8Boundaries(true), SetMaxBacklogAge(time.Minute, OverflowDropNewest))
+	defer tb.Close()
+
+	applied := tb.AppliedOptions()
+	values := map[string][]any{}
+	for i, v := range applied {
+		if v.Name != optionCatalog[i].Name || len(v.Values) != len(optionCatalog[i].Params) {
+			t.Errorf("applied option %v: %+v", i, v)
+		}
+		values[v.Name] = v.Values
+	}
+	expected := map[string][]any{
+		"SetBufferSize":     {100},
+		"SetBufferPoolSize": {2},
+		"SetFlushInterval":  {time.Second},
+		"SetDeadLetter":     {"*syncio.testWriter"},
+		"SetOnFlushError":   {nil},
+		"SetClosePolicy":    {CloseFlush},
+		"SetRecordMode":     {true},
+		// not enabled with SetRecordMode
+		"SetUTF8Boundaries": {false},
+		"SetMaxBacklogAge":  {time.Minute, OverflowDropNewest},
+		"SetAdaptiveSizing": {nil, nil},
+		"SetClock":          {"syncio.systemClock"},
+	}
+	for name, v := range expected {
+		if !reflect.DeepEqual(values[name], v) {
+			t.Errorf("%v: %v, expected: %v", name, values[name], v)
+		}
+	}
+}
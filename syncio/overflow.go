@@ -0,0 +1,50 @@
+package syncio
+
+import (
+	"sync/atomic"
+
+	"github.com/travelgateX/go-io/syncio/internal"
+)
+
+// SetOverflowPolicy sets the behavior of the writes when the queue holds SetBufferPoolSize
+// batches: OverflowBlock waits for space, the default, OverflowDropNewest discards the write
+// and OverflowDropOldest discards the oldest batch waiting to be written. The batch being
+// written and the batches of Flush, Sync, Seek and SwapWriter are never discarded, the write
+// waits when there is no other. The writes discarded are counted in Stats.OverflowDrops and the
+// bytes of both policies in OverflowDropBytes, and as ErrorDropped. Replay always waits, and
+// the small writes that fit in the active buffer without locking aren't affected.
+func SetOverflowPolicy(policy OverflowPolicy) BufferOption {
+	mustValid(overflowPolicyNames, int(policy), "OverflowPolicy")
+	return func(b *Buffer) {
+		b.overflow = policy
+	}
+}
+
+// dropNewest counts the write of n bytes discarded by OverflowDropNewest
+func (tb *Buffer) dropNewest(n int) {
+	atomic.AddInt64(&tb.stats.OverflowDrops, 1)
+	atomic.AddInt64(&tb.stats.OverflowDropBytes, int64(n))
+	tb.countError(ErrorDropped)
+}
+
+// dropOldest discards the oldest queued batch that can be discarded for OverflowDropOldest,
+// the caller must hold bufmu and put the returned buffer back once it's released. It returns
+// false if there is none.
+func (tb *Buffer) dropOldest() (*internal.Buffer, bool) {
+	for i, b := range tb.queue {
+		if b.done != nil || b.swap != nil || b.seek != nil || b.sync != nil || b.classes || b.len() == 0 {
+			continue
+		}
+		n := b.len()
+		tb.acct.remove(n)
+		tb.commitWAL(b.walOff, n)
+		copy(tb.queue[i:], tb.queue[i+1:])
+		tb.queue[len(tb.queue)-1] = nil
+		tb.queue = tb.queue[:len(tb.queue)-1]
+		atomic.AddInt64(&tb.stats.OverflowDrops, 1)
+		atomic.AddInt64(&tb.stats.OverflowDropBytes, int64(n))
+		tb.countError(ErrorDropped)
+		return b.buf, true
+	}
+	return nil, false
+}
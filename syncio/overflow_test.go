@@ -0,0 +1,83 @@
+package syncio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOverflowDropNewest(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(1), SetOverflowPolicy(OverflowDropNewest))
+
+	tb.Write([]byte("aaa")) // in flight once the next write fills the buffer
+	tb.Write([]byte("bbb"))
+	time.Sleep(10 * time.Millisecond)
+	tb.Write([]byte("ccc")) // queues bbb, the queue is full
+	if n, err := tb.Write([]byte("ddd")); n != 3 || err != nil {
+		t.Errorf("dropped write: %v, %v, expected: 3, nil", n, err)
+	}
+
+	close(bw.release)
+	tb.Close()
+	if bw.out.String() != "aaabbbccc" {
+		t.Errorf("written: %q, expected: %q", bw.out.String(), "aaabbbccc")
+	}
+	if s := tb.Stats(); s.OverflowDrops != 1 || s.OverflowDropBytes != 3 || s.Errors[ErrorDropped] != 1 {
+		t.Errorf("drops: %v, bytes: %v, errors: %v, expected 1, 3 and 1", s.OverflowDrops, s.OverflowDropBytes, s.Errors[ErrorDropped])
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(1), SetOverflowPolicy(OverflowDropOldest))
+
+	tb.Write([]byte("aaa"))
+	tb.Write([]byte("bbb"))
+	time.Sleep(10 * time.Millisecond)
+	tb.Write([]byte("ccc"))
+	tb.Write([]byte("ddd")) // discards bbb and queues ccc
+
+	close(bw.release)
+	tb.Close()
+	if bw.out.String() != "aaacccddd" {
+		t.Errorf("written: %q, expected: %q", bw.out.String(), "aaacccddd")
+	}
+	if s := tb.Stats(); s.OverflowDrops != 1 || s.OverflowDropBytes != 3 {
+		t.Errorf("drops: %v, bytes: %v, expected 1 and 3", s.OverflowDrops, s.OverflowDropBytes)
+	}
+}
+
+func TestOverflowDropOldestBarrier(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(1), SetOverflowPolicy(OverflowDropOldest))
+
+	tb.Write([]byte("aaa"))
+	tb.Write([]byte("bbb"))
+	time.Sleep(10 * time.Millisecond)
+	flushed := make(chan error, 1)
+	go func() {
+		flushed <- tb.Flush() // queues bbb with the barrier
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the barrier isn't discarded, the write bigger than the buffer waits
+	written := make(chan struct{})
+	go func() {
+		tb.Write([]byte("ccccc"))
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("the write didn't wait for the barrier")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(bw.release)
+	if err := <-flushed; err != nil {
+		t.Errorf("flush: %v", err)
+	}
+	<-written
+	tb.Close()
+	if bw.out.String() != "aaabbbccccc" {
+		t.Errorf("written: %q, expected: %q", bw.out.String(), "aaabbbccccc")
+	}
+}
@@ -0,0 +1,34 @@
+package syncio
+
+import "github.com/travelgateX/go-io/syncio/bufpool"
+
+// NewPair returns a Buffer and a Reader consuming its data, decoupling a fast producer from a
+// slow consumer in the same process: the Buffer writes into a Pipe and the Reader reads ahead
+// the flushed data, 2 blocks of the buffer size, with the memory of the buffer pool. Closing
+// the Buffer ends the Reader with io.EOF once the data is read, closing the Reader closes the
+// writes of the Buffer, they fail with ErrReaderClosed, and the flushes of the data not read
+// fail with io.ErrClosedPipe. The Buffer must still be closed.
+func NewPair(options ...BufferOption) (*Buffer, *Reader) {
+	pr, tb := Pipe(options...)
+	r := NewReader(pr, SetPrefetchBlockSize(tb.bufSize), setReaderPool(tb.pool.Mem()))
+	r.onClose = func() {
+		tb.readerClosed.Store(true)
+		tb.CloseWrites()
+	}
+	return tb, r
+}
+
+// setReaderPool makes the Reader take its blocks from p
+func setReaderPool(p *bufpool.Pool) ReaderOption {
+	return func(r *Reader) {
+		r.pool = p
+	}
+}
+
+// closedErr returns the error of the writes once closed
+func (tb *Buffer) closedErr() error {
+	if tb.readerClosed.Load() {
+		return ErrReaderClosed
+	}
+	return ErrWriteOnClosed
+}
@@ -0,0 +1,124 @@
+package syncio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPair(t *testing.T) {
+	tb, r := NewPair(SetBufferSize(16), SetBufferPoolSize(4))
+	defer r.Close()
+	var want bytes.Buffer
+	go func() {
+		for i := 0; i < 100; i++ {
+			p := []byte("record\n")
+			want.Write(p)
+			tb.Write(p)
+		}
+		tb.Close()
+	}()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("read %v bytes, expected: %v", len(got), want.Len())
+	}
+	if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("read after the end: %v, expected: %v", err, io.EOF)
+	}
+}
+
+func TestPairReaderClosed(t *testing.T) {
+	tb, r := NewPair(SetBufferSize(16))
+	defer tb.Close()
+	tb.Write([]byte("aaa"))
+	tb.Flush()
+	p := make([]byte, 3)
+	if _, err := io.ReadFull(r, p); err != nil || string(p) != "aaa" {
+		t.Fatalf("read: %q, %v", p, err)
+	}
+	r.Close()
+	if _, err := tb.Write([]byte("bbb")); err != ErrReaderClosed {
+		t.Errorf("write error: %v, expected: %v", err, ErrReaderClosed)
+	}
+	if _, err := tb.Write(bytes.Repeat([]byte("c"), 100)); err != ErrReaderClosed {
+		t.Errorf("write error: %v, expected: %v", err, ErrReaderClosed)
+	}
+	if s := tb.Stats(); s.Errors[ErrorClosed] != 2 {
+		t.Errorf("closed errors: %v, expected: 2", s.Errors[ErrorClosed])
+	}
+}
+
+// TestPairStress writes from several producers while the consumer is slower or faster, every
+// producer must be read complete and in order
+func TestPairStress(t *testing.T) {
+	speeds := map[string][2]time.Duration{
+		"slow consumer": {0, 50 * time.Microsecond},
+		"slow producer": {50 * time.Microsecond, 0},
+	}
+	for name, speed := range speeds {
+		t.Run(name, func(t *testing.T) {
+			const producers, records = 4, 300
+			tb, r := NewPair(SetBufferSize(64), SetBufferPoolSize(3), SetRecordMode(true), SetFlushInterval(time.Millisecond))
+			defer r.Close()
+			var wg sync.WaitGroup
+			for id := 0; id < producers; id++ {
+				wg.Add(1)
+				go func(id int) {
+					defer wg.Done()
+					for seq := 0; seq < records; seq++ {
+						var rec [8]byte
+						binary.BigEndian.PutUint32(rec[:4], uint32(id))
+						binary.BigEndian.PutUint32(rec[4:], uint32(seq))
+						if _, err := tb.Write(rec[:]); err != nil {
+							t.Error(err)
+							return
+						}
+						if speed[0] > 0 {
+							time.Sleep(speed[0])
+						}
+					}
+				}(id)
+			}
+			go func() {
+				wg.Wait()
+				tb.Close()
+			}()
+
+			next := make([]uint32, producers)
+			var rec [8]byte
+			for {
+				if _, err := io.ReadFull(r, rec[:]); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				id, seq := binary.BigEndian.Uint32(rec[:4]), binary.BigEndian.Uint32(rec[4:])
+				if id >= producers || seq != next[id] {
+					t.Fatalf("record %v of producer %v, expected: %v", seq, id, next[id%producers])
+				}
+				next[id]++
+				if speed[1] > 0 {
+					time.Sleep(speed[1])
+				}
+			}
+			for id, n := range next {
+				if n != records {
+					t.Errorf("producer %v: %v records read, expected: %v", id, n, records)
+				}
+			}
+			if err := r.Close(); err != nil {
+				t.Error(err)
+			}
+			if _, err := tb.Write([]byte("x")); !errors.Is(err, ErrReaderClosed) && !errors.Is(err, ErrWriteOnClosed) {
+				t.Errorf("write after close: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// panicWriter panics in the first writes
+type panicWriter struct {
+	bytes.Buffer
+	panics int
+}
+
+func (w *panicWriter) Write(p []byte) (int, error) {
+	if w.panics > 0 {
+		w.panics--
+		panic("sdk bug")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestPanicRecover(t *testing.T) {
+	w := &panicWriter{panics: 1}
+	dead := &bytes.Buffer{}
+	var ferr *FlushError
+	tb := NewBuffer(w, SetDeadLetter(dead), SetOnFlushError(func(err *FlushError, _ []byte) {
+		ferr = err
+	}))
+	tb.Write([]byte("first"))
+	err := tb.Flush()
+	var perr *PanicError
+	if !errors.As(err, &perr) || perr.Value != "sdk bug" || !bytes.Contains(perr.Stack, []byte("panicWriter")) {
+		t.Fatalf("flush error: %v, expected a *PanicError with the stack", err)
+	}
+	if ferr == nil || !ferr.LostData || ferr.Unwritten != 5 {
+		t.Errorf("reported flush error: %+v", ferr)
+	}
+
+	// the Buffer keeps working
+	tb.Write([]byte("second"))
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s := w.String(); s != "second" {
+		t.Errorf("written data: %q, expected: %q", s, "second")
+	}
+	if s := dead.String(); s != "first" {
+		t.Errorf("dead letter data: %q, expected: %q", s, "first")
+	}
+	if s := tb.Stats(); s.Panics != 1 || s.FlushErrors != 1 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+func TestPanicClose(t *testing.T) {
+	w := &panicWriter{panics: 1}
+	tb := NewBuffer(w, SetPanicPolicy(PanicClose))
+	tb.Write([]byte("first"))
+	if err := tb.Flush(); err == nil {
+		t.Fatal("expected the panic error")
+	}
+	if _, err := tb.Write([]byte("second")); err != ErrWriteOnClosed {
+		t.Errorf("write after the panic: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+	tb.Close()
+}
+
+func TestPanicRepanic(t *testing.T) {
+	tb := NewBuffer(&panicWriter{panics: 1}, SetPanicPolicy(PanicRepanic))
+	defer tb.Close()
+	defer func() {
+		if v := recover(); v != "sdk bug" {
+			t.Errorf("recovered: %v, expected the writer panic", v)
+		}
+	}()
+	// the flush goroutine would crash the test
+	tb.writeSink(&batch{}, []byte("p"))
+}
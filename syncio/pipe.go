@@ -0,0 +1,207 @@
+package syncio
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pipe returns a buffered pipe: the data written to the Buffer by any number of goroutines,
+// e.g. with PipeWriter handles, is read from the PipeReader in flush order. The writes don't
+// block while the buffer pool has space, see SetBufferPoolSize, and the reader receives the
+// flushed batches, so the data is readable once flushed by the ticks, SetMaxLatency, the full
+// buffers or Flush. Closing the Buffer ends the stream with io.EOF after the data is read,
+// closing the reader fails the flushes with io.ErrClosedPipe.
+func Pipe(options ...BufferOption) (*PipeReader, *Buffer) {
+	r := &PipeReader{
+		batches:  make(chan []byte),
+		consumed: make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	r.tb = NewBuffer(&pipeSink{r}, options...)
+	return r, r.tb
+}
+
+// PipeReader is the read side of Pipe, the reads are not safe for concurrent use, Stats and
+// Close are
+type PipeReader struct {
+	tb *Buffer
+	// batches are the batches flushed, the flush waits on consumed until the batch is read
+	batches   chan []byte
+	consumed  chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+	// cur is the unread data of the batch being read
+	cur []byte
+
+	stats PipeStats
+}
+
+// PipeStats are the counters of a PipeReader
+type PipeStats struct {
+	// Reads is the number of Read calls and Bytes the bytes read, WriteTo included
+	Reads int64
+	Bytes int64
+	// Depth is the size of the data written and not read yet
+	Depth int64
+	// ReadWait is the time the reader waited for the data, WriteWait the time the flushed
+	// batches waited for the reader
+	ReadWait  time.Duration
+	WriteWait time.Duration
+}
+
+// pipeSink is the underlying writer of the Buffer of a Pipe
+type pipeSink struct {
+	r *PipeReader
+}
+
+// Write hands p to the reader and waits until it's read
+func (s *pipeSink) Write(p []byte) (int, error) {
+	r := s.r
+	start := r.tb.clock.Now()
+	defer func() {
+		atomic.AddInt64((*int64)(&r.stats.WriteWait), int64(r.tb.clock.Now().Sub(start)))
+	}()
+	atomic.AddInt64(&r.stats.Depth, int64(len(p)))
+	select {
+	case r.batches <- p:
+	case <-r.closed:
+		atomic.AddInt64(&r.stats.Depth, -int64(len(p)))
+		return 0, io.ErrClosedPipe
+	}
+	select {
+	case <-r.consumed:
+		return len(p), nil
+	case <-r.closed:
+		return 0, io.ErrClosedPipe
+	}
+}
+
+// next waits for the next batch, it returns io.EOF once the Buffer is closed
+func (r *PipeReader) next() error {
+	select {
+	case r.cur = <-r.batches:
+		return nil
+	default:
+	}
+	start := r.tb.clock.Now()
+	defer func() {
+		atomic.AddInt64((*int64)(&r.stats.ReadWait), int64(r.tb.clock.Now().Sub(start)))
+	}()
+	select {
+	case r.cur = <-r.batches:
+		return nil
+	case <-r.tb.done:
+		return io.EOF
+	case <-r.closed:
+		return io.ErrClosedPipe
+	}
+}
+
+// advance consumes n bytes of the current batch, the flush ends once it's read
+func (r *PipeReader) advance(n int) {
+	r.cur = r.cur[n:]
+	atomic.AddInt64(&r.stats.Bytes, int64(n))
+	atomic.AddInt64(&r.stats.Depth, -int64(n))
+	if len(r.cur) == 0 {
+		r.cur = nil
+		select {
+		case r.consumed <- struct{}{}:
+		case <-r.closed:
+		}
+	}
+}
+
+// Read reads the flushed data, it returns io.EOF once the Buffer is closed and its data read
+func (r *PipeReader) Read(p []byte) (int, error) {
+	if r.batches == nil {
+		return 0, ErrNotInitialized
+	}
+	if r.isClosed() {
+		return 0, io.ErrClosedPipe
+	}
+	atomic.AddInt64(&r.stats.Reads, 1)
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if r.cur == nil {
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.cur)
+	r.advance(n)
+	return n, nil
+}
+
+// WriteTo writes the flushed batches to w without copying them until the Buffer is closed,
+// io.EOF isn't returned
+func (r *PipeReader) WriteTo(w io.Writer) (int64, error) {
+	if r.batches == nil {
+		return 0, ErrNotInitialized
+	}
+	var written int64
+	for {
+		if r.isClosed() {
+			return written, io.ErrClosedPipe
+		}
+		if r.cur == nil {
+			if err := r.next(); err == io.EOF {
+				return written, nil
+			} else if err != nil {
+				return written, err
+			}
+		}
+		n, err := w.Write(r.cur)
+		if n < 0 || n > len(r.cur) {
+			n = 0
+		}
+		written += int64(n)
+		r.advance(n)
+		if err == nil && r.cur != nil {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+func (r *PipeReader) isClosed() bool {
+	select {
+	case <-r.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close closes the read side, the reads fail with io.ErrClosedPipe and so do the flushes of
+// the Buffer, it doesn't close the Buffer
+func (r *PipeReader) Close() error {
+	if r.batches == nil {
+		return ErrNotInitialized
+	}
+	r.closeOnce.Do(func() { close(r.closed) })
+	return nil
+}
+
+// Stats returns a copy of the pipe counters, it doesn't allocate
+func (r *PipeReader) Stats() PipeStats {
+	var s PipeStats
+	r.StatsInto(&s)
+	return s
+}
+
+// StatsInto copies the pipe counters into s, Depth includes the data buffered by the Buffer
+func (r *PipeReader) StatsInto(s *PipeStats) {
+	s.Reads = atomic.LoadInt64(&r.stats.Reads)
+	s.Bytes = atomic.LoadInt64(&r.stats.Bytes)
+	s.Depth = atomic.LoadInt64(&r.stats.Depth)
+	if r.tb != nil {
+		s.Depth += r.tb.pendingSize()
+	}
+	s.ReadWait = time.Duration(atomic.LoadInt64((*int64)(&r.stats.ReadWait)))
+	s.WriteWait = time.Duration(atomic.LoadInt64((*int64)(&r.stats.WriteWait)))
+}
@@ -0,0 +1,156 @@
+package syncio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPipe(t *testing.T) {
+	const writers, records = 4, 200
+	r, tb := Pipe(SetBufferSize(64), SetBufferPoolSize(4), SetCloseOnLastProducer(true))
+	defer r.Close()
+	handles := make([]io.WriteCloser, writers)
+	for i := range handles {
+		handles[i] = tb.PipeWriter()
+	}
+	var wg sync.WaitGroup
+	for i, w := range handles {
+		wg.Add(1)
+		go func(i int, w io.WriteCloser) {
+			defer wg.Done()
+			defer w.Close()
+			for j := 0; j < records; j++ {
+				fmt.Fprintf(w, "%d:%03d\n", i, j)
+			}
+		}(i, w)
+	}
+	out, err := io.ReadAll(r)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the records of every writer are read in order
+	next := make([]int, writers)
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		var i, j int
+		if _, err := fmt.Sscanf(line, "%d:%d", &i, &j); err != nil {
+			t.Fatalf("record %q: %v", line, err)
+		}
+		if j != next[i] {
+			t.Fatalf("writer %v: record %v, expected %v", i, j, next[i])
+		}
+		next[i]++
+	}
+	for i, n := range next {
+		if n != records {
+			t.Errorf("writer %v: %v records, expected %v", i, n, records)
+		}
+	}
+	if s := r.Stats(); s.Bytes != int64(len(out)) || s.Depth != 0 {
+		t.Errorf("stats: %+v, expected %v bytes and depth 0", s, len(out))
+	}
+}
+
+func TestPipeWriteTo(t *testing.T) {
+	r, tb := Pipe(SetBufferSize(16))
+	defer r.Close()
+	res := make(chan string, 1)
+	go func() {
+		var out bytes.Buffer
+		if _, err := io.Copy(&out, r); err != nil {
+			t.Error(err)
+		}
+		res <- out.String()
+	}()
+	var want strings.Builder
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&want, "line %v\n", i)
+		fmt.Fprintf(tb, "line %v\n", i)
+		if i%10 == 0 {
+			tb.Flush()
+		}
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out := <-res; out != want.String() {
+		t.Errorf("read: %q, expected: %q", out, want.String())
+	}
+}
+
+func TestPipeStats(t *testing.T) {
+	r, tb := Pipe(SetBufferSize(16))
+	defer r.Close()
+	// the writes don't wait for the reader while the pool has space
+	tb.Write([]byte("0123456789"))
+	if s := r.Stats(); s.Depth != 10 {
+		t.Errorf("depth: %v, expected: 10", s.Depth)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		tb.Flush()
+	}()
+	p := make([]byte, 4)
+	if n, err := r.Read(p); n != 4 || err != nil || string(p) != "0123" {
+		t.Fatalf("read: %v, %v, %q", n, err, p[:n])
+	}
+	s := r.Stats()
+	if s.Depth != 6 || s.Reads != 1 || s.Bytes != 4 {
+		t.Errorf("stats: %+v, expected depth 6 after a read of 4 bytes", s)
+	}
+	if s.ReadWait < 10*time.Millisecond {
+		t.Errorf("read wait: %v, expected 10ms at least", s.ReadWait)
+	}
+	io.ReadFull(r, p[:3])
+	io.ReadFull(r, p[:3])
+	if s := r.Stats(); s.Depth != 0 {
+		t.Errorf("depth: %v, expected: 0", s.Depth)
+	}
+}
+
+func TestPipeClose(t *testing.T) {
+	r, tb := Pipe()
+	tb.Write([]byte("abc"))
+	r.Close()
+	if err := tb.Flush(); err == nil {
+		t.Error("flush to a closed reader: nil, expected error")
+	}
+	if _, err := r.Read(make([]byte, 1)); err != io.ErrClosedPipe {
+		t.Errorf("read after close: %v, expected: %v", err, io.ErrClosedPipe)
+	}
+	tb.Close()
+
+	// the reader gets io.EOF once the Buffer is closed
+	r, tb = Pipe()
+	defer r.Close()
+	res := make(chan error, 1)
+	go func() {
+		_, err := r.Read(make([]byte, 1))
+		res <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	tb.Close()
+	select {
+	case err := <-res:
+		if err != io.EOF {
+			t.Errorf("read: %v, expected: %v", err, io.EOF)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("read not released by Close")
+	}
+}
+
+func TestPipeZeroValue(t *testing.T) {
+	var r PipeReader
+	if _, err := r.Read(make([]byte, 1)); err != ErrNotInitialized {
+		t.Errorf("read: %v, expected: %v", err, ErrNotInitialized)
+	}
+	if err := r.Close(); err != ErrNotInitialized {
+		t.Errorf("close: %v, expected: %v", err, ErrNotInitialized)
+	}
+}
@@ -0,0 +1,151 @@
+package syncio
+
+import "fmt"
+
+// The policy types and FlushTrigger implement encoding.TextMarshaler and encoding.TextUnmarshaler
+// with the names returned by String, so they can be used in JSON and configuration files.
+// The options panic with unknown values.
+
+// FlushTrigger is the reason why a batch was flushed to the underlying writer
+type FlushTrigger int
+
+const (
+	TriggerSize   FlushTrigger = iota // the buffer was full or the write didn't fit in a buffer
+	TriggerTick                       // the flush interval ticked
+	TriggerManual                     // Flush was called
+	TriggerClose                      // the Buffer was closed
+	TriggerPolicy                     // the FlushPolicy decided it
+	TriggerIdle                       // no write arrived within the idle timeout
+)
+
+var flushTriggerNames = []string{"size", "tick", "manual", "close", "policy", "idle"}
+
+func (t FlushTrigger) String() string {
+	return enumString(flushTriggerNames, int(t))
+}
+
+func (t FlushTrigger) MarshalText() ([]byte, error) {
+	return enumMarshal(flushTriggerNames, int(t), "FlushTrigger")
+}
+
+func (t *FlushTrigger) UnmarshalText(text []byte) error {
+	return enumUnmarshal(flushTriggerNames, text, "FlushTrigger", (*int)(t))
+}
+
+// OverflowPolicy is the behavior of a writer when its queue is full
+type OverflowPolicy int
+
+const (
+	OverflowBlock      OverflowPolicy = iota // the write waits for space
+	OverflowDropNewest                       // the incoming write is discarded
+	OverflowDropOldest                       // the oldest queued write is discarded to make space
+)
+
+var overflowPolicyNames = []string{"block", "drop_newest", "drop_oldest"}
+
+func (p OverflowPolicy) String() string {
+	return enumString(overflowPolicyNames, int(p))
+}
+
+func (p OverflowPolicy) MarshalText() ([]byte, error) {
+	return enumMarshal(overflowPolicyNames, int(p), "OverflowPolicy")
+}
+
+func (p *OverflowPolicy) UnmarshalText(text []byte) error {
+	return enumUnmarshal(overflowPolicyNames, text, "OverflowPolicy", (*int)(p))
+}
+
+// ClosePolicy is the behavior of Close with the data not yet written
+type ClosePolicy int
+
+const (
+	CloseFlush   ClosePolicy = iota // Close waits until the data is flushed
+	CloseAbandon                    // Close discards the batches not yet written, like an expired CloseTimeout
+)
+
+var closePolicyNames = []string{"flush", "abandon"}
+
+func (p ClosePolicy) String() string {
+	return enumString(closePolicyNames, int(p))
+}
+
+func (p ClosePolicy) MarshalText() ([]byte, error) {
+	return enumMarshal(closePolicyNames, int(p), "ClosePolicy")
+}
+
+func (p *ClosePolicy) UnmarshalText(text []byte) error {
+	return enumUnmarshal(closePolicyNames, text, "ClosePolicy", (*int)(p))
+}
+
+// SetClosePolicy sets the behavior of Close, CloseFlush by default. With CloseAbandon the batches
+// not yet written are sent to the dead letter writer and Close returns a *CloseTimeoutError.
+func SetClosePolicy(p ClosePolicy) BufferOption {
+	mustValid(closePolicyNames, int(p), "ClosePolicy")
+	return func(b *Buffer) {
+		b.closePolicy = p
+	}
+}
+
+// PanicPolicy is the behavior of the Buffer when the underlying writer panics
+type PanicPolicy int
+
+const (
+	PanicRecover PanicPolicy = iota // the panic is reported as a flush error and the flushes continue
+	PanicClose                      // the panic is reported as a flush error and the Buffer is closed
+	PanicRepanic                    // the panic isn't recovered
+)
+
+var panicPolicyNames = []string{"recover", "close", "repanic"}
+
+func (p PanicPolicy) String() string {
+	return enumString(panicPolicyNames, int(p))
+}
+
+func (p PanicPolicy) MarshalText() ([]byte, error) {
+	return enumMarshal(panicPolicyNames, int(p), "PanicPolicy")
+}
+
+func (p *PanicPolicy) UnmarshalText(text []byte) error {
+	return enumUnmarshal(panicPolicyNames, text, "PanicPolicy", (*int)(p))
+}
+
+// SetPanicPolicy sets the behavior when the underlying writer panics, PanicRecover by default.
+// A recovered panic is a flush error wrapping a *PanicError, the batch is sent to the dead
+// letter writer as with any flush error. With PanicClose the Buffer is closed as by Close,
+// the queued data is still flushed.
+func SetPanicPolicy(p PanicPolicy) BufferOption {
+	mustValid(panicPolicyNames, int(p), "PanicPolicy")
+	return func(b *Buffer) {
+		b.panicPolicy = p
+	}
+}
+
+func enumString(names []string, v int) string {
+	if v < 0 || v >= len(names) {
+		return "undefined"
+	}
+	return names[v]
+}
+
+func enumMarshal(names []string, v int, kind string) ([]byte, error) {
+	if v < 0 || v >= len(names) {
+		return nil, fmt.Errorf("syncio: unknown %v %d", kind, v)
+	}
+	return []byte(names[v]), nil
+}
+
+func enumUnmarshal(names []string, text []byte, kind string, v *int) error {
+	for i, n := range names {
+		if n == string(text) {
+			*v = i
+			return nil
+		}
+	}
+	return fmt.Errorf("syncio: unknown %v %q", kind, text)
+}
+
+func mustValid(names []string, v int, kind string) {
+	if v < 0 || v >= len(names) {
+		panic(fmt.Sprintf("syncio: unknown %v %d", kind, v))
+	}
+}
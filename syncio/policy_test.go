@@ -0,0 +1,126 @@
+package syncio
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type enum interface {
+	encoding.TextMarshaler
+	String() string
+}
+
+func TestEnumRoundTrip(t *testing.T) {
+	tests := []struct {
+		values []enum
+		new    func() encoding.TextUnmarshaler
+	}{
+		{[]enum{TriggerSize, TriggerTick, TriggerManual, TriggerClose, TriggerPolicy, TriggerIdle}, func() encoding.TextUnmarshaler { return new(FlushTrigger) }},
+		{[]enum{OverflowBlock, OverflowDropNewest, OverflowDropOldest}, func() encoding.TextUnmarshaler { return new(OverflowPolicy) }},
+		{[]enum{CloseFlush, CloseAbandon}, func() encoding.TextUnmarshaler { return new(ClosePolicy) }},
+		{[]enum{PanicRecover, PanicClose, PanicRepanic}, func() encoding.TextUnmarshaler { return new(PanicPolicy) }},
+		{[]enum{ErrorSink, ErrorRetryExhausted, ErrorDropped, ErrorDeadLettered, ErrorTimeout, ErrorClosed}, func() encoding.TextUnmarshaler { return new(ErrorCategory) }},
+		{[]enum{RouteA, RouteB}, func() encoding.TextUnmarshaler { return new(Route) }},
+		{[]enum{SinkBlock, SinkDrop, SinkSkip}, func() encoding.TextUnmarshaler { return new(SinkPolicy) }},
+		{[]enum{LagBlock, LagDetach}, func() encoding.TextUnmarshaler { return new(LagPolicy) }},
+	}
+	for _, tt := range tests {
+		names := map[string]bool{}
+		for _, v := range tt.values {
+			text, err := v.MarshalText()
+			if err != nil {
+				t.Fatalf("%v: marshal: %v", v, err)
+			}
+			if string(text) != v.String() || names[v.String()] {
+				t.Errorf("%v: text %q not the unique String", v, text)
+			}
+			names[v.String()] = true
+
+			u := tt.new()
+			if err := u.UnmarshalText(text); err != nil {
+				t.Fatalf("%v: unmarshal: %v", v, err)
+			}
+			if u.(enum).String() != v.String() {
+				t.Errorf("%v: round trip: %v", v, u)
+			}
+		}
+
+		u := tt.new()
+		if err := u.UnmarshalText([]byte("unknown")); err == nil {
+			t.Errorf("%T: unknown name accepted", u)
+		}
+	}
+
+	for _, v := range []enum{FlushTrigger(-1), FlushTrigger(6), OverflowPolicy(3), ClosePolicy(2), PanicPolicy(3), Route(2), SinkPolicy(3), LagPolicy(2)} {
+		if v.String() != "undefined" {
+			t.Errorf("%T(%v) String: %v, expected: undefined", v, v, v.String())
+		}
+		if _, err := v.MarshalText(); err == nil {
+			t.Errorf("%T: unknown value marshaled", v)
+		}
+	}
+}
+
+func TestEnumJSON(t *testing.T) {
+	type config struct {
+		Overflow OverflowPolicy `json:"overflow"`
+		Close    ClosePolicy    `json:"close"`
+	}
+	c := config{Overflow: OverflowDropOldest, Close: CloseAbandon}
+	p, err := json.Marshal(c)
+	if err != nil || string(p) != `{"overflow":"drop_oldest","close":"abandon"}` {
+		t.Fatalf("marshal: %s, %v", p, err)
+	}
+	var got config
+	if err := json.Unmarshal(p, &got); err != nil || got != c {
+		t.Errorf("unmarshal: %+v, %v, expected: %+v", got, err, c)
+	}
+	if err := json.Unmarshal([]byte(`{"overflow":"drop_all"}`), &got); err == nil {
+		t.Errorf("unknown policy accepted")
+	}
+}
+
+func TestInvalidPolicyOptions(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"SetClosePolicy": func() { SetClosePolicy(ClosePolicy(7)) },
+		"SetPanicPolicy": func() { SetPanicPolicy(PanicPolicy(-1)) },
+		"QueueWriter":    func() { QueueWriter(&testWriter{}, 1, OverflowPolicy(7)) },
+		"NewTee":         func() { NewTee(bytes.NewReader(nil), 0, TeeConsumer{Policy: LagPolicy(5)}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%v: unknown policy accepted", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestClosePolicyAbandon(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	var dl bytes.Buffer
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(4), SetDeadLetter(&dl), SetClosePolicy(CloseAbandon))
+	tb.Write([]byte("aaa")) // in flight once the next write fills the buffer
+	tb.Write([]byte("bbb"))
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan error)
+	go func() {
+		closed <- tb.Close()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	// Close waits for the batch in flight
+	close(bw.release)
+	err := <-closed
+	if cerr, ok := err.(*CloseTimeoutError); !ok || cerr.Unflushed != 3 {
+		t.Errorf("close: %v, expected 3 bytes abandoned", err)
+	}
+	if bw.out.String() != "aaa" || dl.String() != "bbb" {
+		t.Errorf("written: %q, dead letter: %q, expected: %q, %q", bw.out.String(), dl.String(), "aaa", "bbb")
+	}
+}
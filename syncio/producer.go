@@ -0,0 +1,57 @@
+package syncio
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// SetCloseOnLastProducer closes the Buffer when the last open PipeWriter is closed, the
+// Buffer is never closed before the first PipeWriter
+func SetCloseOnLastProducer(enabled bool) BufferOption {
+	return func(b *Buffer) {
+		b.closeOnLastProducer = enabled
+	}
+}
+
+// producer is a PipeWriter handle
+type producer struct {
+	tb     *Buffer
+	closed atomic.Bool
+}
+
+// PipeWriter returns a writer of a producer sharing the Buffer with others, its Close flushes
+// the buffered data as Flush and marks the producer as finished without closing the Buffer,
+// unless SetCloseOnLastProducer is set and it's the last one open. Its writes fail with
+// io.ErrClosedPipe after Close. Producers reports the open handles.
+func (tb *Buffer) PipeWriter() io.WriteCloser {
+	tb.producers.Add(1)
+	return &producer{tb: tb}
+}
+
+// Producers returns the number of PipeWriter handles not closed
+func (tb *Buffer) Producers() int {
+	return int(tb.producers.Load())
+}
+
+func (p *producer) Write(b []byte) (int, error) {
+	if p.closed.Load() {
+		return 0, io.ErrClosedPipe
+	}
+	return p.tb.Write(b)
+}
+
+// Close flushes the data of the producer, it returns the Flush error or, when it closes
+// the Buffer, the Close error. The data is already flushed if the Buffer was closed.
+func (p *producer) Close() error {
+	if p.closed.Swap(true) {
+		return nil
+	}
+	tb := p.tb
+	if tb.producers.Add(-1) == 0 && tb.closeOnLastProducer {
+		return tb.Close()
+	}
+	if err := tb.Flush(); err != ErrWriteOnClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package syncio
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestPipeWriter(t *testing.T) {
+	var out bytes.Buffer
+	tb := NewBuffer(&out, SetBufferSize(64))
+	defer tb.Close()
+
+	a, b := tb.PipeWriter(), tb.PipeWriter()
+	a.Write([]byte("a"))
+	b.Write([]byte("b"))
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the data of the closed producer is flushed, the Buffer stays open
+	if s := out.String(); s != "ab" {
+		t.Errorf("written: %q, expected: %q", s, "ab")
+	}
+	if _, err := a.Write([]byte("a")); err != io.ErrClosedPipe {
+		t.Errorf("write on closed producer: %v, expected: %v", err, io.ErrClosedPipe)
+	}
+	if err := a.Close(); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+	if n := tb.Producers(); n != 1 {
+		t.Errorf("producers: %v, expected: 1", n)
+	}
+	b.Close()
+	if n := tb.Producers(); n != 0 {
+		t.Errorf("producers: %v, expected: 0", n)
+	}
+	if _, err := tb.Write([]byte("c")); err != nil {
+		t.Errorf("write after the producers closed: %v", err)
+	}
+}
+
+func TestCloseOnLastProducer(t *testing.T) {
+	tw := &testWriter{}
+	tb := NewBuffer(tw, SetBufferSize(64), SetCloseOnLastProducer(true))
+
+	producers, lines := 4, 100
+	wg := sync.WaitGroup{}
+	for i := 0; i < producers; i++ {
+		w := tb.PipeWriter()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				w.Write([]byte(strconv.Itoa(i) + "\n"))
+			}
+			if err := w.Close(); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	<-tb.done
+	if _, err := tb.Write([]byte("x")); err != ErrWriteOnClosed {
+		t.Errorf("write after the last producer: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+	if tw.bytes != int64(producers*lines*2) {
+		t.Errorf("written: %v bytes, expected: %v", tw.bytes, producers*lines*2)
+	}
+}
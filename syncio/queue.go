@@ -0,0 +1,158 @@
+package syncio
+
+import (
+	"io"
+	"sync"
+)
+
+// QueuedWriter sends every write to a background goroutine through a bounded queue, the
+// writes are done in order to the underlying writer without being merged. When the queue is
+// full the OverflowPolicy is applied. Write errors are counted in its stats and the first one
+// is returned by Close.
+type QueuedWriter struct {
+	w        io.Writer
+	capacity int
+	policy   OverflowPolicy
+
+	mu     sync.Mutex
+	space  *sync.Cond
+	ready  *sync.Cond
+	queue  [][]byte
+	closed bool
+	err    error
+	done   chan struct{}
+
+	stats QueueStats
+}
+
+// QueueStats are the counters of a QueuedWriter
+type QueueStats struct {
+	// Depth is the number of writes in the queue and HighWater the maximum depth reached
+	Depth     int
+	HighWater int
+	// Writes is the number of writes done to the underlying writer
+	Writes int64
+	// Errors is the number of writes to the underlying writer that failed
+	Errors int64
+	// Dropped and DroppedBytes are the writes discarded by the overflow policy
+	Dropped      int64
+	DroppedBytes int64
+}
+
+var _ io.WriteCloser = &QueuedWriter{}
+
+// QueueWriter returns a writer that queues up to capacity writes for w, it panics with an
+// unknown policy
+func QueueWriter(w io.Writer, capacity int, policy OverflowPolicy) *QueuedWriter {
+	mustValid(overflowPolicyNames, int(policy), "OverflowPolicy")
+	if capacity < 1 {
+		capacity = 1
+	}
+	q := &QueuedWriter{
+		w:        w,
+		capacity: capacity,
+		policy:   policy,
+		queue:    make([][]byte, 0, capacity),
+		done:     make(chan struct{}),
+	}
+	q.space = sync.NewCond(&q.mu)
+	q.ready = sync.NewCond(&q.mu)
+	go q.run()
+	return q
+}
+
+// Write enqueues a copy of p
+func (q *QueuedWriter) Write(p []byte) (int, error) {
+	c := make([]byte, len(p))
+	copy(c, p)
+	return q.WriteOwned(c)
+}
+
+// WriteOwned enqueues p without copying it, p must not be modified after the call
+func (q *QueuedWriter) WriteOwned(p []byte) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for !q.closed && len(q.queue) >= q.capacity {
+		switch q.policy {
+		case OverflowDropNewest:
+			q.stats.Dropped++
+			q.stats.DroppedBytes += int64(len(p))
+			return len(p), nil
+		case OverflowDropOldest:
+			q.stats.Dropped++
+			q.stats.DroppedBytes += int64(len(q.queue[0]))
+			q.queue[0] = nil
+			q.queue = q.queue[1:]
+		default:
+			q.space.Wait()
+		}
+	}
+	if q.closed {
+		return 0, ErrWriteOnClosed
+	}
+
+	q.queue = append(q.queue, p)
+	if len(q.queue) > q.stats.HighWater {
+		q.stats.HighWater = len(q.queue)
+	}
+	q.ready.Signal()
+	return len(p), nil
+}
+
+func (q *QueuedWriter) run() {
+	defer close(q.done)
+	for {
+		q.mu.Lock()
+		for len(q.queue) == 0 && !q.closed {
+			q.ready.Wait()
+		}
+		if len(q.queue) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		p := q.queue[0]
+		q.queue[0] = nil
+		q.queue = q.queue[1:]
+		q.space.Signal()
+		q.mu.Unlock()
+
+		n, err := q.w.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+
+		q.mu.Lock()
+		q.stats.Writes++
+		if err != nil {
+			q.stats.Errors++
+			if q.err == nil {
+				q.err = err
+			}
+		}
+		q.mu.Unlock()
+	}
+}
+
+// Close blocks until the queued writes are written, it returns the first write error.
+// The underlying writer is not closed.
+func (q *QueuedWriter) Close() error {
+	q.mu.Lock()
+	q.closed = true
+	q.ready.Broadcast()
+	q.space.Broadcast()
+	q.mu.Unlock()
+
+	<-q.done
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.err
+}
+
+// Stats returns a copy of the queue counters
+func (q *QueuedWriter) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s := q.stats
+	s.Depth = len(q.queue)
+	return s
+}
@@ -0,0 +1,83 @@
+package syncio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+// fillQueue blocks the consumer of a QueuedWriter with a first write and queues the rest
+func fillQueue(t *testing.T, policy OverflowPolicy, writes ...string) (*QueuedWriter, *blockingWriter) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	q := QueueWriter(bw, 2, policy)
+	q.Write([]byte(writes[0]))
+	for q.Stats().Depth != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for _, w := range writes[1:] {
+		if _, err := q.Write([]byte(w)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	return q, bw
+}
+
+func TestQueueWriterDrop(t *testing.T) {
+	tests := []struct {
+		policy   OverflowPolicy
+		expected string
+	}{
+		{OverflowDropNewest, "abc"},
+		{OverflowDropOldest, "acd"},
+	}
+	for _, tt := range tests {
+		q, bw := fillQueue(t, tt.policy, "a", "b", "c", "d")
+		s := q.Stats()
+		if s.Depth != 2 || s.HighWater != 2 || s.Dropped != 1 || s.DroppedBytes != 1 {
+			t.Errorf("%v: stats: %+v, expected depth 2 and 1 dropped byte", tt.policy, s)
+		}
+		close(bw.release)
+		if err := q.Close(); err != nil {
+			t.Errorf("%v: close: %v", tt.policy, err)
+		}
+		if bw.out.String() != tt.expected {
+			t.Errorf("%v: written: %q, expected: %q", tt.policy, bw.out.String(), tt.expected)
+		}
+	}
+}
+
+func TestQueueWriterBlock(t *testing.T) {
+	q, bw := fillQueue(t, OverflowBlock, "a", "b", "c")
+	written := make(chan struct{})
+	go func() {
+		q.Write([]byte("d"))
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatalf("write not blocked with a full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(bw.release)
+	<-written
+	q.Close()
+	if bw.out.String() != "abcd" {
+		t.Errorf("written: %q, expected: %q", bw.out.String(), "abcd")
+	}
+	if _, err := q.Write([]byte("e")); err != ErrWriteOnClosed {
+		t.Errorf("write after close: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+}
+
+func TestQueueWriterError(t *testing.T) {
+	q := QueueWriter(synctest.NewFailingWriter(nil, nil), 4, OverflowBlock)
+	q.Write([]byte("a"))
+	q.Write([]byte("b"))
+	if err := q.Close(); err != synctest.ErrInjected {
+		t.Errorf("close: %v, expected: %v", err, synctest.ErrInjected)
+	}
+	if s := q.Stats(); s.Writes != 2 || s.Errors != 2 {
+		t.Errorf("stats: %+v, expected 2 failed writes", s)
+	}
+}
@@ -0,0 +1,123 @@
+package syncio
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrQuotaExceeded is returned by a QuotaLimitedWriter when a write doesn't fit in the
+// remaining quota
+var ErrQuotaExceeded = errors.New("write quota exceeded")
+
+// QuotaLimitedWriter limits the bytes written to an underlying writer to a quota that can be
+// topped up, e.g. a daily budget per tenant. The bytes are counted once written, as returned
+// by the underlying writer, so the retries of a Buffer sink count every byte once. By default
+// the writes that don't fit in the remaining quota fail with ErrQuotaExceeded without writing,
+// in soft mode they are written and Exceeded is set. It's safe for concurrent use.
+type QuotaLimitedWriter struct {
+	w io.Writer
+
+	mu    sync.Mutex
+	quota int64
+	// used is the bytes written and reserved the bytes of the writes in progress
+	used       int64
+	reserved   int64
+	soft       bool
+	exceeded   bool
+	onExceeded func()
+}
+
+var _ io.Writer = &QuotaLimitedWriter{}
+
+// QuotaWriter wraps w limiting the bytes written to quota
+func QuotaWriter(w io.Writer, quota int64) *QuotaLimitedWriter {
+	return &QuotaLimitedWriter{w: w, quota: quota}
+}
+
+// SetSoft enables the soft mode: the writes beyond the quota are written flagging Exceeded
+func (q *QuotaLimitedWriter) SetSoft(soft bool) {
+	q.mu.Lock()
+	q.soft = soft
+	q.mu.Unlock()
+}
+
+// SetOnExceeded sets a callback called once when the quota is crossed, by the write that
+// doesn't fit, and again after a top-up bringing the remaining quota above 0
+func (q *QuotaLimitedWriter) SetOnExceeded(fn func()) {
+	q.mu.Lock()
+	q.onExceeded = fn
+	q.mu.Unlock()
+}
+
+// Write writes p if it fits in the remaining quota, or in soft mode
+func (q *QuotaLimitedWriter) Write(p []byte) (int, error) {
+	size := int64(len(p))
+	q.mu.Lock()
+	fits := q.used+q.reserved+size <= q.quota
+	crossed := q.cross(fits)
+	if !fits && !q.soft {
+		q.mu.Unlock()
+		if crossed != nil {
+			crossed()
+		}
+		return 0, ErrQuotaExceeded
+	}
+	// the bytes in progress are reserved so the concurrent writes don't go beyond the quota
+	q.reserved += size
+	q.mu.Unlock()
+	if crossed != nil {
+		crossed()
+	}
+
+	n, err := q.w.Write(p)
+	q.mu.Lock()
+	q.reserved -= size
+	if n > 0 {
+		q.used += int64(n)
+	}
+	q.mu.Unlock()
+	return n, err
+}
+
+// cross flags the quota exceeded when a write doesn't fit, it returns the callback to call
+// out of the lock the first time. The caller must hold the lock.
+func (q *QuotaLimitedWriter) cross(fits bool) func() {
+	if fits || q.exceeded {
+		return nil
+	}
+	q.exceeded = true
+	return q.onExceeded
+}
+
+// AddQuota adds n bytes to the quota, a top-up bringing the remaining quota above 0 clears
+// Exceeded
+func (q *QuotaLimitedWriter) AddQuota(n int64) {
+	q.mu.Lock()
+	q.quota += n
+	if q.quota-q.used > 0 {
+		q.exceeded = false
+	}
+	q.mu.Unlock()
+}
+
+// Remaining returns the quota left, negative in soft mode when it's exceeded
+func (q *QuotaLimitedWriter) Remaining() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.quota - q.used
+}
+
+// Used returns the bytes written
+func (q *QuotaLimitedWriter) Used() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.used
+}
+
+// Exceeded reports if a write didn't fit in the remaining quota since the last top-up
+func (q *QuotaLimitedWriter) Exceeded() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.exceeded
+}
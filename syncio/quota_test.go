@@ -0,0 +1,84 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestQuotaWriter(t *testing.T) {
+	var out bytes.Buffer
+	crossings := 0
+	q := QuotaWriter(&out, 10)
+	q.SetOnExceeded(func() { crossings++ })
+
+	if _, err := q.Write([]byte("0123456")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := q.Write([]byte("7890")); n != 0 || err != ErrQuotaExceeded {
+		t.Errorf("write beyond the quota: %v, %v, expected: 0, %v", n, err, ErrQuotaExceeded)
+	}
+	q.Write([]byte("7890"))
+	if !q.Exceeded() || q.Remaining() != 3 || crossings != 1 {
+		t.Errorf("exceeded: %v, remaining: %v, crossings: %v, expected: true, 3, 1", q.Exceeded(), q.Remaining(), crossings)
+	}
+	// the remaining quota is still usable
+	if _, err := q.Write([]byte("789")); err != nil {
+		t.Fatal(err)
+	}
+
+	q.AddQuota(2)
+	if q.Exceeded() {
+		t.Error("exceeded after the top-up")
+	}
+	q.SetSoft(true)
+	if _, err := q.Write([]byte("abcd")); err != nil {
+		t.Fatal(err)
+	}
+	if !q.Exceeded() || q.Remaining() != -2 || q.Used() != 14 || crossings != 2 {
+		t.Errorf("soft mode exceeded: %v, remaining: %v, used: %v, crossings: %v", q.Exceeded(), q.Remaining(), q.Used(), crossings)
+	}
+	if out.String() != "0123456789abcd" {
+		t.Errorf("data: %q", out.String())
+	}
+}
+
+func TestQuotaWriterRetries(t *testing.T) {
+	// the sink accepts 3 bytes of every write, the Buffer retries the rest
+	sw := &shortWriter{max: 3}
+	q := QuotaWriter(sw, 100)
+	tb := NewBuffer(q, SetBufferSize(32))
+	tb.Write(bytes.Repeat([]byte("x"), 20))
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if q.Used() != 20 || sw.out.Len() != 20 {
+		t.Errorf("used: %v, written: %v, expected: 20", q.Used(), sw.out.Len())
+	}
+}
+
+func TestQuotaWriterConcurrent(t *testing.T) {
+	q := QuotaWriter(&testWriter{}, 1000)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var rejected int
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := q.Write(make([]byte, 7)); errors.Is(err, ErrQuotaExceeded) {
+					mu.Lock()
+					rejected++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	// 142 writes of 7 bytes fit in 1000
+	if q.Used() != 994 || rejected != 400-142 {
+		t.Errorf("used: %v, rejected: %v, expected: 994, %v", q.Used(), rejected, 400-142)
+	}
+}
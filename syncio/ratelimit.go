@@ -0,0 +1,216 @@
+package syncio
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket used to pace writes, the tokens can represent bytes
+// or operations depending on how the limiter is consumed. Reservations are never
+// dropped: a caller that can't be served immediately is queued and served in order
+// as the bucket refills, even if the rate is changed meanwhile.
+type Limiter struct {
+	mu    sync.Mutex
+	rate  float64 // tokens per second, <= 0 means unlimited
+	burst float64
+	// filled is the total of tokens ever added to the bucket and taken the total
+	// of tokens reserved, filled-taken is the number of available tokens. A negative
+	// value means there are reservations waiting for the bucket to refill.
+	filled  float64
+	taken   float64
+	last    time.Time
+	changed chan struct{}
+}
+
+// NewLimiter returns a Limiter that refills at 'rate' tokens per second and holds at most 'burst'
+// tokens, the bucket starts full
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		filled:  float64(burst),
+		last:    time.Now(),
+		changed: make(chan struct{}),
+	}
+}
+
+// SetLimit changes the refill rate, tokens accumulated until now are kept and queued
+// reservations are re-evaluated with the new rate
+func (l *Limiter) SetLimit(rate float64) {
+	l.mu.Lock()
+	l.advance(time.Now())
+	l.rate = rate
+	l.notify()
+	l.mu.Unlock()
+}
+
+// SetBurst changes the bucket capacity
+func (l *Limiter) SetBurst(burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	l.mu.Lock()
+	l.advance(time.Now())
+	l.burst = float64(burst)
+	if l.filled-l.taken > l.burst {
+		l.filled = l.taken + l.burst
+	}
+	l.notify()
+	l.mu.Unlock()
+}
+
+// Limit returns the current refill rate
+func (l *Limiter) Limit() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Burst returns the bucket capacity
+func (l *Limiter) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.burst)
+}
+
+// WaitN blocks until n tokens are available and consumes them. n can be bigger
+// than the burst, in which case the caller waits until the debt is refilled.
+func (l *Limiter) WaitN(n int) {
+	l.wait(context.Background(), n)
+}
+
+// WaitNContext is WaitN returning ctx.Err() once ctx is done, the tokens stay consumed
+func (l *Limiter) WaitNContext(ctx context.Context, n int) error {
+	return l.wait(ctx, n)
+}
+
+// wait waits for n tokens until ctx is done
+func (l *Limiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	l.advance(time.Now())
+	l.taken += float64(n)
+	target := l.taken
+	for l.filled < target {
+		if l.rate <= 0 {
+			break
+		}
+		d := time.Duration((target - l.filled) / l.rate * float64(time.Second))
+		changed := l.changed
+		l.mu.Unlock()
+
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-changed:
+			t.Stop()
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+
+		l.mu.Lock()
+		l.advance(time.Now())
+	}
+	l.mu.Unlock()
+	return nil
+}
+
+// advance refills the bucket with the tokens generated since the last call, the
+// caller must hold the lock
+func (l *Limiter) advance(now time.Time) {
+	elapsed := now.Sub(l.last)
+	if elapsed <= 0 {
+		return
+	}
+	l.last = now
+	if l.rate <= 0 {
+		// unlimited: settle any debt
+		if l.filled < l.taken {
+			l.filled = l.taken
+		}
+		return
+	}
+	l.filled += elapsed.Seconds() * l.rate
+	if l.filled-l.taken > l.burst {
+		l.filled = l.taken + l.burst
+	}
+}
+
+// notify wakes up the queued reservations, the caller must hold the lock
+func (l *Limiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// LimitedWriter paces the writes to W, Bytes limits the throughput in bytes per second,
+// and Ops limits the number of Write calls per second. Both limiters are optional and
+// can be shared between writers to apply a common limit. The limits can be changed at
+// runtime with their SetLimit.
+type LimitedWriter struct {
+	W     io.Writer
+	Bytes *Limiter
+	Ops   *Limiter
+}
+
+var _ io.Writer = &LimitedWriter{}
+
+// RateLimitedWriter wraps w limiting its throughput to bytesPerSec
+func RateLimitedWriter(w io.Writer, bytesPerSec float64, burst int) *LimitedWriter {
+	return &LimitedWriter{W: w, Bytes: NewLimiter(bytesPerSec, burst)}
+}
+
+// OpRateLimitedWriter wraps w limiting the number of writes per second regardless
+// of their size, used as a Buffer sink each flush counts as one operation
+func OpRateLimitedWriter(w io.Writer, opsPerSec float64, burst int) *LimitedWriter {
+	return &LimitedWriter{W: w, Ops: NewLimiter(opsPerSec, burst)}
+}
+
+// Write waits for the limiters and writes p to the underlying writer
+func (w *LimitedWriter) Write(p []byte) (int, error) {
+	if w.Ops != nil {
+		w.Ops.WaitN(1)
+	}
+	if w.Bytes != nil {
+		w.Bytes.WaitN(len(p))
+	}
+	return w.W.Write(p)
+}
+
+// LimitedReader paces the reads from R to Bytes bytes per second, the limiter can be shared
+// and changed at runtime as in LimitedWriter
+type LimitedReader struct {
+	R     io.Reader
+	Bytes *Limiter
+}
+
+var _ io.Reader = &LimitedReader{}
+
+// RateLimitedReader wraps r limiting its throughput to bytesPerSec
+func RateLimitedReader(r io.Reader, bytesPerSec float64, burst int) *LimitedReader {
+	return &LimitedReader{R: r, Bytes: NewLimiter(bytesPerSec, burst)}
+}
+
+// Read reads at most the burst of the limiter from the underlying reader and waits for the
+// bytes read, so the time waited follows the data actually read
+func (r *LimitedReader) Read(p []byte) (int, error) {
+	if r.Bytes == nil {
+		return r.R.Read(p)
+	}
+	if burst := r.Bytes.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := r.R.Read(p)
+	if n > 0 {
+		r.Bytes.WaitN(n)
+	}
+	return n, err
+}
@@ -0,0 +1,68 @@
+// Package ratelimit throttles the throughput of a writer or a reader with the token bucket
+// of syncio.Limiter, e.g. how fast a syncio.Buffer drains into a shared network sink. The
+// limits can be changed at runtime, the pending waits follow the new limit.
+package ratelimit
+
+import (
+	"io"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+// Writer limits the bytes per second written to the underlying writer, a write bigger than
+// the burst waits until the bucket refills the difference
+type Writer struct {
+	lw syncio.LimitedWriter
+}
+
+var _ io.Writer = &Writer{}
+
+// NewWriter returns a Writer writing to w at bytesPerSec with bursts of burst bytes, a
+// bytesPerSec <= 0 doesn't limit the writes
+func NewWriter(w io.Writer, bytesPerSec float64, burst int) *Writer {
+	return &Writer{lw: syncio.LimitedWriter{W: w, Bytes: syncio.NewLimiter(bytesPerSec, burst)}}
+}
+
+// Write waits for len(p) tokens and writes p to the underlying writer
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.lw.Write(p)
+}
+
+// SetLimit changes the bytes per second, the waits in progress are re-evaluated
+func (w *Writer) SetLimit(bytesPerSec float64) {
+	w.lw.Bytes.SetLimit(bytesPerSec)
+}
+
+// Limit returns the bytes per second
+func (w *Writer) Limit() float64 {
+	return w.lw.Bytes.Limit()
+}
+
+// Reader limits the bytes per second read from the underlying reader, the reads are capped
+// to the burst
+type Reader struct {
+	lr syncio.LimitedReader
+}
+
+var _ io.Reader = &Reader{}
+
+// NewReader returns a Reader reading from r at bytesPerSec with bursts of burst bytes, a
+// bytesPerSec <= 0 doesn't limit the reads
+func NewReader(r io.Reader, bytesPerSec float64, burst int) *Reader {
+	return &Reader{lr: syncio.LimitedReader{R: r, Bytes: syncio.NewLimiter(bytesPerSec, burst)}}
+}
+
+// Read reads at most the burst from the underlying reader and waits for the bytes read
+func (r *Reader) Read(p []byte) (int, error) {
+	return r.lr.Read(p)
+}
+
+// SetLimit changes the bytes per second, the waits in progress are re-evaluated
+func (r *Reader) SetLimit(bytesPerSec float64) {
+	r.lr.Bytes.SetLimit(bytesPerSec)
+}
+
+// Limit returns the bytes per second
+func (r *Reader) Limit() float64 {
+	return r.lr.Bytes.Limit()
+}
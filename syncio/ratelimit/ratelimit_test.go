@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+func TestWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, 1000, 100)
+	tb := syncio.NewBuffer(w, syncio.SetBufferSize(100))
+
+	// 500 bytes over the burst at 1000 bytes/s
+	start := time.Now()
+	for i := 0; i < 12; i++ {
+		tb.Write(make([]byte, 50))
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 490*time.Millisecond {
+		t.Errorf("drain took %v, expected at least 500ms", elapsed)
+	}
+	if out.Len() != 600 {
+		t.Errorf("written %v bytes, expected: %v", out.Len(), 600)
+	}
+}
+
+func TestWriterSetLimit(t *testing.T) {
+	w := NewWriter(io.Discard, 1, 100)
+	w.Write(make([]byte, 100))
+
+	done := make(chan struct{})
+	go func() {
+		w.Write(make([]byte, 100))
+		close(done)
+	}()
+	// let the write wait with the slow rate before speeding it up
+	time.Sleep(50 * time.Millisecond)
+	w.SetLimit(1e6)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("write not served after raising the limit")
+	}
+	if l := w.Limit(); l != 1e6 {
+		t.Errorf("limit: %v, expected: %v", l, 1e6)
+	}
+}
+
+func TestReader(t *testing.T) {
+	data := make([]byte, 600)
+	r := NewReader(bytes.NewReader(data), 1000, 100)
+
+	// 500 bytes over the burst at 1000 bytes/s, the reads are capped to the burst
+	start := time.Now()
+	p := make([]byte, 1000)
+	total := 0
+	for {
+		n, err := r.Read(p)
+		if n > 100 {
+			t.Errorf("read %v bytes, more than the burst", n)
+		}
+		total += n
+		if err == io.EOF {
+			break
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 490*time.Millisecond {
+		t.Errorf("reads took %v, expected at least 500ms", elapsed)
+	}
+	if total != len(data) {
+		t.Errorf("read %v bytes, expected: %v", total, len(data))
+	}
+
+	r = NewReader(bytes.NewReader(data), 1, 100)
+	r.SetLimit(1e6)
+	start = time.Now()
+	if n, _ := io.Copy(io.Discard, r); n != int64(len(data)) {
+		t.Errorf("copied %v bytes, expected: %v", n, len(data))
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("reads took %v with the raised limit", elapsed)
+	}
+}
@@ -0,0 +1,156 @@
+package syncio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOpRateLimitedWriter(t *testing.T) {
+	ops := 50.0
+	tw := &testWriter{}
+	w := OpRateLimitedWriter(tw, ops, 1)
+
+	iterations := 11
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		// size must not matter
+		w.Write(make([]byte, i*100))
+	}
+	elapsed := time.Since(start)
+
+	// the first write is served by the burst
+	min := time.Duration(float64(iterations-1) / ops * float64(time.Second))
+	if elapsed < min-10*time.Millisecond {
+		t.Errorf("%v writes took %v, expected at least %v", iterations, elapsed, min)
+	}
+	if tw.writes != int64(iterations) {
+		t.Errorf("test writer writes: %v, expected: %v", tw.writes, iterations)
+	}
+}
+
+func TestLimitedWriterBothLimits(t *testing.T) {
+	tw := &testWriter{}
+	w := &LimitedWriter{
+		W:     tw,
+		Ops:   NewLimiter(1000, 1),
+		Bytes: NewLimiter(1000, 100),
+	}
+
+	// bytes are the constraint: 500 bytes over the burst at 1000 bytes/s
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		w.Write(make([]byte, 100))
+	}
+	if elapsed := time.Since(start); elapsed < 490*time.Millisecond {
+		t.Errorf("writes took %v, expected at least 500ms", elapsed)
+	}
+}
+
+func TestLimiterSetLimitKeepsQueued(t *testing.T) {
+	l := NewLimiter(1, 1)
+	l.WaitN(1)
+
+	waiters := 5
+	wg := sync.WaitGroup{}
+	wg.Add(waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			l.WaitN(1)
+			wg.Done()
+		}()
+	}
+	// let the goroutines queue with the slow rate before speeding it up
+	time.Sleep(50 * time.Millisecond)
+	l.SetLimit(100)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("queued reservations were not served after raising the limit")
+	}
+}
+
+func TestLimiterWaitNContext(t *testing.T) {
+	l := NewLimiter(1, 1)
+	l.WaitN(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.WaitNContext(ctx, 10); err != context.DeadlineExceeded {
+		t.Errorf("wait error: %v, expected: %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("wait returned after %v, expected the context deadline", d)
+	}
+	if err := NewLimiter(0, 1).WaitNContext(ctx, 10); err != nil {
+		t.Errorf("unlimited wait error: %v", err)
+	}
+}
+
+func TestRateLimitedReader(t *testing.T) {
+	data := make([]byte, 600)
+	r := RateLimitedReader(bytes.NewReader(data), 1000, 100)
+
+	// 500 bytes over the burst at 1000 bytes/s, the reads are capped to the burst
+	start := time.Now()
+	p := make([]byte, 1000)
+	total := 0
+	for {
+		n, err := r.Read(p)
+		if n > 100 {
+			t.Errorf("read %v bytes, more than the burst", n)
+		}
+		total += n
+		if err == io.EOF {
+			break
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 490*time.Millisecond {
+		t.Errorf("reads took %v, expected at least 500ms", elapsed)
+	}
+	if total != len(data) {
+		t.Errorf("read %v bytes, expected: %v", total, len(data))
+	}
+
+	// raising the limit at runtime
+	r = RateLimitedReader(bytes.NewReader(data), 1, 100)
+	r.Bytes.SetLimit(1e6)
+	start = time.Now()
+	if n, _ := io.Copy(io.Discard, r); n != int64(len(data)) {
+		t.Errorf("copied %v bytes, expected: %v", n, len(data))
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("reads took %v with the raised limit", elapsed)
+	}
+}
+
+func TestOpRateLimitedWriterAsBufferSink(t *testing.T) {
+	tw := &testWriter{}
+	lw := OpRateLimitedWriter(tw, 20, 1)
+	tb := NewBuffer(lw, SetBufferSize(100))
+
+	p := make([]byte, 60)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		tb.Write(p)
+	}
+	tb.Close()
+
+	// every write of 60 bytes fills the buffer: 4 flushes, one op each
+	if tw.writes != 4 {
+		t.Errorf("test writer writes: %v, expected: %v", tw.writes, 4)
+	}
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("flushes took %v, expected at least 150ms", elapsed)
+	}
+}
@@ -0,0 +1,83 @@
+package syncio
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateBuckets is the number of per second samples, one more than the longest window
+const rateBuckets = 61
+
+// rateSample is a sample of the counters, t is the clock time in nanoseconds
+type rateSample struct {
+	t      int64
+	bytes  int64
+	writes int64
+}
+
+// rates is the ring of per second samples of the flushed bytes and the writes, a bucket
+// keeps the last sample of its second. The samples are taken by NewBuffer, the ticks and
+// StatsInto, a rate is computed from the last sample at least a window old or, in a partial
+// window, the oldest one.
+type rates struct {
+	mu   sync.Mutex
+	ring [rateBuckets]rateSample
+}
+
+// sample records the current counters of tb
+func (r *rates) sample(tb *Buffer) {
+	now := tb.clock.Now()
+	bytes := atomic.LoadInt64(&tb.stats.DirectBytes) + atomic.LoadInt64(&tb.stats.ChildBytes)
+	writes := tb.callerWrites()
+	r.mu.Lock()
+	r.record(now, bytes, writes)
+	r.mu.Unlock()
+}
+
+func (r *rates) record(now time.Time, bytes, writes int64) {
+	i := now.Unix() % rateBuckets
+	if i < 0 {
+		i += rateBuckets
+	}
+	r.ring[i] = rateSample{t: now.UnixNano(), bytes: bytes, writes: writes}
+}
+
+// rate returns the bytes and writes per second over the window w ending at now, the
+// caller must hold mu
+func (r *rates) rate(now int64, w time.Duration, bytes, writes int64) (float64, float64) {
+	var base, oldest *rateSample
+	for i := range r.ring {
+		s := &r.ring[i]
+		if s.t == 0 {
+			continue
+		}
+		if s.t <= now-int64(w) && (base == nil || s.t > base.t) {
+			base = s
+		}
+		if oldest == nil || s.t < oldest.t {
+			oldest = s
+		}
+	}
+	if base == nil {
+		base = oldest
+	}
+	if base == nil || now <= base.t {
+		return 0, 0
+	}
+	elapsed := time.Duration(now - base.t).Seconds()
+	return float64(bytes-base.bytes) / elapsed, float64(writes-base.writes) / elapsed
+}
+
+// load sets the rates of s from its counters and samples them
+func (r *rates) load(tb *Buffer, s *Stats) {
+	now := tb.clock.Now()
+	bytes := s.DirectBytes + s.ChildBytes
+	r.mu.Lock()
+	t := now.UnixNano()
+	s.BytesPerSec1, s.WritesPerSec1 = r.rate(t, time.Second, bytes, s.CallerWrites)
+	s.BytesPerSec10, s.WritesPerSec10 = r.rate(t, 10*time.Second, bytes, s.CallerWrites)
+	s.BytesPerSec60, s.WritesPerSec60 = r.rate(t, time.Minute, bytes, s.CallerWrites)
+	r.record(now, bytes, s.CallerWrites)
+	r.mu.Unlock()
+}
@@ -0,0 +1,47 @@
+package syncio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRates(t *testing.T) {
+	clock := newFakeClock()
+	tb := NewBuffer(&testWriter{}, SetClock(clock), SetSynchronousMode(true))
+	defer tb.Close()
+	write := func(n int) {
+		tb.Write(make([]byte, n))
+		tb.Flush()
+	}
+	expect := func(bytes, writes [3]float64) {
+		t.Helper()
+		s := tb.Stats()
+		if b := [3]float64{s.BytesPerSec1, s.BytesPerSec10, s.BytesPerSec60}; b != bytes {
+			t.Errorf("bytes per sec: %v, expected: %v", b, bytes)
+		}
+		if w := [3]float64{s.WritesPerSec1, s.WritesPerSec10, s.WritesPerSec60}; w != writes {
+			t.Errorf("writes per sec: %v, expected: %v", w, writes)
+		}
+	}
+
+	// the windows are partial after startup
+	write(100)
+	write(100)
+	clock.Advance(time.Second)
+	expect([3]float64{200, 200, 200}, [3]float64{2, 2, 2})
+	write(50)
+	clock.Advance(time.Second)
+	expect([3]float64{50, 125, 125}, [3]float64{1, 1.5, 1.5})
+
+	// the ticks sample the counters without Stats calls
+	for i := 0; i < 10; i++ {
+		write(10)
+		clock.Advance(time.Second)
+		tb.tick()
+	}
+	expect([3]float64{10, 10, 350.0 / 12}, [3]float64{1, 1, 13.0 / 12})
+
+	// idle
+	clock.Advance(2 * time.Minute)
+	expect([3]float64{0, 0, 0}, [3]float64{0, 0, 0})
+}
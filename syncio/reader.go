@@ -0,0 +1,269 @@
+package syncio
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/travelgateX/go-io/syncio/bufpool"
+)
+
+// maxEmptyReads is the number of consecutive empty reads of the underlying reader before
+// failing with io.ErrNoProgress, as bufio.Reader
+const maxEmptyReads = 100
+
+// ReaderOption is an option of NewReader
+type ReaderOption func(*Reader)
+
+// SetPrefetchDepth sets the number of blocks read ahead of the caller, 2 by default
+func SetPrefetchDepth(depth int) ReaderOption {
+	return func(r *Reader) {
+		r.depth = depth
+	}
+}
+
+// SetPrefetchBlockSize sets the size of the reads of the underlying reader, 4096 by default
+func SetPrefetchBlockSize(size int) ReaderOption {
+	return func(r *Reader) {
+		r.blockSize = size
+	}
+}
+
+// Reader is the read counterpart of Buffer: a goroutine reads ahead from the underlying
+// reader into pooled blocks while the caller consumes the previous ones. The reads are not
+// safe for concurrent use, Stats and Close are. FileReader fetches an io.ReaderAt in parallel.
+type Reader struct {
+	r         io.Reader
+	blockSize int
+	depth     int
+	pool      *bufpool.Pool
+	// blocks are the blocks read ahead in order, closed after the last one
+	blocks chan readBlock
+	stop   chan struct{}
+	once   sync.Once
+	// onClose is called by the first Close, see NewPair
+	onClose func()
+
+	// cur is the block being consumed, rest its unread data, err the error ending the stream
+	cur  []byte
+	rest []byte
+	err  error
+
+	stats PrefetchStats
+}
+
+// readBlock is a block read by the fill goroutine, err is the error of the read
+type readBlock struct {
+	buf []byte
+	n   int
+	err error
+}
+
+// PrefetchStats are the counters of a Reader
+type PrefetchStats struct {
+	// Reads is the number of Read calls and Bytes the bytes returned by Read and WriteTo
+	Reads int64
+	Bytes int64
+	// Fills is the number of reads of the underlying reader and FillBytes the bytes read
+	Fills     int64
+	FillBytes int64
+	// Waits is the number of times the caller waited for a block not read yet
+	Waits int64
+	// BlockAllocs is the number of blocks allocated instead of reused
+	BlockAllocs int64
+	// ReadAhead is the number of blocks read ahead and not consumed yet
+	ReadAhead int64
+}
+
+// NewReader returns a Reader reading ahead from r, Close stops the read ahead
+func NewReader(r io.Reader, options ...ReaderOption) *Reader {
+	const (
+		defaultBlockSize = 4096
+		defaultDepth     = 2
+	)
+	rd := &Reader{r: r}
+	for _, o := range options {
+		o(rd)
+	}
+	if rd.blockSize <= 0 {
+		rd.blockSize = defaultBlockSize
+	}
+	if rd.depth <= 0 {
+		rd.depth = defaultDepth
+	}
+	if rd.pool == nil {
+		// the blocks read ahead, the one being filled and the one being consumed
+		rd.pool = bufpool.New(rd.blockSize, rd.depth+2)
+	}
+	rd.blocks = make(chan readBlock, rd.depth)
+	rd.stop = make(chan struct{})
+	go rd.fill()
+	return rd
+}
+
+// fill reads the blocks ahead until the underlying reader fails or the Reader is closed
+func (r *Reader) fill() {
+	defer close(r.blocks)
+	empty := 0
+	for {
+		buf, ok := r.pool.TryGet()
+		if !ok {
+			buf = r.pool.Alloc()
+			atomic.AddInt64(&r.stats.BlockAllocs, 1)
+		}
+		n, err := r.r.Read(buf)
+		atomic.AddInt64(&r.stats.Fills, 1)
+		atomic.AddInt64(&r.stats.FillBytes, int64(n))
+		if n == 0 && err == nil {
+			if empty++; empty < maxEmptyReads {
+				r.pool.Put(buf)
+				continue
+			}
+			err = io.ErrNoProgress
+		}
+		empty = 0
+		select {
+		case r.blocks <- readBlock{buf: buf, n: n, err: err}:
+		case <-r.stop:
+			r.pool.Put(buf)
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// next makes the next block the current one, it returns false at the end of the stream
+func (r *Reader) next() bool {
+	if r.cur != nil {
+		r.pool.Put(r.cur)
+		r.cur, r.rest = nil, nil
+	}
+	if r.err != nil {
+		return false
+	}
+	var b readBlock
+	var ok bool
+	select {
+	case b, ok = <-r.blocks:
+	default:
+		atomic.AddInt64(&r.stats.Waits, 1)
+		select {
+		case b, ok = <-r.blocks:
+		case <-r.stop:
+		}
+	}
+	if !ok || r.closed() {
+		if ok {
+			r.pool.Put(b.buf)
+		}
+		r.err = ErrReaderClosed
+		return false
+	}
+	r.cur, r.rest, r.err = b.buf, b.buf[:b.n], b.err
+	return true
+}
+
+// closed reports if Close was called
+func (r *Reader) closed() bool {
+	select {
+	case <-r.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Read reads the data read ahead, it returns the error of the underlying reader once the
+// data before it is consumed
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.blocks == nil {
+		return 0, ErrNotInitialized
+	}
+	if r.closed() {
+		return 0, ErrReaderClosed
+	}
+	atomic.AddInt64(&r.stats.Reads, 1)
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(r.rest) == 0 {
+		if !r.next() {
+			return 0, r.err
+		}
+	}
+	n := copy(p, r.rest)
+	r.rest = r.rest[n:]
+	atomic.AddInt64(&r.stats.Bytes, int64(n))
+	return n, nil
+}
+
+// WriteTo writes the blocks to w without copying them until the end of the stream, io.EOF
+// isn't returned
+func (r *Reader) WriteTo(w io.Writer) (int64, error) {
+	if r.blocks == nil {
+		return 0, ErrNotInitialized
+	}
+	if r.closed() {
+		return 0, ErrReaderClosed
+	}
+	var written int64
+	for {
+		if len(r.rest) > 0 {
+			n, err := w.Write(r.rest)
+			r.rest = r.rest[n:]
+			written += int64(n)
+			atomic.AddInt64(&r.stats.Bytes, int64(n))
+			if err == nil && len(r.rest) > 0 {
+				err = io.ErrShortWrite
+			}
+			if err != nil {
+				return written, err
+			}
+		}
+		if !r.next() {
+			if r.err == io.EOF {
+				return written, nil
+			}
+			return written, r.err
+		}
+	}
+}
+
+// Stats returns a copy of the reader counters, it doesn't allocate
+func (r *Reader) Stats() PrefetchStats {
+	var s PrefetchStats
+	r.StatsInto(&s)
+	return s
+}
+
+// StatsInto copies the reader counters into s
+func (r *Reader) StatsInto(s *PrefetchStats) {
+	s.Reads = atomic.LoadInt64(&r.stats.Reads)
+	s.Bytes = atomic.LoadInt64(&r.stats.Bytes)
+	s.Fills = atomic.LoadInt64(&r.stats.Fills)
+	s.FillBytes = atomic.LoadInt64(&r.stats.FillBytes)
+	s.Waits = atomic.LoadInt64(&r.stats.Waits)
+	s.BlockAllocs = atomic.LoadInt64(&r.stats.BlockAllocs)
+	s.ReadAhead = int64(len(r.blocks))
+}
+
+// Close stops the read ahead and closes the underlying reader if it's an io.Closer, the reads
+// fail with ErrReaderClosed from now on. The data read ahead is discarded.
+func (r *Reader) Close() error {
+	if r.blocks == nil {
+		return ErrNotInitialized
+	}
+	var err error
+	r.once.Do(func() {
+		close(r.stop)
+		if r.onClose != nil {
+			r.onClose()
+		}
+		if c, ok := r.r.(io.Closer); ok {
+			err = c.Close()
+		}
+	})
+	return err
+}
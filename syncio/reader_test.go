@@ -0,0 +1,161 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestReader(t *testing.T) {
+	data := strings.Repeat("0123456789", 1000)
+	r := NewReader(strings.NewReader(data), SetPrefetchBlockSize(64), SetPrefetchDepth(3))
+	defer r.Close()
+	if err := iotest.TestReader(r, []byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	s := r.Stats()
+	if s.FillBytes != int64(len(data)) || s.Fills < int64(len(data)/64) {
+		t.Errorf("stats: %+v, expected %v bytes in %v fills at least", s, len(data), len(data)/64)
+	}
+	// the pool bounds the blocks allocated
+	if s.BlockAllocs > 5 {
+		t.Errorf("block allocs: %v, expected 5 at most", s.BlockAllocs)
+	}
+	if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("read at the end: %v, expected: %v", err, io.EOF)
+	}
+}
+
+func TestReaderWriteTo(t *testing.T) {
+	data := strings.Repeat("abc", 5000)
+	r := NewReader(iotest.HalfReader(strings.NewReader(data)), SetPrefetchBlockSize(100))
+	defer r.Close()
+	var out bytes.Buffer
+	n, err := io.Copy(&out, r)
+	if err != nil || n != int64(len(data)) || out.String() != data {
+		t.Errorf("copy: %v, %v, expected: %v, nil", n, err, len(data))
+	}
+	if s := r.Stats(); s.Bytes != int64(len(data)) {
+		t.Errorf("bytes: %v, expected: %v", s.Bytes, len(data))
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	errRead := errors.New("read failed")
+	r := NewReader(io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(errRead)))
+	defer r.Close()
+	p, err := io.ReadAll(r)
+	if string(p) != "abc" || err != errRead {
+		t.Errorf("read: %q, %v, expected: %q, %v", p, err, "abc", errRead)
+	}
+}
+
+func TestReaderClose(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	r := NewReader(pr)
+	res := make(chan error, 1)
+	go func() {
+		_, err := r.Read(make([]byte, 10))
+		res <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	r.Close()
+	select {
+	case err := <-res:
+		if err != ErrReaderClosed {
+			t.Errorf("read: %v, expected: %v", err, ErrReaderClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("read not released by Close")
+	}
+	if s := r.Stats(); s.Waits != 1 {
+		t.Errorf("waits: %v, expected: 1", s.Waits)
+	}
+	if _, err := r.Read(make([]byte, 10)); err != ErrReaderClosed {
+		t.Errorf("read after close: %v, expected: %v", err, ErrReaderClosed)
+	}
+}
+
+func TestReaderZeroValue(t *testing.T) {
+	var r Reader
+	if _, err := r.Read(make([]byte, 1)); err != ErrNotInitialized {
+		t.Errorf("read: %v, expected: %v", err, ErrNotInitialized)
+	}
+	if err := r.Close(); err != ErrNotInitialized {
+		t.Errorf("close: %v, expected: %v", err, ErrNotInitialized)
+	}
+}
+
+// TestReaderCopies counts the copies of the data from the prefetched blocks to the sink: the
+// buffers of the pool are allocated by the test, the data must be in a single one of them
+// and the sink must write it from there
+func TestReaderCopies(t *testing.T) {
+	data := make([]byte, 8000)
+	for i := range data {
+		data[i] = byte(i*7 + i/251)
+	}
+	copies := map[string]func(tb *Buffer, r *Reader) (int64, error){
+		"Copy": func(tb *Buffer, r *Reader) (int64, error) {
+			n, err := io.Copy(tb, r)
+			return n, errors.Join(err, tb.Flush())
+		},
+		"ReadFrom": func(tb *Buffer, r *Reader) (int64, error) { return tb.ReadFrom(r) },
+	}
+	for name, copyFn := range copies {
+		t.Run(name, func(t *testing.T) {
+			var allocs [][]byte
+			alloc := func(size int) []byte {
+				p := make([]byte, size)
+				allocs = append(allocs, p)
+				return p
+			}
+			var aliased, writes, held int
+			sink := &lockedBuffer{}
+			w := writerFunc(func(p []byte) (int, error) {
+				writes++
+				// counted before the buffers are released, the debug build scribbles them
+				held = 0
+				for _, a := range allocs {
+					if aliases(a, p) {
+						aliased++
+					}
+					if bytes.Contains(a, data[len(data)-32:]) {
+						held++
+					}
+				}
+				return sink.Write(p)
+			})
+			tb := NewBuffer(w, SetBufferSize(16<<10), SetManualTick(true), SetSynchronousMode(true), SetAllocator(alloc, nil))
+			defer tb.Close()
+			r := NewReader(bytes.NewReader(data), SetPrefetchBlockSize(500))
+			defer r.Close()
+
+			n, err := copyFn(tb, r)
+			if err != nil || n != int64(len(data)) || !bytes.Equal(sink.buf.Bytes(), data) {
+				t.Fatalf("copy: %v, %v, written: %v bytes", n, err, sink.Len())
+			}
+			if writes != 1 || aliased != 1 {
+				t.Errorf("sink writes: %v, from the pool: %v, expected 1", writes, aliased)
+			}
+			if held != 1 {
+				t.Errorf("copies in the pool: %v, expected 1", held)
+			}
+		})
+	}
+}
+
+// aliases reports if p starts in the memory of a
+func aliases(a, p []byte) bool {
+	a = a[:cap(a)]
+	for i := range a {
+		if &a[i] == &p[0] {
+			return true
+		}
+	}
+	return false
+}
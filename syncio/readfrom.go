@@ -0,0 +1,75 @@
+package syncio
+
+import (
+	"errors"
+	"io"
+)
+
+var _ io.ReaderFrom = &Buffer{}
+
+// ReadFrom writes the data read from r until io.EOF and flushes it, so io.Copy pumps a stream
+// through the Buffer. The reads go to a buffer of the pool and are written as Write, one write
+// per read, so they follow the flush policies; with SetRecordMode every read is a record.
+// It returns the bytes read and the read error, other than io.EOF, joined with the Flush one.
+// A write error stops the copy and it's returned alone. A *Reader hands its prefetched blocks
+// to Write instead, so the data is copied once from the blocks to the buffer flushed.
+func (tb *Buffer) ReadFrom(r io.Reader) (int64, error) {
+	if !tb.initialized() {
+		return 0, ErrNotInitialized
+	}
+	if rd, ok := r.(*Reader); ok {
+		w := &readFromWriter{tb: tb}
+		n, err := rd.WriteTo(w)
+		if w.err != nil {
+			return n, w.err
+		}
+		return n, errors.Join(err, tb.Flush())
+	}
+	buf, _ := tb.getBuffer()
+	defer tb.putBuffer(buf)
+	// a read filling the buffer size would take the oversized path
+	p := buf.Scratch()
+	if len(p) > 1 {
+		p = p[:len(p)-1]
+	}
+
+	var n int64
+	var rerr error
+	empty := 0
+	for {
+		m, err := r.Read(p)
+		if m > 0 {
+			empty = 0
+			if _, werr := tb.Write(p[:m]); werr != nil {
+				return n, werr
+			}
+			n += int64(m)
+		} else if err == nil {
+			if empty++; empty >= maxEmptyReads {
+				err = io.ErrNoProgress
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rerr = err
+			break
+		}
+	}
+	return n, errors.Join(rerr, tb.Flush())
+}
+
+// readFromWriter is the writer given to Reader.WriteTo by ReadFrom, it keeps the write error
+type readFromWriter struct {
+	tb  *Buffer
+	err error
+}
+
+func (w *readFromWriter) Write(p []byte) (int, error) {
+	n, err := w.tb.Write(p)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
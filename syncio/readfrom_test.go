@@ -0,0 +1,64 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadFrom(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		sink := &lockedBuffer{}
+		tb := NewBuffer(sink, append([]BufferOption{SetBufferSize(64)}, mode...)...)
+		defer tb.Close()
+		data := strings.Repeat("0123456789", 100)
+		tb.Write([]byte("head:"))
+		n, err := io.Copy(tb, iotest.HalfReader(strings.NewReader(data)))
+		if err != nil || n != int64(len(data)) {
+			t.Fatalf("copy: %v, %v", n, err)
+		}
+		// flushed before returning
+		if s := sink.String(); s != "head:"+data {
+			t.Errorf("written: %v bytes, expected: %v", len(s), len(data)+5)
+		}
+	})
+}
+
+func TestReadFromErrors(t *testing.T) {
+	failing := errors.New("read failed")
+	sink := &lockedBuffer{}
+	tb := NewBuffer(sink)
+	n, err := tb.ReadFrom(io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(failing)))
+	if n != 3 || !errors.Is(err, failing) || sink.String() != "abc" {
+		t.Errorf("read error: %v, %v, written %q", n, err, sink.String())
+	}
+	tb.Close()
+
+	// the flush errors are joined
+	flushing := errors.New("flush failed")
+	tb = NewBuffer(writerFunc(func([]byte) (int, error) { return 0, flushing }))
+	n, err = tb.ReadFrom(strings.NewReader("abc"))
+	if n != 3 || !errors.Is(err, flushing) {
+		t.Errorf("flush error: %v, %v", n, err)
+	}
+	tb.Close()
+
+	if _, err := tb.ReadFrom(bytes.NewReader([]byte("x"))); err != ErrWriteOnClosed {
+		t.Errorf("read from on closed: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+
+	// the readers never returning data
+	tb = NewBuffer(&testWriter{})
+	defer tb.Close()
+	if _, err := tb.ReadFrom(emptyReader{}); !errors.Is(err, io.ErrNoProgress) {
+		t.Errorf("empty reads: %v, expected: %v", err, io.ErrNoProgress)
+	}
+}
+
+// emptyReader returns no data and no error
+type emptyReader struct{}
+
+func (emptyReader) Read([]byte) (int, error) { return 0, nil }
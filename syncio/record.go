@@ -0,0 +1,52 @@
+package syncio
+
+import "sync/atomic"
+
+// RecordWriter is implemented by the underlying writers that receive the records of
+// a flush as separate messages, e.g. datagram transports.
+// WriteBatch returns the number of records written, the records are only valid during the call.
+type RecordWriter interface {
+	WriteBatch(records [][]byte) (int, error)
+}
+
+// SetRecordMode makes the Buffer keep the boundaries of each Write, on flush they are
+// delivered to the underlying writer with WriteBatch if it implements RecordWriter,
+// otherwise the batch is written concatenated as usual.
+// A Write is never split across flushes, with or without this option: it's copied whole to
+// the active buffer, or written as its own batch when it's bigger than the buffer. The writes
+// to the underlying writer only end inside a Write when the writer returns a short write, the
+// rest is written with the next call, or with SetMaxSinkWriteSize for a Write bigger than it.
+// The exception is SetUTF8Boundaries without this option, it carries the incomplete rune at the
+// end of a Write to the next flush; this option takes precedence and disables the carry.
+func SetRecordMode(enabled bool) BufferOption {
+	return func(b *Buffer) {
+		b.recordMode = enabled
+	}
+}
+
+// writeRecords writes the records of a batch, it returns the number of bytes written
+func (tb *Buffer) writeRecords(b *batch) (int, error) {
+	records := tb.records[:0]
+	if b.buf != nil {
+		records = b.buf.Records(records)
+	} else {
+		records = append(records, b.p)
+	}
+	tb.records = records
+	atomic.AddInt64(&tb.stats.Records, int64(len(records)))
+
+	rw, ok := tb.writer.(RecordWriter)
+	if !ok {
+		return tb.writeChunks(b.bytes(), records)
+	}
+	n, err := tb.writeBatches(rw, records)
+	written := 0
+	for _, r := range records[:n] {
+		written += len(r)
+	}
+	// don't retain the buffer memory
+	for i := range records {
+		records[i] = nil
+	}
+	return written, err
+}
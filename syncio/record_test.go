@@ -0,0 +1,163 @@
+package syncio
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testRecordWriter struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (w *testRecordWriter) Write(p []byte) (int, error) {
+	panic("records must be written with WriteBatch")
+}
+
+func (w *testRecordWriter) WriteBatch(records [][]byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	batch := make([]string, len(records))
+	for i, r := range records {
+		batch[i] = string(r)
+	}
+	w.batches = append(w.batches, batch)
+	return len(records), nil
+}
+
+func TestRecordMode(t *testing.T) {
+	rw := &testRecordWriter{}
+	tb := NewBuffer(rw, SetBufferSize(10), SetRecordMode(true))
+
+	for _, r := range []string{"aaa", "bb", "cccc", "dd", "0123456789ab", "e"} {
+		tb.Write([]byte(r))
+	}
+	tb.Close()
+
+	expected := [][]string{{"aaa", "bb", "cccc"}, {"dd"}, {"0123456789ab"}, {"e"}}
+	if len(rw.batches) != len(expected) {
+		t.Fatalf("batches: %v, expected: %v", rw.batches, expected)
+	}
+	for i := range expected {
+		if len(rw.batches[i]) != len(expected[i]) {
+			t.Fatalf("batches: %v, expected: %v", rw.batches, expected)
+		}
+		for j := range expected[i] {
+			if rw.batches[i][j] != expected[i][j] {
+				t.Fatalf("batches: %v, expected: %v", rw.batches, expected)
+			}
+		}
+	}
+	if s := tb.Stats(); s.Flushes != 4 || s.Records != 6 {
+		t.Errorf("stats flushes: %v, records: %v, expected 4 and 6", s.Flushes, s.Records)
+	}
+}
+
+func TestRecordModePlainWriter(t *testing.T) {
+	tw := &testWriter{}
+	tb := NewBuffer(tw, SetBufferSize(10), SetRecordMode(true))
+	tb.Write([]byte("aaa"))
+	tb.Write([]byte("bb"))
+	tb.Close()
+	if tw.writes != 1 || tw.bytes != 5 {
+		t.Errorf("test writer writes: %v, bytes: %v, expected 1 and 5", tw.writes, tw.bytes)
+	}
+}
+
+func TestRecordModeAllocs(t *testing.T) {
+	tb := NewBuffer(&testRecordWriter{}, SetBufferSize(1024), SetRecordMode(true))
+	defer tb.Close()
+	p := make([]byte, 10)
+	// warm up the record offsets of the pooled buffers
+	for i := 0; i < 1000; i++ {
+		tb.Write(p)
+	}
+	tb.Flush()
+	allocs := testing.AllocsPerRun(50, func() {
+		tb.Write(p)
+	})
+	if allocs != 0 {
+		t.Errorf("allocations per write: %v, expected 0", allocs)
+	}
+}
+
+func TestRecordModeUTF8Boundaries(t *testing.T) {
+	// the writes end in the middle of a rune, the carry would split them
+	records := []string{"aa\xe2\x82", "\xac€b", "日本\xe8", "\xaa\x9e", "ü\xf0\x9d\x84", "\x9e"}
+	rw := &testRecordWriter{}
+	tb := NewBuffer(rw, SetBufferSize(8), SetRecordMode(true), SetUTF8Boundaries(true),
+		SetFlushInterval(time.Millisecond))
+	for _, r := range records {
+		tb.Write([]byte(r))
+		time.Sleep(2 * time.Millisecond)
+	}
+	tb.Close()
+	var written []string
+	for _, batch := range rw.batches {
+		written = append(written, batch...)
+	}
+	if strings.Join(written, "|") != strings.Join(records, "|") {
+		t.Errorf("records: %q, expected: %q", written, records)
+	}
+
+	// without record mode the carry splits the writes at the rune boundaries
+	uw := &utf8Writer{}
+	tb = NewBuffer(uw, SetBufferSize(8), SetUTF8Boundaries(true), SetFlushInterval(time.Millisecond))
+	for _, r := range records {
+		tb.Write([]byte(r))
+		time.Sleep(2 * time.Millisecond)
+	}
+	tb.Close()
+	if uw.invalid != 0 || uw.out.String() != strings.Join(records, "") {
+		t.Errorf("invalid writes: %v, data: %q", uw.invalid, uw.out.String())
+	}
+}
+
+// lineWriter fails the test when a write doesn't hold whole lines
+type lineWriter struct {
+	t     *testing.T
+	mu    sync.Mutex
+	lines int
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(p) > 0 && (p[0] != '{' || p[len(p)-1] != '\n') {
+		w.t.Errorf("write with a split line: %q", p)
+	}
+	w.lines += bytes.Count(p, []byte("\n"))
+	return len(p), nil
+}
+
+func TestWritesNotSplit(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		for _, record := range []bool{false, true} {
+			lw := &lineWriter{t: t}
+			options := append([]BufferOption{SetBufferSize(256), SetBufferPoolSize(4), SetRecordMode(record),
+				SetFlushInterval(time.Millisecond), SetMaxFlushBytes(1024)}, mode...)
+			tb := NewBuffer(lw, options...)
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 200; i++ {
+						// some lines are bigger than the buffer
+						pad := strings.Repeat("x", (g*31+i*7)%300)
+						fmt.Fprintf(tb, "{\"g\":%v,\"i\":%v,\"pad\":%q}\n", g, i, pad)
+					}
+				}(g)
+			}
+			wg.Wait()
+			tb.Close()
+			if lw.lines != 8*200 {
+				t.Errorf("record mode %v: lines: %v, expected: %v", record, lw.lines, 8*200)
+			}
+		}
+	})
+}
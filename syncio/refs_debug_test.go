@@ -0,0 +1,19 @@
+//go:build syncio_debug
+
+package syncio
+
+import "testing"
+
+func TestBufferUseAfterRelease(t *testing.T) {
+	tb := NewBuffer(&testWriter{})
+	defer tb.Close()
+	b, _ := tb.getBuffer()
+	b.Write([]byte("data"))
+	p := b.Scratch()[:4]
+	tb.putBuffer(b)
+	if string(p) != "\xdd\xdd\xdd\xdd" {
+		t.Errorf("released memory: %q, expected poisoned", p)
+	}
+	expectPanic(t, "use of a released buffer", func() { b.Bytes() })
+	expectPanic(t, "use of a released buffer", func() { b.Write([]byte("x")) })
+}
@@ -0,0 +1,27 @@
+package syncio
+
+import (
+	"strings"
+	"testing"
+)
+
+func expectPanic(t *testing.T, msg string, fn func()) {
+	t.Helper()
+	defer func() {
+		if v := recover(); v == nil || !strings.Contains(v.(string), msg) {
+			t.Errorf("panic: %v, expected: %q", v, msg)
+		}
+	}()
+	fn()
+}
+
+func TestBufferReleasedTwice(t *testing.T) {
+	tb := NewBuffer(&testWriter{})
+	defer tb.Close()
+	b, _ := tb.getBuffer()
+	b.Retain()
+	tb.putBuffer(b)
+	tb.putBuffer(b)
+	expectPanic(t, "released twice", func() { tb.putBuffer(b) })
+	expectPanic(t, "retain of a released buffer", func() { b.Retain() })
+}
@@ -0,0 +1,124 @@
+package syncio
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync/atomic"
+)
+
+// Replay writes the data read from r ahead of the new writes, it's meant to recover the data
+// sent to the dead letter writer in a previous run. The writes done while replaying wait until
+// Replay returns, the writes already buffered go first.
+// With a delimiter every record, delimiter included, is written as a single Write so it's never
+// split across flushes, otherwise the data is written in chunks of the buffer size.
+// Memory is bounded by the buffer pool as with any Write. It returns the bytes replayed.
+func (tb *Buffer) Replay(r io.Reader, delimiter []byte) (int64, error) {
+	if !tb.initialized() {
+		return 0, ErrNotInitialized
+	}
+	tb.bufmu.Lock()
+	for tb.replaying && !tb.writesClosed.Load() {
+		tb.space.Wait()
+	}
+	if tb.writesClosed.Load() {
+		tb.bufmu.Unlock()
+		tb.countError(ErrorClosed)
+		return 0, tb.closedErr()
+	}
+	tb.own()
+	tb.replaying = true
+	tb.bufmu.Unlock()
+
+	defer func() {
+		tb.bufmu.Lock()
+		tb.replaying = false
+		tb.space.Broadcast()
+		tb.unlockBuf()
+	}()
+
+	var next func() ([]byte, error)
+	if len(delimiter) > 0 {
+		s := bufio.NewScanner(r)
+		s.Buffer(make([]byte, 0, 4096), int(^uint(0)>>1))
+		s.Split(splitAfter(delimiter))
+		next = func() ([]byte, error) {
+			if s.Scan() {
+				return s.Bytes(), nil
+			}
+			if err := s.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+	} else {
+		p := make([]byte, tb.bufSize)
+		next = func() ([]byte, error) {
+			n, err := r.Read(p)
+			if n > 0 {
+				return p[:n], nil
+			}
+			if err == nil {
+				err = io.ErrNoProgress
+			}
+			return nil, err
+		}
+	}
+
+	var replayed int64
+	for {
+		p, err := next()
+		if err == io.EOF {
+			return replayed, nil
+		}
+		if err != nil {
+			return replayed, err
+		}
+		if err := tb.replay(p); err != nil {
+			return replayed, err
+		}
+		replayed += int64(len(p))
+	}
+}
+
+// replay writes p while the Buffer is replaying
+func (tb *Buffer) replay(p []byte) error {
+	tb.bufmu.Lock()
+	for tb.queueFull(len(p)) && !tb.writesClosed.Load() {
+		tb.space.Wait()
+	}
+	if tb.writesClosed.Load() {
+		tb.bufmu.Unlock()
+		tb.countError(ErrorClosed)
+		return tb.closedErr()
+	}
+	if tb.wal != nil {
+		if err := tb.appendWAL(p); err != nil {
+			tb.bufmu.Unlock()
+			return err
+		}
+	}
+	sw := tb.writeLocked(p, nil)
+	tb.bufmu.Unlock()
+
+	atomic.AddInt64(&tb.stats.ReplayedBytes, int64(len(p)))
+	if tb.logger != nil {
+		tb.logSwap(sw)
+	}
+	tb.flushInline()
+	return nil
+}
+
+// splitAfter returns a bufio.SplitFunc returning the records ended by delimiter, delimiter included
+func splitAfter(delimiter []byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.Index(data, delimiter); i >= 0 {
+			n := i + len(delimiter)
+			return n, data[:n], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
@@ -0,0 +1,70 @@
+package syncio
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	rw := &recordingWriter{}
+	tb := NewBuffer(rw, SetBufferSize(16), SetRecordMode(true))
+	n, err := tb.Replay(strings.NewReader("one\ntwo\nthree\nfour"), []byte("\n"))
+	if n != 18 || err != nil {
+		t.Errorf("replay: %v, %v, expected: 18, nil", n, err)
+	}
+	tb.Write([]byte("five\n"))
+	tb.Close()
+
+	// the records are not split across flushes
+	if s := strings.Join(rw.writes, "|"); s != "one\ntwo\nthree\n|fourfive\n" {
+		t.Errorf("writes: %q", rw.writes)
+	}
+	if s := tb.Stats(); s.ReplayedBytes != 18 {
+		t.Errorf("replayed bytes: %v, expected: 18", s.ReplayedBytes)
+	}
+}
+
+// slowReader returns one byte per read after a delay
+type slowReader struct {
+	r     *strings.Reader
+	delay time.Duration
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.r.Read(p[:1])
+}
+
+func TestReplayConcurrentWrites(t *testing.T) {
+	for _, single := range []bool{false, true} {
+		var out bytes.Buffer
+		tb := NewBuffer(&out, SetSingleWriter(single))
+		tb.Write([]byte("a"))
+
+		replayed := make(chan struct{})
+		go func() {
+			tb.Replay(&slowReader{r: strings.NewReader("rrrr"), delay: 5 * time.Millisecond}, nil)
+			close(replayed)
+		}()
+		time.Sleep(7 * time.Millisecond)
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				tb.Write([]byte("w"))
+			}()
+		}
+		wg.Wait()
+		<-replayed
+		tb.Close()
+
+		if s := out.String(); s != "arrrrwwww" {
+			t.Errorf("single writer %v: written: %q, expected: %q", single, s, "arrrrwwww")
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package syncio
+
+import "time"
+
+// StatsReporter receives the periodic stats of the Buffers and readers it's set on, so the
+// reads and writes share a single reporting pipeline. The stats are only valid during the
+// call, which is done from the reporting goroutine of every Buffer or reader.
+type StatsReporter interface {
+	ReportBuffer(name string, s *Stats)
+	ReportReader(name string, s *ReaderStats)
+}
+
+// SetStatsReporter reports the Buffer stats to r every interval with the given name, and once
+// more when the Buffer is closed, before Close returns. Without interval they are only reported
+// on close. It starts a goroutine even with SetSynchronousMode.
+func SetStatsReporter(r StatsReporter, name string, interval time.Duration) BufferOption {
+	return func(b *Buffer) {
+		b.reporter = &statsReporter{r: r, name: name, interval: interval, done: make(chan struct{})}
+	}
+}
+
+type statsReporter struct {
+	r        StatsReporter
+	name     string
+	interval time.Duration
+	// done is closed after the last report
+	done chan struct{}
+}
+
+// run calls fn every interval until done is closed, and once more after it
+func (sr *statsReporter) run(clock Clock, done <-chan struct{}, fn func()) {
+	defer close(sr.done)
+	var c <-chan time.Time
+	if sr.interval > 0 {
+		var stop func()
+		c, stop = clock.NewTicker(sr.interval)
+		defer stop()
+	}
+	for {
+		select {
+		case <-done:
+			fn()
+			return
+		case <-c:
+			fn()
+		}
+	}
+}
+
+// waitReport waits for the last report once the Buffer is closed
+func (tb *Buffer) waitReport() {
+	if tb.reporter != nil {
+		<-tb.reporter.done
+	}
+}
+
+func (tb *Buffer) reportLoop() {
+	var s Stats
+	tb.reporter.run(tb.clock, tb.done, func() {
+		tb.StatsInto(&s)
+		tb.reporter.r.ReportBuffer(tb.reporter.name, &s)
+	})
+}
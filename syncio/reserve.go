@@ -0,0 +1,154 @@
+package syncio
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/travelgateX/go-io/syncio/internal"
+)
+
+// ErrReservation is returned by a Commit without Reserve or of more bytes than reserved, the
+// reservation is released without writing
+var ErrReservation = errors.New("commit without a matching reserve")
+
+// reservation is the space handed out by Reserve, bufmu is held until Commit. The space is in
+// the active buffer unless owned, when it's allocated for a write that doesn't fit, and it's
+// discarded on commit when the overflow policies dropped the write.
+type reservation struct {
+	p       []byte
+	owned   bool
+	discard bool
+	dropped *internal.Buffer
+	// sw and bsw are the flushes done by Reserve and the backlog policy
+	sw  swap
+	bsw swap
+}
+
+// WriteOwned is Write handing the ownership of p over to the Buffer, which keeps it until it's
+// flushed so the caller must not modify or reuse it. p is enqueued without copying it when it
+// doesn't fit in the active buffer, it's copied otherwise as the small writes are cheaper to
+// copy than to flush apart. It's meant for the slices allocated by the caller for every write,
+// Reserve fills the active buffer in place instead.
+func (tb *Buffer) WriteOwned(p []byte) (int, error) {
+	if !tb.initialized() {
+		return 0, ErrNotInitialized
+	}
+	if len(p) < smallWrite {
+		return tb.Write(p)
+	}
+	if tb.selfWrite(p) {
+		return 0, ErrSelfWrite
+	}
+	if tb.writeSizes {
+		tb.stats.WriteSizes.observe(len(p))
+	}
+	return tb.writeSlow(nil, p, &tb.stats.CallerWrites, p)
+}
+
+// Reserve returns n bytes of the active buffer to be filled in place and written by Commit,
+// saving the copy of Write, e.g. to encode a record directly into the Buffer. It waits for
+// space as Write; a reservation of the buffer size or more is allocated and enqueued apart on
+// commit. The Buffer stays locked until Commit, which must be called by the same goroutine
+// as soon as the data is in place, without calling any other method of the Buffer meanwhile.
+// The returned slice must not be used after Commit. Reserve locks as the Writes that don't
+// fit the lock free path, so the small Writes of data already in a slice are cheaper, see
+// BenchmarkReserveCommit.
+func (tb *Buffer) Reserve(n int) ([]byte, error) {
+	if !tb.initialized() {
+		return nil, ErrNotInitialized
+	}
+	if n < 0 {
+		n = 0
+	}
+	var watch spaceWatch
+	defer watch.stop()
+	var r reservation
+	tb.bufmu.Lock()
+	if ok, err := tb.admit(nil, n, &watch, &r.dropped, &r.bsw); !ok {
+		if err != nil {
+			if r.dropped != nil {
+				tb.putBuffer(r.dropped)
+			}
+			return nil, err
+		}
+		// the data is filled and discarded
+		r.discard = true
+		tb.bufmu.Lock()
+	}
+	tb.own()
+	if !r.discard && n < tb.bufSize && n > tb.buf.Available() {
+		r.sw = tb.flush(TriggerSize, nil)
+		tb.flushedBetweenTicks = true
+	}
+	// after the flush it doesn't fit only with the bytes carried by SetUTF8Boundaries
+	if r.discard || n >= tb.bufSize || n > tb.buf.Available() {
+		r.p, r.owned = make([]byte, n), true
+	} else {
+		off := tb.buf.Buffered()
+		r.p = tb.buf.Scratch()[off : off+n : off+n]
+	}
+	tb.reserved = r
+	tb.reserving.Store(true)
+	return r.p, nil
+}
+
+// Commit writes the first n bytes of the space returned by Reserve and unlocks the Buffer,
+// Commit(0) releases the reservation without writing. It counts as a Write in the Stats.
+func (tb *Buffer) Commit(n int) error {
+	if !tb.initialized() {
+		return ErrNotInitialized
+	}
+	if !tb.reserving.Load() {
+		return ErrReservation
+	}
+	r := tb.reserved
+	tb.reserved = reservation{}
+	tb.reserving.Store(false)
+	var err error
+	if n < 0 || n > len(r.p) {
+		n, err = 0, ErrReservation
+	}
+
+	if n > 0 && !r.discard && tb.wal != nil {
+		if werr := tb.appendWAL(r.p[:n]); werr != nil {
+			n, err = 0, werr
+		}
+	}
+
+	var sw swap
+	if n > 0 && !r.discard {
+		p := r.p[:n]
+		if tb.journal != nil {
+			tb.journal.write(p)
+		}
+		tb.acct.accept(n)
+		if r.owned {
+			// the buffered data goes first to keep the order
+			sw = tb.flush(TriggerSize, nil)
+			tb.enqueue(&batch{p: tb.oversized(p, p), trigger: TriggerSize})
+		} else {
+			if tb.backlog != nil && tb.buf.Buffered() == 0 {
+				tb.backlog.stamp(tb)
+			}
+			tb.buf.SetBuffered(tb.buf.Buffered() + n)
+			sw = tb.appended(sw)
+		}
+	}
+	tb.unlockBuf()
+	if r.dropped != nil {
+		tb.putBuffer(r.dropped)
+	}
+	if n > 0 || r.discard {
+		if tb.writeSizes {
+			tb.stats.WriteSizes.observe(n)
+		}
+		atomic.AddInt64(&tb.stats.CallerWrites, 1)
+	}
+	if tb.logger != nil {
+		tb.logSwap(r.bsw)
+		tb.logSwap(r.sw)
+		tb.logSwap(sw)
+	}
+	tb.flushInline()
+	return err
+}
@@ -0,0 +1,145 @@
+package syncio
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestReserveCommit(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		sink := &lockedBuffer{}
+		tb := NewBuffer(sink, append([]BufferOption{SetBufferSize(8)}, mode...)...)
+		tb.Write([]byte("ab"))
+		p, err := tb.Reserve(3)
+		if err != nil || len(p) != 3 {
+			t.Fatalf("reserve: %v, %v", len(p), err)
+		}
+		copy(p, "cde")
+		if err := tb.Commit(3); err != nil {
+			t.Fatal(err)
+		}
+		// doesn't fit, the buffered data is flushed first
+		p, _ = tb.Reserve(5)
+		copy(p, "fg")
+		tb.Commit(2)
+		// bigger than the buffer, allocated and enqueued apart
+		p, _ = tb.Reserve(20)
+		copy(p, "0123456789abcdefghij")
+		tb.Commit(20)
+		// released without writing
+		tb.Reserve(4)
+		tb.Commit(0)
+		tb.Write([]byte("z"))
+		if err := tb.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if s := sink.String(); s != "abcdefg0123456789abcdefghijz" {
+			t.Errorf("written: %q", s)
+		}
+		if s := tb.Stats(); s.CallerWrites != 5 {
+			t.Errorf("caller writes: %v, expected: 5", s.CallerWrites)
+		}
+	})
+}
+
+func TestReserveMisuse(t *testing.T) {
+	sink := &lockedBuffer{}
+	tb := NewBuffer(sink)
+	if err := tb.Commit(1); err != ErrReservation {
+		t.Errorf("commit without reserve: %v, expected: %v", err, ErrReservation)
+	}
+	p, _ := tb.Reserve(2)
+	copy(p, "ab")
+	if err := tb.Commit(3); err != ErrReservation {
+		t.Errorf("commit too large: %v, expected: %v", err, ErrReservation)
+	}
+	// the Buffer isn't locked
+	tb.Write([]byte("c"))
+	tb.Close()
+	if s := sink.String(); s != "c" {
+		t.Errorf("written: %q, expected: %q", s, "c")
+	}
+	if _, err := tb.Reserve(1); err != ErrWriteOnClosed {
+		t.Errorf("reserve on closed: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+}
+
+func TestReserveConcurrent(t *testing.T) {
+	sink := &lockedBuffer{}
+	tb := NewBuffer(sink, SetBufferSize(64))
+	writers, writes := 8, 500
+	var wg sync.WaitGroup
+	wg.Add(writers)
+	for g := 0; g < writers; g++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				if i%2 == 0 {
+					tb.Write([]byte("w\n"))
+					continue
+				}
+				p, err := tb.Reserve(16)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				n := copy(p, strconv.AppendInt(p[:0], int64(i), 10))
+				p[n] = '\n'
+				tb.Commit(n + 1)
+			}
+		}()
+	}
+	wg.Wait()
+	tb.Close()
+	if n := bytes.Count([]byte(sink.String()), []byte("\n")); n != writers*writes {
+		t.Errorf("lines: %v, expected: %v", n, writers*writes)
+	}
+}
+
+func TestReserveAllocs(t *testing.T) {
+	tb := NewBuffer(&testWriter{}, SetBufferSize(1<<20))
+	defer tb.Close()
+	n := testing.AllocsPerRun(1000, func() {
+		p, _ := tb.Reserve(8)
+		copy(p, "abcdefgh")
+		tb.Commit(8)
+	})
+	if n != 0 {
+		t.Errorf("allocs: %v, expected: 0", n)
+	}
+}
+
+func TestWriteOwned(t *testing.T) {
+	sw := &sinkWriter{}
+	tb := NewBuffer(sw, SetBufferSize(128), SetSynchronousMode(true))
+	small := []byte("small")
+	tb.WriteOwned(small)
+	large := bytes.Repeat([]byte("x"), 124)
+	tb.WriteOwned(large)
+	tb.Write([]byte("end"))
+	tb.Close()
+
+	if s := sw.out.String(); s != "small"+string(large)+"end" {
+		t.Errorf("written: %q", s)
+	}
+	// the large slice doesn't fit with the small one and is written without copying it
+	if len(sw.addrs) != 3 || sw.addrs[1] != &large[0] || sw.addrs[0] == &small[0] {
+		t.Errorf("owned slices: %v writes, large not copied %v", len(sw.addrs), len(sw.addrs) > 1 && sw.addrs[1] == &large[0])
+	}
+	if s := tb.Stats(); s.CallerWrites != 3 {
+		t.Errorf("caller writes: %v, expected: 3", s.CallerWrites)
+	}
+}
+
+func BenchmarkReserveCommit(b *testing.B) {
+	tb := NewBuffer(&testWriter{}, SetBufferSize(64*1024))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p, _ := tb.Reserve(20)
+		tb.Commit(len(strconv.AppendInt(p[:0], int64(i), 10)))
+	}
+	tb.Close()
+}
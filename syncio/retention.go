@@ -0,0 +1,142 @@
+package syncio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/travelgateX/go-io/syncio/internal"
+)
+
+// ErrEvicted is returned when reading retained data that is not in the retention window anymore
+var ErrEvicted = errors.New("data evicted from the retention window")
+
+// EvictedError is the error of the reads out of the retention window, it matches ErrEvicted
+type EvictedError struct {
+	// Start and End are the offsets of the retained data when the read was done
+	Start, End int64
+}
+
+func (e *EvictedError) Error() string {
+	return fmt.Sprintf("%v: retained [%v, %v)", ErrEvicted, e.Start, e.End)
+}
+
+// Unwrap returns ErrEvicted
+func (e *EvictedError) Unwrap() error {
+	return ErrEvicted
+}
+
+// SetRetention keeps the last n bytes written to the underlying writer, they can be read with
+// ReaderAt. It has no effect when writing into another Buffer.
+func SetRetention(n int64) BufferOption {
+	return func(b *Buffer) {
+		if n > 0 {
+			b.retention = &retention{size: n, release: b.putBuffer}
+		}
+	}
+}
+
+// ReaderAt returns a reader of the retained data, the offsets are positions in the stream
+// of bytes flushed by the Buffer, as FlushError.Offset. Reading before the retention window
+// returns an *EvictedError and reading past the data written returns io.EOF.
+func (tb *Buffer) ReaderAt() io.ReaderAt {
+	if tb.retention == nil {
+		return &retention{}
+	}
+	return tb.retention
+}
+
+// retention keeps the bytes written at the stream offsets [start, end) in segments, the
+// segments of pooled buffers hold a reference instead of a copy when the data fills half of
+// the buffer, so the memory is bounded to twice the window
+type retention struct {
+	mu         sync.RWMutex
+	size       int64
+	segs       []segment
+	start, end int64
+	release    func(*internal.Buffer)
+}
+
+// segment is the data written at off, buf is the pooled buffer holding it or nil
+type segment struct {
+	off int64
+	p   []byte
+	buf *internal.Buffer
+}
+
+// add retains the bytes written at off, buf is the pooled buffer holding p or nil if p is
+// owned by the caller, owned tells if p can be retained without copying. The window
+// restarts after a gap of data not written.
+func (r *retention) add(off int64, p []byte, buf *internal.Buffer, owned bool) {
+	if int64(len(p)) > r.size {
+		off += int64(len(p)) - r.size
+		p = p[int64(len(p))-r.size:]
+	}
+	if buf != nil && 2*len(p) >= buf.Cap() {
+		buf.Retain()
+	} else {
+		buf = nil
+		if !owned {
+			p = append([]byte(nil), p...)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if off != r.end {
+		r.evict(len(r.segs))
+		r.start, r.end = off, off
+	}
+	r.segs = append(r.segs, segment{off: off, p: p, buf: buf})
+	r.end += int64(len(p))
+	if r.end-r.start > r.size {
+		r.start = r.end - r.size
+	}
+	k := 0
+	for k < len(r.segs) && r.segs[k].off+int64(len(r.segs[k].p)) <= r.start {
+		k++
+	}
+	r.evict(k)
+}
+
+// evict drops the first k segments, the caller must hold mu
+func (r *retention) evict(k int) {
+	for i := range r.segs[:k] {
+		if r.segs[i].buf != nil {
+			r.release(r.segs[i].buf)
+		}
+		r.segs[i] = segment{}
+	}
+	r.segs = append(r.segs[:0], r.segs[k:]...)
+}
+
+func (r *retention) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if off < r.start || r.size == 0 {
+		return 0, &EvictedError{Start: r.start, End: r.end}
+	}
+	n := 0
+	for _, s := range r.segs {
+		if n == len(p) {
+			break
+		}
+		if end := s.off + int64(len(s.p)); off < end {
+			c := copy(p[n:], s.p[off-s.off:])
+			n += c
+			off += int64(c)
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// retained returns the size of the retention window
+func (r *retention) retained() int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.end - r.start
+}
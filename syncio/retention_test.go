@@ -0,0 +1,101 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+func TestRetention(t *testing.T) {
+	tb := NewBuffer(&testWriter{}, SetBufferSize(4), SetRetention(10))
+	for _, w := range []string{"abc", "def", "ghi", "jkl", "mno"} {
+		tb.Write([]byte(w))
+		tb.Flush()
+	}
+	// the window is [5, 15)
+	ra := tb.ReaderAt()
+	p := make([]byte, 4)
+	if n, err := ra.ReadAt(p, 9); n != 4 || err != nil || string(p) != "jklm" {
+		t.Errorf("read at 9: %q, %v", p[:n], err)
+	}
+	if n, err := ra.ReadAt(p, 12); n != 3 || err != io.EOF || string(p[:n]) != "mno" {
+		t.Errorf("read at 12: %q, %v, expected: %q, EOF", p[:n], err, "mno")
+	}
+	full := make([]byte, 10)
+	if n, err := ra.ReadAt(full, 5); n != 10 || err != nil || string(full) != "fghijklmno" {
+		t.Errorf("read the window: %q, %v", full[:n], err)
+	}
+
+	_, err := ra.ReadAt(p, 4)
+	var eerr *EvictedError
+	if !errors.As(err, &eerr) || !errors.Is(err, ErrEvicted) || eerr.Start != 5 || eerr.End != 15 {
+		t.Errorf("read at 4: %v, expected evicted with the window [5, 15)", err)
+	}
+	if s := tb.Stats(); s.RetainedBytes != 10 {
+		t.Errorf("retained bytes: %v, expected: 10", s.RetainedBytes)
+	}
+	tb.Close()
+}
+
+func TestRetentionGap(t *testing.T) {
+	fw := synctest.NewFailingWriter(nil, nil)
+	fw.SetFailing(false)
+	tb := NewBuffer(fw, SetRetention(100))
+	tb.Write([]byte("abc"))
+	tb.Flush()
+	fw.SetFailing(true)
+	tb.Write([]byte("def"))
+	tb.Flush()
+	fw.SetFailing(false)
+	tb.Write([]byte("ghi"))
+	tb.Close()
+
+	// the lost bytes restart the window
+	p := make([]byte, 3)
+	if n, err := tb.ReaderAt().ReadAt(p, 6); n != 3 || err != nil || string(p) != "ghi" {
+		t.Errorf("read at 6: %q, %v", p[:n], err)
+	}
+	if _, err := tb.ReaderAt().ReadAt(p, 0); !errors.Is(err, ErrEvicted) {
+		t.Errorf("read at 0: %v, expected: %v", err, ErrEvicted)
+	}
+}
+
+func TestRetentionSharesBuffers(t *testing.T) {
+	tw := &testWriter{}
+	tb := NewBuffer(tw, SetBufferSize(16), SetRetention(40))
+	defer tb.Close()
+	full := bytes.Repeat([]byte("x"), 12)
+	for i := 0; i < 10; i++ {
+		full[0] = byte('0' + i)
+		tb.Write(full)
+		tb.Flush()
+	}
+	tb.Write([]byte("end"))
+	tb.Flush()
+
+	// the batches filling half of the buffer are held, the small ones copied
+	r := tb.retention
+	r.mu.RLock()
+	held := 0
+	for _, s := range r.segs {
+		if s.buf != nil {
+			held++
+		}
+	}
+	segs := len(r.segs)
+	r.mu.RUnlock()
+	if segs != 5 || held != 4 {
+		t.Errorf("segments: %v, held buffers: %v, expected: 5 and 4", segs, held)
+	}
+	p := make([]byte, 40)
+	if n, err := tb.ReaderAt().ReadAt(p, 83); n != 40 || err != nil || string(p[1:2]) != "7" || string(p[37:]) != "end" {
+		t.Errorf("read at 83: %q, %v", p[:n], err)
+	}
+	// the evicted buffers are reused
+	if s := tb.Stats(); s.BufferAllocs > 6 {
+		t.Errorf("buffer allocs: %v", s.BufferAllocs)
+	}
+}
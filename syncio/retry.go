@@ -0,0 +1,86 @@
+package syncio
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BackoffFunc returns the delay before the retry number attempt of a flush, from 1
+type BackoffFunc func(attempt int) time.Duration
+
+// ExponentialBackoff returns a BackoffFunc waiting base before the first retry and doubling
+// the delay up to max
+func ExponentialBackoff(base, max time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// SetRetryPolicy retries a failed flush to the underlying writer up to maxRetries times,
+// waiting backoff(attempt) before every retry, ExponentialBackoff(100ms, 10s) when it's nil.
+// Only the flushes that wrote nothing are retried so the writer never receives the same bytes
+// twice, the panics recovered aren't retried. The error of the last attempt is reported as
+// usual with FlushError.Attempt, Stats.Retries counts the retries. The flush goroutine waits
+// between the attempts, Close waits for them and CloseTimeout stops them at the deadline.
+func SetRetryPolicy(maxRetries int, backoff BackoffFunc) BufferOption {
+	return func(b *Buffer) {
+		if maxRetries <= 0 {
+			b.retry = nil
+			return
+		}
+		if backoff == nil {
+			backoff = ExponentialBackoff(100*time.Millisecond, 10*time.Second)
+		}
+		b.retry = &retryPolicy{max: maxRetries, backoff: backoff, abort: make(chan struct{})}
+	}
+}
+
+// retryPolicy is the state of SetRetryPolicy, abort ends the waits once closed
+type retryPolicy struct {
+	max       int
+	backoff   BackoffFunc
+	abort     chan struct{}
+	abortOnce sync.Once
+}
+
+// wait waits before the next attempt of a flush that failed with err after attempt attempts,
+// it returns false if the flush isn't retried
+func (r *retryPolicy) wait(tb *Buffer, attempt int, err error) bool {
+	if attempt > r.max {
+		return false
+	}
+	if _, ok := err.(*PanicError); ok {
+		return false
+	}
+	select {
+	case <-r.abort:
+		return false
+	default:
+	}
+	if d := r.backoff(attempt); d > 0 {
+		c, stop := tb.clock.NewTicker(d)
+		defer stop()
+		select {
+		case <-c:
+		case <-r.abort:
+			return false
+		}
+	}
+	atomic.AddInt64(&tb.stats.Retries, 1)
+	return true
+}
+
+// stop ends the retries in progress and the next ones
+func (r *retryPolicy) stop() {
+	r.abortOnce.Do(func() {
+		close(r.abort)
+	})
+}
@@ -0,0 +1,84 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var out bytes.Buffer
+	fw := synctest.NewFailingWriter(&out, nil)
+	var backoffs []int
+	tb := NewBuffer(fw, SetRetryPolicy(3, func(attempt int) time.Duration {
+		backoffs = append(backoffs, attempt)
+		if attempt == 2 {
+			fw.SetFailing(false)
+		}
+		return 0
+	}))
+	tb.Write([]byte("abc"))
+	if err := tb.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if out.String() != "abc" || fw.Calls() != 3 {
+		t.Errorf("written: %q in %v calls, expected: %q in 3", out.String(), fw.Calls(), "abc")
+	}
+	if len(backoffs) != 2 || backoffs[0] != 1 || backoffs[1] != 2 {
+		t.Errorf("backoff attempts: %v, expected: [1 2]", backoffs)
+	}
+	if s := tb.Stats(); s.Retries != 2 || s.FlushErrors != 0 {
+		t.Errorf("retries: %v, flush errors: %v, expected 2 and 0", s.Retries, s.FlushErrors)
+	}
+}
+
+func TestRetryPolicyExhausted(t *testing.T) {
+	fw := synctest.NewFailingWriter(nil, nil)
+	tb := NewBuffer(fw, SetRetryPolicy(2, func(int) time.Duration { return time.Millisecond }))
+	tb.Write([]byte("abc"))
+	var ferr *FlushError
+	if err := tb.Close(); !errors.As(err, &ferr) || ferr.Attempt != 3 {
+		t.Fatalf("close: %v, expected a flush error after 3 attempts", err)
+	}
+	if s := tb.Stats(); s.Retries != 2 || fw.Calls() != 3 {
+		t.Errorf("retries: %v, calls: %v, expected 2 and 3", s.Retries, fw.Calls())
+	}
+}
+
+func TestRetryPolicyPartial(t *testing.T) {
+	fw := synctest.NewFailingWriter(nil, nil)
+	fw.Partial = 1
+	tb := NewBuffer(fw, SetRetryPolicy(2, nil))
+	tb.Write([]byte("abc"))
+	tb.Close()
+	// the bytes written aren't sent again
+	if s := tb.Stats(); s.Retries != 0 || fw.Calls() != 1 {
+		t.Errorf("retries: %v, calls: %v, expected 0 and 1", s.Retries, fw.Calls())
+	}
+}
+
+func TestRetryPolicyCloseTimeout(t *testing.T) {
+	fw := synctest.NewFailingWriter(nil, nil)
+	tb := NewBuffer(fw, SetRetryPolicy(5, func(int) time.Duration { return time.Hour }))
+	tb.Write([]byte("abc"))
+	start := time.Now()
+	tb.CloseTimeout(20 * time.Millisecond)
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("close timeout took %v with the retry waiting", d)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff(time.Second, 5*time.Second)
+	for attempt, expected := range []time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if expected == 0 {
+			continue
+		}
+		if d := b(attempt); d != expected {
+			t.Errorf("attempt %v: %v, expected: %v", attempt, d, expected)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+// Package rotate is a file sink rolling over by size and age, meant to be the underlying
+// writer of a syncio.Buffer: the rolled over files are renamed with a timestamp, optionally
+// gzipped and pruned by count and age in background.
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by the writes of a closed Writer
+var ErrClosed = errors.New("rotate: write on closed writer")
+
+// timeLayout is the timestamp of the backup names, it sorts as the times
+const timeLayout = "20060102T150405.000"
+
+// Option is an option of NewWriter
+type Option func(*Writer)
+
+// SetMaxBackups keeps at most n rolled over files, all of them by default
+func SetMaxBackups(n int) Option {
+	return func(w *Writer) {
+		w.maxBackups = n
+	}
+}
+
+// SetMaxBackupAge removes the rolled over files older than d, none by default
+func SetMaxBackupAge(d time.Duration) Option {
+	return func(w *Writer) {
+		w.maxBackupAge = d
+	}
+}
+
+// SetFileMode sets the permissions of the new files, 0644 by default
+func SetFileMode(mode os.FileMode) Option {
+	return func(w *Writer) {
+		w.mode = mode
+	}
+}
+
+// SetTimeFunc sets the time source of the rollovers and the backup names, time.Now by
+// default, it's meant for the tests
+func SetTimeFunc(now func() time.Time) Option {
+	return func(w *Writer) {
+		w.now = now
+	}
+}
+
+// Writer is an io.WriteCloser appending to a file that is rolled over once a write would
+// exceed the max size or the file is older than the max age. The rolled over file is renamed
+// to the name of the file with the time of the rollover before the extension, e.g.
+// "app-20260102T150405.000.log", and the new writes go to a new file with the original name.
+// A single write is never split across files, so the records of a syncio.Buffer with
+// SetRecordMode aren't either, and a write bigger than the max size fills a file alone.
+// It's safe for concurrent use.
+type Writer struct {
+	path         string
+	maxSize      int64
+	maxAge       time.Duration
+	compress     bool
+	maxBackups   int
+	maxBackupAge time.Duration
+	mode         os.FileMode
+	now          func() time.Time
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+	closed bool
+
+	// mill wakes up the goroutine compressing and pruning the backups, millErr is its first
+	// error
+	mill     chan struct{}
+	millDone chan struct{}
+	millMu   sync.Mutex
+	millErr  error
+}
+
+// NewWriter opens the file at path to append to it, creating it and its directory if needed.
+// The file is rolled over before a write would make it bigger than maxSize bytes, and once
+// it's maxAge old, timed from its opening; 0 disables each rollover. With compress the
+// rolled over files are gzipped, adding ".gz" to their name.
+func NewWriter(path string, maxSize int64, maxAge time.Duration, compress bool, options ...Option) (*Writer, error) {
+	w := &Writer{
+		path:     path,
+		maxSize:  maxSize,
+		maxAge:   maxAge,
+		compress: compress,
+		mode:     0644,
+		now:      time.Now,
+		mill:     make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+	for _, o := range options {
+		o(w)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	go w.runMill()
+	// compresses and prunes the backups left by a previous run
+	w.mill <- struct{}{}
+	return w, nil
+}
+
+// open opens the file at path, the caller holds mu but in NewWriter
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, w.mode)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f, w.size, w.opened = f, info.Size(), w.now()
+	return nil
+}
+
+// Write appends p to the file, rolling it over first if needed
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.size > 0 && (w.maxSize > 0 && w.size+int64(len(p)) > w.maxSize ||
+		w.maxAge > 0 && w.now().Sub(w.opened) >= w.maxAge) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate rolls the file over now, e.g. on SIGHUP, an empty file is rolled over too
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	return w.rotate()
+}
+
+// rotate renames the file to its backup name and opens a new one, the caller holds mu
+func (w *Writer) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	t := w.now()
+	name := w.backupName(t)
+	// a rollover in the same millisecond takes the next free one
+	for exists(name) || exists(name+".gz") {
+		t = t.Add(time.Millisecond)
+		name = w.backupName(t)
+	}
+	if err := os.Rename(w.path, name); err != nil {
+		// keeps writing to the same file
+		if oerr := w.open(); oerr != nil {
+			return oerr
+		}
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	select {
+	case w.mill <- struct{}{}:
+	default:
+		// the pending run sees this backup too
+	}
+	return nil
+}
+
+// Sync commits the file to stable storage, so syncio.Buffer.Sync reaches the disk
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	return w.f.Sync()
+}
+
+// Close closes the file and waits for the backups being compressed and pruned, the returned
+// error is the close error or else the first one of the compressions and prunes
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.closed = true
+	err := w.f.Close()
+	close(w.mill)
+	w.mu.Unlock()
+
+	<-w.millDone
+	if err == nil {
+		w.millMu.Lock()
+		err = w.millErr
+		w.millMu.Unlock()
+	}
+	return err
+}
+
+// backupName returns the name of the file rolled over at t
+func (w *Writer) backupName(t time.Time) string {
+	ext := filepath.Ext(w.path)
+	return strings.TrimSuffix(w.path, ext) + "-" + t.UTC().Format(timeLayout) + ext
+}
+
+// backup is a rolled over file, t is the time of the rollover
+type backup struct {
+	name string
+	t    time.Time
+}
+
+// backups returns the rolled over files, the newest first
+func (w *Writer) backups() ([]backup, error) {
+	entries, err := os.ReadDir(filepath.Dir(w.path))
+	if err != nil {
+		return nil, err
+	}
+	ext := filepath.Ext(w.path)
+	prefix := strings.TrimSuffix(filepath.Base(w.path), ext) + "-"
+	var list []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		t, err := time.Parse(timeLayout, ts)
+		if err != nil {
+			// not a backup of this writer
+			continue
+		}
+		list = append(list, backup{name: filepath.Join(filepath.Dir(w.path), name), t: t})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].t.After(list[j].t) })
+	return list, nil
+}
+
+// runMill compresses and prunes the backups every time it's woken up until Close
+func (w *Writer) runMill() {
+	defer close(w.millDone)
+	for range w.mill {
+		if err := w.millOnce(); err != nil {
+			w.millMu.Lock()
+			if w.millErr == nil {
+				w.millErr = err
+			}
+			w.millMu.Unlock()
+		}
+	}
+}
+
+// millOnce removes the backups beyond the max count and age, and compresses the rest
+func (w *Writer) millOnce() error {
+	list, err := w.backups()
+	if err != nil {
+		return err
+	}
+	var first error
+	keep := func(err error) {
+		if first == nil {
+			first = err
+		}
+	}
+	now := w.now()
+	for i, b := range list {
+		if w.maxBackups > 0 && i >= w.maxBackups || w.maxBackupAge > 0 && now.Sub(b.t) > w.maxBackupAge {
+			if err := os.Remove(b.name); err != nil && !os.IsNotExist(err) {
+				keep(err)
+			}
+			continue
+		}
+		if w.compress && !strings.HasSuffix(b.name, ".gz") {
+			if err := compressFile(b.name, w.mode); err != nil {
+				keep(err)
+			}
+		}
+	}
+	return first
+}
+
+// compressFile gzips the file name into name.gz and removes it, a partial name.gz is removed
+// on error
+func compressFile(name string, mode os.FileMode) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(name + ".gz")
+		}
+	}()
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(name)
+}
+
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
@@ -0,0 +1,242 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+// fakeTime is a time source advanced by the tests
+type fakeTime struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (f *fakeTime) now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.t
+}
+
+func (f *fakeTime) advance(d time.Duration) {
+	f.mu.Lock()
+	f.t = f.t.Add(d)
+	f.mu.Unlock()
+}
+
+// readDir returns the contents of the files of dir by name, the gzipped ones decompressed
+func readDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string, len(entries))
+	for _, e := range entries {
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = f
+		if strings.HasSuffix(e.Name(), ".gz") {
+			if r, err = gzip.NewReader(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data, err := io.ReadAll(r)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[e.Name()] = string(data)
+	}
+	return files
+}
+
+func names(files map[string]string) []string {
+	list := make([]string, 0, len(files))
+	for name := range files {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func TestRotateSize(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeTime{t: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)}
+	w, err := NewWriter(filepath.Join(dir, "app.log"), 10, 0, false, SetTimeFunc(clock.now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("0123"))
+	w.Write([]byte("4567"))
+	clock.advance(time.Second)
+	// doesn't fit, rolls over
+	w.Write([]byte("89ab"))
+	// bigger than the max size, fills a file alone
+	w.Write([]byte("a record longer than the max size"))
+	w.Write([]byte("cd"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readDir(t, dir)
+	expected := map[string]string{
+		"app-20260102T150406.000.log": "01234567",
+		"app-20260102T150406.001.log": "89ab",
+		"app-20260102T150406.002.log": "a record longer than the max size",
+		"app.log":                     "cd",
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("files: %v, expected: %v", names(files), names(expected))
+	}
+	for name, data := range expected {
+		if files[name] != data {
+			t.Errorf("%v: %q, expected: %q", name, files[name], data)
+		}
+	}
+	if _, err := w.Write([]byte("x")); err != ErrClosed {
+		t.Errorf("write on closed: %v, expected: %v", err, ErrClosed)
+	}
+}
+
+func TestRotateAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app")
+	// appends to the existing file
+	os.WriteFile(path, []byte("old "), 0644)
+	clock := &fakeTime{t: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)}
+	w, err := NewWriter(path, 0, time.Hour, false, SetTimeFunc(clock.now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("a"))
+	clock.advance(59 * time.Minute)
+	w.Write([]byte("b"))
+	clock.advance(time.Minute)
+	w.Write([]byte("c"))
+	clock.advance(time.Hour)
+	w.Write([]byte("d"))
+	w.Close()
+
+	files := readDir(t, dir)
+	expected := map[string]string{
+		"app-20260102T010000.000": "old ab",
+		"app-20260102T020000.000": "c",
+		"app":                     "d",
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("files: %v, expected: %v", names(files), names(expected))
+	}
+	for name, data := range expected {
+		if files[name] != data {
+			t.Errorf("%v: %q, expected: %q", name, files[name], data)
+		}
+	}
+}
+
+func TestRotateCompressPrune(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	// an uncompressed backup left by a previous run, other files are not touched
+	os.WriteFile(filepath.Join(dir, "app-20260101T000000.000.log"), []byte("previous"), 0644)
+	os.WriteFile(filepath.Join(dir, "app-other.log"), []byte("other"), 0644)
+	clock := &fakeTime{t: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)}
+	w, err := NewWriter(path, 0, 0, true, SetTimeFunc(clock.now), SetMaxBackups(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"first", "second", "third"} {
+		w.Write([]byte(s))
+		clock.advance(time.Second)
+		if err := w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Write([]byte("current"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readDir(t, dir)
+	expected := map[string]string{
+		"app-20260102T000002.000.log.gz": "second",
+		"app-20260102T000003.000.log.gz": "third",
+		"app-other.log":                  "other",
+		"app.log":                        "current",
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("files: %v, expected: %v", names(files), names(expected))
+	}
+	for name, data := range expected {
+		if files[name] != data {
+			t.Errorf("%v: %q, expected: %q", name, files[name], data)
+		}
+	}
+
+	// the age prune
+	clock.advance(24 * time.Hour)
+	w, err = NewWriter(path, 0, 0, true, SetTimeFunc(clock.now), SetMaxBackupAge(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if files := readDir(t, dir); len(files) != 2 || files["app.log"] != "current" {
+		t.Errorf("files after the age prune: %v", names(files))
+	}
+}
+
+func TestRotateUnderBuffer(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(filepath.Join(dir, "events.log"), 64, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb := syncio.NewBuffer(w, syncio.SetBufferSize(32), syncio.SetRecordMode(true))
+	var want strings.Builder
+	for i := 0; i < 100; i++ {
+		line := strings.Repeat(string(rune('a'+i%26)), 1+i%10) + "\n"
+		tb.Write([]byte(line))
+		want.WriteString(line)
+	}
+	if err := tb.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readDir(t, dir)
+	list := names(files)
+	if len(list) < 3 {
+		t.Fatalf("not rolled over: %v", list)
+	}
+	// the backups sort before the current file, in order of rollover
+	var got strings.Builder
+	for _, name := range list[:len(list)-1] {
+		data := files[name]
+		if len(data) > 64 {
+			t.Errorf("%v: %v bytes, more than the max size", name, len(data))
+		}
+		if !strings.HasSuffix(data, "\n") {
+			t.Errorf("%v: record split: %q", name, data)
+		}
+		got.WriteString(data)
+	}
+	got.WriteString(files["events.log"])
+	if got.String() != want.String() {
+		t.Errorf("data: %q, expected: %q", got.String(), want.String())
+	}
+}
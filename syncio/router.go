@@ -0,0 +1,175 @@
+package syncio
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// Route is a destination of a SplitRoutedWriter
+type Route int
+
+const (
+	RouteA Route = iota // the first writer of SplitRouter, e.g. the current sink
+	RouteB              // the second writer of SplitRouter, e.g. the new sink
+)
+
+var routeNames = []string{"a", "b"}
+
+func (r Route) String() string {
+	return enumString(routeNames, int(r))
+}
+
+func (r Route) MarshalText() ([]byte, error) {
+	return enumMarshal(routeNames, int(r), "Route")
+}
+
+func (r *Route) UnmarshalText(text []byte) error {
+	return enumUnmarshal(routeNames, text, "Route", (*int)(r))
+}
+
+// RouteError is the error of a destination of a SplitRoutedWriter, it wraps the writer error
+type RouteError struct {
+	Route Route
+	Err   error
+}
+
+func (e *RouteError) Error() string {
+	return fmt.Sprintf("route %v: %v", e.Route, e.Err)
+}
+
+// Unwrap returns the writer error
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// RouteStats are the counters of a destination of a SplitRoutedWriter
+type RouteStats struct {
+	// Flushes is the number of batches routed to the destination, Writes the calls to its
+	// writer and Bytes the bytes it accepted
+	Flushes int64
+	Writes  int64
+	Bytes   int64
+	// Errors is the number of writes that failed
+	Errors int64
+}
+
+// batchRouter is implemented by the writers routing whole batches, the Buffer calls
+// beginBatch before writing every batch with data
+type batchRouter interface {
+	beginBatch()
+}
+
+// SplitRoutedWriter routes every batch of a Buffer to one of two writers, see SplitRouter
+type SplitRoutedWriter struct {
+	dest     [2]io.Writer
+	fraction func() float64
+
+	mu sync.Mutex
+	// route is the destination of the batch being written, batched is set once a Buffer
+	// routes its batches and partial when the last write was cut by an error
+	route   Route
+	batched bool
+	partial bool
+	rand    *rand.Rand
+	seed    uint64
+	seq     uint64
+
+	stats [2]RouteStats
+}
+
+var _ io.Writer = &SplitRoutedWriter{}
+
+// SplitRouter returns a writer routing every batch of the Buffer writing to it to a or b, a
+// batch goes to b with the probability returned by fraction at the start of the batch, so the
+// traffic is moved from a to b progressively. The batches are never split, the short writes
+// are retried on the same writer and the errors are returned in a *RouteError. fraction must
+// be safe to call concurrently with the code changing it, e.g. loading an atomic value. Used
+// by other writers, every Write is a batch.
+func SplitRouter(a, b io.Writer, fraction func() float64) *SplitRoutedWriter {
+	return &SplitRoutedWriter{
+		dest:     [2]io.Writer{a, b},
+		fraction: fraction,
+		rand:     rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+// SetDeterministic makes the routes a function of seed and the sequence number of the batch
+// instead of random, so the tests are reproducible
+func (r *SplitRoutedWriter) SetDeterministic(seed uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rand = nil
+	r.seed = seed
+}
+
+// beginBatch is called by the Buffer before writing a batch, the writes until the next
+// batch go to the same destination
+func (r *SplitRoutedWriter) beginBatch() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batched = true
+	r.route = r.pick()
+}
+
+// pick chooses the destination of a new batch, the caller must hold mu
+func (r *SplitRoutedWriter) pick() Route {
+	var x float64
+	if r.rand != nil {
+		x = r.rand.Float64()
+	} else {
+		x = float64(mix64(r.seed+r.seq)>>11) / (1 << 53)
+	}
+	r.seq++
+	route := RouteA
+	// the values out of range route to the closest writer and NaN to a
+	if f := r.fraction(); x < f {
+		route = RouteB
+	}
+	atomic.AddInt64(&r.stats[route].Flushes, 1)
+	return route
+}
+
+// mix64 is the finalizer of splitmix64
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// Write writes p to the destination of the current batch
+func (r *SplitRoutedWriter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	if !r.batched && !r.partial {
+		r.route = r.pick()
+	}
+	route := r.route
+	r.mu.Unlock()
+
+	s := &r.stats[route]
+	n, err := writeFullCount(r.dest[route], p, &s.Writes)
+	atomic.AddInt64(&s.Bytes, int64(n))
+	if err != nil {
+		atomic.AddInt64(&s.Errors, 1)
+		err = &RouteError{Route: route, Err: err}
+	}
+	r.mu.Lock()
+	// the rest of the write isn't sent to the other destination
+	r.partial = err != nil && n > 0
+	r.mu.Unlock()
+	return n, err
+}
+
+// Stats returns the counters of a destination
+func (r *SplitRoutedWriter) Stats(route Route) RouteStats {
+	s := &r.stats[route]
+	return RouteStats{
+		Flushes: atomic.LoadInt64(&s.Flushes),
+		Writes:  atomic.LoadInt64(&s.Writes),
+		Bytes:   atomic.LoadInt64(&s.Bytes),
+		Errors:  atomic.LoadInt64(&s.Errors),
+	}
+}
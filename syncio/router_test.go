@@ -0,0 +1,135 @@
+package syncio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+// routeBatches writes n batches of 8 bytes through a router in synchronous mode, it returns
+// the batches received by every destination
+func routeBatches(t *testing.T, n int, fraction float64, seed uint64) (a, b *recordingWriter, r *SplitRoutedWriter) {
+	a, b = &recordingWriter{}, &recordingWriter{}
+	r = SplitRouter(a, b, func() float64 { return fraction })
+	r.SetDeterministic(seed)
+	tb := NewBuffer(r, SetBufferSize(8), SetSynchronousMode(true))
+	for i := 0; i < n; i++ {
+		// every write fills the buffer and flushes the previous one
+		fmt.Fprintf(tb, "batch%03d", i)
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return a, b, r
+}
+
+func TestSplitRouterDeterministic(t *testing.T) {
+	a, b, r := routeBatches(t, 400, 0.25, 1)
+	if len(a.writes)+len(b.writes) != 400 {
+		t.Fatalf("%v + %v batches", len(a.writes), len(b.writes))
+	}
+	for _, w := range append(append([]string(nil), a.writes...), b.writes...) {
+		if len(w) != 8 || !strings.HasPrefix(w, "batch") {
+			t.Fatalf("batch split: %q", w)
+		}
+	}
+	if n := len(b.writes); n < 60 || n > 140 {
+		t.Fatalf("%v of 400 batches routed to b with 25%%", n)
+	}
+	sa, sb := r.Stats(RouteA), r.Stats(RouteB)
+	if sa.Flushes != int64(len(a.writes)) || sb.Flushes != int64(len(b.writes)) || sb.Bytes != int64(8*len(b.writes)) {
+		t.Fatalf("stats a %+v b %+v", sa, sb)
+	}
+
+	// the same seed routes the same batches
+	a2, b2, _ := routeBatches(t, 400, 0.25, 1)
+	if strings.Join(a.writes, "") != strings.Join(a2.writes, "") || strings.Join(b.writes, "") != strings.Join(b2.writes, "") {
+		t.Fatal("routes not reproduced with the same seed")
+	}
+}
+
+func TestSplitRouterFractionBounds(t *testing.T) {
+	for _, c := range []struct {
+		fraction float64
+		a, b     int
+	}{{0, 50, 0}, {-1, 50, 0}, {1, 0, 50}, {2, 0, 50}, {math.NaN(), 50, 0}} {
+		a, b, _ := routeBatches(t, 50, c.fraction, 7)
+		if len(a.writes) != c.a || len(b.writes) != c.b {
+			t.Errorf("fraction %v: %v batches to a and %v to b", c.fraction, len(a.writes), len(b.writes))
+		}
+	}
+}
+
+func TestSplitRouterShortWrites(t *testing.T) {
+	a, b := &shortWriter{max: 3}, &shortWriter{max: 3}
+	r := SplitRouter(a, b, func() float64 { return 0.5 })
+	tb := NewBuffer(r, SetBufferSize(10), SetSynchronousMode(true))
+	for i := 0; i < 100; i++ {
+		tb.Write([]byte("0123456789"))
+	}
+	tb.Close()
+	for _, w := range []*shortWriter{a, b} {
+		if strings.ReplaceAll(w.out.String(), "0123456789", "") != "" {
+			t.Fatalf("batch split across the routes: %q", w.out.String())
+		}
+	}
+	if a.out.Len()+b.out.Len() != 1000 {
+		t.Fatalf("%v + %v bytes", a.out.Len(), b.out.Len())
+	}
+	if r.Stats(RouteA).Writes <= r.Stats(RouteA).Flushes {
+		t.Fatalf("short writes not retried: %+v", r.Stats(RouteA))
+	}
+}
+
+func TestSplitRouterErrors(t *testing.T) {
+	fw := synctest.NewFailingWriter(nil, nil)
+	r := SplitRouter(&testWriter{}, fw, func() float64 { return 1 })
+	var routeErr *RouteError
+	tb := NewBuffer(r, SetOnFlushError(func(err *FlushError, _ []byte) {
+		errors.As(err, &routeErr)
+	}))
+	tb.Write([]byte("abc"))
+	if err := tb.Flush(); !errors.Is(err, synctest.ErrInjected) {
+		t.Fatalf("Flush: %v", err)
+	}
+	tb.Close()
+	if routeErr == nil || routeErr.Route != RouteB {
+		t.Fatalf("route error %v", routeErr)
+	}
+	if s := r.Stats(RouteB); s.Errors != 1 {
+		t.Fatalf("stats %+v", s)
+	}
+}
+
+func TestSplitRouterFractionChange(t *testing.T) {
+	var fraction atomic.Uint64
+	a, b := &lockedBuffer{}, &lockedBuffer{}
+	r := SplitRouter(a, b, func() float64 { return math.Float64frombits(fraction.Load()) })
+	tb := NewBuffer(r, SetBufferSize(16))
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for _, f := range []float64{0.05, 0.25, 1} {
+			fraction.Store(math.Float64bits(f))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			tb.Write([]byte{byte('a' + rand.Intn(26))})
+		}
+	}()
+	wg.Wait()
+	tb.Close()
+	if a.Len()+b.Len() != 1000 {
+		t.Fatalf("%v + %v bytes", a.Len(), b.Len())
+	}
+}
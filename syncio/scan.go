@@ -0,0 +1,171 @@
+package syncio
+
+import (
+	"bufio"
+	"io"
+	"sync/atomic"
+)
+
+// minScanWindow is the first size of the data of a block appended to a token spanning blocks,
+// it's doubled until the token ends
+const minScanWindow = 256
+
+// Scanner returns the tokens of the data read ahead by a Reader, as bufio.Scanner. The
+// tokens alias the prefetched blocks and are valid until the next Scan, only the tokens
+// spanning blocks are stitched in a buffer of the Scanner, up to the max token size.
+type Scanner struct {
+	r        *Reader
+	split    bufio.SplitFunc
+	maxToken int
+	// carry is the data of the previous blocks of a token spanning blocks, at the start of
+	// buf once the window of the current block is appended; window is the size appended
+	carry  []byte
+	buf    []byte
+	window int
+
+	tok  []byte
+	err  error
+	done bool
+}
+
+// Scan returns a Scanner of the tokens split by split, e.g. bufio.ScanLines. The reads of r
+// must not be mixed with the Scanner ones.
+func (r *Reader) Scan(split bufio.SplitFunc) *Scanner {
+	return &Scanner{r: r, split: split, maxToken: bufio.MaxScanTokenSize}
+}
+
+// SetMaxTokenSize sets the maximum size of a token, bufio.MaxScanTokenSize by default. Scan
+// fails with bufio.ErrTooLong once a token doesn't end within n bytes. It must be called
+// before the first Scan.
+func (s *Scanner) SetMaxTokenSize(n int) {
+	if n > 0 {
+		s.maxToken = n
+	}
+}
+
+// Scan advances to the next token, it returns false at the end of the data or on error
+func (s *Scanner) Scan() bool {
+	if s.done {
+		return false
+	}
+	if s.r.blocks == nil {
+		return s.fail(ErrNotInitialized)
+	}
+	r := s.r
+	s.tok = nil
+	empty := 0
+	for {
+		data := r.rest
+		if len(s.carry) > 0 {
+			if len(s.buf) == 0 || &s.carry[0] != &s.buf[0] {
+				// the previous tokens are no longer valid
+				s.buf = append(s.buf[:0], s.carry...)
+				s.carry = s.buf
+			}
+			if s.window > len(r.rest) {
+				s.window = len(r.rest)
+			}
+			if len(s.carry)+s.window > s.maxToken {
+				s.window = s.maxToken - len(s.carry)
+			}
+			s.buf = append(s.buf[:len(s.carry)], r.rest[:s.window]...)
+			s.carry = s.buf[:len(s.carry)]
+			data = s.buf
+		}
+		// nothing follows data
+		atEOF := r.err != nil && (len(s.carry) == 0 || s.window == len(r.rest))
+
+		if len(data) > 0 || atEOF {
+			adv, tok, err := s.split(data, atEOF)
+			if err != nil && err != bufio.ErrFinalToken {
+				return s.fail(err)
+			}
+			if adv < 0 {
+				return s.fail(bufio.ErrNegativeAdvance)
+			}
+			if adv > len(data) {
+				return s.fail(bufio.ErrAdvanceTooFar)
+			}
+			s.advance(adv)
+			if err == bufio.ErrFinalToken {
+				s.tok, s.done = tok, true
+				return tok != nil
+			}
+			if tok != nil {
+				if adv > 0 {
+					empty = 0
+				} else if empty++; empty > maxEmptyReads {
+					return s.fail(io.ErrNoProgress)
+				}
+				s.tok = tok
+				return true
+			}
+			if adv > 0 {
+				continue
+			}
+			if atEOF {
+				s.done = true
+				if r.err != io.EOF {
+					s.err = r.err
+				}
+				return false
+			}
+		}
+
+		// the token spans the block
+		if len(data) >= s.maxToken {
+			return s.fail(bufio.ErrTooLong)
+		}
+		switch {
+		case len(s.carry) == 0 && len(r.rest) > 0:
+			s.carry = append(s.buf[:0], r.rest...)
+			s.buf = s.carry
+			r.rest = nil
+		case len(s.carry) > 0 && s.window < len(r.rest):
+			s.window *= 2
+			continue
+		case len(s.carry) > 0:
+			s.carry = s.buf
+			r.rest = nil
+		}
+		s.window = minScanWindow
+		r.next()
+	}
+}
+
+// advance consumes n bytes of the data given to split
+func (s *Scanner) advance(n int) {
+	atomic.AddInt64(&s.r.stats.Bytes, int64(n))
+	r := s.r
+	if len(s.carry) == 0 {
+		r.rest = r.rest[n:]
+		return
+	}
+	if n >= len(s.carry) {
+		// back to the block
+		r.rest = r.rest[n-len(s.carry):]
+		s.carry = nil
+		return
+	}
+	s.carry = s.carry[n:]
+}
+
+func (s *Scanner) fail(err error) bool {
+	s.err, s.done, s.tok = err, true, nil
+	return false
+}
+
+// Bytes returns the token, valid until the next Scan
+func (s *Scanner) Bytes() []byte {
+	return s.tok
+}
+
+// Text returns a copy of the token
+func (s *Scanner) Text() string {
+	return string(s.tok)
+}
+
+// Err returns the first error other than io.EOF, ErrReaderClosed once the Reader is closed
+func (s *Scanner) Err() error {
+	return s.err
+}
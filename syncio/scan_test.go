@@ -0,0 +1,213 @@
+package syncio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var scanSize = flag.Int64("scansize", 64<<20, "size of the synthetic data of BenchmarkScan, e.g. 4<<30")
+
+// scanAll returns the tokens of bufio.Scanner and of Reader.Scan
+func scanAll(t *testing.T, data []byte, split bufio.SplitFunc, block int, wrap func(io.Reader) io.Reader) (want, got []string) {
+	t.Helper()
+	bs := bufio.NewScanner(bytes.NewReader(data))
+	bs.Split(split)
+	for bs.Scan() {
+		want = append(want, bs.Text())
+	}
+	if err := bs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	r := NewReader(wrap(bytes.NewReader(data)), SetPrefetchBlockSize(block))
+	defer r.Close()
+	s := r.Scan(split)
+	for s.Scan() {
+		got = append(got, s.Text())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return want, got
+}
+
+func TestScan(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	var b strings.Builder
+	for i := 0; i < 500; i++ {
+		n := rnd.Intn(300)
+		for j := 0; j < n; j++ {
+			b.WriteByte("ab cdé\tf"[rnd.Intn(9)])
+		}
+		b.WriteString("\n")
+		if i%50 == 0 {
+			b.WriteString("\r\n\n")
+		}
+	}
+	b.WriteString("no newline")
+	data := []byte(b.String())
+
+	splits := map[string]bufio.SplitFunc{"lines": bufio.ScanLines, "words": bufio.ScanWords, "runes": bufio.ScanRunes, "bytes": bufio.ScanBytes}
+	wraps := map[string]func(io.Reader) io.Reader{
+		"reader":  func(r io.Reader) io.Reader { return r },
+		"onebyte": iotest.OneByteReader,
+		"half":    iotest.HalfReader,
+	}
+	for sname, split := range splits {
+		for wname, wrap := range wraps {
+			for _, block := range []int{1, 7, 64, 4096} {
+				want, got := scanAll(t, data, split, block, wrap)
+				if fmt.Sprint(want) != fmt.Sprint(got) {
+					t.Errorf("%v %v block %v: %v tokens, expected: %v", sname, wname, block, len(got), len(want))
+				}
+			}
+		}
+	}
+}
+
+func TestScanAllocs(t *testing.T) {
+	data := strings.Repeat("0123456789abcdef\n", 1000)
+	r := NewReader(strings.NewReader(data), SetPrefetchBlockSize(1024))
+	defer r.Close()
+	s := r.Scan(bufio.ScanLines)
+	var tokens int
+	allocs := testing.AllocsPerRun(1, func() {
+		for s.Scan() {
+			tokens++
+		}
+	})
+	if tokens != 1000 || s.Err() != nil {
+		t.Fatalf("tokens: %v, error: %v", tokens, s.Err())
+	}
+	// the buffer of the tokens spanning the blocks and the reads ahead
+	if allocs > 20 {
+		t.Errorf("allocs: %v for %v tokens", allocs, tokens)
+	}
+	if st := r.Stats(); st.Bytes != int64(len(data)) {
+		t.Errorf("bytes: %v, expected: %v", st.Bytes, len(data))
+	}
+}
+
+func TestScanTooLong(t *testing.T) {
+	data := "short\n" + strings.Repeat("x", 100) + "\nnext\n"
+	r := NewReader(strings.NewReader(data), SetPrefetchBlockSize(8))
+	defer r.Close()
+	s := r.Scan(bufio.ScanLines)
+	s.SetMaxTokenSize(32)
+	if !s.Scan() || s.Text() != "short" {
+		t.Fatalf("first token: %q, %v", s.Text(), s.Err())
+	}
+	if s.Scan() {
+		t.Errorf("token too long scanned: %v bytes", len(s.Bytes()))
+	}
+	if !errors.Is(s.Err(), bufio.ErrTooLong) {
+		t.Errorf("error: %v, expected: %v", s.Err(), bufio.ErrTooLong)
+	}
+	if s.Scan() {
+		t.Error("scan after the error")
+	}
+
+	// the tokens up to the max size are stitched across the blocks
+	r2 := NewReader(strings.NewReader(strings.Repeat("y", 32)+"\n"), SetPrefetchBlockSize(8))
+	defer r2.Close()
+	s = r2.Scan(bufio.ScanLines)
+	s.SetMaxTokenSize(33)
+	if !s.Scan() || len(s.Bytes()) != 32 {
+		t.Errorf("token: %q, %v", s.Text(), s.Err())
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	errRead := errors.New("read failed")
+	r := NewReader(io.MultiReader(strings.NewReader("a\nb"), iotest.ErrReader(errRead)), SetPrefetchBlockSize(8))
+	defer r.Close()
+	s := r.Scan(bufio.ScanLines)
+	var got []string
+	for s.Scan() {
+		got = append(got, s.Text())
+	}
+	if fmt.Sprint(got) != "[a b]" || s.Err() != errRead {
+		t.Errorf("tokens: %q, error: %v, expected: [a b], %v", got, s.Err(), errRead)
+	}
+
+	r = NewReader(strings.NewReader("a\nb\n"))
+	r.Close()
+	s = r.Scan(bufio.ScanLines)
+	if s.Scan() || s.Err() != ErrReaderClosed {
+		t.Errorf("scan of the closed reader: %q, %v, expected: %v", s.Text(), s.Err(), ErrReaderClosed)
+	}
+
+	var zero Reader
+	if s := zero.Scan(bufio.ScanLines); s.Scan() || s.Err() != ErrNotInitialized {
+		t.Errorf("scan of the zero value: %v, expected: %v", s.Err(), ErrNotInitialized)
+	}
+}
+
+// syntheticLines is a reader of size bytes of lines
+type syntheticLines struct {
+	chunk []byte
+	off   int
+	left  int64
+}
+
+// syntheticChunk returns 1MiB of lines repeated by syntheticLines
+func syntheticChunk() []byte {
+	rnd := rand.New(rand.NewSource(1))
+	var b bytes.Buffer
+	for b.Len() < 1<<20 {
+		b.WriteString(strings.Repeat("x", rnd.Intn(200)))
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+func (s *syntheticLines) Read(p []byte) (int, error) {
+	if s.left == 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > s.left {
+		p = p[:s.left]
+	}
+	n := copy(p, s.chunk[s.off:])
+	s.off = (s.off + n) % len(s.chunk)
+	s.left -= int64(n)
+	return n, nil
+}
+
+// BenchmarkScan compares Reader.Scan with bufio.Scanner on -scansize bytes of lines, the
+// Reader tokens alias the prefetched blocks while the reads of the source overlap the scan
+func BenchmarkScan(b *testing.B) {
+	chunk := syntheticChunk()
+	b.Run("Reader", func(b *testing.B) {
+		b.SetBytes(*scanSize)
+		for i := 0; i < b.N; i++ {
+			r := NewReader(&syntheticLines{chunk: chunk, left: *scanSize}, SetPrefetchBlockSize(64<<10))
+			s := r.Scan(bufio.ScanLines)
+			for s.Scan() {
+			}
+			if s.Err() != nil {
+				b.Fatal(s.Err())
+			}
+			r.Close()
+		}
+	})
+	b.Run("bufio", func(b *testing.B) {
+		b.SetBytes(*scanSize)
+		for i := 0; i < b.N; i++ {
+			s := bufio.NewScanner(&syntheticLines{chunk: chunk, left: *scanSize})
+			s.Buffer(make([]byte, 64<<10), bufio.MaxScanTokenSize)
+			for s.Scan() {
+			}
+			if s.Err() != nil {
+				b.Fatal(s.Err())
+			}
+		}
+	})
+}
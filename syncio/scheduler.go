@@ -0,0 +1,218 @@
+package syncio
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FlushScheduler dispatches the tick flushes of the Buffers sharing a sink, see
+// SetFlushScheduler. Every interval it flushes the Buffers with data, the oldest data
+// first, within the budgets of flushes and bytes per second, the Buffers out of budget wait
+// for the next round. A budget allows a burst of a second, the bytes of the last flush of a
+// round can exceed it and are discounted from the next round.
+type FlushScheduler struct {
+	interval  time.Duration
+	flushRate float64
+	byteRate  float64
+	clock     Clock
+
+	mu      sync.Mutex
+	members map[*Buffer]*ScheduledStats
+	// flushTokens and byteTokens are the budgets left, last the time of the last round
+	flushTokens float64
+	byteTokens  float64
+	last        time.Time
+	stats       SchedulerStats
+	closed      bool
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// scheduledAge is the age limit of the backlog created by SetFlushScheduler and
+// SetMaxLatency, the backlog only stamps the data
+const scheduledAge = time.Duration(math.MaxInt64)
+
+// SchedulerStats are the counters of a FlushScheduler
+type SchedulerStats struct {
+	// Members is the number of Buffers not closed
+	Members int
+	// Ready is the number of Buffers with data in the last round and Deferred the ones left
+	// for the next round by the budgets
+	Ready    int
+	Deferred int
+	// Rounds is the number of rounds and Dispatches the number of flushes
+	Rounds     int64
+	Dispatches int64
+}
+
+// ScheduledStats are the counters of a Buffer of a FlushScheduler
+type ScheduledStats struct {
+	// Dispatches is the number of flushes and Deferrals the number of rounds the Buffer was
+	// waiting for the budgets
+	Dispatches int64
+	Deferrals  int64
+	// Delay is the age of the oldest data of the last flush and MaxDelay the maximum
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// NewFlushScheduler returns a scheduler with rounds every interval, flushesPerSec and
+// bytesPerSec are the budgets, 0 disables any of them
+func NewFlushScheduler(interval time.Duration, flushesPerSec float64, bytesPerSec int64) *FlushScheduler {
+	s := &FlushScheduler{
+		interval:  interval,
+		flushRate: flushesPerSec,
+		byteRate:  float64(bytesPerSec),
+		clock:     systemClock{},
+		members:   map[*Buffer]*ScheduledStats{},
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+// SetFlushScheduler replaces the ticks of the Buffer by the flushes dispatched by s, the
+// flush interval is ignored. The Buffer is a member of s until it's closed, the size
+// flushes, Flush and Close are not scheduled. With SetSynchronousMode the scheduled flushes
+// are written by the scheduler goroutine.
+func SetFlushScheduler(s *FlushScheduler) BufferOption {
+	return func(b *Buffer) {
+		b.scheduler = s
+	}
+}
+
+// add registers tb, the age of its data is tracked with the backlog stamps
+func (s *FlushScheduler) add(tb *Buffer) {
+	if tb.backlog == nil {
+		tb.backlog = &backlog{maxAge: scheduledAge}
+	}
+	s.mu.Lock()
+	s.members[tb] = &ScheduledStats{}
+	s.mu.Unlock()
+}
+
+// remove unregisters tb once it's closed
+func (s *FlushScheduler) remove(tb *Buffer) {
+	s.mu.Lock()
+	delete(s.members, tb)
+	s.mu.Unlock()
+}
+
+func (s *FlushScheduler) loop() {
+	defer close(s.done)
+	c, stop := s.clock.NewTicker(s.interval)
+	defer stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-c:
+			s.round(now)
+		}
+	}
+}
+
+type readyBuffer struct {
+	tb    *Buffer
+	m     *ScheduledStats
+	since int64
+}
+
+// round flushes the members with data within the budgets
+func (s *FlushScheduler) round(now time.Time) {
+	s.mu.Lock()
+	s.refill(now)
+	var ready []readyBuffer
+	for tb, m := range s.members {
+		if since := tb.backlog.active.Load(); since != 0 {
+			ready = append(ready, readyBuffer{tb, m, since})
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(ready, func(i, j int) bool { return ready[i].since < ready[j].since })
+
+	deferred := 0
+	for i, r := range ready {
+		s.mu.Lock()
+		if (s.flushRate > 0 && s.flushTokens < 1) || (s.byteRate > 0 && s.byteTokens <= 0) {
+			for _, r := range ready[i:] {
+				r.m.Deferrals++
+			}
+			deferred = len(ready) - i
+			s.mu.Unlock()
+			break
+		}
+		s.mu.Unlock()
+
+		delay := time.Duration(r.tb.clock.Now().UnixNano() - r.since)
+		n, _ := r.tb.tickFlush()
+		r.tb.flushInline()
+
+		s.mu.Lock()
+		if n > 0 {
+			s.flushTokens--
+			s.byteTokens -= float64(n)
+			s.stats.Dispatches++
+			r.m.Dispatches++
+			r.m.Delay = delay
+			if delay > r.m.MaxDelay {
+				r.m.MaxDelay = delay
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	s.stats.Rounds++
+	s.stats.Ready = len(ready)
+	s.stats.Deferred = deferred
+	s.mu.Unlock()
+}
+
+// refill adds the budgets since the last round, the caller must hold mu
+func (s *FlushScheduler) refill(now time.Time) {
+	elapsed := 1.0
+	if !s.last.IsZero() {
+		elapsed = now.Sub(s.last).Seconds()
+	}
+	s.last = now
+	s.flushTokens = math.Min(s.flushRate, s.flushTokens+elapsed*s.flushRate)
+	s.byteTokens = math.Min(s.byteRate, s.byteTokens+elapsed*s.byteRate)
+}
+
+// Stats returns the scheduler counters
+func (s *FlushScheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.Members = len(s.members)
+	return stats
+}
+
+// BufferStats returns the counters of tb, false if it's not a member
+func (s *FlushScheduler) BufferStats(tb *Buffer) (ScheduledStats, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.members[tb]
+	if !ok {
+		return ScheduledStats{}, false
+	}
+	return *m, true
+}
+
+// Close stops the rounds, the data of the members is flushed by their size flushes, Flush
+// and Close
+func (s *FlushScheduler) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.stop)
+	<-s.done
+}
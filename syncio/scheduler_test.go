@@ -0,0 +1,94 @@
+package syncio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlushScheduler(t *testing.T) {
+	clock := newFakeClock()
+	s := NewFlushScheduler(time.Hour, 2, 0)
+	defer s.Close()
+	var tws []*testWriter
+	var tbs []*Buffer
+	for i := 0; i < 5; i++ {
+		tw := &testWriter{}
+		tb := NewBuffer(tw, SetClock(clock), SetSynchronousMode(true), SetFlushScheduler(s))
+		tws, tbs = append(tws, tw), append(tbs, tb)
+	}
+	// the last Buffers have the oldest data
+	for i := len(tbs) - 1; i >= 0; i-- {
+		tbs[i].Write([]byte("data"))
+		clock.Advance(time.Second)
+	}
+	flushed := func() (f []int64) {
+		for _, tw := range tws {
+			f = append(f, tw.writes)
+		}
+		return f
+	}
+
+	s.round(clock.Now())
+	if f := flushed(); f[4] != 1 || f[3] != 1 || f[0]+f[1]+f[2] != 0 {
+		t.Errorf("flushed in the first round: %v, expected the 2 oldest", f)
+	}
+	if st := s.Stats(); st.Members != 5 || st.Ready != 5 || st.Deferred != 3 || st.Dispatches != 2 {
+		t.Errorf("stats: %+v", st)
+	}
+	if st, _ := s.BufferStats(tbs[4]); st.Dispatches != 1 || st.Delay != 5*time.Second {
+		t.Errorf("oldest buffer stats: %+v", st)
+	}
+
+	clock.Advance(time.Second)
+	s.round(clock.Now())
+	s.round(clock.Now())
+	if f := flushed(); f[2] != 1 || f[1] != 1 || f[0] != 0 {
+		t.Errorf("flushed in the second rounds: %v, expected the 2 next", f)
+	}
+	if st, _ := s.BufferStats(tbs[0]); st.Deferrals != 3 || st.Dispatches != 0 {
+		t.Errorf("newest buffer stats: %+v", st)
+	}
+
+	for _, tb := range tbs {
+		tb.Close()
+	}
+	if st := s.Stats(); st.Members != 0 {
+		t.Errorf("members after close: %v", st.Members)
+	}
+	if f := flushed(); f[0] != 1 {
+		t.Errorf("newest buffer not flushed on close: %v", f)
+	}
+}
+
+func TestFlushSchedulerBytes(t *testing.T) {
+	clock := newFakeClock()
+	s := NewFlushScheduler(time.Hour, 0, 10)
+	defer s.Close()
+	var tws []*testWriter
+	for i := 0; i < 4; i++ {
+		tw := &testWriter{}
+		tb := NewBuffer(tw, SetClock(clock), SetSynchronousMode(true), SetFlushScheduler(s))
+		defer tb.Close()
+		tb.Write([]byte("01234567"))
+		clock.Advance(time.Millisecond)
+		tws = append(tws, tw)
+	}
+	bytes := func() (n int64) {
+		for _, tw := range tws {
+			n += tw.bytes
+		}
+		return n
+	}
+
+	// the second flush overdraws the budget
+	s.round(clock.Now())
+	if n := bytes(); n != 16 {
+		t.Errorf("bytes of the first round: %v, expected: 16", n)
+	}
+	// the debt is paid first
+	clock.Advance(time.Second)
+	s.round(clock.Now())
+	if n := bytes(); n != 24 {
+		t.Errorf("bytes of the second round: %v, expected: 24", n)
+	}
+}
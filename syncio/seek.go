@@ -0,0 +1,59 @@
+package syncio
+
+import (
+	"errors"
+	"io"
+)
+
+var _ io.Seeker = &Buffer{}
+
+// ErrNotSeeker is returned by Seek when the underlying writer doesn't implement io.Seeker
+var ErrNotSeeker = errors.New("underlying writer is not an io.Seeker")
+
+// writerSeek is a Seek of the underlying writer carried by a barrier
+type writerSeek struct {
+	offset int64
+	whence int
+	pos    int64
+	err    error
+}
+
+// Seek flushes the buffered data and seeks the underlying writer, the writes done after the
+// call are flushed from the new position. It's serialized with the concurrent writes as
+// Flush, the writes done before it are written before seeking. The returned error is the
+// Seek error or else the first flush error since the last call to Flush.
+func (tb *Buffer) Seek(offset int64, whence int) (int64, error) {
+	if !tb.initialized() {
+		return 0, ErrNotInitialized
+	}
+	tb.lockBuf()
+	if tb.closed {
+		tb.unlockBuf()
+		tb.countError(ErrorClosed)
+		return 0, ErrWriteOnClosed
+	}
+	done := make(chan error, 1)
+	s := &writerSeek{offset: offset, whence: whence}
+	sw := tb.flushBatch(&batch{trigger: TriggerManual, done: done, seek: s})
+	tb.unlockBuf()
+
+	if tb.logger != nil {
+		tb.logSwap(sw)
+	}
+	tb.flushInline()
+	err := <-done
+	if s.err != nil {
+		return s.pos, s.err
+	}
+	return s.pos, err
+}
+
+// seekWriter seeks the underlying writer from the flush goroutine
+func (tb *Buffer) seekWriter(s *writerSeek) {
+	seeker, ok := tb.writer.(io.Seeker)
+	if !ok {
+		s.err = ErrNotSeeker
+		return
+	}
+	s.pos, s.err = seeker.Seek(s.offset, s.whence)
+}
@@ -0,0 +1,57 @@
+package syncio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSeek(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		f, err := os.Create(filepath.Join(t.TempDir(), "seek"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		tb := NewBuffer(f, append([]BufferOption{SetBufferSize(64)}, mode...)...)
+
+		// the length field is patched once the body is written
+		tb.Write([]byte{0, 0, 0, 0})
+		tb.Write([]byte("body"))
+		tb.Write([]byte("more body"))
+		if pos, err := tb.Seek(0, io.SeekStart); pos != 0 || err != nil {
+			t.Fatalf("seek start: %v, %v", pos, err)
+		}
+		var size [4]byte
+		binary.LittleEndian.PutUint32(size[:], 13)
+		tb.Write(size[:])
+		if pos, err := tb.Seek(0, io.SeekEnd); pos != 17 || err != nil {
+			t.Fatalf("seek end: %v, %v, expected: 17, nil", pos, err)
+		}
+		tb.Write([]byte("tail"))
+		if err := tb.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		data, _ := os.ReadFile(f.Name())
+		expected := append(size[:], "bodymore bodytail"...)
+		if !bytes.Equal(data, expected) {
+			t.Errorf("file data: %q, expected: %q", data, expected)
+		}
+	})
+}
+
+func TestSeekNotSeeker(t *testing.T) {
+	tb := NewBuffer(&bytes.Buffer{})
+	tb.Write([]byte("data"))
+	if _, err := tb.Seek(0, io.SeekStart); err != ErrNotSeeker {
+		t.Errorf("seek: %v, expected: %v", err, ErrNotSeeker)
+	}
+	tb.Close()
+	if _, err := tb.Seek(0, io.SeekStart); err != ErrWriteOnClosed {
+		t.Errorf("seek on closed: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+}
@@ -0,0 +1,24 @@
+package syncio
+
+import "errors"
+
+// ErrSelfWrite is returned by Write when p is the data the Buffer is flushing, e.g. a sink or
+// a transform writing back into the Buffer, the copy would need the space the flush frees
+var ErrSelfWrite = errors.New("write of the data being flushed by the same buffer")
+
+// handOut marks p as the data handed to the sink, the transform or the callbacks by the
+// flush goroutine, by the address of the last byte of its memory
+func (tb *Buffer) handOut(p []byte) {
+	if cap(p) == 0 {
+		tb.handout.Store(nil)
+		return
+	}
+	tb.handout.Store(&p[:cap(p)][cap(p)-1])
+}
+
+// selfWrite reports if p is a slice of the data handed out, the slices derived from it share
+// the end of its memory
+func (tb *Buffer) selfWrite(p []byte) bool {
+	end := tb.handout.Load()
+	return end != nil && cap(p) > 0 && &p[:cap(p)][cap(p)-1] == end
+}
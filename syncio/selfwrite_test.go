@@ -0,0 +1,68 @@
+package syncio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSelfWrite(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		var tb *Buffer
+		var sink bytes.Buffer
+		var errs []error
+		// a tee of the flushed data into the same Buffer, the copy would wait for the space
+		// freed by the flush
+		tb = NewBuffer(writerFunc(func(p []byte) (int, error) {
+			_, err := tb.Write(p)
+			errs = append(errs, err)
+			_, err = tb.Write(p[1:2])
+			errs = append(errs, err)
+			return sink.Write(p)
+		}), append([]BufferOption{SetBufferSize(4), SetBufferPoolSize(1)}, mode...)...)
+
+		tb.Write([]byte("abcd"))
+		tb.Write([]byte("efgh"))
+		closed := make(chan error, 1)
+		go func() { closed <- tb.Close() }()
+		select {
+		case err := <-closed:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("close blocked by the write of the flushed data")
+		}
+
+		if sink.String() != "abcdefgh" {
+			t.Errorf("sink data: %q, expected: %q", sink.String(), "abcdefgh")
+		}
+		if len(errs) != 4 {
+			t.Fatalf("%v writes back, expected: 4", len(errs))
+		}
+		for i, err := range errs {
+			if err != ErrSelfWrite {
+				t.Errorf("write back %v: %v, expected: %v", i, err, ErrSelfWrite)
+			}
+		}
+	})
+}
+
+func TestSelfWriteTransform(t *testing.T) {
+	var tb *Buffer
+	var err error
+	tb = NewBuffer(&bytes.Buffer{}, SetFlushTransform(func(dst, src []byte) ([]byte, error) {
+		_, err = tb.Write(src)
+		return append(dst, src...), nil
+	}))
+	tb.Write([]byte("data"))
+	tb.Flush()
+	if err != ErrSelfWrite {
+		t.Errorf("write back from the transform: %v, expected: %v", err, ErrSelfWrite)
+	}
+	// the data of the caller isn't mistaken for it
+	if _, err := tb.Write([]byte("data")); err != nil {
+		t.Error(err)
+	}
+	tb.Close()
+}
@@ -0,0 +1,76 @@
+package syncio
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Semaphore limits the number of Buffers writing to their underlying writers at the same
+// time, it's meant for sinks that don't support concurrent writes. The waiting flushes are
+// served in FIFO order so a Buffer can't starve the others.
+type Semaphore struct {
+	mu      sync.Mutex
+	free    int
+	waiters []chan struct{}
+}
+
+// WriteSemaphore returns a Semaphore that allows n concurrent writes, it's shared by passing
+// it to multiple Buffers with SetWriteSemaphore
+func WriteSemaphore(n int) *Semaphore {
+	if n < 1 {
+		n = 1
+	}
+	return &Semaphore{free: n}
+}
+
+// Acquire blocks until a write slot is free
+func (s *Semaphore) Acquire() {
+	s.mu.Lock()
+	if s.free > 0 && len(s.waiters) == 0 {
+		s.free--
+		s.mu.Unlock()
+		return
+	}
+	c := make(chan struct{})
+	s.waiters = append(s.waiters, c)
+	s.mu.Unlock()
+	<-c
+}
+
+// Release frees a write slot, it's handed to the oldest waiter if any
+func (s *Semaphore) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.waiters) == 0 {
+		s.free++
+		return
+	}
+	c := s.waiters[0]
+	s.waiters[0] = nil
+	s.waiters = s.waiters[1:]
+	close(c)
+}
+
+// SetWriteSemaphore makes the Buffer acquire sem before every write to the underlying writer,
+// the time waiting is reported in Stats.SinkWait
+func SetWriteSemaphore(sem *Semaphore) BufferOption {
+	return func(b *Buffer) {
+		b.sem = sem
+	}
+}
+
+// acquire waits for the write semaphore if it's set
+func (tb *Buffer) acquire() {
+	if tb.sem == nil {
+		return
+	}
+	start := tb.clock.Now()
+	tb.sem.Acquire()
+	atomic.AddInt64((*int64)(&tb.stats.SinkWait), int64(tb.clock.Now().Sub(start)))
+}
+
+func (tb *Buffer) release() {
+	if tb.sem != nil {
+		tb.sem.Release()
+	}
+}
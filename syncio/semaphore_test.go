@@ -0,0 +1,98 @@
+package syncio
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyWriter records the maximum number of concurrent writes of a group of writers
+type concurrencyWriter struct {
+	active *int32
+	max    *int32
+}
+
+func (w concurrencyWriter) Write(p []byte) (int, error) {
+	n := atomic.AddInt32(w.active, 1)
+	for {
+		m := atomic.LoadInt32(w.max)
+		if n <= m || atomic.CompareAndSwapInt32(w.max, m, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	atomic.AddInt32(w.active, -1)
+	return len(p), nil
+}
+
+func TestWriteSemaphore(t *testing.T) {
+	var active, max int32
+	sem := WriteSemaphore(1)
+	bufs := make([]*Buffer, 4)
+	for i := range bufs {
+		bufs[i] = NewBuffer(concurrencyWriter{&active, &max}, SetBufferSize(8), SetWriteSemaphore(sem))
+	}
+
+	wg := sync.WaitGroup{}
+	for _, tb := range bufs {
+		wg.Add(1)
+		go func(tb *Buffer) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				tb.Write(make([]byte, 8))
+			}
+			tb.Close()
+		}(tb)
+	}
+	wg.Wait()
+
+	if max != 1 {
+		t.Errorf("concurrent writes: %v, expected: 1", max)
+	}
+	var wait time.Duration
+	for _, tb := range bufs {
+		wait += tb.Stats().SinkWait
+	}
+	if wait == 0 {
+		t.Errorf("sink wait not reported")
+	}
+}
+
+func TestSemaphoreFIFO(t *testing.T) {
+	sem := WriteSemaphore(1)
+	sem.Acquire()
+
+	var mu sync.Mutex
+	var order []int
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem.Acquire()
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			sem.Release()
+		}(i)
+		// wait until queued to know the arrival order
+		for {
+			sem.mu.Lock()
+			n := len(sem.waiters)
+			sem.mu.Unlock()
+			if n == i+1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	sem.Release()
+	wg.Wait()
+
+	for i, n := range order {
+		if i != n {
+			t.Fatalf("acquisition order: %v, expected FIFO", order)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package syncio
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShadowQueueSize is the number of writes a ShadowedWriter can hold for the shadow writer,
+// writes are not sent to the shadow when the queue is full
+const ShadowQueueSize = 128
+
+// ShadowedWriter writes to a primary writer and sends a copy of the writes to a shadow
+// writer in background, only the primary result is returned to the caller. The differences of
+// the shadow are counted in its stats. Meant to compare a new sink with the current one.
+type ShadowedWriter struct {
+	// CloseTimeout is the maximum time Close waits for the shadow queue to be written
+	CloseTimeout time.Duration
+
+	primary io.Writer
+	shadow  io.Writer
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan shadowWrite
+	done   chan struct{}
+
+	stats ShadowStats
+}
+
+type shadowWrite struct {
+	p       []byte
+	latency time.Duration
+}
+
+// ShadowStats are the counters of the shadow writes
+type ShadowStats struct {
+	// Writes is the number of writes sent to the shadow
+	Writes int64
+	// Dropped is the number of writes not sent to the shadow because the queue was full
+	Dropped int64
+	// Errors is the number of shadow writes that failed
+	Errors int64
+	// ShortWrites is the number of shadow writes that wrote less bytes than the primary
+	ShortWrites int64
+	// Slower is the number of shadow writes that took longer than the primary write
+	Slower int64
+	// TotalLatency and MaxLatency are the shadow write durations
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+}
+
+var _ io.WriteCloser = &ShadowedWriter{}
+
+// ShadowWriter returns a writer that writes to primary and shadow, the shadow writes
+// happen asynchronously and never block or fail the caller
+func ShadowWriter(primary, shadow io.Writer) *ShadowedWriter {
+	s := &ShadowedWriter{
+		CloseTimeout: 5 * time.Second,
+		primary:      primary,
+		shadow:       shadow,
+		queue:        make(chan shadowWrite, ShadowQueueSize),
+		done:         make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Write writes p to the primary writer and enqueues a copy for the shadow writer
+func (s *ShadowedWriter) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := s.primary.Write(p)
+	latency := time.Since(start)
+
+	c := make([]byte, n)
+	copy(c, p)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return 0, ErrWriteOnClosed
+	}
+	select {
+	case s.queue <- shadowWrite{p: c, latency: latency}:
+	default:
+		atomic.AddInt64(&s.stats.Dropped, 1)
+	}
+	return n, err
+}
+
+func (s *ShadowedWriter) run() {
+	defer close(s.done)
+	for w := range s.queue {
+		start := time.Now()
+		n, err := s.shadow.Write(w.p)
+		latency := time.Since(start)
+
+		atomic.AddInt64(&s.stats.Writes, 1)
+		if err != nil {
+			atomic.AddInt64(&s.stats.Errors, 1)
+		} else if n < len(w.p) {
+			atomic.AddInt64(&s.stats.ShortWrites, 1)
+		}
+		if latency > w.latency {
+			atomic.AddInt64(&s.stats.Slower, 1)
+		}
+		atomic.AddInt64((*int64)(&s.stats.TotalLatency), int64(latency))
+		if latency > time.Duration(atomic.LoadInt64((*int64)(&s.stats.MaxLatency))) {
+			// only this goroutine updates it
+			atomic.StoreInt64((*int64)(&s.stats.MaxLatency), int64(latency))
+		}
+	}
+}
+
+// Close waits until the shadow queue is written or CloseTimeout expires, in which case
+// ErrCloseTimeout is returned. The primary and shadow writers are not closed.
+func (s *ShadowedWriter) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	t := time.NewTimer(s.CloseTimeout)
+	defer t.Stop()
+	select {
+	case <-s.done:
+		return nil
+	case <-t.C:
+		return ErrCloseTimeout
+	}
+}
+
+// Stats returns a copy of the shadow counters
+func (s *ShadowedWriter) Stats() ShadowStats {
+	return ShadowStats{
+		Writes:       atomic.LoadInt64(&s.stats.Writes),
+		Dropped:      atomic.LoadInt64(&s.stats.Dropped),
+		Errors:       atomic.LoadInt64(&s.stats.Errors),
+		ShortWrites:  atomic.LoadInt64(&s.stats.ShortWrites),
+		Slower:       atomic.LoadInt64(&s.stats.Slower),
+		TotalLatency: time.Duration(atomic.LoadInt64((*int64)(&s.stats.TotalLatency))),
+		MaxLatency:   time.Duration(atomic.LoadInt64((*int64)(&s.stats.MaxLatency))),
+	}
+}
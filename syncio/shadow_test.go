@@ -0,0 +1,62 @@
+package syncio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+func TestShadowWriter(t *testing.T) {
+	primary := &bytes.Buffer{}
+	shadowOut := &bytes.Buffer{}
+	shadow := synctest.NewFailingWriter(shadowOut, nil)
+	shadow.SetFailing(false)
+	s := ShadowWriter(primary, shadow)
+
+	s.Write([]byte("one "))
+	s.Write([]byte("two "))
+	// wait for the shadow before failing it
+	for s.Stats().Writes < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	shadow.SetFailing(true)
+	shadow.Partial = 2
+	n, err := s.Write([]byte("three"))
+	if n != 5 || err != nil {
+		t.Errorf("write: %v, %v, expected the primary result", n, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("close error: %v", err)
+	}
+
+	if primary.String() != "one two three" {
+		t.Errorf("primary: %q, expected: %q", primary.String(), "one two three")
+	}
+	if shadowOut.String() != "one two th" {
+		t.Errorf("shadow: %q, expected: %q", shadowOut.String(), "one two th")
+	}
+	if st := s.Stats(); st.Writes != 3 || st.Errors != 1 || st.Dropped != 0 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestShadowWriterNeverBlocks(t *testing.T) {
+	s := ShadowWriter(&testWriter{}, &synctest.SlowWriter{Delay: 50 * time.Millisecond})
+	s.CloseTimeout = 10 * time.Millisecond
+
+	start := time.Now()
+	for i := 0; i < ShadowQueueSize+10; i++ {
+		s.Write([]byte("x"))
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("writes blocked by the shadow for %v", elapsed)
+	}
+	if err := s.Close(); err != ErrCloseTimeout {
+		t.Errorf("close error: %v, expected: %v", err, ErrCloseTimeout)
+	}
+	if st := s.Stats(); st.Dropped == 0 {
+		t.Errorf("expected dropped shadow writes: %+v", st)
+	}
+}
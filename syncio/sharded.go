@@ -0,0 +1,119 @@
+package syncio
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ShardedBuffer spreads the writes of many goroutines over several child Buffers, the shards,
+// writing into a parent Buffer that writes to the sink, so the writers only contend on the
+// lock of a shard and the batches are serialized when they are handed over to the parent,
+// see the hierarchy of Buffers. The shard of a write is the one cached by the processor
+// running it, so the writes of a goroutine stay in a shard unless it's rescheduled.
+// A write is kept whole, but there is no order between the writes of different shards: use a
+// Buffer when the order of the data matters.
+type ShardedBuffer struct {
+	parent *Buffer
+	shards []*Buffer
+	next   uint32
+	// picks caches a shard by processor
+	picks sync.Pool
+}
+
+// shardPick is the shard cached by picks
+type shardPick struct {
+	buf *Buffer
+}
+
+var _ io.WriteCloser = &ShardedBuffer{}
+
+// NewShardedBuffer returns a ShardedBuffer of n shards writing to w, 1 if n < 1. The options
+// are the ones of the parent Buffer, the shards take its buffer size and flush interval.
+func NewShardedBuffer(w io.Writer, n int, options ...BufferOption) *ShardedBuffer {
+	if n < 1 {
+		n = 1
+	}
+	parent := NewBuffer(w, options...)
+	sb := &ShardedBuffer{parent: parent, shards: make([]*Buffer, n)}
+	for i := range sb.shards {
+		sb.shards[i] = NewBuffer(parent, SetBufferSize(parent.bufSize), SetFlushInterval(parent.flushInterval))
+	}
+	sb.picks.New = func() any {
+		i := atomic.AddUint32(&sb.next, 1) - 1
+		return &shardPick{buf: sb.shards[int(i)%len(sb.shards)]}
+	}
+	return sb
+}
+
+// Write writes p to the shard of the processor
+func (sb *ShardedBuffer) Write(p []byte) (int, error) {
+	if sb.parent == nil {
+		return 0, ErrNotInitialized
+	}
+	pick := sb.picks.Get().(*shardPick)
+	n, err := pick.buf.Write(p)
+	sb.picks.Put(pick)
+	return n, err
+}
+
+// Shards returns the number of shards
+func (sb *ShardedBuffer) Shards() int {
+	return len(sb.shards)
+}
+
+// Shard returns the shard i, e.g. to read its Stats
+func (sb *ShardedBuffer) Shard(i int) *Buffer {
+	return sb.shards[i]
+}
+
+// Parent returns the Buffer writing to the sink
+func (sb *ShardedBuffer) Parent() *Buffer {
+	return sb.parent
+}
+
+// Stats returns the stats of the shards merged by MergeStats, see Parent for the stats of the
+// writes to the sink
+func (sb *ShardedBuffer) Stats() Stats {
+	stats := make([]Stats, len(sb.shards))
+	for i, s := range sb.shards {
+		s.StatsInto(&stats[i])
+	}
+	return MergeStats(stats...)
+}
+
+// Flush hands the data of every shard over to the parent and flushes it, the returned error is
+// the first error of the shards or else the parent one
+func (sb *ShardedBuffer) Flush() error {
+	if sb.parent == nil {
+		return ErrNotInitialized
+	}
+	var first error
+	for _, s := range sb.shards {
+		if err := s.Flush(); err != nil && first == nil {
+			first = err
+		}
+	}
+	if err := sb.parent.Flush(); err != nil && first == nil {
+		first = err
+	}
+	return first
+}
+
+// Close closes the shards and then the parent, the returned error is the first error of the
+// shards or else the parent one
+func (sb *ShardedBuffer) Close() error {
+	if sb.parent == nil {
+		return ErrNotInitialized
+	}
+	var first error
+	for _, s := range sb.shards {
+		if err := s.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	if err := sb.parent.Close(); err != nil && first == nil {
+		first = err
+	}
+	return first
+}
@@ -0,0 +1,104 @@
+package syncio
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedBuffer(t *testing.T) {
+	sink := &lockedBuffer{}
+	sb := NewShardedBuffer(sink, 4, SetBufferSize(64))
+	if sb.Shards() != 4 {
+		t.Fatalf("shards: %v", sb.Shards())
+	}
+
+	writers, writes := 16, 200
+	var wg sync.WaitGroup
+	wg.Add(writers)
+	for g := 0; g < writers; g++ {
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				fmt.Fprintf(sb, "%02d-%03d\n", g, i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	if err := sb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(sink.String(), "\n"); n != writers*writes {
+		t.Errorf("lines after flush: %v, expected: %v", n, writers*writes)
+	}
+	if err := sb.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// every write is whole, in any order
+	lines := strings.Split(strings.TrimSuffix(sink.String(), "\n"), "\n")
+	sort.Strings(lines)
+	var expected []string
+	for g := 0; g < writers; g++ {
+		for i := 0; i < writes; i++ {
+			expected = append(expected, fmt.Sprintf("%02d-%03d", g, i))
+		}
+	}
+	if strings.Join(lines, ",") != strings.Join(expected, ",") {
+		t.Errorf("lines: %v, expected %v", len(lines), len(expected))
+	}
+	if s := sb.Stats(); s.CallerWrites != int64(writers*writes) {
+		t.Errorf("caller writes: %v, expected: %v", s.CallerWrites, writers*writes)
+	}
+	if s := sb.Parent().Stats(); s.ChildBytes != int64(sink.Len()) || s.DirectBytes != 0 {
+		t.Errorf("parent child bytes: %v, direct bytes: %v, expected: %v, 0", s.ChildBytes, s.DirectBytes, sink.Len())
+	}
+	if _, err := sb.Write([]byte("x")); err != ErrWriteOnClosed {
+		t.Errorf("write on closed: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+}
+
+func TestShardedBufferTicks(t *testing.T) {
+	sink := &lockedBuffer{}
+	sb := NewShardedBuffer(sink, 2, SetFlushInterval(10*time.Millisecond))
+	defer sb.Close()
+	sb.Write([]byte("ticked"))
+	deadline := time.Now().Add(time.Second)
+	for sink.String() != "ticked" {
+		if time.Now().After(deadline) {
+			t.Fatalf("not flushed by the ticks: %q", sink.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if d := sb.Shard(0).flushInterval; d != 10*time.Millisecond {
+		t.Errorf("shard flush interval: %v", d)
+	}
+}
+
+func BenchmarkShardedParallelWrites(b *testing.B) {
+	for _, shards := range []int{0, 8} {
+		b.Run(fmt.Sprint("shards=", shards), func(b *testing.B) {
+			var w interface {
+				Write([]byte) (int, error)
+				Close() error
+			}
+			if shards == 0 {
+				w = NewBuffer(&testWriter{}, SetBufferSize(64<<10))
+			} else {
+				w = NewShardedBuffer(&testWriter{}, shards, SetBufferSize(64<<10))
+			}
+			p := bytes.Repeat([]byte("x"), 64)
+			b.SetParallelism(16)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					w.Write(p)
+				}
+			})
+			w.Close()
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package syncio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCloseTimeout is returned when the remaining data couldn't be flushed within the close deadline
+var ErrCloseTimeout = errors.New("close timeout: data not flushed")
+
+// CloseOnContext closes the Buffer when ctx is done, the remaining data is given grace time to be flushed.
+// The returned channel receives the Close result, or ErrCloseTimeout if the flush didn't finish in time;
+// in that case the flush keeps running in background. The channel is closed without a value if
+// the Buffer is closed before ctx is done.
+func (tb *Buffer) CloseOnContext(ctx context.Context, grace time.Duration) <-chan error {
+	res := make(chan error, 1)
+	if !tb.initialized() {
+		res <- ErrNotInitialized
+		close(res)
+		return res
+	}
+	go func() {
+		defer close(res)
+		select {
+		case <-tb.done:
+			// closed manually
+			return
+		case <-ctx.Done():
+		}
+
+		closed := make(chan error, 1)
+		go func() {
+			closed <- tb.Close()
+		}()
+		t := time.NewTimer(grace)
+		defer t.Stop()
+		select {
+		case err := <-closed:
+			res <- err
+		case <-t.C:
+			tb.countError(ErrorTimeout)
+			res <- ErrCloseTimeout
+		}
+	}()
+	return res
+}
+
+// AutoClose calls CloseOnContext for every Buffer, the returned channel receives the errors in
+// a *MultiError by index of the Buffer and it's closed once all buffers are closed
+func AutoClose(ctx context.Context, grace time.Duration, bufs ...*Buffer) <-chan error {
+	res := make(chan error, 1)
+	chans := make([]<-chan error, len(bufs))
+	for i, b := range bufs {
+		chans[i] = b.CloseOnContext(ctx, grace)
+	}
+	go func() {
+		defer close(res)
+		var errs MultiError
+		for i, c := range chans {
+			errs.add("", i, <-c)
+		}
+		if err := errs.err(); err != nil {
+			res <- err
+		}
+	}()
+	return res
+}
+
+// CloseTimeoutError is returned by CloseTimeout when the remaining data couldn't be flushed
+// before the deadline, it matches ErrCloseTimeout
+type CloseTimeoutError struct {
+	// Unflushed is the number of bytes abandoned
+	Unflushed int64
+	// Batches is the number of batches abandoned
+	Batches int
+	// Escalation is the last action taken to interrupt the sink write in flight, Interrupted
+	// reports if the write ended
+	Escalation  Escalation
+	Interrupted bool
+}
+
+func (e *CloseTimeoutError) Error() string {
+	msg := fmt.Sprintf("%v: %v bytes in %v batches abandoned", ErrCloseTimeout, e.Unflushed, e.Batches)
+	if e.Escalation != EscalationNone {
+		if e.Interrupted {
+			msg += fmt.Sprintf(", write in flight interrupted by %v", e.Escalation)
+		} else {
+			msg += fmt.Sprintf(", write in flight not interrupted by %v", e.Escalation)
+		}
+	}
+	return msg
+}
+
+// Unwrap returns ErrCloseTimeout
+func (e *CloseTimeoutError) Unwrap() error {
+	return ErrCloseTimeout
+}
+
+// CloseTimeout closes the Buffer giving d time to flush the remaining data. When the deadline expires
+// the batches not yet written are abandoned and sent to the dead letter writer if it's set, the
+// returned values are the abandoned bytes and a *CloseTimeoutError.
+// The batch being written when the deadline expires isn't counted as abandoned and its result is
+// reported as any other flush error. Its write is interrupted when the underlying writer allows
+// it: the context of a ContextWriter with SetFlushContext is cancelled, the write deadline of a
+// net.Conn is set to now or the writer is closed, the next action is taken when the write doesn't
+// end in 100ms. The error records the last action in Escalation.
+func (tb *Buffer) CloseTimeout(d time.Duration) (unflushed int64, err error) {
+	if !tb.initialized() {
+		return 0, ErrNotInitialized
+	}
+	expired := make(chan struct{})
+	t := time.AfterFunc(d, func() { close(expired) })
+	defer t.Stop()
+	return tb.closeUntil(expired)
+}
+
+// CloseContext is CloseTimeout with ctx as deadline, the remaining data is abandoned when ctx
+// is done before it's flushed
+func (tb *Buffer) CloseContext(ctx context.Context) (unflushed int64, err error) {
+	if !tb.initialized() {
+		return 0, ErrNotInitialized
+	}
+	return tb.closeUntil(ctx.Done())
+}
+
+// closeUntil closes the Buffer abandoning the remaining data once expired is closed
+func (tb *Buffer) closeUntil(expired <-chan struct{}) (unflushed int64, err error) {
+	done, ok := tb.startClose()
+	if !ok {
+		select {
+		case <-tb.done:
+		case <-expired:
+		}
+		return 0, nil
+	}
+
+	tb.flushInline()
+	select {
+	case err = <-done:
+		<-tb.done
+		tb.freeMemory()
+		tb.waitReport()
+		return 0, err
+	case <-expired:
+	}
+
+	cerr := tb.abandon()
+	tb.countError(ErrorTimeout)
+	cerr.Escalation, cerr.Interrupted = tb.interrupt()
+	// the batch in flight is freed once written
+	tb.freeMemory()
+	if cerr.Interrupted {
+		tb.waitReport()
+	}
+	return cerr.Unflushed, cerr
+}
+
+// abandon discards the batches not yet written after closing, they are sent to the dead letter
+// writer. The flush goroutine ends after the batch in flight once the queue is empty.
+func (tb *Buffer) abandon() *CloseTimeoutError {
+	if tb.retry != nil {
+		tb.retry.stop()
+	}
+	tb.bufmu.Lock()
+	queue := tb.queue
+	tb.queue = nil
+	if tb.spill != nil {
+		queue = append(queue, tb.spill.abandon(tb)...)
+	}
+	for _, b := range queue {
+		tb.acct.remove(b.len())
+		tb.commitWAL(b.walOff, b.len())
+	}
+	if p := tb.dropClasses(); len(p) > 0 {
+		queue = append(queue, &batch{p: p})
+	}
+	tb.bufmu.Unlock()
+
+	cerr := &CloseTimeoutError{}
+	var barriers []chan error
+	for _, b := range queue {
+		if p := b.bytes(); len(p) > 0 {
+			cerr.Unflushed += int64(len(p))
+			cerr.Batches++
+			tb.toDeadLetter(p)
+		}
+		if b.buf != nil {
+			tb.putBuffer(b.buf)
+		}
+		if b.done != nil {
+			barriers = append(barriers, b.done)
+		}
+	}
+	// wakes up the Flush and Close calls waiting for the abandoned batches
+	var err error
+	if cerr.Batches > 0 {
+		err = cerr
+	}
+	for _, done := range barriers {
+		done <- err
+	}
+	return cerr
+}
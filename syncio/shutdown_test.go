@@ -0,0 +1,138 @@
+package syncio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+func TestCloseOnContext(t *testing.T) {
+	tw := &testWriter{}
+	tb := NewBuffer(tw)
+	ctx, cancel := context.WithCancel(context.Background())
+	res := tb.CloseOnContext(ctx, time.Second)
+
+	tb.Write(make([]byte, 8))
+	cancel()
+	if err := <-res; err != nil {
+		t.Errorf("close error: %v, expected nil", err)
+	}
+	if tw.bytes != 8 {
+		t.Errorf("test writer bytes: %v, expected: %v", tw.bytes, 8)
+	}
+	if _, err := tb.Write(make([]byte, 8)); err != ErrWriteOnClosed {
+		t.Errorf("write after context close: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+}
+
+func TestCloseOnContextGrace(t *testing.T) {
+	tb := NewBuffer(&synctest.SlowWriter{Delay: 500 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	res := tb.CloseOnContext(ctx, 20*time.Millisecond)
+
+	tb.Write(make([]byte, 8))
+	cancel()
+	if err := <-res; err != ErrCloseTimeout {
+		t.Errorf("close error: %v, expected: %v", err, ErrCloseTimeout)
+	}
+}
+
+func TestCloseOnContextManualClose(t *testing.T) {
+	b1 := NewBuffer(&testWriter{})
+	b2 := NewBuffer(&testWriter{})
+	res := AutoClose(context.Background(), time.Second, b1, b2)
+
+	b1.Close()
+	b2.Close()
+	select {
+	case err, ok := <-res:
+		if ok {
+			t.Errorf("unexpected result after manual close: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("watcher goroutines not released after manual close")
+	}
+}
+
+func TestCloseTimeout(t *testing.T) {
+	tw := &testWriter{}
+	tb := NewBuffer(tw)
+	tb.Write(make([]byte, 8))
+	if n, err := tb.CloseTimeout(time.Second); n != 0 || err != nil {
+		t.Errorf("close: %v, %v, expected: 0, nil", n, err)
+	}
+	if tw.bytes != 8 {
+		t.Errorf("test writer bytes: %v, expected: %v", tw.bytes, 8)
+	}
+}
+
+func TestCloseTimeoutAbandon(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	var dl bytes.Buffer
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(4), SetDeadLetter(&dl))
+
+	tb.Write([]byte("aaa")) // in flight once the next write fills the buffer
+	tb.Write([]byte("bbb"))
+	time.Sleep(10 * time.Millisecond)
+	tb.Write([]byte("ccc"))
+
+	n, err := tb.CloseTimeout(20 * time.Millisecond)
+	var cerr *CloseTimeoutError
+	if !errors.As(err, &cerr) || !errors.Is(err, ErrCloseTimeout) {
+		t.Fatalf("close error: %v, expected: %v", err, ErrCloseTimeout)
+	}
+	if n != 6 || cerr.Unflushed != 6 || cerr.Batches != 2 {
+		t.Errorf("unflushed: %v, error: %+v, expected 6 bytes in 2 batches", n, cerr)
+	}
+	if dl.String() != "bbbccc" {
+		t.Errorf("dead letter: %q, expected: %q", dl.String(), "bbbccc")
+	}
+	if _, err := tb.Write([]byte("d")); err != ErrWriteOnClosed {
+		t.Errorf("write after close: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+
+	// the batch in flight is still written
+	close(bw.release)
+	tb.Close()
+	if bw.out.String() != "aaa" {
+		t.Errorf("written: %q, expected: %q", bw.out.String(), "aaa")
+	}
+}
+
+func TestCloseContext(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	var dl bytes.Buffer
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(4), SetDeadLetter(&dl))
+
+	tb.Write([]byte("aaa"))
+	tb.Write([]byte("bbb"))
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	n, err := tb.CloseContext(ctx)
+	if !errors.Is(err, ErrCloseTimeout) || n != 3 {
+		t.Errorf("close: %v, %v, expected: 3, %v", n, err, ErrCloseTimeout)
+	}
+	if dl.String() != "bbb" {
+		t.Errorf("dead letter: %q, expected: %q", dl.String(), "bbb")
+	}
+	close(bw.release)
+	tb.Close()
+}
+
+func TestDeadLetterFlushError(t *testing.T) {
+	fw := synctest.NewFailingWriter(nil, nil)
+	fw.Partial = 2
+	var dl bytes.Buffer
+	tb := NewBuffer(fw, SetDeadLetter(&dl))
+	tb.Write([]byte("abcdef"))
+	tb.Close()
+	if dl.String() != "cdef" {
+		t.Errorf("dead letter: %q, expected: %q", dl.String(), "cdef")
+	}
+}
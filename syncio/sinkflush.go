@@ -0,0 +1,32 @@
+package syncio
+
+// flusher is implemented by the writers with their own buffering, e.g. bufio.Writer or gzip.Writer
+type flusher interface {
+	Flush() error
+}
+
+// httpFlusher is the http.Flusher of the http.ResponseWriter, e.g. to stream server-sent events
+type httpFlusher interface {
+	Flush()
+}
+
+// SetSinkFlush makes the Buffer call the Flush method of the underlying writer after every batch,
+// so the data doesn't wait in the writer buffer for longer than the flush interval. The Flush errors
+// are reported as any flush error. An http.ResponseWriter implementing http.Flusher is flushed too, so
+// the events batched by the Buffer reach the client every tick. It has no effect if the writer doesn't
+// implement Flush() error or Flush().
+func SetSinkFlush(enabled bool) BufferOption {
+	return func(b *Buffer) {
+		b.sinkFlush = enabled
+	}
+}
+
+func (tb *Buffer) flushSink() error {
+	if f, ok := tb.writer.(flusher); ok {
+		return f.Flush()
+	}
+	if f, ok := tb.writer.(httpFlusher); ok {
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package syncio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer safe to read while the flush goroutine writes
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSinkFlush(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		dst := &lockedBuffer{}
+		clock := newFakeClock()
+		tb := NewBuffer(bufio.NewWriter(dst), SetClock(clock), SetFlushInterval(time.Second), SetSinkFlush(enabled))
+		tb.Write([]byte("abc"))
+		// the second tick is received once the first one is handled
+		ticker := clock.ticker(0)
+		ticker <- time.Time{}
+		ticker <- time.Time{}
+
+		deadline := time.Now().Add(100 * time.Millisecond)
+		for dst.Len() == 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if n := dst.Len(); (n == 3) != enabled {
+			t.Errorf("sink flush %v: %v bytes in the destination after a tick", enabled, n)
+		}
+		tb.Close()
+	}
+}
+
+type failingFlusher struct {
+	testWriter
+}
+
+var errSinkFlush = errors.New("sink flush error")
+
+func (f *failingFlusher) Flush() error {
+	return errSinkFlush
+}
+
+func TestSinkFlushError(t *testing.T) {
+	tb := NewBuffer(&failingFlusher{}, SetSinkFlush(true))
+	tb.Write([]byte("abc"))
+	err := tb.Flush()
+	var ferr *FlushError
+	if !errors.As(err, &ferr) || !errors.Is(err, errSinkFlush) || ferr.Written != 3 || ferr.LostData {
+		t.Errorf("flush error: %v, expected the sink Flush error", err)
+	}
+	tb.Close()
+}
+
+func TestSinkFlushHTTP(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		tb := NewBuffer(w, SetFlushInterval(interval), SetRecordMode(true), SetSinkFlush(true))
+		defer tb.Close()
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(tb, "data: %v\n\n", i)
+		}
+		// the handler keeps the response open, the events are sent by the tick
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewScanner(resp.Body)
+	for i := 0; i < 3; i++ {
+		if !events.Scan() || events.Text() != fmt.Sprintf("data: %v", i) || !events.Scan() {
+			t.Fatalf("event %v: %q, %v", i, events.Text(), events.Err())
+		}
+	}
+	// a generous bound for the loaded machines, the events wait forever without the flush
+	if elapsed := time.Since(start); elapsed > 50*interval {
+		t.Errorf("events received after %v, expected about %v", elapsed, interval)
+	}
+}
@@ -0,0 +1,89 @@
+package syncio
+
+import "sync/atomic"
+
+// SetMaxSinkWriteSize limits the size of the writes to the underlying writer to n bytes,
+// the batches are split in several writes. With SetRecordMode they are cut after the last
+// record that fits and a RecordWriter receives the records in several WriteBatch calls, a
+// record bigger than n is split in bytes, or written alone by WriteBatch. With
+// SetUTF8Boundaries the cuts are done at rune boundaries. The vectored writes are disabled.
+// On error FlushError.Written is the number of bytes of the writes done.
+func SetMaxSinkWriteSize(n int) BufferOption {
+	return func(b *Buffer) {
+		b.maxSinkWrite = n
+	}
+}
+
+// writeChunks writes p to the sink in writes of at most SetMaxSinkWriteSize bytes, cut
+// after the records when possible, records can be nil
+func (tb *Buffer) writeChunks(p []byte, records [][]byte) (int, error) {
+	if tb.maxSinkWrite <= 0 || len(p) <= tb.maxSinkWrite {
+		return tb.writeFull(p)
+	}
+	ends := tb.ends[:0]
+	end := 0
+	for _, r := range records {
+		end += len(r)
+		ends = append(ends, end)
+	}
+	tb.ends = ends
+
+	written, r := 0, 0
+	for written < len(p) {
+		limit := written + tb.maxSinkWrite
+		if limit > len(p) {
+			limit = len(p)
+		}
+		k := limit - written
+		// ends[r] is the first record end after written
+		for r < len(ends) && ends[r] <= written {
+			r++
+		}
+		if i := r; i < len(ends) && ends[i] <= limit {
+			for i+1 < len(ends) && ends[i+1] <= limit {
+				i++
+			}
+			k = ends[i] - written
+		} else if tb.utf8Boundaries && limit < len(p) {
+			if c := partialRune(p[written:limit]); c < k {
+				k -= c
+			}
+		}
+		n, err := tb.writeFull(p[written : written+k])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// writeBatches writes the records with WriteBatch calls of at most SetMaxSinkWriteSize
+// bytes, it returns the number of records written
+func (tb *Buffer) writeBatches(rw RecordWriter, records [][]byte) (int, error) {
+	written := 0
+	for written < len(records) {
+		end, size := written+1, len(records[written])
+		for tb.maxSinkWrite > 0 && end < len(records) && size+len(records[end]) <= tb.maxSinkWrite {
+			size += len(records[end])
+			end++
+		}
+		if tb.maxSinkWrite <= 0 {
+			end = len(records)
+		}
+		want := end - written
+		atomic.AddInt64(&tb.stats.SinkWrites, 1)
+		n, err := rw.WriteBatch(records[written:end])
+		if n < 0 || n > want {
+			n = 0
+		}
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if n < want {
+			return written, errShortBatch
+		}
+	}
+	return written, nil
+}
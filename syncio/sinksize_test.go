@@ -0,0 +1,72 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMaxSinkWriteSize(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		opts     []BufferOption
+		writes   []string
+		expected []string
+	}{
+		{"bytes", nil, []string{"0123456789", "0123456789", "01234"}, []string{"0123456789", "0123456789", "01234"}},
+		{"records", []BufferOption{SetRecordMode(true)}, []string{"aaaa", "bbbb", "cccccccccccccc", "dd"}, []string{"aaaabbbb", "cccccccccc", "ccccdd"}},
+		{"runes", []BufferOption{SetUTF8Boundaries(true)}, []string{strings.Repeat("€", 8)}, []string{"€€€", "€€€", "€€"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := &recordingWriter{}
+			tb := NewBuffer(w, append([]BufferOption{SetBufferSize(64), SetMaxSinkWriteSize(10)}, c.opts...)...)
+			for _, p := range c.writes {
+				tb.Write([]byte(p))
+			}
+			tb.Close()
+			if !reflect.DeepEqual(w.writes, c.expected) {
+				t.Errorf("writes: %q, expected: %q", w.writes, c.expected)
+			}
+		})
+	}
+}
+
+func TestMaxSinkWriteSizeBatches(t *testing.T) {
+	rw := &testRecordWriter{}
+	tb := NewBuffer(rw, SetBufferSize(64), SetRecordMode(true), SetMaxSinkWriteSize(10))
+	for _, r := range []string{"aaaa", "bbbb", "cccc", "dddddddddddd", "e"} {
+		tb.Write([]byte(r))
+	}
+	tb.Close()
+	expected := [][]string{{"aaaa", "bbbb"}, {"cccc"}, {"dddddddddddd"}, {"e"}}
+	if !reflect.DeepEqual(rw.batches, expected) {
+		t.Errorf("batches: %v, expected: %v", rw.batches, expected)
+	}
+}
+
+func TestMaxSinkWriteSizeError(t *testing.T) {
+	var out bytes.Buffer
+	fail := errors.New("sink down")
+	w := writerFunc(func(p []byte) (int, error) {
+		if out.Len() > 0 {
+			return 0, fail
+		}
+		return out.Write(p)
+	})
+	var ferr *FlushError
+	var unwritten []byte
+	tb := NewBuffer(w, SetBufferSize(64), SetMaxSinkWriteSize(10), SetOnFlushError(func(err *FlushError, p []byte) {
+		ferr, unwritten = err, append([]byte(nil), p...)
+	}))
+	p := []byte("0123456789abcdefghijklmno")
+	tb.Write(p)
+	tb.Close()
+	if ferr == nil || ferr.Written != 10 || ferr.Unwritten != 15 || ferr.Offset != 10 || !errors.Is(ferr, fail) {
+		t.Fatalf("flush error: %v", ferr)
+	}
+	if !bytes.Equal(unwritten, p[10:]) || !bytes.Equal(out.Bytes(), p[:10]) {
+		t.Errorf("written: %q, unwritten: %q", out.Bytes(), unwritten)
+	}
+}
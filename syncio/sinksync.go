@@ -0,0 +1,59 @@
+package syncio
+
+// syncer is implemented by the writers persisting their data on Sync, e.g. *os.File
+type syncer interface {
+	Sync() error
+}
+
+// writerSync is a Sync of the underlying writer carried by a barrier, deep syncs the writer
+// chain of FlushDeep instead
+type writerSync struct {
+	err  error
+	deep bool
+}
+
+// Sync flushes the buffered data as Flush and then calls the Sync method of the underlying
+// writer, if it has one as *os.File, so the Buffer is a WriteSyncer of the loggers like
+// zap or the slog handlers. The returned error is the Sync error or else the Flush one.
+func (tb *Buffer) Sync() error {
+	return tb.syncBarrier(false)
+}
+
+// syncBarrier flushes the buffered data and syncs the underlying writer from the flush
+// goroutine once it's written
+func (tb *Buffer) syncBarrier(deep bool) error {
+	if !tb.initialized() {
+		return ErrNotInitialized
+	}
+	tb.lockBuf()
+	if tb.closed {
+		tb.unlockBuf()
+		tb.countError(ErrorClosed)
+		return ErrWriteOnClosed
+	}
+	done := make(chan error, 1)
+	s := &writerSync{deep: deep}
+	sw := tb.flushBatch(&batch{trigger: TriggerManual, done: done, sync: s})
+	tb.unlockBuf()
+
+	if tb.logger != nil {
+		tb.logSwap(sw)
+	}
+	tb.flushInline()
+	err := <-done
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
+
+// syncWriter syncs the underlying writer from the flush goroutine
+func (tb *Buffer) syncWriter(deep bool) error {
+	if deep {
+		return tb.flushChain()
+	}
+	if s, ok := tb.writer.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeSyncer is the zapcore.WriteSyncer interface
+type writeSyncer interface {
+	io.Writer
+	Sync() error
+}
+
+var _ writeSyncer = &Buffer{}
+
+// syncSink records the data and the syncs
+type syncSink struct {
+	bytes.Buffer
+	synced int
+	err    error
+}
+
+func (s *syncSink) Sync() error {
+	s.synced = s.Len()
+	return s.err
+}
+
+// lineLogger is a minimal logger writing to a WriteSyncer as the zap core does
+type lineLogger struct {
+	out writeSyncer
+}
+
+func (l *lineLogger) Info(msg string) {
+	fmt.Fprintf(l.out, "level=info msg=%q\n", msg)
+}
+
+func (l *lineLogger) Sync() error {
+	return l.out.Sync()
+}
+
+func ExampleBuffer_Sync() {
+	sink := &syncSink{}
+	tb := NewBuffer(sink)
+	defer tb.Close()
+	logger := &lineLogger{out: tb}
+
+	logger.Info("started")
+	logger.Info("listening")
+	fmt.Printf("before sync: %q\n", sink.String())
+	if err := logger.Sync(); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Printf("after sync: %q, synced %v bytes\n", sink.String(), sink.synced)
+	// Output:
+	// before sync: ""
+	// after sync: "level=info msg=\"started\"\nlevel=info msg=\"listening\"\n", synced 52 bytes
+}
+
+func TestSync(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		f, err := os.Create(filepath.Join(t.TempDir(), "sync"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		tb := NewBuffer(f, mode...)
+		tb.Write([]byte("data"))
+		if err := tb.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(f.Name()); string(data) != "data" {
+			t.Errorf("file data after sync: %q, expected: %q", data, "data")
+		}
+
+		failing := errors.New("sync failed")
+		tb = NewBuffer(&syncSink{err: failing}, mode...)
+		tb.Write([]byte("data"))
+		if err := tb.Sync(); err != failing {
+			t.Errorf("sync: %v, expected: %v", err, failing)
+		}
+		tb.Close()
+		if err := tb.Sync(); err != ErrWriteOnClosed {
+			t.Errorf("sync on closed: %v, expected: %v", err, ErrWriteOnClosed)
+		}
+
+		// the writers without Sync are only flushed
+		sink := &bytes.Buffer{}
+		tb = NewBuffer(sink, mode...)
+		tb.Write([]byte("data"))
+		if err := tb.Sync(); err != nil || sink.String() != "data" {
+			t.Errorf("sync without Sync: %q, %v", sink.String(), err)
+		}
+		tb.Close()
+	})
+}
@@ -0,0 +1,252 @@
+// Package sinktest is the conformance suite of the underlying writers of a syncio.Buffer,
+// meant to be run by the tests of the custom sinks
+package sinktest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+// bufferSize is small so the scenarios flush many batches
+const bufferSize = 64
+
+// the interfaces of the sinks negotiated by the Buffer
+type (
+	flusher interface{ Flush() error }
+	syncer  interface{ Sync() error }
+)
+
+var modes = []struct {
+	name string
+	opts []syncio.BufferOption
+}{
+	{"goroutine", nil},
+	{"synchronous", []syncio.BufferOption{syncio.SetSynchronousMode(true)}},
+}
+
+// TestSink runs the conformance suite of a sink: every subtest creates a Buffer writing to a
+// new sink returned by newSink, and readBack must return the bytes received by the last one,
+// e.g. reading back its file. The Buffer is driven with sequential and concurrent writes,
+// ticks, flushes, SwapWriter and Close, and the data must be received byte-exact, in the
+// order of the writes of every goroutine and without losses or flush errors. The optional
+// interfaces of the sink are enabled in every Buffer: syncio.RecordWriter with SetRecordMode,
+// syncio.ContextWriter with SetFlushContext and Flush() error with SetSinkFlush, Sync() error
+// is called by Sync and FlushDeep.
+func TestSink(t *testing.T, newSink func() io.Writer, readBack func() []byte) {
+	t.Helper()
+	s := &suite{newSink: newSink, readBack: readBack}
+	for _, m := range modes {
+		m := m
+		t.Run(m.name, func(t *testing.T) {
+			t.Run("sequential", func(t *testing.T) { s.sequential(t, m.opts) })
+			t.Run("concurrent", func(t *testing.T) { s.concurrent(t, m.opts) })
+			t.Run("flush", func(t *testing.T) { s.flush(t, m.opts) })
+			t.Run("swap", func(t *testing.T) { s.swap(t, m.opts) })
+			t.Run("close", func(t *testing.T) { s.close(t, m.opts) })
+			t.Run("interfaces", func(t *testing.T) { s.interfaces(t, m.opts) })
+		})
+	}
+	t.Run("ticks", s.ticks)
+}
+
+type suite struct {
+	newSink  func() io.Writer
+	readBack func() []byte
+}
+
+// buffer returns a Buffer writing to a new sink with the options of its interfaces
+func (s *suite) buffer(mode []syncio.BufferOption, opts ...syncio.BufferOption) (*syncio.Buffer, io.Writer) {
+	w := s.newSink()
+	opts = append([]syncio.BufferOption{syncio.SetBufferSize(bufferSize)}, opts...)
+	if _, ok := w.(syncio.RecordWriter); ok {
+		opts = append(opts, syncio.SetRecordMode(true))
+	}
+	if _, ok := w.(syncio.ContextWriter); ok {
+		opts = append(opts, syncio.SetFlushContext(context.Background))
+	}
+	if _, ok := w.(flusher); ok {
+		opts = append(opts, syncio.SetSinkFlush(true))
+	}
+	return syncio.NewBuffer(w, append(opts, mode...)...), w
+}
+
+// payload returns n bytes of a pattern depending on seed
+func payload(n, seed int) []byte {
+	p := make([]byte, n)
+	for i := range p {
+		p[i] = byte('a' + (i+seed)%26)
+	}
+	return p
+}
+
+// writes writes payloads of every size around the buffer size, it returns the data written
+func writes(t *testing.T, tb *syncio.Buffer, seed int) []byte {
+	t.Helper()
+	var all []byte
+	for i, n := range []int{1, 7, bufferSize - 1, bufferSize, bufferSize + 1, 3 * bufferSize, 5} {
+		p := payload(n, seed+i)
+		if _, err := tb.Write(p); err != nil {
+			t.Fatalf("write of %v bytes: %v", n, err)
+		}
+		all = append(all, p...)
+	}
+	return all
+}
+
+// check compares the data received by the last sink with want
+func (s *suite) check(t *testing.T, tb *syncio.Buffer, want []byte) {
+	t.Helper()
+	got := s.readBack()
+	if !bytes.Equal(got, want) {
+		t.Fatalf("received %v bytes, want %v:\ngot:  %q\nwant: %q", len(got), len(want), got, want)
+	}
+	if st := tb.Stats(); st.FlushErrors != 0 {
+		t.Fatalf("%v flush errors", st.FlushErrors)
+	}
+}
+
+func (s *suite) sequential(t *testing.T, mode []syncio.BufferOption) {
+	tb, _ := s.buffer(mode)
+	want := writes(t, tb, 0)
+	if err := tb.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	s.check(t, tb, want)
+}
+
+func (s *suite) concurrent(t *testing.T, mode []syncio.BufferOption) {
+	const writers, lines = 8, 200
+	tb, _ := s.buffer(mode, syncio.SetMaxFlushBytes(4*bufferSize))
+	var wg sync.WaitGroup
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				if _, err := fmt.Fprintf(tb, "%02d %05d\n", g, i); err != nil {
+					t.Errorf("write: %v", err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if err := tb.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// every line is whole and the lines of a writer are in order
+	got := s.readBack()
+	next := make([]int, writers)
+	for n, line := range bytes.SplitAfter(got, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var g, i int
+		if _, err := fmt.Sscanf(string(line), "%02d %05d\n", &g, &i); err != nil || len(line) != 9 || g < 0 || g >= writers {
+			t.Fatalf("line %v torn: %q", n, line)
+		}
+		if i != next[g] {
+			t.Fatalf("line %v of writer %v is %v, want %v", n, g, i, next[g])
+		}
+		next[g]++
+	}
+	for g, n := range next {
+		if n != lines {
+			t.Fatalf("%v lines of writer %v received, want %v", n, g, lines)
+		}
+	}
+}
+
+func (s *suite) flush(t *testing.T, mode []syncio.BufferOption) {
+	tb, _ := s.buffer(mode)
+	defer tb.Close()
+	var want []byte
+	for i := 0; i < 5; i++ {
+		want = append(want, writes(t, tb, i)...)
+		if err := tb.Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		// the data is delivered once Flush returns
+		s.check(t, tb, want)
+	}
+}
+
+func (s *suite) swap(t *testing.T, mode []syncio.BufferOption) {
+	tb, first := s.buffer(mode)
+	want := writes(t, tb, 0)
+	if err := tb.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	s.check(t, tb, want)
+
+	old, err := tb.SwapWriter(s.newSink())
+	if err != nil {
+		t.Fatalf("SwapWriter: %v", err)
+	}
+	if old != first {
+		t.Fatalf("SwapWriter returned %T, not the first sink", old)
+	}
+	want = writes(t, tb, 1)
+	if err := tb.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	s.check(t, tb, want)
+}
+
+func (s *suite) close(t *testing.T, mode []syncio.BufferOption) {
+	tb, _ := s.buffer(mode)
+	want := writes(t, tb, 0)
+	if err := tb.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	if _, err := tb.Write([]byte("late")); err != syncio.ErrWriteOnClosed {
+		t.Fatalf("Write after Close: %v", err)
+	}
+	s.check(t, tb, want)
+}
+
+func (s *suite) interfaces(t *testing.T, mode []syncio.BufferOption) {
+	tb, w := s.buffer(mode)
+	want := writes(t, tb, 0)
+	if _, ok := w.(syncer); ok {
+		if err := tb.Sync(); err != nil {
+			t.Fatalf("Sync: %v", err)
+		}
+		s.check(t, tb, want)
+	}
+	if err := tb.FlushDeep(); err != nil {
+		t.Fatalf("FlushDeep: %v", err)
+	}
+	s.check(t, tb, want)
+	want = append(want, writes(t, tb, 1)...)
+	if err := tb.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	s.check(t, tb, want)
+}
+
+func (s *suite) ticks(t *testing.T) {
+	tb, _ := s.buffer(nil, syncio.SetManualTick(true))
+	defer tb.Close()
+	var want []byte
+	for i := 0; i < 5; i++ {
+		// a write smaller than the buffer only waits for the tick
+		p := payload(bufferSize/2, i)
+		tb.Write(p)
+		want = append(want, p...)
+		if err := tb.Tick(); err != nil {
+			t.Fatalf("Tick: %v", err)
+		}
+		s.check(t, tb, want)
+	}
+}
@@ -0,0 +1,127 @@
+package sinktest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sync"
+	"testing"
+)
+
+// memorySink is the simplest sink, safe for the reads of readBack
+type memorySink struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *memorySink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *memorySink) bytes() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]byte(nil), s.buf.Bytes()...)
+}
+
+// fullSink implements every optional interface, it holds the records until Flush
+type fullSink struct {
+	memorySink
+	pending [][]byte
+}
+
+func (s *fullSink) WriteBatch(records [][]byte) (int, error) {
+	for _, r := range records {
+		s.pending = append(s.pending, append([]byte(nil), r...))
+	}
+	return len(records), nil
+}
+
+func (s *fullSink) WriteContext(ctx context.Context, p []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.Write(p)
+}
+
+func (s *fullSink) Flush() error {
+	for _, r := range s.pending {
+		s.Write(r)
+	}
+	s.pending = nil
+	return nil
+}
+
+func (s *fullSink) Sync() error {
+	return nil
+}
+
+func TestMemorySink(t *testing.T) {
+	var last *memorySink
+	TestSink(t, func() io.Writer {
+		last = &memorySink{}
+		return last
+	}, func() []byte { return last.bytes() })
+}
+
+func TestFullSink(t *testing.T) {
+	var last *fullSink
+	TestSink(t, func() io.Writer {
+		last = &fullSink{}
+		return last
+	}, func() []byte { return last.bytes() })
+}
+
+// shortSink accepts at most 5 bytes per write, the Buffer retries the rest
+type shortSink struct {
+	memorySink
+}
+
+func (s *shortSink) Write(p []byte) (int, error) {
+	if len(p) > 5 {
+		p = p[:5]
+	}
+	return s.memorySink.Write(p)
+}
+
+func TestShortWritesSink(t *testing.T) {
+	var last *shortSink
+	TestSink(t, func() io.Writer {
+		last = &shortSink{}
+		return last
+	}, func() []byte { return last.bytes() })
+}
+
+// fileSink buffers the writes to a file until Flush
+type fileSink struct {
+	*bufio.Writer
+	f *os.File
+}
+
+func (s *fileSink) Sync() error {
+	return s.f.Sync()
+}
+
+func TestFileSink(t *testing.T) {
+	dir := t.TempDir()
+	var last *fileSink
+	TestSink(t, func() io.Writer {
+		f, err := os.CreateTemp(dir, "sink")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		last = &fileSink{bufio.NewWriter(f), f}
+		return last
+	}, func() []byte {
+		p, err := os.ReadFile(last.f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	})
+}
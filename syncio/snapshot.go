@@ -0,0 +1,93 @@
+package syncio
+
+import "github.com/travelgateX/go-io/syncio/internal"
+
+// Snapshot returns a copy of the data accepted but not yet sent to the underlying writer,
+// the queued batches, the ones spilled by SetSpillDir and the active buffer. The batch
+// currently being written by the flush goroutine and the data of WriteClass are not included.
+// Writes are never torn: each one is either entirely in the snapshot or not at all.
+func (tb *Buffer) Snapshot() []byte {
+	if !tb.initialized() {
+		return nil
+	}
+	tb.lockBuf()
+	defer tb.unlockBuf()
+	p, _ := tb.pendingBytes(false)
+	return p
+}
+
+// Steal removes and returns the data that Snapshot would return, the stolen data
+// won't be written to the underlying writer
+func (tb *Buffer) Steal() []byte {
+	if !tb.initialized() {
+		return nil
+	}
+	tb.lockBuf()
+	p, freed := tb.pendingBytes(true)
+	tb.unlockBuf()
+
+	// out of the lock, putting buffers back may log
+	for _, b := range freed {
+		tb.putBuffer(b)
+	}
+	return p
+}
+
+// pendingBytes copies the queued and buffered data, if remove is true the data is
+// discarded keeping the barriers in the queue and the released buffers are returned.
+// The caller must hold bufmu.
+func (tb *Buffer) pendingBytes(remove bool) (p []byte, freed []*internal.Buffer) {
+	size := tb.buf.Buffered()
+	for _, b := range tb.queue {
+		size += b.len()
+	}
+	if tb.spill != nil {
+		size += int(tb.spill.size)
+	}
+	p = make([]byte, 0, size)
+
+	batches := tb.queue
+	if remove && tb.spill != nil {
+		// the spilled batches are read back to be removed as the queued ones
+		batches = append(batches, tb.spill.abandon(tb)...)
+	}
+	queue := batches[:0]
+	for _, b := range batches {
+		p = append(p, b.bytes()...)
+		if !remove {
+			continue
+		}
+		tb.commitWAL(b.walOff, b.len())
+		if b.buf != nil {
+			freed = append(freed, b.buf)
+		}
+		b.buf, b.p = nil, nil
+		if b.done != nil || b.classes {
+			queue = append(queue, b)
+		}
+	}
+	if !remove && tb.spill != nil {
+		p = tb.spill.peek(p)
+	}
+	p = append(p, tb.buf.Bytes()...)
+
+	if remove {
+		tb.acct.remove(len(p))
+		if tb.wal != nil {
+			n := tb.buf.Buffered()
+			tb.commitWAL(tb.walRange(n), n)
+		}
+		for i := len(queue); i < len(batches); i++ {
+			batches[i] = nil
+		}
+		tb.queue = queue
+		tb.buf.Reset()
+		tb.childBytes = 0
+		if tb.backlog != nil {
+			tb.backlog.active.Store(0)
+			tb.backlog.check(tb)
+		}
+		tb.space.Broadcast()
+	}
+	return p, freed
+}
@@ -0,0 +1,84 @@
+package syncio
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingWriter blocks every write until release is closed
+type blockingWriter struct {
+	release chan struct{}
+	mu      sync.Mutex
+	out     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.out.Write(p)
+}
+
+func TestSnapshotAndSteal(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(4))
+
+	tb.Write([]byte("aaa")) // in flight once the next write fills the buffer
+	tb.Write([]byte("bbb"))
+	time.Sleep(10 * time.Millisecond)
+	tb.Write([]byte("ccc"))
+	tb.Write([]byte("dd"))
+
+	if s := string(tb.Snapshot()); s != "bbbcccdd" {
+		t.Errorf("snapshot: %q, expected: %q", s, "bbbcccdd")
+	}
+	// snapshot doesn't modify the buffer
+	if s := string(tb.Snapshot()); s != "bbbcccdd" {
+		t.Errorf("second snapshot: %q, expected: %q", s, "bbbcccdd")
+	}
+	if s := string(tb.Steal()); s != "bbbcccdd" {
+		t.Errorf("steal: %q, expected: %q", s, "bbbcccdd")
+	}
+	if s := tb.Snapshot(); len(s) != 0 {
+		t.Errorf("snapshot after steal: %q, expected empty", s)
+	}
+
+	tb.Write([]byte("e"))
+	close(bw.release)
+	tb.Close()
+	if s := bw.out.String(); s != "aaae" {
+		t.Errorf("written: %q, expected: %q", s, "aaae")
+	}
+}
+
+func TestStealConcurrentWrites(t *testing.T) {
+	tw := &testWriter{}
+	tb := NewBuffer(tw, SetBufferSize(64))
+
+	record := []byte("0123456789")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				tb.Write(record)
+			}
+		}()
+	}
+	var stolen int
+	for i := 0; i < 50; i++ {
+		p := tb.Steal()
+		if len(p)%len(record) != 0 {
+			t.Fatalf("torn record: stolen %v bytes", len(p))
+		}
+		stolen += len(p)
+	}
+	wg.Wait()
+	tb.Close()
+	if total := stolen + int(tw.bytes); total != 8*100*len(record) {
+		t.Errorf("stolen + written: %v, expected: %v", total, 8*100*len(record))
+	}
+}
@@ -0,0 +1,87 @@
+package syncio
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio/synctest"
+)
+
+// countingWriter counts the bytes written
+type countingWriter struct {
+	bytes int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	atomic.AddInt64(&w.bytes, int64(len(p)))
+	return len(p), nil
+}
+
+// TestSoakChaos runs a Buffer against a misbehaving sink and checks that every accepted byte
+// is written, dropped after a flush error or abandoned on close
+func TestSoakChaos(t *testing.T) {
+	if testing.Short() {
+		t.Skip("soak test")
+	}
+	out := &countingWriter{}
+	cw := synctest.NewChaosWriter(out, 1)
+	cw.ErrorRate = 0.05
+	cw.ShortRate = 0.05
+	cw.HangRate = 0.01
+	cw.MaxHang = 50 * time.Millisecond
+	cw.SlowRate = 0.1
+	cw.Delay = time.Millisecond
+
+	var dropped int64
+	dl := &countingWriter{}
+	tb := NewBuffer(cw,
+		SetBufferSize(1024),
+		SetFlushInterval(10*time.Millisecond),
+		SetOnFlushError(func(_ *FlushError, p []byte) { atomic.AddInt64(&dropped, int64(len(p))) }),
+		SetDeadLetter(dl),
+	)
+
+	var in int64
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := bytes.Repeat([]byte{'a'}, 10+i*100)
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if n, err := tb.Write(p); err == nil {
+					atomic.AddInt64(&in, int64(n))
+				}
+				if i == 0 {
+					tb.Flush()
+				}
+			}
+		}(i)
+	}
+	time.Sleep(2 * time.Second)
+	close(stop)
+	wg.Wait()
+	abandoned, _ := tb.CloseTimeout(20 * time.Millisecond)
+	tb.Close()
+
+	t.Logf("chaos writer calls: %v, errors: %v, short writes: %v", cw.Calls(), cw.Errors(), cw.ShortWrites())
+	t.Logf("in: %v, out: %v, dropped: %v, abandoned: %v", in, out.bytes, dropped, abandoned)
+	if in != out.bytes+dropped+abandoned {
+		t.Errorf("bytes in: %v, expected out + dropped + abandoned: %v", in, out.bytes+dropped+abandoned)
+	}
+	if dl.bytes != dropped+abandoned {
+		t.Errorf("dead letter bytes: %v, expected dropped + abandoned: %v", dl.bytes, dropped+abandoned)
+	}
+	if cw.Errors() == 0 || cw.ShortWrites() == 0 {
+		t.Errorf("no failures injected")
+	}
+}
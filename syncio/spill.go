@@ -0,0 +1,229 @@
+package syncio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// spill files start with spillMagic followed by the format version, the records are a header
+// of recordMagic, the payload length and the CRC32C of the length and the payload, all little
+// endian, followed by the payload
+const (
+	spillMagic        = "SYSP"
+	spillVersion      = 1
+	spillHeaderSize   = len(spillMagic) + 1
+	spillExt          = ".spill"
+	recordMagic       = 0x5243_5953
+	recordHeaderSize  = 12
+	maxSpillRecordLen = 1<<31 - 1
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrSpillVersion is returned when a spill file has an unknown format version
+var ErrSpillVersion = errors.New("unknown spill file version")
+
+// SpillWriter writes every Write as a CRC protected record to a new file of a spill directory,
+// it's meant to be the dead letter of a Buffer (see SetDeadLetter) so the discarded data
+// can be replayed with RecoverSpill after a crash. It's safe for concurrent use.
+type SpillWriter struct {
+	name string
+
+	mu   sync.Mutex
+	f    *os.File
+	hdr  [recordHeaderSize]byte
+	size int64
+}
+
+// NewSpillWriter creates a new spill file in dir, the files are named to be recovered in
+// creation order
+func NewSpillWriter(dir string) (*SpillWriter, error) {
+	name := filepath.Join(dir, fmt.Sprintf("%020d%s", time.Now().UnixNano(), spillExt))
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(append([]byte(spillMagic), spillVersion)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &SpillWriter{name: name, f: f, size: int64(spillHeaderSize)}, nil
+}
+
+// Name returns the path of the spill file
+func (s *SpillWriter) Name() string {
+	return s.name
+}
+
+// Write writes p as a single record
+func (s *SpillWriter) Write(p []byte) (int, error) {
+	if len(p) > maxSpillRecordLen {
+		return 0, fmt.Errorf("spill record of %v bytes is too big", len(p))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return 0, ErrWriteOnClosed
+	}
+	binary.LittleEndian.PutUint32(s.hdr[0:], recordMagic)
+	binary.LittleEndian.PutUint32(s.hdr[4:], uint32(len(p)))
+	crc := crc32.Update(crc32.Checksum(s.hdr[4:8], castagnoli), castagnoli, p)
+	binary.LittleEndian.PutUint32(s.hdr[8:], crc)
+	if _, err := s.f.Write(s.hdr[:]); err != nil {
+		return 0, err
+	}
+	n, err := s.f.Write(p)
+	s.size += int64(recordHeaderSize + n)
+	return n, err
+}
+
+// Size returns the size of the spill file
+func (s *SpillWriter) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// Sync commits the spill file to stable storage
+func (s *SpillWriter) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return ErrWriteOnClosed
+	}
+	return s.f.Sync()
+}
+
+// Close syncs and closes the spill file
+func (s *SpillWriter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Sync()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	s.f = nil
+	return err
+}
+
+// remove closes the spill file without syncing it and removes it
+func (s *SpillWriter) remove() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f != nil {
+		s.f.Close()
+		s.f = nil
+	}
+	return os.Remove(s.name)
+}
+
+// RecoverStats are the counters of a RecoverSpill
+type RecoverStats struct {
+	Files int
+	// Records and Bytes are the intact records passed to fn and their payload size
+	Records int64
+	Bytes   int64
+	// SkippedRecords is the number of corrupt or truncated regions skipped and SkippedBytes
+	// their size, a file with an invalid header is skipped as a whole
+	SkippedRecords int64
+	SkippedBytes   int64
+}
+
+// RecoverSpill calls fn with the intact records of the spill files in dir in the order they
+// were written, the corrupt records are skipped and the recovery continues with the next
+// intact record. record is only valid during the call, an error of fn stops the recovery and is
+// returned. The files are not removed and must not be in use by a SpillWriter.
+func RecoverSpill(dir string, fn func(record []byte) error) (RecoverStats, error) {
+	var stats RecoverStats
+	names, err := filepath.Glob(filepath.Join(dir, "*"+spillExt))
+	if err != nil {
+		return stats, err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return stats, err
+		}
+		stats.Files++
+		if err := recoverFile(data, &stats, fn); err != nil {
+			return stats, fmt.Errorf("recover spill file %v: %w", name, err)
+		}
+	}
+	return stats, nil
+}
+
+func recoverFile(data []byte, stats *RecoverStats, fn func(record []byte) error) error {
+	if len(data) < spillHeaderSize || string(data[:len(spillMagic)]) != spillMagic {
+		stats.SkippedRecords++
+		stats.SkippedBytes += int64(len(data))
+		return nil
+	}
+	if v := data[len(spillMagic)]; v != spillVersion {
+		return fmt.Errorf("%w: %v", ErrSpillVersion, v)
+	}
+
+	var magic [4]byte
+	binary.LittleEndian.PutUint32(magic[:], recordMagic)
+	// corrupt is the start of the bytes being skipped or -1
+	off, corrupt := spillHeaderSize, -1
+	for off < len(data) {
+		p, ok := spillRecord(data[off:])
+		if !ok {
+			if corrupt < 0 {
+				corrupt = off
+			}
+			// resynchronize at the next record magic
+			next := bytes.Index(data[off+1:], magic[:])
+			if next < 0 {
+				break
+			}
+			off += 1 + next
+			continue
+		}
+		if corrupt >= 0 {
+			stats.SkippedRecords++
+			stats.SkippedBytes += int64(off - corrupt)
+			corrupt = -1
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+		stats.Records++
+		stats.Bytes += int64(len(p))
+		off += recordHeaderSize + len(p)
+	}
+	if corrupt >= 0 {
+		stats.SkippedRecords++
+		stats.SkippedBytes += int64(len(data) - corrupt)
+	}
+	return nil
+}
+
+// spillRecord returns the payload of the record at the start of data if it's intact
+func spillRecord(data []byte) ([]byte, bool) {
+	if len(data) < recordHeaderSize || binary.LittleEndian.Uint32(data) != recordMagic {
+		return nil, false
+	}
+	n := binary.LittleEndian.Uint32(data[4:])
+	if uint64(n) > uint64(len(data)-recordHeaderSize) {
+		return nil, false
+	}
+	p := data[recordHeaderSize : recordHeaderSize+int(n)]
+	crc := crc32.Update(crc32.Checksum(data[4:8], castagnoli), castagnoli, p)
+	if crc != binary.LittleEndian.Uint32(data[8:]) {
+		return nil, false
+	}
+	return p, true
+}
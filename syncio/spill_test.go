@@ -0,0 +1,166 @@
+package syncio
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// writeSpill writes the records to a new spill file of dir and returns its name
+func writeSpill(t *testing.T, dir string, records ...string) string {
+	t.Helper()
+	s, err := NewSpillWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		if _, err := s.Write([]byte(r)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return s.Name()
+}
+
+func recoverAll(t *testing.T, dir string) ([]string, RecoverStats) {
+	t.Helper()
+	var records []string
+	stats, err := RecoverSpill(dir, func(r []byte) error {
+		records = append(records, string(r))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records, stats
+}
+
+func TestSpillDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewSpillWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail := writerFunc(func(p []byte) (int, error) { return 0, errors.New("sink down") })
+	tb := NewBuffer(fail, SetBufferSize(8), SetDeadLetter(s))
+	tb.Write([]byte("abcde"))
+	tb.Flush()
+	tb.Write([]byte("fgh"))
+	tb.Close()
+	s.Close()
+
+	records, stats := recoverAll(t, dir)
+	if !reflect.DeepEqual(records, []string{"abcde", "fgh"}) {
+		t.Errorf("recovered records: %q", records)
+	}
+	if stats != (RecoverStats{Files: 1, Records: 2, Bytes: 8}) {
+		t.Errorf("recover stats: %+v", stats)
+	}
+}
+
+func TestRecoverSpillCorrupt(t *testing.T) {
+	records := []string{"first", "second", "third"}
+	// the offset of the second record
+	second := int64(spillHeaderSize + recordHeaderSize + len(records[0]))
+
+	tests := []struct {
+		name     string
+		corrupt  func(name string)
+		expected []string
+		stats    RecoverStats
+	}{
+		{
+			name:     "truncated tail",
+			corrupt:  func(name string) { os.Truncate(name, second+recordHeaderSize+2) },
+			expected: []string{"first"},
+			stats:    RecoverStats{Files: 1, Records: 1, Bytes: 5, SkippedRecords: 1, SkippedBytes: recordHeaderSize + 2},
+		},
+		{
+			name:     "truncated header",
+			corrupt:  func(name string) { os.Truncate(name, second+3) },
+			expected: []string{"first"},
+			stats:    RecoverStats{Files: 1, Records: 1, Bytes: 5, SkippedRecords: 1, SkippedBytes: 3},
+		},
+		{
+			name:     "payload bit flip",
+			corrupt:  func(name string) { flipBit(t, name, second+recordHeaderSize+1) },
+			expected: []string{"first", "third"},
+			stats:    RecoverStats{Files: 1, Records: 2, Bytes: 10, SkippedRecords: 1, SkippedBytes: recordHeaderSize + 6},
+		},
+		{
+			name:     "length bit flip",
+			corrupt:  func(name string) { flipBit(t, name, second+4) },
+			expected: []string{"first", "third"},
+			stats:    RecoverStats{Files: 1, Records: 2, Bytes: 10, SkippedRecords: 1, SkippedBytes: recordHeaderSize + 6},
+		},
+		{
+			name:     "file header",
+			corrupt:  func(name string) { flipBit(t, name, 0) },
+			expected: nil,
+			stats:    RecoverStats{Files: 1, SkippedRecords: 1, SkippedBytes: second + 2*recordHeaderSize + 11},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.corrupt(writeSpill(t, dir, records...))
+			got, stats := recoverAll(t, dir)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("recovered records: %q, expected: %q", got, tt.expected)
+			}
+			if stats != tt.stats {
+				t.Errorf("recover stats: %+v, expected: %+v", stats, tt.stats)
+			}
+		})
+	}
+}
+
+func flipBit(t *testing.T, name string, off int64) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[off] ^= 0x10
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecoverSpillFiles(t *testing.T) {
+	dir := t.TempDir()
+	var expected []string
+	for i := 0; i < 3; i++ {
+		r := strconv.Itoa(i)
+		writeSpill(t, dir, r, r+r)
+		expected = append(expected, r, r+r)
+	}
+	got, stats := recoverAll(t, dir)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("recovered records: %q, expected: %q", got, expected)
+	}
+	if stats.Files != 3 || stats.Records != 6 {
+		t.Errorf("recover stats: %+v", stats)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	stats, err := RecoverSpill(dir, func([]byte) error {
+		if n++; n == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || stats.Records != 1 {
+		t.Errorf("recover with fn error: %v, stats: %+v", err, stats)
+	}
+
+	os.WriteFile(filepath.Join(dir, "z"+spillExt), []byte(spillMagic+"\x09"), 0o644)
+	if _, err := RecoverSpill(dir, func([]byte) error { return nil }); !errors.Is(err, ErrSpillVersion) {
+		t.Errorf("recover unknown version: %v, expected: %v", err, ErrSpillVersion)
+	}
+}
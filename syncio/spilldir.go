@@ -0,0 +1,268 @@
+package syncio
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// errSpillLost is returned by the reads of a spill file that failed before
+var errSpillLost = errors.New("spill file unreadable")
+
+// SetSpillDir spills the batches to a file in dir instead of blocking the writes when the
+// queue holds SetBufferPoolSize batches, they are read back in order as the underlying writer
+// catches up. Once a batch is spilled the next ones follow it until the file is drained, so the
+// order is kept, and the writes wait for space while the spilled data would exceed maxBytes.
+// The file is a SpillWriter file removed once drained, the data left by a crash can be
+// recovered with RecoverSpill. An empty dir is os.TempDir and a maxBytes not positive disables
+// it. Stats.SpilledBytes counts the bytes spilled and SpillBytes the ones not read back; the
+// batches that can't be spilled are kept in memory in order and counted in SpillErrors, as the
+// spilled batches lost by a failed read, which are counted as ErrorDropped too.
+func SetSpillDir(dir string, maxBytes int64) BufferOption {
+	return func(b *Buffer) {
+		b.spill = nil
+		if maxBytes > 0 {
+			if dir == "" {
+				dir = os.TempDir()
+			}
+			b.spill = &spillQueue{dir: dir, max: maxBytes}
+		}
+	}
+}
+
+// spillQueue is the end of the queue spilled to disk, it goes after tb.queue and before the
+// active buffer. It's guarded by bufmu.
+type spillQueue struct {
+	dir string
+	max int64
+	// w is the spill file and r its read side, they are nil when nothing is spilled. broken
+	// reports a failed write, the next batches are kept in memory until the file is drained.
+	w      *SpillWriter
+	r      *os.File
+	broken bool
+	// items are the spilled records and the batches kept in memory between them, in order
+	items []spillItem
+	// size is the data size of the items
+	size int64
+	// scratch is the memory of the records read back
+	scratch []byte
+}
+
+// spillItem is a spilled record of n bytes or, if b isn't nil, a batch kept in memory: the
+// barriers of the spilled batches and the batches that couldn't be spilled
+type spillItem struct {
+	n       int
+	since   int64
+	walOff  int64
+	trigger FlushTrigger
+	child   int
+	b       *batch
+}
+
+// queueFull reports if a write of n bytes must wait for the flush goroutine: the queue holds
+// poolSize batches or, with SetSpillDir, the spill file is full. The caller must hold bufmu.
+func (tb *Buffer) queueFull(n int) bool {
+	if tb.spill != nil {
+		return tb.spill.full(tb, n)
+	}
+	return len(tb.queue) >= tb.poolSize
+}
+
+// spilling reports if the next batches go to the spill file
+func (sq *spillQueue) spilling(tb *Buffer) bool {
+	return len(sq.items) > 0 || len(tb.queue) >= tb.poolSize
+}
+
+// full reports if the spill file has no space for n bytes and the active buffer data
+func (sq *spillQueue) full(tb *Buffer, n int) bool {
+	return sq.spilling(tb) && sq.size+int64(tb.buf.Buffered()+n) > sq.max
+}
+
+// add counts n bytes of the items
+func (sq *spillQueue) add(tb *Buffer, n int) {
+	sq.size += int64(n)
+	atomic.StoreInt64(&tb.stats.SpillBytes, sq.size)
+}
+
+// take spills b when the queue is full or there is data spilled, it returns false if b must
+// be queued
+func (sq *spillQueue) take(tb *Buffer, b *batch) bool {
+	if !sq.spilling(tb) {
+		return false
+	}
+	n := b.len()
+	if n == 0 || b.classes {
+		sq.items = append(sq.items, spillItem{b: b})
+		return true
+	}
+	sq.add(tb, n)
+	if err := sq.write(b.bytes()); err != nil {
+		atomic.AddInt64(&tb.stats.SpillErrors, 1)
+		sq.items = append(sq.items, spillItem{n: n, b: b})
+		return true
+	}
+	atomic.AddInt64(&tb.stats.SpilledBytes, int64(n))
+	sq.items = append(sq.items, spillItem{n: n, since: b.since, walOff: b.walOff, trigger: b.trigger, child: b.childBytes})
+	if b.buf != nil {
+		tb.putBuffer(b.buf)
+	}
+	if b.done != nil || b.swap != nil || b.seek != nil || b.sync != nil {
+		// the barrier goes after the spilled data
+		sq.items = append(sq.items, spillItem{b: &batch{trigger: b.trigger, done: b.done, swap: b.swap, seek: b.seek, sync: b.sync}})
+	}
+	return true
+}
+
+// write appends p to the spill file as a record, the file is created by the first one
+func (sq *spillQueue) write(p []byte) error {
+	if sq.broken {
+		return errSpillLost
+	}
+	if sq.w == nil {
+		w, err := NewSpillWriter(sq.dir)
+		if err != nil {
+			return err
+		}
+		r, err := os.Open(w.Name())
+		if err == nil {
+			_, err = r.Seek(int64(spillHeaderSize), io.SeekStart)
+			if err != nil {
+				r.Close()
+			}
+		}
+		if err != nil {
+			w.remove()
+			return err
+		}
+		sq.w, sq.r = w, r
+	}
+	if _, err := sq.w.Write(p); err != nil {
+		// the records after a partial one can't be read back
+		sq.broken = true
+		return err
+	}
+	return nil
+}
+
+// refill moves the items to the queue while it has space, the file is removed once drained
+func (sq *spillQueue) refill(tb *Buffer) {
+	for len(sq.items) > 0 && len(tb.queue) < tb.poolSize {
+		it := sq.items[0]
+		sq.items[0] = spillItem{}
+		sq.items = sq.items[1:]
+		if b := sq.batch(tb, it); b != nil {
+			tb.queue = append(tb.queue, b)
+		}
+	}
+	if len(sq.items) == 0 {
+		sq.reset()
+	}
+}
+
+// batch returns the batch of an item, the spilled records are read back. It returns nil if
+// the record is lost.
+func (sq *spillQueue) batch(tb *Buffer, it spillItem) *batch {
+	sq.add(tb, -it.n)
+	if it.b != nil {
+		return it.b
+	}
+	p, err := sq.read(it.n)
+	if err != nil {
+		atomic.AddInt64(&tb.stats.SpillErrors, 1)
+		tb.acct.remove(it.n)
+		tb.commitWAL(it.walOff, it.n)
+		tb.countError(ErrorDropped)
+		return nil
+	}
+	b := &batch{since: it.since, walOff: it.walOff, trigger: it.trigger, childBytes: it.child}
+	if buf, _ := tb.getBuffer(); len(p) <= buf.Available() {
+		buf.Write(p)
+		b.buf = buf
+	} else {
+		tb.putBuffer(buf)
+		b.p = append([]byte(nil), p...)
+	}
+	return b
+}
+
+// read reads the next record of the spill file, a failure makes the rest unreadable
+func (sq *spillQueue) read(n int) ([]byte, error) {
+	if sq.r == nil {
+		return nil, errSpillLost
+	}
+	size := recordHeaderSize + n
+	if cap(sq.scratch) < size {
+		sq.scratch = make([]byte, size)
+	}
+	data := sq.scratch[:size]
+	_, err := io.ReadFull(sq.r, data)
+	p, ok := spillRecord(data)
+	if err == nil && (!ok || len(p) != n) {
+		err = errSpillLost
+	}
+	if err != nil {
+		sq.r.Close()
+		sq.r = nil
+		return nil, err
+	}
+	return p, nil
+}
+
+// reset removes the drained spill file
+func (sq *spillQueue) reset() {
+	sq.broken = false
+	sq.scratch = nil
+	if sq.r != nil {
+		sq.r.Close()
+		sq.r = nil
+	}
+	if sq.w != nil {
+		sq.w.remove()
+		sq.w = nil
+	}
+}
+
+// peek appends the data of the items to p without reading them back, the records that can't
+// be read are left out
+func (sq *spillQueue) peek(p []byte) []byte {
+	var off int64
+	if sq.r != nil {
+		off, _ = sq.r.Seek(0, io.SeekCurrent)
+	}
+	for _, it := range sq.items {
+		if it.b != nil {
+			p = append(p, it.b.bytes()...)
+			continue
+		}
+		if sq.r == nil {
+			continue
+		}
+		size := recordHeaderSize + it.n
+		if cap(sq.scratch) < size {
+			sq.scratch = make([]byte, size)
+		}
+		data := sq.scratch[:size]
+		if _, err := sq.r.ReadAt(data, off); err == nil {
+			if rec, ok := spillRecord(data); ok && len(rec) == it.n {
+				p = append(p, rec...)
+			}
+		}
+		off += int64(size)
+	}
+	return p
+}
+
+// abandon empties the spill queue for CloseTimeout and CloseAbandon, it returns the batches
+// in order
+func (sq *spillQueue) abandon(tb *Buffer) []*batch {
+	var queue []*batch
+	for _, it := range sq.items {
+		if b := sq.batch(tb, it); b != nil {
+			queue = append(queue, b)
+		}
+	}
+	sq.items = nil
+	sq.reset()
+	return queue
+}
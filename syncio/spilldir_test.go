@@ -0,0 +1,286 @@
+package syncio
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatedWriter is a sink blocked until release is closed
+type gatedWriter struct {
+	release chan struct{}
+	out     lockedBuffer
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{release: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return w.out.Write(p)
+}
+
+func spillFiles(t *testing.T, dir string) int {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join(dir, "*"+spillExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(names)
+}
+
+func TestSpillDir(t *testing.T) {
+	dir := t.TempDir()
+	sink := newGatedWriter()
+	tb := NewBuffer(sink, SetBufferSize(16), SetBufferPoolSize(1), SetSpillDir(dir, 1<<20))
+	var want strings.Builder
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(&want, "record %02d\n", i)
+			fmt.Fprintf(tb, "record %02d\n", i)
+		}
+	}()
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("writes blocked by the sink")
+	}
+	s := tb.Stats()
+	if s.SpilledBytes == 0 || s.SpillBytes != s.SpilledBytes {
+		t.Errorf("stats: spilled %v, on disk %v, expected the same non zero size", s.SpilledBytes, s.SpillBytes)
+	}
+	if n := spillFiles(t, dir); n != 1 {
+		t.Errorf("spill files: %v, expected: 1", n)
+	}
+
+	close(sink.release)
+	if err := tb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if out := sink.out.String(); out != want.String() {
+		t.Errorf("written:\n%v\nexpected:\n%v", out, want.String())
+	}
+	if s := tb.Stats(); s.SpillBytes != 0 || s.SpillErrors != 0 {
+		t.Errorf("stats after the drain: %v bytes on disk, %v errors", s.SpillBytes, s.SpillErrors)
+	}
+	if n := spillFiles(t, dir); n != 0 {
+		t.Errorf("spill files after the drain: %v, expected: 0", n)
+	}
+	tb.Close()
+}
+
+func TestSpillDirFull(t *testing.T) {
+	const maxBytes = 64
+	sink := newGatedWriter()
+	tb := NewBuffer(sink, SetBufferSize(16), SetBufferPoolSize(1), SetSpillDir(t.TempDir(), maxBytes))
+	var records atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			if _, err := fmt.Fprintf(tb, "record %02d\n", i); err != nil {
+				t.Errorf("write %v: %v", i, err)
+				return
+			}
+			records.Add(1)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if n := records.Load(); n == 50 {
+		t.Error("writes not blocked by a full spill file")
+	}
+	if s := tb.Stats(); s.SpillBytes > maxBytes {
+		t.Errorf("spilled: %v bytes, expected %v at most", s.SpillBytes, maxBytes)
+	}
+	close(sink.release)
+	<-done
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := records.Load(); n != 50 {
+		t.Fatalf("records written: %v, expected: 50", n)
+	}
+	lines := strings.Split(strings.TrimSuffix(sink.out.String(), "\n"), "\n")
+	if len(lines) != 50 {
+		t.Fatalf("lines: %v, expected: 50", len(lines))
+	}
+	for i, line := range lines {
+		if expected := fmt.Sprintf("record %02d", i); line != expected {
+			t.Fatalf("line %v: %q, expected: %q", i, line, expected)
+		}
+	}
+}
+
+// eventWriter is a gatedWriter logging the writes and the Seek and Sync calls
+type eventWriter struct {
+	*gatedWriter
+	mu     sync.Mutex
+	events []string
+}
+
+func (w *eventWriter) log(event string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.events = append(w.events, event)
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	n, err := w.gatedWriter.Write(p)
+	w.log(string(p))
+	return n, err
+}
+
+func (w *eventWriter) Seek(offset int64, whence int) (int64, error) {
+	w.log("seek")
+	return 0, nil
+}
+
+func (w *eventWriter) Sync() error {
+	w.log("sync")
+	return nil
+}
+
+func TestSpillDirBarriers(t *testing.T) {
+	// the barriers spilled are done after the data spilled before them
+	barriers := map[string]func(tb *Buffer) error{
+		"sync": func(tb *Buffer) error { return tb.Sync() },
+		"seek": func(tb *Buffer) error {
+			_, err := tb.Seek(0, io.SeekCurrent)
+			return err
+		},
+		"swap": func(tb *Buffer) error {
+			_, err := tb.SwapWriter(io.Discard)
+			return err
+		},
+	}
+	for name, barrier := range barriers {
+		t.Run(name, func(t *testing.T) {
+			sink := &eventWriter{gatedWriter: newGatedWriter()}
+			tb := NewBuffer(sink, SetBufferSize(16), SetBufferPoolSize(1), SetSpillDir(t.TempDir(), 1<<20))
+			var want strings.Builder
+			done := make(chan error, 1)
+			go func() {
+				for i := 0; i < 6; i++ {
+					fmt.Fprintf(&want, "record %02d\n", i)
+					fmt.Fprintf(tb, "record %02d\n", i)
+				}
+				done <- barrier(tb)
+			}()
+			for deadline := time.Now().Add(time.Second); tb.Stats().SpillBytes == 0; {
+				if time.Now().After(deadline) {
+					t.Fatal("nothing spilled")
+				}
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(10 * time.Millisecond)
+			close(sink.release)
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			if err := tb.Close(); err != nil {
+				t.Fatal(err)
+			}
+			sink.mu.Lock()
+			defer sink.mu.Unlock()
+			written := strings.Join(sink.events, "")
+			if name != "swap" {
+				written = strings.TrimSuffix(written, name)
+			}
+			if written != want.String() {
+				t.Errorf("events: %q, expected the records then %v", sink.events, name)
+			}
+		})
+	}
+}
+
+func TestSpillDirError(t *testing.T) {
+	// the batches that can't be spilled stay in memory in order
+	sink := newGatedWriter()
+	tb := NewBuffer(sink, SetBufferSize(16), SetBufferPoolSize(1), SetSpillDir(filepath.Join(t.TempDir(), "missing"), 1<<20))
+	var want strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&want, "record %02d\n", i)
+		fmt.Fprintf(tb, "record %02d\n", i)
+	}
+	close(sink.release)
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out := sink.out.String(); out != want.String() {
+		t.Errorf("written:\n%v\nexpected:\n%v", out, want.String())
+	}
+	if s := tb.Stats(); s.SpillErrors == 0 || s.SpilledBytes != 0 {
+		t.Errorf("stats: %v errors and %v bytes spilled, expected errors only", s.SpillErrors, s.SpilledBytes)
+	}
+}
+
+func TestSpillDirAbandon(t *testing.T) {
+	dir := t.TempDir()
+	sink := newGatedWriter()
+	defer close(sink.release)
+	var dl lockedBuffer
+	tb := NewBuffer(sink, SetBufferSize(16), SetBufferPoolSize(1), SetSpillDir(dir, 1<<20), SetDeadLetter(&dl))
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(tb, "record %02d\n", i)
+	}
+	unflushed, err := tb.CloseTimeout(10 * time.Millisecond)
+	if err == nil || unflushed == 0 {
+		t.Fatalf("close: %v, %v, expected abandoned data", unflushed, err)
+	}
+	if int64(dl.Len()) != unflushed {
+		t.Errorf("dead letter: %v bytes, expected: %v", dl.Len(), unflushed)
+	}
+	if n := spillFiles(t, dir); n != 0 {
+		t.Errorf("spill files after abandoning: %v, expected: 0", n)
+	}
+}
+
+func TestSpillDirSteal(t *testing.T) {
+	dir := t.TempDir()
+	sink := newGatedWriter()
+	tb := NewBuffer(sink, SetBufferSize(16), SetBufferPoolSize(1), SetSpillDir(dir, 1<<20))
+	var want strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&want, "record %02d\n", i)
+		fmt.Fprintf(tb, "record %02d\n", i)
+	}
+	// the sink holds the first batch, the rest stays pending
+	for deadline := time.Now().Add(time.Second); tb.Stats().Flushes == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("batch not in flight")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if s := tb.Stats(); s.SpillBytes == 0 {
+		t.Fatal("nothing spilled")
+	}
+	snapshot := tb.Snapshot()
+	if s := tb.Stats(); int64(len(snapshot)) != s.PendingBytes || !strings.HasSuffix(want.String(), string(snapshot)) {
+		t.Errorf("snapshot: %q, pending: %v bytes", snapshot, s.PendingBytes)
+	}
+	stolen := tb.Steal()
+	if string(stolen) != string(snapshot) {
+		t.Errorf("stolen: %q, expected the snapshot: %q", stolen, snapshot)
+	}
+	if s := tb.Stats(); s.SpillBytes != 0 || s.PendingBytes != 0 {
+		t.Errorf("stats after steal: %v bytes spilled, %v pending", s.SpillBytes, s.PendingBytes)
+	}
+	if n := spillFiles(t, dir); n != 0 {
+		t.Errorf("spill files after steal: %v, expected: 0", n)
+	}
+	close(sink.release)
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s := sink.out.String() + string(stolen); s != want.String() {
+		t.Errorf("written and stolen: %q, expected: %q", s, want.String())
+	}
+}
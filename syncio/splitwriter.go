@@ -0,0 +1,150 @@
+package syncio
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// SplitWriter re-chunks the writes on a delimiter before writing to an underlying writer, so
+// every write of the underlying writer is whole lines, delimiter included, e.g. records of a
+// Buffer with SetRecordMode. The data after the last delimiter is retained until a next write
+// ends it, Close writes it. A write of whole lines is written without copies.
+type SplitWriter struct {
+	mu        sync.Mutex
+	w         io.Writer
+	delim     []byte
+	maxLine   int
+	truncated func(line []byte, size int)
+
+	// pending is the line not ended yet: once it's longer than maxLine its first maxLine
+	// bytes and the last bytes that can start a delimiter, dropped is the size of the rest
+	pending []byte
+	dropped int
+	// out and scratch are reused by the writes
+	out     []byte
+	scratch []byte
+}
+
+var _ io.WriteCloser = &SplitWriter{}
+
+// NewSplitWriter returns a SplitWriter writing the lines ended by delimiter to w, it panics
+// with an empty delimiter
+func NewSplitWriter(w io.Writer, delimiter []byte) *SplitWriter {
+	if len(delimiter) == 0 {
+		panic("syncio: empty SplitWriter delimiter")
+	}
+	return &SplitWriter{w: w, delim: append([]byte(nil), delimiter...)}
+}
+
+// SetMaxLine truncates the lines longer than n bytes, delimiter excluded, to their first n
+// bytes, and calls truncated, if not nil, with them and the size of the line. The line must not
+// be retained. It must be called before the writes, n <= 0 is no limit.
+func (s *SplitWriter) SetMaxLine(n int, truncated func(line []byte, size int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxLine = n
+	s.truncated = truncated
+}
+
+// Write writes the lines ended in p with a single write to the underlying writer, it returns
+// len(p) once they're written
+func (s *SplitWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == 0 {
+		if end := bytes.LastIndex(p, s.delim); end >= 0 && s.fit(p[:end]) {
+			end += len(s.delim)
+			if _, err := s.w.Write(p[:end]); err != nil {
+				return 0, err
+			}
+			s.retain(p[end:])
+			return len(p), nil
+		}
+	}
+
+	data, from := p, 0
+	if len(s.pending) > 0 {
+		s.scratch = append(append(s.scratch[:0], s.pending...), p...)
+		data = s.scratch
+		if s.dropped > 0 {
+			// a delimiter can only start in the bytes kept after the head
+			from = s.maxLine
+		}
+	}
+	out := s.out[:0]
+	start := 0
+	for {
+		i := bytes.Index(data[from:], s.delim)
+		if i < 0 {
+			break
+		}
+		i += from
+		out = s.appendLine(out, data[start:i])
+		out = append(out, s.delim...)
+		start = i + len(s.delim)
+		from = start
+	}
+	s.out = out
+	s.retain(data[start:])
+	if len(out) > 0 {
+		if _, err := s.w.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// fit reports if the lines ended in p are not longer than the maximum
+func (s *SplitWriter) fit(p []byte) bool {
+	if s.maxLine <= 0 {
+		return true
+	}
+	for len(p) > s.maxLine {
+		i := bytes.Index(p, s.delim)
+		if i < 0 || i > s.maxLine {
+			return false
+		}
+		p = p[i+len(s.delim):]
+	}
+	return true
+}
+
+// appendLine appends the ended line to out, truncated to the maximum
+func (s *SplitWriter) appendLine(out, line []byte) []byte {
+	size := len(line) + s.dropped
+	s.dropped = 0
+	if s.maxLine > 0 && size > s.maxLine {
+		line = line[:s.maxLine]
+		if s.truncated != nil {
+			s.truncated(line, size)
+		}
+	}
+	return append(out, line...)
+}
+
+// retain keeps the line not ended yet
+func (s *SplitWriter) retain(rest []byte) {
+	keep := len(s.delim) - 1
+	if s.maxLine > 0 && len(rest) > s.maxLine+keep {
+		s.dropped += len(rest) - s.maxLine - keep
+		s.pending = append(append(s.pending[:0], rest[:s.maxLine]...), rest[len(rest)-keep:]...)
+		return
+	}
+	s.pending = append(s.pending[:0], rest...)
+}
+
+// Close writes the line not ended, if any, without delimiter, it doesn't close the
+// underlying writer
+func (s *SplitWriter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == 0 {
+		return nil
+	}
+	out := s.appendLine(s.out[:0], s.pending)
+	s.out = out
+	s.pending = s.pending[:0]
+	_, err := s.w.Write(out)
+	return err
+}
@@ -0,0 +1,151 @@
+package syncio
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// writesRecorder records every write
+type writesRecorder struct {
+	writes [][]byte
+}
+
+func (w *writesRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+// splitReference is a straightforward truncation of the lines of a complete stream
+func splitReference(p, delim []byte, maxLine int) []byte {
+	lines := bytes.Split(p, delim)
+	for i, l := range lines {
+		if maxLine > 0 && len(l) > maxLine {
+			lines[i] = l[:maxLine]
+		}
+	}
+	return bytes.Join(lines, delim)
+}
+
+func FuzzSplitWriter(f *testing.F) {
+	f.Add([]byte("a\nbb\nccc\ndddd"), int64(1), uint8(0), uint8(0))
+	f.Add([]byte("long line\r\nx\r\n\r\nlonger line\r"), int64(2), uint8(1), uint8(3))
+	f.Add([]byte("no delimiter at all"), int64(3), uint8(2), uint8(4))
+	delims := [][]byte{[]byte("\n"), []byte("\r\n"), []byte("<eol>")}
+
+	f.Fuzz(func(t *testing.T, data []byte, seed int64, delimIdx, maxLine uint8) {
+		delim := delims[int(delimIdx)%len(delims)]
+		limit := int(maxLine % 8)
+		out := &writesRecorder{}
+		sw := NewSplitWriter(out, delim)
+		sw.SetMaxLine(limit, nil)
+
+		// random chunking
+		r := rand.New(rand.NewSource(seed))
+		for rest := data; len(rest) > 0; {
+			n := r.Intn(len(rest)) + 1
+			if _, err := sw.Write(rest[:n]); err != nil {
+				t.Fatal(err)
+			}
+			rest = rest[n:]
+		}
+		// every write before Close is whole lines
+		for _, w := range out.writes {
+			if !bytes.HasSuffix(w, delim) {
+				t.Fatalf("write %q not ended by %q", w, delim)
+			}
+		}
+		sw.Close()
+
+		if expected := splitReference(data, delim, limit); !bytes.Equal(bytes.Join(out.writes, nil), expected) {
+			t.Errorf("split %q to %q, expected: %q", data, bytes.Join(out.writes, nil), expected)
+		}
+	})
+}
+
+func TestSplitWriter(t *testing.T) {
+	out := &writesRecorder{}
+	sw := NewSplitWriter(out, []byte("\n"))
+	sw.Write([]byte("one\ntw"))
+	sw.Write([]byte("o"))
+	sw.Write([]byte("\nthree\nfour\nfi"))
+	sw.Write([]byte("ve"))
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"one\n", "two\nthree\nfour\n", "five"}
+	if len(out.writes) != len(expected) {
+		t.Fatalf("writes: %q, expected: %q", out.writes, expected)
+	}
+	for i, w := range out.writes {
+		if string(w) != expected[i] {
+			t.Errorf("write %v: %q, expected: %q", i, w, expected[i])
+		}
+	}
+}
+
+func TestSplitWriterMaxLine(t *testing.T) {
+	out := &writesRecorder{}
+	sw := NewSplitWriter(out, []byte("\r\n"))
+	var truncated []string
+	var sizes []int
+	sw.SetMaxLine(4, func(line []byte, size int) {
+		truncated = append(truncated, string(line))
+		sizes = append(sizes, size)
+	})
+	sw.Write([]byte("ok\r\nlong"))
+	sw.Write([]byte(" line straddling writes\r"))
+	sw.Write([]byte("\nlast line"))
+	sw.Close()
+
+	if got := string(bytes.Join(out.writes, nil)); got != "ok\r\nlong\r\nlast" {
+		t.Errorf("written: %q, expected: %q", got, "ok\r\nlong\r\nlast")
+	}
+	if len(truncated) != 2 || truncated[0] != "long" || truncated[1] != "last" {
+		t.Errorf("truncated: %q, expected: [long last]", truncated)
+	}
+	if len(sizes) != 2 || sizes[0] != len("long line straddling writes") || sizes[1] != len("last line") {
+		t.Errorf("truncated sizes: %v, expected: [%v %v]", sizes, len("long line straddling writes"), len("last line"))
+	}
+}
+
+func TestSplitWriterRecords(t *testing.T) {
+	out := &writesRecorder{}
+	tb := NewBuffer(out, SetRecordMode(true), SetBufferSize(16))
+	sw := NewSplitWriter(tb, []byte("\n"))
+	for _, p := range []string{"first li", "ne\nsecond line\nth", "ird line\n"} {
+		if _, err := sw.Write([]byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sw.Close()
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range out.writes {
+		if !bytes.HasSuffix(w, []byte("\n")) {
+			t.Errorf("flush %q splits a line", w)
+		}
+	}
+	if got := string(bytes.Join(out.writes, nil)); got != "first line\nsecond line\nthird line\n" {
+		t.Errorf("flushed: %q", got)
+	}
+}
+
+func TestSplitWriterAllocs(t *testing.T) {
+	sw := NewSplitWriter(&testWriter{}, []byte("\n"))
+	sw.SetMaxLine(64, nil)
+	lines := []byte("line one\nline two\n")
+	partial := []byte("line three\nline fo")
+	end := []byte("ur\n")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		sw.Write(lines)
+		sw.Write(partial)
+		sw.Write(end)
+	})
+	if allocs != 0 {
+		t.Errorf("allocations per split write: %v, expected 0", allocs)
+	}
+}
@@ -0,0 +1,48 @@
+package syncio
+
+// Delta returns the change of the Stats since prev, a previous Stats of the same Buffer, e.g.
+// the one of the last scrape to compute rates over the scrape interval. The counters, the
+// histogram buckets and FlushLatencySum are the difference, the gauges, the rates,
+// FlushLatencyMin and FlushLatencyMax are the ones of s.
+func (s Stats) Delta(prev Stats) Stats {
+	d := s
+	d.BufferAllocs -= prev.BufferAllocs
+	d.FlushErrors -= prev.FlushErrors
+	d.Resizes -= prev.Resizes
+	d.Flushes -= prev.Flushes
+	d.Batches -= prev.Batches
+	d.Records -= prev.Records
+	d.ReplayedBytes -= prev.ReplayedBytes
+	d.SinkWait -= prev.SinkWait
+	d.BudgetWait -= prev.BudgetWait
+	d.DirectBytes -= prev.DirectBytes
+	d.ChildBytes -= prev.ChildBytes
+	d.Panics -= prev.Panics
+	d.BacklogDrops -= prev.BacklogDrops
+	for i := range d.Errors {
+		d.Errors[i] -= prev.Errors[i]
+	}
+	for i := range d.WriteSizes {
+		d.WriteSizes[i] -= prev.WriteSizes[i]
+	}
+	d.CallerWrites -= prev.CallerWrites
+	d.SinkWrites -= prev.SinkWrites
+	d.DiscardedPayloads -= prev.DiscardedPayloads
+	d.DiscardedPayloadBytes -= prev.DiscardedPayloadBytes
+	for i := range d.ClassBytes {
+		d.ClassBytes[i] -= prev.ClassBytes[i]
+		d.ClassDrops[i] -= prev.ClassDrops[i]
+	}
+	d.Retries -= prev.Retries
+	d.OverflowDrops -= prev.OverflowDrops
+	d.OverflowDropBytes -= prev.OverflowDropBytes
+	for i := range d.FlushLatencies {
+		d.FlushLatencies[i] -= prev.FlushLatencies[i]
+	}
+	d.FlushLatencySum -= prev.FlushLatencySum
+	d.SpilledBytes -= prev.SpilledBytes
+	d.SpillErrors -= prev.SpillErrors
+	d.DedupDrops -= prev.DedupDrops
+	d.DedupDropBytes -= prev.DedupDropBytes
+	return d
+}
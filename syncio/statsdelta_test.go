@@ -0,0 +1,53 @@
+package syncio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsDelta(t *testing.T) {
+	clock := newFakeClock()
+	latencies := []time.Duration{2 * time.Millisecond, 5 * time.Millisecond, 20 * time.Millisecond}
+	calls := 0
+	tb := NewBuffer(writerFunc(func(p []byte) (int, error) {
+		clock.Advance(latencies[calls])
+		calls++
+		return len(p), nil
+	}), SetClock(clock), SetBufferSize(4))
+	defer tb.Close()
+
+	tb.Write([]byte("abcd"))
+	tb.Flush()
+	prev := tb.Stats()
+	if prev.FlushLatencyMin != 2*time.Millisecond || prev.FlushLatencyMax != 2*time.Millisecond || prev.FlushLatencyAvg() != 2*time.Millisecond {
+		t.Errorf("flush latency min %v max %v avg %v after one call", prev.FlushLatencyMin, prev.FlushLatencyMax, prev.FlushLatencyAvg())
+	}
+
+	tb.Write([]byte("efgh"))
+	tb.Flush()
+	tb.Write([]byte("ijkl"))
+	tb.Flush()
+	tb.Write([]byte("mn"))
+	cur := tb.Stats()
+	if cur.PendingBytes != 2 {
+		t.Errorf("pending %v bytes, want 2", cur.PendingBytes)
+	}
+	if cur.FlushLatencyMin != 2*time.Millisecond || cur.FlushLatencyMax != 20*time.Millisecond || cur.FlushLatencySum != 27*time.Millisecond {
+		t.Errorf("flush latency min %v max %v sum %v", cur.FlushLatencyMin, cur.FlushLatencyMax, cur.FlushLatencySum)
+	}
+
+	d := cur.Delta(prev)
+	if d.Flushes != 2 || d.DirectBytes != 8 || d.CallerWrites != 3 {
+		t.Errorf("delta flushes %v, bytes %v, writes %v", d.Flushes, d.DirectBytes, d.CallerWrites)
+	}
+	if d.FlushLatencies.calls() != 2 || d.FlushLatencyAvg() != 12500*time.Microsecond {
+		t.Errorf("delta flush latencies %v, avg %v", d.FlushLatencies, d.FlushLatencyAvg())
+	}
+	// the gauges are the current ones
+	if d.PendingBytes != 2 || d.BufferSize != cur.BufferSize || d.FlushLatencyMax != 20*time.Millisecond {
+		t.Errorf("delta pending %v, buffer size %v, max %v", d.PendingBytes, d.BufferSize, d.FlushLatencyMax)
+	}
+	if z := cur.Delta(cur); z.Flushes != 0 || z.FlushLatencySum != 0 || z.FlushLatencyAvg() != 0 {
+		t.Errorf("delta with itself: %+v", z)
+	}
+}
@@ -0,0 +1,84 @@
+package syncio
+
+// MergeStats combines the Stats of several Buffers, e.g. the shards of a stream, in a single
+// view: the counters, the histogram buckets and the rates are summed, the rates being the
+// throughput of all the Buffers in the same windows. Of the gauges, BufferSize and BacklogAge
+// are the maximum, RetainedBytes, PendingBytes and SpillBytes the sum and FlushInterval the
+// minimum of the Buffers with ticks. FlushLatencySum is summed, FlushLatencyMin and
+// FlushLatencyMax are the extremes of the Buffers with calls. The merge is associative and the
+// zero Stats is its identity.
+func MergeStats(stats ...Stats) Stats {
+	var m Stats
+	for i := range stats {
+		m.merge(&stats[i])
+	}
+	return m
+}
+
+func (m *Stats) merge(s *Stats) {
+	m.BufferAllocs += s.BufferAllocs
+	m.FlushErrors += s.FlushErrors
+	if s.BufferSize > m.BufferSize {
+		m.BufferSize = s.BufferSize
+	}
+	m.Resizes += s.Resizes
+	m.Flushes += s.Flushes
+	m.Batches += s.Batches
+	m.Records += s.Records
+	m.ReplayedBytes += s.ReplayedBytes
+	m.RetainedBytes += s.RetainedBytes
+	m.SinkWait += s.SinkWait
+	m.BudgetWait += s.BudgetWait
+	m.DirectBytes += s.DirectBytes
+	m.ChildBytes += s.ChildBytes
+	// 0 is the interval of the Buffers without ticks
+	if m.FlushInterval == 0 || s.FlushInterval != 0 && s.FlushInterval < m.FlushInterval {
+		m.FlushInterval = s.FlushInterval
+	}
+	m.Panics += s.Panics
+	if s.BacklogAge > m.BacklogAge {
+		m.BacklogAge = s.BacklogAge
+	}
+	m.BacklogDrops += s.BacklogDrops
+	for i := range m.Errors {
+		m.Errors[i] += s.Errors[i]
+	}
+	// the buckets have the same bounds in every Buffer
+	for i := range m.WriteSizes {
+		m.WriteSizes[i] += s.WriteSizes[i]
+	}
+	m.CallerWrites += s.CallerWrites
+	m.SinkWrites += s.SinkWrites
+	m.BytesPerSec1 += s.BytesPerSec1
+	m.BytesPerSec10 += s.BytesPerSec10
+	m.BytesPerSec60 += s.BytesPerSec60
+	m.WritesPerSec1 += s.WritesPerSec1
+	m.WritesPerSec10 += s.WritesPerSec10
+	m.WritesPerSec60 += s.WritesPerSec60
+	m.DiscardedPayloads += s.DiscardedPayloads
+	m.DiscardedPayloadBytes += s.DiscardedPayloadBytes
+	m.PendingBytes += s.PendingBytes
+	for i := range m.ClassBytes {
+		m.ClassBytes[i] += s.ClassBytes[i]
+		m.ClassDrops[i] += s.ClassDrops[i]
+	}
+	m.Retries += s.Retries
+	m.OverflowDrops += s.OverflowDrops
+	m.OverflowDropBytes += s.OverflowDropBytes
+	// 0 is the minimum of the Buffers without calls
+	if s.FlushLatencies.calls() != 0 && (m.FlushLatencies.calls() == 0 || s.FlushLatencyMin < m.FlushLatencyMin) {
+		m.FlushLatencyMin = s.FlushLatencyMin
+	}
+	for i := range m.FlushLatencies {
+		m.FlushLatencies[i] += s.FlushLatencies[i]
+	}
+	m.FlushLatencySum += s.FlushLatencySum
+	if s.FlushLatencyMax > m.FlushLatencyMax {
+		m.FlushLatencyMax = s.FlushLatencyMax
+	}
+	m.SpilledBytes += s.SpilledBytes
+	m.SpillBytes += s.SpillBytes
+	m.SpillErrors += s.SpillErrors
+	m.DedupDrops += s.DedupDrops
+	m.DedupDropBytes += s.DedupDropBytes
+}
@@ -0,0 +1,69 @@
+package syncio
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// randomStats fills every field of Stats, the rates are multiples of 0.5 so their sums are
+// exact and the merge associative
+func randomStats(r *rand.Rand) Stats {
+	var s Stats
+	v := reflect.ValueOf(&s).Elem()
+	var fill func(f reflect.Value)
+	fill = func(f reflect.Value) {
+		switch f.Kind() {
+		case reflect.Int32, reflect.Int64:
+			f.SetInt(r.Int63n(1000))
+		case reflect.Float64:
+			f.SetFloat(float64(r.Intn(2000)) / 2)
+		case reflect.Array:
+			for i := 0; i < f.Len(); i++ {
+				fill(f.Index(i))
+			}
+		default:
+			panic("unexpected Stats field kind " + f.Kind().String())
+		}
+	}
+	for i := 0; i < v.NumField(); i++ {
+		fill(v.Field(i))
+	}
+	// a Buffer without ticks
+	if r.Intn(3) == 0 {
+		s.FlushInterval = 0
+	}
+	return s
+}
+
+func TestMergeStatsProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		a, b, c := randomStats(r), randomStats(r), randomStats(r)
+		if m := MergeStats(a, Stats{}); m != a {
+			t.Fatalf("merge with the zero Stats isn't an identity:\n%+v\n%+v", a, m)
+		}
+		if m := MergeStats(Stats{}, a); m != a {
+			t.Fatalf("merge of the zero Stats isn't an identity:\n%+v\n%+v", a, m)
+		}
+		left := MergeStats(MergeStats(a, b), c)
+		right := MergeStats(a, MergeStats(b, c))
+		if left != right || MergeStats(a, b, c) != left {
+			t.Fatalf("merge isn't associative:\n%+v\n%+v", left, right)
+		}
+	}
+}
+
+func TestMergeStats(t *testing.T) {
+	a := Stats{BufferSize: 4096, FlushInterval: time.Second, BacklogAge: time.Second, DirectBytes: 10, BytesPerSec1: 1.5}
+	b := Stats{BufferSize: 1024, BacklogAge: 3 * time.Second, DirectBytes: 5, BytesPerSec1: 2}
+	c := Stats{FlushInterval: 500 * time.Millisecond}
+	a.WriteSizes[1], b.WriteSizes[1] = 2, 3
+	m := MergeStats(a, b, c)
+	expected := Stats{BufferSize: 4096, FlushInterval: 500 * time.Millisecond, BacklogAge: 3 * time.Second, DirectBytes: 15, BytesPerSec1: 3.5}
+	expected.WriteSizes[1] = 5
+	if m != expected {
+		t.Errorf("merged: %+v, expected: %+v", m, expected)
+	}
+}
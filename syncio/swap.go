@@ -0,0 +1,55 @@
+package syncio
+
+import "io"
+
+// writerSwap is the replacement of the underlying writer carried by a barrier
+type writerSwap struct {
+	w   io.Writer
+	old io.Writer
+}
+
+// SwapWriter replaces the underlying writer, the data written before the call is sent to the
+// previous writer, which is returned once that data is written, e.g. to close a rotated file.
+// The returned error is the first flush error since the last call to Flush, as with Flush.
+// When the sink is pending, see NewBuffer, w receives the data buffered and nil is returned.
+func (tb *Buffer) SwapWriter(w io.Writer) (io.Writer, error) {
+	if !tb.initialized() {
+		return nil, ErrNotInitialized
+	}
+	tb.lockBuf()
+	if tb.closed {
+		tb.unlockBuf()
+		tb.countError(ErrorClosed)
+		return nil, ErrWriteOnClosed
+	}
+	if tb.sinkPending {
+		// the queued batches are the data of w
+		tb.provideSink(w)
+		tb.unlockBuf()
+		tb.flushInline()
+		return nil, nil
+	}
+	done := make(chan error, 1)
+	s := &writerSwap{w: w}
+	sw := tb.flushBatch(&batch{trigger: TriggerManual, done: done, swap: s})
+	tb.unlockBuf()
+
+	if tb.logger != nil {
+		tb.logSwap(sw)
+	}
+	tb.flushInline()
+	err := <-done
+	return s.old, err
+}
+
+// swapWriter replaces the underlying writer from the flush goroutine, the writer is guarded
+// by bufmu when it changes the parent
+func (tb *Buffer) swapWriter(s *writerSwap) {
+	tb.bufmu.Lock()
+	s.old = tb.writer
+	tb.writer = s.w
+	tb.parent, _ = s.w.(*Buffer)
+	tb.headerPending = tb.header != nil
+	tb.detectSink()
+	tb.bufmu.Unlock()
+}
@@ -0,0 +1,53 @@
+package syncio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSwapWriter(t *testing.T) {
+	inModes(t, testSwapWriter)
+}
+
+func testSwapWriter(t *testing.T, mode []BufferOption) {
+	first, second := &bytes.Buffer{}, &bytes.Buffer{}
+	tb := NewBuffer(first, append([]BufferOption{SetBufferSize(4)}, mode...)...)
+	tb.Write([]byte("abcdef"))
+	tb.Write([]byte("gh"))
+
+	old, err := tb.SwapWriter(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old != first {
+		t.Errorf("previous writer: %v, expected: %v", old, first)
+	}
+	if s := first.String(); s != "abcdefgh" {
+		t.Errorf("previous writer data: %q, expected: %q", s, "abcdefgh")
+	}
+	tb.Write([]byte("ij"))
+	tb.Close()
+	if s := second.String(); s != "ij" {
+		t.Errorf("new writer data: %q, expected: %q", s, "ij")
+	}
+	if _, err := tb.SwapWriter(first); err != ErrWriteOnClosed {
+		t.Errorf("swap on closed: %v, expected: %v", err, ErrWriteOnClosed)
+	}
+}
+
+func TestSwapWriterParent(t *testing.T) {
+	sink := &bytes.Buffer{}
+	parent := NewBuffer(sink)
+	child := NewBuffer(&bytes.Buffer{})
+	child.Write([]byte("first"))
+	child.SwapWriter(parent)
+	child.Write([]byte("handed"))
+	child.Close()
+	parent.Close()
+	if s := sink.String(); s != "handed" {
+		t.Errorf("parent data: %q, expected: %q", s, "handed")
+	}
+	if s := parent.Stats(); s.ChildBytes != 6 {
+		t.Errorf("parent child bytes: %v, expected: 6", s.ChildBytes)
+	}
+}
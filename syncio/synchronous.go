@@ -0,0 +1,63 @@
+package syncio
+
+// SetSynchronousMode creates the Buffer without goroutines: the batches are written inline by
+// the Write that fills the buffer, and by Flush and Close, the flush interval is ignored.
+// The writes to the underlying writer are still serialized, a Write waits while another
+// goroutine is flushing, and the callbacks like SetOnFlushError are called from the goroutine
+// flushing. The underlying writer must not write to the Buffer. CloseTimeout and
+// CloseOnContext can't interrupt a flush in this mode.
+func SetSynchronousMode(enabled bool) BufferOption {
+	return func(b *Buffer) {
+		b.synchronous = enabled
+	}
+}
+
+// SetManualTick replaces the ticker goroutine by calls to Tick from the application scheduler,
+// it enables SetSynchronousMode so the Buffer has no goroutines and the size flushes are
+// written inline by Write too
+func SetManualTick(enabled bool) BufferOption {
+	return func(b *Buffer) {
+		b.manualTick = enabled
+		if enabled {
+			b.synchronous = true
+		}
+	}
+}
+
+// Tick flushes the active buffer as a tick of the flush interval, unless a full buffer was
+// flushed since the previous tick, it's meant to be called with SetManualTick. The flush
+// errors are reported as the tick ones, to SetOnFlushError and by the next Flush or Close.
+// It fails if the Buffer is closed.
+func (tb *Buffer) Tick() error {
+	if !tb.initialized() {
+		return ErrNotInitialized
+	}
+	if !tb.tick() {
+		tb.countError(ErrorClosed)
+		return ErrWriteOnClosed
+	}
+	tb.flushInline()
+	return nil
+}
+
+// flushInline writes the queued batches from the calling goroutine in synchronous mode, the
+// caller must not hold bufmu. done is closed once the Buffer is closed and the queue empty.
+func (tb *Buffer) flushInline() {
+	if !tb.synchronous {
+		return
+	}
+	tb.inlinemu.Lock()
+	defer tb.inlinemu.Unlock()
+	for group := tb.dequeue(false); group != nil; group = tb.dequeue(false) {
+		tb.writeGroup(group)
+	}
+
+	tb.bufmu.Lock()
+	closed := tb.closed && len(tb.queue) == 0
+	tb.bufmu.Unlock()
+	if closed && !tb.inlineDone {
+		tb.inlineDone = true
+		tb.errs.close()
+		close(tb.done)
+	}
+}
@@ -0,0 +1,41 @@
+package syncio
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSynchronousMode(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	tw := &testWriter{}
+	tb := NewBuffer(tw, SetSynchronousMode(true), SetBufferSize(8), SetFlushInterval(time.Millisecond))
+	if n := runtime.NumGoroutine(); n != goroutines {
+		t.Errorf("goroutines: %v, expected: %v", n, goroutines)
+	}
+
+	tb.Write([]byte("abcde"))
+	time.Sleep(10 * time.Millisecond)
+	if tw.writes != 0 {
+		t.Errorf("writes before the buffer is full: %v, the ticks must be disabled", tw.writes)
+	}
+	// the full buffer is written before Write returns
+	tb.Write([]byte("fghij"))
+	if tw.writes != 1 || tw.bytes != 5 {
+		t.Errorf("inline flush: %+v, expected 1 write of 5 bytes", *tw)
+	}
+	tb.Write(make([]byte, 20))
+	if tw.writes != 3 || tw.bytes != 30 {
+		t.Errorf("oversized write: %+v, expected 3 writes of 30 bytes", *tw)
+	}
+	if err := tb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s := tb.Stats()
+	if s.Flushes != 3 || s.Batches != 3 || s.DirectBytes != 30 {
+		t.Errorf("stats: %+v", s)
+	}
+}
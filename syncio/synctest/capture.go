@@ -0,0 +1,61 @@
+package synctest
+
+import "sync"
+
+// CaptureWriter is an io.Writer keeping a copy of every write for the assertions, it's safe
+// for concurrent use. The zero value is ready to use.
+type CaptureWriter struct {
+	mu     sync.Mutex
+	data   []byte
+	writes [][]byte
+}
+
+func (c *CaptureWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = append(c.data, p...)
+	c.writes = append(c.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+// Bytes returns a copy of the bytes written
+func (c *CaptureWriter) Bytes() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.data...)
+}
+
+// String returns the bytes written as a string
+func (c *CaptureWriter) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return string(c.data)
+}
+
+// Len returns the number of bytes written
+func (c *CaptureWriter) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.data)
+}
+
+// Writes returns the data of every write in order, the slices must not be modified
+func (c *CaptureWriter) Writes() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]byte(nil), c.writes...)
+}
+
+// Calls returns the number of writes received
+func (c *CaptureWriter) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.writes)
+}
+
+// Reset discards the bytes written
+func (c *CaptureWriter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data, c.writes = nil, nil
+}
@@ -0,0 +1,98 @@
+package synctest
+
+import (
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ChaosWriter is an io.Writer that misbehaves randomly, it's meant to soak test code writing
+// through a syncio.Buffer. Each write picks at most one behavior with the configured
+// probabilities, in [0, 1], and the rest of the writes succeed.
+// The same seed produces the same sequence of behaviors.
+type ChaosWriter struct {
+	// W receives the written bytes, it can be nil
+	W io.Writer
+	// Err is the error returned by the failing writes, ErrInjected if nil
+	Err error
+
+	// ErrorRate is the probability of failing a write without writing anything
+	ErrorRate float64
+	// ShortRate is the probability of writing a random part of p without error
+	ShortRate float64
+	// HangRate is the probability of hanging a random duration up to MaxHang before writing
+	HangRate float64
+	MaxHang  time.Duration
+	// SlowRate is the probability of waiting Delay before writing
+	SlowRate float64
+	Delay    time.Duration
+
+	mu     sync.Mutex
+	rnd    *rand.Rand
+	calls  int
+	errors int
+	shorts int
+}
+
+// NewChaosWriter returns a ChaosWriter with the random behaviors seeded by seed, it behaves
+// as W until the probabilities are set
+func NewChaosWriter(w io.Writer, seed int64) *ChaosWriter {
+	return &ChaosWriter{W: w, rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (c *ChaosWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+
+	// the random values are always drawn to keep the sequence independent of the rates
+	r := c.rnd.Float64()
+	part := c.rnd.Intn(len(p) + 1)
+	hang := time.Duration(c.rnd.Int63n(int64(c.MaxHang) + 1))
+
+	switch {
+	case r < c.ErrorRate:
+		c.errors++
+		if c.Err != nil {
+			return 0, c.Err
+		}
+		return 0, ErrInjected
+	case r < c.ErrorRate+c.ShortRate:
+		c.shorts++
+		return c.write(p[:part])
+	case r < c.ErrorRate+c.ShortRate+c.HangRate:
+		time.Sleep(hang)
+	case r < c.ErrorRate+c.ShortRate+c.HangRate+c.SlowRate:
+		time.Sleep(c.Delay)
+	}
+	return c.write(p)
+}
+
+func (c *ChaosWriter) write(p []byte) (int, error) {
+	if c.W == nil {
+		return len(p), nil
+	}
+	return c.W.Write(p)
+}
+
+// Calls returns the number of writes received
+func (c *ChaosWriter) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+// Errors returns the number of writes failed on purpose
+func (c *ChaosWriter) Errors() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.errors
+}
+
+// ShortWrites returns the number of short writes done on purpose
+func (c *ChaosWriter) ShortWrites() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.shorts
+}
@@ -0,0 +1,124 @@
+package synctest
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a manual syncio.Clock to test the tick based behaviors without sleeping: the
+// time only moves with Advance and the tickers tick when it reaches their period. It's safe for
+// concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// fakeTicker is a ticker of FakeClock, next is the time of its next tick
+type fakeTicker struct {
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped chan struct{}
+}
+
+// NewFakeClock returns a FakeClock at start
+func NewFakeClock(start time.Time) *FakeClock {
+	c := &FakeClock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the time of the clock
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker returns a ticker ticking every d of the clock time, stop removes it
+func (c *FakeClock) NewTicker(d time.Duration) (<-chan time.Time, func()) {
+	if d <= 0 {
+		panic("synctest: non-positive interval for NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{c: make(chan time.Time), period: d, next: c.now.Add(d), stopped: make(chan struct{})}
+	c.tickers = append(c.tickers, t)
+	c.cond.Broadcast()
+	var once sync.Once
+	return t.c, func() { once.Do(func() { c.stop(t) }) }
+}
+
+func (c *FakeClock) stop(t *fakeTicker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, ticker := range c.tickers {
+		if ticker == t {
+			c.tickers = append(c.tickers[:i], c.tickers[i+1:]...)
+			break
+		}
+	}
+	close(t.stopped)
+	c.cond.Broadcast()
+}
+
+// Advance moves the clock d forward and delivers the ticks due, in time order. It returns once
+// every tick has been received or its ticker stopped, so the receiver is handling the last
+// tick. As with time.Ticker, a ticker ticks once for several periods elapsed while its
+// receiver was busy.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		t := c.due(end)
+		if t == nil {
+			break
+		}
+		tick := t.next
+		c.now = tick
+		for !t.next.After(c.now) {
+			t.next = t.next.Add(t.period)
+		}
+		// the ticker can be stopped while the receiver is busy
+		c.mu.Unlock()
+		select {
+		case t.c <- tick:
+		case <-t.stopped:
+		}
+		c.mu.Lock()
+	}
+	if end.After(c.now) {
+		c.now = end
+	}
+	c.mu.Unlock()
+}
+
+// due returns the ticker with the earliest tick until end, the caller must hold mu
+func (c *FakeClock) due(end time.Time) *fakeTicker {
+	var first *fakeTicker
+	for _, t := range c.tickers {
+		if !t.next.After(end) && (first == nil || t.next.Before(first.next)) {
+			first = t
+		}
+	}
+	return first
+}
+
+// BlockUntil waits until n tickers are running, e.g. the ticker started by the goroutine of a
+// syncio.Buffer with a flush interval
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.tickers) < n {
+		c.cond.Wait()
+	}
+}
+
+// Tickers returns the number of tickers running
+func (c *FakeClock) Tickers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.tickers)
+}
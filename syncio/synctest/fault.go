@@ -0,0 +1,157 @@
+package synctest
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// FaultWriter is an io.Writer injecting faults by call number, the deterministic counterpart of
+// ChaosWriter for the tests asserting exact outcomes. The calls are counted from 1 and the
+// faults of a call combine: the delay is waited first, then the write fails or is cut short.
+type FaultWriter struct {
+	// W receives the written bytes, it can be nil
+	W io.Writer
+	// Err is the error returned by the failing writes, ErrInjected if nil
+	Err error
+
+	// FailEvery fails every FailEvery-th write after writing Partial bytes of it
+	FailEvery int
+	// ShortEvery writes only Partial bytes of every ShortEvery-th write without error
+	ShortEvery int
+	Partial    int
+	// Delay is waited before every DelayEvery-th write, every write if DelayEvery is 0
+	Delay      time.Duration
+	DelayEvery int
+
+	mu     sync.Mutex
+	calls  int
+	fails  int
+	shorts int
+}
+
+func (f *FaultWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.Delay > 0 && (f.DelayEvery <= 0 || every(f.DelayEvery, f.calls)) {
+		time.Sleep(f.Delay)
+	}
+
+	switch {
+	case every(f.FailEvery, f.calls):
+		f.fails++
+		n, _ := f.write(p[:partial(f.Partial, len(p))])
+		return n, injected(f.Err)
+	case every(f.ShortEvery, f.calls):
+		f.shorts++
+		return f.write(p[:partial(f.Partial, len(p))])
+	}
+	return f.write(p)
+}
+
+func (f *FaultWriter) write(p []byte) (int, error) {
+	if f.W == nil {
+		return len(p), nil
+	}
+	return f.W.Write(p)
+}
+
+// Calls returns the number of writes received
+func (f *FaultWriter) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// Fails returns the number of writes failed on purpose
+func (f *FaultWriter) Fails() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fails
+}
+
+// ShortWrites returns the number of short writes done on purpose
+func (f *FaultWriter) ShortWrites() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.shorts
+}
+
+// FaultReader is an io.Reader injecting faults by call number as FaultWriter
+type FaultReader struct {
+	// R is the reader of the data, nil is an empty reader
+	R io.Reader
+	// Err is the error returned by the failing reads, ErrInjected if nil
+	Err error
+
+	// FailEvery fails every FailEvery-th read without reading
+	FailEvery int
+	// MaxRead is the maximum number of bytes returned by a read, no limit if 0
+	MaxRead int
+	// Delay is waited before every DelayEvery-th read, every read if DelayEvery is 0
+	Delay      time.Duration
+	DelayEvery int
+
+	mu    sync.Mutex
+	calls int
+	fails int
+}
+
+func (f *FaultReader) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.Delay > 0 && (f.DelayEvery <= 0 || every(f.DelayEvery, f.calls)) {
+		time.Sleep(f.Delay)
+	}
+	if every(f.FailEvery, f.calls) {
+		f.fails++
+		return 0, injected(f.Err)
+	}
+	if f.R == nil {
+		return 0, io.EOF
+	}
+	if f.MaxRead > 0 && len(p) > f.MaxRead {
+		p = p[:f.MaxRead]
+	}
+	return f.R.Read(p)
+}
+
+// Calls returns the number of reads received
+func (f *FaultReader) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// Fails returns the number of reads failed on purpose
+func (f *FaultReader) Fails() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fails
+}
+
+// every reports if call is a multiple of n, never if n isn't positive
+func every(n, call int) bool {
+	return n > 0 && call%n == 0
+}
+
+// partial returns the size of a write of n bytes cut to limit
+func partial(limit, n int) int {
+	if limit < 0 {
+		return 0
+	}
+	if limit > n {
+		return n
+	}
+	return limit
+}
+
+// injected returns err, ErrInjected if nil
+func injected(err error) error {
+	if err == nil {
+		return ErrInjected
+	}
+	return err
+}
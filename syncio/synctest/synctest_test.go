@@ -0,0 +1,166 @@
+package synctest
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+func TestFaultWriter(t *testing.T) {
+	var c CaptureWriter
+	f := &FaultWriter{W: &c, FailEvery: 3, ShortEvery: 2, Partial: 1}
+	results := []struct {
+		n   int
+		err error
+	}{{3, nil}, {1, nil}, {1, ErrInjected}, {1, nil}, {3, nil}, {1, ErrInjected}}
+	for i, r := range results {
+		n, err := f.Write([]byte("abc"))
+		if n != r.n || err != r.err {
+			t.Errorf("write %v: %v, %v, expected: %v, %v", i+1, n, err, r.n, r.err)
+		}
+	}
+	if got := c.String(); got != "abcaaaabca" {
+		t.Errorf("captured %q", got)
+	}
+	if f.Calls() != 6 || f.Fails() != 2 || f.ShortWrites() != 2 || c.Calls() != 6 {
+		t.Errorf("calls %v, fails %v, short writes %v, captured writes %v", f.Calls(), f.Fails(), f.ShortWrites(), c.Calls())
+	}
+	c.Reset()
+	if c.Len() != 0 || len(c.Writes()) != 0 {
+		t.Errorf("captured %q after reset", c.String())
+	}
+}
+
+func TestFaultWriterBuffer(t *testing.T) {
+	// the short writes are retried by the Buffer, the failures are flush errors
+	var c CaptureWriter
+	f := &FaultWriter{W: &c, ShortEvery: 2, Partial: 2}
+	tb := syncio.NewBuffer(f, syncio.SetBufferSize(8), syncio.SetSynchronousMode(true))
+	for i := 0; i < 4; i++ {
+		tb.Write([]byte("01234567"))
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.String(); got != strings.Repeat("01234567", 4) {
+		t.Errorf("captured %q", got)
+	}
+	if f.ShortWrites() == 0 {
+		t.Error("no short writes")
+	}
+
+	f = &FaultWriter{FailEvery: 1, Err: io.ErrClosedPipe}
+	tb = syncio.NewBuffer(f, syncio.SetBufferSize(8), syncio.SetSynchronousMode(true))
+	tb.Write([]byte("01234567"))
+	if err := tb.Close(); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("close: %v", err)
+	}
+}
+
+func TestFaultReader(t *testing.T) {
+	f := &FaultReader{R: strings.NewReader("abcdefg"), MaxRead: 3, FailEvery: 2}
+	var got []byte
+	var fails int
+	p := make([]byte, 16)
+	for {
+		n, err := f.Read(p)
+		got = append(got, p[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err != ErrInjected {
+				t.Fatal(err)
+			}
+			fails++
+			continue
+		}
+		if n > 3 {
+			t.Errorf("read %v bytes", n)
+		}
+	}
+	if string(got) != "abcdefg" || fails != f.Fails() || fails < 2 {
+		t.Errorf("read %q, %v fails, counted %v", got, fails, f.Fails())
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	fast, stopFast := c.NewTicker(time.Second)
+	slow, stopSlow := c.NewTicker(3 * time.Second)
+	ticks := make(chan string, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 4; i++ {
+			select {
+			case tick := <-fast:
+				ticks <- "fast " + tick.Sub(start).String()
+			case tick := <-slow:
+				ticks <- "slow " + tick.Sub(start).String()
+			}
+		}
+	}()
+	c.Advance(3500 * time.Millisecond)
+	<-done
+	close(ticks)
+	var got []string
+	for tick := range ticks {
+		got = append(got, tick)
+	}
+	// the tickers due at the same time tick in creation order
+	if strings.Join(got, ", ") != "fast 1s, fast 2s, fast 3s, slow 3s" {
+		t.Errorf("ticks: %v", got)
+	}
+	if now := c.Now().Sub(start); now != 3500*time.Millisecond {
+		t.Errorf("now: %v", now)
+	}
+
+	// nobody receives from the stopped tickers
+	stopFast()
+	stopSlow()
+	c.Advance(10 * time.Second)
+	if c.Tickers() != 0 {
+		t.Errorf("%v tickers after stop", c.Tickers())
+	}
+}
+
+func TestFakeClockBuffer(t *testing.T) {
+	c := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	flushed := make(chan string, 1)
+	sink := writerFunc(func(p []byte) (int, error) {
+		flushed <- string(p)
+		return len(p), nil
+	})
+	tb := syncio.NewBuffer(sink, syncio.SetClock(c), syncio.SetFlushInterval(time.Minute))
+	defer tb.Close()
+	tb.Write([]byte("abc"))
+
+	c.BlockUntil(1)
+	c.Advance(59 * time.Second)
+	select {
+	case p := <-flushed:
+		t.Fatalf("flushed %q before the tick", p)
+	default:
+	}
+	c.Advance(time.Second)
+	select {
+	case p := <-flushed:
+		if p != "abc" {
+			t.Errorf("flushed %q", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("not flushed by the tick")
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,97 @@
+package synctest
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrInjected is the error returned by the test writers when no other error is configured
+var ErrInjected = errors.New("synctest: injected error")
+
+// FailingWriter is an io.Writer that fails while failing is enabled, it's meant to test
+// the error paths of code writing through a syncio.Buffer.
+// When failing, Partial bytes of each write are written to W before returning Err.
+type FailingWriter struct {
+	// W receives the written bytes, it can be nil
+	W io.Writer
+	// Err is the error returned when failing, ErrInjected if nil
+	Err error
+	// Partial is the number of bytes accepted by a failing write
+	Partial int
+
+	mu      sync.Mutex
+	failing bool
+	calls   int
+	fails   int
+}
+
+// NewFailingWriter returns a FailingWriter that fails every write with err
+func NewFailingWriter(w io.Writer, err error) *FailingWriter {
+	return &FailingWriter{W: w, Err: err, failing: true}
+}
+
+// SetFailing enables or disables the failures
+func (f *FailingWriter) SetFailing(failing bool) {
+	f.mu.Lock()
+	f.failing = failing
+	f.mu.Unlock()
+}
+
+func (f *FailingWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if !f.failing {
+		return f.write(p)
+	}
+
+	f.fails++
+	n := f.Partial
+	if n > len(p) {
+		n = len(p)
+	}
+	n, _ = f.write(p[:n])
+	err := f.Err
+	if err == nil {
+		err = ErrInjected
+	}
+	return n, err
+}
+
+func (f *FailingWriter) write(p []byte) (int, error) {
+	if f.W == nil {
+		return len(p), nil
+	}
+	return f.W.Write(p)
+}
+
+// Calls returns the number of writes received
+func (f *FailingWriter) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// Fails returns the number of writes that failed
+func (f *FailingWriter) Fails() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fails
+}
+
+// SlowWriter is an io.Writer that waits Delay before each write
+type SlowWriter struct {
+	// W receives the written bytes, it can be nil
+	W     io.Writer
+	Delay time.Duration
+}
+
+func (s *SlowWriter) Write(p []byte) (int, error) {
+	time.Sleep(s.Delay)
+	if s.W == nil {
+		return len(p), nil
+	}
+	return s.W.Write(p)
+}
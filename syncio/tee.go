@@ -0,0 +1,381 @@
+package syncio
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/travelgateX/go-io/syncio/bufpool"
+)
+
+// ErrDetached is returned by the reads of a TeeReader detached by LagDetach
+var ErrDetached = errors.New("tee consumer detached: lagging behind")
+
+// LagPolicy is the behavior of a Tee for a consumer lagging MaxLag blocks behind the source
+type LagPolicy int
+
+const (
+	LagBlock  LagPolicy = iota // the source waits for the consumer, pacing the others
+	LagDetach                  // the consumer is detached and its reads fail with ErrDetached
+)
+
+var lagPolicyNames = []string{"block", "detach"}
+
+func (p LagPolicy) String() string {
+	return enumString(lagPolicyNames, int(p))
+}
+
+func (p LagPolicy) MarshalText() ([]byte, error) {
+	return enumMarshal(lagPolicyNames, int(p), "LagPolicy")
+}
+
+func (p *LagPolicy) UnmarshalText(text []byte) error {
+	return enumUnmarshal(lagPolicyNames, text, "LagPolicy", (*int)(p))
+}
+
+// TeeConsumer is a consumer of a Tee, MaxLag is the number of blocks read from the source and
+// not consumed yet it can lag behind, 4 by default
+type TeeConsumer struct {
+	Name   string
+	MaxLag int
+	Policy LagPolicy
+}
+
+// TeeStats are the counters of the source of a Tee, as the Fills of PrefetchStats
+type TeeStats struct {
+	Fills       int64
+	FillBytes   int64
+	BlockAllocs int64
+}
+
+// TeeReaderStats are the counters of a consumer of a Tee: Bytes is the data read, Lag the
+// blocks waiting to be consumed, Waits the number of times the source waited for it and
+// Detached reports if it was detached by LagDetach
+type TeeReaderStats struct {
+	Bytes    int64
+	Lag      int64
+	Waits    int64
+	Detached bool
+}
+
+// Tee reads a source once for several consumers, each reading at its own pace: a goroutine
+// reads the source into pooled blocks shared by the consumers, a block is returned to the pool
+// once every consumer read it. The source stops being read when every consumer is closed or
+// detached.
+type Tee struct {
+	r         io.Reader
+	blockSize int
+	pool      *bufpool.Pool
+	readers   []*TeeReader
+	stop      chan struct{}
+	once      sync.Once
+	stats     TeeStats
+}
+
+// teeBlock is a block shared by the consumers, refs is the number of them not done with it
+type teeBlock struct {
+	buf  []byte
+	n    int
+	err  error
+	refs int32
+}
+
+// TeeReader is a consumer of a Tee, its reads are not safe for concurrent use, Stats and Close
+// are
+type TeeReader struct {
+	tee    *Tee
+	name   string
+	policy LagPolicy
+	blocks chan *teeBlock
+	// closed is closed by Close and by LagDetach, detached tells them apart
+	closed   chan struct{}
+	once     sync.Once
+	detached atomic.Bool
+
+	cur  *teeBlock
+	rest []byte
+	err  error
+
+	bytes int64
+	waits int64
+}
+
+// NewTee returns a Tee reading r in blocks of blockSize bytes, 4096 if it's not positive, for
+// the consumers. The readers of the consumers are returned by Reader in the same order.
+func NewTee(r io.Reader, blockSize int, consumers ...TeeConsumer) *Tee {
+	const defaultMaxLag = 4
+	if blockSize <= 0 {
+		blockSize = 4096
+	}
+	t := &Tee{r: r, blockSize: blockSize, stop: make(chan struct{})}
+	blocks := 1
+	for _, c := range consumers {
+		lag := c.MaxLag
+		if lag <= 0 {
+			lag = defaultMaxLag
+		}
+		mustValid(lagPolicyNames, int(c.Policy), "LagPolicy")
+		t.readers = append(t.readers, &TeeReader{
+			tee:    t,
+			name:   c.Name,
+			policy: c.Policy,
+			blocks: make(chan *teeBlock, lag),
+			closed: make(chan struct{}),
+		})
+		// the blocks lagging and the one being consumed
+		blocks += lag + 1
+	}
+	t.pool = bufpool.New(blockSize, blocks)
+	go t.fill()
+	return t
+}
+
+// Reader returns the reader of the consumer i, in the order of NewTee
+func (t *Tee) Reader(i int) *TeeReader {
+	return t.readers[i]
+}
+
+// fill reads the source until it fails, the Tee is closed or no consumer is left
+func (t *Tee) fill() {
+	defer func() {
+		for _, r := range t.readers {
+			close(r.blocks)
+		}
+	}()
+	empty := 0
+	for {
+		live := 0
+		for _, r := range t.readers {
+			if !r.gone() {
+				live++
+			}
+		}
+		if live == 0 {
+			return
+		}
+		buf, ok := t.pool.TryGet()
+		if !ok {
+			buf = t.pool.Alloc()
+			atomic.AddInt64(&t.stats.BlockAllocs, 1)
+		}
+		n, err := t.r.Read(buf)
+		atomic.AddInt64(&t.stats.Fills, 1)
+		atomic.AddInt64(&t.stats.FillBytes, int64(n))
+		if n == 0 && err == nil {
+			if empty++; empty < maxEmptyReads {
+				t.pool.Put(buf)
+				continue
+			}
+			err = io.ErrNoProgress
+		}
+		empty = 0
+
+		b := &teeBlock{buf: buf, n: n, err: err, refs: int32(len(t.readers))}
+		for _, r := range t.readers {
+			if !r.send(b) {
+				t.release(b)
+			}
+		}
+		if err != nil {
+			return
+		}
+		select {
+		case <-t.stop:
+			return
+		default:
+		}
+	}
+}
+
+// release drops a reference of b, the last one returns it to the pool
+func (t *Tee) release(b *teeBlock) {
+	if atomic.AddInt32(&b.refs, -1) == 0 {
+		t.pool.Put(b.buf)
+	}
+}
+
+// send hands b to the consumer following its policy, it returns false if the consumer
+// didn't take it
+func (r *TeeReader) send(b *teeBlock) bool {
+	if r.gone() {
+		return false
+	}
+	select {
+	case r.blocks <- b:
+		return true
+	default:
+	}
+	if r.policy == LagDetach {
+		r.detached.Store(true)
+		r.close()
+		return false
+	}
+	atomic.AddInt64(&r.waits, 1)
+	select {
+	case r.blocks <- b:
+		return true
+	case <-r.closed:
+	case <-r.tee.stop:
+	}
+	r.drain()
+	return false
+}
+
+// gone reports if the consumer is closed or detached
+func (r *TeeReader) gone() bool {
+	select {
+	case <-r.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// close marks the consumer gone and releases its blocks
+func (r *TeeReader) close() {
+	r.once.Do(func() { close(r.closed) })
+	r.drain()
+}
+
+// drain releases the blocks waiting for a consumer gone
+func (r *TeeReader) drain() {
+	for {
+		select {
+		case b, ok := <-r.blocks:
+			if !ok {
+				return
+			}
+			r.tee.release(b)
+		default:
+			return
+		}
+	}
+}
+
+// next makes the next block the current one, it returns false at the end of the stream
+func (r *TeeReader) next() bool {
+	if r.cur != nil {
+		r.tee.release(r.cur)
+		r.cur, r.rest = nil, nil
+	}
+	if r.err != nil {
+		return false
+	}
+	var b *teeBlock
+	var ok bool
+	select {
+	case b, ok = <-r.blocks:
+	case <-r.closed:
+	}
+	if !ok || r.gone() {
+		if ok {
+			r.tee.release(b)
+		}
+		r.err = ErrReaderClosed
+		if r.detached.Load() {
+			r.err = ErrDetached
+		}
+		r.drain()
+		return false
+	}
+	r.cur, r.rest, r.err = b, b.buf[:b.n], b.err
+	return true
+}
+
+// Read reads the data of the source, it returns the error of the source once the data before
+// it is consumed
+func (r *TeeReader) Read(p []byte) (int, error) {
+	if r.tee == nil {
+		return 0, ErrNotInitialized
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(r.rest) == 0 {
+		if !r.next() {
+			return 0, r.err
+		}
+	}
+	n := copy(p, r.rest)
+	r.rest = r.rest[n:]
+	atomic.AddInt64(&r.bytes, int64(n))
+	return n, nil
+}
+
+// WriteTo writes the blocks to w without copying them until the end of the stream, io.EOF
+// isn't returned
+func (r *TeeReader) WriteTo(w io.Writer) (int64, error) {
+	if r.tee == nil {
+		return 0, ErrNotInitialized
+	}
+	var written int64
+	for {
+		if len(r.rest) > 0 {
+			n, err := w.Write(r.rest)
+			r.rest = r.rest[n:]
+			written += int64(n)
+			atomic.AddInt64(&r.bytes, int64(n))
+			if err == nil && len(r.rest) > 0 {
+				err = io.ErrShortWrite
+			}
+			if err != nil {
+				return written, err
+			}
+		}
+		if !r.next() {
+			if r.err == io.EOF {
+				return written, nil
+			}
+			return written, r.err
+		}
+	}
+}
+
+// Stats returns the counters of the consumer
+func (r *TeeReader) Stats() TeeReaderStats {
+	return TeeReaderStats{
+		Bytes:    atomic.LoadInt64(&r.bytes),
+		Lag:      int64(len(r.blocks)),
+		Waits:    atomic.LoadInt64(&r.waits),
+		Detached: r.detached.Load(),
+	}
+}
+
+// Close detaches the consumer from the Tee, its reads fail with ErrReaderClosed from now on
+// and the source doesn't wait for it
+func (r *TeeReader) Close() error {
+	if r.tee == nil {
+		return ErrNotInitialized
+	}
+	r.close()
+	return nil
+}
+
+// Stats returns the counters of the source
+func (t *Tee) Stats() TeeStats {
+	return TeeStats{
+		Fills:       atomic.LoadInt64(&t.stats.Fills),
+		FillBytes:   atomic.LoadInt64(&t.stats.FillBytes),
+		BlockAllocs: atomic.LoadInt64(&t.stats.BlockAllocs),
+	}
+}
+
+// Close stops reading the source and closes it if it's an io.Closer, the consumers are
+// closed
+func (t *Tee) Close() error {
+	if t.stop == nil {
+		return ErrNotInitialized
+	}
+	var err error
+	t.once.Do(func() {
+		close(t.stop)
+		for _, r := range t.readers {
+			r.close()
+		}
+		if c, ok := t.r.(io.Closer); ok {
+			err = c.Close()
+		}
+	})
+	return err
+}
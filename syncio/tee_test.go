@@ -0,0 +1,129 @@
+package syncio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTee(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	tee := NewTee(bytes.NewReader(data), 512,
+		TeeConsumer{Name: "checksum"},
+		TeeConsumer{Name: "upload", MaxLag: 1},
+		TeeConsumer{Name: "index", MaxLag: 2},
+	)
+	defer tee.Close()
+
+	var wg sync.WaitGroup
+	results := make([][]byte, 3)
+	errs := make([]error, 3)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := tee.Reader(i)
+			switch i {
+			case 0:
+				h := sha256.New()
+				_, errs[i] = io.Copy(h, r)
+				results[i] = h.Sum(nil)
+			case 1:
+				// the slow consumer paces the others
+				var out bytes.Buffer
+				p := make([]byte, 100)
+				for {
+					n, err := r.Read(p)
+					out.Write(p[:n])
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						errs[i] = err
+						break
+					}
+					time.Sleep(10 * time.Microsecond)
+				}
+				results[i] = out.Bytes()
+			default:
+				results[i], errs[i] = io.ReadAll(r)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	sum := sha256.Sum256(data)
+	if !bytes.Equal(results[0], sum[:]) || !bytes.Equal(results[1], data) || !bytes.Equal(results[2], data) {
+		t.Errorf("consumers read %v, %v bytes", len(results[1]), len(results[2]))
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("consumer %v: %v", i, err)
+		}
+	}
+	if s := tee.Stats(); s.FillBytes != int64(len(data)) {
+		t.Errorf("filled %v bytes", s.FillBytes)
+	}
+	if s := tee.Reader(1).Stats(); s.Bytes != int64(len(data)) || s.Detached {
+		t.Errorf("upload stats: %+v", s)
+	}
+}
+
+func TestTeeDetach(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 64*1024)
+	tee := NewTee(bytes.NewReader(data), 1024,
+		TeeConsumer{Name: "fast"},
+		TeeConsumer{Name: "stuck", MaxLag: 2, Policy: LagDetach},
+	)
+	defer tee.Close()
+
+	got, err := io.ReadAll(tee.Reader(0))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("fast consumer read %v bytes, %v", len(got), err)
+	}
+	stuck := tee.Reader(1)
+	if s := stuck.Stats(); !s.Detached || s.Lag != 0 {
+		t.Errorf("stuck stats: %+v", s)
+	}
+	if _, err := stuck.Read(make([]byte, 10)); !errors.Is(err, ErrDetached) {
+		t.Errorf("read of the detached consumer: %v", err)
+	}
+	// the detached blocks went back to the pool
+	if s := tee.Stats(); s.BlockAllocs > 8 {
+		t.Errorf("%v blocks allocated", s.BlockAllocs)
+	}
+}
+
+func TestTeeClose(t *testing.T) {
+	pr, pw := io.Pipe()
+	tee := NewTee(pr, 16, TeeConsumer{}, TeeConsumer{})
+	a, b := tee.Reader(0), tee.Reader(1)
+	go pw.Write([]byte("hello"))
+	p := make([]byte, 16)
+	if n, err := a.Read(p); err != nil || string(p[:n]) != "hello" {
+		t.Fatalf("read %q, %v", p[:n], err)
+	}
+	// the source doesn't wait for a closed consumer
+	b.Close()
+	if _, err := b.Read(p); err != ErrReaderClosed {
+		t.Errorf("read of a closed consumer: %v", err)
+	}
+	go pw.Write([]byte("world"))
+	if n, err := a.Read(p); err != nil || string(p[:n]) != "world" {
+		t.Fatalf("read %q, %v", p[:n], err)
+	}
+	if err := tee.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Read(p); err != ErrReaderClosed {
+		t.Errorf("read after the tee close: %v", err)
+	}
+	var zero TeeReader
+	if _, err := zero.Read(p); err != ErrNotInitialized {
+		t.Errorf("zero reader: %v", err)
+	}
+}
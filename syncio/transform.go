@@ -0,0 +1,57 @@
+package syncio
+
+import (
+	"fmt"
+
+	"github.com/travelgateX/go-io/syncio/internal"
+)
+
+// SetFlushTransform sets a function applied to every batch just before it's written, e.g. to
+// redact sensitive data. It appends the transformed src to dst, a pool buffer, and returns it,
+// or returns src to leave the batch unchanged. If it fails the batch isn't written and it's
+// reported as a flush error with the original data, which is sent to the dead letter writer.
+// The transform runs before the underlying writer, so a compressing writer (e.g. a gzip.Writer
+// with SetSinkFlush) encodes the transformed data. It's called from the flush goroutine and
+// it has no effect with SetRecordMode or when writing into another Buffer.
+func SetFlushTransform(fn func(dst, src []byte) ([]byte, error)) BufferOption {
+	return func(b *Buffer) {
+		b.transform = fn
+	}
+}
+
+// transformBatch returns the data to write for p and the pool buffer holding it, to be put
+// back once written, after SetFlushTransform and SetCompression. p is returned with their errors.
+func (tb *Buffer) transformBatch(p []byte) ([]byte, *internal.Buffer, error) {
+	if tb.transform == nil && tb.compressor == nil || tb.parent != nil || tb.recordMode {
+		return p, nil, nil
+	}
+	t, dst := p, (*internal.Buffer)(nil)
+	if tb.transform != nil {
+		var err error
+		if t, dst, err = tb.transformStage(tb.transform, p); err != nil {
+			return p, nil, fmt.Errorf("flush transform: %w", err)
+		}
+	}
+	if tb.compressor != nil {
+		c, cdst, err := tb.transformStage(tb.compressor.Compress, t)
+		if dst != nil {
+			tb.putBuffer(dst)
+		}
+		if err != nil {
+			return p, nil, fmt.Errorf("flush compression: %w", err)
+		}
+		t, dst = c, cdst
+	}
+	return t, dst, nil
+}
+
+// transformStage applies fn to src into a pool buffer, it's put back on error
+func (tb *Buffer) transformStage(fn func(dst, src []byte) ([]byte, error), src []byte) ([]byte, *internal.Buffer, error) {
+	dst, _ := tb.getBuffer()
+	t, err := fn(dst.Scratch()[:0], src)
+	if err != nil {
+		tb.putBuffer(dst)
+		return nil, nil, err
+	}
+	return t, dst, nil
+}
@@ -0,0 +1,99 @@
+package syncio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"regexp"
+	"testing"
+)
+
+var cardNumber = regexp.MustCompile(`\b\d{13,16}\b`)
+
+// redact replaces the card numbers, src is returned when there's none
+func redact(dst, src []byte) ([]byte, error) {
+	locs := cardNumber.FindAllIndex(src, -1)
+	if len(locs) == 0 {
+		return src, nil
+	}
+	last := 0
+	for _, l := range locs {
+		dst = append(dst, src[last:l[0]]...)
+		dst = append(dst, "[redacted]"...)
+		last = l[1]
+	}
+	return append(dst, src[last:]...), nil
+}
+
+func TestFlushTransform(t *testing.T) {
+	out := &bytes.Buffer{}
+	tb := NewBuffer(out, SetBufferSize(64), SetFlushTransform(redact))
+	tb.Write([]byte("paid with 4111111111111111 ok\n"))
+	tb.Flush()
+	tb.Write([]byte("nothing to hide\n"))
+	tb.Close()
+
+	expected := "paid with [redacted] ok\nnothing to hide\n"
+	if s := out.String(); s != expected {
+		t.Errorf("transformed data: %q, expected: %q", s, expected)
+	}
+	if s := tb.Stats(); s.Flushes != 2 || s.DirectBytes != 46 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+func TestFlushTransformError(t *testing.T) {
+	out, dead := &bytes.Buffer{}, &bytes.Buffer{}
+	var ferrs []*FlushError
+	fail := errors.New("transform failed")
+	tb := NewBuffer(out, SetDeadLetter(dead), SetOnFlushError(func(err *FlushError, _ []byte) {
+		ferrs = append(ferrs, err)
+	}), SetFlushTransform(func(dst, src []byte) ([]byte, error) {
+		if bytes.Contains(src, []byte("bad")) {
+			return nil, fail
+		}
+		return src, nil
+	}))
+	tb.Write([]byte("bad data"))
+	if err := tb.Flush(); !errors.Is(err, fail) {
+		t.Errorf("flush error: %v, expected: %v", err, fail)
+	}
+	tb.Write([]byte("good data"))
+	tb.Close()
+
+	if s := out.String(); s != "good data" {
+		t.Errorf("written data: %q, expected: %q", s, "good data")
+	}
+	if s := dead.String(); s != "bad data" {
+		t.Errorf("dead letter data: %q, expected: %q", s, "bad data")
+	}
+	if len(ferrs) != 1 || ferrs[0].Unwritten != 8 || !ferrs[0].LostData {
+		t.Errorf("flush errors: %+v", ferrs)
+	}
+	if s := tb.Stats(); s.Flushes != 1 || s.FlushErrors != 1 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+// TestFlushTransformCompression checks that a compressing writer receives the transformed data
+func TestFlushTransformCompression(t *testing.T) {
+	out := &bytes.Buffer{}
+	zw := gzip.NewWriter(out)
+	tb := NewBuffer(zw, SetFlushTransform(redact), SetSinkFlush(true))
+	tb.Write([]byte("card 5500000000000004\n"))
+	tb.Close()
+	zw.Close()
+
+	zr, err := gzip.NewReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(p); s != "card [redacted]\n" {
+		t.Errorf("decompressed data: %q, expected: %q", s, "card [redacted]\n")
+	}
+}
@@ -0,0 +1,245 @@
+// Package upload writes an object of a cloud storage in multipart upload parts, e.g. as the
+// underlying writer of a syncio.Buffer: the writes are staged in pooled buffers of the part
+// size, every full part is uploaded while the next one fills and Close completes the upload.
+// The storage is behind the Uploader interface so the SDKs stay out of this module.
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/travelgateX/go-io/syncio/bufpool"
+)
+
+const (
+	// DefaultPartSize is the default size of the parts, S3 requires 5MiB but for the last one
+	DefaultPartSize = 8 << 20
+	// DefaultConcurrency is the default number of parts uploaded at the same time
+	DefaultConcurrency = 4
+)
+
+var (
+	// ErrClosed is returned by the calls of a Writer completed or aborted
+	ErrClosed         = errors.New("upload: write on closed writer")
+	errNotInitialized = errors.New("upload: not initialized")
+)
+
+// Part is an uploaded part, Number counts from 1 and ID is the identifier returned by the
+// storage, e.g. the ETag of S3
+type Part struct {
+	Number int
+	ID     string
+	Size   int
+}
+
+// Uploader is a multipart upload of an object started by the storage SDK, e.g. with the
+// upload ID of a CreateMultipartUpload. UploadPart is called concurrently for different parts
+// and p must not be retained after it returns, it's returned to the pool.
+type Uploader interface {
+	UploadPart(ctx context.Context, number int, p []byte) (id string, err error)
+	// Complete finishes the upload with the parts in order
+	Complete(ctx context.Context, parts []Part) error
+	// Abort discards the uploaded parts
+	Abort(ctx context.Context) error
+}
+
+// Option is an option of NewWriter
+type Option func(*Writer)
+
+// SetPartSize sets the size of the parts, DefaultPartSize by default
+func SetPartSize(n int) Option {
+	return func(w *Writer) {
+		w.partSize = n
+	}
+}
+
+// SetConcurrency sets the number of parts uploaded at the same time, DefaultConcurrency by
+// default. The writes wait once as many parts are being uploaded and the next one is full.
+func SetConcurrency(n int) Option {
+	return func(w *Writer) {
+		w.concurrency = n
+	}
+}
+
+// Stats are the counters of a Writer: Parts is the number of parts uploaded, Bytes their size
+// and Uploading the parts being uploaded
+type Stats struct {
+	Parts     int64
+	Bytes     int64
+	Uploading int64
+}
+
+// Writer stages the writes in parts uploaded by an Uploader, it's safe for concurrent use.
+// Once a part fails the next writes return its error and Close aborts the upload.
+type Writer struct {
+	ctx         context.Context
+	u           Uploader
+	partSize    int
+	concurrency int
+	pool        *bufpool.Pool
+	sem         chan struct{}
+	wg          sync.WaitGroup
+
+	// mu guards the part being staged
+	mu     sync.Mutex
+	cur    []byte
+	next   int
+	closed bool
+
+	// done guards the results of the uploads
+	done  sync.Mutex
+	parts []Part
+	err   error
+
+	stats Stats
+}
+
+// NewWriter returns a Writer uploading the parts of u, ctx is the context of the Uploader calls
+func NewWriter(ctx context.Context, u Uploader, options ...Option) *Writer {
+	w := &Writer{ctx: ctx, u: u, next: 1}
+	for _, o := range options {
+		o(w)
+	}
+	if w.partSize <= 0 {
+		w.partSize = DefaultPartSize
+	}
+	if w.concurrency <= 0 {
+		w.concurrency = DefaultConcurrency
+	}
+	// the parts being uploaded and the one being staged
+	w.pool = bufpool.New(w.partSize, w.concurrency+1)
+	w.sem = make(chan struct{}, w.concurrency)
+	return w
+}
+
+// Write copies p into the parts, the full ones are uploaded
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.u == nil {
+		return 0, errNotInitialized
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if err := w.failed(); err != nil {
+		return 0, err
+	}
+	written := 0
+	for len(p) > 0 {
+		if w.cur == nil {
+			w.cur = w.pool.Get()[:0]
+		}
+		n := copy(w.cur[len(w.cur):w.partSize], p)
+		w.cur = w.cur[:len(w.cur)+n]
+		written += n
+		p = p[n:]
+		if len(w.cur) == w.partSize {
+			w.upload()
+		}
+	}
+	return written, nil
+}
+
+// upload uploads the part being staged in background, the caller must hold mu
+func (w *Writer) upload() {
+	part, number := w.cur, w.next
+	w.cur = nil
+	w.next++
+	w.sem <- struct{}{}
+	w.wg.Add(1)
+	atomic.AddInt64(&w.stats.Uploading, 1)
+	go func() {
+		defer w.wg.Done()
+		id, err := w.u.UploadPart(w.ctx, number, part)
+		size := len(part)
+		w.pool.Put(part)
+		atomic.AddInt64(&w.stats.Uploading, -1)
+		<-w.sem
+
+		w.done.Lock()
+		defer w.done.Unlock()
+		if err != nil {
+			if w.err == nil {
+				w.err = fmt.Errorf("upload: part %v: %w", number, err)
+			}
+			return
+		}
+		w.parts = append(w.parts, Part{Number: number, ID: id, Size: size})
+		atomic.AddInt64(&w.stats.Parts, 1)
+		atomic.AddInt64(&w.stats.Bytes, int64(size))
+	}()
+}
+
+// failed returns the error of the first part failed
+func (w *Writer) failed() error {
+	w.done.Lock()
+	defer w.done.Unlock()
+	return w.err
+}
+
+// Stats returns the counters of the uploads
+func (w *Writer) Stats() Stats {
+	return Stats{
+		Parts:     atomic.LoadInt64(&w.stats.Parts),
+		Bytes:     atomic.LoadInt64(&w.stats.Bytes),
+		Uploading: atomic.LoadInt64(&w.stats.Uploading),
+	}
+}
+
+// Close uploads the last part, waits for the uploads and completes the upload, an object
+// without writes is a single empty part. If a part failed the upload is aborted and the
+// error returned. The next calls return ErrClosed.
+func (w *Writer) Close() error {
+	if w.u == nil {
+		return errNotInitialized
+	}
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.closed = true
+	if w.cur != nil || w.next == 1 {
+		if w.cur == nil {
+			w.cur = w.pool.Get()[:0]
+		}
+		w.upload()
+	}
+	w.mu.Unlock()
+	w.wg.Wait()
+
+	if err := w.failed(); err != nil {
+		if aerr := w.u.Abort(w.ctx); aerr != nil {
+			return errors.Join(err, aerr)
+		}
+		return err
+	}
+	sort.Slice(w.parts, func(i, j int) bool { return w.parts[i].Number < w.parts[j].Number })
+	return w.u.Complete(w.ctx, w.parts)
+}
+
+// Abort waits for the uploads in flight and aborts the upload, the data isn't stored. The next
+// calls return ErrClosed.
+func (w *Writer) Abort() error {
+	if w.u == nil {
+		return errNotInitialized
+	}
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.closed = true
+	if w.cur != nil {
+		w.pool.Put(w.cur)
+		w.cur = nil
+	}
+	w.mu.Unlock()
+	w.wg.Wait()
+	return w.u.Abort(w.ctx)
+}
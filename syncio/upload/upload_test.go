@@ -0,0 +1,119 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/travelgateX/go-io/syncio"
+)
+
+// memoryUpload keeps the parts in memory, fail fails the part with that number
+type memoryUpload struct {
+	mu        sync.Mutex
+	parts     map[int][]byte
+	fail      int
+	completed []Part
+	aborted   bool
+}
+
+func (m *memoryUpload) UploadPart(ctx context.Context, number int, p []byte) (string, error) {
+	if number == m.fail {
+		return "", errors.New("storage unavailable")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.parts == nil {
+		m.parts = map[int][]byte{}
+	}
+	m.parts[number] = append([]byte(nil), p...)
+	return string(rune('a' + number)), nil
+}
+
+func (m *memoryUpload) Complete(ctx context.Context, parts []Part) error {
+	m.completed = parts
+	return nil
+}
+
+func (m *memoryUpload) Abort(ctx context.Context) error {
+	m.aborted = true
+	return nil
+}
+
+// object returns the data of the completed parts
+func (m *memoryUpload) object() []byte {
+	var b []byte
+	for _, p := range m.completed {
+		b = append(b, m.parts[p.Number]...)
+	}
+	return b
+}
+
+func TestWriter(t *testing.T) {
+	m := &memoryUpload{}
+	w := NewWriter(context.Background(), m, SetPartSize(100), SetConcurrency(2))
+	tb := syncio.NewBuffer(w, syncio.SetBufferSize(64))
+	var expected []byte
+	for i := 0; i < 200; i++ {
+		p := bytes.Repeat([]byte{byte(i)}, i%7+1)
+		tb.Write(p)
+		expected = append(expected, p...)
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(m.object(), expected) {
+		t.Errorf("object of %v bytes, expected %v", len(m.object()), len(expected))
+	}
+	parts := (len(expected) + 99) / 100
+	if len(m.completed) != parts {
+		t.Fatalf("%v parts, expected %v", len(m.completed), parts)
+	}
+	for i, p := range m.completed {
+		if p.Number != i+1 || p.ID != string(rune('a'+i+1)) || i < parts-1 && p.Size != 100 {
+			t.Errorf("part %v: %+v", i, p)
+		}
+	}
+	if s := w.Stats(); s.Parts != int64(parts) || s.Bytes != int64(len(expected)) || s.Uploading != 0 {
+		t.Errorf("stats: %+v", s)
+	}
+	if _, err := w.Write([]byte("x")); err != ErrClosed {
+		t.Errorf("write after close: %v", err)
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	m := &memoryUpload{}
+	w := NewWriter(context.Background(), m)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.completed) != 1 || m.completed[0].Size != 0 {
+		t.Errorf("parts of an empty object: %+v", m.completed)
+	}
+}
+
+func TestWriterPartError(t *testing.T) {
+	m := &memoryUpload{fail: 2}
+	w := NewWriter(context.Background(), m, SetPartSize(10), SetConcurrency(1))
+	w.Write(bytes.Repeat([]byte("x"), 25))
+	err := w.Close()
+	if err == nil || !m.aborted || m.completed != nil {
+		t.Errorf("close: %v, aborted: %v, completed: %v", err, m.aborted, m.completed)
+	}
+
+	m = &memoryUpload{}
+	w = NewWriter(context.Background(), m, SetPartSize(10))
+	w.Write([]byte("abc"))
+	if err := w.Abort(); err != nil || !m.aborted || len(m.parts) != 0 {
+		t.Errorf("abort: %v, aborted: %v, parts: %v", err, m.aborted, len(m.parts))
+	}
+	if err := w.Close(); err != ErrClosed {
+		t.Errorf("close after abort: %v", err)
+	}
+}
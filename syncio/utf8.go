@@ -0,0 +1,55 @@
+package syncio
+
+import "unicode/utf8"
+
+// SetUTF8Boundaries makes the size, tick and idle flushes cut the data only at rune boundaries,
+// the bytes of an incomplete rune at the end of a buffer, up to 3, are carried to the next
+// buffer. Flush and Close write all the data. It has no effect with SetRecordMode, where the
+// writes are never split.
+func SetUTF8Boundaries(enabled bool) BufferOption {
+	return func(b *Buffer) {
+		b.utf8Boundaries = enabled
+	}
+}
+
+// carryRune returns the active buffer data followed by p, the trailing bytes of an incomplete
+// rune are left in the active buffer instead
+func (tb *Buffer) carryRune(p []byte) []byte {
+	carried := tb.buf.Bytes()
+	b := make([]byte, len(carried)+len(p))
+	copy(b, carried)
+	copy(b[len(carried):], p)
+	tb.buf.Reset()
+	if k := partialRune(b); k > 0 && k < len(b) {
+		tb.buf.Write(b[len(b)-k:])
+		b = b[:len(b)-k]
+	}
+	return b
+}
+
+// partialRune returns the number of bytes of an incomplete rune at the end of p
+func partialRune(p []byte) int {
+	for i := 1; i < utf8.UTFMax && i <= len(p); i++ {
+		c := p[len(p)-i]
+		if c < utf8.RuneSelf {
+			return 0
+		}
+		if !utf8.RuneStart(c) {
+			continue
+		}
+		size := 0
+		switch {
+		case c >= 0xf0:
+			size = 4
+		case c >= 0xe0:
+			size = 3
+		case c >= 0xc0:
+			size = 2
+		}
+		if size > i {
+			return i
+		}
+		return 0
+	}
+	return 0
+}
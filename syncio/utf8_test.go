@@ -0,0 +1,75 @@
+package syncio
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// utf8Writer checks that every write is valid UTF-8
+type utf8Writer struct {
+	mu      sync.Mutex
+	out     bytes.Buffer
+	invalid int
+}
+
+func (w *utf8Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !utf8.Valid(p) {
+		w.invalid++
+	}
+	return w.out.Write(p)
+}
+
+func TestUTF8Boundaries(t *testing.T) {
+	text := []byte(strings.Repeat("añb€c𝄞d日本語ü ", 200))
+	rnd := rand.New(rand.NewSource(1))
+	for _, enabled := range []bool{true, false} {
+		w := &utf8Writer{}
+		tb := NewBuffer(w, SetBufferSize(16), SetFlushInterval(time.Millisecond), SetUTF8Boundaries(enabled))
+		for p := text; len(p) > 0; {
+			n := 1 + rnd.Intn(24)
+			if n > len(p) {
+				n = len(p)
+			}
+			tb.Write(p[:n])
+			p = p[n:]
+		}
+		tb.Close()
+
+		if !bytes.Equal(w.out.Bytes(), text) {
+			t.Fatalf("utf8 boundaries %v: written data doesn't match", enabled)
+		}
+		if (w.invalid == 0) != enabled {
+			t.Errorf("utf8 boundaries %v: invalid writes: %v", enabled, w.invalid)
+		}
+	}
+}
+
+func TestPartialRune(t *testing.T) {
+	r := []byte("𝄞")
+	tests := []struct {
+		p []byte
+		n int
+	}{
+		{[]byte("abc"), 0},
+		{[]byte("ab€"), 0},
+		{r, 0},
+		{r[:1], 1},
+		{r[:2], 2},
+		{r[:3], 3},
+		{append([]byte("a"), r[:3]...), 3},
+		{[]byte("€")[:2], 2},
+		{[]byte{0x80, 0x80}, 0},
+	}
+	for _, tt := range tests {
+		if n := partialRune(tt.p); n != tt.n {
+			t.Errorf("partial rune of %v: %v, expected: %v", tt.p, n, tt.n)
+		}
+	}
+}
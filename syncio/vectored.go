@@ -0,0 +1,131 @@
+package syncio
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// The queued batches are written to a net.Conn with net.Buffers, a single writev on TCP and
+// Unix sockets, instead of one write each; with SetMaxFlushBytes they're written up to its
+// size, and to an *os.File with writev on Unix too. The single batches are written with the
+// io.ReaderFrom of the writers that have one, as *net.TCPConn and *os.File, from a reader
+// of the batch, unless it's promoted from an embedded field: it would skip the Write of the
+// type embedding it. Both are detected when the writer is set, the others get plain writes.
+
+// batchReaders are the readers of the batches written with io.ReaderFrom, the flush workers
+// write concurrently
+var batchReaders = sync.Pool{New: func() any { return new(bytes.Reader) }}
+
+// detectSink enables the vectored writes and io.ReaderFrom for the underlying writers that
+// support them, it's called when the writer changes
+func (tb *Buffer) detectSink() {
+	_, conn := tb.writer.(net.Conn)
+	_, file := tb.writer.(*os.File)
+	plain := !tb.recordMode && tb.parent == nil && tb.transform == nil && tb.compressor == nil &&
+		tb.retention == nil && tb.flushContext == nil && tb.maxSinkWrite <= 0 && tb.framing == nil &&
+		tb.writeTimeout <= 0
+	tb.vectored = plain && tb.flushConcurrency <= 1 && (conn || file && writevFiles && tb.coalesces())
+	tb.readerFrom = nil
+	if rf, ok := tb.writer.(io.ReaderFrom); ok && plain && !embedsReaderFrom(tb.writer) {
+		tb.readerFrom = rf
+	}
+}
+
+var readerFromType = reflect.TypeOf((*io.ReaderFrom)(nil)).Elem()
+
+// embedsReaderFrom reports if w is a struct with an embedded field implementing io.ReaderFrom
+func embedsReaderFrom(w io.Writer) bool {
+	t := reflect.TypeOf(w)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && (f.Type.Implements(readerFromType) || reflect.PointerTo(f.Type).Implements(readerFromType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// readFromSink writes p with the io.ReaderFrom of the underlying writer
+func (tb *Buffer) readFromSink(p []byte) (int, error) {
+	atomic.AddInt64(&tb.stats.SinkWrites, 1)
+	r := batchReaders.Get().(*bytes.Reader)
+	r.Reset(p)
+	n, err := tb.readerFrom.ReadFrom(r)
+	r.Reset(nil)
+	batchReaders.Put(r)
+	if err == nil && int(n) < len(p) {
+		err = io.ErrShortWrite
+	}
+	return int(n), err
+}
+
+// vectorize returns a batch with the data of a group without copying it, the buffers of the
+// group are sent back to the pool once it's written. The last batch of the group is the only
+// one that can be a barrier.
+func (tb *Buffer) vectorize(group []*batch) *batch {
+	vec, bufs := tb.vec[:0], tb.vecBufs[:0]
+	for _, b := range group {
+		if p := b.bytes(); len(p) > 0 {
+			vec = append(vec, p)
+		}
+		if b.buf != nil {
+			bufs = append(bufs, b.buf)
+		}
+	}
+	tb.vec, tb.vecBufs = vec, bufs
+	last := group[len(group)-1]
+	return &batch{vec: vec, bufs: bufs, walOff: group[0].walOff, trigger: group[0].trigger, done: last.done, swap: last.swap, seek: last.seek, sync: last.sync}
+}
+
+// writeVectored writes vec with a single vectored write
+func (tb *Buffer) writeVectored(vec [][]byte) (int, error) {
+	// WriteTo consumes the slices, vec is kept for the unwritten bytes
+	tb.vecw = append(tb.vecw[:0], vec...)
+	v := tb.vecw
+	var n int
+	var err error
+	if f, ok := tb.writer.(*os.File); ok {
+		var calls int
+		n, calls, err = writevFile(f, v)
+		atomic.AddInt64(&tb.stats.SinkWrites, int64(calls))
+	} else {
+		// a single write of a net.Conn
+		atomic.AddInt64(&tb.stats.SinkWrites, 1)
+		var n64 int64
+		n64, err = v.WriteTo(tb.writer)
+		n = int(n64)
+	}
+	for i := range tb.vecw {
+		tb.vecw[i] = nil
+	}
+	return n, err
+}
+
+// consumeVec removes the first n bytes of vec, the slice partially written is resliced
+func consumeVec(vec [][]byte, n int) [][]byte {
+	for len(vec) > 0 && n >= len(vec[0]) {
+		n -= len(vec[0])
+		vec[0] = nil
+		vec = vec[1:]
+	}
+	if len(vec) > 0 {
+		vec[0] = vec[0][n:]
+	}
+	return vec
+}
+
+// flatten returns the data of a vectored batch
+func (b *batch) flatten() []byte {
+	return bytes.Join(b.vec, nil)
+}
@@ -0,0 +1,247 @@
+package syncio
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// tcpPair returns the client side of a localhost TCP connection and a channel receiving
+// everything read by the server side once the client is closed
+func tcpPair(t testing.TB) (net.Conn, <-chan []byte) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("no localhost network:", err)
+	}
+	received := make(chan []byte, 1)
+	go func() {
+		defer l.Close()
+		c, err := l.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		data, _ := io.ReadAll(c)
+		c.Close()
+		received <- data
+	}()
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, received
+}
+
+// queueBatches enqueues a batch per record and returns their data
+func queueBatches(tb *Buffer, records int) []byte {
+	var data []byte
+	tb.bufmu.Lock()
+	for i := 0; i < records; i++ {
+		p := []byte(strconv.Itoa(i) + " record\n")
+		tb.acct.accept(len(p))
+		tb.enqueue(&batch{p: p})
+		data = append(data, p...)
+	}
+	tb.bufmu.Unlock()
+	return data
+}
+
+func TestVectoredWrites(t *testing.T) {
+	// the queued batches are written together without SetMaxFlushBytes too
+	for _, maxFlush := range []int{0, 1024} {
+		c, received := tcpPair(t)
+		tb := NewBuffer(c, SetSynchronousMode(true), SetMaxFlushBytes(maxFlush))
+		if !tb.vectored {
+			t.Fatal("vectored writes not enabled for a net.Conn")
+		}
+		expected := queueBatches(tb, 10)
+		tb.flushInline()
+		if err := tb.Close(); err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+		if data := <-received; !bytes.Equal(data, expected) {
+			t.Errorf("max flush %v: received: %q, expected: %q", maxFlush, data, expected)
+		}
+		if s := tb.Stats(); s.Flushes != 1 || s.Batches != 10 || s.SinkWrites != 1 {
+			t.Errorf("max flush %v: stats: %+v, expected 10 batches in a single write", maxFlush, s)
+		}
+	}
+
+	if tb := NewBuffer(&bytes.Buffer{}, SetMaxFlushBytes(1024)); tb.vectored {
+		t.Error("vectored writes enabled for a bytes.Buffer")
+	}
+}
+
+// readerFrom is a sink counting the ReadFrom calls
+type readerFrom struct {
+	buf   bytes.Buffer
+	calls int
+}
+
+func (w *readerFrom) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *readerFrom) ReadFrom(r io.Reader) (int64, error) {
+	w.calls++
+	return w.buf.ReadFrom(r)
+}
+
+// embeddedReaderFrom overrides the Write of its embedded bytes.Buffer
+type embeddedReaderFrom struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *embeddedReaderFrom) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestReaderFromSink(t *testing.T) {
+	rf := &readerFrom{}
+	tb := NewBuffer(rf, SetBufferSize(8), SetSynchronousMode(true))
+	tb.Write([]byte("aaaaaa"))
+	tb.Write([]byte("bbbbbb"))
+	tb.Close()
+	if rf.buf.String() != "aaaaaabbbbbb" || rf.calls != 2 {
+		t.Errorf("written: %q with %v ReadFrom calls, expected 2", rf.buf.String(), rf.calls)
+	}
+	if s := tb.Stats(); s.SinkWrites != 2 {
+		t.Errorf("sink writes: %v, expected: 2", s.SinkWrites)
+	}
+
+	// the promoted ReadFrom would skip the Write
+	ew := &embeddedReaderFrom{}
+	tb = NewBuffer(ew, SetBufferSize(8), SetSynchronousMode(true))
+	if tb.readerFrom != nil {
+		t.Error("ReadFrom promoted from an embedded field used")
+	}
+	tb.Write([]byte("aaaaaa"))
+	tb.Close()
+	if ew.String() != "aaaaaa" || ew.writes != 1 {
+		t.Errorf("written: %q with %v writes, expected 1", ew.String(), ew.writes)
+	}
+
+	// the options transforming the writes use plain writes
+	tb = NewBuffer(&readerFrom{}, SetMaxSinkWriteSize(4))
+	if tb.readerFrom != nil {
+		t.Error("ReadFrom used with SetMaxSinkWriteSize")
+	}
+	tb.Close()
+}
+
+func TestVectoredWritesError(t *testing.T) {
+	c, peer := net.Pipe()
+	peer.Close()
+	dl := &bytes.Buffer{}
+	fe := &flushErrors{}
+	tb := NewBuffer(c, SetSynchronousMode(true), SetMaxFlushBytes(1024), SetDeadLetter(dl), SetOnFlushError(fe.callback))
+	expected := queueBatches(tb, 5)
+	tb.flushInline()
+	tb.Close()
+	if !bytes.Equal(dl.Bytes(), expected) {
+		t.Errorf("dead letter: %q, expected: %q", dl.Bytes(), expected)
+	}
+	if errs, lost := fe.get(); len(errs) != 1 || errs[0].BatchBytes != len(expected) || lost != len(expected) {
+		t.Errorf("flush errors: %v, unwritten: %v", errs, lost)
+	}
+}
+
+// plainWriter hides the io.ReaderFrom and net.Conn of a writer
+type plainWriter struct {
+	io.Writer
+}
+
+// connReaderFrom hides the net.Conn of a connection but keeps its io.ReaderFrom
+type connReaderFrom struct {
+	io.Writer
+	rf io.ReaderFrom
+}
+
+func (c connReaderFrom) ReadFrom(r io.Reader) (int64, error) {
+	return c.rf.ReadFrom(r)
+}
+
+// writeSyscalls returns the write system calls of the process, the wrappers of a connection
+// can't count the writev of net.Buffers. It skips b without /proc/self/io.
+func writeSyscalls(b *testing.B) int64 {
+	data, err := os.ReadFile("/proc/self/io")
+	if err != nil {
+		b.Skip("no write system calls count:", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "syscw: "); ok {
+			n, _ := strconv.ParseInt(v, 10, 64)
+			return n
+		}
+	}
+	b.Skip("no syscw in /proc/self/io")
+	return 0
+}
+
+// BenchmarkTCPWrites compares the writes of every batch, with Write or ReadFrom, with the
+// vectored writes of the queued batches, writes/op is the number of write system calls to
+// the connection per Write
+func BenchmarkTCPWrites(b *testing.B) {
+	p := bytes.Repeat([]byte("x"), 99)
+	p = append(p, '\n')
+	sinks := []struct {
+		name string
+		sink func(c net.Conn) io.Writer
+	}{
+		{"write", func(c net.Conn) io.Writer { return plainWriter{c} }},
+		{"readfrom", func(c net.Conn) io.Writer { return connReaderFrom{c, c.(io.ReaderFrom)} }},
+		{"vectored", func(c net.Conn) io.Writer { return c }},
+	}
+	for _, s := range sinks {
+		b.Run(s.name, func(b *testing.B) {
+			c, received := tcpPair(b)
+			tb := NewBuffer(s.sink(c), SetBufferSize(1024), SetBufferPoolSize(16))
+			start := writeSyscalls(b)
+			b.SetBytes(int64(len(p)))
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					tb.Write(p)
+				}
+			})
+			tb.Close()
+			b.StopTimer()
+			writes := writeSyscalls(b) - start
+			c.Close()
+			<-received
+			b.ReportMetric(float64(writes)/float64(b.N), "writes/op")
+		})
+	}
+}
+
+func TestConsumeVec(t *testing.T) {
+	tests := []struct {
+		n        int
+		expected []string
+	}{
+		{0, []string{"abc", "de", "f"}},
+		{2, []string{"c", "de", "f"}},
+		{3, []string{"de", "f"}},
+		{4, []string{"e", "f"}},
+		{5, []string{"f"}},
+		{6, []string{}},
+	}
+	for _, test := range tests {
+		vec := [][]byte{[]byte("abc"), []byte("de"), []byte("f")}
+		rest := consumeVec(vec, test.n)
+		got := make([]string, len(rest))
+		for i, p := range rest {
+			got[i] = string(p)
+		}
+		if strings.Join(got, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("consume %v: %q, expected: %q", test.n, got, test.expected)
+		}
+	}
+}
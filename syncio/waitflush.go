@@ -0,0 +1,82 @@
+package syncio
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosedBeforeFlush is returned by WaitForFlushes and WaitForBytes when the Buffer is
+// closed and its data written before reaching the count
+var ErrClosedBeforeFlush = errors.New("buffer closed before the flushes waited for")
+
+// flushWait signals the end of the writes of the flush goroutine, flushes and bytes are the
+// Stats.Flushes and flushed bytes once the writes returned
+type flushWait struct {
+	flushes atomic.Int64
+	bytes   atomic.Int64
+
+	mu sync.Mutex
+	// ch is closed by the next wake, it's only created when there are waiters
+	ch chan struct{}
+}
+
+// wake publishes the counters after a write and wakes up the waiters
+func (w *flushWait) wake(tb *Buffer) {
+	w.flushes.Store(atomic.LoadInt64(&tb.stats.Flushes))
+	w.bytes.Store(atomic.LoadInt64(&tb.stats.DirectBytes) + atomic.LoadInt64(&tb.stats.ChildBytes))
+	w.mu.Lock()
+	if w.ch != nil {
+		close(w.ch)
+		w.ch = nil
+	}
+	w.mu.Unlock()
+}
+
+// changed returns a channel closed by the next wake
+func (w *flushWait) changed() <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ch == nil {
+		w.ch = make(chan struct{})
+	}
+	return w.ch
+}
+
+// WaitForFlushes blocks until Stats.Flushes reaches n and those writes to the underlying
+// writer returned, failed or not, or ctx is done. It returns ErrClosedBeforeFlush when the
+// Buffer is closed with fewer flushes. It's a barrier for the tests of the code writing
+// through a Buffer, the data isn't flushed by waiting.
+func (tb *Buffer) WaitForFlushes(ctx context.Context, n int64) error {
+	return tb.waitFor(ctx, &tb.flushWait.flushes, n)
+}
+
+// WaitForBytes is WaitForFlushes for n bytes flushed, Stats.DirectBytes plus ChildBytes
+func (tb *Buffer) WaitForBytes(ctx context.Context, n int64) error {
+	return tb.waitFor(ctx, &tb.flushWait.bytes, n)
+}
+
+func (tb *Buffer) waitFor(ctx context.Context, count *atomic.Int64, n int64) error {
+	if !tb.initialized() {
+		return ErrNotInitialized
+	}
+	for {
+		// the channel is taken before the count so a wake in between isn't missed
+		changed := tb.flushWait.changed()
+		if count.Load() >= n {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tb.done:
+			// the last write woke up before done was closed
+			if count.Load() >= n {
+				return nil
+			}
+			return ErrClosedBeforeFlush
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package syncio
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitForFlushes(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		clock := newFakeClock()
+		tw := &testWriter{}
+		tb := NewBuffer(tw, append([]BufferOption{SetBufferSize(4), SetClock(clock)}, mode...)...)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		waited := make(chan error, 1)
+		go func() {
+			waited <- tb.WaitForBytes(ctx, 8)
+		}()
+		tb.Write([]byte("1234"))
+		tb.Write([]byte("5678"))
+		if err := <-waited; err != nil {
+			t.Fatalf("wait for bytes: %v", err)
+		}
+		if tw.bytes != 8 {
+			t.Errorf("bytes written once waited: %v, expected: 8", tw.bytes)
+		}
+		if err := tb.WaitForFlushes(ctx, 2); err != nil {
+			t.Errorf("wait for the flushes done: %v", err)
+		}
+
+		short, stop := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer stop()
+		if err := tb.WaitForFlushes(short, 3); err != context.DeadlineExceeded {
+			t.Errorf("wait for a flush not done: %v, expected: %v", err, context.DeadlineExceeded)
+		}
+
+		tb.Write([]byte("9"))
+		tb.Close()
+		if err := tb.WaitForFlushes(ctx, 3); err != nil {
+			t.Errorf("wait for the close flush: %v", err)
+		}
+		if err := tb.WaitForFlushes(ctx, 4); err != ErrClosedBeforeFlush {
+			t.Errorf("wait on closed: %v, expected: %v", err, ErrClosedBeforeFlush)
+		}
+	})
+}
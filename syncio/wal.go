@@ -0,0 +1,95 @@
+package syncio
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrWriteAheadLog is matched by the errors of the writes failed by the SetWriteAheadLog log
+var ErrWriteAheadLog = errors.New("write-ahead log append failed")
+
+// WriteAheadLog persists the writes of a Buffer until they are flushed, see SetWriteAheadLog.
+// The journal package implements it with segment files.
+type WriteAheadLog interface {
+	// Append persists p before it's buffered, p isn't retained
+	Append(p []byte) error
+	// Commit marks the next n bytes appended as flushed
+	Commit(n int64) error
+}
+
+// SetWriteAheadLog appends every write to l before it's buffered, a write is accepted once
+// it's appended and fails with ErrWriteAheadLog otherwise. The bytes leave the Buffer once
+// their batch is written to the underlying writer or reported as failed, or dropped by the
+// overflow policies, CloseTimeout or Steal, and they are committed in the order they were
+// appended: a batch that ends before the older ones, e.g. with SetFlushConcurrency, is only
+// committed with them, so the data not committed holds the data lost if the process dies.
+// The writes are journaled in order holding the lock, the lock free paths are disabled.
+// A child Buffer commits its batches once handed over to the parent. NewBuffer panics
+// with SetPriorityClasses, the class records aren't written in the order they're appended.
+func SetWriteAheadLog(l WriteAheadLog) BufferOption {
+	return func(tb *Buffer) {
+		tb.wal = l
+	}
+}
+
+// appendWAL appends p to the log before it's buffered, the caller holds bufmu. p is copied
+// to walBuf so the Write slices don't escape.
+func (tb *Buffer) appendWAL(p []byte) error {
+	tb.walBuf = append(tb.walBuf[:0], p...)
+	err := tb.wal.Append(tb.walBuf)
+	if cap(tb.walBuf) > tb.bufSize {
+		// the copies of the oversized writes aren't kept
+		tb.walBuf = nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrWriteAheadLog, err)
+	}
+	return nil
+}
+
+// walCommits orders the commits of the ranges of the log that leave the Buffer
+type walCommits struct {
+	mu sync.Mutex
+	// next is the offset of the first byte not committed, ended maps the start of the ranges
+	// that left after it to their end
+	next  int64
+	ended map[int64]int64
+}
+
+// walRange returns the log offset of the next n bytes queued, the caller holds bufmu
+func (tb *Buffer) walRange(n int) int64 {
+	off := tb.walOff
+	tb.walOff += int64(n)
+	return off
+}
+
+// commitWAL commits the n bytes at off that left the Buffer once the bytes before them left
+// too, a failed commit only delivers the data again after a crash
+func (tb *Buffer) commitWAL(off int64, n int) {
+	if tb.wal == nil || n <= 0 {
+		return
+	}
+	c := &tb.walCommits
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := off + int64(n)
+	if off != c.next {
+		if c.ended == nil {
+			c.ended = map[int64]int64{}
+		}
+		c.ended[off] = end
+		return
+	}
+	for {
+		e, ok := c.ended[end]
+		if !ok {
+			break
+		}
+		delete(c.ended, end)
+		end = e
+	}
+	c.next = end
+	// committed holding mu so the log sees the commits in order
+	tb.wal.Commit(end - off)
+}
@@ -0,0 +1,135 @@
+package syncio
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testLog is a WriteAheadLog in memory, it fails the appends once fail is set
+type testLog struct {
+	mu        sync.Mutex
+	data      bytes.Buffer
+	committed int64
+	fail      error
+}
+
+func (l *testLog) Append(p []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.fail != nil {
+		return l.fail
+	}
+	l.data.Write(p)
+	return nil
+}
+
+func (l *testLog) Commit(n int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.committed += n
+	return nil
+}
+
+// pending returns the data appended and not committed
+func (l *testLog) pending() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.data.String()[l.committed:]
+}
+
+func TestWriteAheadLog(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		var l testLog
+		var out lockedBuffer
+		tb := NewBuffer(&out, append([]BufferOption{SetBufferSize(8), SetWriteAheadLog(&l), SetManualTick(true)}, mode...)...)
+		tb.Write([]byte("aaaa"))
+		tb.Write([]byte("bbbbbbbbbbbb"))
+		tb.Write([]byte("cc"))
+		if err := tb.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if s := l.pending(); s != "" {
+			t.Errorf("pending after flush: %q", s)
+		}
+		tb.Write([]byte("dd"))
+		if s := l.pending(); s != "dd" {
+			t.Errorf("pending: %q, expected: %q", s, "dd")
+		}
+
+		l.mu.Lock()
+		l.fail = errors.New("disk full")
+		l.mu.Unlock()
+		if n, err := tb.Write([]byte("ee")); n != 0 || !errors.Is(err, ErrWriteAheadLog) {
+			t.Errorf("write: %v, %v, expected: 0, %v", n, err, ErrWriteAheadLog)
+		}
+		tb.Close()
+		if s := out.String(); s != "aaaabbbbbbbbbbbbccdd" {
+			t.Errorf("written: %q", s)
+		}
+		if s := l.pending(); s != "" {
+			t.Errorf("pending after close: %q", s)
+		}
+	})
+}
+
+func TestWriteAheadLogDrops(t *testing.T) {
+	var l testLog
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetBufferPoolSize(2), SetOverflowPolicy(OverflowDropOldest), SetWriteAheadLog(&l))
+	for i := 0; i < 8; i++ {
+		tb.Write([]byte("aaaa"))
+	}
+	p := tb.Steal()
+	if len(p) == 0 || tb.Stats().OverflowDropBytes == 0 {
+		t.Fatalf("stolen: %v, dropped: %v, expected both", len(p), tb.Stats().OverflowDropBytes)
+	}
+	close(bw.release)
+	tb.Close()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.committed != 32 {
+		t.Errorf("committed: %v, expected: 32 (stolen: %v, written: %v, dropped: %v)", l.committed, len(p), bw.out.Len(), tb.Stats().OverflowDropBytes)
+	}
+}
+
+func TestWriteAheadLogOrder(t *testing.T) {
+	// the data stolen is newer than the batch in flight, it's committed once that one is written
+	var l testLog
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetBufferSize(4), SetWriteAheadLog(&l))
+	tb.Write([]byte("aaaa"))
+	for deadline := time.Now().Add(time.Second); tb.Stats().Flushes == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("batch not in flight")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	tb.Write([]byte("bb"))
+	if p := tb.Steal(); string(p) != "bb" {
+		t.Fatalf("stolen: %q, expected: %q", p, "bb")
+	}
+	if s := l.pending(); s != "aaaabb" {
+		t.Errorf("pending: %q, expected: %q", s, "aaaabb")
+	}
+	tb.Write([]byte("cc"))
+	close(bw.release)
+	if err := tb.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if s := l.pending(); s != "" {
+		t.Errorf("pending after flush: %q", s)
+	}
+	tb.Close()
+}
+
+func TestWriteAheadLogClasses(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("SetPriorityClasses accepted")
+		}
+	}()
+	NewBuffer(&lockedBuffer{}, SetWriteAheadLog(&testLog{}), SetPriorityClasses(64, OverflowBlock))
+}
@@ -0,0 +1,81 @@
+package syncio
+
+import (
+	"encoding/json"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// SetWriteSizeHistogram enables Stats.WriteSizes, the counting adds an atomic operation to
+// every Write that is noticeable in the small writes, see BenchmarkSmallWrites
+func SetWriteSizeHistogram(enabled bool) BufferOption {
+	return func(b *Buffer) {
+		b.writeSizes = enabled
+	}
+}
+
+// WriteSizeBounds are the upper bounds of the WriteSizeHistogram buckets, the last bucket
+// counts the writes of the last bound or more. The bounds are powers of 4 so the bucket is
+// found without comparing them.
+var WriteSizeBounds = [...]int{16, 64, 256, 1 << 10, 4 << 10, 16 << 10}
+
+// WriteSizeHistogram counts the writes smaller than every WriteSizeBounds, and the ones of
+// the last bound or more
+type WriteSizeHistogram [len(WriteSizeBounds) + 1]int64
+
+func (h *WriteSizeHistogram) observe(n int) {
+	i := (bits.Len(uint(n)) - 3) / 2
+	if i < 0 {
+		i = 0
+	} else if i > len(WriteSizeBounds) {
+		i = len(WriteSizeBounds)
+	}
+	atomic.AddInt64(&h[i], 1)
+}
+
+// load copies the histogram reading it atomically
+func (h *WriteSizeHistogram) load(dst *WriteSizeHistogram) {
+	for i := range h {
+		dst[i] = atomic.LoadInt64(&h[i])
+	}
+}
+
+// bucketName returns the name of the bucket i, e.g. "<1K" or ">=16K"
+func (h *WriteSizeHistogram) bucketName(i int) string {
+	if i < len(WriteSizeBounds) {
+		return "<" + sizeName(WriteSizeBounds[i])
+	}
+	return ">=" + sizeName(WriteSizeBounds[len(WriteSizeBounds)-1])
+}
+
+func sizeName(n int) string {
+	if n >= 1<<10 && n%(1<<10) == 0 {
+		return strconv.Itoa(n>>10) + "K"
+	}
+	return strconv.Itoa(n)
+}
+
+// String returns the counts of the buckets, e.g. "<16:3 <64:0 ... >=16K:1"
+func (h WriteSizeHistogram) String() string {
+	var sb strings.Builder
+	for i, n := range h {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(h.bucketName(i))
+		sb.WriteByte(':')
+		sb.WriteString(strconv.FormatInt(n, 10))
+	}
+	return sb.String()
+}
+
+// MarshalJSON returns an object with the counts by bucket name
+func (h WriteSizeHistogram) MarshalJSON() ([]byte, error) {
+	m := make(map[string]int64, len(h))
+	for i, n := range h {
+		m[h.bucketName(i)] = n
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,39 @@
+package syncio
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteSizeHistogram(t *testing.T) {
+	tb := NewBuffer(&testWriter{}, SetBufferSize(1024), SetWriteSizeHistogram(true))
+	for _, n := range []int{0, 1, 15, 16, 63, 64, 255, 256, 1023, 1024, 4095, 4096, 16383, 16384, 1 << 20} {
+		tb.Write(make([]byte, n))
+	}
+	tb.Close()
+
+	s := tb.Stats()
+	expected := WriteSizeHistogram{3, 2, 2, 2, 2, 2, 2}
+	if s.WriteSizes != expected {
+		t.Errorf("write sizes: %v, expected: %v", s.WriteSizes, expected)
+	}
+	if str := s.WriteSizes.String(); str != "<16:3 <64:2 <256:2 <1K:2 <4K:2 <16K:2 >=16K:2" {
+		t.Errorf("write sizes string: %q", str)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct{ WriteSizes map[string]int64 }
+	json.Unmarshal(data, &decoded)
+	if decoded.WriteSizes["<16"] != 3 || decoded.WriteSizes[">=16K"] != 2 || len(decoded.WriteSizes) != len(expected) {
+		t.Errorf("write sizes json: %s", data)
+	}
+
+	tb = NewBuffer(&testWriter{})
+	tb.Write([]byte("not counted"))
+	tb.Close()
+	if s := tb.Stats(); s.WriteSizes != (WriteSizeHistogram{}) {
+		t.Errorf("write sizes without the option: %v", s.WriteSizes)
+	}
+}
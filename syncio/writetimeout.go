@@ -0,0 +1,64 @@
+package syncio
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrWriteTimeout is the error of a flush whose sink write didn't end within the write timeout,
+// and of the next flushes until that write ends
+var ErrWriteTimeout = errors.New("sink write timeout")
+
+// SetWriteTimeout bounds every write to the underlying writer to d. The writes are done by a
+// goroutine: at the deadline the flush fails with ErrWriteTimeout, reported as any flush error
+// with ErrorTimeout, and the context of a ContextWriter with SetFlushContext is cancelled.
+// The write keeps its batch memory until it ends, meanwhile the next flushes fail with
+// ErrWriteTimeout without writing, or wait with SetRetryPolicy, so a wedged writer doesn't
+// hold the data in memory. The data of the write timed out can still be written when it ends.
+// It disables the vectored writes of SetMaxFlushBytes.
+func SetWriteTimeout(d time.Duration) BufferOption {
+	return func(b *Buffer) {
+		b.writeTimeout = d
+	}
+}
+
+// sinkResult is the result of a sink write
+type sinkResult struct {
+	n   int
+	err error
+}
+
+// attemptSinkTimeout is attemptSinkWrite by a goroutine, the batch is detached if it doesn't
+// end within the write timeout
+func (tb *Buffer) attemptSinkTimeout(b *batch, p []byte) (int, error) {
+	if tb.sinkWedged.Load() {
+		return 0, ErrWriteTimeout
+	}
+	res := make(chan sinkResult, 1)
+	go func() {
+		n, err := tb.attemptSinkWrite(b, p)
+		res <- sinkResult{n, err}
+	}()
+	t := time.NewTimer(tb.writeTimeout)
+	select {
+	case r := <-res:
+		t.Stop()
+		return r.n, r.err
+	case <-t.C:
+	}
+
+	tb.sinkWedged.Store(true)
+	tb.escalate(EscalationCancel, nil)
+	b.detached = make(chan struct{})
+	if b.scratch {
+		// the merge scratch memory is the write's until it ends
+		tb.merged = nil
+	}
+	go func() {
+		<-res
+		<-b.detached
+		tb.releaseBatch(b)
+		tb.sinkWedged.Store(false)
+	}()
+	return 0, ErrWriteTimeout
+}
@@ -0,0 +1,81 @@
+package syncio
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteTimeout(t *testing.T) {
+	inModes(t, func(t *testing.T, mode []BufferOption) {
+		bw := &blockingWriter{release: make(chan struct{})}
+		var mu sync.Mutex
+		var unwritten []string
+		onError := func(err *FlushError, p []byte) {
+			mu.Lock()
+			unwritten = append(unwritten, string(p))
+			mu.Unlock()
+		}
+		tb := NewBuffer(bw, append([]BufferOption{SetWriteTimeout(20 * time.Millisecond), SetOnFlushError(onError)}, mode...)...)
+		defer tb.Close()
+
+		tb.Write([]byte("aaa"))
+		start := time.Now()
+		if err := tb.Flush(); !errors.Is(err, ErrWriteTimeout) {
+			t.Fatalf("flush error: %v, expected: %v", err, ErrWriteTimeout)
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("timed out after %v", d)
+		}
+		// the next flushes fail until the write timed out ends
+		tb.Write([]byte("bbb"))
+		if err := tb.Flush(); !errors.Is(err, ErrWriteTimeout) {
+			t.Fatalf("flush error while wedged: %v, expected: %v", err, ErrWriteTimeout)
+		}
+		if s := tb.Stats(); s.Errors[ErrorTimeout] != 2 || s.FlushErrors != 2 {
+			t.Errorf("timeout errors: %v, flush errors: %v, expected: 2", s.Errors[ErrorTimeout], s.FlushErrors)
+		}
+		mu.Lock()
+		if len(unwritten) != 2 || unwritten[0] != "aaa" || unwritten[1] != "bbb" {
+			t.Errorf("unwritten: %q, expected: [aaa bbb]", unwritten)
+		}
+		mu.Unlock()
+
+		close(bw.release)
+		tb.Write([]byte("ccc"))
+		for deadline := time.Now().Add(time.Second); tb.Flush() != nil && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+			tb.Write([]byte("ccc"))
+		}
+		bw.mu.Lock()
+		defer bw.mu.Unlock()
+		// the write timed out ends after the deadline
+		if s := bw.out.String(); s != "aaaccc" {
+			t.Errorf("written: %q, expected: %q", s, "aaaccc")
+		}
+	})
+}
+
+func TestWriteTimeoutRetry(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	tb := NewBuffer(bw, SetWriteTimeout(20*time.Millisecond), SetRetryPolicy(100, func(int) time.Duration { return 5 * time.Millisecond }))
+	tb.Write([]byte("aaa"))
+	if err := tb.Flush(); !errors.Is(err, ErrWriteTimeout) {
+		t.Fatalf("flush error: %v, expected: %v", err, ErrWriteTimeout)
+	}
+	// retried until the write timed out ends
+	tb.Write([]byte("bbb"))
+	time.AfterFunc(30*time.Millisecond, func() { close(bw.release) })
+	if err := tb.Flush(); err != nil {
+		t.Fatalf("retried flush error: %v", err)
+	}
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+	if s := bw.out.String(); s != "aaabbb" {
+		t.Errorf("written: %q, expected: %q", s, "aaabbb")
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package syncio
+
+import (
+	"net"
+	"os"
+)
+
+// writevFiles enables the vectored writes of the *os.File sinks, there's no writev
+const writevFiles = false
+
+// writevFile writes every slice of vec, it's not used without writevFiles
+func writevFile(f *os.File, vec [][]byte) (written, calls int, err error) {
+	v := net.Buffers(vec)
+	n, err := v.WriteTo(f)
+	return int(n), len(vec), err
+}
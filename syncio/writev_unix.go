@@ -0,0 +1,68 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package syncio
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// writevFiles enables the vectored writes of the *os.File sinks
+const writevFiles = true
+
+// maxIovecs is the number of slices of a writev call, IOV_MAX on the supported systems
+const maxIovecs = 1024
+
+// writevFile writes vec to f with writev calls without copying it, the partial writes are
+// resumed from the first unwritten byte. vec is consumed, it returns the bytes written and
+// the number of calls.
+func writevFile(f *os.File, vec [][]byte) (written, calls int, err error) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := len(vec)
+	if size > maxIovecs {
+		size = maxIovecs
+	}
+	iov := make([]syscall.Iovec, 0, size)
+	for len(vec) > 0 {
+		iov = iov[:0]
+		for _, p := range vec {
+			if len(iov) == maxIovecs {
+				break
+			}
+			if len(p) > 0 {
+				v := syscall.Iovec{Base: &p[0]}
+				v.SetLen(len(p))
+				iov = append(iov, v)
+			}
+		}
+		if len(iov) == 0 {
+			break
+		}
+		var n uintptr
+		var errno syscall.Errno
+		// the callback returns false to wait until the fd is writable again
+		werr := rc.Write(func(fd uintptr) bool {
+			n, _, errno = syscall.Syscall(syscall.SYS_WRITEV, fd, uintptr(unsafe.Pointer(&iov[0])), uintptr(len(iov)))
+			return errno != syscall.EAGAIN
+		})
+		calls++
+		switch {
+		case werr != nil:
+			return written, calls, werr
+		case errno == syscall.EINTR:
+			continue
+		case errno != 0:
+			return written, calls, &os.PathError{Op: "writev", Path: f.Name(), Err: errno}
+		case n == 0:
+			return written, calls, io.ErrShortWrite
+		}
+		written += int(n)
+		vec = consumeVec(vec, int(n))
+	}
+	return written, calls, nil
+}
@@ -0,0 +1,51 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package syncio
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestWritevPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	tb := NewBuffer(w, SetSynchronousMode(true), SetMaxFlushBytes(1<<20))
+	if !tb.vectored {
+		t.Fatal("vectored writes not enabled for an *os.File")
+	}
+	received := make(chan []byte, 1)
+	go func() {
+		data, _ := io.ReadAll(r)
+		received <- data
+	}()
+
+	// the group is bigger than the pipe buffer, the nonblocking writev is partial and resumed
+	// in the middle of a batch once the reader drains the pipe
+	var expected []byte
+	tb.bufmu.Lock()
+	for i := 0; i < 10; i++ {
+		p := bytes.Repeat([]byte{'a' + byte(i)}, 20001)
+		tb.acct.accept(len(p))
+		tb.enqueue(&batch{p: p})
+		expected = append(expected, p...)
+	}
+	tb.bufmu.Unlock()
+	tb.flushInline()
+	if err := tb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if data := <-received; !bytes.Equal(data, expected) {
+		t.Errorf("received %v bytes, expected %v in order", len(data), len(expected))
+	}
+	if s := tb.Stats(); s.Flushes != 1 || s.SinkWrites < 2 {
+		t.Errorf("%v flushes, %v sink writes, expected a flush with partial writes", s.Flushes, s.SinkWrites)
+	}
+}